@@ -87,6 +87,14 @@ func main() {
 	onrampOptions := u.AddGroup(6, "OnRamp Options", "Options that control the Aether OnRamp provider")
 	flagOnRampDir := u.AddStringOption("", "onramp-dir", envOr("AETHER_ONRAMP_DIR", ""), "Path to aether-onramp repo; default: {data-dir}/aether-onramp (env: AETHER_ONRAMP_DIR)", "", onrampOptions)
 	flagOnRampVersion := u.AddStringOption("", "onramp-version", envOr("AETHER_ONRAMP_VERSION", "main"), "Tag, branch, or commit to pin aether-onramp to (env: AETHER_ONRAMP_VERSION)", "", onrampOptions)
+	flagOnRampBundle := u.AddStringOption("", "onramp-bundle", envOr("AETHER_ONRAMP_BUNDLE", ""), "Tarball or git bundle of aether-onramp to import instead of cloning, for offline sites (env: AETHER_ONRAMP_BUNDLE)", "", onrampOptions)
+	flagOnRampBundleSHA256 := u.AddStringOption("", "onramp-bundle-sha256", envOr("AETHER_ONRAMP_BUNDLE_SHA256", ""), "Expected SHA-256 of --onramp-bundle, hex encoded (env: AETHER_ONRAMP_BUNDLE_SHA256)", "", onrampOptions)
+	flagOnRampBundleSig := u.AddStringOption("", "onramp-bundle-signature", envOr("AETHER_ONRAMP_BUNDLE_SIGNATURE", ""), "Base64 ed25519 signature of the bundle's SHA-256 digest (env: AETHER_ONRAMP_BUNDLE_SIGNATURE)", "", onrampOptions)
+	flagOnRampTrustedKeys := u.AddStringOption("", "onramp-trusted-keys", envOr("AETHER_ONRAMP_TRUSTED_KEYS", ""), "PEM file of ed25519 public keys trusted to sign OnRamp bundles (env: AETHER_ONRAMP_TRUSTED_KEYS)", "", onrampOptions)
+	flagOnRampComponents := u.AddStringOption("", "onramp-components", envOr("AETHER_ONRAMP_COMPONENTS", ""), "YAML overlay describing OnRamp components, tiers and node roles; merged over the built-in one (env: AETHER_ONRAMP_COMPONENTS)", "", onrampOptions)
+	flagOnRampPlaybookDir := u.AddStringOption("", "onramp-playbook-dir", envOr("AETHER_ONRAMP_PLAYBOOK_DIR", ""), "Directory holding site playbooks that custom component actions may run (env: AETHER_ONRAMP_PLAYBOOK_DIR)", "", onrampOptions)
+	flagOnRampScriptDir := u.AddStringOption("", "onramp-script-dir", envOr("AETHER_ONRAMP_SCRIPT_DIR", ""), "Directory holding scripts that custom component actions may run (env: AETHER_ONRAMP_SCRIPT_DIR)", "", onrampOptions)
+	flagOnRampVaultPasswordFile := u.AddStringOption("", "onramp-vault-password-file", envOr("AETHER_ONRAMP_VAULT_PASSWORD_FILE", ""), "ansible-vault password file; node passwords are then kept vault-encrypted in host_vars (env: AETHER_ONRAMP_VAULT_PASSWORD_FILE)", "", onrampOptions)
 
 	frontendOptions := u.AddGroup(3, "Frontend Options", "Options that control frontend serving")
 	flagServeFrontend := u.AddBooleanOption("f", "serve-frontend", envBool("AETHER_SERVE_FRONTEND", true), "Enable serving frontend static files from embedded or custom directory (env: AETHER_SERVE_FRONTEND)", "", frontendOptions)
@@ -103,6 +111,11 @@ func main() {
 	metricsOptions := u.AddGroup(5, "Metrics Options", "Options that control metrics collection")
 	flagMetricsInterval := u.AddStringOption("", "metrics-interval", envOr("AETHER_METRICS_INTERVAL", "10s"), "How often to collect system metrics, e.g. 10s, 30s, 1m (env: AETHER_METRICS_INTERVAL)", "", metricsOptions)
 	flagMetricsRetention := u.AddStringOption("", "metrics-retention", envOr("AETHER_METRICS_RETENTION", "24h"), "How long to retain historical metrics data, e.g. 24h, 7d (env: AETHER_METRICS_RETENTION)", "", metricsOptions)
+	flagMetricsCollectNodes := u.AddBooleanOption("", "metrics-collect-nodes", envBool("AETHER_METRICS_COLLECT_NODES", false), "Also collect metrics from every managed node over SSH, labeled by node (env: AETHER_METRICS_COLLECT_NODES)", "", metricsOptions)
+
+	nodeOptions := u.AddGroup(8, "Node Options", "Options that control managed cluster nodes")
+	flagNodeHealthInterval := u.AddStringOption("", "node-health-interval", envOr("AETHER_NODE_HEALTH_INTERVAL", "1m"), "How often to probe managed nodes over SSH, e.g. 30s, 5m; 0 disables the health monitor (env: AETHER_NODE_HEALTH_INTERVAL)", "", nodeOptions)
+	flagNodeFactsInterval := u.AddStringOption("", "node-facts-interval", envOr("AETHER_NODE_FACTS_INTERVAL", "0"), "How often to re-gather node facts and check applied config defaults, e.g. 1h; 0 gathers only on request (env: AETHER_NODE_FACTS_INTERVAL)", "", nodeOptions)
 
 	parsed := u.Parse()
 
@@ -123,6 +136,18 @@ func main() {
 		os.Exit(1)
 	}
 
+	nodeHealthInterval, err := time.ParseDuration(*flagNodeHealthInterval)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid --node-health-interval: %v\n", err)
+		os.Exit(1)
+	}
+
+	nodeFactsInterval, err := time.ParseDuration(*flagNodeFactsInterval)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid --node-facts-interval: %v\n", err)
+		os.Exit(1)
+	}
+
 	var corsOrigins []string
 	if *flagCORSOrigins != "" {
 		for _, o := range strings.Split(*flagCORSOrigins, ",") {
@@ -154,10 +179,13 @@ func main() {
 		controller.WithProvider("system", true, func(_ context.Context, _ store.Client, opts []provider.Option) (provider.Provider, error) {
 			return system.NewProvider(system.Config{
 				CollectInterval: collectInterval,
+				CollectNodes:    *flagMetricsCollectNodes,
 			}, opts...), nil
 		}),
 		controller.WithProvider("nodes", true, func(_ context.Context, _ store.Client, opts []provider.Option) (provider.Provider, error) {
-			return nodes.NewProvider(opts...), nil
+			return nodes.NewProvider(nodes.Config{
+				HealthInterval: nodeHealthInterval,
+			}, opts...), nil
 		}),
 		controller.WithProvider("preflight", true, func(_ context.Context, _ store.Client, opts []provider.Option) (provider.Provider, error) {
 			return preflight.NewProvider(opts...), nil
@@ -171,9 +199,21 @@ func main() {
 				dir = filepath.Join(*flagDataDir, "aether-onramp")
 			}
 			return onramp.NewProvider(onramp.Config{
-				OnRampDir: dir,
-				RepoURL:   "https://github.com/opennetworkinglab/aether-onramp.git",
-				Version:   *flagOnRampVersion,
+				OnRampDir:     dir,
+				RepoURL:       "https://github.com/opennetworkinglab/aether-onramp.git",
+				Version:       *flagOnRampVersion,
+				WorkspacesDir: filepath.Join(*flagDataDir, "workspaces"),
+
+				BundlePath:      *flagOnRampBundle,
+				BundleSHA256:    *flagOnRampBundleSHA256,
+				BundleSignature: *flagOnRampBundleSig,
+				TrustedKeysFile: *flagOnRampTrustedKeys,
+
+				ComponentsFile: *flagOnRampComponents,
+				PlaybookDir:    *flagOnRampPlaybookDir,
+				ScriptDir:      *flagOnRampScriptDir,
+
+				VaultPasswordFile: *flagOnRampVaultPasswordFile,
 			}, opts...), nil
 		}),
 		controller.WithProvider("configdefaults", true, func(_ context.Context, _ store.Client, opts []provider.Option) (provider.Provider, error) {
@@ -182,7 +222,8 @@ func main() {
 				dir = filepath.Join(*flagDataDir, "aether-onramp")
 			}
 			return configdefaults.NewProvider(configdefaults.Config{
-				OnRampDir: dir,
+				OnRampDir:     dir,
+				FactsInterval: nodeFactsInterval,
 			}, &nodefacts.SSHGatherer{}, opts...), nil
 		}),
 	)
@@ -32,7 +32,7 @@ func main() {
 	opts := transport.ProviderOpts
 
 	system.NewProvider(system.Config{CollectInterval: 10 * time.Second}, opts("system")...)
-	nodes.NewProvider(opts("nodes")...)
+	nodes.NewProvider(nodes.Config{}, opts("nodes")...)
 	onramp.NewProvider(onramp.Config{}, opts("onramp")...)
 	meta.NewProvider(
 		meta.VersionInfo{},
@@ -203,6 +203,7 @@ func (c *Controller) initProviders(ctx context.Context, transport *rest.Transpor
 		}
 		c.providers = append(c.providers, p)
 	}
+	c.linkProviders()
 
 	metaProvider := c.createMetaProvider(transport)
 	c.providers = append(c.providers, metaProvider)
@@ -219,6 +220,28 @@ func (c *Controller) initProviders(ctx context.Context, transport *rest.Transpor
 	return nil
 }
 
+// linkProviders wires providers that depend on one another. Node roles are
+// validated against the component registry and topology rules of the OnRamp
+// checkout.
+func (c *Controller) linkProviders() {
+	var (
+		nodesProvider  *nodes.Nodes
+		onrampProvider *onramp.OnRamp
+	)
+	for _, p := range c.providers {
+		switch v := p.(type) {
+		case *nodes.Nodes:
+			nodesProvider = v
+		case *onramp.OnRamp:
+			onrampProvider = v
+		}
+	}
+	if nodesProvider != nil && onrampProvider != nil {
+		nodesProvider.SetRoleSource(onrampProvider.Roles)
+		nodesProvider.SetTopologySource(onrampProvider.CheckTopology)
+	}
+}
+
 // createMetaProvider builds the meta provider with closures that capture runtime state.
 func (c *Controller) createMetaProvider(transport *rest.Transport) *meta.Meta {
 	frontendSource := ""
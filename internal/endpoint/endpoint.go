@@ -1,6 +1,9 @@
 package endpoint
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 type Semantics uint8
 
@@ -15,6 +18,11 @@ const (
 type HTTPHint struct {
 	Method string
 	Path   string
+
+	// MaxBodyBytes and BodyReadTimeout override the transport defaults for
+	// endpoints that accept large uploads. Zero keeps the default.
+	MaxBodyBytes    int64
+	BodyReadTimeout time.Duration
 }
 
 type GRPCHint struct {
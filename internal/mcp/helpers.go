@@ -5,6 +5,8 @@ import (
 	"fmt"
 
 	gomcp "github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/bengrewell/aether-webui/internal/provider/onramp"
 )
 
 // jsonResult marshals v to JSON and returns it as a TextContent CallToolResult.
@@ -26,3 +28,14 @@ func errorResult(err error) *gomcp.CallToolResult {
 	}
 	return r
 }
+
+// onrampWorkspace converts a tool's workspace parameter for the OnRamp
+// handlers.
+func onrampWorkspace(p WorkspaceParam) onramp.WorkspaceParam {
+	return onramp.WorkspaceParam{Workspace: p.Workspace}
+}
+
+// workspaceInput is the input of OnRamp handlers that take only a workspace.
+func workspaceInput(p WorkspaceParam) *onramp.WorkspaceInput {
+	return &onramp.WorkspaceInput{WorkspaceParam: onrampWorkspace(p)}
+}
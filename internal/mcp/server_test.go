@@ -2,6 +2,7 @@ package mcp
 
 import (
 	"encoding/json"
+	"strings"
 	"testing"
 	"time"
 
@@ -25,7 +26,7 @@ func newTestServer(t *testing.T) *Server {
 	}
 	t.Cleanup(func() { st.Close() })
 
-	n := nodes.NewProvider(provider.WithStore(st))
+	n := nodes.NewProvider(nodes.Config{}, provider.WithStore(st))
 	o := onramp.NewProvider(onramp.Config{
 		OnRampDir: t.TempDir(),
 		RepoURL:   "https://example.com/repo.git",
@@ -93,8 +94,8 @@ func TestNew_RegistersExpectedToolCount(t *testing.T) {
 	srv := newTestServer(t)
 	names := listToolNames(t, srv)
 
-	// Expected: 5 nodes + 8 onramp + 5 tasks + 3 system + 3 meta = 24 tools
-	const expectedCount = 24
+	// Expected: 5 nodes + 8 onramp + 11 tasks + 3 system + 3 meta = 30 tools
+	const expectedCount = 30
 	if len(names) != expectedCount {
 		t.Errorf("got %d tools, want %d\ntools: %v", len(names), expectedCount, names)
 	}
@@ -114,6 +115,8 @@ func TestNew_ToolNamesPresent(t *testing.T) {
 		"components_list", "component_get", "deploy_action", "repo_status",
 		"repo_refresh", "config_get", "config_patch", "profiles_list",
 		"tasks_list", "task_get", "task_cancel", "actions_list", "action_get",
+		"queue_get", "queue_move", "queue_prioritize", "queue_pause",
+		"queue_resume", "queue_drain",
 		"component_states_list", "component_state_get",
 		"system_overview", "system_network", "system_metrics",
 		"server_status",
@@ -336,3 +339,77 @@ func TestCallTool_ComponentStatesList(t *testing.T) {
 		}
 	}
 }
+
+func TestCallTool_Workspace(t *testing.T) {
+	srv := newTestServer(t)
+	session := newTestSession(t, srv)
+	ctx := t.Context()
+	if err := srv.store.CreateWorkspace(ctx, store.Workspace{Name: "lab", OnRampDir: t.TempDir()}); err != nil {
+		t.Fatalf("CreateWorkspace: %v", err)
+	}
+
+	// Every node, OnRamp and task tool takes a workspace.
+	tools, err := session.ListTools(ctx, nil)
+	if err != nil {
+		t.Fatalf("ListTools: %v", err)
+	}
+	for _, tool := range tools.Tools {
+		if strings.HasPrefix(tool.Name, "system_") || tool.Name == "server_status" || strings.HasPrefix(tool.Name, "component_state") {
+			continue
+		}
+		schema, _ := json.Marshal(tool.InputSchema)
+		if !strings.Contains(string(schema), `"workspace"`) {
+			t.Errorf("%s has no workspace input", tool.Name)
+		}
+	}
+
+	result, err := session.CallTool(ctx, &gomcp.CallToolParams{
+		Name: "nodes_create",
+		Arguments: NodesCreateInput{
+			WorkspaceParam: WorkspaceParam{Workspace: "lab"},
+			Name:           "lab-node",
+			AnsibleHost:    "10.0.0.1",
+			AnsibleUser:    "admin",
+			Password:       "secret",
+			SudoPassword:   "secret",
+		},
+	})
+	if err != nil || result.IsError {
+		t.Fatalf("nodes_create in lab = %v, %v", result.Content[0].(*gomcp.TextContent).Text, err)
+	}
+	count := func(workspace string) int {
+		t.Helper()
+		result, err := session.CallTool(ctx, &gomcp.CallToolParams{
+			Name:      "nodes_list",
+			Arguments: NodesListInput{WorkspaceParam: WorkspaceParam{Workspace: workspace}},
+		})
+		if err != nil || result.IsError {
+			t.Fatalf("nodes_list %q = %v, %v", workspace, result, err)
+		}
+		var list []any
+		if err := json.Unmarshal([]byte(result.Content[0].(*gomcp.TextContent).Text), &list); err != nil {
+			t.Fatalf("unmarshal: %v", err)
+		}
+		return len(list)
+	}
+	if n := count("lab"); n != 1 {
+		t.Errorf("lab nodes = %d, want 1", n)
+	}
+	if n := count(""); n != 0 {
+		t.Errorf("default nodes = %d, want 0", n)
+	}
+
+	for name, args := range map[string]any{
+		"tasks_list":  TasksListInput{WorkspaceParam: WorkspaceParam{Workspace: "nope"}},
+		"queue_get":   QueueGetInput{WorkspaceParam: WorkspaceParam{Workspace: "nope"}},
+		"task_cancel": TaskCancelInput{WorkspaceParam: WorkspaceParam{Workspace: "nope"}, ID: "x"},
+	} {
+		result, err := session.CallTool(ctx, &gomcp.CallToolParams{Name: name, Arguments: args})
+		if err != nil {
+			t.Fatalf("CallTool %s: %v", name, err)
+		}
+		if !result.IsError || !strings.Contains(result.Content[0].(*gomcp.TextContent).Text, "workspace") {
+			t.Errorf("%s in unknown workspace = %v", name, result.Content)
+		}
+	}
+}
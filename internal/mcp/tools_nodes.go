@@ -12,8 +12,8 @@ func (s *Server) registerNodeTools() {
 	gomcp.AddTool(s.srv, &gomcp.Tool{
 		Name:        "nodes_list",
 		Description: "List all managed cluster nodes with roles and connection info",
-	}, func(ctx context.Context, _ *gomcp.CallToolRequest, _ NodesListInput) (*gomcp.CallToolResult, any, error) {
-		out, err := s.nodes.HandleList(ctx, nil)
+	}, func(ctx context.Context, _ *gomcp.CallToolRequest, args NodesListInput) (*gomcp.CallToolResult, any, error) {
+		out, err := s.nodes.HandleList(ctx, &nodes.NodeListInput{WorkspaceParam: nodes.WorkspaceParam{Workspace: args.Workspace}})
 		if err != nil {
 			return errorResult(err), nil, nil
 		}
@@ -24,7 +24,7 @@ func (s *Server) registerNodeTools() {
 		Name:        "nodes_get",
 		Description: "Get a single managed node by ID",
 	}, func(ctx context.Context, _ *gomcp.CallToolRequest, args NodesGetInput) (*gomcp.CallToolResult, any, error) {
-		out, err := s.nodes.HandleGet(ctx, &nodes.NodeGetInput{ID: args.ID})
+		out, err := s.nodes.HandleGet(ctx, &nodes.NodeGetInput{WorkspaceParam: nodes.WorkspaceParam{Workspace: args.Workspace}, ID: args.ID})
 		if err != nil {
 			return errorResult(err), nil, nil
 		}
@@ -35,7 +35,7 @@ func (s *Server) registerNodeTools() {
 		Name:        "nodes_create",
 		Description: "Create a new managed cluster node with credentials and role assignments",
 	}, func(ctx context.Context, _ *gomcp.CallToolRequest, args NodesCreateInput) (*gomcp.CallToolResult, any, error) {
-		in := &nodes.NodeCreateInput{}
+		in := &nodes.NodeCreateInput{WorkspaceParam: nodes.WorkspaceParam{Workspace: args.Workspace}}
 		in.Body.Name = args.Name
 		in.Body.AnsibleHost = args.AnsibleHost
 		in.Body.AnsibleUser = args.AnsibleUser
@@ -54,7 +54,7 @@ func (s *Server) registerNodeTools() {
 		Name:        "nodes_update",
 		Description: "Partial update a managed node (only provided fields are changed)",
 	}, func(ctx context.Context, _ *gomcp.CallToolRequest, args NodesUpdateInput) (*gomcp.CallToolResult, any, error) {
-		in := &nodes.NodeUpdateInput{ID: args.ID}
+		in := &nodes.NodeUpdateInput{WorkspaceParam: nodes.WorkspaceParam{Workspace: args.Workspace}, ID: args.ID}
 		in.Body.Name = args.Name
 		in.Body.AnsibleHost = args.AnsibleHost
 		in.Body.AnsibleUser = args.AnsibleUser
@@ -73,7 +73,7 @@ func (s *Server) registerNodeTools() {
 		Name:        "nodes_delete",
 		Description: "Delete a managed node by ID",
 	}, func(ctx context.Context, _ *gomcp.CallToolRequest, args NodesDeleteInput) (*gomcp.CallToolResult, any, error) {
-		out, err := s.nodes.HandleDelete(ctx, &nodes.NodeDeleteInput{ID: args.ID})
+		out, err := s.nodes.HandleDelete(ctx, &nodes.NodeDeleteInput{WorkspaceParam: nodes.WorkspaceParam{Workspace: args.Workspace}, ID: args.ID})
 		if err != nil {
 			return errorResult(err), nil, nil
 		}
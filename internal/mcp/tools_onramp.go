@@ -13,8 +13,8 @@ func (s *Server) registerOnRampTools() {
 	gomcp.AddTool(s.srv, &gomcp.Tool{
 		Name:        "components_list",
 		Description: "List all deployable OnRamp components and their available actions",
-	}, func(ctx context.Context, _ *gomcp.CallToolRequest, _ ComponentsListInput) (*gomcp.CallToolResult, any, error) {
-		out, err := s.onramp.HandleListComponents(ctx, nil)
+	}, func(ctx context.Context, _ *gomcp.CallToolRequest, args ComponentsListInput) (*gomcp.CallToolResult, any, error) {
+		out, err := s.onramp.HandleListComponents(ctx, workspaceInput(args.WorkspaceParam))
 		if err != nil {
 			return errorResult(err), nil, nil
 		}
@@ -25,7 +25,7 @@ func (s *Server) registerOnRampTools() {
 		Name:        "component_get",
 		Description: "Get a single OnRamp component's details and available actions",
 	}, func(ctx context.Context, _ *gomcp.CallToolRequest, args ComponentGetInput) (*gomcp.CallToolResult, any, error) {
-		out, err := s.onramp.HandleGetComponent(ctx, &onramp.ComponentGetInput{Component: args.Component})
+		out, err := s.onramp.HandleGetComponent(ctx, &onramp.ComponentGetInput{WorkspaceParam: onrampWorkspace(args.WorkspaceParam), Component: args.Component})
 		if err != nil {
 			return errorResult(err), nil, nil
 		}
@@ -37,8 +37,9 @@ func (s *Server) registerOnRampTools() {
 		Description: "Execute a deployment action on a component (async, returns task ID for monitoring)",
 	}, func(ctx context.Context, _ *gomcp.CallToolRequest, args DeployActionInput) (*gomcp.CallToolResult, any, error) {
 		in := &onramp.ExecuteActionInput{
-			Component: args.Component,
-			Action:    args.Action,
+			WorkspaceParam: onrampWorkspace(args.WorkspaceParam),
+			Component:      args.Component,
+			Action:         args.Action,
 		}
 		if len(args.Labels) > 0 || len(args.Tags) > 0 {
 			in.Body = &onramp.ExecuteActionBody{
@@ -56,8 +57,8 @@ func (s *Server) registerOnRampTools() {
 	gomcp.AddTool(s.srv, &gomcp.Tool{
 		Name:        "repo_status",
 		Description: "Get the OnRamp git repository clone status, branch, commit, and dirty state",
-	}, func(ctx context.Context, _ *gomcp.CallToolRequest, _ RepoStatusInput) (*gomcp.CallToolResult, any, error) {
-		out, err := s.onramp.HandleGetRepoStatus(ctx, nil)
+	}, func(ctx context.Context, _ *gomcp.CallToolRequest, args RepoStatusInput) (*gomcp.CallToolResult, any, error) {
+		out, err := s.onramp.HandleGetRepoStatus(ctx, workspaceInput(args.WorkspaceParam))
 		if err != nil {
 			return errorResult(err), nil, nil
 		}
@@ -67,8 +68,8 @@ func (s *Server) registerOnRampTools() {
 	gomcp.AddTool(s.srv, &gomcp.Tool{
 		Name:        "repo_refresh",
 		Description: "Clone the OnRamp repository if missing, or validate and refresh it",
-	}, func(ctx context.Context, _ *gomcp.CallToolRequest, _ RepoRefreshInput) (*gomcp.CallToolResult, any, error) {
-		out, err := s.onramp.HandleRefreshRepo(ctx, nil)
+	}, func(ctx context.Context, _ *gomcp.CallToolRequest, args RepoRefreshInput) (*gomcp.CallToolResult, any, error) {
+		out, err := s.onramp.HandleRefreshRepo(ctx, workspaceInput(args.WorkspaceParam))
 		if err != nil {
 			return errorResult(err), nil, nil
 		}
@@ -78,8 +79,8 @@ func (s *Server) registerOnRampTools() {
 	gomcp.AddTool(s.srv, &gomcp.Tool{
 		Name:        "config_get",
 		Description: "Get the current OnRamp configuration (vars/main.yml)",
-	}, func(ctx context.Context, _ *gomcp.CallToolRequest, _ ConfigGetInput) (*gomcp.CallToolResult, any, error) {
-		out, err := s.onramp.HandleGetConfig(ctx, nil)
+	}, func(ctx context.Context, _ *gomcp.CallToolRequest, args ConfigGetInput) (*gomcp.CallToolResult, any, error) {
+		out, err := s.onramp.HandleGetConfig(ctx, workspaceInput(args.WorkspaceParam))
 		if err != nil {
 			return errorResult(err), nil, nil
 		}
@@ -98,7 +99,7 @@ func (s *Server) registerOnRampTools() {
 		if err != nil {
 			return errorResult(err), nil, nil
 		}
-		in := &onramp.ConfigPatchInput{RawBody: rawBody}
+		in := &onramp.ConfigPatchInput{WorkspaceParam: onrampWorkspace(args.WorkspaceParam), RawBody: rawBody}
 		out, err := s.onramp.HandlePatchConfig(ctx, in)
 		if err != nil {
 			return errorResult(err), nil, nil
@@ -109,8 +110,8 @@ func (s *Server) registerOnRampTools() {
 	gomcp.AddTool(s.srv, &gomcp.Tool{
 		Name:        "profiles_list",
 		Description: "List available OnRamp configuration profiles (main-*.yml files)",
-	}, func(ctx context.Context, _ *gomcp.CallToolRequest, _ ProfilesListInput) (*gomcp.CallToolResult, any, error) {
-		out, err := s.onramp.HandleListProfiles(ctx, nil)
+	}, func(ctx context.Context, _ *gomcp.CallToolRequest, args ProfilesListInput) (*gomcp.CallToolResult, any, error) {
+		out, err := s.onramp.HandleListProfiles(ctx, workspaceInput(args.WorkspaceParam))
 		if err != nil {
 			return errorResult(err), nil, nil
 		}
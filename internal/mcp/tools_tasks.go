@@ -14,8 +14,8 @@ func (s *Server) registerTaskTools() {
 	gomcp.AddTool(s.srv, &gomcp.Tool{
 		Name:        "tasks_list",
 		Description: "List all active and recent tasks (make target executions)",
-	}, func(ctx context.Context, _ *gomcp.CallToolRequest, _ TasksListInput) (*gomcp.CallToolResult, any, error) {
-		out, err := s.onramp.HandleListTasks(ctx, nil)
+	}, func(ctx context.Context, _ *gomcp.CallToolRequest, args TasksListInput) (*gomcp.CallToolResult, any, error) {
+		out, err := s.onramp.HandleListTasks(ctx, workspaceInput(args.WorkspaceParam))
 		if err != nil {
 			return errorResult(err), nil, nil
 		}
@@ -27,8 +27,9 @@ func (s *Server) registerTaskTools() {
 		Description: "Get task details and output (supports incremental reads via offset)",
 	}, func(ctx context.Context, _ *gomcp.CallToolRequest, args TaskGetInput) (*gomcp.CallToolResult, any, error) {
 		out, err := s.onramp.HandleGetTask(ctx, &onramp.TaskGetInput{
-			ID:     args.ID,
-			Offset: args.Offset,
+			WorkspaceParam: onrampWorkspace(args.WorkspaceParam),
+			ID:             args.ID,
+			Offset:         args.Offset,
 		})
 		if err != nil {
 			return errorResult(err), nil, nil
@@ -39,9 +40,8 @@ func (s *Server) registerTaskTools() {
 	gomcp.AddTool(s.srv, &gomcp.Tool{
 		Name:        "task_cancel",
 		Description: "Cancel a pending or running task",
-	}, func(_ context.Context, _ *gomcp.CallToolRequest, args TaskCancelInput) (*gomcp.CallToolResult, any, error) {
-		runner := s.onramp.Runner()
-		err := runner.Cancel(args.ID)
+	}, func(ctx context.Context, _ *gomcp.CallToolRequest, args TaskCancelInput) (*gomcp.CallToolResult, any, error) {
+		err := s.onramp.CancelTask(ctx, args.Workspace, args.ID)
 		if err != nil {
 			if err == taskrunner.ErrNotFound {
 				return errorResult(fmt.Errorf("task not found: %s", args.ID)), nil, nil
@@ -54,6 +54,74 @@ func (s *Server) registerTaskTools() {
 		return jsonResult(map[string]string{"message": fmt.Sprintf("task %s canceled", args.ID)}), nil, nil
 	})
 
+	gomcp.AddTool(s.srv, &gomcp.Tool{
+		Name:        "queue_get",
+		Description: "Show pending tasks in start order with queue positions, estimated start times, and whether the queue is paused",
+	}, func(ctx context.Context, _ *gomcp.CallToolRequest, args QueueGetInput) (*gomcp.CallToolResult, any, error) {
+		out, err := s.onramp.HandleGetQueue(ctx, workspaceInput(args.WorkspaceParam))
+		if err != nil {
+			return errorResult(err), nil, nil
+		}
+		return jsonResult(out.Body), nil, nil
+	})
+
+	gomcp.AddTool(s.srv, &gomcp.Tool{
+		Name:        "queue_move",
+		Description: "Move a pending task to a new position in the queue",
+	}, func(ctx context.Context, _ *gomcp.CallToolRequest, args QueueMoveInput) (*gomcp.CallToolResult, any, error) {
+		in := &onramp.QueueMoveInput{WorkspaceParam: onrampWorkspace(args.WorkspaceParam), ID: args.ID}
+		in.Body.Position = args.Position
+		out, err := s.onramp.HandleMoveQueueTask(ctx, in)
+		if err != nil {
+			return errorResult(err), nil, nil
+		}
+		return jsonResult(out.Body), nil, nil
+	})
+
+	gomcp.AddTool(s.srv, &gomcp.Tool{
+		Name:        "queue_prioritize",
+		Description: "Move a pending task to the front of the queue so it starts next",
+	}, func(ctx context.Context, _ *gomcp.CallToolRequest, args QueuePrioritizeInput) (*gomcp.CallToolResult, any, error) {
+		out, err := s.onramp.HandlePrioritizeQueueTask(ctx, &onramp.QueueTaskInput{WorkspaceParam: onrampWorkspace(args.WorkspaceParam), ID: args.ID})
+		if err != nil {
+			return errorResult(err), nil, nil
+		}
+		return jsonResult(out.Body), nil, nil
+	})
+
+	gomcp.AddTool(s.srv, &gomcp.Tool{
+		Name:        "queue_pause",
+		Description: "Pause the task queue so no pending task starts; the running task continues",
+	}, func(ctx context.Context, _ *gomcp.CallToolRequest, args QueuePauseInput) (*gomcp.CallToolResult, any, error) {
+		out, err := s.onramp.HandlePauseQueue(ctx, workspaceInput(args.WorkspaceParam))
+		if err != nil {
+			return errorResult(err), nil, nil
+		}
+		return jsonResult(out.Body), nil, nil
+	})
+
+	gomcp.AddTool(s.srv, &gomcp.Tool{
+		Name:        "queue_resume",
+		Description: "Resume a paused task queue",
+	}, func(ctx context.Context, _ *gomcp.CallToolRequest, args QueueResumeInput) (*gomcp.CallToolResult, any, error) {
+		out, err := s.onramp.HandleResumeQueue(ctx, workspaceInput(args.WorkspaceParam))
+		if err != nil {
+			return errorResult(err), nil, nil
+		}
+		return jsonResult(out.Body), nil, nil
+	})
+
+	gomcp.AddTool(s.srv, &gomcp.Tool{
+		Name:        "queue_drain",
+		Description: "Cancel every pending task in the queue; the running task continues",
+	}, func(ctx context.Context, _ *gomcp.CallToolRequest, args QueueDrainInput) (*gomcp.CallToolResult, any, error) {
+		out, err := s.onramp.HandleDrainQueue(ctx, workspaceInput(args.WorkspaceParam))
+		if err != nil {
+			return errorResult(err), nil, nil
+		}
+		return jsonResult(out.Body), nil, nil
+	})
+
 	gomcp.AddTool(s.srv, &gomcp.Tool{
 		Name:        "actions_list",
 		Description: "Query action execution history with optional filters for component, action, and status",
@@ -63,11 +131,12 @@ func (s *Server) registerTaskTools() {
 			limit = 50
 		}
 		out, err := s.onramp.HandleListActions(ctx, &onramp.ActionListInput{
-			Component: args.Component,
-			Action:    args.Action,
-			Status:    args.Status,
-			Limit:     limit,
-			Offset:    args.Offset,
+			WorkspaceParam: onrampWorkspace(args.WorkspaceParam),
+			Component:      args.Component,
+			Action:         args.Action,
+			Status:         args.Status,
+			Limit:          limit,
+			Offset:         args.Offset,
 		})
 		if err != nil {
 			return errorResult(err), nil, nil
@@ -79,7 +148,7 @@ func (s *Server) registerTaskTools() {
 		Name:        "action_get",
 		Description: "Get a single action execution record by ID",
 	}, func(ctx context.Context, _ *gomcp.CallToolRequest, args ActionGetInput) (*gomcp.CallToolResult, any, error) {
-		out, err := s.onramp.HandleGetAction(ctx, &onramp.ActionGetInput{ID: args.ID})
+		out, err := s.onramp.HandleGetAction(ctx, &onramp.ActionGetInput{WorkspaceParam: onrampWorkspace(args.WorkspaceParam), ID: args.ID})
 		if err != nil {
 			return errorResult(err), nil, nil
 		}
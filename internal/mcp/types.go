@@ -4,15 +4,24 @@ package mcp
 // tags for automatic schema generation by the go-sdk. They are decoupled from
 // Huma-specific input types.
 
+// WorkspaceParam selects the workspace a node, OnRamp or task tool acts on.
+type WorkspaceParam struct {
+	Workspace string `json:"workspace,omitempty" jsonschema:"workspace name; omit for the default workspace"`
+}
+
 // --- Nodes ---
 
-type NodesListInput struct{}
+type NodesListInput struct {
+	WorkspaceParam
+}
 
 type NodesGetInput struct {
+	WorkspaceParam
 	ID string `json:"id" jsonschema:"node ID"`
 }
 
 type NodesCreateInput struct {
+	WorkspaceParam
 	Name         string   `json:"name" jsonschema:"unique node name (Ansible inventory hostname)"`
 	AnsibleHost  string   `json:"ansible_host" jsonschema:"IP or hostname for SSH"`
 	AnsibleUser  string   `json:"ansible_user" jsonschema:"SSH username"`
@@ -23,6 +32,7 @@ type NodesCreateInput struct {
 }
 
 type NodesUpdateInput struct {
+	WorkspaceParam
 	ID           string   `json:"id" jsonschema:"node ID"`
 	Name         *string  `json:"name,omitempty" jsonschema:"unique node name"`
 	AnsibleHost  *string  `json:"ansible_host,omitempty" jsonschema:"IP or hostname for SSH"`
@@ -34,50 +44,96 @@ type NodesUpdateInput struct {
 }
 
 type NodesDeleteInput struct {
+	WorkspaceParam
 	ID string `json:"id" jsonschema:"node ID"`
 }
 
 // --- OnRamp ---
 
-type ComponentsListInput struct{}
+type ComponentsListInput struct {
+	WorkspaceParam
+}
 
 type ComponentGetInput struct {
+	WorkspaceParam
 	Component string `json:"component" jsonschema:"component name (e.g. k8s, 5gc, gnbsim)"`
 }
 
 type DeployActionInput struct {
+	WorkspaceParam
 	Component string            `json:"component" jsonschema:"component name"`
 	Action    string            `json:"action" jsonschema:"action name (e.g. install, uninstall)"`
 	Labels    map[string]string `json:"labels,omitempty" jsonschema:"optional labels for the action"`
 	Tags      []string          `json:"tags,omitempty" jsonschema:"optional tags for the action"`
 }
 
-type RepoStatusInput struct{}
+type RepoStatusInput struct {
+	WorkspaceParam
+}
 
-type RepoRefreshInput struct{}
+type RepoRefreshInput struct {
+	WorkspaceParam
+}
 
-type ConfigGetInput struct{}
+type ConfigGetInput struct {
+	WorkspaceParam
+}
 
 type ConfigPatchInput struct {
+	WorkspaceParam
 	Config map[string]any `json:"config" jsonschema:"partial config to merge into vars/main.yml"`
 }
 
-type ProfilesListInput struct{}
+type ProfilesListInput struct {
+	WorkspaceParam
+}
 
 // --- Tasks ---
 
-type TasksListInput struct{}
+type TasksListInput struct {
+	WorkspaceParam
+}
 
 type TaskGetInput struct {
+	WorkspaceParam
 	ID     string `json:"id" jsonschema:"task ID"`
 	Offset int    `json:"offset,omitempty" jsonschema:"byte offset for incremental output reads"`
 }
 
 type TaskCancelInput struct {
+	WorkspaceParam
 	ID string `json:"id" jsonschema:"task ID to cancel"`
 }
 
+type QueueGetInput struct {
+	WorkspaceParam
+}
+
+type QueueMoveInput struct {
+	WorkspaceParam
+	ID       string `json:"id" jsonschema:"pending task ID"`
+	Position int    `json:"position" jsonschema:"new 1-based queue position (clamped to the queue length)"`
+}
+
+type QueuePrioritizeInput struct {
+	WorkspaceParam
+	ID string `json:"id" jsonschema:"pending task ID to move to the front of the queue"`
+}
+
+type QueuePauseInput struct {
+	WorkspaceParam
+}
+
+type QueueResumeInput struct {
+	WorkspaceParam
+}
+
+type QueueDrainInput struct {
+	WorkspaceParam
+}
+
 type ActionsListInput struct {
+	WorkspaceParam
 	Component string `json:"component,omitempty" jsonschema:"filter by component name"`
 	Action    string `json:"action,omitempty" jsonschema:"filter by action name"`
 	Status    string `json:"status,omitempty" jsonschema:"filter by status"`
@@ -86,6 +142,7 @@ type ActionsListInput struct {
 }
 
 type ActionGetInput struct {
+	WorkspaceParam
 	ID string `json:"id" jsonschema:"action ID"`
 }
 
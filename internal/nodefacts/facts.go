@@ -3,9 +3,16 @@ package nodefacts
 import (
 	"context"
 	"time"
+
+	internalssh "github.com/bengrewell/aether-webui/internal/ssh"
 )
 
-// NodeFacts holds discovered network information about a managed node.
+// KernelModules are the kernel modules whose state is reported in
+// KernelInfo.Modules.
+var KernelModules = []string{"sctp", "gtp5g"}
+
+// NodeFacts holds discovered network and hardware information about a
+// managed node. The hardware sections are nil when their probe failed.
 type NodeFacts struct {
 	NodeID        string          `json:"node_id"`
 	NodeName      string          `json:"node_name"`
@@ -14,19 +21,120 @@ type NodeFacts struct {
 	DefaultIP     string          `json:"default_ip"`
 	DefaultSubnet string          `json:"default_subnet"`
 	Interfaces    []InterfaceInfo `json:"interfaces"`
+	CPU           *CPUInfo        `json:"cpu,omitempty"`
+	Memory        *MemoryInfo     `json:"memory,omitempty"`
+	Kernel        *KernelInfo     `json:"kernel,omitempty"`
+	OS            *OSInfo         `json:"os,omitempty"`
+	TimeSync      *TimeSyncInfo   `json:"time_sync,omitempty"`
 	GatheredAt    time.Time       `json:"gathered_at"`
 	Error         string          `json:"error,omitempty"`
 }
 
-// InterfaceInfo describes a single network interface on a node.
+// InterfaceInfo describes a single network interface on a node. The device
+// fields are empty for virtual interfaces.
 type InterfaceInfo struct {
-	Name      string   `json:"name"`
-	Addresses []string `json:"addresses"`
-	MAC       string   `json:"mac"`
-	IsUp      bool     `json:"is_up"`
+	Name          string   `json:"name"`
+	Addresses     []string `json:"addresses"`
+	MAC           string   `json:"mac"`
+	IsUp          bool     `json:"is_up"`
+	MTU           int      `json:"mtu,omitempty"`
+	Driver        string   `json:"driver,omitempty"`
+	PCIAddress    string   `json:"pci_address,omitempty"`
+	SpeedMbps     int      `json:"speed_mbps,omitempty"`
+	SRIOVTotalVFs int      `json:"sriov_total_vfs,omitempty"` // 0 if SR-IOV is not supported
+	SRIOVNumVFs   int      `json:"sriov_num_vfs,omitempty"`
+}
+
+// CPUInfo describes a node's processors.
+type CPUInfo struct {
+	Model   string   `json:"model"`
+	Sockets int      `json:"sockets"`
+	Cores   int      `json:"cores"`   // physical cores across all sockets
+	Threads int      `json:"threads"` // logical CPUs
+	Flags   []string `json:"flags"`
+	AVX2    bool     `json:"avx2"`
+	AVX512  bool     `json:"avx512"` // avx512f
+}
+
+// MemoryInfo describes a node's memory and hugepage pools.
+type MemoryInfo struct {
+	TotalBytes     uint64         `json:"total_bytes"`
+	AvailableBytes uint64         `json:"available_bytes"`
+	Hugepages      []HugepagePool `json:"hugepages"`
+}
+
+// HugepagePool is the hugepage reservation for one page size.
+type HugepagePool struct {
+	SizeKB uint64 `json:"size_kb"`
+	Total  uint64 `json:"total"`
+	Free   uint64 `json:"free"`
+}
+
+// KernelInfo describes a node's kernel.
+type KernelInfo struct {
+	Release string       `json:"release"`
+	Modules []ModuleInfo `json:"modules"`
+}
+
+// ModuleInfo is the state of one of KernelModules on a node.
+type ModuleInfo struct {
+	Name      string `json:"name"`
+	Loaded    bool   `json:"loaded"`
+	Available bool   `json:"available"` // installed or built in
+}
+
+// OSInfo is a node's distribution, from /etc/os-release.
+type OSInfo struct {
+	ID         string `json:"id"`
+	VersionID  string `json:"version_id"`
+	PrettyName string `json:"pretty_name"`
+}
+
+// TimeSyncInfo is a node's clock synchronization state.
+type TimeSyncInfo struct {
+	Synchronized bool   `json:"synchronized"`
+	NTPEnabled   bool   `json:"ntp_enabled"`
+	Service      string `json:"service,omitempty"` // active time sync daemon, e.g. chronyd
+}
+
+// Interface returns the interface with the given name.
+func (f NodeFacts) Interface(name string) (InterfaceInfo, bool) {
+	for _, i := range f.Interfaces {
+		if i.Name == name {
+			return i, true
+		}
+	}
+	return InterfaceInfo{}, false
+}
+
+// HugepagesTotal returns the total hugepage reservation in bytes across all
+// page sizes, or 0 if memory facts are missing.
+func (f NodeFacts) HugepagesTotal() uint64 {
+	if f.Memory == nil {
+		return 0
+	}
+	var total uint64
+	for _, p := range f.Memory.Hugepages {
+		total += p.SizeKB * 1024 * p.Total
+	}
+	return total
+}
+
+// Module returns the state of the named kernel module, if it was probed.
+func (f NodeFacts) Module(name string) (ModuleInfo, bool) {
+	if f.Kernel == nil {
+		return ModuleInfo{}, false
+	}
+	for _, m := range f.Kernel.Modules {
+		if m.Name == name {
+			return m, true
+		}
+	}
+	return ModuleInfo{}, false
 }
 
-// Gatherer discovers network facts from a remote node.
+// Gatherer discovers network facts from a remote node. cfg holds the node's
+// connection settings, jump hosts included.
 type Gatherer interface {
-	Gather(ctx context.Context, host, user string, password string, sshKey []byte) (NodeFacts, error)
+	Gather(ctx context.Context, cfg internalssh.Config) (NodeFacts, error)
 }
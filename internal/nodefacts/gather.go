@@ -23,27 +23,33 @@ type SSHGatherer struct {
 	Timeout time.Duration // SSH dial timeout; defaults to 10s
 }
 
-// Gather connects to the node via SSH, runs discovery commands, and returns
-// structured facts. Falls back to text parsing if `ip -j` is unavailable.
-func (g *SSHGatherer) Gather(ctx context.Context, host, user string, password string, sshKey []byte) (NodeFacts, error) {
-	timeout := g.Timeout
-	if timeout == 0 {
-		timeout = 10 * time.Second
+// Gather connects to the node via SSH, through its jump hosts if it has any,
+// runs discovery commands, and returns structured facts. Falls back to text
+// parsing if `ip -j` is unavailable. Hardware probes that fail leave their
+// section of the facts empty.
+func (g *SSHGatherer) Gather(ctx context.Context, cfg internalssh.Config) (NodeFacts, error) {
+	if cfg.Timeout == 0 {
+		cfg.Timeout = g.Timeout
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 10 * time.Second
 	}
 
-	client, err := internalssh.Dial(ctx, internalssh.Config{
-		Host:     host,
-		User:     user,
-		Password: password,
-		Key:      sshKey,
-		Timeout:  timeout,
-	})
+	client, err := internalssh.Dial(ctx, cfg)
 	if err != nil {
-		return NodeFacts{}, fmt.Errorf("nodefacts: dial %s: %w", host, err)
+		return NodeFacts{}, fmt.Errorf("nodefacts: dial %s: %w", cfg.Host, err)
 	}
 	defer client.Close()
 
-	return gatherFromRunner(ctx, client, host)
+	return gatherFromRunner(ctx, client, HostOf(cfg))
+}
+
+// HostOf returns the host of cfg without its port.
+func HostOf(cfg internalssh.Config) string {
+	if host, _, err := net.SplitHostPort(cfg.Host); err == nil {
+		return host
+	}
+	return cfg.Host
 }
 
 // gatherFromRunner runs discovery commands using the provided Runner.
@@ -74,6 +80,9 @@ func gatherFromRunner(ctx context.Context, r Runner, host string) (NodeFacts, er
 		facts.Interfaces = ifaces
 	}
 
+	// Discover CPU, memory, kernel, OS, NIC and time sync details.
+	gatherHardware(ctx, r, &facts)
+
 	return facts, nil
 }
 
@@ -196,6 +205,7 @@ type ipLinkJSON struct {
 	Ifname   string `json:"ifname"`
 	Address  string `json:"address"`
 	Operstate string `json:"operstate"`
+	MTU       int    `json:"mtu"`
 }
 
 func discoverInterfaces(ctx context.Context, r Runner) ([]InterfaceInfo, error) {
@@ -217,6 +227,7 @@ func discoverInterfaces(ctx context.Context, r Runner) ([]InterfaceInfo, error)
 			Name:      link.Ifname,
 			MAC:       link.Address,
 			IsUp:      strings.EqualFold(link.Operstate, "up"),
+			MTU:       link.MTU,
 			Addresses: addrMap[link.Ifname],
 		}
 		result = append(result, info)
@@ -0,0 +1,256 @@
+package nodefacts
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bengrewell/aether-webui/internal/store"
+)
+
+// FactChange is one difference between two versions of a node's facts.
+type FactChange struct {
+	Path string `json:"path" doc:"Dotted fact path; interfaces, modules and hugepage pools are keyed by name or page size" example:"interfaces.ens18.addresses"`
+	Kind string `json:"kind" enum:"added,removed,changed"`
+	Old  any    `json:"old,omitempty"`
+	New  any    `json:"new,omitempty"`
+}
+
+// Version is one stored version of a node's facts. Facts.GatheredAt is the
+// latest gather that found them.
+type Version struct {
+	ID             int64        `json:"id"`
+	NodeID         string       `json:"node_id"`
+	GatheredAt     time.Time    `json:"gathered_at" doc:"First gather that found these facts"`
+	LastGatheredAt time.Time    `json:"last_gathered_at" doc:"Latest gather that found these facts"`
+	Changes        []FactChange `json:"changes" doc:"Changes from the previous version; empty for the first"`
+	Facts          NodeFacts    `json:"-"`
+}
+
+// volatileFacts are fact paths that change on nearly every gather or are
+// not facts of the node, so Diff ignores them. A "*" matches one path
+// element.
+var volatileFacts = []string{
+	"node_id",
+	"node_name",
+	"gathered_at",
+	"error",
+	"memory.available_bytes",
+	"memory.hugepages.*.free",
+}
+
+// Diff returns the changes from a to b ordered by path. Interfaces, kernel
+// modules and hugepage pools are compared by name or page size, so a renamed
+// interface is one removal and one addition. Other lists are compared as a
+// whole.
+func Diff(a, b NodeFacts) []FactChange {
+	fa, fb := flattenFacts(a), flattenFacts(b)
+	paths := make([]string, 0, len(fa)+len(fb))
+	for p := range fa {
+		paths = append(paths, p)
+	}
+	for p := range fb {
+		if _, ok := fa[p]; !ok {
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+
+	var out []FactChange
+	for _, p := range paths {
+		av, inA := fa[p]
+		bv, inB := fb[p]
+		switch {
+		case !inA:
+			out = append(out, FactChange{Path: p, Kind: "added", New: bv})
+		case !inB:
+			out = append(out, FactChange{Path: p, Kind: "removed", Old: av})
+		case !reflect.DeepEqual(av, bv):
+			out = append(out, FactChange{Path: p, Kind: "changed", Old: av, New: bv})
+		}
+	}
+	return out
+}
+
+// ChangesUnder returns the changes at or below any of the given paths.
+func ChangesUnder(changes []FactChange, paths []string) []FactChange {
+	var out []FactChange
+	for _, c := range changes {
+		for _, p := range paths {
+			if c.Path == p || strings.HasPrefix(c.Path, p+".") {
+				out = append(out, c)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// flattenFacts maps each leaf of f's JSON form to its dotted path, leaving
+// out volatile facts and empty values.
+func flattenFacts(f NodeFacts) map[string]any {
+	data, _ := json.Marshal(f)
+	var v any
+	_ = json.Unmarshal(data, &v)
+	out := make(map[string]any)
+	flattenValue("", v, out)
+	return out
+}
+
+func flattenValue(prefix string, v any, out map[string]any) {
+	join := func(k string) string {
+		if prefix == "" {
+			return k
+		}
+		return prefix + "." + k
+	}
+	switch t := v.(type) {
+	case map[string]any:
+		for k, e := range t {
+			flattenValue(join(k), e, out)
+		}
+		return
+	case []any:
+		if keys, ok := listKeys(t); ok {
+			for i, e := range t {
+				flattenValue(join(keys[i]), e, out)
+			}
+			return
+		}
+		if len(t) == 0 {
+			return
+		}
+	case nil:
+		return
+	}
+	for _, pattern := range volatileFacts {
+		if ok, _ := path.Match(strings.ReplaceAll(pattern, ".", "/"), strings.ReplaceAll(prefix, ".", "/")); ok {
+			return
+		}
+	}
+	out[prefix] = v
+}
+
+// listKeys returns a key for each element of a list of objects that have a
+// name or a page size.
+func listKeys(list []any) ([]string, bool) {
+	if len(list) == 0 {
+		return nil, false
+	}
+	keys := make([]string, len(list))
+	for i, e := range list {
+		m, ok := e.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		switch {
+		case m["name"] != nil:
+			keys[i] = fmt.Sprint(m["name"])
+		case m["size_kb"] != nil:
+			keys[i] = fmt.Sprintf("%vkB", m["size_kb"])
+		default:
+			return nil, false
+		}
+	}
+	return keys, true
+}
+
+// Load returns version id of a node's facts, or the latest version if id is
+// 0.
+func Load(ctx context.Context, st store.Client, nodeID string, id int64) (Version, bool, error) {
+	sv, ok, err := st.GetNodeFacts(ctx, nodeID, id)
+	if err != nil || !ok {
+		return Version{}, false, err
+	}
+	v, err := versionFromStore(sv)
+	return v, err == nil, err
+}
+
+// History returns up to limit versions of a node's facts, newest first.
+func History(ctx context.Context, st store.Client, nodeID string, limit int) ([]Version, error) {
+	list, err := st.ListNodeFacts(ctx, nodeID, limit)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Version, 0, len(list))
+	for _, sv := range list {
+		v, err := versionFromStore(sv)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+// Record stores freshly gathered facts of a node. When they match the
+// latest version, that version is marked as gathered again; otherwise a new
+// version is added with the changes from the latest. changed reports whether
+// a version was added.
+func Record(ctx context.Context, st store.Client, f NodeFacts) (v Version, changed bool, err error) {
+	if f.GatheredAt.IsZero() {
+		f.GatheredAt = time.Now().UTC()
+	}
+	prev, ok, err := Load(ctx, st, f.NodeID, 0)
+	if err != nil {
+		return Version{}, false, err
+	}
+	var changes []FactChange
+	if ok {
+		changes = Diff(prev.Facts, f)
+		if len(changes) == 0 {
+			if err := st.TouchNodeFacts(ctx, prev.ID, f.GatheredAt); err != nil {
+				return Version{}, false, err
+			}
+			prev.LastGatheredAt = f.GatheredAt
+			prev.Facts.GatheredAt = f.GatheredAt
+			return prev, false, nil
+		}
+	}
+
+	data, err := json.Marshal(f)
+	if err != nil {
+		return Version{}, false, err
+	}
+	sv := store.NodeFactsVersion{NodeID: f.NodeID, Facts: data, GatheredAt: f.GatheredAt}
+	if ok {
+		if sv.Changes, err = json.Marshal(changes); err != nil {
+			return Version{}, false, err
+		}
+	}
+	if sv.ID, err = st.AddNodeFacts(ctx, sv); err != nil {
+		return Version{}, false, err
+	}
+	if changes == nil {
+		changes = []FactChange{}
+	}
+	return Version{
+		ID: sv.ID, NodeID: f.NodeID, GatheredAt: f.GatheredAt, LastGatheredAt: f.GatheredAt,
+		Changes: changes, Facts: f,
+	}, true, nil
+}
+
+func versionFromStore(sv store.NodeFactsVersion) (Version, error) {
+	v := Version{
+		ID:             sv.ID,
+		NodeID:         sv.NodeID,
+		GatheredAt:     sv.GatheredAt,
+		LastGatheredAt: sv.LastGatheredAt,
+		Changes:        []FactChange{},
+	}
+	if err := json.Unmarshal(sv.Facts, &v.Facts); err != nil {
+		return Version{}, fmt.Errorf("nodefacts: decode version %d: %w", sv.ID, err)
+	}
+	if sv.Changes != nil {
+		if err := json.Unmarshal(sv.Changes, &v.Changes); err != nil {
+			return Version{}, fmt.Errorf("nodefacts: decode changes of version %d: %w", sv.ID, err)
+		}
+	}
+	v.Facts.GatheredAt = sv.LastGatheredAt
+	return v, nil
+}
@@ -0,0 +1,111 @@
+package nodefacts
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bengrewell/aether-webui/internal/store"
+)
+
+func TestDiff(t *testing.T) {
+	a := NodeFacts{
+		NodeID:       "n1",
+		DefaultIface: "ens18",
+		Interfaces:   []InterfaceInfo{{Name: "ens18", Addresses: []string{"10.0.0.10/24"}, IsUp: true}},
+		Memory:       &MemoryInfo{TotalBytes: 1024, AvailableBytes: 512, Hugepages: []HugepagePool{{SizeKB: 2048, Total: 8, Free: 8}}},
+		GatheredAt:   time.Unix(1, 0),
+	}
+	b := NodeFacts{
+		NodeID:       "n1",
+		DefaultIface: "ens19",
+		Interfaces:   []InterfaceInfo{{Name: "ens19", Addresses: []string{"10.0.0.10/24"}, IsUp: true}},
+		Memory:       &MemoryInfo{TotalBytes: 1024, AvailableBytes: 256, Hugepages: []HugepagePool{{SizeKB: 2048, Total: 16, Free: 4}}},
+		GatheredAt:   time.Unix(2, 0),
+	}
+
+	got := map[string]FactChange{}
+	for _, c := range Diff(a, b) {
+		got[c.Path] = c
+	}
+	want := map[string]string{
+		"default_iface":                 "changed",
+		"interfaces.ens18.name":         "removed",
+		"interfaces.ens18.addresses":    "removed",
+		"interfaces.ens18.is_up":        "removed",
+		"interfaces.ens18.mac":          "removed",
+		"interfaces.ens19.name":         "added",
+		"interfaces.ens19.addresses":    "added",
+		"interfaces.ens19.is_up":        "added",
+		"interfaces.ens19.mac":          "added",
+		"memory.hugepages.2048kB.total": "changed",
+	}
+	for path, kind := range want {
+		if got[path].Kind != kind {
+			t.Errorf("%s: kind = %q, want %q", path, got[path].Kind, kind)
+		}
+	}
+	for path := range got {
+		if _, ok := want[path]; !ok {
+			t.Errorf("unexpected change %+v", got[path])
+		}
+	}
+
+	if changes := Diff(a, a); len(changes) != 0 {
+		t.Errorf("Diff(a, a) = %+v", changes)
+	}
+
+	under := ChangesUnder(Diff(a, b), []string{"default_iface", "memory"})
+	if len(under) != 2 {
+		t.Errorf("ChangesUnder = %+v", under)
+	}
+}
+
+func TestRecord(t *testing.T) {
+	ctx := t.Context()
+	st, err := store.New(ctx, filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("store.New: %v", err)
+	}
+	defer st.Close()
+	if err := st.UpsertNode(ctx, store.Node{ID: "n1", Name: "node1", AnsibleHost: "10.0.0.10"}); err != nil {
+		t.Fatalf("UpsertNode: %v", err)
+	}
+
+	t0 := time.Unix(1_700_000_000, 0)
+	f := NodeFacts{NodeID: "n1", DefaultIface: "ens18", GatheredAt: t0}
+	v1, changed, err := Record(ctx, st, f)
+	if err != nil || !changed || v1.ID == 0 || len(v1.Changes) != 0 {
+		t.Fatalf("first Record = %+v, changed=%v err=%v", v1, changed, err)
+	}
+
+	// The same facts only advance the latest version.
+	f.GatheredAt = t0.Add(time.Hour)
+	v, changed, err := Record(ctx, st, f)
+	if err != nil || changed || v.ID != v1.ID {
+		t.Fatalf("repeat Record = %+v, changed=%v err=%v", v, changed, err)
+	}
+	loaded, ok, err := Load(ctx, st, "n1", 0)
+	if err != nil || !ok || !loaded.LastGatheredAt.Equal(t0.Add(time.Hour)) || !loaded.Facts.GatheredAt.Equal(t0.Add(time.Hour)) {
+		t.Fatalf("Load = %+v, ok=%v err=%v", loaded, ok, err)
+	}
+
+	f.DefaultIface = "ens19"
+	f.GatheredAt = t0.Add(2 * time.Hour)
+	v2, changed, err := Record(ctx, st, f)
+	if err != nil || !changed || v2.ID == v1.ID {
+		t.Fatalf("changed Record = %+v, changed=%v err=%v", v2, changed, err)
+	}
+	if len(v2.Changes) != 1 || v2.Changes[0].Path != "default_iface" || v2.Changes[0].Old != "ens18" || v2.Changes[0].New != "ens19" {
+		t.Errorf("changes = %+v", v2.Changes)
+	}
+
+	history, err := History(ctx, st, "n1", 0)
+	if err != nil || len(history) != 2 || history[0].ID != v2.ID || len(history[0].Changes) != 1 {
+		t.Errorf("History = %+v, %v", history, err)
+	}
+	old, ok, err := Load(ctx, st, "n1", v1.ID)
+	if err != nil || !ok || old.Facts.DefaultIface != "ens18" {
+		t.Errorf("Load(v1) = %+v, ok=%v err=%v", old, ok, err)
+	}
+}
@@ -0,0 +1,307 @@
+package nodefacts
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// gatherHardware runs the hardware and OS probes, filling in the sections of
+// facts they discover. A failed probe leaves its section nil; it does not
+// fail the gather.
+func gatherHardware(ctx context.Context, r Runner, facts *NodeFacts) {
+	if nics, err := discoverNICs(ctx, r); err == nil {
+		for i := range facts.Interfaces {
+			iface := &facts.Interfaces[i]
+			if nic, ok := nics[iface.Name]; ok {
+				iface.Driver = nic.Driver
+				iface.PCIAddress = nic.PCIAddress
+				iface.SpeedMbps = nic.SpeedMbps
+				iface.SRIOVTotalVFs = nic.SRIOVTotalVFs
+				iface.SRIOVNumVFs = nic.SRIOVNumVFs
+			}
+		}
+	}
+	if out, ok := runOutput(ctx, r, "cat /proc/cpuinfo"); ok {
+		facts.CPU = parseCPUInfo(out)
+	}
+	if out, ok := runOutput(ctx, r, "cat /proc/meminfo"); ok {
+		facts.Memory = parseMemInfo(out)
+		if pools, ok := runOutput(ctx, r, hugepagesCommand); ok {
+			facts.Memory.Hugepages = parseHugepages(pools)
+		}
+	}
+	if out, ok := runOutput(ctx, r, "uname -r"); ok {
+		facts.Kernel = &KernelInfo{Release: strings.TrimSpace(out)}
+		if mods, ok := runOutput(ctx, r, modulesCommand()); ok {
+			facts.Kernel.Modules = parseModules(mods)
+		}
+	}
+	if out, ok := runOutput(ctx, r, "cat /etc/os-release"); ok {
+		facts.OS = parseOSRelease(out)
+	}
+	if out, ok := runOutput(ctx, r, "timedatectl show -p NTPSynchronized -p NTP"); ok {
+		facts.TimeSync = parseTimedatectl(out)
+		if svc, ok := runOutput(ctx, r, timeSyncServiceCommand); ok {
+			facts.TimeSync.Service = strings.TrimSpace(svc)
+		}
+	}
+}
+
+// runOutput runs cmd and returns its stdout if it exited 0.
+func runOutput(ctx context.Context, r Runner, cmd string) (string, bool) {
+	stdout, _, exitCode, err := r.Run(ctx, cmd)
+	if err != nil || exitCode != 0 {
+		return "", false
+	}
+	return string(stdout), true
+}
+
+// --- NIC discovery ---
+
+// nicCommand prints one line per interface backed by a device:
+// name|device path|driver|speed|sriov_totalvfs|sriov_numvfs.
+const nicCommand = `for d in /sys/class/net/*; do ` +
+	`[ -e "$d/device" ] || continue; ` +
+	`drv=; [ -e "$d/device/driver" ] && drv=$(basename "$(readlink -f "$d/device/driver")"); ` +
+	`printf '%s|%s|%s|%s|%s|%s\n' "${d##*/}" "$(readlink -f "$d/device")" "$drv" ` +
+	`"$(cat "$d/speed" 2>/dev/null)" "$(cat "$d/device/sriov_totalvfs" 2>/dev/null)" "$(cat "$d/device/sriov_numvfs" 2>/dev/null)"; ` +
+	`done`
+
+// pciAddress matches a PCI address such as 0000:3b:00.0.
+var pciAddress = regexp.MustCompile(`^[0-9a-f]{4}:[0-9a-f]{2}:[0-9a-f]{2}\.[0-7]$`)
+
+func discoverNICs(ctx context.Context, r Runner) (map[string]InterfaceInfo, error) {
+	out, ok := runOutput(ctx, r, nicCommand)
+	if !ok {
+		return nil, fmt.Errorf("failed to read NIC info")
+	}
+	return parseNICs(out), nil
+}
+
+// parseNICs parses nicCommand output into device details by interface
+// name.
+func parseNICs(output string) map[string]InterfaceInfo {
+	result := make(map[string]InterfaceInfo)
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Split(strings.TrimSpace(line), "|")
+		if len(fields) != 6 || fields[0] == "" {
+			continue
+		}
+		info := InterfaceInfo{Driver: fields[2]}
+		// The device path ends at the PCI function, or below it for
+		// virtio and similar buses.
+		for dir := fields[1]; dir != "/" && dir != "."; dir = path.Dir(dir) {
+			if base := path.Base(dir); pciAddress.MatchString(base) {
+				info.PCIAddress = base
+				break
+			}
+		}
+		if speed, err := strconv.Atoi(fields[3]); err == nil && speed > 0 {
+			info.SpeedMbps = speed
+		}
+		info.SRIOVTotalVFs, _ = strconv.Atoi(fields[4])
+		info.SRIOVNumVFs, _ = strconv.Atoi(fields[5])
+		result[fields[0]] = info
+	}
+	return result
+}
+
+// --- CPU discovery ---
+
+// parseCPUInfo parses /proc/cpuinfo. Model and flags come from the first
+// processor; they are the same on every processor of a node.
+func parseCPUInfo(output string) *CPUInfo {
+	info := &CPUInfo{}
+	sockets := map[string]bool{}
+	cores := map[string]bool{}
+	var physID string
+	sc := bufio.NewScanner(strings.NewReader(output))
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for sc.Scan() {
+		key, value, ok := strings.Cut(sc.Text(), ":")
+		if !ok {
+			continue
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		switch key {
+		case "processor":
+			info.Threads++
+		case "model name":
+			if info.Model == "" {
+				info.Model = value
+			}
+		case "flags":
+			if info.Flags == nil {
+				info.Flags = strings.Fields(value)
+			}
+		case "physical id":
+			physID = value
+			sockets[value] = true
+		case "core id":
+			cores[physID+"/"+value] = true
+		}
+	}
+	info.Sockets = max(len(sockets), 1)
+	info.Cores = len(cores)
+	if info.Cores == 0 {
+		// Some virtual machines and non-x86 kernels omit the topology.
+		info.Cores = info.Threads
+	}
+	info.AVX2 = slices.Contains(info.Flags, "avx2")
+	info.AVX512 = slices.Contains(info.Flags, "avx512f")
+	return info
+}
+
+// --- Memory discovery ---
+
+// hugepagesCommand prints "<pool dir>/<file>:<count>" for every hugepage
+// pool's total and free counts.
+const hugepagesCommand = `grep -H . /sys/kernel/mm/hugepages/*/nr_hugepages /sys/kernel/mm/hugepages/*/free_hugepages`
+
+func parseMemInfo(output string) *MemoryInfo {
+	info := &MemoryInfo{Hugepages: []HugepagePool{}}
+	for _, line := range strings.Split(output, "\n") {
+		key, rest, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(rest)
+		if len(fields) == 0 {
+			continue
+		}
+		v, err := strconv.ParseUint(fields[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		switch key {
+		case "MemTotal":
+			info.TotalBytes = v * 1024
+		case "MemAvailable":
+			info.AvailableBytes = v * 1024
+		}
+	}
+	return info
+}
+
+// parseHugepages parses hugepagesCommand output into pools ordered by page
+// size.
+func parseHugepages(output string) []HugepagePool {
+	pools := map[uint64]*HugepagePool{}
+	for _, line := range strings.Split(output, "\n") {
+		file, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok {
+			continue
+		}
+		count, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			continue
+		}
+		// e.g. /sys/kernel/mm/hugepages/hugepages-2048kB/nr_hugepages
+		size, ok := strings.CutPrefix(path.Base(path.Dir(file)), "hugepages-")
+		if !ok {
+			continue
+		}
+		sizeKB, err := strconv.ParseUint(strings.TrimSuffix(size, "kB"), 10, 64)
+		if err != nil {
+			continue
+		}
+		p := pools[sizeKB]
+		if p == nil {
+			p = &HugepagePool{SizeKB: sizeKB}
+			pools[sizeKB] = p
+		}
+		switch path.Base(file) {
+		case "nr_hugepages":
+			p.Total = count
+		case "free_hugepages":
+			p.Free = count
+		}
+	}
+	out := make([]HugepagePool, 0, len(pools))
+	for _, p := range pools {
+		out = append(out, *p)
+	}
+	slices.SortFunc(out, func(a, b HugepagePool) int { return cmp.Compare(a.SizeKB, b.SizeKB) })
+	return out
+}
+
+// --- Kernel discovery ---
+
+// modulesCommand prints "<name> <loaded> <available>" for each of
+// KernelModules. /sys/module has an entry for loaded and most built-in
+// modules; modinfo finds installed ones.
+func modulesCommand() string {
+	return `for m in ` + strings.Join(KernelModules, " ") + `; do l=no; a=no; ` +
+		`[ -d "/sys/module/$m" ] && l=yes; ` +
+		`{ [ $l = yes ] || modinfo -n "$m" >/dev/null 2>&1; } && a=yes; ` +
+		`echo "$m $l $a"; done`
+}
+
+func parseModules(output string) []ModuleInfo {
+	var out []ModuleInfo
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			continue
+		}
+		out = append(out, ModuleInfo{Name: fields[0], Loaded: fields[1] == "yes", Available: fields[2] == "yes"})
+	}
+	return out
+}
+
+// --- OS discovery ---
+
+func parseOSRelease(output string) *OSInfo {
+	info := &OSInfo{}
+	for _, line := range strings.Split(output, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok {
+			continue
+		}
+		if v, err := strconv.Unquote(value); err == nil {
+			value = v
+		} else {
+			value = strings.Trim(value, `'"`)
+		}
+		switch key {
+		case "ID":
+			info.ID = value
+		case "VERSION_ID":
+			info.VersionID = value
+		case "PRETTY_NAME":
+			info.PrettyName = value
+		}
+	}
+	return info
+}
+
+// --- Time sync discovery ---
+
+// timeSyncServiceCommand prints the first active time sync daemon.
+const timeSyncServiceCommand = `for s in chronyd chrony systemd-timesyncd ntpd ntp; do ` +
+	`systemctl is-active --quiet "$s" 2>/dev/null && { echo "$s"; break; }; done; true`
+
+// parseTimedatectl parses `timedatectl show` properties.
+func parseTimedatectl(output string) *TimeSyncInfo {
+	info := &TimeSyncInfo{}
+	for _, line := range strings.Split(output, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "NTPSynchronized":
+			info.Synchronized = value == "yes"
+		case "NTP":
+			info.NTPEnabled = value == "yes"
+		}
+	}
+	return info
+}
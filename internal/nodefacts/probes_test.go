@@ -0,0 +1,158 @@
+package nodefacts
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseCPUInfo(t *testing.T) {
+	output := `processor	: 0
+model name	: Intel(R) Xeon(R) Gold 6230 CPU @ 2.10GHz
+physical id	: 0
+core id		: 0
+flags		: fpu sse4_2 avx avx2 avx512f
+
+processor	: 1
+model name	: Intel(R) Xeon(R) Gold 6230 CPU @ 2.10GHz
+physical id	: 0
+core id		: 0
+flags		: fpu sse4_2 avx avx2 avx512f
+
+processor	: 2
+model name	: Intel(R) Xeon(R) Gold 6230 CPU @ 2.10GHz
+physical id	: 1
+core id		: 0
+flags		: fpu sse4_2 avx avx2 avx512f
+`
+	cpu := parseCPUInfo(output)
+	if cpu.Model != "Intel(R) Xeon(R) Gold 6230 CPU @ 2.10GHz" {
+		t.Errorf("Model = %q", cpu.Model)
+	}
+	if cpu.Threads != 3 || cpu.Cores != 2 || cpu.Sockets != 2 {
+		t.Errorf("threads/cores/sockets = %d/%d/%d, want 3/2/2", cpu.Threads, cpu.Cores, cpu.Sockets)
+	}
+	if !cpu.AVX2 || !cpu.AVX512 {
+		t.Errorf("AVX2 = %v, AVX512 = %v", cpu.AVX2, cpu.AVX512)
+	}
+
+	// Without topology lines every logical CPU counts as a core.
+	cpu = parseCPUInfo("processor : 0\nflags : fpu\nprocessor : 1\nflags : fpu\n")
+	if cpu.Threads != 2 || cpu.Cores != 2 || cpu.Sockets != 1 || cpu.AVX2 {
+		t.Errorf("unexpected cpu: %+v", cpu)
+	}
+}
+
+func TestParseMemory(t *testing.T) {
+	mem := parseMemInfo("MemTotal:       16384 kB\nMemFree:         1024 kB\nMemAvailable:    8192 kB\n")
+	if mem.TotalBytes != 16384*1024 || mem.AvailableBytes != 8192*1024 {
+		t.Errorf("unexpected memory: %+v", mem)
+	}
+
+	pools := parseHugepages(`/sys/kernel/mm/hugepages/hugepages-2048kB/nr_hugepages:512
+/sys/kernel/mm/hugepages/hugepages-1048576kB/nr_hugepages:2
+/sys/kernel/mm/hugepages/hugepages-2048kB/free_hugepages:500
+/sys/kernel/mm/hugepages/hugepages-1048576kB/free_hugepages:2
+`)
+	want := []HugepagePool{{SizeKB: 2048, Total: 512, Free: 500}, {SizeKB: 1048576, Total: 2, Free: 2}}
+	if !slices.Equal(pools, want) {
+		t.Errorf("pools = %+v, want %+v", pools, want)
+	}
+
+	f := NodeFacts{Memory: &MemoryInfo{Hugepages: pools}}
+	if got, want := f.HugepagesTotal(), uint64(512*2048*1024+2*1048576*1024); got != want {
+		t.Errorf("HugepagesTotal = %d, want %d", got, want)
+	}
+	if (NodeFacts{}).HugepagesTotal() != 0 {
+		t.Error("HugepagesTotal without memory facts should be 0")
+	}
+}
+
+func TestParseNICs(t *testing.T) {
+	nics := parseNICs(`ens18|/sys/devices/pci0000:00/0000:00:12.0/virtio2|virtio_net|-1||
+ens1f0|/sys/devices/pci0000:3a/0000:3a:00.0/0000:3b:00.0|ice|25000|128|4
+`)
+	if got := nics["ens18"]; got.PCIAddress != "0000:00:12.0" || got.Driver != "virtio_net" || got.SpeedMbps != 0 || got.SRIOVTotalVFs != 0 {
+		t.Errorf("ens18 = %+v", got)
+	}
+	if got := nics["ens1f0"]; got.PCIAddress != "0000:3b:00.0" || got.Driver != "ice" || got.SpeedMbps != 25000 ||
+		got.SRIOVTotalVFs != 128 || got.SRIOVNumVFs != 4 {
+		t.Errorf("ens1f0 = %+v", got)
+	}
+}
+
+func TestParseKernelOSAndTimeSync(t *testing.T) {
+	mods := parseModules("sctp yes yes\ngtp5g no no\n")
+	want := []ModuleInfo{{Name: "sctp", Loaded: true, Available: true}, {Name: "gtp5g"}}
+	if !slices.Equal(mods, want) {
+		t.Errorf("modules = %+v", mods)
+	}
+	f := NodeFacts{Kernel: &KernelInfo{Modules: mods}}
+	if m, ok := f.Module("sctp"); !ok || !m.Loaded {
+		t.Errorf("Module(sctp) = %+v, %v", m, ok)
+	}
+
+	osInfo := parseOSRelease(`NAME="Ubuntu"
+VERSION_ID="22.04"
+ID=ubuntu
+PRETTY_NAME="Ubuntu 22.04.4 LTS"
+`)
+	if *osInfo != (OSInfo{ID: "ubuntu", VersionID: "22.04", PrettyName: "Ubuntu 22.04.4 LTS"}) {
+		t.Errorf("os = %+v", osInfo)
+	}
+
+	ts := parseTimedatectl("NTP=yes\nNTPSynchronized=no\n")
+	if !ts.NTPEnabled || ts.Synchronized {
+		t.Errorf("time sync = %+v", ts)
+	}
+}
+
+func TestGatherFromRunner_Hardware(t *testing.T) {
+	r := newMockRunner()
+	r.responses[nicCommand] = mockResponse{stdout: []byte("ens18|/sys/devices/pci0000:00/0000:00:12.0/virtio2|virtio_net|1000||\n")}
+	r.responses["cat /proc/cpuinfo"] = mockResponse{stdout: []byte("processor : 0\nmodel name : Test CPU\nflags : avx2\n")}
+	r.responses["cat /proc/meminfo"] = mockResponse{stdout: []byte("MemTotal: 1024 kB\n")}
+	r.responses["uname -r"] = mockResponse{stdout: []byte("6.8.0-45-generic\n")}
+	r.responses[modulesCommand()] = mockResponse{stdout: []byte("sctp yes yes\ngtp5g no no\n")}
+	r.responses["cat /etc/os-release"] = mockResponse{stdout: []byte("ID=ubuntu\nVERSION_ID=\"24.04\"\n")}
+	r.responses["timedatectl show -p NTPSynchronized -p NTP"] = mockResponse{stdout: []byte("NTP=yes\nNTPSynchronized=yes\n")}
+	r.responses[timeSyncServiceCommand] = mockResponse{stdout: []byte("chronyd\n")}
+
+	facts, err := gatherFromRunner(t.Context(), r, "10.0.0.10")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ens, ok := facts.Interface("ens18")
+	if !ok || ens.Driver != "virtio_net" || ens.PCIAddress != "0000:00:12.0" || ens.SpeedMbps != 1000 || ens.MAC != "aa:bb:cc:dd:ee:ff" {
+		t.Errorf("ens18 = %+v", ens)
+	}
+	if facts.CPU == nil || facts.CPU.Model != "Test CPU" || !facts.CPU.AVX2 {
+		t.Errorf("cpu = %+v", facts.CPU)
+	}
+	// The hugepages probe is missing from the mock; memory is still set.
+	if facts.Memory == nil || facts.Memory.TotalBytes != 1024*1024 || len(facts.Memory.Hugepages) != 0 {
+		t.Errorf("memory = %+v", facts.Memory)
+	}
+	if facts.Kernel == nil || facts.Kernel.Release != "6.8.0-45-generic" || len(facts.Kernel.Modules) != 2 {
+		t.Errorf("kernel = %+v", facts.Kernel)
+	}
+	if facts.OS == nil || facts.OS.VersionID != "24.04" {
+		t.Errorf("os = %+v", facts.OS)
+	}
+	if facts.TimeSync == nil || !facts.TimeSync.Synchronized || facts.TimeSync.Service != "chronyd" {
+		t.Errorf("time sync = %+v", facts.TimeSync)
+	}
+}
+
+func TestGatherFromRunner_HardwareProbesFail(t *testing.T) {
+	// The base mock answers only the network probes.
+	facts, err := gatherFromRunner(t.Context(), newMockRunner(), "10.0.0.10")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if facts.CPU != nil || facts.Memory != nil || facts.Kernel != nil || facts.OS != nil || facts.TimeSync != nil {
+		t.Errorf("expected no hardware facts, got %+v", facts)
+	}
+	if facts.Error != "" {
+		t.Errorf("Error = %q, want empty", facts.Error)
+	}
+}
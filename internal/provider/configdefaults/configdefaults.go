@@ -1,6 +1,10 @@
 package configdefaults
 
 import (
+	"context"
+	"sync"
+	"time"
+
 	"github.com/bengrewell/aether-webui/internal/endpoint"
 	"github.com/bengrewell/aether-webui/internal/nodefacts"
 	"github.com/bengrewell/aether-webui/internal/provider"
@@ -11,13 +15,22 @@ var _ provider.Provider = (*Provider)(nil)
 // Provider exposes node fact discovery and config defaults endpoints.
 type Provider struct {
 	*provider.Base
+	config    Config
 	onRampDir string
 	gatherer  nodefacts.Gatherer
+
+	// recordMu serializes storing gathered facts.
+	recordMu sync.Mutex
+
+	cancel   context.CancelFunc
+	done     chan struct{}
+	stopOnce sync.Once
 }
 
 // Config holds settings for the configdefaults provider.
 type Config struct {
-	OnRampDir string // path to aether-onramp on disk
+	OnRampDir     string        // path to aether-onramp on disk
+	FactsInterval time.Duration // how often to re-gather node facts; 0 disables
 }
 
 // NewProvider creates a new configdefaults provider.
@@ -25,6 +38,7 @@ func NewProvider(cfg Config, gatherer nodefacts.Gatherer, opts ...provider.Optio
 	base := provider.New("configdefaults", opts...)
 	p := &Provider{
 		Base:      base,
+		config:    cfg,
 		onRampDir: cfg.OnRampDir,
 		gatherer:  gatherer,
 	}
@@ -33,40 +47,78 @@ func NewProvider(cfg Config, gatherer nodefacts.Gatherer, opts ...provider.Optio
 		Desc: endpoint.Descriptor{
 			OperationID: "get-node-facts",
 			Semantics:   endpoint.Read,
-			Summary:     "Get node network facts",
-			Description: "Returns discovered network facts for a single node. Uses cached facts unless refresh=true.",
+			Summary:     "Get node facts",
+			Description: "Returns discovered facts for a single node. Uses the latest stored facts if they are recent, unless refresh=true. With version, returns that stored version instead.",
 			Tags:        []string{"configdefaults"},
 			HTTP:        endpoint.HTTPHint{Path: "/api/v1/nodes/{id}/facts"},
 		},
 		Handler: p.handleGetNodeFacts,
 	})
 
+	provider.Register(base, endpoint.Endpoint[NodeFactsHistoryInput, NodeFactsHistoryOutput]{
+		Desc: endpoint.Descriptor{
+			OperationID: "get-node-facts-history",
+			Semantics:   endpoint.Read,
+			Summary:     "Get node facts history",
+			Description: "Returns the stored versions of a node's facts, newest first, each with the changes from the version before it.",
+			Tags:        []string{"configdefaults"},
+			HTTP:        endpoint.HTTPHint{Path: "/api/v1/nodes/{id}/facts/history"},
+		},
+		Handler: p.handleNodeFactsHistory,
+	})
+
 	provider.Register(base, endpoint.Endpoint[ConfigDefaultsApplyInput, ConfigDefaultsApplyOutput]{
 		Desc: endpoint.Descriptor{
 			OperationID: "apply-config-defaults",
 			Semantics:   endpoint.Action,
 			Summary:     "Apply config defaults from node facts",
-			Description: "Gathers facts for all registered nodes, computes config defaults based on node roles, and merges them into vars/main.yml.",
+			Description: "Gathers facts for all registered nodes, computes config defaults based on node roles, and merges them into vars/main.yml. With dry_run=true, returns the resulting file and a diff against the current one without writing it.",
 			Tags:        []string{"configdefaults"},
 			HTTP:        endpoint.HTTPHint{Path: "/api/v1/onramp/config/defaults"},
 		},
 		Handler: p.handleApplyConfigDefaults,
 	})
 
+	provider.Register(base, endpoint.Endpoint[ConfigDefaultsListInput, ConfigDefaultsListOutput]{
+		Desc: endpoint.Descriptor{
+			OperationID: "list-config-defaults",
+			Semantics:   endpoint.Read,
+			Summary:     "List applied config defaults",
+			Description: "Returns the config defaults applied to the workspace and flags those whose source node's facts have since changed the computed value.",
+			Tags:        []string{"configdefaults"},
+			HTTP:        endpoint.HTTPHint{Path: "/api/v1/onramp/config/defaults"},
+		},
+		Handler: p.handleListConfigDefaults,
+	})
+
 	return p
 }
 
 // Endpoints returns all registered endpoints.
 func (p *Provider) Endpoints() []endpoint.AnyEndpoint { return nil }
 
-// Start marks the provider as running.
+// Start overrides Base.Start to spawn the periodic facts gatherer when a
+// facts interval is configured.
 func (p *Provider) Start() error {
+	if p.config.FactsInterval > 0 {
+		ctx, cancel := context.WithCancel(context.Background())
+		p.cancel = cancel
+		p.done = make(chan struct{})
+		go p.refresh(ctx)
+	}
 	p.SetRunning(true)
 	return nil
 }
 
-// Stop marks the provider as no longer running.
+// Stop overrides Base.Stop to cancel the facts gatherer and wait for it to
+// exit. Safe to call multiple times; only the first call performs cleanup.
 func (p *Provider) Stop() error {
-	p.SetRunning(false)
+	p.stopOnce.Do(func() {
+		if p.cancel != nil {
+			p.cancel()
+			<-p.done
+		}
+		p.SetRunning(false)
+	})
 	return nil
 }
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -13,6 +14,7 @@ import (
 	"github.com/bengrewell/aether-webui/internal/nodefacts"
 	"github.com/bengrewell/aether-webui/internal/provider"
 	"github.com/bengrewell/aether-webui/internal/provider/onramp"
+	internalssh "github.com/bengrewell/aether-webui/internal/ssh"
 	"github.com/bengrewell/aether-webui/internal/store"
 )
 
@@ -21,7 +23,8 @@ type mockGatherer struct {
 	facts map[string]nodefacts.NodeFacts // keyed by host
 }
 
-func (m *mockGatherer) Gather(_ context.Context, host, user string, password string, sshKey []byte) (nodefacts.NodeFacts, error) {
+func (m *mockGatherer) Gather(_ context.Context, cfg internalssh.Config) (nodefacts.NodeFacts, error) {
+	host := nodefacts.HostOf(cfg)
 	if f, ok := m.facts[host]; ok {
 		return f, nil
 	}
@@ -177,13 +180,104 @@ func TestHandleApplyConfigDefaults(t *testing.T) {
 	}
 
 	// Verify vars/main.yml was updated on disk.
-	diskCfg, err := p.readVarsFile()
+	diskCfg, err := readVarsFile(p.onRampDir)
 	if err != nil {
 		t.Fatalf("readVarsFile: %v", err)
 	}
 	if diskCfg.Core == nil || diskCfg.Core.DataIface != "ens18" {
 		t.Errorf("disk config core.data_iface = %v, want ens18", diskCfg.Core)
 	}
+
+	// The write is recorded in the config history.
+	latest, ok, err := p.Store().LatestConfigRevision(t.Context(), "")
+	if err != nil || !ok || latest.Source != "configdefaults" {
+		t.Errorf("latest config revision = %+v, %v, %v", latest, ok, err)
+	}
+}
+
+func TestHandleApplyConfigDefaultsDryRun(t *testing.T) {
+	g := &mockGatherer{
+		facts: map[string]nodefacts.NodeFacts{
+			"10.0.0.10": {
+				DefaultIface:  "ens18",
+				DefaultIP:     "10.0.0.10",
+				DefaultSubnet: "10.0.0.0/24",
+				GatheredAt:    time.Now().UTC(),
+			},
+		},
+	}
+
+	p, st := newTestProvider(t, g)
+	ctx := t.Context()
+
+	node := store.Node{
+		ID:          "node-1",
+		Name:        "node1",
+		AnsibleHost: "10.0.0.10",
+		AnsibleUser: "ubuntu",
+		Roles:       []string{"master"},
+		CreatedAt:   time.Now().UTC(),
+		UpdatedAt:   time.Now().UTC(),
+	}
+	if err := st.UpsertNode(ctx, node); err != nil {
+		t.Fatalf("UpsertNode: %v", err)
+	}
+
+	mainPath := filepath.Join(p.onRampDir, "vars", "main.yml")
+	before, err := os.ReadFile(mainPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	in := &ConfigDefaultsApplyInput{Refresh: true}
+	in.DryRun = true
+	out, err := p.handleApplyConfigDefaults(ctx, in)
+	if err != nil {
+		t.Fatalf("handleApplyConfigDefaults: %v", err)
+	}
+
+	after, err := os.ReadFile(mainPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(after) != string(before) {
+		t.Errorf("vars/main.yml changed by dry run:\n%s", after)
+	}
+	if _, ok, _ := st.LatestConfigRevision(ctx, ""); ok {
+		t.Error("dry run recorded a config revision")
+	}
+
+	result := out.Body
+	if len(result.Applied) == 0 || !result.DryRun || result.Preview == nil {
+		t.Fatalf("result = %+v", result)
+	}
+	if result.Config.Core == nil || result.Config.Core.DataIface != "ens18" {
+		t.Errorf("config core.data_iface = %v, want ens18", result.Config.Core)
+	}
+
+	changes := make(map[string]onramp.ConfigChange)
+	for _, c := range result.Preview.Changes {
+		changes[c.Path] = c
+	}
+	if c := changes["core.data_iface"]; c.Kind != "changed" || c.Old != "old_iface" || c.New != "ens18" {
+		t.Errorf("core.data_iface change = %+v", c)
+	}
+	if c := changes["core.amf"]; c.Kind != "added" {
+		t.Errorf("core.amf change = %+v", c)
+	}
+
+	// Applying for real writes exactly the previewed content.
+	in.DryRun = false
+	if _, err := p.handleApplyConfigDefaults(ctx, in); err != nil {
+		t.Fatalf("handleApplyConfigDefaults: %v", err)
+	}
+	after, err = os.ReadFile(mainPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(after) != result.Preview.Content {
+		t.Errorf("written config differs from preview:\n%s\nwant:\n%s", after, result.Preview.Content)
+	}
 }
 
 func TestHandleApplyConfigDefaultsNoNodes(t *testing.T) {
@@ -345,18 +439,18 @@ type countingGatherer struct {
 	count *int
 }
 
-func (g *countingGatherer) Gather(ctx context.Context, host, user string, password string, sshKey []byte) (nodefacts.NodeFacts, error) {
+func (g *countingGatherer) Gather(ctx context.Context, cfg internalssh.Config) (nodefacts.NodeFacts, error) {
 	*g.count++
-	return g.inner.Gather(ctx, host, user, password, sshKey)
+	return g.inner.Gather(ctx, cfg)
 }
 
-func TestDeepMergeConfig(t *testing.T) {
-	base := onramp.OnRampConfig{
-		Core: &onramp.CoreConfig{
-			DataIface: "old_iface",
-			AMF:       &onramp.AMFConfig{IP: "1.2.3.4"},
-		},
-	}
+func TestMergeDefaultsKeepsUnmodeledKeys(t *testing.T) {
+	base := []byte(`core:
+  data_iface: old_iface # replaced by defaults
+  amf:
+    ip: "1.2.3.4"
+  log_level: debug
+`)
 
 	patch := map[string]any{
 		"core": map[string]any{
@@ -366,9 +460,9 @@ func TestDeepMergeConfig(t *testing.T) {
 	}
 	patchJSON, _ := json.Marshal(patch)
 
-	merged, err := deepMergeConfig(&base, patchJSON)
+	data, merged, err := onramp.MergeVars(base, patchJSON)
 	if err != nil {
-		t.Fatalf("deepMergeConfig: %v", err)
+		t.Fatalf("MergeVars: %v", err)
 	}
 	if merged.Core.DataIface != "ens18" {
 		t.Errorf("DataIface = %q, want %q", merged.Core.DataIface, "ens18")
@@ -376,6 +470,15 @@ func TestDeepMergeConfig(t *testing.T) {
 	if merged.Core.AMF == nil || merged.Core.AMF.IP != "10.0.0.10" {
 		t.Errorf("AMF.IP = %v, want 10.0.0.10", merged.Core.AMF)
 	}
+	want := `core:
+  data_iface: ens18 # replaced by defaults
+  amf:
+    ip: "10.0.0.10"
+  log_level: debug
+`
+	if string(data) != want {
+		t.Errorf("merged file =\n%s\nwant\n%s", data, want)
+	}
 }
 
 func TestMultiNodeMultiRole(t *testing.T) {
@@ -510,3 +613,188 @@ func TestOAIGNBIPRule(t *testing.T) {
 		t.Errorf("oai.servers.0.gnb_ip = %v, want 10.0.0.40", v)
 	}
 }
+
+func TestUPFModeRule(t *testing.T) {
+	var rule Rule
+	for _, r := range defaultRules {
+		if r.Field == "core.upf.mode" {
+			rule = r
+		}
+	}
+	if rule.ComputeFn == nil {
+		t.Fatal("no core.upf.mode rule")
+	}
+
+	ready := func() nodefacts.NodeFacts {
+		return nodefacts.NodeFacts{
+			NodeName:     "node1",
+			DefaultIface: "ens18",
+			Interfaces:   []nodefacts.InterfaceInfo{{Name: "ens18", SRIOVTotalVFs: 8, SRIOVNumVFs: 2}},
+			CPU:          &nodefacts.CPUInfo{AVX2: true},
+			Memory:       &nodefacts.MemoryInfo{Hugepages: []nodefacts.HugepagePool{{SizeKB: 1048576, Total: 2}}},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		mutate  func(*nodefacts.NodeFacts)
+		want    any
+		explain string
+	}{
+		{"dpdk ready keeps mode", func(*nodefacts.NodeFacts) {}, nil, ""},
+		{"facts without hardware", func(f *nodefacts.NodeFacts) { f.CPU = nil }, nil, ""},
+		{"no hugepages", func(f *nodefacts.NodeFacts) { f.Memory.Hugepages = nil }, "af_packet", "no hugepages reserved"},
+		{"no sriov", func(f *nodefacts.NodeFacts) { f.Interfaces[0].SRIOVTotalVFs = 0 }, "af_packet", "ens18 does not support SR-IOV"},
+		{"no vfs", func(f *nodefacts.NodeFacts) { f.Interfaces[0].SRIOVNumVFs = 0 }, "af_packet", "no SR-IOV VFs created on ens18"},
+		{"no avx2", func(f *nodefacts.NodeFacts) { f.CPU.AVX2 = false }, "af_packet", "no AVX2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := ready()
+			tt.mutate(&f)
+			val, explanation := rule.ComputeFn(f)
+			if val != tt.want {
+				t.Fatalf("value = %v, want %v", val, tt.want)
+			}
+			if !strings.Contains(explanation, tt.explain) {
+				t.Errorf("explanation = %q, want it to mention %q", explanation, tt.explain)
+			}
+		})
+	}
+}
+
+func TestNodeFactsHistoryAndStaleDefaults(t *testing.T) {
+	g := &mockGatherer{
+		facts: map[string]nodefacts.NodeFacts{
+			"10.0.0.10": {
+				DefaultIface:  "ens18",
+				DefaultIP:     "10.0.0.10",
+				DefaultSubnet: "10.0.0.0/24",
+				GatheredAt:    time.Now().UTC(),
+			},
+		},
+	}
+
+	p, st := newTestProvider(t, g)
+	ctx := t.Context()
+
+	node := store.Node{
+		ID:          "node-1",
+		Name:        "node1",
+		AnsibleHost: "10.0.0.10",
+		AnsibleUser: "ubuntu",
+		Password:    []byte("pass"),
+		Roles:       []string{"master"},
+		UpdatedAt:   time.Now().Add(-time.Minute),
+	}
+	if err := st.UpsertNode(ctx, node); err != nil {
+		t.Fatalf("UpsertNode: %v", err)
+	}
+
+	if _, err := p.handleApplyConfigDefaults(ctx, &ConfigDefaultsApplyInput{Refresh: true}); err != nil {
+		t.Fatalf("handleApplyConfigDefaults: %v", err)
+	}
+	list, err := p.handleListConfigDefaults(ctx, &ConfigDefaultsListInput{})
+	if err != nil {
+		t.Fatalf("handleListConfigDefaults: %v", err)
+	}
+	if len(list.Body) == 0 {
+		t.Fatal("expected recorded defaults")
+	}
+	for _, s := range list.Body {
+		if s.Stale || s.SourceNode != "node1" || s.FactsVersion == 0 {
+			t.Errorf("fresh default = %+v", s)
+		}
+	}
+
+	// The data interface is renamed on the node.
+	f := g.facts["10.0.0.10"]
+	f.DefaultIface = "ens19"
+	g.facts["10.0.0.10"] = f
+
+	list, err = p.handleListConfigDefaults(ctx, &ConfigDefaultsListInput{Refresh: true})
+	if err != nil {
+		t.Fatalf("handleListConfigDefaults(refresh): %v", err)
+	}
+	byField := make(map[string]ConfigDefaultStatus)
+	for _, s := range list.Body {
+		byField[s.Field] = s
+	}
+	iface := byField["core.data_iface"]
+	if !iface.Stale || iface.Value != "ens18" || iface.CurrentValue != "ens19" {
+		t.Errorf("core.data_iface = %+v", iface)
+	}
+	if len(iface.FactChanges) != 1 || iface.FactChanges[0].Path != "default_iface" {
+		t.Errorf("fact changes = %+v", iface.FactChanges)
+	}
+	// The address did not change, so the AMF default is not stale even
+	// though a fact it reads did.
+	if amf := byField["core.amf.ip"]; amf.Stale || len(amf.FactChanges) != 1 {
+		t.Errorf("core.amf.ip = %+v", amf)
+	}
+
+	history, err := p.handleNodeFactsHistory(ctx, &NodeFactsHistoryInput{ID: "node-1", Limit: 20})
+	if err != nil {
+		t.Fatalf("handleNodeFactsHistory: %v", err)
+	}
+	if len(history.Body) != 2 || len(history.Body[0].Changes) != 1 {
+		t.Fatalf("history = %+v", history.Body)
+	}
+
+	// An older version can be fetched by id.
+	old, err := p.handleGetNodeFacts(ctx, &NodeFactsGetInput{ID: "node-1", Version: history.Body[1].ID})
+	if err != nil {
+		t.Fatalf("handleGetNodeFacts(version): %v", err)
+	}
+	if old.Body.DefaultIface != "ens18" {
+		t.Errorf("old DefaultIface = %q, want ens18", old.Body.DefaultIface)
+	}
+	if _, err := p.handleGetNodeFacts(ctx, &NodeFactsGetInput{ID: "node-1", Version: 999}); err == nil {
+		t.Error("expected error for missing version")
+	}
+
+	// Re-applying records the new value.
+	if _, err := p.handleApplyConfigDefaults(ctx, &ConfigDefaultsApplyInput{}); err != nil {
+		t.Fatalf("handleApplyConfigDefaults: %v", err)
+	}
+	list, err = p.handleListConfigDefaults(ctx, &ConfigDefaultsListInput{})
+	if err != nil {
+		t.Fatalf("handleListConfigDefaults: %v", err)
+	}
+	for _, s := range list.Body {
+		if s.Stale {
+			t.Errorf("default still stale after apply: %+v", s)
+		}
+	}
+}
+
+func TestRefreshAll(t *testing.T) {
+	callCount := 0
+	g := &countingGatherer{inner: &mockGatherer{}, count: &callCount}
+	p, st := newTestProvider(t, g)
+	ctx := t.Context()
+
+	for _, n := range []store.Node{
+		{ID: "n1", Name: "node1", AnsibleHost: "10.0.0.1"},
+		{ID: "n2", Workspace: "lab2", Name: "node2", AnsibleHost: "10.0.0.2"},
+	} {
+		if n.Workspace != "" {
+			if err := st.CreateWorkspace(ctx, store.Workspace{Name: n.Workspace, OnRampDir: t.TempDir()}); err != nil {
+				t.Fatalf("CreateWorkspace: %v", err)
+			}
+		}
+		if err := st.UpsertNode(ctx, n); err != nil {
+			t.Fatalf("UpsertNode: %v", err)
+		}
+	}
+
+	p.refreshAll(ctx)
+	if callCount != 2 {
+		t.Errorf("gather calls = %d, want 2", callCount)
+	}
+	for _, id := range []string{"n1", "n2"} {
+		if _, ok, err := st.GetNodeFacts(ctx, id, 0); err != nil || !ok {
+			t.Errorf("no facts stored for %s: %v", id, err)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package configdefaults
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -14,13 +15,12 @@ import (
 
 	"github.com/bengrewell/aether-webui/internal/nodefacts"
 	"github.com/bengrewell/aether-webui/internal/provider/onramp"
+	internalssh "github.com/bengrewell/aether-webui/internal/ssh"
 	"github.com/bengrewell/aether-webui/internal/store"
 )
 
-const (
-	factsNamespace = "_nodefacts"
-	factsTTL       = 5 * time.Minute
-)
+// factsTTL is how long gathered facts are reused before gathering again.
+const factsTTL = 5 * time.Minute
 
 // handleGetNodeFacts returns discovered network facts for a single node.
 func (p *Provider) handleGetNodeFacts(ctx context.Context, in *NodeFactsGetInput) (*NodeFactsGetOutput, error) {
@@ -34,22 +34,68 @@ func (p *Provider) handleGetNodeFacts(ctx context.Context, in *NodeFactsGetInput
 		return nil, huma.Error404NotFound("node not found", fmt.Errorf("no node with id %s", in.ID))
 	}
 
-	facts, err := p.getOrGatherFacts(ctx, st, node, in.Refresh)
+	if in.Version != 0 {
+		v, ok, err := nodefacts.Load(ctx, st, node.ID, in.Version)
+		if err != nil {
+			return nil, huma.Error500InternalServerError("failed to load facts", err)
+		}
+		if !ok {
+			return nil, huma.Error404NotFound("facts version not found", fmt.Errorf("node %s has no facts version %d", node.ID, in.Version))
+		}
+		return &NodeFactsGetOutput{Body: v.Facts}, nil
+	}
+
+	v, err := p.getOrGatherFacts(ctx, st, node, in.Refresh)
 	if err != nil {
 		return nil, huma.Error500InternalServerError("failed to gather facts", err)
 	}
 
-	return &NodeFactsGetOutput{Body: facts}, nil
+	return &NodeFactsGetOutput{Body: v.Facts}, nil
+}
+
+// handleNodeFactsHistory returns the stored versions of a node's facts with
+// the changes between them.
+func (p *Provider) handleNodeFactsHistory(ctx context.Context, in *NodeFactsHistoryInput) (*NodeFactsHistoryOutput, error) {
+	st := p.Store()
+
+	node, ok, err := st.GetNode(ctx, in.ID)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("failed to get node", err)
+	}
+	if !ok {
+		return nil, huma.Error404NotFound("node not found", fmt.Errorf("no node with id %s", in.ID))
+	}
+
+	versions, err := nodefacts.History(ctx, st, node.ID, in.Limit)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("failed to list facts history", err)
+	}
+	return &NodeFactsHistoryOutput{Body: versions}, nil
 }
 
 // handleApplyConfigDefaults gathers facts for all nodes, computes defaults,
-// and merges them into vars/main.yml.
+// and merges them into vars/main.yml. A dry run computes the same result and
+// previews the file instead of writing it.
 func (p *Provider) handleApplyConfigDefaults(ctx context.Context, in *ConfigDefaultsApplyInput) (*ConfigDefaultsApplyOutput, error) {
+	ws, err := p.ResolveWorkspace(ctx, in.Workspace)
+	if err != nil {
+		return nil, err
+	}
+	if !in.DryRun {
+		if err := p.CheckChangeLock(ctx, ws.Name); err != nil {
+			return nil, err
+		}
+	}
+	dir := p.onRampDir
+	if ws.Name != store.DefaultWorkspace {
+		dir = ws.OnRampDir
+	}
+
 	st := p.Store()
 	log := p.Log()
 
-	// List all nodes.
-	nodeInfos, err := st.ListNodes(ctx)
+	// List the workspace's nodes.
+	nodeInfos, err := st.ListNodes(ctx, ws.Name)
 	if err != nil {
 		return nil, huma.Error500InternalServerError("failed to list nodes", err)
 	}
@@ -60,16 +106,22 @@ func (p *Provider) handleApplyConfigDefaults(ctx context.Context, in *ConfigDefa
 			Errors:  []string{"no nodes registered"},
 		}
 		// Return current config even with no nodes.
-		cfg, readErr := p.readVarsFile()
+		cfg, readErr := readVarsFile(dir)
 		if readErr == nil {
 			result.Config = cfg
 		}
+		if in.DryRun {
+			raw, _ := os.ReadFile(filepath.Join(dir, "vars", "main.yml"))
+			preview := onramp.PreviewVars(raw, raw)
+			result.DryRun = true
+			result.Preview = &preview
+		}
 		return &ConfigDefaultsApplyOutput{Body: result}, nil
 	}
 
 	// Gather facts for each node.
 	var (
-		factsMap = make(map[string]nodefacts.NodeFacts)
+		factsMap = make(map[string]nodefacts.Version)
 		errs     []string
 	)
 	for _, ni := range nodeInfos {
@@ -80,23 +132,21 @@ func (p *Provider) handleApplyConfigDefaults(ctx context.Context, in *ConfigDefa
 			continue
 		}
 
-		facts, err := p.getOrGatherFacts(ctx, st, node, in.Refresh)
+		v, err := p.getOrGatherFacts(ctx, st, node, in.Refresh)
 		if err != nil {
 			errs = append(errs, fmt.Sprintf("node %s: %v", ni.Name, err))
 			continue
 		}
-		if facts.Error != "" {
-			errs = append(errs, fmt.Sprintf("node %s: %s", ni.Name, facts.Error))
+		if v.Facts.Error != "" {
+			errs = append(errs, fmt.Sprintf("node %s: %s", ni.Name, v.Facts.Error))
 		}
-		facts.NodeID = node.ID
-		facts.NodeName = node.Name
-		factsMap[node.ID] = facts
+		factsMap[node.ID] = v
 	}
 
 	// Evaluate rules against node facts.
 	var applied []AppliedDefault
 	for _, ni := range nodeInfos {
-		facts, ok := factsMap[ni.ID]
+		v, ok := factsMap[ni.ID]
 		if !ok {
 			continue
 		}
@@ -104,7 +154,7 @@ func (p *Provider) handleApplyConfigDefaults(ctx context.Context, in *ConfigDefa
 			if !matchesRole(ni.Roles, rule.Roles) {
 				continue
 			}
-			val, explanation := rule.ComputeFn(facts)
+			val, explanation := rule.ComputeFn(v.Facts)
 			if val == nil {
 				continue
 			}
@@ -113,17 +163,24 @@ func (p *Provider) handleApplyConfigDefaults(ctx context.Context, in *ConfigDefa
 				Value:       val,
 				Explanation: explanation,
 				SourceNode:  ni.Name,
+				nodeID:      ni.ID,
+				factsID:     v.ID,
 			})
 		}
 	}
 
 	// Read current config, apply defaults, write back.
-	mainYML := filepath.Join(p.onRampDir, "vars", "main.yml")
-	cfg, err := p.readVarsFile()
+	defer onramp.LockVars(dir)()
+	cfg, err := readVarsFile(dir)
 	if err != nil {
 		log.Error("failed to read vars/main.yml", "error", err)
 		return nil, huma.Error500InternalServerError("failed to read config", err)
 	}
+	raw, err := os.ReadFile(filepath.Join(dir, "vars", "main.yml"))
+	if err != nil {
+		return nil, huma.Error500InternalServerError("failed to read config", err)
+	}
+	data := raw
 
 	if len(applied) > 0 {
 		patchMap := buildPatchMap(applied)
@@ -132,51 +189,214 @@ func (p *Provider) handleApplyConfigDefaults(ctx context.Context, in *ConfigDefa
 			return nil, huma.Error500InternalServerError("failed to build defaults patch", err)
 		}
 
-		cfg, err = deepMergeConfig(&cfg, patchJSON)
+		// Merge into the file as written so comments and keys the typed
+		// config does not model are kept.
+		data, cfg, err = onramp.MergeVars(raw, patchJSON)
 		if err != nil {
 			return nil, huma.Error500InternalServerError("failed to merge defaults", err)
 		}
-
-		if err := writeVarsFile(mainYML, &cfg); err != nil {
-			return nil, huma.Error500InternalServerError("failed to write config", err)
-		}
 	}
 
-	return &ConfigDefaultsApplyOutput{Body: ConfigDefaultsResult{
+	result := ConfigDefaultsResult{
 		Applied: applied,
 		Errors:  errs,
 		Config:  cfg,
-	}}, nil
+	}
+	if in.DryRun {
+		preview := onramp.PreviewVars(raw, data)
+		result.DryRun = true
+		result.Preview = &preview
+		return &ConfigDefaultsApplyOutput{Body: result}, nil
+	}
+
+	if len(applied) > 0 {
+		if _, err := onramp.SaveVars(ctx, st, onramp.VarsChange{
+			Workspace: ws.Name,
+			Dir:       dir,
+			Source:    "configdefaults",
+			Note:      fmt.Sprintf("%d default(s) applied", len(applied)),
+			Author:    in.Author,
+		}, data); err != nil {
+			return nil, huma.Error500InternalServerError("failed to write config", err)
+		}
+		p.recordApplied(ctx, ws.Name, applied)
+	}
+
+	return &ConfigDefaultsApplyOutput{Body: result}, nil
 }
 
-// getOrGatherFacts returns cached facts if valid, or gathers new ones via SSH.
-func (p *Provider) getOrGatherFacts(ctx context.Context, st store.Client, node store.Node, forceRefresh bool) (nodefacts.NodeFacts, error) {
-	key := store.Key{Namespace: factsNamespace, ID: node.ID}
+// recordApplied remembers which facts version each applied default came
+// from, so it can be flagged once the facts change. Defaults computed from
+// facts that were not stored are skipped.
+func (p *Provider) recordApplied(ctx context.Context, workspace string, applied []AppliedDefault) {
+	now := time.Now()
+	for _, a := range applied {
+		if a.factsID == 0 {
+			continue
+		}
+		value, err := json.Marshal(a.Value)
+		if err == nil {
+			err = p.Store().UpsertConfigDefault(ctx, store.ConfigDefault{
+				Workspace: workspace, Field: a.Field, Value: value,
+				NodeID: a.nodeID, FactsID: a.factsID, AppliedAt: now,
+			})
+		}
+		if err != nil {
+			p.Log().Warn("failed to record applied config default", "field", a.Field, "error", err)
+		}
+	}
+}
 
-	if !forceRefresh {
-		item, ok, err := store.Load[nodefacts.NodeFacts](st, ctx, key)
-		if err == nil && ok {
-			// Check if cache is still valid: not expired and node hasn't been updated since.
-			if item.Data.GatheredAt.After(node.UpdatedAt) {
-				return item.Data, nil
+// handleListConfigDefaults returns the workspace's applied defaults and
+// flags those whose source node's facts now give a different value.
+func (p *Provider) handleListConfigDefaults(ctx context.Context, in *ConfigDefaultsListInput) (*ConfigDefaultsListOutput, error) {
+	ws, err := p.ResolveWorkspace(ctx, in.Workspace)
+	if err != nil {
+		return nil, err
+	}
+	if in.Refresh {
+		infos, err := p.Store().ListNodes(ctx, ws.Name)
+		if err != nil {
+			return nil, huma.Error500InternalServerError("failed to list nodes", err)
+		}
+		for _, ni := range infos {
+			if node, ok, err := p.Store().GetNode(ctx, ni.ID); err == nil && ok {
+				if _, err := p.gatherFacts(ctx, p.Store(), node); err != nil {
+					p.Log().Warn("failed to gather node facts", "node", node.Name, "error", err)
+				}
 			}
 		}
 	}
+	statuses, err := p.defaultStatuses(ctx, ws.Name)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("failed to check config defaults", err)
+	}
+	return &ConfigDefaultsListOutput{Body: statuses}, nil
+}
 
-	// Gather fresh facts via SSH.
-	facts, err := p.gatherer.Gather(ctx, node.AnsibleHost, node.AnsibleUser, string(node.Password), node.SSHKey)
+// defaultStatuses checks each recorded default of a workspace against the
+// latest stored facts of its source node.
+func (p *Provider) defaultStatuses(ctx context.Context, workspace string) ([]ConfigDefaultStatus, error) {
+	st := p.Store()
+	records, err := st.ListConfigDefaults(ctx, workspace)
 	if err != nil {
-		return nodefacts.NodeFacts{}, err
+		return nil, err
+	}
+	out := make([]ConfigDefaultStatus, 0, len(records))
+	for _, r := range records {
+		node, ok, err := st.GetNode(ctx, r.NodeID)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		s := ConfigDefaultStatus{
+			Field:        r.Field,
+			SourceNode:   node.Name,
+			FactsVersion: r.FactsID,
+			AppliedAt:    r.AppliedAt,
+			FactChanges:  []nodefacts.FactChange{},
+		}
+		if err := json.Unmarshal(r.Value, &s.Value); err != nil {
+			return nil, fmt.Errorf("decode applied value of %s: %w", r.Field, err)
+		}
+
+		latest, ok, err := nodefacts.Load(ctx, st, r.NodeID, 0)
+		if err != nil {
+			return nil, err
+		}
+		if !ok || latest.ID == r.FactsID {
+			out = append(out, s)
+			continue
+		}
+		rule, found := ruleFor(r.Field)
+		if !found {
+			out = append(out, s)
+			continue
+		}
+
+		// Versions are few, so loading the history is cheap.
+		history, err := nodefacts.History(ctx, st, r.NodeID, 0)
+		if err != nil {
+			return nil, err
+		}
+		for i := len(history) - 1; i >= 0; i-- {
+			if history[i].ID > r.FactsID {
+				s.FactChanges = append(s.FactChanges, nodefacts.ChangesUnder(history[i].Changes, rule.Facts)...)
+			}
+		}
+
+		latest.Facts.NodeName = node.Name
+		current, _ := rule.ComputeFn(latest.Facts)
+		if current == nil {
+			out = append(out, s)
+			continue
+		}
+		if cur, err := json.Marshal(current); err == nil && !bytes.Equal(cur, r.Value) {
+			s.Stale = true
+			s.CurrentValue = current
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+// ruleFor returns the rule that sets field.
+func ruleFor(field string) (Rule, bool) {
+	for _, r := range defaultRules {
+		if r.Field == field {
+			return r, true
+		}
+	}
+	return Rule{}, false
+}
+
+// getOrGatherFacts returns the node's latest stored facts if they are recent
+// and newer than the node's last update, or gathers new ones via SSH.
+func (p *Provider) getOrGatherFacts(ctx context.Context, st store.Client, node store.Node, forceRefresh bool) (nodefacts.Version, error) {
+	if !forceRefresh {
+		v, ok, err := nodefacts.Load(ctx, st, node.ID, 0)
+		if err == nil && ok && v.LastGatheredAt.After(node.UpdatedAt) && time.Since(v.LastGatheredAt) < factsTTL {
+			v.Facts.NodeName = node.Name
+			return v, nil
+		}
+	}
+	return p.gatherFacts(ctx, st, node)
+}
+
+// gatherFacts gathers a node's facts via SSH and records them as a new
+// version if they changed. Facts whose gather failed are returned but not
+// stored, so a node that is briefly down does not lose its history.
+func (p *Provider) gatherFacts(ctx context.Context, st store.Client, node store.Node) (nodefacts.Version, error) {
+	cfg, err := internalssh.NodeConfig(ctx, st, node, 0)
+	if err != nil {
+		return nodefacts.Version{}, err
+	}
+	facts, err := p.gatherer.Gather(ctx, cfg)
+	if err != nil {
+		return nodefacts.Version{}, err
 	}
 	facts.NodeID = node.ID
 	facts.NodeName = node.Name
-
-	// Cache the result.
-	if _, err := store.Save(st, ctx, key, facts, store.WithTTL(factsTTL)); err != nil {
-		p.Log().Warn("failed to cache node facts", "node_id", node.ID, "error", err)
+	if facts.Error != "" {
+		return nodefacts.Version{NodeID: node.ID, Facts: facts, Changes: []nodefacts.FactChange{}}, nil
 	}
 
-	return facts, nil
+	// Serialize recording so concurrent gathers of a node diff against the
+	// version the other one stored.
+	p.recordMu.Lock()
+	v, changed, err := nodefacts.Record(ctx, st, facts)
+	p.recordMu.Unlock()
+	if err != nil {
+		p.Log().Warn("failed to store node facts", "node_id", node.ID, "error", err)
+		return nodefacts.Version{NodeID: node.ID, Facts: facts, Changes: []nodefacts.FactChange{}}, nil
+	}
+	if changed && len(v.Changes) > 0 {
+		p.Log().Info("node facts changed", "node", node.Name, "workspace", node.Workspace, "version", v.ID, "changes", len(v.Changes))
+	}
+	v.Facts.NodeName = node.Name
+	return v, nil
 }
 
 // buildPatchMap converts applied defaults into a nested map suitable for
@@ -201,10 +421,10 @@ func buildPatchMap(applied []AppliedDefault) map[string]any {
 	return root
 }
 
-// --- YAML/config helpers (duplicated from onramp to avoid import cycle) ---
+// --- YAML/config helpers ---
 
-func (p *Provider) readVarsFile() (onramp.OnRampConfig, error) {
-	path := filepath.Join(p.onRampDir, "vars", "main.yml")
+func readVarsFile(dir string) (onramp.OnRampConfig, error) {
+	path := filepath.Join(dir, "vars", "main.yml")
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return onramp.OnRampConfig{}, err
@@ -215,56 +435,3 @@ func (p *Provider) readVarsFile() (onramp.OnRampConfig, error) {
 	}
 	return cfg, nil
 }
-
-func writeVarsFile(path string, cfg *onramp.OnRampConfig) error {
-	data, err := yaml.Marshal(cfg)
-	if err != nil {
-		return fmt.Errorf("marshal config: %w", err)
-	}
-	return os.WriteFile(path, data, 0o644)
-}
-
-func deepMergeConfig(base *onramp.OnRampConfig, patchJSON []byte) (onramp.OnRampConfig, error) {
-	baseData, err := json.Marshal(base)
-	if err != nil {
-		return onramp.OnRampConfig{}, fmt.Errorf("marshal base: %w", err)
-	}
-	var baseMap map[string]any
-	if err := json.Unmarshal(baseData, &baseMap); err != nil {
-		return onramp.OnRampConfig{}, fmt.Errorf("unmarshal base: %w", err)
-	}
-
-	var patchMap map[string]any
-	if err := json.Unmarshal(patchJSON, &patchMap); err != nil {
-		return onramp.OnRampConfig{}, fmt.Errorf("unmarshal patch: %w", err)
-	}
-
-	deepMergeMaps(baseMap, patchMap)
-
-	merged, err := json.Marshal(baseMap)
-	if err != nil {
-		return onramp.OnRampConfig{}, fmt.Errorf("marshal merged: %w", err)
-	}
-	var result onramp.OnRampConfig
-	if err := json.Unmarshal(merged, &result); err != nil {
-		return onramp.OnRampConfig{}, fmt.Errorf("unmarshal merged: %w", err)
-	}
-	return result, nil
-}
-
-func deepMergeMaps(base, patch map[string]any) {
-	for k, pv := range patch {
-		bv, exists := base[k]
-		if !exists {
-			base[k] = pv
-			continue
-		}
-		bm, bok := bv.(map[string]any)
-		pm, pok := pv.(map[string]any)
-		if bok && pok {
-			deepMergeMaps(bm, pm)
-		} else {
-			base[k] = pv
-		}
-	}
-}
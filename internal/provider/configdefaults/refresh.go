@@ -0,0 +1,78 @@
+package configdefaults
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/bengrewell/aether-webui/internal/store"
+)
+
+// refreshConcurrency is how many nodes have their facts gathered at once.
+const refreshConcurrency = 8
+
+func (p *Provider) refresh(ctx context.Context) {
+	defer close(p.done)
+
+	ticker := time.NewTicker(p.config.FactsInterval)
+	defer ticker.Stop()
+
+	p.refreshAll(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.refreshAll(ctx)
+		}
+	}
+}
+
+// refreshAll gathers the facts of every node of every workspace and warns
+// about applied defaults that the changed facts have made stale.
+func (p *Provider) refreshAll(ctx context.Context) {
+	st := p.Store()
+	var (
+		mu      sync.Mutex
+		changed = make(map[string]bool)
+	)
+	err := p.ForEachNode(ctx, refreshConcurrency, func(ctx context.Context, ws string, info store.NodeInfo) {
+		node, ok, err := st.GetNode(ctx, info.ID)
+		if err != nil || !ok {
+			return
+		}
+		prev, _, _ := st.GetNodeFacts(ctx, info.ID, 0)
+		v, err := p.gatherFacts(ctx, st, node)
+		if err != nil {
+			if ctx.Err() == nil {
+				p.Log().Warn("facts refresh: failed to gather node facts", "node", node.Name, "error", err)
+			}
+			return
+		}
+		if v.ID != 0 && v.ID != prev.ID {
+			mu.Lock()
+			changed[ws] = true
+			mu.Unlock()
+		}
+	})
+	if err != nil {
+		p.Log().Error("facts refresh: failed to list nodes", "error", err)
+	}
+	if ctx.Err() != nil {
+		return
+	}
+
+	for ws := range changed {
+		statuses, err := p.defaultStatuses(ctx, ws)
+		if err != nil {
+			p.Log().Error("facts refresh: failed to check config defaults", "workspace", ws, "error", err)
+			continue
+		}
+		for _, s := range statuses {
+			if s.Stale {
+				p.Log().Warn("config default is stale", "workspace", ws, "field", s.Field,
+					"applied", s.Value, "current", s.CurrentValue, "node", s.SourceNode)
+			}
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package configdefaults
 
 import (
+	"strings"
+
 	"github.com/bengrewell/aether-webui/internal/nodefacts"
 )
 
@@ -11,6 +13,7 @@ type Rule struct {
 	Field     string   // dotted config path, e.g. "core.data_iface"
 	Label     string   // human-readable description
 	Roles     []string // node roles that trigger this rule
+	Facts     []string // fact paths ComputeFn reads, see nodefacts.Diff
 	ComputeFn func(facts nodefacts.NodeFacts) (value any, explanation string)
 }
 
@@ -21,6 +24,7 @@ var defaultRules = []Rule{
 		Field:     "core.data_iface",
 		Label:     "Core data interface",
 		Roles:     []string{"master"},
+		Facts:     []string{"default_iface"},
 		ComputeFn: func(f nodefacts.NodeFacts) (any, string) {
 			if f.DefaultIface == "" {
 				return nil, ""
@@ -42,6 +46,7 @@ var defaultRules = []Rule{
 		Field:     "core.amf.ip",
 		Label:     "AMF IP address",
 		Roles:     []string{"master"},
+		Facts:     []string{"default_iface", "default_ip"},
 		ComputeFn: func(f nodefacts.NodeFacts) (any, string) {
 			if f.DefaultIP == "" {
 				return nil, ""
@@ -49,11 +54,28 @@ var defaultRules = []Rule{
 			return f.DefaultIP, "primary IP on " + f.DefaultIface
 		},
 	},
+	{
+		Component: "core",
+		Field:     "core.upf.mode",
+		Label:     "UPF data plane mode",
+		Roles:     []string{"master"},
+		Facts:     []string{"default_iface", "cpu.avx2", "memory.hugepages", "interfaces"},
+		ComputeFn: func(f nodefacts.NodeFacts) (any, string) {
+			// Only fall back to af_packet; switching to dpdk also needs a
+			// DPDK values file, so a capable node keeps the configured mode.
+			gaps, ok := dpdkGaps(f)
+			if !ok || len(gaps) == 0 {
+				return nil, ""
+			}
+			return "af_packet", f.NodeName + " cannot run a DPDK UPF: " + strings.Join(gaps, ", ")
+		},
+	},
 	{
 		Component: "gnbsim",
 		Field:     "gnbsim.router.data_iface",
 		Label:     "gNBSim router data interface",
 		Roles:     []string{"gnbsim"},
+		Facts:     []string{"default_iface"},
 		ComputeFn: func(f nodefacts.NodeFacts) (any, string) {
 			if f.DefaultIface == "" {
 				return nil, ""
@@ -66,6 +88,7 @@ var defaultRules = []Rule{
 		Field:     "ueransim.gnb.ip",
 		Label:     "UERANSIM gNB IP",
 		Roles:     []string{"ueransim"},
+		Facts:     []string{"default_iface", "default_ip"},
 		ComputeFn: func(f nodefacts.NodeFacts) (any, string) {
 			if f.DefaultIP == "" {
 				return nil, ""
@@ -78,6 +101,7 @@ var defaultRules = []Rule{
 		Field:     "oai.docker.network.data_iface",
 		Label:     "OAI Docker network data interface",
 		Roles:     []string{"oai"},
+		Facts:     []string{"default_iface"},
 		ComputeFn: func(f nodefacts.NodeFacts) (any, string) {
 			if f.DefaultIface == "" {
 				return nil, ""
@@ -90,6 +114,7 @@ var defaultRules = []Rule{
 		Field:     "srsran.servers.0.gnb_ip",
 		Label:     "srsRAN gNB IP",
 		Roles:     []string{"srsran"},
+		Facts:     []string{"default_iface", "default_ip"},
 		ComputeFn: func(f nodefacts.NodeFacts) (any, string) {
 			if f.DefaultIP == "" {
 				return nil, ""
@@ -102,6 +127,7 @@ var defaultRules = []Rule{
 		Field:     "oai.servers.0.gnb_ip",
 		Label:     "OAI gNB IP",
 		Roles:     []string{"oai"},
+		Facts:     []string{"default_iface", "default_ip"},
 		ComputeFn: func(f nodefacts.NodeFacts) (any, string) {
 			if f.DefaultIP == "" {
 				return nil, ""
@@ -111,6 +137,29 @@ var defaultRules = []Rule{
 	},
 }
 
+// dpdkGaps lists what a node lacks to run a DPDK UPF on its data interface:
+// AVX2, reserved hugepages and SR-IOV virtual functions. ok is false when the
+// facts predate the hardware probes or the probes failed.
+func dpdkGaps(f nodefacts.NodeFacts) (gaps []string, ok bool) {
+	nic, found := f.Interface(f.DefaultIface)
+	if f.CPU == nil || f.Memory == nil || !found {
+		return nil, false
+	}
+	if !f.CPU.AVX2 {
+		gaps = append(gaps, "no AVX2")
+	}
+	if f.HugepagesTotal() == 0 {
+		gaps = append(gaps, "no hugepages reserved")
+	}
+	switch {
+	case nic.SRIOVTotalVFs == 0:
+		gaps = append(gaps, f.DefaultIface+" does not support SR-IOV")
+	case nic.SRIOVNumVFs == 0:
+		gaps = append(gaps, "no SR-IOV VFs created on "+f.DefaultIface)
+	}
+	return gaps, true
+}
+
 // matchesRole returns true if the node has any of the required roles.
 func matchesRole(nodeRoles []string, ruleRoles []string) bool {
 	for _, rr := range ruleRoles {
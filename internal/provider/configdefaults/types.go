@@ -1,6 +1,8 @@
 package configdefaults
 
 import (
+	"time"
+
 	"github.com/bengrewell/aether-webui/internal/nodefacts"
 	"github.com/bengrewell/aether-webui/internal/provider/onramp"
 )
@@ -11,6 +13,7 @@ import (
 type NodeFactsGetInput struct {
 	ID      string `path:"id" doc:"Node ID"`
 	Refresh bool   `query:"refresh" default:"false" doc:"Force SSH re-gathering of facts"`
+	Version int64  `query:"version" minimum:"0" doc:"Return this stored version instead of the current facts"`
 }
 
 // NodeFactsGetOutput wraps the response for the get-node-facts endpoint.
@@ -18,10 +21,24 @@ type NodeFactsGetOutput struct {
 	Body nodefacts.NodeFacts
 }
 
+// NodeFactsHistoryInput is the input for the node-facts-history endpoint.
+type NodeFactsHistoryInput struct {
+	ID    string `path:"id" doc:"Node ID"`
+	Limit int    `query:"limit" default:"20" minimum:"1" maximum:"1000" doc:"Maximum number of versions"`
+}
+
+// NodeFactsHistoryOutput wraps the response for the node-facts-history endpoint.
+type NodeFactsHistoryOutput struct {
+	Body []nodefacts.Version
+}
+
 // --- Config defaults endpoint ---
 
 // ConfigDefaultsApplyInput is the input for the apply-config-defaults endpoint.
 type ConfigDefaultsApplyInput struct {
+	onramp.WorkspaceParam
+	onramp.AuthorParam
+	onramp.DryRunParam
 	Refresh bool `query:"refresh" default:"false" doc:"Force SSH re-gathering of facts for all nodes"`
 }
 
@@ -31,6 +48,9 @@ type AppliedDefault struct {
 	Value       any    `json:"value"`
 	Explanation string `json:"explanation"`
 	SourceNode  string `json:"source_node"`
+
+	nodeID  string // node the value was computed from
+	factsID int64  // facts version it was computed from; 0 if not stored
 }
 
 // ConfigDefaultsApplyOutput wraps the response for the apply-config-defaults endpoint.
@@ -43,4 +63,32 @@ type ConfigDefaultsResult struct {
 	Applied []AppliedDefault `json:"applied"`
 	Errors  []string         `json:"errors"`
 	Config  onramp.OnRampConfig `json:"config"`
+	DryRun  bool                  `json:"dry_run,omitempty"`
+	Preview *onramp.ConfigPreview `json:"preview,omitempty" doc:"Only set for a dry run"`
+}
+
+// --- Applied defaults status endpoint ---
+
+// ConfigDefaultsListInput is the input for the list-config-defaults endpoint.
+type ConfigDefaultsListInput struct {
+	onramp.WorkspaceParam
+	Refresh bool `query:"refresh" default:"false" doc:"Gather facts again before checking for staleness"`
+}
+
+// ConfigDefaultsListOutput wraps the response for the list-config-defaults endpoint.
+type ConfigDefaultsListOutput struct {
+	Body []ConfigDefaultStatus
+}
+
+// ConfigDefaultStatus is a config value last set by the defaults engine and
+// whether the facts it was computed from still give the same value.
+type ConfigDefaultStatus struct {
+	Field        string                 `json:"field"`
+	Value        any                    `json:"value" doc:"Value that was applied"`
+	SourceNode   string                 `json:"source_node"`
+	FactsVersion int64                  `json:"facts_version" doc:"Facts version the value was computed from"`
+	AppliedAt    time.Time              `json:"applied_at"`
+	Stale        bool                   `json:"stale" doc:"The node's current facts give a different value"`
+	CurrentValue any                    `json:"current_value,omitempty" doc:"Value the current facts give; only set when stale"`
+	FactChanges  []nodefacts.FactChange `json:"fact_changes" doc:"Changes since the applied version to the facts the rule reads"`
 }
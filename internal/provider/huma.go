@@ -68,6 +68,9 @@ func opFrom(d endpoint.Descriptor) huma.Operation {
 		Summary:     d.Summary,
 		Description: d.Description,
 		Tags:        d.Tags,
+
+		MaxBodyBytes:    d.HTTP.MaxBodyBytes,
+		BodyReadTimeout: d.HTTP.BodyReadTimeout,
 	}
 }
 
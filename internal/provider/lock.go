@@ -0,0 +1,44 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"github.com/bengrewell/aether-webui/internal/store"
+)
+
+// CheckChangeLock returns a 423 error if the workspace's change lock is held.
+// Handlers that modify the cluster, the OnRamp checkout, or the node
+// inventory call this first. Providers without a store never block.
+func (b *Base) CheckChangeLock(ctx context.Context, workspace string) error {
+	st := b.Store()
+	if st.Path() == "" {
+		return nil
+	}
+	l, ok, err := st.GetLock(ctx, store.ChangeLockFor(workspace))
+	if err != nil {
+		return huma.Error500InternalServerError("failed to check change lock", err)
+	}
+	if !ok {
+		return nil
+	}
+	return ChangeLockError(l)
+}
+
+// ChangeLockError builds the 423 response that describes who holds the lock.
+func ChangeLockError(l store.Lock) error {
+	msg := fmt.Sprintf("cluster is locked for %s", l.Kind)
+	if l.Owner != "" {
+		msg += " by " + l.Owner
+	}
+	if l.Reason != "" {
+		msg += ": " + l.Reason
+	}
+	if !l.ExpiresAt.IsZero() {
+		msg += fmt.Sprintf(" (expires %s)", l.ExpiresAt.Format(time.RFC3339))
+	}
+	return huma.Error423Locked(msg)
+}
@@ -4,36 +4,60 @@ import (
 	"context"
 	"crypto/rand"
 	"fmt"
+	"slices"
+	"strings"
 
 	"github.com/danielgtaylor/huma/v2"
 
+	"github.com/bengrewell/aether-webui/internal/provider/onramp"
 	"github.com/bengrewell/aether-webui/internal/store"
 )
 
-func (n *Nodes) HandleList(ctx context.Context, _ *struct{}) (*ManagedNodeListOutput, error) {
-	infos, err := n.Store().ListNodes(ctx)
+func (n *Nodes) HandleList(ctx context.Context, in *NodeListInput) (*ManagedNodeListOutput, error) {
+	var name string
+	if in != nil {
+		name = in.Workspace
+	}
+	ws, err := n.ResolveWorkspace(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	infos, err := n.Store().ListNodes(ctx, ws.Name)
 	if err != nil {
 		return nil, huma.Error500InternalServerError("failed to list nodes", err)
 	}
+	health, err := n.healthByNode(ctx, ws.Name)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("failed to list node health", err)
+	}
 	out := make([]ManagedNode, len(infos))
 	for i, info := range infos {
-		out[i] = managedNodeFromInfo(info)
+		h, ok := health[info.ID]
+		out[i] = withHealth(managedNodeFromInfo(info), h, ok)
 	}
 	return &ManagedNodeListOutput{Body: out}, nil
 }
 
 func (n *Nodes) HandleGet(ctx context.Context, in *NodeGetInput) (*NodeGetOutput, error) {
-	node, ok, err := n.Store().GetNode(ctx, in.ID)
+	node, err := n.getScopedNode(ctx, in.Workspace, in.ID)
 	if err != nil {
-		return nil, huma.Error500InternalServerError("failed to get node", err)
+		return nil, err
 	}
-	if !ok {
-		return nil, huma.Error404NotFound("node not found", fmt.Errorf("no node with id %s", in.ID))
+	m, err := n.managedNode(ctx, node)
+	if err != nil {
+		return nil, err
 	}
-	return &NodeGetOutput{Body: managedNodeFromNode(node)}, nil
+	return &NodeGetOutput{Body: m}, nil
 }
 
 func (n *Nodes) HandleCreate(ctx context.Context, in *NodeCreateInput) (*NodeCreateOutput, error) {
+	ws, err := n.ResolveWorkspace(ctx, in.Workspace)
+	if err != nil {
+		return nil, err
+	}
+	if err := n.CheckChangeLock(ctx, ws.Name); err != nil {
+		return nil, err
+	}
 	if in.Body.Name == "" {
 		return nil, huma.Error422UnprocessableEntity("name is required")
 	}
@@ -49,7 +73,21 @@ func (n *Nodes) HandleCreate(ctx context.Context, in *NodeCreateInput) (*NodeCre
 	if in.Body.SudoPassword == "" {
 		return nil, huma.Error422UnprocessableEntity("sudo_password is required")
 	}
-	if err := validateRoles(in.Body.Roles); err != nil {
+	if err := n.validateRoles(ctx, ws.Name, in.Body.Roles); err != nil {
+		return nil, err
+	}
+	vars := store.NodeVars{
+		AnsiblePort:       in.Body.AnsiblePort,
+		PythonInterpreter: in.Body.PythonInterpreter,
+		SSHCommonArgs:     in.Body.SSHCommonArgs,
+		BecomeMethod:      in.Body.BecomeMethod,
+		HostVars:          in.Body.HostVars,
+		JumpHost:          in.Body.JumpHost,
+	}
+	if err := onramp.CheckNodeVars(vars); err != nil {
+		return nil, huma.Error422UnprocessableEntity(err.Error())
+	}
+	if err := n.validateJumpHost(ctx, ws.Name, vars.JumpHost); err != nil {
 		return nil, err
 	}
 
@@ -60,6 +98,7 @@ func (n *Nodes) HandleCreate(ctx context.Context, in *NodeCreateInput) (*NodeCre
 
 	node := store.Node{
 		ID:           id,
+		Workspace:    ws.Name,
 		Name:         in.Body.Name,
 		AnsibleHost:  in.Body.AnsibleHost,
 		AnsibleUser:  in.Body.AnsibleUser,
@@ -67,6 +106,10 @@ func (n *Nodes) HandleCreate(ctx context.Context, in *NodeCreateInput) (*NodeCre
 		SudoPassword: []byte(in.Body.SudoPassword),
 		SSHKey:       []byte(in.Body.SSHKey),
 		Roles:        in.Body.Roles,
+		NodeVars:     vars,
+	}
+	if err := n.validateTopology(ctx, node); err != nil {
+		return nil, err
 	}
 
 	if err := n.Store().UpsertNode(ctx, node); err != nil {
@@ -77,16 +120,16 @@ func (n *Nodes) HandleCreate(ctx context.Context, in *NodeCreateInput) (*NodeCre
 	if err != nil || !ok {
 		return nil, huma.Error500InternalServerError("failed to read back node", err)
 	}
-	return &NodeCreateOutput{Body: managedNodeFromNode(created)}, nil
+	return &NodeCreateOutput{Body: withHealth(managedNodeFromNode(created), store.NodeHealth{}, false)}, nil
 }
 
 func (n *Nodes) HandleUpdate(ctx context.Context, in *NodeUpdateInput) (*NodeUpdateOutput, error) {
-	existing, ok, err := n.Store().GetNode(ctx, in.ID)
+	existing, err := n.getScopedNode(ctx, in.Workspace, in.ID)
 	if err != nil {
-		return nil, huma.Error500InternalServerError("failed to get node", err)
+		return nil, err
 	}
-	if !ok {
-		return nil, huma.Error404NotFound("node not found", fmt.Errorf("no node with id %s", in.ID))
+	if err := n.CheckChangeLock(ctx, existing.Workspace); err != nil {
+		return nil, err
 	}
 
 	if in.Body.Name != nil {
@@ -98,6 +141,30 @@ func (n *Nodes) HandleUpdate(ctx context.Context, in *NodeUpdateInput) (*NodeUpd
 	if in.Body.AnsibleUser != nil {
 		existing.AnsibleUser = *in.Body.AnsibleUser
 	}
+	if in.Body.AnsiblePort != nil {
+		existing.AnsiblePort = *in.Body.AnsiblePort
+	}
+	if in.Body.PythonInterpreter != nil {
+		existing.PythonInterpreter = *in.Body.PythonInterpreter
+	}
+	if in.Body.SSHCommonArgs != nil {
+		existing.SSHCommonArgs = *in.Body.SSHCommonArgs
+	}
+	if in.Body.BecomeMethod != nil {
+		existing.BecomeMethod = *in.Body.BecomeMethod
+	}
+	if in.Body.HostVars != nil {
+		existing.HostVars = in.Body.HostVars
+	}
+	if err := onramp.CheckNodeVars(existing.NodeVars); err != nil {
+		return nil, huma.Error422UnprocessableEntity(err.Error())
+	}
+	if in.Body.JumpHost != nil {
+		if err := n.validateJumpHost(ctx, existing.Workspace, *in.Body.JumpHost); err != nil {
+			return nil, err
+		}
+		existing.JumpHost = *in.Body.JumpHost
+	}
 	if in.Body.Password != nil {
 		existing.Password = []byte(*in.Body.Password)
 	}
@@ -108,11 +175,16 @@ func (n *Nodes) HandleUpdate(ctx context.Context, in *NodeUpdateInput) (*NodeUpd
 		existing.SSHKey = []byte(*in.Body.SSHKey)
 	}
 	if in.Body.Roles != nil {
-		if err := validateRoles(in.Body.Roles); err != nil {
+		if err := n.validateRoles(ctx, existing.Workspace, in.Body.Roles); err != nil {
 			return nil, err
 		}
 		existing.Roles = in.Body.Roles
 	}
+	if in.Body.Roles != nil {
+		if err := n.validateTopology(ctx, existing); err != nil {
+			return nil, err
+		}
+	}
 
 	if err := n.Store().UpsertNode(ctx, existing); err != nil {
 		return nil, huma.Error500InternalServerError("failed to update node", err)
@@ -122,10 +194,25 @@ func (n *Nodes) HandleUpdate(ctx context.Context, in *NodeUpdateInput) (*NodeUpd
 	if err != nil || !ok {
 		return nil, huma.Error500InternalServerError("failed to read back node", err)
 	}
-	return &NodeUpdateOutput{Body: managedNodeFromNode(updated)}, nil
+	m, err := n.managedNode(ctx, updated)
+	if err != nil {
+		return nil, err
+	}
+	return &NodeUpdateOutput{Body: m}, nil
 }
 
 func (n *Nodes) HandleDelete(ctx context.Context, in *NodeDeleteInput) (*NodeDeleteOutput, error) {
+	ws, err := n.ResolveWorkspace(ctx, in.Workspace)
+	if err != nil {
+		return nil, err
+	}
+	if err := n.CheckChangeLock(ctx, ws.Name); err != nil {
+		return nil, err
+	}
+	// Refuse to delete a node that belongs to another workspace.
+	if node, ok, err := n.Store().GetNode(ctx, in.ID); err == nil && ok && node.Workspace != ws.Name {
+		return nil, huma.Error404NotFound("node not found", fmt.Errorf("no node with id %s", in.ID))
+	}
 	if err := n.Store().DeleteNode(ctx, in.ID); err != nil {
 		return nil, huma.Error500InternalServerError("failed to delete node", err)
 	}
@@ -138,42 +225,140 @@ func (n *Nodes) HandleDelete(ctx context.Context, in *NodeDeleteInput) (*NodeDel
 // Helpers
 // ---------------------------------------------------------------------------
 
+// getScopedNode loads a node and returns a 404 if it is missing or belongs to
+// a workspace other than the requested one.
+func (n *Nodes) getScopedNode(ctx context.Context, workspace, id string) (store.Node, error) {
+	ws, err := n.ResolveWorkspace(ctx, workspace)
+	if err != nil {
+		return store.Node{}, err
+	}
+	node, ok, err := n.Store().GetNode(ctx, id)
+	if err != nil {
+		return store.Node{}, huma.Error500InternalServerError("failed to get node", err)
+	}
+	if !ok || node.Workspace != ws.Name {
+		return store.Node{}, huma.Error404NotFound("node not found", fmt.Errorf("no node with id %s", id))
+	}
+	return node, nil
+}
+
+// managedNode returns the API view of node with its latest health.
+func (n *Nodes) managedNode(ctx context.Context, node store.Node) (ManagedNode, error) {
+	h, ok, err := n.Store().GetNodeHealth(ctx, node.ID)
+	if err != nil {
+		return ManagedNode{}, huma.Error500InternalServerError("failed to get node health", err)
+	}
+	return withHealth(managedNodeFromNode(node), h, ok), nil
+}
+
 func managedNodeFromNode(n store.Node) ManagedNode {
 	return ManagedNode{
-		ID:              n.ID,
-		Name:            n.Name,
-		AnsibleHost:     n.AnsibleHost,
-		AnsibleUser:     n.AnsibleUser,
-		HasPassword:     len(n.Password) > 0,
-		HasSudoPassword: len(n.SudoPassword) > 0,
-		HasSSHKey:       len(n.SSHKey) > 0,
-		Roles:           n.Roles,
-		CreatedAt:       n.CreatedAt,
-		UpdatedAt:       n.UpdatedAt,
+		ID:                n.ID,
+		Workspace:         n.Workspace,
+		Name:              n.Name,
+		AnsibleHost:       n.AnsibleHost,
+		AnsibleUser:       n.AnsibleUser,
+		AnsiblePort:       n.AnsiblePort,
+		PythonInterpreter: n.PythonInterpreter,
+		SSHCommonArgs:     n.SSHCommonArgs,
+		BecomeMethod:      n.BecomeMethod,
+		HostVars:          n.HostVars,
+		JumpHost:          n.JumpHost,
+		HasPassword:       len(n.Password) > 0,
+		HasSudoPassword:   len(n.SudoPassword) > 0,
+		HasSSHKey:         len(n.SSHKey) > 0,
+		Roles:             n.Roles,
+		CreatedAt:         n.CreatedAt,
+		UpdatedAt:         n.UpdatedAt,
 	}
 }
 
 func managedNodeFromInfo(info store.NodeInfo) ManagedNode {
 	return ManagedNode{
-		ID:          info.ID,
-		Name:        info.Name,
-		AnsibleHost: info.AnsibleHost,
-		AnsibleUser: info.AnsibleUser,
-		Roles:       info.Roles,
-		CreatedAt:   info.CreatedAt,
-		UpdatedAt:   info.UpdatedAt,
+		ID:                info.ID,
+		Workspace:         info.Workspace,
+		Name:              info.Name,
+		AnsibleHost:       info.AnsibleHost,
+		AnsibleUser:       info.AnsibleUser,
+		AnsiblePort:       info.AnsiblePort,
+		PythonInterpreter: info.PythonInterpreter,
+		SSHCommonArgs:     info.SSHCommonArgs,
+		BecomeMethod:      info.BecomeMethod,
+		HostVars:          info.HostVars,
+		JumpHost:          info.JumpHost,
+		Roles:             info.Roles,
+		CreatedAt:         info.CreatedAt,
+		UpdatedAt:         info.UpdatedAt,
 	}
 }
 
-func validateRoles(roles []string) error {
+// validateRoles checks roles against the roles of the workspace's OnRamp
+// checkout.
+func (n *Nodes) validateRoles(ctx context.Context, workspace string, roles []string) error {
+	if len(roles) == 0 {
+		return nil
+	}
+	valid, err := n.roles(ctx, workspace)
+	if err != nil {
+		return huma.Error500InternalServerError("failed to load node roles", err)
+	}
 	for _, r := range roles {
-		if !ValidRoles[r] {
-			return huma.Error422UnprocessableEntity(fmt.Sprintf("invalid role %q", r))
+		if !slices.Contains(valid, r) {
+			return huma.Error422UnprocessableEntity(fmt.Sprintf("invalid role %q; valid roles: %s", r, strings.Join(valid, ", ")))
+		}
+	}
+	return nil
+}
+
+// validateTopology checks the workspace's role assignments with node saved
+// against the rules that hold for any subset of nodes: role limits such as the
+// single master and roles that cannot share a node. Roles that need another
+// role or a deployed component are left to inventory sync and deploy, so
+// nodes can be added in any order. Only issues involving node are reported.
+func (n *Nodes) validateTopology(ctx context.Context, node store.Node) error {
+	if len(node.Roles) == 0 {
+		return nil
+	}
+	infos, err := n.Store().ListNodes(ctx, node.Workspace)
+	if err != nil {
+		return huma.Error500InternalServerError("failed to list nodes", err)
+	}
+	assigned := []onramp.TopologyNode{{Name: node.Name, Roles: node.Roles}}
+	for _, info := range infos {
+		if info.ID != node.ID {
+			assigned = append(assigned, onramp.TopologyNode{Name: info.Name, Roles: info.Roles})
 		}
 	}
+	issues, err := n.topologyIssues(ctx, node.Workspace, assigned, []string{node.Name})
+	if err != nil {
+		return huma.Error500InternalServerError("failed to check node topology", err)
+	}
+	if len(issues) > 0 {
+		return onramp.TopologyError(fmt.Sprintf("roles of node %s break topology rules", node.Name), issues)
+	}
 	return nil
 }
 
+// topologyIssues checks assigned, a workspace's complete role assignments,
+// against the rules validateTopology enforces and returns the issues that
+// involve any of names.
+func (n *Nodes) topologyIssues(ctx context.Context, workspace string, assigned []onramp.TopologyNode, names []string) ([]onramp.TopologyIssue, error) {
+	res, err := n.topology(ctx, workspace, assigned)
+	if err != nil {
+		return nil, err
+	}
+	var issues []onramp.TopologyIssue
+	for _, issue := range res.Errors {
+		if issue.Rule != onramp.TopologyRoleMax && issue.Rule != onramp.TopologyRoleConflict {
+			continue
+		}
+		if slices.ContainsFunc(issue.Nodes, func(name string) bool { return slices.Contains(names, name) }) {
+			issues = append(issues, issue)
+		}
+	}
+	return issues, nil
+}
+
 func generateID() (string, error) {
 	b := make([]byte, 16)
 	if _, err := rand.Read(b); err != nil {
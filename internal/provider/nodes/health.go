@@ -0,0 +1,286 @@
+package nodes
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	internalssh "github.com/bengrewell/aether-webui/internal/ssh"
+	"github.com/bengrewell/aether-webui/internal/store"
+)
+
+const (
+	// probeTimeout bounds each step of a health probe.
+	probeTimeout = 10 * time.Second
+	// probeConcurrency is how many nodes are probed at once.
+	probeConcurrency = 8
+)
+
+// Start overrides Base.Start to spawn the background health monitor when a
+// health interval is configured.
+func (n *Nodes) Start() error {
+	if n.config.HealthInterval > 0 {
+		ctx, cancel := context.WithCancel(context.Background())
+		n.cancel = cancel
+		n.done = make(chan struct{})
+		go n.monitor(ctx)
+	}
+	n.Base.SetRunning(true)
+	return nil
+}
+
+// Stop overrides Base.Stop to cancel the health monitor and wait for it to
+// exit. Safe to call multiple times; only the first call performs cleanup.
+func (n *Nodes) Stop() error {
+	n.stopOnce.Do(func() {
+		if n.cancel != nil {
+			n.cancel()
+			<-n.done
+		}
+		n.Base.SetRunning(false)
+	})
+	return nil
+}
+
+func (n *Nodes) monitor(ctx context.Context) {
+	defer close(n.done)
+
+	ticker := time.NewTicker(n.config.HealthInterval)
+	defer ticker.Stop()
+
+	n.checkAll(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n.checkAll(ctx)
+		}
+	}
+}
+
+// checkAll probes every node of every workspace.
+func (n *Nodes) checkAll(ctx context.Context) {
+	err := n.ForEachNode(ctx, probeConcurrency, func(ctx context.Context, _ string, info store.NodeInfo) {
+		if _, err := n.checkNode(ctx, info.ID); err != nil && ctx.Err() == nil {
+			n.Log().Error("health monitor: failed to check node", "node_id", info.ID, "error", err)
+		}
+	})
+	if err != nil {
+		n.Log().Error("health monitor: failed to list nodes", "error", err)
+	}
+}
+
+// checkNode probes one node and records the result, logging a change of
+// status.
+func (n *Nodes) checkNode(ctx context.Context, id string) (store.NodeHealth, error) {
+	st := n.Store()
+	node, ok, err := st.GetNode(ctx, id)
+	if err != nil {
+		return store.NodeHealth{}, err
+	}
+	if !ok {
+		return store.NodeHealth{}, fmt.Errorf("no node with id %s", id)
+	}
+	h := n.probe(ctx, node)
+	h.NodeID = node.ID
+	if ctx.Err() != nil {
+		// A probe cut short by shutdown says nothing about the node.
+		return store.NodeHealth{}, ctx.Err()
+	}
+	ev, changed, err := st.RecordNodeHealth(ctx, h)
+	if err != nil {
+		return store.NodeHealth{}, err
+	}
+	if changed {
+		log := n.Log().With("node", node.Name, "workspace", node.Workspace, "from", ev.From, "to", ev.To)
+		if ev.To == store.NodeHealthHealthy {
+			log.Info("node health changed")
+		} else {
+			log.Warn("node health changed", "error", ev.Error)
+		}
+	}
+	saved, _, err := st.GetNodeHealth(ctx, node.ID)
+	return saved, err
+}
+
+// probeWithJumps probes a node through the jump hosts it is reached by.
+func (n *Nodes) probeWithJumps(ctx context.Context, node store.Node) store.NodeHealth {
+	cfg, err := internalssh.NodeConfig(ctx, n.Store(), node, probeTimeout)
+	if err != nil {
+		return store.NodeHealth{NodeID: node.ID, CheckedAt: time.Now(), Status: store.NodeHealthUnreachable, Error: err.Error()}
+	}
+	return probeNode(ctx, cfg, node)
+}
+
+// probeNode checks that a node's SSH port answers, that the stored
+// credentials log in, and that sudo works. Nodes whose become method is not
+// sudo skip the last step. With jump hosts in cfg, the port is reached
+// through them and the latency includes connecting to them.
+func probeNode(ctx context.Context, cfg internalssh.Config, node store.Node) store.NodeHealth {
+	h := store.NodeHealth{NodeID: node.ID, CheckedAt: time.Now(), Status: store.NodeHealthUnreachable}
+
+	dialCtx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+	start := time.Now()
+	conn, err := internalssh.DialTCP(dialCtx, cfg.Jumps, cfg.Host, probeTimeout)
+	if err != nil {
+		h.Error = err.Error()
+		return h
+	}
+	h.Latency = time.Since(start)
+	conn.Close()
+	h.TCP = true
+	h.Status = store.NodeHealthDegraded
+
+	client, err := internalssh.Dial(ctx, cfg)
+	if err != nil {
+		h.Error = err.Error()
+		return h
+	}
+	defer client.Close()
+	h.SSH = true
+
+	if node.BecomeMethod != "" && node.BecomeMethod != "sudo" {
+		h.Status = store.NodeHealthHealthy
+		return h
+	}
+	cmd, stdin := "sudo -n true", []byte(nil)
+	if len(node.SudoPassword) > 0 {
+		cmd, stdin = "sudo -S -p '' true", slices.Concat(node.SudoPassword, []byte("\n"))
+	}
+	runCtx, cancelRun := context.WithTimeout(ctx, probeTimeout)
+	defer cancelRun()
+	_, stderr, code, err := client.RunInput(runCtx, cmd, stdin)
+	switch {
+	case err != nil:
+		h.Error = fmt.Sprintf("sudo: %v", err)
+	case code != 0:
+		h.Error = fmt.Sprintf("sudo exited %d: %s", code, strings.TrimSpace(string(stderr)))
+	default:
+		h.Sudo = true
+		h.Status = store.NodeHealthHealthy
+	}
+	return h
+}
+
+// ---------------------------------------------------------------------------
+// Handlers
+// ---------------------------------------------------------------------------
+
+func (n *Nodes) HandleGetHealth(ctx context.Context, in *NodeHealthGetInput) (*NodeHealthGetOutput, error) {
+	node, err := n.getScopedNode(ctx, in.Workspace, in.ID)
+	if err != nil {
+		return nil, err
+	}
+	h, ok, err := n.Store().GetNodeHealth(ctx, node.ID)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("failed to get node health", err)
+	}
+	out := &NodeHealthGetOutput{}
+	out.Body.NodeHealth = nodeHealthFromStore(node.ID, h, ok)
+	out.Body.Events = []NodeHealthEvent{}
+	if in.Events > 0 {
+		events, err := n.Store().ListNodeHealthEvents(ctx, store.NodeHealthEventFilter{
+			Workspace: node.Workspace, NodeID: node.ID, Limit: in.Events,
+		})
+		if err != nil {
+			return nil, huma.Error500InternalServerError("failed to list node health events", err)
+		}
+		out.Body.Events = nodeHealthEvents(events)
+	}
+	return out, nil
+}
+
+func (n *Nodes) HandleCheckHealth(ctx context.Context, in *NodeHealthCheckInput) (*NodeHealthCheckOutput, error) {
+	node, err := n.getScopedNode(ctx, in.Workspace, in.ID)
+	if err != nil {
+		return nil, err
+	}
+	h, err := n.checkNode(ctx, node.ID)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("failed to check node health", err)
+	}
+	return &NodeHealthCheckOutput{Body: nodeHealthFromStore(node.ID, h, true)}, nil
+}
+
+func (n *Nodes) HandleListHealthEvents(ctx context.Context, in *NodeHealthEventsInput) (*NodeHealthEventsOutput, error) {
+	ws, err := n.ResolveWorkspace(ctx, in.Workspace)
+	if err != nil {
+		return nil, err
+	}
+	events, err := n.Store().ListNodeHealthEvents(ctx, store.NodeHealthEventFilter{
+		Workspace: ws.Name, NodeID: in.NodeID, Limit: in.Limit,
+	})
+	if err != nil {
+		return nil, huma.Error500InternalServerError("failed to list node health events", err)
+	}
+	return &NodeHealthEventsOutput{Body: nodeHealthEvents(events)}, nil
+}
+
+// ---------------------------------------------------------------------------
+// Helpers
+// ---------------------------------------------------------------------------
+
+// healthByNode returns the latest health of a workspace's nodes by node ID.
+func (n *Nodes) healthByNode(ctx context.Context, workspace string) (map[string]store.NodeHealth, error) {
+	list, err := n.Store().ListNodeHealth(ctx, workspace)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]store.NodeHealth, len(list))
+	for _, h := range list {
+		out[h.NodeID] = h
+	}
+	return out, nil
+}
+
+// withHealth returns m with the health fields set from h; ok is false for a
+// node never probed.
+func withHealth(m ManagedNode, h store.NodeHealth, ok bool) ManagedNode {
+	m.Health = store.NodeHealthUnknown
+	if ok {
+		m.Health = h.Status
+		m.HealthCheckedAt = timePtr(h.CheckedAt)
+		m.LastSeen = timePtr(h.LastSeen)
+	}
+	return m
+}
+
+func nodeHealthFromStore(nodeID string, h store.NodeHealth, ok bool) NodeHealth {
+	if !ok {
+		return NodeHealth{NodeID: nodeID, Status: store.NodeHealthUnknown}
+	}
+	return NodeHealth{
+		NodeID:    nodeID,
+		Status:    h.Status,
+		TCP:       h.TCP,
+		SSH:       h.SSH,
+		Sudo:      h.Sudo,
+		LatencyMS: h.Latency.Milliseconds(),
+		Error:     h.Error,
+		CheckedAt: timePtr(h.CheckedAt),
+		Since:     timePtr(h.Since),
+		LastSeen:  timePtr(h.LastSeen),
+	}
+}
+
+func nodeHealthEvents(events []store.NodeHealthEvent) []NodeHealthEvent {
+	out := make([]NodeHealthEvent, len(events))
+	for i, ev := range events {
+		out[i] = NodeHealthEvent{ID: ev.ID, NodeID: ev.NodeID, From: ev.From, To: ev.To, Error: ev.Error, At: ev.At}
+	}
+	return out
+}
+
+// timePtr returns &t, or nil for the zero time.
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
@@ -0,0 +1,170 @@
+package nodes
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	internalssh "github.com/bengrewell/aether-webui/internal/ssh"
+	"github.com/bengrewell/aether-webui/internal/store"
+)
+
+// fakeProbe reports each node with the status in statuses, keyed by name.
+func fakeProbe(statuses map[string]string) func(context.Context, store.Node) store.NodeHealth {
+	return func(_ context.Context, node store.Node) store.NodeHealth {
+		status := statuses[node.Name]
+		return store.NodeHealth{
+			Status: status,
+			TCP:    status != store.NodeHealthUnreachable,
+			SSH:    status == store.NodeHealthHealthy,
+			Sudo:   status == store.NodeHealthHealthy,
+		}
+	}
+}
+
+func TestCheckAll_RecordsHealth(t *testing.T) {
+	p := newTestProvider(t)
+	n1, err := createWithRoles(t, p, "node1", "master")
+	if err != nil {
+		t.Fatalf("create node1: %v", err)
+	}
+	if _, err := createWithRoles(t, p, "node2", "worker"); err != nil {
+		t.Fatalf("create node2: %v", err)
+	}
+	if n1.Body.Health != store.NodeHealthUnknown || n1.Body.LastSeen != nil {
+		t.Errorf("new node health = %q, last seen %v", n1.Body.Health, n1.Body.LastSeen)
+	}
+
+	statuses := map[string]string{"node1": store.NodeHealthHealthy, "node2": store.NodeHealthDegraded}
+	p.probe = fakeProbe(statuses)
+	p.checkAll(t.Context())
+
+	list, err := p.HandleList(t.Context(), &NodeListInput{})
+	if err != nil {
+		t.Fatalf("HandleList: %v", err)
+	}
+	got := map[string]ManagedNode{}
+	for _, m := range list.Body {
+		got[m.Name] = m
+	}
+	if got["node1"].Health != store.NodeHealthHealthy || got["node1"].LastSeen == nil {
+		t.Errorf("node1 = %+v", got["node1"])
+	}
+	if got["node2"].Health != store.NodeHealthDegraded || got["node2"].LastSeen != nil || got["node2"].HealthCheckedAt == nil {
+		t.Errorf("node2 = %+v", got["node2"])
+	}
+
+	statuses["node1"] = store.NodeHealthUnreachable
+	p.checkAll(t.Context())
+
+	h, err := p.HandleGetHealth(t.Context(), &NodeHealthGetInput{ID: n1.Body.ID, Events: 10})
+	if err != nil {
+		t.Fatalf("HandleGetHealth: %v", err)
+	}
+	if h.Body.Status != store.NodeHealthUnreachable || h.Body.TCP || h.Body.LastSeen == nil {
+		t.Errorf("health = %+v", h.Body.NodeHealth)
+	}
+	if len(h.Body.Events) != 2 || h.Body.Events[0].From != store.NodeHealthHealthy || h.Body.Events[1].From != store.NodeHealthUnknown {
+		t.Errorf("events = %+v", h.Body.Events)
+	}
+
+	events, err := p.HandleListHealthEvents(t.Context(), &NodeHealthEventsInput{Limit: 100})
+	if err != nil {
+		t.Fatalf("HandleListHealthEvents: %v", err)
+	}
+	if len(events.Body) != 3 {
+		t.Errorf("workspace events = %+v", events.Body)
+	}
+}
+
+func TestHandleCheckHealth(t *testing.T) {
+	p := newTestProvider(t)
+	n1, err := createWithRoles(t, p, "node1", "master")
+	if err != nil {
+		t.Fatalf("create node1: %v", err)
+	}
+	p.probe = fakeProbe(map[string]string{"node1": store.NodeHealthDegraded})
+
+	out, err := p.HandleCheckHealth(t.Context(), &NodeHealthCheckInput{ID: n1.Body.ID})
+	if err != nil {
+		t.Fatalf("HandleCheckHealth: %v", err)
+	}
+	if out.Body.Status != store.NodeHealthDegraded || out.Body.Since == nil {
+		t.Errorf("health = %+v", out.Body)
+	}
+
+	// Nodes of other workspaces are not found.
+	_, err = p.HandleCheckHealth(t.Context(), &NodeHealthCheckInput{WorkspaceParam: WorkspaceParam{Workspace: "lab2"}, ID: n1.Body.ID})
+	if err == nil {
+		t.Error("expected error for node of another workspace")
+	}
+}
+
+func TestProbeNode(t *testing.T) {
+	// A closed port is unreachable.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+	node := store.Node{ID: "n1", AnsibleHost: "127.0.0.1", AnsibleUser: "aether", Password: []byte("pw")}
+	cfg := internalssh.Config{Host: net.JoinHostPort("127.0.0.1", strconv.Itoa(port)), User: "aether", Password: "pw", Timeout: time.Second}
+	if h := probeNode(t.Context(), cfg, node); h.Status != store.NodeHealthUnreachable || h.TCP || h.Error == "" {
+		t.Errorf("closed port = %+v", h)
+	}
+
+	// A port that is not an SSH server answers but cannot be logged in to.
+	l, err = net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	cfg.Host = l.Addr().String()
+	if h := probeNode(t.Context(), cfg, node); h.Status != store.NodeHealthDegraded || !h.TCP || h.SSH || h.Error == "" {
+		t.Errorf("non-SSH port = %+v", h)
+	}
+}
+
+func TestStartStop_HealthMonitor(t *testing.T) {
+	p := newTestProvider(t)
+	if _, err := createWithRoles(t, p, "node1", "master"); err != nil {
+		t.Fatalf("create node1: %v", err)
+	}
+	var probes atomic.Int32
+	p.probe = func(ctx context.Context, node store.Node) store.NodeHealth {
+		probes.Add(1)
+		return fakeProbe(map[string]string{"node1": store.NodeHealthHealthy})(ctx, node)
+	}
+	p.config.HealthInterval = time.Hour
+
+	if err := p.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	// The monitor probes once on start.
+	deadline := time.Now().Add(5 * time.Second)
+	for probes.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := p.Stop(); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	if err := p.Stop(); err != nil {
+		t.Fatalf("second Stop: %v", err)
+	}
+	if probes.Load() != 1 {
+		t.Errorf("probes = %d, want 1", probes.Load())
+	}
+}
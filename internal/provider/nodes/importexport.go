@@ -0,0 +1,472 @@
+package nodes
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"gopkg.in/yaml.v3"
+
+	"github.com/bengrewell/aether-webui/internal/provider/onramp"
+	"github.com/bengrewell/aether-webui/internal/store"
+)
+
+// nodeRecord is a node as read from or written to an import/export file.
+// Export leaves the secrets out.
+type nodeRecord struct {
+	Name              string            `yaml:"name"`
+	AnsibleHost       string            `yaml:"ansible_host"`
+	AnsibleUser       string            `yaml:"ansible_user"`
+	AnsiblePort       int               `yaml:"ansible_port,omitempty"`
+	PythonInterpreter string            `yaml:"python_interpreter,omitempty"`
+	SSHCommonArgs     string            `yaml:"ssh_common_args,omitempty"`
+	BecomeMethod      string            `yaml:"become_method,omitempty"`
+	HostVars          map[string]string `yaml:"host_vars,omitempty"`
+	Password          string            `yaml:"password,omitempty"`
+	SudoPassword      string            `yaml:"sudo_password,omitempty"`
+	SSHKey            string            `yaml:"ssh_key,omitempty"`
+	Roles             []string          `yaml:"roles,omitempty"`
+}
+
+func (r nodeRecord) nodeVars() store.NodeVars {
+	return store.NodeVars{
+		AnsiblePort:       r.AnsiblePort,
+		PythonInterpreter: r.PythonInterpreter,
+		SSHCommonArgs:     r.SSHCommonArgs,
+		BecomeMethod:      r.BecomeMethod,
+		HostVars:          r.HostVars,
+	}
+}
+
+// recordFromInfo is a node as export writes it.
+func recordFromInfo(info store.NodeInfo) nodeRecord {
+	return nodeRecord{
+		Name:              info.Name,
+		AnsibleHost:       info.AnsibleHost,
+		AnsibleUser:       info.AnsibleUser,
+		AnsiblePort:       info.AnsiblePort,
+		PythonInterpreter: info.PythonInterpreter,
+		SSHCommonArgs:     info.SSHCommonArgs,
+		BecomeMethod:      info.BecomeMethod,
+		HostVars:          info.HostVars,
+		Roles:             info.Roles,
+	}
+}
+
+// csvColumns are the columns a CSV import may have, in export order. Host
+// vars have no column; use INI or YAML for them.
+var csvColumns = []string{
+	"name", "ansible_host", "ansible_user", "ansible_port", "python_interpreter", "ssh_common_args", "become_method",
+	"password", "sudo_password", "ssh_key", "roles",
+}
+
+func (n *Nodes) HandleImport(ctx context.Context, in *NodeImportInput) (*NodeImportOutput, error) {
+	ws, err := n.ResolveWorkspace(ctx, in.Workspace)
+	if err != nil {
+		return nil, err
+	}
+	if !in.DryRun {
+		if err := n.CheckChangeLock(ctx, ws.Name); err != nil {
+			return nil, err
+		}
+	}
+	onConflict := in.Body.OnConflict
+	if onConflict == "" {
+		onConflict = OnConflictError
+	}
+
+	records, err := parseNodeFile(in.Body.Format, in.Body.Content)
+	if err != nil {
+		return nil, huma.Error422UnprocessableEntity(fmt.Sprintf("invalid %s content", in.Body.Format), err)
+	}
+	if len(records) == 0 {
+		return nil, huma.Error422UnprocessableEntity("no nodes found in content")
+	}
+	roles, err := n.roles(ctx, ws.Name)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("failed to load node roles", err)
+	}
+	infos, err := n.Store().ListNodes(ctx, ws.Name)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("failed to list nodes", err)
+	}
+	existing := make(map[string]string, len(infos))
+	for _, info := range infos {
+		existing[info.Name] = info.ID
+	}
+
+	out := &NodeImportOutput{}
+	out.Body.DryRun = in.DryRun
+	seen := make(map[string]bool)
+	var invalid, conflicts []string
+	for _, r := range records {
+		item := ImportedNode{
+			Name:            r.Name,
+			AnsibleHost:     r.AnsibleHost,
+			AnsibleUser:     r.AnsibleUser,
+			HasPassword:     r.Password != "",
+			HasSudoPassword: r.SudoPassword != "",
+			HasSSHKey:       r.SSHKey != "",
+			Roles:           r.Roles,
+			Action:          "create",
+		}
+		if msg := checkRecord(r, roles, seen); msg != "" {
+			item.Error = msg
+			item.Action = "skip"
+			invalid = append(invalid, fmt.Sprintf("%s: %s", r.Name, msg))
+		} else if id, ok := existing[r.Name]; ok {
+			item.ID = id
+			item.Conflict = true
+			conflicts = append(conflicts, r.Name)
+			if onConflict == OnConflictUpdate {
+				item.Action = "update"
+			} else {
+				item.Action = "skip"
+			}
+		}
+		seen[r.Name] = true
+		out.Body.Nodes = append(out.Body.Nodes, item)
+	}
+	out.Body.Conflicts = len(conflicts)
+
+	if !in.DryRun {
+		if len(invalid) > 0 {
+			return nil, huma.Error422UnprocessableEntity(
+				fmt.Sprintf("%d nodes cannot be imported: %s", len(invalid), strings.Join(invalid, "; ")))
+		}
+		if len(conflicts) > 0 && onConflict == OnConflictError {
+			return nil, huma.Error409Conflict(fmt.Sprintf(
+				"nodes already exist: %s; set on_conflict to skip or update", strings.Join(conflicts, ", ")))
+		}
+	}
+
+	// Build every node before writing, so the import is checked as a whole
+	// and then written in one transaction.
+	nodes := make([]store.Node, 0, len(records))
+	var names []string
+	for i, r := range records {
+		item := &out.Body.Nodes[i]
+		switch item.Action {
+		case "skip":
+			out.Body.Skipped++
+			continue
+		case "create":
+			out.Body.Created++
+		case "update":
+			out.Body.Updated++
+		}
+		node, err := n.importNode(ctx, ws.Name, item.ID, r)
+		if err != nil {
+			return nil, huma.Error500InternalServerError(fmt.Sprintf("failed to import node %s", r.Name), err)
+		}
+		nodes = append(nodes, node)
+		names = append(names, node.Name)
+	}
+
+	assigned := make([]onramp.TopologyNode, 0, len(infos)+len(nodes))
+	for _, node := range nodes {
+		assigned = append(assigned, onramp.TopologyNode{Name: node.Name, Roles: node.Roles})
+	}
+	for _, info := range infos {
+		if !slices.Contains(names, info.Name) {
+			assigned = append(assigned, onramp.TopologyNode{Name: info.Name, Roles: info.Roles})
+		}
+	}
+	issues, err := n.topologyIssues(ctx, ws.Name, assigned, names)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("failed to check node topology", err)
+	}
+	out.Body.TopologyErrors = issues
+	if in.DryRun {
+		return out, nil
+	}
+	if len(issues) > 0 {
+		return nil, onramp.TopologyError("imported node roles break topology rules", issues)
+	}
+	if err := n.Store().ApplyNodes(ctx, ws.Name, store.NodeBatch{Upsert: nodes}); err != nil {
+		return nil, huma.Error500InternalServerError("failed to import nodes", err)
+	}
+	for i, j := 0, 0; i < len(out.Body.Nodes); i++ {
+		if out.Body.Nodes[i].Action != "skip" {
+			out.Body.Nodes[i].ID = nodes[j].ID
+			j++
+		}
+	}
+	return out, nil
+}
+
+// importNode returns the node r creates, or the node with id updated by r.
+// An update keeps the node's secrets, roles and inventory variables where r
+// has none.
+func (n *Nodes) importNode(ctx context.Context, workspace, id string, r nodeRecord) (store.Node, error) {
+	node := store.Node{Workspace: workspace}
+	if id == "" {
+		var err error
+		if node.ID, err = generateID(); err != nil {
+			return store.Node{}, err
+		}
+	} else {
+		cur, ok, err := n.Store().GetNode(ctx, id)
+		if err != nil {
+			return store.Node{}, err
+		}
+		if !ok {
+			return store.Node{}, fmt.Errorf("node %s disappeared", id)
+		}
+		node = cur
+		node.UpdatedAt = time.Time{}
+	}
+	node.Name = r.Name
+	node.AnsibleHost = r.AnsibleHost
+	node.AnsibleUser = r.AnsibleUser
+	if r.Password != "" {
+		node.Password = []byte(r.Password)
+	}
+	if r.SudoPassword != "" {
+		node.SudoPassword = []byte(r.SudoPassword)
+	}
+	if r.SSHKey != "" {
+		node.SSHKey = []byte(r.SSHKey)
+	}
+	if len(r.Roles) > 0 {
+		node.Roles = r.Roles
+	}
+	if v := r.nodeVars(); !reflect.DeepEqual(v, store.NodeVars{}) {
+		// Import files do not name jump hosts.
+		v.JumpHost = node.JumpHost
+		node.NodeVars = v
+	}
+	return node, nil
+}
+
+// checkRecord returns why r cannot be imported, or "" if it can.
+func checkRecord(r nodeRecord, roles []string, seen map[string]bool) string {
+	switch {
+	case r.Name == "":
+		return "name is required"
+	case seen[r.Name]:
+		return "duplicate node name in content"
+	case r.AnsibleHost == "":
+		return "ansible_host is required"
+	case r.AnsibleUser == "":
+		return "ansible_user is required"
+	}
+	for _, role := range r.Roles {
+		if !slices.Contains(roles, role) {
+			return fmt.Sprintf("invalid role %q; valid roles: %s", role, strings.Join(roles, ", "))
+		}
+	}
+	if err := onramp.CheckNodeVars(r.nodeVars()); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+func (n *Nodes) HandleExport(ctx context.Context, in *NodeExportInput) (*NodeExportOutput, error) {
+	ws, err := n.ResolveWorkspace(ctx, in.Workspace)
+	if err != nil {
+		return nil, err
+	}
+	infos, err := n.Store().ListNodes(ctx, ws.Name)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("failed to list nodes", err)
+	}
+
+	format := in.Format
+	if format == "" {
+		format = FormatINI
+	}
+	var data []byte
+	switch format {
+	case FormatINI:
+		roles, err := n.roles(ctx, ws.Name)
+		if err != nil {
+			return nil, huma.Error500InternalServerError("failed to load node roles", err)
+		}
+		nodes := make([]store.Node, len(infos))
+		for i, info := range infos {
+			nodes[i] = store.Node{
+				Name: info.Name, AnsibleHost: info.AnsibleHost, AnsibleUser: info.AnsibleUser,
+				Roles: info.Roles, NodeVars: info.NodeVars,
+			}
+		}
+		groupVars, err := n.Store().ListGroupVars(ctx, ws.Name)
+		if err != nil {
+			return nil, huma.Error500InternalServerError("failed to load group vars", err)
+		}
+		data = onramp.HostsINI(nodes, roles, groupVars)
+	case FormatCSV, FormatYAML:
+		records := make([]nodeRecord, len(infos))
+		for i, info := range infos {
+			records[i] = recordFromInfo(info)
+		}
+		if format == FormatCSV {
+			data, err = writeCSV(records)
+		} else {
+			data, err = yaml.Marshal(records)
+		}
+		if err != nil {
+			return nil, huma.Error500InternalServerError("failed to export nodes", err)
+		}
+	default:
+		return nil, huma.Error422UnprocessableEntity(fmt.Sprintf("unknown format %q", format))
+	}
+
+	out := &NodeExportOutput{}
+	out.Body.Format = format
+	out.Body.Content = string(data)
+	return out, nil
+}
+
+// ---------------------------------------------------------------------------
+// Formats
+// ---------------------------------------------------------------------------
+
+// parseNodeFile reads the nodes of an import file.
+func parseNodeFile(format, content string) ([]nodeRecord, error) {
+	switch format {
+	case FormatINI:
+		return parseINI(content), nil
+	case FormatCSV:
+		return parseCSV(content)
+	case FormatYAML:
+		return parseYAML(content)
+	}
+	return nil, fmt.Errorf("unknown format %q", format)
+}
+
+// parseINI reads nodes from an Ansible hosts.ini. Credentials come from the
+// usual connection variables, roles from the [<role>_nodes] groups and other
+// variables become the node's; a host without ansible_host is reached by its
+// name, as Ansible does.
+func parseINI(content string) []nodeRecord {
+	var records []nodeRecord
+	for _, h := range onramp.ReadHostsINI([]byte(content)) {
+		r := nodeRecord{
+			Name:        h.Name,
+			AnsibleHost: firstVar(h.Vars, "ansible_host", "ansible_ssh_host"),
+			AnsibleUser: firstVar(h.Vars, "ansible_user", "ansible_ssh_user"),
+		}
+		v := h.NodeVars()
+		r.AnsiblePort, r.PythonInterpreter, r.SSHCommonArgs, r.BecomeMethod, r.HostVars =
+			v.AnsiblePort, v.PythonInterpreter, v.SSHCommonArgs, v.BecomeMethod, v.HostVars
+		r.Password, r.SudoPassword = onramp.HostCredentials(h.Vars)
+		if r.AnsibleHost == "" {
+			r.AnsibleHost = h.Name
+		}
+		for _, g := range h.Groups {
+			if role, ok := strings.CutSuffix(g, "_nodes"); ok {
+				r.Roles = append(r.Roles, role)
+			}
+		}
+		records = append(records, r)
+	}
+	return records
+}
+
+func firstVar(vars map[string]string, keys ...string) string {
+	for _, k := range keys {
+		if v := vars[k]; v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// parseCSV reads nodes from CSV with a header row naming csvColumns. Roles
+// are separated by semicolons or spaces.
+func parseCSV(content string) ([]nodeRecord, error) {
+	rows, err := csv.NewReader(strings.NewReader(content)).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	header := rows[0]
+	for i, col := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(col))
+		if !slices.Contains(csvColumns, header[i]) {
+			return nil, fmt.Errorf("unknown column %q; columns: %s", col, strings.Join(csvColumns, ", "))
+		}
+	}
+	if !slices.Contains(header, "name") {
+		return nil, errors.New("missing name column")
+	}
+
+	var records []nodeRecord
+	for _, row := range rows[1:] {
+		var r nodeRecord
+		for i, v := range row {
+			v = strings.TrimSpace(v)
+			switch header[i] {
+			case "name":
+				r.Name = v
+			case "ansible_host":
+				r.AnsibleHost = v
+			case "ansible_user":
+				r.AnsibleUser = v
+			case "ansible_port":
+				if v == "" {
+					continue
+				}
+				port, err := strconv.Atoi(v)
+				if err != nil {
+					return nil, fmt.Errorf("node %s: ansible_port %q is not a number", r.Name, v)
+				}
+				r.AnsiblePort = port
+			case "python_interpreter":
+				r.PythonInterpreter = v
+			case "ssh_common_args":
+				r.SSHCommonArgs = v
+			case "become_method":
+				r.BecomeMethod = v
+			case "password":
+				r.Password = v
+			case "sudo_password":
+				r.SudoPassword = v
+			case "ssh_key":
+				r.SSHKey = v
+			case "roles":
+				r.Roles = strings.FieldsFunc(v, func(c rune) bool { return c == ';' || c == ' ' })
+			}
+		}
+		records = append(records, r)
+	}
+	return records, nil
+}
+
+func writeCSV(records []nodeRecord) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"name", "ansible_host", "ansible_user", "ansible_port", "python_interpreter", "ssh_common_args", "become_method", "roles"})
+	for _, r := range records {
+		port := ""
+		if r.AnsiblePort != 0 {
+			port = strconv.Itoa(r.AnsiblePort)
+		}
+		w.Write([]string{r.Name, r.AnsibleHost, r.AnsibleUser, port, r.PythonInterpreter, r.SSHCommonArgs, r.BecomeMethod, strings.Join(r.Roles, ";")})
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// parseYAML reads nodes from a YAML list using the export's field names.
+func parseYAML(content string) ([]nodeRecord, error) {
+	dec := yaml.NewDecoder(strings.NewReader(content))
+	dec.KnownFields(true)
+	var records []nodeRecord
+	if err := dec.Decode(&records); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	return records, nil
+}
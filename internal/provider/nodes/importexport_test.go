@@ -0,0 +1,280 @@
+package nodes
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"github.com/bengrewell/aether-webui/internal/store"
+)
+
+const importINI = `[all]
+node1 ansible_host=10.0.0.1 ansible_user=aether ansible_password=pw1 ansible_sudo_pass=sudo1
+node2 ansible_host=10.0.0.2 ansible_user=aether
+
+[master_nodes]
+node1
+
+[worker_nodes]
+node2
+`
+
+func importNodes(t *testing.T, p *Nodes, format, content, onConflict string, dryRun bool) (*NodeImportOutput, error) {
+	t.Helper()
+	in := &NodeImportInput{DryRun: dryRun}
+	in.Body.Format = format
+	in.Body.Content = content
+	in.Body.OnConflict = onConflict
+	return p.HandleImport(t.Context(), in)
+}
+
+func wantStatus(t *testing.T, err error, code int) {
+	t.Helper()
+	var se huma.StatusError
+	if !errors.As(err, &se) || se.GetStatus() != code {
+		t.Fatalf("error = %v, want status %d", err, code)
+	}
+}
+
+func TestHandleImport_INI(t *testing.T) {
+	p := newTestProvider(t)
+
+	preview, err := importNodes(t, p, FormatINI, importINI, "", true)
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if !preview.Body.DryRun || preview.Body.Created != 2 || len(preview.Body.Nodes) != 2 {
+		t.Fatalf("preview = %+v", preview.Body)
+	}
+	if list, _ := p.HandleList(t.Context(), nil); len(list.Body) != 0 {
+		t.Fatalf("dry run created %d nodes", len(list.Body))
+	}
+
+	out, err := importNodes(t, p, FormatINI, importINI, "", false)
+	if err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	if out.Body.Created != 2 || out.Body.Nodes[0].ID == "" {
+		t.Fatalf("import = %+v", out.Body)
+	}
+	node, ok, err := p.Store().GetNode(t.Context(), out.Body.Nodes[0].ID)
+	if err != nil || !ok {
+		t.Fatalf("GetNode: %v, %v", ok, err)
+	}
+	if string(node.Password) != "pw1" || string(node.SudoPassword) != "sudo1" || !reflect.DeepEqual(node.Roles, []string{"master"}) {
+		t.Errorf("node1 = %+v", node)
+	}
+	if n := out.Body.Nodes[1]; n.HasPassword || !reflect.DeepEqual(n.Roles, []string{"worker"}) {
+		t.Errorf("node2 = %+v", n)
+	}
+}
+
+func TestHandleImport_Conflicts(t *testing.T) {
+	p := newTestProvider(t)
+	if _, err := importNodes(t, p, FormatINI, importINI, "", false); err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	csv := "name,ansible_host,ansible_user,roles\nnode1,10.0.0.9,ubuntu,master;worker\nnode3,10.0.0.3,ubuntu,\n"
+
+	_, err := importNodes(t, p, FormatCSV, csv, "", false)
+	wantStatus(t, err, 409)
+
+	preview, err := importNodes(t, p, FormatCSV, csv, "", true)
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if preview.Body.Conflicts != 1 || !preview.Body.Nodes[0].Conflict || preview.Body.Nodes[0].Action != "skip" {
+		t.Errorf("preview = %+v", preview.Body)
+	}
+
+	out, err := importNodes(t, p, FormatCSV, csv, OnConflictSkip, false)
+	if err != nil {
+		t.Fatalf("skip: %v", err)
+	}
+	if out.Body.Created != 1 || out.Body.Skipped != 1 {
+		t.Errorf("skip = %+v", out.Body)
+	}
+
+	out, err = importNodes(t, p, FormatCSV, csv, OnConflictUpdate, false)
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if out.Body.Updated != 2 {
+		t.Errorf("update = %+v", out.Body)
+	}
+	node, _, _ := p.Store().GetNode(t.Context(), out.Body.Nodes[0].ID)
+	if node.AnsibleHost != "10.0.0.9" || !reflect.DeepEqual(node.Roles, []string{"master", "worker"}) {
+		t.Errorf("updated node1 = %+v", node)
+	}
+	// Secrets the file lacks are kept.
+	if string(node.Password) != "pw1" {
+		t.Errorf("password = %q, want kept", node.Password)
+	}
+}
+
+func TestHandleImport_Invalid(t *testing.T) {
+	p := newTestProvider(t)
+	content := `
+- name: node1
+  ansible_host: 10.0.0.1
+  ansible_user: aether
+  roles: [bogus]
+- name: node2
+  ansible_host: 10.0.0.2
+- name: node3
+  ansible_host: 10.0.0.3
+  ansible_user: aether
+- name: node3
+  ansible_host: 10.0.0.4
+  ansible_user: aether
+`
+	_, err := importNodes(t, p, FormatYAML, content, "", false)
+	wantStatus(t, err, 422)
+
+	preview, err := importNodes(t, p, FormatYAML, content, "", true)
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	var errs []string
+	for _, n := range preview.Body.Nodes {
+		errs = append(errs, n.Error)
+	}
+	if !strings.Contains(errs[0], "invalid role") || !strings.Contains(errs[1], "ansible_user") ||
+		errs[2] != "" || !strings.Contains(errs[3], "duplicate") {
+		t.Errorf("errors = %q", errs)
+	}
+
+	for _, tt := range []struct{ format, content string }{
+		{FormatYAML, "- name: node1\n  address: 10.0.0.1\n"},
+		{FormatCSV, "name,address\nnode1,10.0.0.1\n"},
+		{FormatINI, "# nothing here\n"},
+	} {
+		_, err := importNodes(t, p, tt.format, tt.content, "", true)
+		wantStatus(t, err, 422)
+	}
+}
+
+func TestHandleImport_Vars(t *testing.T) {
+	p := newTestProvider(t)
+	content := `[all]
+node1 ansible_host=10.0.0.1 ansible_user=aether ansible_port=2222 ansible_ssh_common_args='-o ProxyJump=bastion' data_iface=ens18
+
+[master_nodes]
+node1
+`
+	out, err := importNodes(t, p, FormatINI, content, "", false)
+	if err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	node, _, _ := p.Store().GetNode(t.Context(), out.Body.Nodes[0].ID)
+	want := store.NodeVars{AnsiblePort: 2222, SSHCommonArgs: "-o ProxyJump=bastion", HostVars: map[string]string{"data_iface": "ens18"}}
+	if !reflect.DeepEqual(node.NodeVars, want) {
+		t.Errorf("vars = %+v, want %+v", node.NodeVars, want)
+	}
+
+	// Exports carry the vars back, except CSV's host vars.
+	for _, format := range []string{FormatINI, FormatCSV, FormatYAML} {
+		exp, err := p.HandleExport(t.Context(), &NodeExportInput{Format: format})
+		if err != nil {
+			t.Fatalf("export %s: %v", format, err)
+		}
+		q := newTestProvider(t)
+		back, err := importNodes(t, q, format, exp.Body.Content, "", false)
+		if err != nil {
+			t.Fatalf("re-import %s: %v\n%s", format, err, exp.Body.Content)
+		}
+		got, _, _ := q.Store().GetNode(t.Context(), back.Body.Nodes[0].ID)
+		wantFormat := want
+		if format == FormatCSV {
+			wantFormat.HostVars = nil
+		}
+		if !reflect.DeepEqual(got.NodeVars, wantFormat) {
+			t.Errorf("%s round trip vars = %+v\n%s", format, got.NodeVars, exp.Body.Content)
+		}
+	}
+
+	// Variables that cannot be written to hosts.ini are refused.
+	_, err = importNodes(t, p, FormatYAML, "- name: node2\n  ansible_host: 10.0.0.2\n  ansible_user: aether\n  host_vars: {bad-name: x}\n", "", false)
+	wantStatus(t, err, 422)
+}
+
+func TestHandleImport_Topology(t *testing.T) {
+	p := newTestProvider(t)
+	if _, err := importNodes(t, p, FormatINI, importINI, "", false); err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	count := func() int {
+		t.Helper()
+		list, err := p.HandleList(t.Context(), nil)
+		if err != nil {
+			t.Fatalf("HandleList: %v", err)
+		}
+		return len(list.Body)
+	}
+
+	// A second master or conflicting roles fail the whole import, and nothing
+	// is written.
+	for _, csv := range []string{
+		"name,ansible_host,ansible_user,roles\nnode3,10.0.0.3,ubuntu,worker\nnode4,10.0.0.4,ubuntu,master\n",
+		"name,ansible_host,ansible_user,roles\nnode3,10.0.0.3,ubuntu,worker\nnode4,10.0.0.4,ubuntu,gnbsim;oai\n",
+	} {
+		preview, err := importNodes(t, p, FormatCSV, csv, "", true)
+		if err != nil {
+			t.Fatalf("dry run: %v", err)
+		}
+		if len(preview.Body.TopologyErrors) == 0 {
+			t.Errorf("preview topology errors = %+v", preview.Body)
+		}
+		_, err = importNodes(t, p, FormatCSV, csv, "", false)
+		wantStatus(t, err, 422)
+		if n := count(); n != 2 {
+			t.Errorf("failed import left %d nodes, want 2", n)
+		}
+	}
+
+	// Moving the master role within the file is checked against the result.
+	csv := "name,ansible_host,ansible_user,roles\nnode1,10.0.0.1,aether,worker\nnode2,10.0.0.2,aether,master\n"
+	if _, err := importNodes(t, p, FormatCSV, csv, OnConflictUpdate, false); err != nil {
+		t.Fatalf("swap master: %v", err)
+	}
+}
+
+func TestHandleExport(t *testing.T) {
+	p := newTestProvider(t)
+	if _, err := importNodes(t, p, FormatINI, importINI, "", false); err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	if err := p.Store().SetGroupVars(t.Context(), "", "master", map[string]string{"ntp_server": "pool.ntp.org"}); err != nil {
+		t.Fatalf("SetGroupVars: %v", err)
+	}
+	ini, err := p.HandleExport(t.Context(), &NodeExportInput{Format: FormatINI})
+	if err != nil {
+		t.Fatalf("export ini: %v", err)
+	}
+	if !strings.Contains(ini.Body.Content, "[master_nodes:vars]\nntp_server=") {
+		t.Errorf("ini export is missing the group vars:\n%s", ini.Body.Content)
+	}
+
+	for _, format := range []string{FormatINI, FormatCSV, FormatYAML} {
+		out, err := p.HandleExport(t.Context(), &NodeExportInput{Format: format})
+		if err != nil {
+			t.Fatalf("export %s: %v", format, err)
+		}
+		if strings.Contains(out.Body.Content, "pw1") || strings.Contains(out.Body.Content, "sudo1") {
+			t.Errorf("%s export holds secrets:\n%s", format, out.Body.Content)
+		}
+		// Every export imports back into a fresh workspace unchanged.
+		q := newTestProvider(t)
+		back, err := importNodes(t, q, format, out.Body.Content, "", false)
+		if err != nil {
+			t.Fatalf("re-import %s: %v\n%s", format, err, out.Body.Content)
+		}
+		if back.Body.Created != 2 || !reflect.DeepEqual(back.Body.Nodes[0].Roles, []string{"master"}) ||
+			back.Body.Nodes[1].AnsibleHost != "10.0.0.2" {
+			t.Errorf("%s round trip = %+v", format, back.Body.Nodes)
+		}
+	}
+}
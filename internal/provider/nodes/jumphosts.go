@@ -0,0 +1,265 @@
+package nodes
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	internalssh "github.com/bengrewell/aether-webui/internal/ssh"
+	"github.com/bengrewell/aether-webui/internal/store"
+)
+
+// jumpHostSyncWarning is returned with every jump host change: the SSH
+// settings in hosts.ini are only rewritten by inventory sync.
+const jumpHostSyncWarning = "hosts.ini still has the previous jump host settings until the next inventory sync"
+
+// jumpHostNamePattern limits jump host names to what can be used as an
+// ssh_config alias and a file name in the OnRamp checkout.
+var jumpHostNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+func (n *Nodes) HandleListJumpHosts(ctx context.Context, in *JumpHostListInput) (*JumpHostListOutput, error) {
+	var name string
+	if in != nil {
+		name = in.Workspace
+	}
+	ws, err := n.ResolveWorkspace(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	hosts, err := n.Store().ListJumpHosts(ctx, ws.Name)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("failed to list jump hosts", err)
+	}
+	out := make([]JumpHost, len(hosts))
+	for i, j := range hosts {
+		out[i] = jumpHostFrom(j)
+	}
+	return &JumpHostListOutput{Body: out}, nil
+}
+
+func (n *Nodes) HandleGetJumpHost(ctx context.Context, in *JumpHostGetInput) (*JumpHostGetOutput, error) {
+	j, err := n.getJumpHost(ctx, in.Workspace, in.Name)
+	if err != nil {
+		return nil, err
+	}
+	return &JumpHostGetOutput{Body: jumpHostFrom(j)}, nil
+}
+
+func (n *Nodes) HandleCreateJumpHost(ctx context.Context, in *JumpHostCreateInput) (*JumpHostCreateOutput, error) {
+	ws, err := n.ResolveWorkspace(ctx, in.Workspace)
+	if err != nil {
+		return nil, err
+	}
+	if err := n.CheckChangeLock(ctx, ws.Name); err != nil {
+		return nil, err
+	}
+	j := store.JumpHost{
+		Workspace: ws.Name,
+		Name:      in.Body.Name,
+		Host:      in.Body.Host,
+		Port:      in.Body.Port,
+		User:      in.Body.User,
+		Password:  []byte(in.Body.Password),
+		SSHKey:    []byte(in.Body.SSHKey),
+		Via:       in.Body.Via,
+		Default:   in.Body.Default,
+	}
+	if err := n.validateJumpHostFields(ctx, j); err != nil {
+		return nil, err
+	}
+	if _, ok, err := n.Store().GetJumpHost(ctx, ws.Name, j.Name); err != nil {
+		return nil, huma.Error500InternalServerError("failed to get jump host", err)
+	} else if ok {
+		return nil, huma.Error409Conflict(fmt.Sprintf("jump host %s already exists", j.Name))
+	}
+	if err := n.Store().UpsertJumpHost(ctx, j); err != nil {
+		return nil, huma.Error500InternalServerError("failed to create jump host", err)
+	}
+	created, err := n.getJumpHost(ctx, ws.Name, j.Name)
+	if err != nil {
+		return nil, err
+	}
+	return &JumpHostCreateOutput{Body: JumpHostResult{
+		JumpHost: jumpHostFrom(created),
+		Warnings: []string{jumpHostSyncWarning},
+	}}, nil
+}
+
+func (n *Nodes) HandleUpdateJumpHost(ctx context.Context, in *JumpHostUpdateInput) (*JumpHostUpdateOutput, error) {
+	existing, err := n.getJumpHost(ctx, in.Workspace, in.Name)
+	if err != nil {
+		return nil, err
+	}
+	if err := n.CheckChangeLock(ctx, existing.Workspace); err != nil {
+		return nil, err
+	}
+
+	if in.Body.Host != nil {
+		existing.Host = *in.Body.Host
+	}
+	if in.Body.Port != nil {
+		existing.Port = *in.Body.Port
+	}
+	if in.Body.User != nil {
+		existing.User = *in.Body.User
+	}
+	if in.Body.Password != nil {
+		existing.Password = []byte(*in.Body.Password)
+	}
+	if in.Body.SSHKey != nil {
+		existing.SSHKey = []byte(*in.Body.SSHKey)
+	}
+	if in.Body.Via != nil {
+		existing.Via = *in.Body.Via
+	}
+	if in.Body.Default != nil {
+		existing.Default = *in.Body.Default
+	}
+	if err := n.validateJumpHostFields(ctx, existing); err != nil {
+		return nil, err
+	}
+	existing.UpdatedAt = time.Time{}
+
+	if err := n.Store().UpsertJumpHost(ctx, existing); err != nil {
+		return nil, huma.Error500InternalServerError("failed to update jump host", err)
+	}
+	updated, err := n.getJumpHost(ctx, existing.Workspace, existing.Name)
+	if err != nil {
+		return nil, err
+	}
+	return &JumpHostUpdateOutput{Body: JumpHostResult{
+		JumpHost: jumpHostFrom(updated),
+		Warnings: []string{jumpHostSyncWarning},
+	}}, nil
+}
+
+func (n *Nodes) HandleDeleteJumpHost(ctx context.Context, in *JumpHostDeleteInput) (*JumpHostDeleteOutput, error) {
+	j, err := n.getJumpHost(ctx, in.Workspace, in.Name)
+	if err != nil {
+		return nil, err
+	}
+	if err := n.CheckChangeLock(ctx, j.Workspace); err != nil {
+		return nil, err
+	}
+
+	// Nodes and jump hosts that use it would be left unreachable. The
+	// default is used by every node that names no jump host.
+	var users []string
+	infos, err := n.Store().ListNodes(ctx, j.Workspace)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("failed to list nodes", err)
+	}
+	for _, info := range infos {
+		switch {
+		case info.JumpHost == j.Name:
+			users = append(users, "node "+info.Name)
+		case info.JumpHost == "" && j.Default:
+			users = append(users, "node "+info.Name+" (as the default)")
+		}
+	}
+	hosts, err := n.Store().ListJumpHosts(ctx, j.Workspace)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("failed to list jump hosts", err)
+	}
+	for _, h := range hosts {
+		if h.Via == j.Name {
+			users = append(users, "jump host "+h.Name)
+		}
+	}
+	if len(users) > 0 {
+		return nil, huma.Error409Conflict(fmt.Sprintf("jump host %s is used by %s", j.Name, strings.Join(users, ", ")))
+	}
+
+	if err := n.Store().DeleteJumpHost(ctx, j.Workspace, j.Name); err != nil {
+		return nil, huma.Error500InternalServerError("failed to delete jump host", err)
+	}
+	out := &JumpHostDeleteOutput{}
+	out.Body.Message = fmt.Sprintf("jump host %s deleted", j.Name)
+	out.Body.Warnings = []string{jumpHostSyncWarning}
+	return out, nil
+}
+
+// ---------------------------------------------------------------------------
+// Helpers
+// ---------------------------------------------------------------------------
+
+// getJumpHost loads a jump host of a workspace and returns a 404 if it is
+// missing.
+func (n *Nodes) getJumpHost(ctx context.Context, workspace, name string) (store.JumpHost, error) {
+	ws, err := n.ResolveWorkspace(ctx, workspace)
+	if err != nil {
+		return store.JumpHost{}, err
+	}
+	j, ok, err := n.Store().GetJumpHost(ctx, ws.Name, name)
+	if err != nil {
+		return store.JumpHost{}, huma.Error500InternalServerError("failed to get jump host", err)
+	}
+	if !ok {
+		return store.JumpHost{}, huma.Error404NotFound("jump host not found", fmt.Errorf("no jump host named %s", name))
+	}
+	return j, nil
+}
+
+// validateJumpHostFields checks a jump host about to be saved: its fields go
+// into an ssh_config, and the jump host it is reached through must exist
+// without leading back to it.
+func (n *Nodes) validateJumpHostFields(ctx context.Context, j store.JumpHost) error {
+	switch {
+	case !jumpHostNamePattern.MatchString(j.Name) || j.Name == store.JumpHostNone:
+		return huma.Error422UnprocessableEntity(fmt.Sprintf("invalid jump host name %q", j.Name))
+	case j.Host == "":
+		return huma.Error422UnprocessableEntity("host is required")
+	case j.User == "":
+		return huma.Error422UnprocessableEntity("user is required")
+	case strings.ContainsAny(j.Host+j.User, " \t\r\n\"'"):
+		return huma.Error422UnprocessableEntity("host and user must not contain spaces or quotes")
+	case j.Port < 0 || j.Port > 65535:
+		return huma.Error422UnprocessableEntity(fmt.Sprintf("port %d is out of range", j.Port))
+	case j.Via == j.Name:
+		return huma.Error422UnprocessableEntity(fmt.Sprintf("jump host %s cannot be reached through itself", j.Name))
+	case j.Via == "":
+		return nil
+	}
+	chain, err := internalssh.JumpChain(ctx, n.Store(), j.Workspace, j.Via)
+	if err != nil {
+		return huma.Error422UnprocessableEntity(fmt.Sprintf("via: %v", err))
+	}
+	for _, hop := range chain {
+		if hop.Name == j.Name {
+			return huma.Error422UnprocessableEntity(fmt.Sprintf("jump host %s cannot be reached through itself", j.Name))
+		}
+	}
+	return nil
+}
+
+// validateJumpHost checks that a node of workspace can be reached through
+// the jump host it names.
+func (n *Nodes) validateJumpHost(ctx context.Context, workspace, name string) error {
+	if name == "" || name == store.JumpHostNone {
+		return nil
+	}
+	if _, err := internalssh.JumpChain(ctx, n.Store(), workspace, name); err != nil {
+		return huma.Error422UnprocessableEntity(fmt.Sprintf("jump_host: %v", err))
+	}
+	return nil
+}
+
+func jumpHostFrom(j store.JumpHost) JumpHost {
+	return JumpHost{
+		Workspace:   j.Workspace,
+		Name:        j.Name,
+		Host:        j.Host,
+		Port:        j.Port,
+		User:        j.User,
+		Via:         j.Via,
+		Default:     j.Default,
+		HasPassword: len(j.Password) > 0,
+		HasSSHKey:   len(j.SSHKey) > 0,
+		CreatedAt:   j.CreatedAt,
+		UpdatedAt:   j.UpdatedAt,
+	}
+}
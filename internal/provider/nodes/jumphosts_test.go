@@ -0,0 +1,142 @@
+package nodes
+
+import (
+	"testing"
+)
+
+func createJumpHost(t *testing.T, p *Nodes, name, via string) JumpHost {
+	t.Helper()
+	in := &JumpHostCreateInput{}
+	in.Body.Name = name
+	in.Body.Host = "203.0.113.1"
+	in.Body.User = "ops"
+	in.Body.SSHKey = "KEY"
+	in.Body.Via = via
+	out, err := p.HandleCreateJumpHost(t.Context(), in)
+	if err != nil {
+		t.Fatalf("HandleCreateJumpHost(%s): %v", name, err)
+	}
+	if len(out.Body.Warnings) == 0 {
+		t.Errorf("create %s: no warning that hosts.ini is stale", name)
+	}
+	return out.Body.JumpHost
+}
+
+func TestJumpHostCRUD(t *testing.T) {
+	p := newTestProvider(t)
+	ctx := t.Context()
+
+	gw := createJumpHost(t, p, "gw", "")
+	if gw.Workspace != "default" || !gw.HasSSHKey || gw.HasPassword {
+		t.Errorf("created = %+v", gw)
+	}
+	createJumpHost(t, p, "lab", "gw")
+
+	dup := &JumpHostCreateInput{}
+	dup.Body.Name, dup.Body.Host, dup.Body.User = "gw", "198.51.100.1", "ops"
+	_, err := p.HandleCreateJumpHost(ctx, dup)
+	wantStatus(t, err, 409)
+
+	for name, body := range map[string]func(*JumpHostCreateInput){
+		"reserved name": func(in *JumpHostCreateInput) { in.Body.Name = "none" },
+		"bad name":      func(in *JumpHostCreateInput) { in.Body.Name = "a/b" },
+		"missing via":   func(in *JumpHostCreateInput) { in.Body.Via = "nope" },
+		"spaced host":   func(in *JumpHostCreateInput) { in.Body.Host = "a b" },
+	} {
+		in := &JumpHostCreateInput{}
+		in.Body.Name, in.Body.Host, in.Body.User = "x", "198.51.100.1", "ops"
+		body(in)
+		_, err := p.HandleCreateJumpHost(ctx, in)
+		if err == nil {
+			t.Errorf("%s: expected error", name)
+			continue
+		}
+		wantStatus(t, err, 422)
+	}
+
+	// gw through lab would loop.
+	upd := &JumpHostUpdateInput{Name: "gw"}
+	lab := "lab"
+	upd.Body.Via = &lab
+	_, err = p.HandleUpdateJumpHost(ctx, upd)
+	wantStatus(t, err, 422)
+
+	upd = &JumpHostUpdateInput{Name: "gw"}
+	def, pw := true, "pw"
+	upd.Body.Default, upd.Body.Password = &def, &pw
+	out, err := p.HandleUpdateJumpHost(ctx, upd)
+	if err != nil {
+		t.Fatalf("HandleUpdateJumpHost: %v", err)
+	}
+	if !out.Body.Default || !out.Body.HasPassword || !out.Body.HasSSHKey || len(out.Body.Warnings) == 0 {
+		t.Errorf("updated = %+v", out.Body)
+	}
+
+	list, err := p.HandleListJumpHosts(ctx, &JumpHostListInput{})
+	if err != nil {
+		t.Fatalf("HandleListJumpHosts: %v", err)
+	}
+	if len(list.Body) != 2 || list.Body[0].Name != "gw" || list.Body[1].Via != "gw" {
+		t.Errorf("list = %+v", list.Body)
+	}
+
+	// A node naming lab keeps it, and lab keeps gw.
+	in := &NodeCreateInput{}
+	in.Body.Name, in.Body.AnsibleHost, in.Body.AnsibleUser = "node1", "10.0.0.1", "ubuntu"
+	in.Body.Password, in.Body.SudoPassword = "secret", "sudosecret"
+	in.Body.JumpHost = "lab"
+	node, err := p.HandleCreate(ctx, in)
+	if err != nil {
+		t.Fatalf("HandleCreate: %v", err)
+	}
+	if node.Body.JumpHost != "lab" {
+		t.Errorf("JumpHost = %q, want lab", node.Body.JumpHost)
+	}
+	_, err = p.HandleDeleteJumpHost(ctx, &JumpHostDeleteInput{Name: "lab"})
+	wantStatus(t, err, 409)
+	_, err = p.HandleDeleteJumpHost(ctx, &JumpHostDeleteInput{Name: "gw"})
+	wantStatus(t, err, 409)
+
+	none := "none"
+	nodeUpd := &NodeUpdateInput{ID: node.Body.ID}
+	nodeUpd.Body.JumpHost = &none
+	if _, err := p.HandleUpdate(ctx, nodeUpd); err != nil {
+		t.Fatalf("HandleUpdate: %v", err)
+	}
+	del, err := p.HandleDeleteJumpHost(ctx, &JumpHostDeleteInput{Name: "lab"})
+	if err != nil {
+		t.Fatalf("HandleDeleteJumpHost(lab): %v", err)
+	}
+	if len(del.Body.Warnings) == 0 {
+		t.Error("delete: no warning that hosts.ini is stale")
+	}
+
+	// A node naming no jump host uses gw as the default.
+	in.Body.Name, in.Body.AnsibleHost, in.Body.JumpHost = "node2", "10.0.0.2", ""
+	if _, err := p.HandleCreate(ctx, in); err != nil {
+		t.Fatalf("HandleCreate: %v", err)
+	}
+	_, err = p.HandleDeleteJumpHost(ctx, &JumpHostDeleteInput{Name: "gw"})
+	wantStatus(t, err, 409)
+	upd = &JumpHostUpdateInput{Name: "gw"}
+	def = false
+	upd.Body.Default = &def
+	if _, err := p.HandleUpdateJumpHost(ctx, upd); err != nil {
+		t.Fatalf("HandleUpdateJumpHost: %v", err)
+	}
+	if _, err := p.HandleDeleteJumpHost(ctx, &JumpHostDeleteInput{Name: "gw"}); err != nil {
+		t.Fatalf("HandleDeleteJumpHost(gw): %v", err)
+	}
+	_, err = p.HandleGetJumpHost(ctx, &JumpHostGetInput{Name: "gw"})
+	wantStatus(t, err, 404)
+}
+
+func TestHandleCreate_UnknownJumpHost(t *testing.T) {
+	p := newTestProvider(t)
+	in := &NodeCreateInput{}
+	in.Body.Name, in.Body.AnsibleHost, in.Body.AnsibleUser = "node1", "10.0.0.1", "ubuntu"
+	in.Body.Password, in.Body.SudoPassword = "secret", "sudosecret"
+	in.Body.JumpHost = "gw"
+	_, err := p.HandleCreate(t.Context(), in)
+	wantStatus(t, err, 422)
+}
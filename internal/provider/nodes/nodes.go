@@ -1,26 +1,81 @@
 package nodes
 
 import (
+	"context"
+	"sync"
+	"time"
+
 	"github.com/bengrewell/aether-webui/internal/endpoint"
 	"github.com/bengrewell/aether-webui/internal/provider"
+	"github.com/bengrewell/aether-webui/internal/provider/onramp"
+	"github.com/bengrewell/aether-webui/internal/store"
 )
 
 var _ provider.Provider = (*Nodes)(nil)
 
+// Config holds settings for the nodes provider.
+type Config struct {
+	// HealthInterval is how often every node is probed for SSH
+	// reachability; zero disables the health monitor.
+	HealthInterval time.Duration
+}
+
 // Nodes is a provider for managing cluster nodes and their role assignments.
 type Nodes struct {
 	*provider.Base
+	config    Config
 	endpoints []endpoint.AnyEndpoint
+	roles     RoleSource
+	topology  TopologySource
+	probe     func(ctx context.Context, node store.Node) store.NodeHealth
+	cancel    context.CancelFunc
+	done      chan struct{} // closed when the health monitor exits
+	stopOnce  sync.Once
+}
+
+// RoleSource returns the node roles valid in a workspace. Roles are the
+// hosts.ini groups of the workspace's OnRamp checkout.
+type RoleSource func(ctx context.Context, workspace string) ([]string, error)
+
+// SetRoleSource replaces the role source used to validate role assignments.
+// It must be called before the provider starts serving requests.
+func (n *Nodes) SetRoleSource(fn RoleSource) {
+	n.roles = fn
+}
+
+// TopologySource checks node role assignments against the topology rules of
+// a workspace's OnRamp checkout.
+type TopologySource func(ctx context.Context, workspace string, nodes []onramp.TopologyNode) (onramp.TopologyResult, error)
+
+// SetTopologySource replaces the topology source used to validate role
+// assignments. It must be called before the provider starts serving requests.
+func (n *Nodes) SetTopologySource(fn TopologySource) {
+	n.topology = fn
+}
+
+// defaultRoles accepts the roles of OnRamp's built-in component registry.
+func defaultRoles(context.Context, string) ([]string, error) {
+	return onramp.DefaultRoles(), nil
+}
+
+// defaultTopology checks against the rules of OnRamp's built-in component
+// registry with nothing deployed.
+func defaultTopology(_ context.Context, _ string, nodes []onramp.TopologyNode) (onramp.TopologyResult, error) {
+	return onramp.CheckBuiltinTopology(nodes), nil
 }
 
 // NewProvider creates a new Nodes provider with all CRUD endpoints registered.
-func NewProvider(opts ...provider.Option) *Nodes {
+func NewProvider(cfg Config, opts ...provider.Option) *Nodes {
 	n := &Nodes{
 		Base:      provider.New("nodes", opts...),
-		endpoints: make([]endpoint.AnyEndpoint, 0, 5),
+		config:    cfg,
+		endpoints: make([]endpoint.AnyEndpoint, 0, 10),
+		roles:     defaultRoles,
+		topology:  defaultTopology,
 	}
+	n.probe = n.probeWithJumps
 
-	provider.Register(n.Base, endpoint.Endpoint[struct{}, ManagedNodeListOutput]{
+	provider.Register(n.Base, endpoint.Endpoint[NodeListInput, ManagedNodeListOutput]{
 		Desc: endpoint.Descriptor{
 			OperationID: "nodes-list",
 			Semantics:   endpoint.Read,
@@ -80,6 +135,126 @@ func NewProvider(opts ...provider.Option) *Nodes {
 		Handler: n.HandleDelete,
 	})
 
+	provider.Register(n.Base, endpoint.Endpoint[JumpHostListInput, JumpHostListOutput]{
+		Desc: endpoint.Descriptor{
+			OperationID: "jump-hosts-list",
+			Semantics:   endpoint.Read,
+			Summary:     "List jump hosts",
+			Description: "Returns the SSH jump hosts of a workspace with secret-presence flags.",
+			Tags:        []string{"nodes"},
+			HTTP:        endpoint.HTTPHint{Path: "/api/v1/jump-hosts"},
+		},
+		Handler: n.HandleListJumpHosts,
+	})
+
+	provider.Register(n.Base, endpoint.Endpoint[JumpHostGetInput, JumpHostGetOutput]{
+		Desc: endpoint.Descriptor{
+			OperationID: "jump-hosts-get",
+			Semantics:   endpoint.Read,
+			Summary:     "Get a jump host",
+			Description: "Returns a single jump host with secret-presence flags.",
+			Tags:        []string{"nodes"},
+			HTTP:        endpoint.HTTPHint{Path: "/api/v1/jump-hosts/{name}"},
+		},
+		Handler: n.HandleGetJumpHost,
+	})
+
+	provider.Register(n.Base, endpoint.Endpoint[JumpHostCreateInput, JumpHostCreateOutput]{
+		Desc: endpoint.Descriptor{
+			OperationID: "jump-hosts-create",
+			Semantics:   endpoint.Create,
+			Summary:     "Create a jump host",
+			Description: "Creates an SSH jump host that nodes, or other jump hosts, are reached through.",
+			Tags:        []string{"nodes"},
+			HTTP:        endpoint.HTTPHint{Path: "/api/v1/jump-hosts"},
+		},
+		Handler: n.HandleCreateJumpHost,
+	})
+
+	provider.Register(n.Base, endpoint.Endpoint[JumpHostUpdateInput, JumpHostUpdateOutput]{
+		Desc: endpoint.Descriptor{
+			OperationID: "jump-hosts-update",
+			Semantics:   endpoint.Update,
+			Summary:     "Update a jump host",
+			Description: "Partial update — merges non-nil fields.",
+			Tags:        []string{"nodes"},
+			HTTP:        endpoint.HTTPHint{Path: "/api/v1/jump-hosts/{name}"},
+		},
+		Handler: n.HandleUpdateJumpHost,
+	})
+
+	provider.Register(n.Base, endpoint.Endpoint[JumpHostDeleteInput, JumpHostDeleteOutput]{
+		Desc: endpoint.Descriptor{
+			OperationID: "jump-hosts-delete",
+			Semantics:   endpoint.Delete,
+			Summary:     "Delete a jump host",
+			Description: "Deletes a jump host no node or other jump host names.",
+			Tags:        []string{"nodes"},
+			HTTP:        endpoint.HTTPHint{Path: "/api/v1/jump-hosts/{name}"},
+		},
+		Handler: n.HandleDeleteJumpHost,
+	})
+
+	provider.Register(n.Base, endpoint.Endpoint[NodeImportInput, NodeImportOutput]{
+		Desc: endpoint.Descriptor{
+			OperationID: "nodes-import",
+			Semantics:   endpoint.Action,
+			Summary:     "Import nodes",
+			Description: "Creates or updates nodes, with roles and credentials, from a hosts.ini, CSV or YAML file. Reports nodes whose names already exist; dry_run previews the result.",
+			Tags:        []string{"nodes"},
+			HTTP:        endpoint.HTTPHint{Path: "/api/v1/nodes/import"},
+		},
+		Handler: n.HandleImport,
+	})
+
+	provider.Register(n.Base, endpoint.Endpoint[NodeExportInput, NodeExportOutput]{
+		Desc: endpoint.Descriptor{
+			OperationID: "nodes-export",
+			Semantics:   endpoint.Read,
+			Summary:     "Export nodes",
+			Description: "Returns the workspace's nodes as a hosts.ini, CSV or YAML file without their secrets.",
+			Tags:        []string{"nodes"},
+			HTTP:        endpoint.HTTPHint{Path: "/api/v1/nodes/export"},
+		},
+		Handler: n.HandleExport,
+	})
+
+	provider.Register(n.Base, endpoint.Endpoint[NodeHealthEventsInput, NodeHealthEventsOutput]{
+		Desc: endpoint.Descriptor{
+			OperationID: "nodes-list-health-events",
+			Semantics:   endpoint.Read,
+			Summary:     "List node health changes",
+			Description: "Returns changes of the workspace's node health statuses, newest first.",
+			Tags:        []string{"nodes"},
+			HTTP:        endpoint.HTTPHint{Path: "/api/v1/nodes/health/events"},
+		},
+		Handler: n.HandleListHealthEvents,
+	})
+
+	provider.Register(n.Base, endpoint.Endpoint[NodeHealthGetInput, NodeHealthGetOutput]{
+		Desc: endpoint.Descriptor{
+			OperationID: "nodes-get-health",
+			Semantics:   endpoint.Read,
+			Summary:     "Get node health",
+			Description: "Returns the node's latest health probe: SSH port, login and sudo, with its recent status changes.",
+			Tags:        []string{"nodes"},
+			HTTP:        endpoint.HTTPHint{Path: "/api/v1/nodes/{id}/health"},
+		},
+		Handler: n.HandleGetHealth,
+	})
+
+	provider.Register(n.Base, endpoint.Endpoint[NodeHealthCheckInput, NodeHealthCheckOutput]{
+		Desc: endpoint.Descriptor{
+			OperationID: "nodes-check-health",
+			Semantics:   endpoint.Action,
+			Summary:     "Probe node health",
+			Description: "Probes the node now instead of waiting for the health monitor, records the result and returns it.",
+			Tags:        []string{"nodes"},
+			HTTP:        endpoint.HTTPHint{Path: "/api/v1/nodes/{id}/health/check"},
+		},
+		Handler: n.HandleCheckHealth,
+	})
+
 	return n
 }
 
@@ -1,10 +1,12 @@
 package nodes
 
 import (
+	"context"
 	"strings"
 	"testing"
 
 	"github.com/bengrewell/aether-webui/internal/provider"
+	"github.com/bengrewell/aether-webui/internal/provider/onramp"
 	"github.com/bengrewell/aether-webui/internal/store"
 )
 
@@ -17,7 +19,7 @@ func newTestProvider(t *testing.T) *Nodes {
 		t.Fatalf("store.New: %v", err)
 	}
 	t.Cleanup(func() { st.Close() })
-	return NewProvider(provider.WithStore(st))
+	return NewProvider(Config{}, provider.WithStore(st))
 }
 
 // ---------------------------------------------------------------------------
@@ -31,8 +33,8 @@ func TestNewProvider_ImplementsInterface(t *testing.T) {
 func TestNewProvider_EndpointCount(t *testing.T) {
 	p := newTestProvider(t)
 	descs := p.Base.Descriptors()
-	if len(descs) != 5 {
-		t.Errorf("registered %d endpoints, want 5", len(descs))
+	if len(descs) != 15 {
+		t.Errorf("registered %d endpoints, want 15", len(descs))
 	}
 }
 
@@ -40,11 +42,21 @@ func TestNewProvider_EndpointPaths(t *testing.T) {
 	p := newTestProvider(t)
 
 	wantOps := map[string]string{
-		"nodes-list":   "/api/v1/nodes",
-		"nodes-get":    "/api/v1/nodes/{id}",
-		"nodes-create": "/api/v1/nodes",
-		"nodes-update": "/api/v1/nodes/{id}",
-		"nodes-delete": "/api/v1/nodes/{id}",
+		"nodes-list":               "/api/v1/nodes",
+		"nodes-get":                "/api/v1/nodes/{id}",
+		"nodes-create":             "/api/v1/nodes",
+		"nodes-update":             "/api/v1/nodes/{id}",
+		"nodes-delete":             "/api/v1/nodes/{id}",
+		"nodes-import":             "/api/v1/nodes/import",
+		"nodes-export":             "/api/v1/nodes/export",
+		"nodes-list-health-events": "/api/v1/nodes/health/events",
+		"nodes-get-health":         "/api/v1/nodes/{id}/health",
+		"nodes-check-health":       "/api/v1/nodes/{id}/health/check",
+		"jump-hosts-list":          "/api/v1/jump-hosts",
+		"jump-hosts-get":           "/api/v1/jump-hosts/{name}",
+		"jump-hosts-create":        "/api/v1/jump-hosts",
+		"jump-hosts-update":        "/api/v1/jump-hosts/{name}",
+		"jump-hosts-delete":        "/api/v1/jump-hosts/{name}",
 	}
 
 	descs := p.Base.Descriptors()
@@ -330,6 +342,55 @@ func TestHandleUpdate_InvalidRole(t *testing.T) {
 	}
 }
 
+func TestHandleUpdate_Vars(t *testing.T) {
+	p := newTestProvider(t)
+
+	in := &NodeCreateInput{}
+	in.Body.Name = "node1"
+	in.Body.AnsibleHost = "10.0.0.1"
+	in.Body.AnsibleUser = "aether"
+	in.Body.Password = "aether"
+	in.Body.SudoPassword = "aether"
+	in.Body.AnsiblePort = 2222
+	in.Body.SSHCommonArgs = "-o ProxyJump=bastion"
+	in.Body.HostVars = map[string]string{"data_iface": "ens18"}
+	created, err := p.HandleCreate(t.Context(), in)
+	if err != nil {
+		t.Fatalf("handleCreate: %v", err)
+	}
+	if created.Body.AnsiblePort != 2222 || created.Body.SSHCommonArgs != "-o ProxyJump=bastion" ||
+		created.Body.HostVars["data_iface"] != "ens18" {
+		t.Errorf("created = %+v", created.Body)
+	}
+
+	// Omitted fields are kept; host_vars replaces the whole set.
+	become := "su"
+	upd := &NodeUpdateInput{ID: created.Body.ID}
+	upd.Body.BecomeMethod = &become
+	upd.Body.HostVars = map[string]string{}
+	out, err := p.HandleUpdate(t.Context(), upd)
+	if err != nil {
+		t.Fatalf("handleUpdate: %v", err)
+	}
+	if out.Body.BecomeMethod != "su" || out.Body.AnsiblePort != 2222 || out.Body.HostVars != nil {
+		t.Errorf("updated = %+v", out.Body)
+	}
+	list, _ := p.HandleList(t.Context(), &NodeListInput{})
+	if list.Body[0].BecomeMethod != "su" || list.Body[0].AnsiblePort != 2222 {
+		t.Errorf("listed = %+v", list.Body[0])
+	}
+
+	upd = &NodeUpdateInput{ID: created.Body.ID}
+	upd.Body.HostVars = map[string]string{"ansible_password": "pw"}
+	_, err = p.HandleUpdate(t.Context(), upd)
+	wantStatus(t, err, 422)
+
+	in.Body.Name = "node2"
+	in.Body.AnsiblePort = 70000
+	_, err = p.HandleCreate(t.Context(), in)
+	wantStatus(t, err, 422)
+}
+
 // ---------------------------------------------------------------------------
 // Delete handler
 // ---------------------------------------------------------------------------
@@ -365,25 +426,102 @@ func TestHandleDelete(t *testing.T) {
 // ---------------------------------------------------------------------------
 
 func TestValidateRoles_AllValid(t *testing.T) {
-	for role := range ValidRoles {
-		if err := validateRoles([]string{role}); err != nil {
+	p := newTestProvider(t)
+	for _, role := range onramp.DefaultRoles() {
+		if err := p.validateRoles(t.Context(), "", []string{role}); err != nil {
 			t.Errorf("validateRoles(%q): %v", role, err)
 		}
 	}
 }
 
 func TestValidateRoles_Invalid(t *testing.T) {
-	if err := validateRoles([]string{"invalid"}); err == nil {
+	p := newTestProvider(t)
+	if err := p.validateRoles(t.Context(), "", []string{"invalid"}); err == nil {
 		t.Error("expected error for invalid role")
 	}
 }
 
+func TestValidateRoles_RoleSource(t *testing.T) {
+	p := newTestProvider(t)
+	var gotWorkspace string
+	p.SetRoleSource(func(_ context.Context, workspace string) ([]string, error) {
+		gotWorkspace = workspace
+		return []string{"master", "foo"}, nil
+	})
+	if err := p.validateRoles(t.Context(), "lab2", []string{"foo"}); err != nil {
+		t.Errorf("validateRoles(foo): %v", err)
+	}
+	if gotWorkspace != "lab2" {
+		t.Errorf("role source workspace = %q, want lab2", gotWorkspace)
+	}
+	if err := p.validateRoles(t.Context(), "lab2", []string{"gnbsim"}); err == nil {
+		t.Error("expected error for role the source does not list")
+	}
+}
+
 func TestValidateRoles_Empty(t *testing.T) {
-	if err := validateRoles(nil); err != nil {
+	p := newTestProvider(t)
+	if err := p.validateRoles(t.Context(), "", nil); err != nil {
 		t.Errorf("validateRoles(nil): %v", err)
 	}
 }
 
+func createWithRoles(t *testing.T, p *Nodes, name string, roles ...string) (*NodeCreateOutput, error) {
+	t.Helper()
+	in := &NodeCreateInput{}
+	in.Body.Name = name
+	in.Body.AnsibleHost = "10.0.0.1"
+	in.Body.AnsibleUser = "aether"
+	in.Body.Password = "aether"
+	in.Body.SudoPassword = "aether"
+	in.Body.Roles = roles
+	return p.HandleCreate(t.Context(), in)
+}
+
+func TestHandleCreate_Topology(t *testing.T) {
+	p := newTestProvider(t)
+
+	// A worker may come before its master; that rule is checked at sync.
+	if _, err := createWithRoles(t, p, "node1", "worker"); err != nil {
+		t.Fatalf("create worker: %v", err)
+	}
+	master, err := createWithRoles(t, p, "node2", "master")
+	if err != nil {
+		t.Fatalf("create master: %v", err)
+	}
+	if _, err := createWithRoles(t, p, "node3", "master"); err == nil || !strings.Contains(err.Error(), "topology") {
+		t.Errorf("second master: err = %v", err)
+	}
+	if _, err := createWithRoles(t, p, "node4", "gnbsim", "oai"); err == nil {
+		t.Error("expected error for conflicting RAN roles")
+	}
+
+	// Updating the master's own roles does not count it twice.
+	in := &NodeUpdateInput{ID: master.Body.ID}
+	in.Body.Roles = []string{"master", "gnbsim"}
+	if _, err := p.HandleUpdate(t.Context(), in); err != nil {
+		t.Errorf("update master: %v", err)
+	}
+}
+
+func TestHandleCreate_TopologySource(t *testing.T) {
+	p := newTestProvider(t)
+	var gotWorkspace string
+	p.SetTopologySource(func(_ context.Context, workspace string, nodes []onramp.TopologyNode) (onramp.TopologyResult, error) {
+		gotWorkspace = workspace
+		return onramp.TopologyResult{Errors: []onramp.TopologyIssue{
+			{Rule: onramp.TopologyRoleMax, Role: "worker", Nodes: []string{nodes[0].Name}},
+			{Rule: onramp.TopologyRoleRequires, Role: "worker", Nodes: []string{nodes[0].Name}},
+		}}, nil
+	})
+	if _, err := createWithRoles(t, p, "node1", "worker"); err == nil {
+		t.Error("expected error from topology source")
+	}
+	if gotWorkspace != store.DefaultWorkspace {
+		t.Errorf("topology source workspace = %q, want %q", gotWorkspace, store.DefaultWorkspace)
+	}
+}
+
 // ---------------------------------------------------------------------------
 // generateID
 // ---------------------------------------------------------------------------
@@ -401,3 +539,71 @@ func TestGenerateID_Unique(t *testing.T) {
 		ids[id] = true
 	}
 }
+
+func TestHandleCreate_ChangeLocked(t *testing.T) {
+	p := newTestProvider(t)
+	if _, err := p.Store().AcquireLock(t.Context(), store.Lock{
+		Name:     store.ChangeLockName,
+		HolderID: "dep-1",
+		Kind:     store.LockKindDeployment,
+	}); err != nil {
+		t.Fatalf("AcquireLock: %v", err)
+	}
+
+	in := &NodeCreateInput{}
+	in.Body.Name = "node1"
+	in.Body.AnsibleHost = "10.0.0.1"
+	_, err := p.HandleCreate(t.Context(), in)
+	if err == nil || !strings.Contains(err.Error(), "locked") {
+		t.Fatalf("err = %v, want change lock error", err)
+	}
+}
+
+func TestHandlers_ScopedByWorkspace(t *testing.T) {
+	p := newTestProvider(t)
+	if err := p.Store().CreateWorkspace(t.Context(), store.Workspace{Name: "lab2", OnRampDir: t.TempDir()}); err != nil {
+		t.Fatalf("CreateWorkspace: %v", err)
+	}
+
+	// The same name may be reused across workspaces.
+	var lab2ID string
+	for _, ws := range []string{"", "lab2"} {
+		in := &NodeCreateInput{}
+		in.Workspace = ws
+		in.Body.Name = "node1"
+		in.Body.AnsibleHost = "10.0.0.1"
+		in.Body.AnsibleUser = "ubuntu"
+		in.Body.Password = "secret"
+		in.Body.SudoPassword = "sudosecret"
+		out, err := p.HandleCreate(t.Context(), in)
+		if err != nil {
+			t.Fatalf("HandleCreate(%q): %v", ws, err)
+		}
+		if ws == "lab2" {
+			lab2ID = out.Body.ID
+			if out.Body.Workspace != "lab2" {
+				t.Errorf("Workspace = %q, want lab2", out.Body.Workspace)
+			}
+		}
+	}
+
+	list, err := p.HandleList(t.Context(), &NodeListInput{WorkspaceParam{Workspace: "lab2"}})
+	if err != nil {
+		t.Fatalf("HandleList: %v", err)
+	}
+	if len(list.Body) != 1 || list.Body[0].ID != lab2ID {
+		t.Errorf("lab2 nodes = %+v", list.Body)
+	}
+
+	// A node is invisible from other workspaces.
+	if _, err := p.HandleGet(t.Context(), &NodeGetInput{ID: lab2ID}); err == nil {
+		t.Error("expected lab2 node to be hidden from the default workspace")
+	}
+	if _, err := p.HandleDelete(t.Context(), &NodeDeleteInput{ID: lab2ID}); err == nil {
+		t.Error("expected delete from the default workspace to fail")
+	}
+
+	if _, err := p.HandleList(t.Context(), &NodeListInput{WorkspaceParam{Workspace: "nope"}}); err == nil {
+		t.Error("expected unknown workspace to fail")
+	}
+}
@@ -1,43 +1,56 @@
 package nodes
 
-import "time"
+import (
+	"time"
 
-// ValidRoles are the Ansible inventory group names corresponding to hosts.ini sections.
-var ValidRoles = map[string]bool{
-	"master":  true,
-	"worker":  true,
-	"gnbsim":  true,
-	"oai":     true,
-	"ueransim": true,
-	"srsran":  true,
-	"oscric":  true,
-	"n3iwf":   true,
-}
+	"github.com/bengrewell/aether-webui/internal/provider/onramp"
+)
 
 // ManagedNode is the API-facing representation of a cluster node.
 // Secrets are never returned; only boolean presence flags are exposed.
 type ManagedNode struct {
-	ID              string    `json:"id"`
-	Name            string    `json:"name"`
-	AnsibleHost     string    `json:"ansible_host"`
-	AnsibleUser     string    `json:"ansible_user"`
-	HasPassword     bool      `json:"has_password"`
-	HasSudoPassword bool      `json:"has_sudo_password"`
-	HasSSHKey       bool      `json:"has_ssh_key"`
-	Roles           []string  `json:"roles"`
-	CreatedAt       time.Time `json:"created_at"`
-	UpdatedAt       time.Time `json:"updated_at"`
+	ID                string            `json:"id"`
+	Workspace         string            `json:"workspace"`
+	Name              string            `json:"name"`
+	AnsibleHost       string            `json:"ansible_host"`
+	AnsibleUser       string            `json:"ansible_user"`
+	AnsiblePort       int               `json:"ansible_port,omitempty"`
+	PythonInterpreter string            `json:"python_interpreter,omitempty"`
+	SSHCommonArgs     string            `json:"ssh_common_args,omitempty"`
+	BecomeMethod      string            `json:"become_method,omitempty"`
+	HostVars          map[string]string `json:"host_vars,omitempty"`
+	JumpHost          string            `json:"jump_host,omitempty" doc:"Jump host the node is reached through; empty uses the workspace default, none connects directly"`
+	HasPassword       bool              `json:"has_password"`
+	HasSudoPassword   bool              `json:"has_sudo_password"`
+	HasSSHKey         bool              `json:"has_ssh_key"`
+	Roles             []string          `json:"roles"`
+	Health            string            `json:"health" enum:"unknown,healthy,degraded,unreachable" doc:"Status from the latest health probe"`
+	HealthCheckedAt   *time.Time        `json:"health_checked_at,omitempty" doc:"When the node was last probed"`
+	LastSeen          *time.Time        `json:"last_seen,omitempty" doc:"When an SSH login to the node last succeeded"`
+	CreatedAt         time.Time         `json:"created_at"`
+	UpdatedAt         time.Time         `json:"updated_at"`
 }
 
 // ---------------------------------------------------------------------------
 // Huma I/O types
 // ---------------------------------------------------------------------------
 
+// WorkspaceParam selects the OnRamp workspace a node request applies to.
+// Node names are unique within a workspace.
+type WorkspaceParam struct {
+	Workspace string `query:"workspace" doc:"Workspace name; omit for the default workspace" example:"lab2"`
+}
+
+type NodeListInput struct {
+	WorkspaceParam
+}
+
 type ManagedNodeListOutput struct {
 	Body []ManagedNode
 }
 
 type NodeGetInput struct {
+	WorkspaceParam
 	ID string `path:"id" doc:"Node ID"`
 }
 
@@ -46,14 +59,21 @@ type NodeGetOutput struct {
 }
 
 type NodeCreateInput struct {
+	WorkspaceParam
 	Body struct {
-		Name         string   `json:"name" doc:"Unique node name (Ansible inventory hostname)"`
-		AnsibleHost  string   `json:"ansible_host" doc:"IP or hostname for SSH"`
-		AnsibleUser  string   `json:"ansible_user,omitempty" doc:"SSH username"`
-		Password     string   `json:"password,omitempty" doc:"SSH password"`
-		SudoPassword string   `json:"sudo_password,omitempty" doc:"Sudo password"`
-		SSHKey       string   `json:"ssh_key,omitempty" doc:"SSH private key"`
-		Roles        []string `json:"roles,omitempty" doc:"Role assignments"`
+		Name              string            `json:"name" doc:"Node name, unique within the workspace (Ansible inventory hostname)"`
+		AnsibleHost       string            `json:"ansible_host" doc:"IP or hostname for SSH"`
+		AnsibleUser       string            `json:"ansible_user,omitempty" doc:"SSH username"`
+		AnsiblePort       int               `json:"ansible_port,omitempty" doc:"SSH port; omit for Ansible's default"`
+		PythonInterpreter string            `json:"python_interpreter,omitempty" doc:"ansible_python_interpreter, e.g. /usr/bin/python3"`
+		SSHCommonArgs     string            `json:"ssh_common_args,omitempty" doc:"ansible_ssh_common_args, e.g. -o ProxyJump=bastion"`
+		BecomeMethod      string            `json:"become_method,omitempty" doc:"ansible_become_method, e.g. sudo or su"`
+		HostVars          map[string]string `json:"host_vars,omitempty" doc:"Other inventory variables for the node's hosts.ini line; passwords are refused"`
+		JumpHost          string            `json:"jump_host,omitempty" doc:"Jump host to reach the node through; omit for the workspace default, none to connect directly"`
+		Password          string            `json:"password,omitempty" doc:"SSH password"`
+		SudoPassword      string            `json:"sudo_password,omitempty" doc:"Sudo password"`
+		SSHKey            string            `json:"ssh_key,omitempty" doc:"SSH private key"`
+		Roles             []string          `json:"roles,omitempty" doc:"Role assignments"`
 	}
 }
 
@@ -62,15 +82,22 @@ type NodeCreateOutput struct {
 }
 
 type NodeUpdateInput struct {
+	WorkspaceParam
 	ID   string `path:"id" doc:"Node ID"`
 	Body struct {
-		Name         *string  `json:"name,omitempty" doc:"Unique node name"`
-		AnsibleHost  *string  `json:"ansible_host,omitempty" doc:"IP or hostname for SSH"`
-		AnsibleUser  *string  `json:"ansible_user,omitempty" doc:"SSH username"`
-		Password     *string  `json:"password,omitempty" doc:"SSH password (set to empty string to clear)"`
-		SudoPassword *string  `json:"sudo_password,omitempty" doc:"Sudo password (set to empty string to clear)"`
-		SSHKey       *string  `json:"ssh_key,omitempty" doc:"SSH private key (set to empty string to clear)"`
-		Roles        []string `json:"roles,omitempty" doc:"Role assignments (replaces entire set)"`
+		Name              *string           `json:"name,omitempty" doc:"Unique node name"`
+		AnsibleHost       *string           `json:"ansible_host,omitempty" doc:"IP or hostname for SSH"`
+		AnsibleUser       *string           `json:"ansible_user,omitempty" doc:"SSH username"`
+		AnsiblePort       *int              `json:"ansible_port,omitempty" doc:"SSH port (set to 0 for Ansible's default)"`
+		PythonInterpreter *string           `json:"python_interpreter,omitempty" doc:"ansible_python_interpreter (set to empty string to clear)"`
+		SSHCommonArgs     *string           `json:"ssh_common_args,omitempty" doc:"ansible_ssh_common_args (set to empty string to clear)"`
+		BecomeMethod      *string           `json:"become_method,omitempty" doc:"ansible_become_method (set to empty string to clear)"`
+		HostVars          map[string]string `json:"host_vars,omitempty" doc:"Other inventory variables (replaces entire set; {} clears)"`
+		JumpHost          *string           `json:"jump_host,omitempty" doc:"Jump host name (set to empty string for the workspace default, none to connect directly)"`
+		Password          *string           `json:"password,omitempty" doc:"SSH password (set to empty string to clear)"`
+		SudoPassword      *string           `json:"sudo_password,omitempty" doc:"Sudo password (set to empty string to clear)"`
+		SSHKey            *string           `json:"ssh_key,omitempty" doc:"SSH private key (set to empty string to clear)"`
+		Roles             []string          `json:"roles,omitempty" doc:"Role assignments (replaces entire set)"`
 	}
 }
 
@@ -79,6 +106,7 @@ type NodeUpdateOutput struct {
 }
 
 type NodeDeleteInput struct {
+	WorkspaceParam
 	ID string `path:"id" doc:"Node ID"`
 }
 
@@ -87,3 +115,223 @@ type NodeDeleteOutput struct {
 		Message string `json:"message"`
 	}
 }
+
+// ---------------------------------------------------------------------------
+// Jump hosts
+// ---------------------------------------------------------------------------
+
+// JumpHost is the API-facing representation of an SSH jump host.
+// Secrets are never returned; only boolean presence flags are exposed.
+type JumpHost struct {
+	Workspace   string    `json:"workspace"`
+	Name        string    `json:"name"`
+	Host        string    `json:"host"`
+	Port        int       `json:"port,omitempty"`
+	User        string    `json:"user"`
+	Via         string    `json:"via,omitempty" doc:"Jump host this one is reached through"`
+	Default     bool      `json:"default" doc:"Used by the workspace's nodes that name no jump host"`
+	HasPassword bool      `json:"has_password"`
+	HasSSHKey   bool      `json:"has_ssh_key"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// JumpHostResult is a created or updated jump host. Warnings note what
+// the change does not do by itself, such as updating hosts.ini.
+type JumpHostResult struct {
+	JumpHost
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+type JumpHostListInput struct {
+	WorkspaceParam
+}
+
+type JumpHostListOutput struct {
+	Body []JumpHost
+}
+
+type JumpHostGetInput struct {
+	WorkspaceParam
+	Name string `path:"name" doc:"Jump host name"`
+}
+
+type JumpHostGetOutput struct {
+	Body JumpHost
+}
+
+type JumpHostCreateInput struct {
+	WorkspaceParam
+	Body struct {
+		Name     string `json:"name" doc:"Jump host name, unique within the workspace"`
+		Host     string `json:"host" doc:"IP or hostname for SSH"`
+		Port     int    `json:"port,omitempty" doc:"SSH port; omit for 22"`
+		User     string `json:"user" doc:"SSH username"`
+		Password string `json:"password,omitempty" doc:"SSH password; Ansible needs sshpass on the daemon host to use it"`
+		SSHKey   string `json:"ssh_key,omitempty" doc:"SSH private key"`
+		Via      string `json:"via,omitempty" doc:"Jump host to reach this one through"`
+		Default  bool   `json:"default,omitempty" doc:"Use for the workspace's nodes that name no jump host"`
+	}
+}
+
+type JumpHostCreateOutput struct {
+	Body JumpHostResult
+}
+
+type JumpHostUpdateInput struct {
+	WorkspaceParam
+	Name string `path:"name" doc:"Jump host name"`
+	Body struct {
+		Host     *string `json:"host,omitempty" doc:"IP or hostname for SSH"`
+		Port     *int    `json:"port,omitempty" doc:"SSH port (set to 0 for 22)"`
+		User     *string `json:"user,omitempty" doc:"SSH username"`
+		Password *string `json:"password,omitempty" doc:"SSH password (set to empty string to clear)"`
+		SSHKey   *string `json:"ssh_key,omitempty" doc:"SSH private key (set to empty string to clear)"`
+		Via      *string `json:"via,omitempty" doc:"Jump host to reach this one through (set to empty string to connect directly)"`
+		Default  *bool   `json:"default,omitempty" doc:"Use for the workspace's nodes that name no jump host"`
+	}
+}
+
+type JumpHostUpdateOutput struct {
+	Body JumpHostResult
+}
+
+type JumpHostDeleteInput struct {
+	WorkspaceParam
+	Name string `path:"name" doc:"Jump host name"`
+}
+
+type JumpHostDeleteOutput struct {
+	Body struct {
+		Message  string   `json:"message"`
+		Warnings []string `json:"warnings,omitempty"`
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Import / export
+// ---------------------------------------------------------------------------
+
+// Node file formats accepted by import and produced by export.
+const (
+	FormatINI  = "ini"
+	FormatCSV  = "csv"
+	FormatYAML = "yaml"
+)
+
+// How import treats a node whose name already exists in the workspace.
+const (
+	OnConflictError  = "error"
+	OnConflictSkip   = "skip"
+	OnConflictUpdate = "update"
+)
+
+type NodeImportInput struct {
+	WorkspaceParam
+	DryRun bool `query:"dry_run" default:"false" doc:"Preview the import without writing anything"`
+	Body   struct {
+		Format     string `json:"format" enum:"ini,csv,yaml" doc:"Format of content: an Ansible hosts.ini, CSV with a header row, or a YAML list of nodes"`
+		Content    string `json:"content" doc:"File contents to import"`
+		OnConflict string `json:"on_conflict,omitempty" enum:"error,skip,update" default:"error" doc:"What to do with nodes whose name already exists: fail the import, skip them, or update them"`
+	}
+}
+
+// ImportedNode is one node of an import and what the import does with it.
+// Secrets are never returned; only boolean presence flags are exposed.
+type ImportedNode struct {
+	ID              string   `json:"id,omitempty" doc:"ID of the existing or created node"`
+	Name            string   `json:"name"`
+	AnsibleHost     string   `json:"ansible_host"`
+	AnsibleUser     string   `json:"ansible_user"`
+	HasPassword     bool     `json:"has_password"`
+	HasSudoPassword bool     `json:"has_sudo_password"`
+	HasSSHKey       bool     `json:"has_ssh_key"`
+	Roles           []string `json:"roles"`
+	Action          string   `json:"action" enum:"create,update,skip" doc:"What the import does with the node"`
+	Conflict        bool     `json:"conflict,omitempty" doc:"A node with this name already exists in the workspace"`
+	Error           string   `json:"error,omitempty" doc:"Why the node cannot be imported"`
+}
+
+type NodeImportOutput struct {
+	Body struct {
+		DryRun         bool                   `json:"dry_run,omitempty"`
+		Nodes          []ImportedNode         `json:"nodes"`
+		Created        int                    `json:"created"`
+		Updated        int                    `json:"updated"`
+		Skipped        int                    `json:"skipped"`
+		Conflicts      int                    `json:"conflicts"`
+		TopologyErrors []onramp.TopologyIssue `json:"topology_errors,omitempty" doc:"Topology rules the imported node roles break; a dry run reports them, an import fails with 422"`
+	}
+}
+
+type NodeExportInput struct {
+	WorkspaceParam
+	Format string `query:"format" enum:"ini,csv,yaml" default:"ini" doc:"Export format"`
+}
+
+type NodeExportOutput struct {
+	Body struct {
+		Format  string `json:"format"`
+		Content string `json:"content" doc:"Exported nodes; secrets are never included"`
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Health
+// ---------------------------------------------------------------------------
+
+// NodeHealth is the latest health probe of a node.
+type NodeHealth struct {
+	NodeID    string     `json:"node_id"`
+	Status    string     `json:"status" enum:"unknown,healthy,degraded,unreachable"`
+	TCP       bool       `json:"tcp" doc:"The SSH port accepted a connection"`
+	SSH       bool       `json:"ssh" doc:"Login with the stored credentials succeeded"`
+	Sudo      bool       `json:"sudo" doc:"sudo with the stored sudo password succeeded"`
+	LatencyMS int64      `json:"latency_ms" doc:"Time to connect to the SSH port"`
+	Error     string     `json:"error,omitempty" doc:"Why the first failing step failed"`
+	CheckedAt *time.Time `json:"checked_at,omitempty"`
+	Since     *time.Time `json:"since,omitempty" doc:"When the node entered its status"`
+	LastSeen  *time.Time `json:"last_seen,omitempty" doc:"When an SSH login last succeeded"`
+}
+
+// NodeHealthEvent is a change of a node's health status.
+type NodeHealthEvent struct {
+	ID     int64     `json:"id"`
+	NodeID string    `json:"node_id"`
+	From   string    `json:"from" enum:"unknown,healthy,degraded,unreachable"`
+	To     string    `json:"to" enum:"unknown,healthy,degraded,unreachable"`
+	Error  string    `json:"error,omitempty"`
+	At     time.Time `json:"at"`
+}
+
+type NodeHealthGetInput struct {
+	WorkspaceParam
+	ID     string `path:"id" doc:"Node ID"`
+	Events int    `query:"events" default:"20" minimum:"0" maximum:"500" doc:"Number of recent status changes to include"`
+}
+
+type NodeHealthGetOutput struct {
+	Body struct {
+		NodeHealth
+		Events []NodeHealthEvent `json:"events"`
+	}
+}
+
+type NodeHealthCheckInput struct {
+	WorkspaceParam
+	ID string `path:"id" doc:"Node ID"`
+}
+
+type NodeHealthCheckOutput struct {
+	Body NodeHealth
+}
+
+type NodeHealthEventsInput struct {
+	WorkspaceParam
+	NodeID string `query:"node_id" doc:"Only changes of this node"`
+	Limit  int    `query:"limit" default:"100" minimum:"1" maximum:"1000" doc:"Maximum number of changes to return"`
+}
+
+type NodeHealthEventsOutput struct {
+	Body []NodeHealthEvent
+}
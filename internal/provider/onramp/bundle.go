@@ -0,0 +1,633 @@
+package onramp
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"github.com/bengrewell/aether-webui/internal/store"
+)
+
+// maxBundleBytes caps the size of an uploaded OnRamp bundle, and
+// bundleReadTimeout allows for slow links at remote sites.
+const (
+	maxBundleBytes    = 1 << 30
+	bundleReadTimeout = 10 * time.Minute
+)
+
+// provenanceFile is written inside the checkout's .git directory when it was
+// imported from a bundle. Its presence keeps ensureRepo from contacting the
+// remote.
+const provenanceFile = "aether-bundle.json"
+
+// errBundleRejected marks bundles that failed verification or are not a
+// usable OnRamp checkout. Handlers map it to a 422.
+var errBundleRejected = errors.New("bundle rejected")
+
+// bundleSource describes an archive to import and the credentials it must
+// match.
+type bundleSource struct {
+	Source    string // upload or file
+	Filename  string
+	SHA256    string
+	Signature string
+}
+
+// Resolve hands the handler the request body as a stream. Huma would read a
+// RawBody field into memory first.
+func (in *RepoBundleImportInput) Resolve(ctx huma.Context) []error {
+	// Without a body field huma does not apply the operation's read timeout.
+	_ = ctx.SetReadDeadline(time.Now().Add(bundleReadTimeout))
+	in.Bundle = ctx.BodyReader()
+	return nil
+}
+
+// HandleImportRepoBundle replaces a workspace's checkout with an uploaded
+// tarball so sites without internet access can install OnRamp.
+func (o *OnRamp) HandleImportRepoBundle(ctx context.Context, in *RepoBundleImportInput) (*RepoBundleImportOutput, error) {
+	ws, err := o.workspace(ctx, in.Workspace)
+	if err != nil {
+		return nil, err
+	}
+	if err := o.CheckChangeLock(ctx, ws.name); err != nil {
+		return nil, err
+	}
+	if err := ws.requireIdle(); err != nil {
+		return nil, err
+	}
+	if in.Bundle == nil {
+		return nil, huma.Error422UnprocessableEntity("request body must contain the bundle")
+	}
+	if !in.Force {
+		if err := o.requireReplaceable(ctx, ws); err != nil {
+			return nil, err
+		}
+	}
+
+	// The archive is streamed to disk; bundles can be far larger than the
+	// daemon should hold in memory.
+	f, err := os.CreateTemp("", "onramp-bundle-*")
+	if err != nil {
+		return nil, huma.Error500InternalServerError("failed to buffer bundle", err)
+	}
+	defer os.Remove(f.Name())
+	n, err := io.Copy(f, io.LimitReader(in.Bundle, maxBundleBytes+1))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	switch {
+	case err != nil:
+		return nil, huma.Error500InternalServerError("failed to buffer bundle", err)
+	case n == 0:
+		return nil, huma.Error422UnprocessableEntity("request body must contain the bundle")
+	case n > maxBundleBytes:
+		return nil, huma.Error413RequestEntityTooLarge(fmt.Sprintf("bundle exceeds %d bytes", int64(maxBundleBytes)))
+	}
+
+	status, err := o.installBundle(ctx, ws, f.Name(), bundleSource{
+		Source:    "upload",
+		Filename:  filepath.Base(in.Filename),
+		SHA256:    in.SHA256,
+		Signature: in.Signature,
+	})
+	if err != nil {
+		if errors.Is(err, errBundleRejected) {
+			return nil, huma.Error422UnprocessableEntity(err.Error())
+		}
+		return nil, huma.Error500InternalServerError("failed to import bundle", err)
+	}
+	return &RepoBundleImportOutput{Body: status}, nil
+}
+
+// requireReplaceable returns a 409 when replacing the checkout would strand
+// installed components or discard local changes. Changes to the files the
+// daemon writes are carried over by installBundle.
+func (o *OnRamp) requireReplaceable(ctx context.Context, ws *workspace) error {
+	installed, err := o.installedComponents(ctx, ws.name)
+	if err != nil {
+		return huma.Error500InternalServerError("failed to check component state", err)
+	}
+	if len(installed) > 0 {
+		return huma.Error409Conflict(fmt.Sprintf(
+			"components installed: %s; uninstall them or set force to import anyway",
+			strings.Join(installed, ", ")))
+	}
+	dir := ws.currentConfig().OnRampDir
+	if requireClone(dir) == nil {
+		if _, err := requireUnmodified(dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// importStartupBundle imports Config.BundlePath into the default workspace.
+// An archive that was already imported is skipped so restarts keep the
+// current checkout and pin.
+func (o *OnRamp) importStartupBundle() error {
+	ws := o.defaultWorkspace()
+	digest, err := fileSHA256(o.config.BundlePath)
+	if err != nil {
+		return err
+	}
+	if prov := readProvenance(ws.currentConfig().OnRampDir); prov != nil && prov.SHA256 == hex.EncodeToString(digest) {
+		o.Log().Info("onramp bundle already imported", "bundle", o.config.BundlePath, "version", prov.Version)
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), bundleReadTimeout)
+	defer cancel()
+	_, err = o.installBundle(ctx, ws, o.config.BundlePath, bundleSource{
+		Source:    "file",
+		Filename:  filepath.Base(o.config.BundlePath),
+		SHA256:    o.config.BundleSHA256,
+		Signature: o.config.BundleSignature,
+	})
+	return err
+}
+
+// installBundle verifies the archive, unpacks it over the workspace checkout,
+// and pins the workspace to the imported commit.
+func (o *OnRamp) installBundle(ctx context.Context, ws *workspace, archive string, src bundleSource) (RepoStatus, error) {
+	digest, err := fileSHA256(archive)
+	if err != nil {
+		return RepoStatus{}, err
+	}
+	keys, err := o.trustedKeys()
+	if err != nil {
+		return RepoStatus{}, err
+	}
+	prov := RepoProvenance{
+		Source:   src.Source,
+		Filename: src.Filename,
+		SHA256:   hex.EncodeToString(digest),
+	}
+	prov.VerifiedBy, prov.KeyID, err = verifyBundle(digest, src.SHA256, src.Signature, keys)
+	if err != nil {
+		return RepoStatus{}, err
+	}
+
+	cfg := ws.currentConfig()
+	log := o.Log().With("workspace", ws.name)
+	log.Info("importing onramp bundle", "file", src.Filename, "sha256", prov.SHA256, "verified_by", prov.VerifiedBy)
+
+	// The workspace's config and node secrets are carried over to the new
+	// checkout.
+	defer LockVars(cfg.OnRampDir)()
+	var keep []string
+	if requireClone(cfg.OnRampDir) == nil {
+		if _, managed, err := localChanges(cfg.OnRampDir); err == nil {
+			keep = managed
+		}
+	}
+	secrets, _ := filepath.Glob(filepath.Join(cfg.OnRampDir, "host_vars", "*", secretsFile))
+	for _, p := range secrets {
+		if rel, err := filepath.Rel(cfg.OnRampDir, p); err == nil {
+			keep = append(keep, filepath.ToSlash(rel))
+		}
+	}
+	saved, err := saveManagedFiles(cfg.OnRampDir, keep)
+	if err != nil {
+		return RepoStatus{}, fmt.Errorf("save config files: %w", err)
+	}
+	if err := unpackBundle(archive, cfg, &prov); err != nil {
+		return RepoStatus{}, err
+	}
+	if err := restoreManagedFiles(cfg.OnRampDir, saved); err != nil {
+		return RepoStatus{}, fmt.Errorf("restore config files: %w", err)
+	}
+	if err := o.persistVersion(ctx, ws.name, prov.Version); err != nil {
+		return RepoStatus{}, fmt.Errorf("save pinned version: %w", err)
+	}
+	ws.setVersion(prov.Version)
+	log.Info("onramp bundle imported", "version", prov.Version, "commit", prov.Commit)
+
+	cfg.Version = prov.Version
+	setupErr := ensureRepo(cfg, log)
+	if ws.name == store.DefaultWorkspace {
+		if setupErr != nil {
+			o.SetDegraded(fmt.Sprintf("repo setup: %v", setupErr))
+		} else {
+			o.ClearDegraded()
+		}
+	}
+	status := gatherRepoStatus(ws)
+	if setupErr != nil {
+		status.Error = setupErr.Error()
+	}
+	return status, nil
+}
+
+// trustedKeys loads the ed25519 public keys accepted for bundle signatures.
+func (o *OnRamp) trustedKeys() ([]ed25519.PublicKey, error) {
+	if o.config.TrustedKeysFile == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(o.config.TrustedKeysFile)
+	if err != nil {
+		return nil, fmt.Errorf("read trusted keys: %w", err)
+	}
+	return parseTrustedKeys(data)
+}
+
+// parseTrustedKeys decodes every PEM "PUBLIC KEY" block in data. Keys of
+// other types are an error so a misconfigured file is not silently ignored.
+func parseTrustedKeys(data []byte) ([]ed25519.PublicKey, error) {
+	var keys []ed25519.PublicKey
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "PUBLIC KEY" {
+			continue
+		}
+		pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parse trusted key: %w", err)
+		}
+		key, ok := pub.(ed25519.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("trusted key is %T, want ed25519", pub)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// verifyBundle checks the archive digest against the expected checksum and
+// signature. At least one must be supplied; when both are, both must match.
+// It returns how the bundle was verified and, for signatures, the key ID.
+func verifyBundle(digest []byte, wantSHA, signature string, keys []ed25519.PublicKey) (string, string, error) {
+	if wantSHA == "" && signature == "" {
+		return "", "", fmt.Errorf("%w: a sha256 checksum or signature is required", errBundleRejected)
+	}
+	verifiedBy := ""
+	if wantSHA != "" {
+		want, err := hex.DecodeString(strings.TrimSpace(wantSHA))
+		if err != nil || !bytes.Equal(want, digest) {
+			return "", "", fmt.Errorf("%w: sha256 mismatch: archive is %x", errBundleRejected, digest)
+		}
+		verifiedBy = "sha256"
+	}
+	if signature == "" {
+		return verifiedBy, "", nil
+	}
+	if len(keys) == 0 {
+		return "", "", fmt.Errorf("%w: signature given but no trusted keys are configured", errBundleRejected)
+	}
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(signature))
+	if err != nil {
+		sig, err = base64.URLEncoding.DecodeString(strings.TrimSpace(signature))
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("%w: signature is not valid base64", errBundleRejected)
+	}
+	for _, key := range keys {
+		if ed25519.Verify(key, digest, sig) {
+			return "signature", keyID(key), nil
+		}
+	}
+	return "", "", fmt.Errorf("%w: signature does not match any trusted key", errBundleRejected)
+}
+
+// keyID is a short fingerprint of a public key for provenance records.
+func keyID(key ed25519.PublicKey) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:8])
+}
+
+func fileSHA256(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open bundle: %w", err)
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, fmt.Errorf("read bundle: %w", err)
+	}
+	return h.Sum(nil), nil
+}
+
+// unpackBundle extracts the archive into a staging directory next to the
+// checkout, validates it, records its provenance, and swaps it into place.
+// The existing checkout is only removed once the new one is ready.
+func unpackBundle(archive string, cfg Config, prov *RepoProvenance) error {
+	dir := filepath.Clean(cfg.OnRampDir)
+	if err := os.MkdirAll(filepath.Dir(dir), 0o755); err != nil {
+		return fmt.Errorf("create workspace parent: %w", err)
+	}
+	staging, err := os.MkdirTemp(filepath.Dir(dir), "."+filepath.Base(dir)+"-import-")
+	if err != nil {
+		return fmt.Errorf("create staging dir: %w", err)
+	}
+	defer os.RemoveAll(staging)
+
+	root, err := extractBundle(archive, staging, prov)
+	if err != nil {
+		return err
+	}
+	if err := validateRepo(root); err != nil {
+		return fmt.Errorf("%w: %v", errBundleRejected, err)
+	}
+	if prov.Commit, err = gitOutput(root, "rev-parse", "HEAD"); err != nil {
+		return fmt.Errorf("%w: no commit checked out", errBundleRejected)
+	}
+	prov.Version = prov.Commit
+	if tag, err := gitOutput(root, "describe", "--tags", "--exact-match", "HEAD"); err == nil {
+		prov.Version = tag
+	}
+	prov.ImportedAt = time.Now().Unix()
+	if err := writeProvenance(root, *prov); err != nil {
+		return err
+	}
+
+	backup := filepath.Join(staging, "previous")
+	hadCheckout := false
+	if _, err := os.Lstat(dir); err == nil {
+		if err := os.Rename(dir, backup); err != nil {
+			return fmt.Errorf("move existing checkout aside: %w", err)
+		}
+		hadCheckout = true
+	}
+	if err := os.Rename(root, dir); err != nil {
+		if hadCheckout {
+			_ = os.Rename(backup, dir)
+		}
+		return fmt.Errorf("install checkout: %w", err)
+	}
+	return nil
+}
+
+// extractBundle unpacks a tar or tar.gz into staging and returns the directory
+// holding the checkout. Tarballs without a .git directory are committed into
+// a fresh repository so versions can be tracked, unless they hold a git
+// bundle set. A git bundle on its own is cloned; it holds only the top-level
+// repository, so it is refused if that has submodules.
+func extractBundle(archive, staging string, prov *RepoProvenance) (string, error) {
+	f, err := os.Open(archive)
+	if err != nil {
+		return "", fmt.Errorf("open bundle: %w", err)
+	}
+	defer f.Close()
+	br := bufio.NewReader(f)
+	head, _ := br.Peek(512)
+
+	root := filepath.Join(staging, "checkout")
+	switch {
+	case isGitBundle(head):
+		prov.Format = "git-bundle"
+		if err := cloneBundleSet(archive, "", root); err != nil {
+			return "", err
+		}
+		return root, nil
+	case bytes.HasPrefix(head, []byte{0x1f, 0x8b}):
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return "", fmt.Errorf("%w: %v", errBundleRejected, err)
+		}
+		defer zr.Close()
+		prov.Format = "tar"
+		err = extractTar(zr, root)
+		if err != nil {
+			return "", err
+		}
+	case len(head) >= 262 && string(head[257:262]) == "ustar":
+		prov.Format = "tar"
+		if err := extractTar(br, root); err != nil {
+			return "", err
+		}
+	default:
+		return "", fmt.Errorf("%w: not a tar, tar.gz or git bundle", errBundleRejected)
+	}
+
+	// Archives usually wrap the checkout in a single top-level directory.
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return "", fmt.Errorf("read staging dir: %w", err)
+	}
+	if len(entries) == 1 && entries[0].IsDir() && entries[0].Name() != ".git" {
+		root = filepath.Join(root, entries[0].Name())
+	}
+	if _, err := os.Stat(filepath.Join(root, bundleSetFile)); err == nil {
+		prov.Format = "git-bundle"
+		checkout := filepath.Join(staging, "bundle-checkout")
+		if err := cloneBundleSet(filepath.Join(root, bundleSetFile), filepath.Join(root, bundleSetModules), checkout); err != nil {
+			return "", err
+		}
+		return checkout, nil
+	}
+	if _, err := os.Stat(filepath.Join(root, ".git")); errors.Is(err, os.ErrNotExist) {
+		if err := commitSnapshot(root, prov.Filename); err != nil {
+			return "", err
+		}
+	}
+	return root, nil
+}
+
+// A git bundle set is a tar holding bundleSetFile, the bundle of the
+// top-level repository, and under bundleSetModules one bundle per submodule
+// named by its path, e.g. modules/deps/k8s.bundle. Nested submodules are
+// named by their full path from the top level.
+const (
+	bundleSetFile    = "onramp.bundle"
+	bundleSetModules = "modules"
+)
+
+func isGitBundle(head []byte) bool {
+	return bytes.HasPrefix(head, []byte("# v2 git bundle")) || bytes.HasPrefix(head, []byte("# v3 git bundle"))
+}
+
+// cloneBundleSet clones the git bundle into dst and its submodules from the
+// bundles under modules. An empty modules means there are none to use.
+// The clone keeps no remote; the submodules are pointed back at the URLs in
+// .gitmodules but are never fetched, see ensureSubmodules.
+func cloneBundleSet(bundle, modules, dst string) error {
+	if err := gitRun(filepath.Dir(dst), "clone", "--quiet", bundle, dst); err != nil {
+		return fmt.Errorf("%w: clone git bundle: %v", errBundleRejected, err)
+	}
+	if err := gitRun(dst, "remote", "remove", "origin"); err != nil {
+		return fmt.Errorf("remove bundle remote: %w", err)
+	}
+	return cloneBundleSubmodules(dst, modules, "")
+}
+
+// cloneBundleSubmodules checks out the submodules of the repository at dir
+// from their bundles, recursing into nested submodules. prefix is dir's
+// path from the top-level checkout.
+func cloneBundleSubmodules(dir, modules, prefix string) error {
+	subs, err := listSubmodules(dir)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errBundleRejected, err)
+	}
+	for _, sub := range subs {
+		full := prefix + sub.path
+		if modules == "" {
+			return fmt.Errorf("%w: a single git bundle cannot carry submodule %s; import a tar of %s and a bundle per submodule under %s/",
+				errBundleRejected, full, bundleSetFile, bundleSetModules)
+		}
+		if !filepath.IsLocal(filepath.FromSlash(full)) {
+			return fmt.Errorf("%w: unsafe submodule path %q", errBundleRejected, full)
+		}
+		bundle := filepath.Join(modules, filepath.FromSlash(full)+".bundle")
+		if _, err := os.Stat(bundle); err != nil {
+			return fmt.Errorf("%w: no bundle for submodule %s; expected %s/%s.bundle", errBundleRejected, full, bundleSetModules, full)
+		}
+		// submodule init keeps a URL that is already configured.
+		if err := gitRun(dir, "config", "submodule."+sub.name+".url", bundle); err != nil {
+			return fmt.Errorf("configure submodule %s: %w", full, err)
+		}
+		if err := gitRun(dir, "-c", "protocol.file.allow=always", "submodule", "update", "--init", "--", sub.path); err != nil {
+			return fmt.Errorf("%w: check out submodule %s from its bundle: %v", errBundleRejected, full, err)
+		}
+		if err := cloneBundleSubmodules(filepath.Join(dir, sub.path), modules, full+"/"); err != nil {
+			return err
+		}
+	}
+	if len(subs) == 0 {
+		return nil
+	}
+	if err := gitRun(dir, "submodule", "sync"); err != nil {
+		return fmt.Errorf("restore submodule URLs: %w", err)
+	}
+	return nil
+}
+
+// extractTar writes the archive under root. Entries may not escape root
+// through their names, symlink targets, or hard links; devices and other
+// special files are skipped.
+func extractTar(r io.Reader, root string) error {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return fmt.Errorf("create staging dir: %w", err)
+	}
+	rt, err := os.OpenRoot(root)
+	if err != nil {
+		return fmt.Errorf("open staging dir: %w", err)
+	}
+	defer rt.Close()
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%w: read tar: %v", errBundleRejected, err)
+		}
+		name := filepath.Clean(filepath.FromSlash(hdr.Name))
+		if name == "." {
+			continue
+		}
+		if !filepath.IsLocal(name) {
+			return fmt.Errorf("%w: unsafe path %q", errBundleRejected, hdr.Name)
+		}
+		mode := hdr.FileInfo().Mode().Perm()
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			err = rt.MkdirAll(name, 0o755)
+		case tar.TypeReg:
+			err = writeTarFile(rt, name, mode, tr)
+		case tar.TypeSymlink:
+			target := filepath.FromSlash(hdr.Linkname)
+			if filepath.IsAbs(target) || !filepath.IsLocal(filepath.Join(filepath.Dir(name), target)) {
+				return fmt.Errorf("%w: symlink %q points outside the archive", errBundleRejected, hdr.Name)
+			}
+			if err = rt.MkdirAll(filepath.Dir(name), 0o755); err == nil {
+				err = rt.Symlink(target, name)
+			}
+		case tar.TypeLink:
+			target := filepath.Clean(filepath.FromSlash(hdr.Linkname))
+			if !filepath.IsLocal(target) {
+				return fmt.Errorf("%w: hard link %q points outside the archive", errBundleRejected, hdr.Name)
+			}
+			if err = rt.MkdirAll(filepath.Dir(name), 0o755); err == nil {
+				err = rt.Link(target, name)
+			}
+		default:
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("%w: extract %s: %v", errBundleRejected, hdr.Name, err)
+		}
+	}
+}
+
+func writeTarFile(rt *os.Root, name string, mode os.FileMode, r io.Reader) error {
+	if err := rt.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+	f, err := rt.OpenFile(name, os.O_CREATE|os.O_EXCL|os.O_WRONLY, mode|0o600)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, r)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// commitSnapshot turns a plain source tree into a repository with a single
+// commit so the checkout has a version and local changes can be detected.
+func commitSnapshot(dir, filename string) error {
+	msg := "Import offline OnRamp bundle"
+	if filename != "" {
+		msg += " " + filename
+	}
+	for _, args := range [][]string{
+		{"init", "--quiet"},
+		{"add", "-A"},
+		{"-c", "user.name=aether-webd", "-c", "user.email=aether-webd@localhost", "commit", "--quiet", "-m", msg},
+	} {
+		if err := gitRun(dir, args...); err != nil {
+			return fmt.Errorf("git %s: %w", args[0], err)
+		}
+	}
+	return nil
+}
+
+func writeProvenance(dir string, prov RepoProvenance) error {
+	data, err := json.MarshalIndent(prov, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".git", provenanceFile), data, 0o644); err != nil {
+		return fmt.Errorf("write bundle provenance: %w", err)
+	}
+	return nil
+}
+
+// readProvenance returns the bundle provenance of a checkout, or nil if it
+// was cloned from the remote.
+func readProvenance(dir string) *RepoProvenance {
+	data, err := os.ReadFile(filepath.Join(dir, ".git", provenanceFile))
+	if err != nil {
+		return nil
+	}
+	var prov RepoProvenance
+	if err := json.Unmarshal(data, &prov); err != nil {
+		return nil
+	}
+	return &prov
+}
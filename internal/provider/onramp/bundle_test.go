@@ -0,0 +1,344 @@
+package onramp
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bengrewell/aether-webui/internal/provider"
+)
+
+// tarGz archives src under prefix, the way a site would package a checkout.
+func tarGz(t *testing.T, src, prefix string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(zw)
+	err := filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(src, path)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(filepath.Join(prefix, rel))
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			_, err = tw.Write(data)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("build tarball: %v", err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("close tar: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("close gzip: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func importBundle(t *testing.T, o *OnRamp, data []byte, checksum string) (*RepoBundleImportOutput, error) {
+	t.Helper()
+	return o.HandleImportRepoBundle(t.Context(), &RepoBundleImportInput{
+		Filename: "aether-onramp.tar.gz",
+		SHA256:   checksum,
+		Bundle:   bytes.NewReader(data),
+	})
+}
+
+func TestHandleImportRepoBundle_Tarball(t *testing.T) {
+	src := t.TempDir()
+	initVersionedRepo(t, src)
+	data := tarGz(t, src, "aether-onramp")
+
+	o := newTestProviderWithStore(t, "")
+	dir := o.config.OnRampDir
+	out, err := importBundle(t, o, data, sha256Hex(data))
+	if err != nil {
+		t.Fatalf("HandleImportRepoBundle: %v", err)
+	}
+	if out.Body.Error != "" || !out.Body.Cloned || out.Body.Tag != "v2.0.0" {
+		t.Fatalf("status = %+v", out.Body)
+	}
+	b := out.Body.Bundle
+	if b == nil || b.Format != "tar" || b.VerifiedBy != "sha256" || b.SHA256 != sha256Hex(data) ||
+		b.Version != "v2.0.0" || b.Source != "upload" || b.Filename != "aether-onramp.tar.gz" {
+		t.Fatalf("provenance = %+v", b)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "vars", "main.yml")); err != nil {
+		t.Errorf("checkout not unpacked into workspace: %v", err)
+	}
+
+	// The import is the pinned version and survives a restart.
+	if got := o.defaultWorkspace().currentConfig().Version; got != "v2.0.0" {
+		t.Errorf("version = %q, want v2.0.0", got)
+	}
+	o2 := NewProvider(o.config, provider.WithStore(o.Store()))
+	o2.loadPinnedVersion()
+	if got := o2.defaultWorkspace().currentConfig().Version; got != "v2.0.0" {
+		t.Errorf("pinned version after restart = %q, want v2.0.0", got)
+	}
+
+	// The fake remote is unreachable, so a refresh only succeeds offline.
+	refresh, err := o.HandleRefreshRepo(t.Context(), nil)
+	if err != nil {
+		t.Fatalf("HandleRefreshRepo: %v", err)
+	}
+	if refresh.Body.Error != "" || refresh.Body.Bundle == nil {
+		t.Errorf("refresh = %+v", refresh.Body)
+	}
+}
+
+func TestHandleImportRepoBundle_PlainTree(t *testing.T) {
+	src := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(src, "vars"), 0o755); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "Makefile"), []byte("all:\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "vars", "main.yml"), []byte(testMainYML), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	data := tarGz(t, src, "")
+
+	o := newTestProvider(t, "")
+	out, err := importBundle(t, o, data, sha256Hex(data))
+	if err != nil {
+		t.Fatalf("HandleImportRepoBundle: %v", err)
+	}
+	if out.Body.Dirty || out.Body.Commit == "" || out.Body.Bundle.Version != out.Body.Commit {
+		t.Errorf("status = %+v", out.Body)
+	}
+}
+
+// gitBundle bundles every ref of the repository at src into path.
+func gitBundle(t *testing.T, src, path string) []byte {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+	gitRunTest(t, src, "bundle", "create", path, "--all")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	return data
+}
+
+func TestHandleImportRepoBundle_GitBundle(t *testing.T) {
+	src := t.TempDir()
+	initVersionedRepo(t, src)
+	data := gitBundle(t, src, filepath.Join(t.TempDir(), "onramp.bundle"))
+
+	o := newTestProvider(t, "")
+	out, err := importBundle(t, o, data, sha256Hex(data))
+	if err != nil {
+		t.Fatalf("HandleImportRepoBundle: %v", err)
+	}
+	if out.Body.Error != "" || out.Body.Tag != "v2.0.0" || out.Body.Bundle.Format != "git-bundle" {
+		t.Fatalf("status = %+v, bundle = %+v", out.Body, out.Body.Bundle)
+	}
+	if remotes, _ := gitOutput(o.config.OnRampDir, "remote"); remotes != "" {
+		t.Errorf("remotes = %q, want none", remotes)
+	}
+}
+
+func TestHandleImportRepoBundle_GitBundleSet(t *testing.T) {
+	sub := t.TempDir()
+	initGitRepo(t, sub)
+	if err := os.WriteFile(filepath.Join(sub, "site.yml"), []byte("- hosts: all\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	gitRunTest(t, sub, "add", "-A")
+	gitRunTest(t, sub, "commit", "-m", "role")
+
+	src := t.TempDir()
+	initVersionedRepo(t, src)
+	gitRunTest(t, src, "-c", "protocol.file.allow=always", "submodule", "add", "--quiet", sub, "deps/k8s")
+	gitRunTest(t, src, "commit", "-m", "add k8s")
+	gitRunTest(t, src, "tag", "-a", "v3.0.0", "-m", "v3.0.0")
+
+	// On its own the top-level bundle lacks the submodule.
+	pkg := t.TempDir()
+	single := gitBundle(t, src, filepath.Join(pkg, bundleSetFile))
+	o := newTestProvider(t, "")
+	_, err := importBundle(t, o, single, sha256Hex(single))
+	wantStatus(t, err, 422)
+	if readProvenance(o.config.OnRampDir) != nil {
+		t.Error("git bundle without its submodules was imported")
+	}
+
+	gitBundle(t, filepath.Join(src, "deps", "k8s"), filepath.Join(pkg, bundleSetModules, "deps", "k8s.bundle"))
+	data := tarGz(t, pkg, "aether-onramp")
+	out, err := importBundle(t, o, data, sha256Hex(data))
+	if err != nil {
+		t.Fatalf("HandleImportRepoBundle: %v", err)
+	}
+	if out.Body.Error != "" || out.Body.Tag != "v3.0.0" || out.Body.Bundle.Format != "git-bundle" {
+		t.Fatalf("status = %+v, bundle = %+v", out.Body, out.Body.Bundle)
+	}
+	dir := o.config.OnRampDir
+	if _, err := os.Stat(filepath.Join(dir, "deps", "k8s", "site.yml")); err != nil {
+		t.Errorf("submodule not checked out: %v", err)
+	}
+	if url, _ := gitOutput(dir, "config", "submodule.deps/k8s.url"); url != sub {
+		t.Errorf("submodule url = %q, want %q from .gitmodules", url, sub)
+	}
+
+	// The submodule's objects are local, so a refresh needs no remote.
+	if err := os.RemoveAll(sub); err != nil {
+		t.Fatalf("RemoveAll: %v", err)
+	}
+	refresh, err := o.HandleRefreshRepo(t.Context(), nil)
+	if err != nil {
+		t.Fatalf("HandleRefreshRepo: %v", err)
+	}
+	if refresh.Body.Error != "" {
+		t.Errorf("refresh = %+v", refresh.Body)
+	}
+}
+
+func TestHandleImportRepoBundle_Rejected(t *testing.T) {
+	o := newTestProvider(t, testMainYML)
+	dir := o.config.OnRampDir
+	src := t.TempDir()
+	initVersionedRepo(t, src)
+	data := tarGz(t, src, "")
+
+	_, err := importBundle(t, o, data, "")
+	wantStatus(t, err, 422)
+	_, err = importBundle(t, o, data, sha256Hex([]byte("other")))
+	wantStatus(t, err, 422)
+	_, err = importBundle(t, o, []byte("not an archive"), sha256Hex([]byte("not an archive")))
+	wantStatus(t, err, 422)
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	_ = tw.WriteHeader(&tar.Header{Name: "../escape", Mode: 0o644, Size: 1, Typeflag: tar.TypeReg})
+	_, _ = tw.Write([]byte("x"))
+	_ = tw.Close()
+	_, err = importBundle(t, o, buf.Bytes(), sha256Hex(buf.Bytes()))
+	wantStatus(t, err, 422)
+
+	buf.Reset()
+	tw = tar.NewWriter(&buf)
+	_ = tw.WriteHeader(&tar.Header{Name: "link", Linkname: "../../etc", Typeflag: tar.TypeSymlink})
+	_ = tw.Close()
+	_, err = importBundle(t, o, buf.Bytes(), sha256Hex(buf.Bytes()))
+	wantStatus(t, err, 422)
+
+	// A failed import leaves the existing checkout alone.
+	if _, err := os.Stat(filepath.Join(dir, "vars", "main.yml")); err != nil {
+		t.Errorf("existing checkout was disturbed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(dir), "escape")); !os.IsNotExist(err) {
+		t.Error("tar entry escaped the staging directory")
+	}
+}
+
+func TestHandleImportRepoBundle_LocalChanges(t *testing.T) {
+	o := newTestProvider(t, "")
+	initVersionedRepo(t, o.config.OnRampDir)
+	if err := os.WriteFile(filepath.Join(o.config.OnRampDir, "Makefile"), []byte("edited"), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	src := t.TempDir()
+	initVersionedRepo(t, src)
+	data := tarGz(t, src, "")
+
+	_, err := importBundle(t, o, data, sha256Hex(data))
+	wantStatus(t, err, 409)
+
+	in := &RepoBundleImportInput{SHA256: sha256Hex(data), Force: true, Bundle: bytes.NewReader(data)}
+	if _, err := o.HandleImportRepoBundle(t.Context(), in); err != nil {
+		t.Fatalf("forced HandleImportRepoBundle: %v", err)
+	}
+}
+
+func TestVerifyBundle_Signature(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		t.Fatalf("MarshalPKIXPublicKey: %v", err)
+	}
+	keys, err := parseTrustedKeys(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	if err != nil || len(keys) != 1 {
+		t.Fatalf("parseTrustedKeys = %v, %v", keys, err)
+	}
+
+	digest := sha256.Sum256([]byte("bundle"))
+	sig := base64.StdEncoding.EncodeToString(ed25519.Sign(priv, digest[:]))
+
+	by, id, err := verifyBundle(digest[:], "", sig, keys)
+	if err != nil || by != "signature" || id != keyID(pub) {
+		t.Errorf("verifyBundle = %q, %q, %v", by, id, err)
+	}
+	if _, _, err := verifyBundle(digest[:], "", sig, nil); err == nil {
+		t.Error("signature accepted without trusted keys")
+	}
+	other := sha256.Sum256([]byte("tampered"))
+	if _, _, err := verifyBundle(other[:], "", sig, keys); err == nil {
+		t.Error("signature accepted for a different archive")
+	}
+	if _, _, err := verifyBundle(digest[:], hex.EncodeToString(other[:]), sig, keys); err == nil {
+		t.Error("checksum mismatch ignored when a valid signature is present")
+	}
+}
+
+func TestHandleImportRepoBundle_KeepsConfig(t *testing.T) {
+	o := newTestProvider(t, "")
+	dir := o.config.OnRampDir
+	initVersionedRepo(t, dir)
+	config := testMainYML + "# configured through the API\n"
+	writeTestFile(t, filepath.Join(dir, "vars", "main.yml"), config)
+	writeTestFile(t, filepath.Join(dir, "host_vars", "node1", secretsFile), "ansible_password: x\n")
+
+	src := t.TempDir()
+	initVersionedRepo(t, src)
+	data := tarGz(t, src, "")
+	if _, err := importBundle(t, o, data, sha256Hex(data)); err != nil {
+		t.Fatalf("HandleImportRepoBundle: %v", err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(dir, "vars", "main.yml")); string(got) != config {
+		t.Errorf("vars/main.yml = %q, want the workspace's config", got)
+	}
+	fi, err := os.Stat(filepath.Join(dir, "host_vars", "node1", secretsFile))
+	if err != nil || fi.Mode().Perm() != 0o600 {
+		t.Errorf("secrets file = %v, %v", fi, err)
+	}
+}
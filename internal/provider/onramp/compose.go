@@ -2,31 +2,31 @@ package onramp
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
+	"strings"
 
 	"github.com/danielgtaylor/huma/v2"
 	"gopkg.in/yaml.v3"
 )
 
-// rolesToComponents derives compose components from node role assignments.
-// master → k8s + 5gc, worker → nothing, other roles map 1:1 if they exist
-// in componentYAMLKey.
-func rolesToComponents(roles []string) []string {
+// rolesToComponents derives compose components from node role assignments:
+// each role selects the registered components that run on it and have a
+// vars/main.yml section. With the built-in overlay, master selects k8s and
+// 5gc and worker selects nothing.
+func rolesToComponents(reg *componentRegistry, roles []string) []string {
 	seen := make(map[string]bool)
-	for _, role := range roles {
-		switch role {
-		case "master":
-			seen["k8s"] = true
-			seen["5gc"] = true
-		case "worker":
-			// no corresponding compose component
-		default:
-			if _, ok := componentYAMLKey[role]; ok {
-				seen[role] = true
+	for _, comp := range reg.components {
+		if comp.Section == "" {
+			continue
+		}
+		for _, role := range roles {
+			if slices.Contains(comp.Roles, role) {
+				seen[comp.Name] = true
+				break
 			}
 		}
 	}
@@ -38,51 +38,21 @@ func rolesToComponents(roles []string) []string {
 	return out
 }
 
-// componentBlueprint maps component names to their blueprint filenames.
-// Only components that have a separate blueprint file are included.
-var componentBlueprint = map[string]string{
-	"srsran":  "main-srsran.yml",
-	"ueransim": "main-ueransim.yml",
-	"oai":     "main-oai.yml",
-	"n3iwf":   "main-n3iwf.yml",
-	"sdran":   "main-sdran.yml",
-}
-
-// componentYAMLKey maps component names to their top-level YAML key.
-var componentYAMLKey = map[string]string{
-	"k8s":     "k8s",
-	"5gc":     "core",
-	"4gc":     "core",
-	"gnbsim":  "gnbsim",
-	"srsran":  "srsran",
-	"ueransim": "ueransim",
-	"oai":     "oai",
-	"n3iwf":   "n3iwf",
-	"sdran":   "sdran",
-	"amp":     "amp",
-}
-
-// implicitDeps maps a component to its implied dependencies.
-var implicitDeps = map[string][]string{
-	"5gc": {"k8s"},
-	"4gc": {"k8s"},
-}
-
-// prunableKeys lists top-level YAML keys that are removed when not selected.
-// k8s and core are infrastructure and never pruned.
-var prunableKeys = map[string]bool{
-	"gnbsim":  true,
-	"srsran":  true,
-	"ueransim": true,
-	"oai":     true,
-	"n3iwf":   true,
-	"sdran":   true,
-	"amp":     true,
-}
-
 // HandleComposeConfig builds vars/main.yml from the base config plus selected
 // component blueprints, pruning sections for unselected components.
 func (o *OnRamp) HandleComposeConfig(ctx context.Context, in *ConfigComposeInput) (*ConfigComposeOutput, error) {
+	ws, err := o.workspace(ctx, in.Workspace)
+	if err != nil {
+		return nil, err
+	}
+	// A dry run writes nothing, so the change lock does not apply.
+	if !in.DryRun {
+		if err := o.CheckChangeLock(ctx, ws.name); err != nil {
+			return nil, err
+		}
+	}
+
+	reg := o.registry(ws)
 	components := in.Body.Components
 
 	// Derive from node roles when the caller doesn't specify.
@@ -94,7 +64,7 @@ func (o *OnRamp) HandleComposeConfig(ctx context.Context, in *ConfigComposeInput
 				fmt.Errorf("onramp provider store client is not configured"),
 			)
 		}
-		infos, err := sc.ListNodes(ctx)
+		infos, err := sc.ListNodes(ctx, ws.name)
 		if err != nil {
 			return nil, huma.Error500InternalServerError("failed to list nodes", err)
 		}
@@ -102,12 +72,12 @@ func (o *OnRamp) HandleComposeConfig(ctx context.Context, in *ConfigComposeInput
 		for _, ni := range infos {
 			allRoles = append(allRoles, ni.Roles...)
 		}
-		components = rolesToComponents(allRoles)
+		components = rolesToComponents(reg, allRoles)
 	}
 
 	// Validate all components.
 	for _, c := range components {
-		if _, ok := componentYAMLKey[c]; !ok {
+		if comp, ok := reg.component(c); !ok || comp.Section == "" {
 			return nil, huma.Error422UnprocessableEntity(
 				fmt.Sprintf("unknown component: %s", c),
 				fmt.Errorf("component %q is not in the component registry", c),
@@ -120,7 +90,8 @@ func (o *OnRamp) HandleComposeConfig(ctx context.Context, in *ConfigComposeInput
 	seen := make(map[string]bool, len(components))
 	for _, c := range components {
 		seen[c] = true
-		for _, dep := range implicitDeps[c] {
+		comp, _ := reg.component(c)
+		for _, dep := range comp.Requires {
 			seen[dep] = true
 		}
 	}
@@ -130,24 +101,31 @@ func (o *OnRamp) HandleComposeConfig(ctx context.Context, in *ConfigComposeInput
 	}
 	sort.Strings(components)
 
-	varsDir := filepath.Join(o.config.OnRampDir, "vars")
+	varsDir := filepath.Join(ws.config.OnRampDir, "vars")
 	mainPath := filepath.Join(varsDir, "main.yml")
 
-	// Read base config as untyped map.
-	base, err := readRawYAML(mainPath)
+	// Work on the document tree so comments and keys the typed config does
+	// not model survive the compose.
+	defer LockVars(ws.config.OnRampDir)()
+	current, err := os.ReadFile(mainPath)
 	if err != nil {
 		return nil, huma.Error500InternalServerError("failed to read base config", err)
 	}
+	doc, err := parseVarsDoc(current)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("failed to read base config", fmt.Errorf("parse %s: %w", mainPath, err))
+	}
 
 	// Merge blueprints in sorted order for deterministic results.
 	var activeBlueprints []string
-	for _, comp := range components {
-		bpFile, hasBP := componentBlueprint[comp]
-		if !hasBP {
+	for _, name := range components {
+		comp, ok := reg.component(name)
+		if !ok || comp.Blueprint == "" {
 			continue
 		}
+		bpFile := comp.Blueprint
 		bpPath := filepath.Join(varsDir, bpFile)
-		bp, err := readRawYAML(bpPath)
+		bp, err := loadVarsDoc(bpPath)
 		if err != nil {
 			if os.IsNotExist(err) {
 				return nil, huma.Error404NotFound(
@@ -158,30 +136,16 @@ func (o *OnRamp) HandleComposeConfig(ctx context.Context, in *ConfigComposeInput
 			return nil, huma.Error500InternalServerError("failed to read blueprint", err)
 		}
 
-		yamlKey := componentYAMLKey[comp]
+		yamlKey := comp.Section
 
 		// Merge the component's top-level key from the blueprint.
-		if bpSection, ok := bp[yamlKey]; ok {
-			if bpMap, ok := bpSection.(map[string]any); ok {
-				if baseMap, ok := base[yamlKey].(map[string]any); ok {
-					deepMergeMaps(baseMap, bpMap)
-				} else {
-					base[yamlKey] = bpSection
-				}
-			} else {
-				base[yamlKey] = bpSection
-			}
+		if bpSection := bp.section(yamlKey); bpSection != nil {
+			doc.mergeSection(yamlKey, bpSection)
 		}
 
 		// Merge the blueprint's core section into the base core.
-		if bpCore, ok := bp["core"]; ok {
-			if bpCoreMap, ok := bpCore.(map[string]any); ok {
-				if baseCore, ok := base["core"].(map[string]any); ok {
-					deepMergeMaps(baseCore, bpCoreMap)
-				} else {
-					base["core"] = bpCore
-				}
-			}
+		if bpCore := bp.section("core"); bpCore != nil && bpCore.Kind == yaml.MappingNode {
+			doc.mergeSection("core", bpCore)
 		}
 
 		activeBlueprints = append(activeBlueprints, bpFile)
@@ -189,90 +153,52 @@ func (o *OnRamp) HandleComposeConfig(ctx context.Context, in *ConfigComposeInput
 
 	// Determine which YAML keys are "kept" by selected components.
 	keptKeys := make(map[string]bool)
-	for _, comp := range components {
-		if key, ok := componentYAMLKey[comp]; ok {
-			keptKeys[key] = true
+	for _, name := range components {
+		if comp, ok := reg.component(name); ok {
+			keptKeys[comp.Section] = true
 		}
 	}
 
-	// Prune unselected prunable keys.
-	for key := range prunableKeys {
-		if !keptKeys[key] {
-			delete(base, key)
+	// Prune the sections of unselected components. Sections marked
+	// keep_section (k8s and core) are infrastructure and never pruned.
+	prunable := make(map[string]bool)
+	for _, comp := range reg.components {
+		if comp.Section != "" && !comp.KeepSection {
+			prunable[comp.Section] = true
 		}
 	}
+	pruned := []string{}
+	for key := range prunable {
+		if !keptKeys[key] && doc.remove(key) {
+			pruned = append(pruned, key)
+		}
+	}
+	sort.Strings(pruned)
 
-	// Convert the untyped map to the typed struct via JSON round-trip.
-	// Direct YAML marshal of the untyped map would quote numeric string keys
-	// (e.g. "0":) with !!str tags, which yaml.v3 cannot unmarshal into
-	// map[int] fields like SRSRanConfig.Servers. JSON handles the conversion
-	// cleanly because JSON always uses string keys.
-	jsonData, err := jsonMarshalAny(base)
+	cfg, err := doc.config()
 	if err != nil {
-		return nil, huma.Error500InternalServerError("failed to serialize config", err)
-	}
-	var cfg OnRampConfig
-	if err := json.Unmarshal(jsonData, &cfg); err != nil {
 		return nil, huma.Error500InternalServerError("failed to parse config", err)
 	}
-
-	// Write through the typed struct so YAML keys have correct types.
-	if err := o.writeVarsFile(mainPath, &cfg); err != nil {
-		return nil, huma.Error500InternalServerError("failed to write config", err)
+	data, err := doc.bytes()
+	if err != nil {
+		return nil, huma.Error500InternalServerError("failed to serialize config", err)
 	}
 
-	return &ConfigComposeOutput{
-		Body: ConfigComposeResult{
-			ActiveBlueprints: activeBlueprints,
-			Components:       components,
-			Config:           cfg,
-		},
-	}, nil
-}
-
-// jsonMarshalAny marshals an untyped map to JSON, converting any
-// map[interface{}]interface{} values (produced by yaml.v3 for maps with
-// non-string keys) into map[string]interface{} so encoding/json can handle them.
-func jsonMarshalAny(v any) ([]byte, error) {
-	return json.Marshal(convertKeysToStrings(v))
-}
-
-// convertKeysToStrings recursively converts map[interface{}]interface{} to
-// map[string]interface{} for JSON compatibility.
-func convertKeysToStrings(v any) any {
-	switch val := v.(type) {
-	case map[string]any:
-		out := make(map[string]any, len(val))
-		for k, v := range val {
-			out[k] = convertKeysToStrings(v)
-		}
-		return out
-	case map[any]any:
-		out := make(map[string]any, len(val))
-		for k, v := range val {
-			out[fmt.Sprintf("%v", k)] = convertKeysToStrings(v)
-		}
-		return out
-	case []any:
-		out := make([]any, len(val))
-		for i, v := range val {
-			out[i] = convertKeysToStrings(v)
-		}
-		return out
-	default:
-		return v
+	result := ConfigComposeResult{
+		ActiveBlueprints: activeBlueprints,
+		Components:       components,
+		Pruned:           pruned,
+		Config:           cfg,
 	}
-}
-
-// readRawYAML reads a YAML file into an untyped map.
-func readRawYAML(path string) (map[string]any, error) {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return nil, err
+	if in.DryRun {
+		preview := PreviewVars(current, data)
+		result.DryRun = true
+		result.Preview = &preview
+		return &ConfigComposeOutput{Body: result}, nil
 	}
-	var m map[string]any
-	if err := yaml.Unmarshal(data, &m); err != nil {
-		return nil, fmt.Errorf("parse %s: %w", path, err)
+	if err := o.saveVars(ctx, ws, data, "compose", "components: "+strings.Join(components, ", "), in.Author); err != nil {
+		return nil, huma.Error500InternalServerError("failed to write config", err)
 	}
-	return m, nil
+
+	return &ConfigComposeOutput{Body: result}, nil
 }
@@ -1,9 +1,12 @@
 package onramp
 
 import (
-	"encoding/json"
+	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+
+	"gopkg.in/yaml.v3"
 )
 
 // TestComposeConfig_RawYAMLBlueprint_AllFieldsSurvive verifies that compose
@@ -107,7 +110,7 @@ func TestComposeConfig_RawYAMLBlueprint_AllFieldsSurvive(t *testing.T) {
 
 // TestComposeConfig_ThenApplyDefaults_PreservesFields simulates the full
 // compose → apply-defaults flow: compose writes the config, then a partial
-// patch (like apply-defaults setting gnb_ip) is merged via deepMergeConfig.
+// patch (like apply-defaults setting gnb_ip) is merged via MergeVars.
 // All non-patched fields must survive.
 func TestComposeConfig_ThenApplyDefaults_PreservesFields(t *testing.T) {
 	p := newTestProvider(t, baseConfig())
@@ -123,21 +126,21 @@ func TestComposeConfig_ThenApplyDefaults_PreservesFields(t *testing.T) {
 
 	// Step 2: read the composed config back (as apply-defaults would)
 	mainPath := filepath.Join(p.config.OnRampDir, "vars", "main.yml")
-	cfg, err := p.readVarsFile(mainPath)
+	data, err := os.ReadFile(mainPath)
 	if err != nil {
-		t.Fatalf("readVarsFile: %v", err)
+		t.Fatalf("ReadFile: %v", err)
 	}
 
 	// Step 3: simulate apply-defaults patch — sets gnb_ip
 	patchJSON := []byte(`{"srsran":{"servers":{"0":{"gnb_ip":"10.103.102.30"}}}}`)
-	merged, err := deepMergeConfig(&cfg, patchJSON)
+	merged, _, err := MergeVars(data, patchJSON)
 	if err != nil {
-		t.Fatalf("deepMergeConfig: %v", err)
+		t.Fatalf("MergeVars: %v", err)
 	}
 
 	// Step 4: write merged config back
-	if err := p.writeVarsFile(mainPath, &merged); err != nil {
-		t.Fatalf("writeVarsFile: %v", err)
+	if _, err := SaveVars(t.Context(), p.Store(), VarsChange{Dir: p.config.OnRampDir, Source: "configdefaults"}, merged); err != nil {
+		t.Fatalf("SaveVars: %v", err)
 	}
 
 	// Step 5: read final result and verify ALL fields survived
@@ -189,34 +192,13 @@ func TestComposeConfig_ThenApplyDefaults_PreservesFields(t *testing.T) {
 	}
 }
 
-// TestDeepMergeConfig_DoesNotDropSiblingKeys verifies that deepMergeConfig
-// preserves sibling keys when patching a nested leaf.
-func TestDeepMergeConfig_DoesNotDropSiblingKeys(t *testing.T) {
-	sim := true
-	base := OnRampConfig{
-		SRSRan: &SRSRanConfig{
-			Docker: &SRSRanDocker{
-				Container: &SRSRanContainer{
-					GNBImage: "srsran/gnb:latest",
-					UEImage:  "srsran/ue:latest",
-				},
-				Network: &SRSRanNetwork{Name: "srsran-net"},
-			},
-			Simulation: &sim,
-			Servers: map[int]*SRSRanServer{
-				0: {
-					GNBIP:   "",
-					GNBConf: "deps/srsran/roles/gNB/templates/gnb_zmq.yaml",
-					UEConf:  "deps/srsran/roles/uEsimulator/templates/ue_zmq.conf",
-				},
-			},
-		},
-	}
-
+// TestMergeVars_DoesNotDropSiblingKeys verifies that MergeVars preserves
+// sibling keys when patching a nested leaf.
+func TestMergeVars_DoesNotDropSiblingKeys(t *testing.T) {
 	patch := `{"srsran":{"servers":{"0":{"gnb_ip":"10.1.2.3"}}}}`
-	merged, err := deepMergeConfig(&base, []byte(patch))
+	_, merged, err := MergeVars([]byte(rawBlueprintSRSRan), []byte(patch))
 	if err != nil {
-		t.Fatalf("deepMergeConfig: %v", err)
+		t.Fatalf("MergeVars: %v", err)
 	}
 
 	if merged.SRSRan == nil {
@@ -247,56 +229,34 @@ func TestDeepMergeConfig_DoesNotDropSiblingKeys(t *testing.T) {
 	}
 }
 
-// TestConvertKeysToStrings_IntegerKeys verifies that convertKeysToStrings
-// handles map[any]any with integer keys (produced by yaml.v3 for bare 0: keys).
-func TestConvertKeysToStrings_IntegerKeys(t *testing.T) {
-	// Simulate what yaml.v3 produces for bare integer keys.
-	input := map[string]any{
-		"srsran": map[string]any{
-			"servers": map[any]any{
-				0: map[string]any{
-					"gnb_ip":   "",
-					"gnb_conf": "path/to/gnb.yaml",
-					"ue_conf":  "path/to/ue.conf",
-				},
-			},
-			"docker": map[string]any{
-				"container": map[string]any{
-					"gnb_image": "srsran/gnb:latest",
-				},
-			},
-			"simulation": true,
-		},
-	}
-
-	result := convertKeysToStrings(input)
-	data, err := json.Marshal(result)
+// TestMergeVars_IntegerKeys verifies that JSON's string keys line up with the
+// bare integer keys (0:) OnRamp uses for servers, and that new numeric keys
+// are written unquoted so they still decode into map[int] fields.
+func TestMergeVars_IntegerKeys(t *testing.T) {
+	patch := `{"srsran":{"servers":{"0":{"gnb_ip":"10.1.2.3"},"1":{"gnb_ip":"10.1.2.4"}}}}`
+	data, cfg, err := MergeVars([]byte(rawBlueprintSRSRan), []byte(patch))
 	if err != nil {
-		t.Fatalf("json.Marshal: %v", err)
+		t.Fatalf("MergeVars: %v", err)
 	}
-
-	var cfg OnRampConfig
-	if err := json.Unmarshal(data, &cfg); err != nil {
-		t.Fatalf("json.Unmarshal: %v", err)
+	if !strings.Contains(string(data), "\n    0:\n") || !strings.Contains(string(data), "\n    1:\n") {
+		t.Errorf("server keys not written as bare integers:\n%s", data)
 	}
 
-	if cfg.SRSRan == nil {
-		t.Fatal("srsran nil after conversion")
-	}
-	if cfg.SRSRan.Docker == nil || cfg.SRSRan.Docker.Container == nil {
-		t.Fatal("docker lost after conversion")
+	if cfg.SRSRan == nil || len(cfg.SRSRan.Servers) != 2 {
+		t.Fatalf("servers = %+v", cfg.SRSRan)
 	}
-	if cfg.SRSRan.Docker.Container.GNBImage != "srsran/gnb:latest" {
-		t.Errorf("gnb_image = %q", cfg.SRSRan.Docker.Container.GNBImage)
+	if srv := cfg.SRSRan.Servers[0]; srv == nil || srv.GNBIP != "10.1.2.3" || srv.GNBConf != "deps/srsran/roles/gNB/templates/gnb_zmq.yaml" {
+		t.Errorf("servers[0] = %+v", srv)
 	}
-	if cfg.SRSRan.Simulation == nil || !*cfg.SRSRan.Simulation {
-		t.Error("simulation lost after conversion")
+	if srv := cfg.SRSRan.Servers[1]; srv == nil || srv.GNBIP != "10.1.2.4" {
+		t.Errorf("servers[1] = %+v", srv)
 	}
-	srv, ok := cfg.SRSRan.Servers[0]
-	if !ok || srv == nil {
-		t.Fatal("servers[0] lost after conversion")
+
+	var disk OnRampConfig
+	if err := yaml.Unmarshal(data, &disk); err != nil {
+		t.Fatalf("written config does not decode: %v", err)
 	}
-	if srv.GNBConf != "path/to/gnb.yaml" {
-		t.Errorf("gnb_conf = %q", srv.GNBConf)
+	if disk.SRSRan == nil || disk.SRSRan.Servers[1] == nil {
+		t.Error("servers[1] lost on disk")
 	}
 }
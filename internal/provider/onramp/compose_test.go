@@ -1,8 +1,12 @@
 package onramp
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
 
 	"gopkg.in/yaml.v3"
@@ -56,6 +60,19 @@ func writeBlueprint(t *testing.T, p *OnRamp, filename string, content map[string
 	}
 }
 
+// readRawYAML reads a YAML file into an untyped map.
+func readRawYAML(path string) (map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]any
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 func baseConfig() string {
 	return `
 k8s:
@@ -284,6 +301,66 @@ func TestComposeConfig_WritesMainYML(t *testing.T) {
 	}
 }
 
+func TestComposeConfig_DryRun(t *testing.T) {
+	p := newTestProviderWithStore(t, baseConfig())
+	mainPath := filepath.Join(p.config.OnRampDir, "vars", "main.yml")
+	before, err := os.ReadFile(mainPath)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+
+	// A preview is allowed while the cluster is locked.
+	acquireMaintenance(t, p)
+
+	in := &ConfigComposeInput{Body: ConfigComposeBody{Components: []string{"k8s", "5gc"}}}
+	in.DryRun = true
+	out, err := p.HandleComposeConfig(t.Context(), in)
+	if err != nil {
+		t.Fatalf("HandleComposeConfig: %v", err)
+	}
+
+	after, err := os.ReadFile(mainPath)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if string(after) != string(before) {
+		t.Errorf("main.yml changed by dry run:\n%s", after)
+	}
+	if _, ok, _ := p.Store().LatestConfigRevision(t.Context(), ""); ok {
+		t.Error("dry run recorded a config revision")
+	}
+
+	res := out.Body
+	if !res.DryRun || res.Preview == nil {
+		t.Fatalf("result = %+v", res)
+	}
+	if want := []string{"amp", "gnbsim"}; !slices.Equal(res.Pruned, want) {
+		t.Errorf("pruned = %v, want %v", res.Pruned, want)
+	}
+	if res.Config.GNBSim != nil || res.Config.K8s == nil {
+		t.Errorf("config = %+v", res.Config)
+	}
+	var paths []string
+	for _, c := range res.Preview.Changes {
+		if c.Kind != "removed" {
+			t.Errorf("change %+v, want only removals", c)
+		}
+		paths = append(paths, c.Path)
+	}
+	if want := []string{"amp", "gnbsim"}; !slices.Equal(paths, want) {
+		t.Errorf("changed paths = %v, want %v", paths, want)
+	}
+	if strings.Contains(res.Preview.Content, "gnbsim") || !strings.Contains(res.Preview.Unified, "-gnbsim:") {
+		t.Errorf("preview = %+v", res.Preview)
+	}
+
+	// Without dry_run the lock still applies.
+	in.DryRun = false
+	if _, err := p.HandleComposeConfig(t.Context(), in); err == nil {
+		t.Fatal("expected the change lock to block the write")
+	}
+}
+
 func TestComposeConfig_DeterministicOutput(t *testing.T) {
 	p := newTestProvider(t, baseConfig())
 
@@ -319,3 +396,51 @@ func TestComposeConfig_DeterministicOutput(t *testing.T) {
 		}
 	}
 }
+
+func TestComposeConfig_ConcurrentPatch(t *testing.T) {
+	p := newTestProvider(t, baseConfig())
+
+	// Each patch adds a key; a compose that read the file before a patch
+	// and wrote it after would drop it.
+	const n = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, 2*n)
+	for i := range n {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, err := p.HandleComposeConfig(t.Context(), &ConfigComposeInput{
+				Body: ConfigComposeBody{Components: []string{"k8s", "5gc"}},
+			})
+			errs <- err
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := p.HandlePatchConfig(t.Context(), &ConfigPatchInput{
+				RawBody: []byte(fmt.Sprintf(`{"extra_%d": "x"}`, i)),
+			})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("concurrent write: %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(p.config.OnRampDir, "vars", "main.yml"))
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	var doc map[string]any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	for i := range n {
+		if _, ok := doc[fmt.Sprintf("extra_%d", i)]; !ok {
+			t.Errorf("patch %d lost:\n%s", i, data)
+		}
+	}
+}
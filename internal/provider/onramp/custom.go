@@ -0,0 +1,393 @@
+package onramp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"github.com/bengrewell/aether-webui/internal/store"
+)
+
+// customNameRe matches custom component, action and role names. Roles
+// become hosts.ini group names, so they share the same restricted form.
+var customNameRe = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+// makeTargetRe matches a make target a custom action may run. A leading dash
+// would be read as an option.
+var makeTargetRe = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]*$`)
+
+// actionVarRe matches an argument of a make or playbook action: a KEY=value
+// variable. Values go into make recipes and Jinja templates, so they are
+// limited to characters neither shell nor template expands.
+var actionVarRe = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*)=([A-Za-z0-9_.,:/@%+=-]*)$`)
+
+// reservedMakeVars change how make runs recipes or reads makefiles rather
+// than what a target does.
+var reservedMakeVars = map[string]bool{
+	"SHELL": true, "MAKE": true, "MAKEFLAGS": true, "MFLAGS": true,
+	"GNUMAKEFLAGS": true, "MAKEFILES": true, "MAKEOVERRIDES": true,
+}
+
+// isMakeAction reports whether an action runs a make target.
+func isMakeAction(a Action) bool {
+	return a.Kind == "" || a.Kind == store.CustomActionMake
+}
+
+// customComponent converts a stored definition to a registry component.
+func customComponent(cc store.CustomComponent) Component {
+	c := Component{
+		Name:        cc.Name,
+		Description: cc.Description,
+		Tier:        cc.Tier,
+		Roles:       cc.Roles,
+		Custom:      true,
+		Actions:     make([]Action, len(cc.Actions)),
+	}
+	for i, a := range cc.Actions {
+		c.Actions[i] = Action{
+			Name:        a.Name,
+			Description: a.Description,
+			Kind:        a.Kind,
+			Target:      a.Target,
+			Args:        a.Args,
+		}
+	}
+	return c
+}
+
+// listCustomComponents loads a workspace's custom components; none without
+// a store.
+func (o *OnRamp) listCustomComponents(workspace string) ([]store.CustomComponent, error) {
+	st := o.Store()
+	if st.Path() == "" {
+		return nil, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return st.ListCustomComponents(ctx, workspace)
+}
+
+// actionCommand is the process an action runs in the workspace checkout.
+type actionCommand struct {
+	command string
+	args    []string
+	env     []string
+}
+
+// commandFor returns the process that runs an action. make targets run as
+// OnRamp's own do; playbooks run against the checkout's hosts.ini and
+// vars/main.yml like OnRamp's playbooks; scripts get the checkout's paths in
+// their environment.
+func (o *OnRamp) commandFor(ws *workspace, a Action) (actionCommand, error) {
+	dir := ws.config.OnRampDir
+	if a.Target == "" {
+		return actionCommand{}, errors.New("action has no target")
+	}
+	// Actions saved before args were checked are refused here too.
+	if err := checkArgs(a.Kind, a.Args); err != nil {
+		return actionCommand{}, err
+	}
+	switch a.Kind {
+	case "", store.CustomActionMake:
+		return actionCommand{command: "make", args: append([]string{a.Target}, a.Args...)}, nil
+	case store.CustomActionPlaybook:
+		path, err := customFile(o.config.PlaybookDir, "playbook", a.Target)
+		if err != nil {
+			return actionCommand{}, err
+		}
+		args := []string{
+			"-i", filepath.Join(dir, "hosts.ini"),
+			"--extra-vars", "ROOT_DIR=" + dir,
+			"--extra-vars", "@" + filepath.Join(dir, "vars", "main.yml"),
+		}
+		for _, v := range a.Args {
+			args = append(args, "--extra-vars", v)
+		}
+		return actionCommand{command: "ansible-playbook", args: append(args, path)}, nil
+	case store.CustomActionScript:
+		path, err := customFile(o.config.ScriptDir, "script", a.Target)
+		if err != nil {
+			return actionCommand{}, err
+		}
+		return actionCommand{
+			command: path,
+			args:    a.Args,
+			env: []string{
+				"ONRAMP_DIR=" + dir,
+				"ONRAMP_WORKSPACE=" + ws.name,
+				"ONRAMP_HOSTS_INI=" + filepath.Join(dir, "hosts.ini"),
+				"ONRAMP_VARS=" + filepath.Join(dir, "vars", "main.yml"),
+			},
+		}, nil
+	}
+	return actionCommand{}, fmt.Errorf("unknown action kind %q", a.Kind)
+}
+
+// customFile resolves a playbook or script path inside its directory and
+// checks that it exists.
+func customFile(dir, kind, rel string) (string, error) {
+	path, err := customPath(dir, kind, rel)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(path); err != nil {
+		return "", fmt.Errorf("%s %s: %w", kind, rel, err)
+	}
+	return path, nil
+}
+
+// customPath joins a path relative to the playbook or script directory,
+// refusing paths that leave it.
+func customPath(dir, kind, rel string) (string, error) {
+	if dir == "" {
+		return "", fmt.Errorf("no %s directory is configured", kind)
+	}
+	clean := filepath.Clean(rel)
+	if rel == "" || filepath.IsAbs(rel) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s path %q must be relative to the %s directory", kind, rel, kind)
+	}
+	return filepath.Join(dir, clean), nil
+}
+
+// validateCustomSpec checks a custom component definition and converts it
+// for the store.
+func (o *OnRamp) validateCustomSpec(ws *workspace, name string, spec CustomComponentSpec) (store.CustomComponent, error) {
+	cc := store.CustomComponent{
+		Workspace:   ws.name,
+		Name:        name,
+		Description: spec.Description,
+		Tier:        defaultTier,
+		Roles:       spec.Roles,
+	}
+	if spec.Tier != nil {
+		cc.Tier = *spec.Tier
+	}
+	for _, r := range spec.Roles {
+		if !customNameRe.MatchString(r) {
+			return cc, huma.Error422UnprocessableEntity(fmt.Sprintf("invalid role %q: use lowercase letters, digits, dashes, and underscores", r))
+		}
+	}
+	if len(spec.Actions) == 0 {
+		return cc, huma.Error422UnprocessableEntity("a custom component needs at least one action")
+	}
+	seen := make(map[string]bool, len(spec.Actions))
+	for _, a := range spec.Actions {
+		if !customNameRe.MatchString(a.Name) {
+			return cc, huma.Error422UnprocessableEntity(fmt.Sprintf("invalid action name %q: use lowercase letters, digits, dashes, and underscores", a.Name))
+		}
+		if seen[a.Name] {
+			return cc, huma.Error422UnprocessableEntity(fmt.Sprintf("duplicate action %q", a.Name))
+		}
+		seen[a.Name] = true
+
+		kind, err := o.checkTarget(a.Kind, a.Target, a.Args)
+		if err != nil {
+			return cc, huma.Error422UnprocessableEntity(fmt.Sprintf("action %s: %v", a.Name, err))
+		}
+		cc.Actions = append(cc.Actions, store.CustomAction{
+			Name:        a.Name,
+			Description: a.Description,
+			Kind:        kind,
+			Target:      a.Target,
+			Args:        a.Args,
+		})
+	}
+	return cc, nil
+}
+
+// checkTarget validates what a custom action or hook of the given kind runs
+// and its args, and returns the kind, make when empty. Playbooks and scripts
+// need not exist yet; commandFor checks that when they run.
+func (o *OnRamp) checkTarget(kind, target string, args []string) (string, error) {
+	if kind == "" {
+		kind = store.CustomActionMake
+	}
+	var err error
+	switch kind {
+	case store.CustomActionMake:
+		if !makeTargetRe.MatchString(target) {
+			err = fmt.Errorf("invalid make target %q", target)
+		}
+	case store.CustomActionPlaybook:
+		_, err = customPath(o.config.PlaybookDir, "playbook", target)
+	case store.CustomActionScript:
+		_, err = customPath(o.config.ScriptDir, "script", target)
+	default:
+		err = fmt.Errorf("unknown kind %q", kind)
+	}
+	if err == nil {
+		err = checkArgs(kind, args)
+	}
+	return kind, err
+}
+
+// checkArgs validates the args of an action of the given kind. make and
+// ansible-playbook would take options or extra makefiles and playbooks from
+// free-form args, so theirs must be KEY=value variables; playbooks get each
+// as --extra-vars. Scripts are confined to the script directory and get
+// their args as they are.
+func checkArgs(kind string, args []string) error {
+	if kind == store.CustomActionScript {
+		return nil
+	}
+	for _, arg := range args {
+		m := actionVarRe.FindStringSubmatch(arg)
+		switch {
+		case m == nil:
+			return fmt.Errorf("invalid arg %q: use KEY=value with a value of letters, digits and _.,:/@%%+=-", arg)
+		case kind == store.CustomActionPlaybook && strings.HasPrefix(strings.ToLower(m[1]), "ansible_"):
+			return fmt.Errorf("invalid arg %q: connection variables cannot be overridden", arg)
+		case kind != store.CustomActionPlaybook && reservedMakeVars[m[1]]:
+			return fmt.Errorf("invalid arg %q: %s cannot be overridden", arg, m[1])
+		}
+	}
+	return nil
+}
+
+// ---------------------------------------------------------------------------
+// Handlers
+// ---------------------------------------------------------------------------
+
+// HandleListCustomComponents returns the workspace's custom components.
+func (o *OnRamp) HandleListCustomComponents(ctx context.Context, in *WorkspaceInput) (*ComponentListOutput, error) {
+	st, err := o.requireStore("custom components are")
+	if err != nil {
+		return nil, err
+	}
+	ws, err := o.workspace(ctx, in.workspaceName())
+	if err != nil {
+		return nil, err
+	}
+	list, err := st.ListCustomComponents(ctx, ws.name)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("failed to list custom components", err)
+	}
+	out := make([]Component, len(list))
+	for i, cc := range list {
+		out[i] = customComponent(cc)
+	}
+	return &ComponentListOutput{Body: out}, nil
+}
+
+// HandleGetCustomComponent returns one custom component.
+func (o *OnRamp) HandleGetCustomComponent(ctx context.Context, in *CustomComponentGetInput) (*CustomComponentOutput, error) {
+	st, err := o.requireStore("custom components are")
+	if err != nil {
+		return nil, err
+	}
+	ws, err := o.workspace(ctx, in.Workspace)
+	if err != nil {
+		return nil, err
+	}
+	cc, ok, err := st.GetCustomComponent(ctx, ws.name, in.Name)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("failed to get custom component", err)
+	}
+	if !ok {
+		return nil, huma.Error404NotFound(fmt.Sprintf("custom component %s not found", in.Name))
+	}
+	return &CustomComponentOutput{Body: customComponent(cc)}, nil
+}
+
+// HandleCreateCustomComponent registers a custom component. Its name must
+// not be taken by an OnRamp component of the workspace's checkout.
+func (o *OnRamp) HandleCreateCustomComponent(ctx context.Context, in *CustomComponentCreateInput) (*CustomComponentOutput, error) {
+	st, err := o.requireStore("custom components are")
+	if err != nil {
+		return nil, err
+	}
+	ws, err := o.workspace(ctx, in.Workspace)
+	if err != nil {
+		return nil, err
+	}
+	if err := o.CheckChangeLock(ctx, ws.name); err != nil {
+		return nil, err
+	}
+	if c, ok := o.registry(ws).component(in.Body.Name); ok {
+		if c.Custom {
+			return nil, huma.Error409Conflict(fmt.Sprintf("custom component %s already exists", in.Body.Name))
+		}
+		return nil, huma.Error409Conflict(fmt.Sprintf("%s is an OnRamp component", in.Body.Name))
+	}
+	if _, ok, err := st.GetCustomComponent(ctx, ws.name, in.Body.Name); err != nil {
+		return nil, huma.Error500InternalServerError("failed to get custom component", err)
+	} else if ok {
+		return nil, huma.Error409Conflict(fmt.Sprintf("custom component %s already exists", in.Body.Name))
+	}
+	cc, err := o.validateCustomSpec(ws, in.Body.Name, in.Body.CustomComponentSpec)
+	if err != nil {
+		return nil, err
+	}
+	return o.saveCustomComponent(ctx, st, ws, cc)
+}
+
+// HandleUpdateCustomComponent replaces a custom component's definition.
+func (o *OnRamp) HandleUpdateCustomComponent(ctx context.Context, in *CustomComponentUpdateInput) (*CustomComponentOutput, error) {
+	st, err := o.requireStore("custom components are")
+	if err != nil {
+		return nil, err
+	}
+	ws, err := o.workspace(ctx, in.Workspace)
+	if err != nil {
+		return nil, err
+	}
+	if err := o.CheckChangeLock(ctx, ws.name); err != nil {
+		return nil, err
+	}
+	existing, ok, err := st.GetCustomComponent(ctx, ws.name, in.Name)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("failed to get custom component", err)
+	}
+	if !ok {
+		return nil, huma.Error404NotFound(fmt.Sprintf("custom component %s not found", in.Name))
+	}
+	cc, err := o.validateCustomSpec(ws, in.Name, in.Body)
+	if err != nil {
+		return nil, err
+	}
+	cc.CreatedAt = existing.CreatedAt
+	return o.saveCustomComponent(ctx, st, ws, cc)
+}
+
+func (o *OnRamp) saveCustomComponent(ctx context.Context, st store.Client, ws *workspace, cc store.CustomComponent) (*CustomComponentOutput, error) {
+	saved, err := st.SaveCustomComponent(ctx, cc)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("failed to save custom component", err)
+	}
+	ws.dropRegistry()
+	o.Log().Info("custom component saved", "workspace", ws.name, "component", cc.Name, "actions", len(cc.Actions))
+	return &CustomComponentOutput{Body: customComponent(saved)}, nil
+}
+
+// HandleDeleteCustomComponent removes a custom component. Its action
+// history and deployment state are kept.
+func (o *OnRamp) HandleDeleteCustomComponent(ctx context.Context, in *CustomComponentDeleteInput) (*CustomComponentDeleteOutput, error) {
+	st, err := o.requireStore("custom components are")
+	if err != nil {
+		return nil, err
+	}
+	ws, err := o.workspace(ctx, in.Workspace)
+	if err != nil {
+		return nil, err
+	}
+	if err := o.CheckChangeLock(ctx, ws.name); err != nil {
+		return nil, err
+	}
+	if err := st.DeleteCustomComponent(ctx, ws.name, in.Name); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, huma.Error404NotFound(fmt.Sprintf("custom component %s not found", in.Name))
+		}
+		return nil, huma.Error500InternalServerError("failed to delete custom component", err)
+	}
+	ws.dropRegistry()
+	out := &CustomComponentDeleteOutput{}
+	out.Body.Message = fmt.Sprintf("custom component %s deleted", in.Name)
+	return out, nil
+}
@@ -0,0 +1,258 @@
+package onramp
+
+import (
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bengrewell/aether-webui/internal/store"
+)
+
+// newCustomTestProvider returns a store-backed provider with playbook and
+// script directories.
+func newCustomTestProvider(t *testing.T) *OnRamp {
+	t.Helper()
+	o := newTestProviderWithStore(t, "")
+	o.config.PlaybookDir = t.TempDir()
+	o.config.ScriptDir = t.TempDir()
+	return o
+}
+
+func createCustom(t *testing.T, o *OnRamp, name string, spec CustomComponentSpec) (*CustomComponentOutput, error) {
+	t.Helper()
+	in := &CustomComponentCreateInput{}
+	in.Body.Name = name
+	in.Body.CustomComponentSpec = spec
+	return o.HandleCreateCustomComponent(t.Context(), in)
+}
+
+func TestCustomComponents_CRUD(t *testing.T) {
+	o := newCustomTestProvider(t)
+	tier := 4
+	spec := CustomComponentSpec{
+		Description: "Site monitoring",
+		Tier:        &tier,
+		Roles:       []string{"monitor"},
+		Actions: []Action{
+			{Name: "install", Kind: store.CustomActionPlaybook, Target: "monitoring/install.yml", Args: []string{"SITE=lab"}},
+			{Name: "uninstall", Target: "site-monitoring-clean"},
+		},
+	}
+	out, err := createCustom(t, o, "monitoring", spec)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if !out.Body.Custom || out.Body.Tier != 4 || out.Body.Actions[1].Kind != store.CustomActionMake {
+		t.Errorf("created = %+v", out.Body)
+	}
+
+	_, err = createCustom(t, o, "monitoring", spec)
+	wantStatus(t, err, 409)
+	_, err = createCustom(t, o, "k8s", spec)
+	wantStatus(t, err, 409)
+
+	// The registry picks the component up with its roles.
+	ws := o.defaultWorkspace()
+	c, ok := o.registry(ws).component("monitoring")
+	if !ok || !c.Custom {
+		t.Fatalf("registry component = %+v, %v", c, ok)
+	}
+	roles, err := o.Roles(t.Context(), "")
+	if err != nil {
+		t.Fatalf("Roles: %v", err)
+	}
+	if roles[len(roles)-1] != "monitor" {
+		t.Errorf("roles = %v, want monitor appended", roles)
+	}
+	got, err := o.HandleGetComponent(t.Context(), &ComponentGetInput{Component: "monitoring"})
+	if err != nil || got.Body.Description != "Site monitoring" {
+		t.Errorf("HandleGetComponent = %+v, %v", got, err)
+	}
+
+	spec.Description = "Prometheus and Grafana"
+	spec.Tier = nil
+	upd, err := o.HandleUpdateCustomComponent(t.Context(), &CustomComponentUpdateInput{Name: "monitoring", Body: spec})
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if upd.Body.Tier != defaultTier || upd.Body.Description != "Prometheus and Grafana" {
+		t.Errorf("updated = %+v", upd.Body)
+	}
+	if c, _ := o.registry(ws).component("monitoring"); c.Description != "Prometheus and Grafana" {
+		t.Errorf("registry kept the old definition: %+v", c)
+	}
+	_, err = o.HandleUpdateCustomComponent(t.Context(), &CustomComponentUpdateInput{Name: "missing", Body: spec})
+	wantStatus(t, err, 404)
+
+	list, err := o.HandleListCustomComponents(t.Context(), &WorkspaceInput{})
+	if err != nil || len(list.Body) != 1 {
+		t.Fatalf("list = %+v, %v", list, err)
+	}
+
+	if _, err := o.HandleDeleteCustomComponent(t.Context(), &CustomComponentDeleteInput{Name: "monitoring"}); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, ok := o.registry(ws).component("monitoring"); ok {
+		t.Error("deleted component still in the registry")
+	}
+	_, err = o.HandleGetCustomComponent(t.Context(), &CustomComponentGetInput{Name: "monitoring"})
+	wantStatus(t, err, 404)
+	_, err = o.HandleDeleteCustomComponent(t.Context(), &CustomComponentDeleteInput{Name: "monitoring"})
+	wantStatus(t, err, 404)
+}
+
+func TestCustomComponents_Validation(t *testing.T) {
+	o := newCustomTestProvider(t)
+	tests := []struct {
+		name   string
+		action Action
+		roles  []string
+	}{
+		{"unknown kind", Action{Name: "run", Kind: "docker", Target: "x"}, nil},
+		{"bad action name", Action{Name: "Run", Target: "x"}, nil},
+		{"option as make target", Action{Name: "run", Target: "-f/etc/passwd"}, nil},
+		{"option as make arg", Action{Name: "run", Target: "x", Args: []string{"-f/tmp/evil.mk"}}, nil},
+		{"make shell override", Action{Name: "run", Target: "x", Args: []string{"SHELL=/tmp/evil"}}, nil},
+		{"make var expansion", Action{Name: "run", Target: "x", Args: []string{"V=$(shell id)"}}, nil},
+		{"option as playbook arg", Action{Name: "run", Kind: store.CustomActionPlaybook, Target: "site.yml", Args: []string{"-e@/etc/x.yml"}}, nil},
+		{"extra playbook", Action{Name: "run", Kind: store.CustomActionPlaybook, Target: "site.yml", Args: []string{"/tmp/evil.yml"}}, nil},
+		{"playbook connection var", Action{Name: "run", Kind: store.CustomActionPlaybook, Target: "site.yml", Args: []string{"ansible_ssh_common_args=-oProxyCommand=/tmp/x"}}, nil},
+		{"playbook template", Action{Name: "run", Kind: store.CustomActionPlaybook, Target: "site.yml", Args: []string{"V={{lookup('pipe','id')}}"}}, nil},
+		{"absolute playbook", Action{Name: "run", Kind: store.CustomActionPlaybook, Target: "/etc/site.yml"}, nil},
+		{"playbook escapes dir", Action{Name: "run", Kind: store.CustomActionPlaybook, Target: "../site.yml"}, nil},
+		{"empty script", Action{Name: "run", Kind: store.CustomActionScript}, nil},
+		{"bad role", Action{Name: "run", Target: "x"}, []string{"Edge Nodes"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := createCustom(t, o, "site", CustomComponentSpec{Roles: tt.roles, Actions: []Action{tt.action}})
+			wantStatus(t, err, 422)
+		})
+	}
+
+	_, err := createCustom(t, o, "site", CustomComponentSpec{Actions: []Action{
+		{Name: "run", Target: "a"}, {Name: "run", Target: "b"},
+	}})
+	wantStatus(t, err, 422)
+
+	o.config.ScriptDir = ""
+	_, err = createCustom(t, o, "site", CustomComponentSpec{Actions: []Action{
+		{Name: "run", Kind: store.CustomActionScript, Target: "run.sh"},
+	}})
+	wantStatus(t, err, 422)
+}
+
+func TestCustomComponents_NoStore(t *testing.T) {
+	o := newTestProvider(t, "")
+	_, err := createCustom(t, o, "site", CustomComponentSpec{Actions: []Action{{Name: "run", Target: "x"}}})
+	wantStatus(t, err, 503)
+}
+
+func TestCommandFor(t *testing.T) {
+	o := newCustomTestProvider(t)
+	ws := o.defaultWorkspace()
+	dir := ws.config.OnRampDir
+	writeTestFile(t, filepath.Join(o.config.PlaybookDir, "site", "backup.yml"), "- hosts: all\n")
+
+	cmd, err := o.commandFor(ws, Action{Target: "aether-k8s-install"})
+	if err != nil || cmd.command != "make" || !reflect.DeepEqual(cmd.args, []string{"aether-k8s-install"}) {
+		t.Errorf("make = %+v, %v", cmd, err)
+	}
+
+	cmd, err = o.commandFor(ws, Action{Kind: store.CustomActionPlaybook, Target: "site/backup.yml", Args: []string{"DB=main"}})
+	if err != nil {
+		t.Fatalf("playbook: %v", err)
+	}
+	want := []string{
+		"-i", filepath.Join(dir, "hosts.ini"),
+		"--extra-vars", "ROOT_DIR=" + dir,
+		"--extra-vars", "@" + filepath.Join(dir, "vars", "main.yml"),
+		"--extra-vars", "DB=main",
+		filepath.Join(o.config.PlaybookDir, "site", "backup.yml"),
+	}
+	if cmd.command != "ansible-playbook" || !reflect.DeepEqual(cmd.args, want) {
+		t.Errorf("playbook = %+v", cmd)
+	}
+
+	if _, err := o.commandFor(ws, Action{Kind: store.CustomActionPlaybook, Target: "site/missing.yml"}); err == nil {
+		t.Error("expected error for a missing playbook")
+	}
+	if _, err := o.commandFor(ws, Action{Name: "reset"}); err == nil {
+		t.Error("expected error for an action without a target")
+	}
+}
+
+func TestCustomComponents_ExecuteScript(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available on PATH")
+	}
+	o := newCustomTestProvider(t)
+	script := filepath.Join(o.config.ScriptDir, "install.sh")
+	writeTestFile(t, script, "#!/bin/sh\necho \"$1 $ONRAMP_WORKSPACE\"\n")
+	if err := exec.Command("chmod", "+x", script).Run(); err != nil {
+		t.Fatalf("chmod: %v", err)
+	}
+	if _, err := createCustom(t, o, "backup", CustomComponentSpec{Actions: []Action{
+		{Name: "install", Kind: store.CustomActionScript, Target: "install.sh", Args: []string{"hello"}},
+	}}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	out, err := o.HandleExecuteAction(t.Context(), &ExecuteActionInput{Component: "backup", Action: "install"})
+	if err != nil {
+		t.Fatalf("HandleExecuteAction: %v", err)
+	}
+	if out.Body.Target != "install.sh" {
+		t.Errorf("target = %q", out.Body.Target)
+	}
+	waitForTask(t, o.runner, out.Body.ID, 5*time.Second)
+
+	chunk, err := o.runner.Output(out.Body.ID, 0)
+	if err != nil || !strings.Contains(chunk.Data, "hello "+store.DefaultWorkspace) {
+		t.Errorf("output = %q, %v", chunk.Data, err)
+	}
+	// OnComplete runs after the task turns terminal.
+	var cs store.ComponentState
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		cs, _, _ = o.Store().GetComponentState(t.Context(), "", "backup")
+		if cs.Status != "" {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if cs.Status != "installed" || cs.ActionID != out.Body.ID {
+		t.Errorf("component state = %+v", cs)
+	}
+	rec, ok, err := o.Store().GetAction(t.Context(), out.Body.ID)
+	if err != nil || !ok || rec.Component != "backup" || rec.Target != "install.sh" {
+		t.Errorf("action record = %+v, %v, %v", rec, ok, err)
+	}
+}
+
+func TestOrderActions_Custom(t *testing.T) {
+	reg := builtinRegistry().clone()
+	reg.addCustom([]store.CustomComponent{
+		{Name: "early", Tier: 0, Actions: []store.CustomAction{{Name: "install", Kind: store.CustomActionMake, Target: "early"}}},
+		{Name: "late", Tier: 9, Actions: []store.CustomAction{{Name: "install", Kind: store.CustomActionMake, Target: "late"}}},
+	}, func(string) {})
+
+	got := orderActions(reg, []ComponentActionPair{
+		{Component: "late", Action: "install"},
+		{Component: "5gc", Action: "install"},
+		{Component: "early", Action: "install"},
+	})
+	var names []string
+	for _, p := range got {
+		names = append(names, p.Component)
+	}
+	if want := []string{"early", "5gc", "late"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("order = %v, want %v", names, want)
+	}
+	if _, ok := builtinRegistry().component("early"); ok {
+		t.Error("addCustom changed the built-in registry")
+	}
+}
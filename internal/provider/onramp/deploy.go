@@ -13,28 +13,11 @@ import (
 	"github.com/bengrewell/aether-webui/internal/taskrunner"
 )
 
-// installTier maps component names to their dependency tier for ordering.
-// Lower tiers are installed first.
-var installTier = map[string]int{
-	"k8s":      0,
-	"5gc":      1,
-	"4gc":      1,
-	"amp":      2,
-	"sdran":    2,
-	"oscric":   2,
-	"gnbsim":   3,
-	"ueransim": 3,
-	"oai":      3,
-	"srsran":   3,
-	"n3iwf":    3,
-	"cluster":  4,
-}
-
-// orderActions sorts component/action pairs by dependency tier. Install actions
+// orderActions sorts component/action pairs by the registry's install tier. Install actions
 // sort ascending (dependencies first); all-uninstall sorts descending (dependents
 // first). Mixed actions use install order. Stable sort preserves user-specified
 // sub-order within the same tier.
-func orderActions(pairs []ComponentActionPair) []ComponentActionPair {
+func orderActions(reg *componentRegistry, pairs []ComponentActionPair) []ComponentActionPair {
 	out := make([]ComponentActionPair, len(pairs))
 	copy(out, pairs)
 
@@ -47,8 +30,8 @@ func orderActions(pairs []ComponentActionPair) []ComponentActionPair {
 	}
 
 	sort.SliceStable(out, func(i, j int) bool {
-		ti := installTier[out[i].Component]
-		tj := installTier[out[j].Component]
+		ti := reg.tier(out[i].Component)
+		tj := reg.tier(out[j].Component)
 		if allUninstall {
 			return ti > tj // reverse order for uninstall
 		}
@@ -60,39 +43,57 @@ func orderActions(pairs []ComponentActionPair) []ComponentActionPair {
 
 // HandleDeploy validates and submits a batch deployment.
 func (o *OnRamp) HandleDeploy(ctx context.Context, in *DeployInput) (*DeployOutput, error) {
+	ws, err := o.workspace(ctx, in.Workspace)
+	if err != nil {
+		return nil, err
+	}
 	if len(in.Body.Actions) == 0 {
 		return nil, huma.Error422UnprocessableEntity("actions list must not be empty")
 	}
 
 	// Validate all component/action pairs.
+	reg := o.registry(ws)
 	for _, pair := range in.Body.Actions {
-		comp, ok := componentIndex[pair.Component]
-		if !ok {
+		if _, ok := reg.component(pair.Component); !ok {
 			return nil, huma.Error422UnprocessableEntity(
 				fmt.Sprintf("unknown component: %s", pair.Component))
 		}
-		found := false
-		for _, a := range comp.Actions {
-			if a.Name == pair.Action {
-				found = true
-				break
-			}
-		}
-		if !found {
+		act, ok := reg.action(pair.Component, pair.Action)
+		if !ok {
 			return nil, huma.Error422UnprocessableEntity(
 				fmt.Sprintf("component %s has no action %s", pair.Component, pair.Action))
 		}
+		if _, err := o.commandFor(ws, act); err != nil {
+			return nil, huma.Error422UnprocessableEntity(
+				fmt.Sprintf("cannot run %s/%s: %v", pair.Component, pair.Action, err))
+		}
+	}
+
+	if !in.Body.SkipValidation {
+		if err := o.validateForDeploy(ctx, ws, in.Body.Actions); err != nil {
+			return nil, err
+		}
+		if err := o.checkTopologyForDeploy(ctx, ws, in.Body.Actions); err != nil {
+			return nil, err
+		}
 	}
 
-	ordered := orderActions(in.Body.Actions)
+	ordered := orderActions(reg, in.Body.Actions)
 
 	deployID := uuid.NewString()
 	now := time.Now().UTC()
 	st := o.Store()
 	log := o.Log()
 
+	// The deployment holds the change lock until it reaches a terminal state,
+	// which also rejects a second deployment while this one is in flight.
+	if err := o.acquireDeploymentLock(ctx, ws.name, deployID); err != nil {
+		return nil, err
+	}
+
 	dep := store.Deployment{
 		ID:        deployID,
+		Workspace: ws.name,
 		Status:    "running",
 		CreatedAt: now,
 		StartedAt: now,
@@ -115,14 +116,16 @@ func (o *OnRamp) HandleDeploy(ctx context.Context, in *DeployInput) (*DeployOutp
 	defer dbCancel()
 	if err := st.InsertDeployment(dbCtx, dep); err != nil {
 		log.Error("failed to insert deployment", "deployment_id", deployID, "error", err)
+		o.releaseDeploymentLock(ws.name, deployID)
 		return nil, huma.Error500InternalServerError("failed to create deployment", err)
 	}
 
 	// Insert action_history records for each action.
 	for _, da := range dep.Actions {
-		target := resolveTarget(da.Component, da.Action)
+		target := reg.target(da.Component, da.Action)
 		rec := store.ActionRecord{
 			ID:        da.ActionID,
+			Workspace: ws.name,
 			Component: da.Component,
 			Action:    da.Action,
 			Target:    target,
@@ -134,6 +137,7 @@ func (o *OnRamp) HandleDeploy(ctx context.Context, in *DeployInput) (*DeployOutp
 			log.Error("failed to insert action record for deployment", "action_id", da.ActionID, "error", err)
 			_ = st.UpdateDeploymentStatus(dbCtx, deployID, "failed", err.Error(), time.Now().UTC())
 			o.cancelRemainingActions(dep, 0)
+			o.releaseDeploymentLock(ws.name, deployID)
 			return nil, huma.Error500InternalServerError("failed to create deployment", err)
 		}
 	}
@@ -141,30 +145,30 @@ func (o *OnRamp) HandleDeploy(ctx context.Context, in *DeployInput) (*DeployOutp
 	// Submit the first action. The deployment is already "running" so there is
 	// no race if the task completes before this function returns.
 	first := dep.Actions[0]
-	target := resolveTarget(first.Component, first.Action)
+	act, _ := reg.action(first.Component, first.Action)
 
-	if err := o.submitDeploymentAction(dep, 0, first.ActionID, first.Component, first.Action, target); err != nil {
+	if err := o.submitDeploymentAction(ws, dep, 0, first.ActionID, first.Component, first.Action, act); err != nil {
 		_ = st.UpdateDeploymentStatus(dbCtx, deployID, "failed", err.Error(), time.Now().UTC())
 		o.cancelRemainingActions(dep, 0)
+		o.releaseDeploymentLock(ws.name, deployID)
 		return nil, huma.Error500InternalServerError("failed to start deployment", err)
 	}
 
 	return &DeployOutput{Body: o.buildDeploymentItem(ctx, dep)}, nil
 }
 
-// submitDeploymentAction submits one action from a deployment to the task runner
-// with chained OnComplete logic.
-func (o *OnRamp) submitDeploymentAction(dep store.Deployment, seq int, actionID, component, action, target string) error {
+// submitDeploymentAction submits one action from a deployment to the
+// workspace's task runner with chained OnComplete logic.
+func (o *OnRamp) submitDeploymentAction(ws *workspace, dep store.Deployment, seq int, actionID, component, action string, act Action) error {
 	st := o.Store()
 	log := o.Log()
 
-	baseOnComplete := buildOnComplete(st, log, actionID, component, action)
-	baseOnStart := buildOnStart(st, log, actionID, component, action)
+	baseOnComplete := buildOnComplete(st, log, ws.name, actionID, component, action)
+	baseOnStart := buildOnStart(st, log, ws.name, actionID, component, action)
 
+	// Runs once the action and its hooks are done; the action's own result
+	// is already recorded by baseOnComplete.
 	chainedOnComplete := func(v taskrunner.TaskView) {
-		// Run the standard action_history + component_state updates.
-		baseOnComplete(v)
-
 		dCtx, dCancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer dCancel()
 
@@ -186,14 +190,16 @@ func (o *OnRamp) submitDeploymentAction(dep store.Deployment, seq int, actionID,
 				if err := st.UpdateDeploymentStatus(dCtx, dep.ID, "succeeded", "", time.Now().UTC()); err != nil {
 					log.Error("failed to mark deployment succeeded", "deployment_id", dep.ID, "error", err)
 				}
+				o.releaseDeploymentLock(dep.Workspace, dep.ID)
 			} else {
 				// Submit the next action.
 				next := dep.Actions[seq+1]
-				nextTarget := resolveTarget(next.Component, next.Action)
-				if err := o.submitDeploymentAction(dep, seq+1, next.ActionID, next.Component, next.Action, nextTarget); err != nil {
+				nextAct, _ := o.registry(ws).action(next.Component, next.Action)
+				if err := o.submitDeploymentAction(ws, dep, seq+1, next.ActionID, next.Component, next.Action, nextAct); err != nil {
 					log.Error("failed to submit next deployment action", "deployment_id", dep.ID, "seq", seq+1, "error", err)
 					_ = st.UpdateDeploymentStatus(dCtx, dep.ID, "failed", err.Error(), time.Now().UTC())
 					o.cancelRemainingActions(dep, seq+1)
+					o.releaseDeploymentLock(dep.Workspace, dep.ID)
 				}
 			}
 
@@ -207,24 +213,30 @@ func (o *OnRamp) submitDeploymentAction(dep store.Deployment, seq int, actionID,
 			}
 			// Cancel remaining actions.
 			o.cancelRemainingActions(dep, seq+1)
+			o.releaseDeploymentLock(dep.Workspace, dep.ID)
 		}
 	}
 
-	_, err := o.runner.Submit(taskrunner.TaskSpec{
+	cmd, err := o.commandFor(ws, act)
+	if err != nil {
+		return fmt.Errorf("%s/%s: %w", component, action, err)
+	}
+	_, err = o.submitAction(ws, taskrunner.TaskSpec{
 		ID:          actionID,
-		Command:     "make",
-		Args:        []string{target},
-		Dir:         o.config.OnRampDir,
+		Command:     cmd.command,
+		Args:        cmd.args,
+		Env:         cmd.env,
+		Dir:         ws.config.OnRampDir,
 		Description: fmt.Sprintf("deploy:%s/%s", component, action),
 		Labels: map[string]string{
 			"component":     component,
 			"action":        action,
-			"target":        target,
+			"target":        act.Target,
 			"deployment_id": dep.ID,
 		},
 		OnStart:    baseOnStart,
-		OnComplete: chainedOnComplete,
-	})
+		OnComplete: baseOnComplete,
+	}, chainedOnComplete)
 	return err
 }
 
@@ -252,11 +264,15 @@ func (o *OnRamp) cancelRemainingActions(dep store.Deployment, startSeq int) {
 
 // HandleGetDeployment returns a single deployment with enriched action statuses.
 func (o *OnRamp) HandleGetDeployment(ctx context.Context, in *DeploymentGetInput) (*DeploymentGetOutput, error) {
+	ws, err := o.workspace(ctx, in.Workspace)
+	if err != nil {
+		return nil, err
+	}
 	dep, found, err := o.Store().GetDeployment(ctx, in.ID)
 	if err != nil {
 		return nil, huma.Error500InternalServerError("failed to get deployment", err)
 	}
-	if !found {
+	if !found || dep.Workspace != ws.name {
 		return nil, huma.Error404NotFound("deployment not found", fmt.Errorf("no deployment with id %s", in.ID))
 	}
 	return &DeploymentGetOutput{Body: o.buildDeploymentItem(ctx, dep)}, nil
@@ -264,10 +280,15 @@ func (o *OnRamp) HandleGetDeployment(ctx context.Context, in *DeploymentGetInput
 
 // HandleListDeployments returns a paginated list of deployments.
 func (o *OnRamp) HandleListDeployments(ctx context.Context, in *DeploymentListInput) (*DeploymentListOutput, error) {
+	ws, err := o.workspace(ctx, in.Workspace)
+	if err != nil {
+		return nil, err
+	}
 	deps, err := o.Store().ListDeployments(ctx, store.DeploymentFilter{
-		Status: in.Status,
-		Limit:  in.Limit,
-		Offset: in.Offset,
+		Workspace: ws.name,
+		Status:    in.Status,
+		Limit:     in.Limit,
+		Offset:    in.Offset,
 	})
 	if err != nil {
 		return nil, huma.Error500InternalServerError("failed to list deployments", err)
@@ -282,13 +303,17 @@ func (o *OnRamp) HandleListDeployments(ctx context.Context, in *DeploymentListIn
 
 // HandleCancelDeployment cancels a running or pending deployment.
 func (o *OnRamp) HandleCancelDeployment(ctx context.Context, in *DeploymentCancelInput) (*DeploymentCancelOutput, error) {
+	ws, err := o.workspace(ctx, in.Workspace)
+	if err != nil {
+		return nil, err
+	}
 	st := o.Store()
 
 	dep, found, err := st.GetDeployment(ctx, in.ID)
 	if err != nil {
 		return nil, huma.Error500InternalServerError("failed to get deployment", err)
 	}
-	if !found {
+	if !found || dep.Workspace != ws.name {
 		return nil, huma.Error404NotFound("deployment not found", fmt.Errorf("no deployment with id %s", in.ID))
 	}
 
@@ -309,7 +334,10 @@ func (o *OnRamp) HandleCancelDeployment(ctx context.Context, in *DeploymentCance
 	// ErrNotFound/ErrNotRunning are expected for tasks that haven't started or
 	// have already finished.
 	for _, a := range dep.Actions {
-		_ = o.runner.Cancel(a.ActionID)
+		_ = ws.runner.Cancel(a.ActionID)
+		for _, v := range ws.runner.List(&taskrunner.ListFilter{Label: map[string]string{"parent_action_id": a.ActionID}}) {
+			_ = ws.runner.Cancel(v.ID)
+		}
 
 		rec, ok, err := st.GetAction(ctx, a.ActionID)
 		if err != nil || !ok {
@@ -326,6 +354,8 @@ func (o *OnRamp) HandleCancelDeployment(ctx context.Context, in *DeploymentCance
 		}
 	}
 
+	o.releaseDeploymentLock(dep.Workspace, dep.ID)
+
 	out := &DeploymentCancelOutput{}
 	out.Body.Message = fmt.Sprintf("deployment %s canceled", in.ID)
 	return out, nil
@@ -337,9 +367,10 @@ func (o *OnRamp) buildDeploymentItem(ctx context.Context, dep store.Deployment)
 	st := o.Store()
 
 	item := DeploymentItem{
-		ID:      dep.ID,
-		Status:  dep.Status,
-		Actions: make([]DeploymentActionItem, len(dep.Actions)),
+		ID:        dep.ID,
+		Workspace: dep.Workspace,
+		Status:    dep.Status,
+		Actions:   make([]DeploymentActionItem, len(dep.Actions)),
 	}
 	if !dep.CreatedAt.IsZero() {
 		item.CreatedAt = dep.CreatedAt.Unix()
@@ -363,23 +394,9 @@ func (o *OnRamp) buildDeploymentItem(ctx context.Context, dep store.Deployment)
 		if rec, ok, err := st.GetAction(ctx, a.ActionID); err == nil && ok {
 			dai.Status = rec.Status
 		}
+		dai.Hooks = o.hookRunItems(ctx, a.ActionID)
 		item.Actions[i] = dai
 	}
 
 	return item
 }
-
-// resolveTarget looks up the Makefile target for a component/action pair.
-func resolveTarget(component, action string) string {
-	comp, ok := componentIndex[component]
-	if !ok {
-		return ""
-	}
-	for _, a := range comp.Actions {
-		if a.Name == action {
-			return a.Target
-		}
-	}
-	return ""
-}
-
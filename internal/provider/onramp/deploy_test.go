@@ -19,7 +19,7 @@ func TestOrderActions_Install(t *testing.T) {
 		{Component: "5gc", Action: "install"},
 		{Component: "k8s", Action: "install"},
 	}
-	got := orderActions(input)
+	got := orderActions(builtinRegistry(), input)
 
 	want := []string{"k8s", "5gc", "srsran"}
 	for i, w := range want {
@@ -35,7 +35,7 @@ func TestOrderActions_Uninstall(t *testing.T) {
 		{Component: "5gc", Action: "uninstall"},
 		{Component: "srsran", Action: "gnb-uninstall"},
 	}
-	got := orderActions(input)
+	got := orderActions(builtinRegistry(), input)
 
 	// Reverse order: srsran (tier 3) > 5gc (tier 1) > k8s (tier 0)
 	want := []string{"srsran", "5gc", "k8s"}
@@ -52,7 +52,7 @@ func TestOrderActions_Mixed(t *testing.T) {
 		{Component: "k8s", Action: "uninstall"},
 		{Component: "5gc", Action: "install"},
 	}
-	got := orderActions(input)
+	got := orderActions(builtinRegistry(), input)
 
 	// Mixed uses install order: k8s (0), 5gc (1), srsran (3)
 	want := []string{"k8s", "5gc", "srsran"}
@@ -69,7 +69,7 @@ func TestOrderActions_StableSort(t *testing.T) {
 		{Component: "sdran", Action: "install"},
 		{Component: "oscric", Action: "ric-install"},
 	}
-	got := orderActions(input)
+	got := orderActions(builtinRegistry(), input)
 
 	// All tier 2 — stable sort preserves original order.
 	want := []string{"amp", "sdran", "oscric"}
@@ -152,7 +152,7 @@ func TestSubmitDeploymentAction_Chain(t *testing.T) {
 			ID:        a.ActionID,
 			Component: a.Component,
 			Action:    a.Action,
-			Target:    resolveTarget(a.Component, a.Action),
+			Target:    builtinRegistry().target(a.Component, a.Action),
 			Status:    "pending",
 			ExitCode:  -1,
 			StartedAt: time.Now().UTC(),
@@ -172,12 +172,12 @@ func TestSubmitDeploymentAction_Chain(t *testing.T) {
 		Labels: map[string]string{
 			"component":     first.Component,
 			"action":        first.Action,
-			"target":        resolveTarget(first.Component, first.Action),
+			"target":        builtinRegistry().target(first.Component, first.Action),
 			"deployment_id": dep.ID,
 		},
-		OnStart: buildOnStart(st, o.Log(), first.ActionID, first.Component, first.Action),
+		OnStart: buildOnStart(st, o.Log(), store.DefaultWorkspace, first.ActionID, first.Component, first.Action),
 		OnComplete: func(v taskrunner.TaskView) {
-			buildOnComplete(st, o.Log(), first.ActionID, first.Component, first.Action)(v)
+			buildOnComplete(st, o.Log(), store.DefaultWorkspace, first.ActionID, first.Component, first.Action)(v)
 			// Simulate the chaining: on success, submit next action.
 			if v.Status == taskrunner.StatusSucceeded && len(dep.Actions) > 1 {
 				next := dep.Actions[1]
@@ -187,7 +187,7 @@ func TestSubmitDeploymentAction_Chain(t *testing.T) {
 					Args:        []string{"ok"},
 					Description: "test chain 2",
 					OnComplete: func(v2 taskrunner.TaskView) {
-						buildOnComplete(st, o.Log(), next.ActionID, next.Component, next.Action)(v2)
+						buildOnComplete(st, o.Log(), store.DefaultWorkspace, next.ActionID, next.Component, next.Action)(v2)
 						if v2.Status == taskrunner.StatusSucceeded {
 							st.UpdateDeploymentStatus(ctx, dep.ID, "succeeded", "", time.Now().UTC())
 						}
@@ -253,7 +253,7 @@ func TestSubmitDeploymentAction_FailFast(t *testing.T) {
 			ID:        a.ActionID,
 			Component: a.Component,
 			Action:    a.Action,
-			Target:    resolveTarget(a.Component, a.Action),
+			Target:    builtinRegistry().target(a.Component, a.Action),
 			Status:    "pending",
 			ExitCode:  -1,
 			StartedAt: time.Now().UTC(),
@@ -265,7 +265,7 @@ func TestSubmitDeploymentAction_FailFast(t *testing.T) {
 
 	// Submit using "false" which always exits with code 1.
 	first := dep.Actions[0]
-	baseOnComplete := buildOnComplete(st, o.Log(), first.ActionID, first.Component, first.Action)
+	baseOnComplete := buildOnComplete(st, o.Log(), store.DefaultWorkspace, first.ActionID, first.Component, first.Action)
 	_, err := o.runner.Submit(taskrunner.TaskSpec{
 		ID:      first.ActionID,
 		Command: "false",
@@ -516,7 +516,7 @@ func TestHandleCancelDeployment_RunningAction(t *testing.T) {
 	}
 }
 
-func TestResolveTarget(t *testing.T) {
+func TestRegistryTarget(t *testing.T) {
 	tests := []struct {
 		comp, action, want string
 	}{
@@ -527,9 +527,9 @@ func TestResolveTarget(t *testing.T) {
 		{"k8s", "nonexistent", ""},
 	}
 	for _, tt := range tests {
-		got := resolveTarget(tt.comp, tt.action)
+		got := builtinRegistry().target(tt.comp, tt.action)
 		if got != tt.want {
-			t.Errorf("resolveTarget(%q, %q) = %q, want %q", tt.comp, tt.action, got, tt.want)
+			t.Errorf("target(%q, %q) = %q, want %q", tt.comp, tt.action, got, tt.want)
 		}
 	}
 }
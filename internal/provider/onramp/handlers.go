@@ -2,9 +2,9 @@ package onramp
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
+	"mime"
 	"os"
 	"path/filepath"
 	"strings"
@@ -22,53 +22,77 @@ import (
 // Repo handlers
 // ---------------------------------------------------------------------------
 
-func (o *OnRamp) HandleGetRepoStatus(_ context.Context, _ *struct{}) (*RepoStatusOutput, error) {
-	status := o.gatherRepoStatus()
+func (o *OnRamp) HandleGetRepoStatus(ctx context.Context, in *WorkspaceInput) (*RepoStatusOutput, error) {
+	ws, err := o.workspace(ctx, in.workspaceName())
+	if err != nil {
+		return nil, err
+	}
+	status := gatherRepoStatus(ws)
 	return &RepoStatusOutput{Body: status}, nil
 }
 
-func (o *OnRamp) HandleRefreshRepo(_ context.Context, _ *struct{}) (*RepoRefreshOutput, error) {
-	log := o.Log()
-	if err := ensureRepo(o.config, log); err != nil {
-		o.SetDegraded(fmt.Sprintf("repo setup: %v", err))
-		status := o.gatherRepoStatus()
+func (o *OnRamp) HandleRefreshRepo(ctx context.Context, in *WorkspaceInput) (*RepoRefreshOutput, error) {
+	ws, err := o.workspace(ctx, in.workspaceName())
+	if err != nil {
+		return nil, err
+	}
+	if err := o.CheckChangeLock(ctx, ws.name); err != nil {
+		return nil, err
+	}
+
+	// Only the default workspace's checkout affects provider health; the
+	// others report failures through their own repo status.
+	isDefault := ws.name == store.DefaultWorkspace
+	log := o.Log().With("workspace", ws.name)
+	if err := ensureRepo(ws.currentConfig(), log); err != nil {
+		if isDefault {
+			o.SetDegraded(fmt.Sprintf("repo setup: %v", err))
+		}
+		status := gatherRepoStatus(ws)
 		status.Error = err.Error()
 		return &RepoRefreshOutput{Body: status}, nil
 	}
-	o.ClearDegraded()
-	status := o.gatherRepoStatus()
+	if isDefault {
+		o.ClearDegraded()
+	}
+	status := gatherRepoStatus(ws)
 	return &RepoRefreshOutput{Body: status}, nil
 }
 
-// gatherRepoStatus inspects the OnRamp directory and returns its git state.
-func (o *OnRamp) gatherRepoStatus() RepoStatus {
+// gatherRepoStatus inspects a workspace's OnRamp directory and returns its
+// git state.
+func gatherRepoStatus(ws *workspace) RepoStatus {
+	cfg := ws.currentConfig()
+	dir := cfg.OnRampDir
 	rs := RepoStatus{
-		Dir:     o.config.OnRampDir,
-		RepoURL: o.config.RepoURL,
-		Version: o.config.Version,
+		Workspace: ws.name,
+		Dir:       dir,
+		RepoURL:   cfg.RepoURL,
+		Version:   cfg.Version,
 	}
 
-	gitDir := filepath.Join(o.config.OnRampDir, ".git")
+	gitDir := filepath.Join(dir, ".git")
 	if info, err := os.Stat(gitDir); err != nil || !info.IsDir() {
 		return rs
 	}
 	rs.Cloned = true
+	rs.Bundle = readProvenance(dir)
 
-	if commit, err := gitOutput(o.config.OnRampDir, "rev-parse", "HEAD"); err == nil {
+	if commit, err := gitOutput(dir, "rev-parse", "HEAD"); err == nil {
 		rs.Commit = commit
 	}
 
-	if branch, err := gitOutput(o.config.OnRampDir, "rev-parse", "--abbrev-ref", "HEAD"); err == nil {
+	if branch, err := gitOutput(dir, "rev-parse", "--abbrev-ref", "HEAD"); err == nil {
 		rs.Branch = branch
 	}
 
 	// Resolve the tag pointing at HEAD, if any.
-	if tag, err := gitOutput(o.config.OnRampDir, "describe", "--tags", "--exact-match", "HEAD"); err == nil {
+	if tag, err := gitOutput(dir, "describe", "--tags", "--exact-match", "HEAD"); err == nil {
 		rs.Tag = tag
 	}
 
 	// A non-empty output from `git status --porcelain` indicates uncommitted changes.
-	if porcelain, err := gitOutput(o.config.OnRampDir, "status", "--porcelain"); err == nil && porcelain != "" {
+	if porcelain, err := gitOutput(dir, "status", "--porcelain"); err == nil && porcelain != "" {
 		rs.Dirty = true
 	}
 
@@ -79,12 +103,20 @@ func (o *OnRamp) gatherRepoStatus() RepoStatus {
 // Component handlers
 // ---------------------------------------------------------------------------
 
-func (o *OnRamp) HandleListComponents(_ context.Context, _ *struct{}) (*ComponentListOutput, error) {
-	return &ComponentListOutput{Body: componentRegistry}, nil
+func (o *OnRamp) HandleListComponents(ctx context.Context, in *WorkspaceInput) (*ComponentListOutput, error) {
+	ws, err := o.workspace(ctx, in.workspaceName())
+	if err != nil {
+		return nil, err
+	}
+	return &ComponentListOutput{Body: o.registry(ws).components}, nil
 }
 
-func (o *OnRamp) HandleGetComponent(_ context.Context, in *ComponentGetInput) (*ComponentGetOutput, error) {
-	comp, ok := componentIndex[in.Component]
+func (o *OnRamp) HandleGetComponent(ctx context.Context, in *ComponentGetInput) (*ComponentGetOutput, error) {
+	ws, err := o.workspace(ctx, in.Workspace)
+	if err != nil {
+		return nil, err
+	}
+	comp, ok := o.registry(ws).component(in.Component)
 	if !ok {
 		return nil, huma.Error404NotFound("component not found", fmt.Errorf("unknown component: %s", in.Component))
 	}
@@ -92,22 +124,28 @@ func (o *OnRamp) HandleGetComponent(_ context.Context, in *ComponentGetInput) (*
 }
 
 func (o *OnRamp) HandleExecuteAction(ctx context.Context, in *ExecuteActionInput) (*ExecuteActionOutput, error) {
-	comp, ok := componentIndex[in.Component]
-	if !ok {
-		return nil, huma.Error404NotFound("component not found", fmt.Errorf("unknown component: %s", in.Component))
+	ws, err := o.workspace(ctx, in.Workspace)
+	if err != nil {
+		return nil, err
+	}
+	if err := o.CheckChangeLock(ctx, ws.name); err != nil {
+		return nil, err
 	}
 
-	var target string
-	for _, a := range comp.Actions {
-		if a.Name == in.Action {
-			target = a.Target
-			break
-		}
+	reg := o.registry(ws)
+	if _, ok := reg.component(in.Component); !ok {
+		return nil, huma.Error404NotFound("component not found", fmt.Errorf("unknown component: %s", in.Component))
 	}
-	if target == "" {
+	act, ok := reg.action(in.Component, in.Action)
+	if !ok {
 		return nil, huma.Error404NotFound("action not found",
 			fmt.Errorf("component %s has no action %s", in.Component, in.Action))
 	}
+	cmd, err := o.commandFor(ws, act)
+	if err != nil {
+		return nil, huma.Error422UnprocessableEntity(fmt.Sprintf("cannot run %s/%s: %v", in.Component, in.Action, err))
+	}
+	target := act.Target
 
 	// Extract optional labels/tags from the request body.
 	labels, tags := map[string]string(nil), []string(nil)
@@ -129,6 +167,7 @@ func (o *OnRamp) HandleExecuteAction(ctx context.Context, in *ExecuteActionInput
 
 	rec := store.ActionRecord{
 		ID:        actionID,
+		Workspace: ws.name,
 		Component: in.Component,
 		Action:    in.Action,
 		Target:    target,
@@ -142,20 +181,21 @@ func (o *OnRamp) HandleExecuteAction(ctx context.Context, in *ExecuteActionInput
 		log.Error("failed to insert action record", "action_id", actionID, "error", err)
 	}
 
-	view, err := o.runner.Submit(taskrunner.TaskSpec{
+	view, err := o.submitAction(ws, taskrunner.TaskSpec{
 		ID:          actionID,
-		Command:     "make",
-		Args:        []string{target},
-		Dir:         o.config.OnRampDir,
+		Command:     cmd.command,
+		Args:        cmd.args,
+		Env:         cmd.env,
+		Dir:         ws.config.OnRampDir,
 		Description: fmt.Sprintf("%s/%s", in.Component, in.Action),
 		Labels: map[string]string{
 			"component": in.Component,
 			"action":    in.Action,
 			"target":    target,
 		},
-		OnComplete: buildOnComplete(st, log, actionID, in.Component, in.Action),
-		OnStart:    buildOnStart(st, log, actionID, in.Component, in.Action),
-	})
+		OnComplete: buildOnComplete(st, log, ws.name, actionID, in.Component, in.Action),
+		OnStart:    buildOnStart(st, log, ws.name, actionID, in.Component, in.Action),
+	}, nil)
 	if err != nil {
 		// Submit failed — mark the already-inserted action as failed.
 		failResult := store.ActionResult{
@@ -177,22 +217,30 @@ func (o *OnRamp) HandleExecuteAction(ctx context.Context, in *ExecuteActionInput
 // Task handlers
 // ---------------------------------------------------------------------------
 
-func (o *OnRamp) HandleListTasks(_ context.Context, _ *struct{}) (*TaskListOutput, error) {
-	views := o.runner.List(nil)
+func (o *OnRamp) HandleListTasks(ctx context.Context, in *WorkspaceInput) (*TaskListOutput, error) {
+	ws, err := o.workspace(ctx, in.workspaceName())
+	if err != nil {
+		return nil, err
+	}
+	views := ws.runner.List(nil)
 	out := make([]OnRampTask, len(views))
 	for i, v := range views {
-		chunk, _ := o.runner.Output(v.ID, 0)
+		chunk, _ := ws.runner.Output(v.ID, 0)
 		out[i] = toOnRampTask(v, chunk.Data, chunk.NewOffset)
 	}
 	return &TaskListOutput{Body: out}, nil
 }
 
-func (o *OnRamp) HandleGetTask(_ context.Context, in *TaskGetInput) (*TaskGetOutput, error) {
-	view, err := o.runner.Get(in.ID)
+func (o *OnRamp) HandleGetTask(ctx context.Context, in *TaskGetInput) (*TaskGetOutput, error) {
+	ws, err := o.workspace(ctx, in.Workspace)
+	if err != nil {
+		return nil, err
+	}
+	view, err := ws.runner.Get(in.ID)
 	if err != nil {
 		return nil, huma.Error404NotFound("task not found", fmt.Errorf("no task with id %s", in.ID))
 	}
-	chunk, _ := o.runner.Output(in.ID, in.Offset)
+	chunk, _ := ws.runner.Output(in.ID, in.Offset)
 	return &TaskGetOutput{Body: toOnRampTask(view, chunk.Data, chunk.NewOffset)}, nil
 }
 
@@ -201,7 +249,12 @@ func (o *OnRamp) HandleGetTask(_ context.Context, in *TaskGetInput) (*TaskGetOut
 // ---------------------------------------------------------------------------
 
 func (o *OnRamp) HandleListActions(ctx context.Context, in *ActionListInput) (*ActionListOutput, error) {
+	ws, err := o.workspace(ctx, in.Workspace)
+	if err != nil {
+		return nil, err
+	}
 	recs, err := o.Store().ListActions(ctx, store.ActionFilter{
+		Workspace: ws.name,
 		Component: in.Component,
 		Action:    in.Action,
 		Status:    in.Status,
@@ -219,19 +272,26 @@ func (o *OnRamp) HandleListActions(ctx context.Context, in *ActionListInput) (*A
 }
 
 func (o *OnRamp) HandleGetAction(ctx context.Context, in *ActionGetInput) (*ActionGetOutput, error) {
+	ws, err := o.workspace(ctx, in.Workspace)
+	if err != nil {
+		return nil, err
+	}
 	rec, ok, err := o.Store().GetAction(ctx, in.ID)
 	if err != nil {
 		return nil, huma.Error500InternalServerError("failed to get action", err)
 	}
-	if !ok {
+	if !ok || rec.Workspace != ws.name {
 		return nil, huma.Error404NotFound("action not found", fmt.Errorf("no action with id %s", in.ID))
 	}
-	return &ActionGetOutput{Body: actionRecordToItem(rec)}, nil
+	item := actionRecordToItem(rec)
+	item.Hooks = o.hookRunItems(ctx, rec.ID)
+	return &ActionGetOutput{Body: item}, nil
 }
 
 func actionRecordToItem(r store.ActionRecord) ActionHistoryItem {
 	item := ActionHistoryItem{
 		ID:        r.ID,
+		Workspace: r.Workspace,
 		Component: r.Component,
 		Action:    r.Action,
 		Target:    r.Target,
@@ -252,8 +312,12 @@ func actionRecordToItem(r store.ActionRecord) ActionHistoryItem {
 // Component state handlers
 // ---------------------------------------------------------------------------
 
-func (o *OnRamp) HandleListComponentStates(ctx context.Context, _ *struct{}) (*ComponentStateListOutput, error) {
-	states, err := o.Store().ListComponentStates(ctx)
+func (o *OnRamp) HandleListComponentStates(ctx context.Context, in *WorkspaceInput) (*ComponentStateListOutput, error) {
+	ws, err := o.workspace(ctx, in.workspaceName())
+	if err != nil {
+		return nil, err
+	}
+	states, err := o.Store().ListComponentStates(ctx, ws.name)
 	if err != nil {
 		return nil, huma.Error500InternalServerError("failed to list component states", err)
 	}
@@ -265,8 +329,9 @@ func (o *OnRamp) HandleListComponentStates(ctx context.Context, _ *struct{}) (*C
 	}
 
 	// Emit an entry for every registered component, defaulting to not_installed.
-	items := make([]ComponentStateItem, 0, len(componentRegistry))
-	for _, comp := range componentRegistry {
+	components := o.registry(ws).components
+	items := make([]ComponentStateItem, 0, len(components))
+	for _, comp := range components {
 		if s, ok := stateMap[comp.Name]; ok {
 			items = append(items, componentStateToItem(s))
 		} else {
@@ -280,12 +345,15 @@ func (o *OnRamp) HandleListComponentStates(ctx context.Context, _ *struct{}) (*C
 }
 
 func (o *OnRamp) HandleGetComponentState(ctx context.Context, in *ComponentStateGetInput) (*ComponentStateGetOutput, error) {
+	ws, err := o.workspace(ctx, in.Workspace)
+	if err != nil {
+		return nil, err
+	}
 	// Validate the component name against the registry.
-	if _, ok := componentIndex[in.Component]; !ok {
+	if _, ok := o.registry(ws).component(in.Component); !ok {
 		return nil, huma.Error404NotFound("component not found", fmt.Errorf("unknown component: %s", in.Component))
 	}
-
-	cs, ok, err := o.Store().GetComponentState(ctx, in.Component)
+	cs, ok, err := o.Store().GetComponentState(ctx, ws.name, in.Component)
 	if err != nil {
 		return nil, huma.Error500InternalServerError("failed to get component state", err)
 	}
@@ -315,93 +383,110 @@ func componentStateToItem(cs store.ComponentState) ComponentStateItem {
 // Config handlers
 // ---------------------------------------------------------------------------
 
-func (o *OnRamp) HandleGetConfig(_ context.Context, _ *struct{}) (*ConfigGetOutput, error) {
-	cfg, err := o.readVarsFile(filepath.Join(o.config.OnRampDir, "vars", "main.yml"))
+func (o *OnRamp) HandleGetConfig(ctx context.Context, in *WorkspaceInput) (*ConfigGetOutput, error) {
+	ws, err := o.workspace(ctx, in.workspaceName())
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(ws.config.OnRampDir, "vars", "main.yml"))
 	if err != nil {
 		return nil, huma.Error500InternalServerError("failed to read config", err)
 	}
-	return &ConfigGetOutput{Body: cfg}, nil
+	var cfg OnRampConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, huma.Error500InternalServerError("failed to read config", err)
+	}
+	return &ConfigGetOutput{ETag: configETag(data), Body: cfg}, nil
 }
 
-func (o *OnRamp) HandlePatchConfig(_ context.Context, in *ConfigPatchInput) (*ConfigPatchOutput, error) {
-	mainYML := filepath.Join(o.config.OnRampDir, "vars", "main.yml")
-
-	base, err := o.readVarsFile(mainYML)
+// HandlePatchConfig applies a merge patch or JSON Patch to vars/main.yml.
+// The read, the If-Match check and the write happen under the checkout's
+// vars lock, so a stale ETag cannot slip past a concurrent write.
+func (o *OnRamp) HandlePatchConfig(ctx context.Context, in *ConfigPatchInput) (*ConfigPatchOutput, error) {
+	ws, err := o.workspace(ctx, in.Workspace)
 	if err != nil {
-		return nil, huma.Error500InternalServerError("failed to read current config", err)
+		return nil, err
 	}
-
-	merged, err := deepMergeConfig(&base, in.RawBody)
-	if err != nil {
-		return nil, huma.Error500InternalServerError("failed to merge config", err)
+	if err := o.CheckChangeLock(ctx, ws.name); err != nil {
+		return nil, err
 	}
 
-	if err := o.writeVarsFile(mainYML, &merged); err != nil {
-		return nil, huma.Error500InternalServerError("failed to write config", err)
-	}
-	return &ConfigPatchOutput{Body: merged}, nil
-}
+	defer LockVars(ws.config.OnRampDir)()
 
-// ---------------------------------------------------------------------------
-// Profile handlers
-// ---------------------------------------------------------------------------
+	mainYML := filepath.Join(ws.config.OnRampDir, "vars", "main.yml")
 
-func (o *OnRamp) HandleListProfiles(_ context.Context, _ *struct{}) (*ProfileListOutput, error) {
-	pattern := filepath.Join(o.config.OnRampDir, "vars", "main-*.yml")
-	matches, err := filepath.Glob(pattern)
+	base, err := os.ReadFile(mainYML)
 	if err != nil {
-		return nil, huma.Error500InternalServerError("failed to list profiles", err)
+		return nil, huma.Error500InternalServerError("failed to read current config", err)
 	}
-	names := make([]string, 0, len(matches))
-	for _, m := range matches {
-		base := filepath.Base(m)
-		name := strings.TrimPrefix(base, "main-")
-		name = strings.TrimSuffix(name, ".yml")
-		names = append(names, name)
+	if in.IfMatch != "" && !etagMatches(in.IfMatch, configETag(base)) {
+		return nil, huma.Error412PreconditionFailed("config has changed since it was read; fetch it again and reapply the patch")
 	}
-	return &ProfileListOutput{Body: names}, nil
-}
 
-func (o *OnRamp) HandleGetProfile(_ context.Context, in *ProfileGetInput) (*ProfileGetOutput, error) {
-	path := filepath.Join(o.config.OnRampDir, "vars", fmt.Sprintf("main-%s.yml", in.Name))
-	cfg, err := o.readVarsFile(path)
+	doc, err := parseVarsDoc(base)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, huma.Error404NotFound("profile not found",
-				fmt.Errorf("no profile named %s", in.Name))
-		}
-		return nil, huma.Error500InternalServerError("failed to read profile", err)
+		return nil, huma.Error500InternalServerError("failed to parse current config", err)
+	}
+	if isJSONPatch(in.ContentType) {
+		err = doc.jsonPatch(in.RawBody)
+	} else {
+		err = doc.patch(in.RawBody)
+	}
+	switch {
+	case errors.Is(err, errBadPatch):
+		return nil, huma.Error400BadRequest(err.Error())
+	case errors.Is(err, errPatchTest):
+		return nil, huma.Error409Conflict(err.Error())
+	case err != nil:
+		return nil, huma.Error422UnprocessableEntity(err.Error())
+	}
+	merged, err := doc.config()
+	if err != nil {
+		return nil, huma.Error422UnprocessableEntity("patched config does not match the config schema", err)
 	}
-	return &ProfileGetOutput{Body: cfg}, nil
-}
-
-func (o *OnRamp) HandleActivateProfile(_ context.Context, in *ProfileActivateInput) (*ProfileActivateOutput, error) {
-	src := filepath.Join(o.config.OnRampDir, "vars", fmt.Sprintf("main-%s.yml", in.Name))
-	dst := filepath.Join(o.config.OnRampDir, "vars", "main.yml")
-
-	srcFile, err := os.Open(src)
+	data, err := doc.bytes()
 	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, huma.Error404NotFound("profile not found",
-				fmt.Errorf("no profile named %s", in.Name))
+		return nil, huma.Error500InternalServerError("failed to encode config", err)
+	}
+
+	// Reject only the errors the patch introduces, so a config that is
+	// already broken can still be fixed one field at a time.
+	if !in.Force {
+		var before OnRampConfig
+		_ = yaml.Unmarshal(base, &before)
+		nodes := o.validationNodes(ctx, ws)
+		if issues := newIssues(validateConfig(&before, nodes), validateConfig(&merged, nodes)); len(issues) > 0 {
+			return nil, validationError("patch introduces validation errors; fix them or set force", issues)
 		}
-		return nil, huma.Error500InternalServerError("failed to open profile", err)
 	}
-	defer srcFile.Close()
 
-	dstFile, err := os.Create(dst)
-	if err != nil {
-		return nil, huma.Error500InternalServerError("failed to write main.yml", err)
+	if err := o.saveVars(ctx, ws, data, "patch", "", in.Author); err != nil {
+		return nil, huma.Error500InternalServerError("failed to write config", err)
 	}
-	defer dstFile.Close()
+	return &ConfigPatchOutput{ETag: configETag(data), Body: merged}, nil
+}
 
-	if _, err := io.Copy(dstFile, srcFile); err != nil {
-		return nil, huma.Error500InternalServerError("failed to copy profile", err)
+// configETag returns the strong ETag for a vars file's content.
+func configETag(data []byte) string {
+	return `"` + contentHash(data) + `"`
+}
+
+// etagMatches reports whether an If-Match header matches etag. Weak tags
+// never match, as RFC 9110 requires strong comparison for If-Match.
+func etagMatches(ifMatch, etag string) bool {
+	for _, t := range strings.Split(ifMatch, ",") {
+		t = strings.TrimSpace(t)
+		if t == "*" || t == etag {
+			return true
+		}
 	}
+	return false
+}
 
-	out := &ProfileActivateOutput{}
-	out.Body.Message = fmt.Sprintf("profile %q activated", in.Name)
-	return out, nil
+// isJSONPatch reports whether a Content-Type selects RFC 6902 JSON Patch.
+func isJSONPatch(contentType string) bool {
+	mt, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mt == mediaJSONPatch
 }
 
 // ---------------------------------------------------------------------------
@@ -419,67 +504,3 @@ func (o *OnRamp) readVarsFile(path string) (OnRampConfig, error) {
 	}
 	return cfg, nil
 }
-
-func (o *OnRamp) writeVarsFile(path string, cfg *OnRampConfig) error {
-	data, err := yaml.Marshal(cfg)
-	if err != nil {
-		return fmt.Errorf("marshal config: %w", err)
-	}
-	return os.WriteFile(path, data, 0o644)
-}
-
-// ---------------------------------------------------------------------------
-// Config merge
-// ---------------------------------------------------------------------------
-
-// deepMergeConfig performs a recursive deep merge of patchJSON into base. The
-// patch is parsed directly from the raw request JSON so that explicitly set
-// zero values (e.g. empty strings) are preserved — struct-level omitempty tags
-// do not interfere with field presence detection.
-func deepMergeConfig(base *OnRampConfig, patchJSON []byte) (OnRampConfig, error) {
-	baseData, err := json.Marshal(base)
-	if err != nil {
-		return OnRampConfig{}, fmt.Errorf("marshal base: %w", err)
-	}
-	var baseMap map[string]any
-	if err := json.Unmarshal(baseData, &baseMap); err != nil {
-		return OnRampConfig{}, fmt.Errorf("unmarshal base: %w", err)
-	}
-
-	var patchMap map[string]any
-	if err := json.Unmarshal(patchJSON, &patchMap); err != nil {
-		return OnRampConfig{}, fmt.Errorf("unmarshal patch: %w", err)
-	}
-
-	deepMergeMaps(baseMap, patchMap)
-
-	merged, err := json.Marshal(baseMap)
-	if err != nil {
-		return OnRampConfig{}, fmt.Errorf("marshal merged: %w", err)
-	}
-	var result OnRampConfig
-	if err := json.Unmarshal(merged, &result); err != nil {
-		return OnRampConfig{}, fmt.Errorf("unmarshal merged: %w", err)
-	}
-	return result, nil
-}
-
-// deepMergeMaps recursively merges patch into base. When both sides have a
-// nested map for the same key, the maps are merged recursively. Otherwise the
-// patch value overwrites the base value.
-func deepMergeMaps(base, patch map[string]any) {
-	for k, pv := range patch {
-		bv, exists := base[k]
-		if !exists {
-			base[k] = pv
-			continue
-		}
-		bm, bok := bv.(map[string]any)
-		pm, pok := pv.(map[string]any)
-		if bok && pok {
-			deepMergeMaps(bm, pm)
-		} else {
-			base[k] = pv
-		}
-	}
-}
@@ -0,0 +1,445 @@
+package onramp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/google/uuid"
+
+	"github.com/bengrewell/aether-webui/internal/store"
+	"github.com/bengrewell/aether-webui/internal/taskrunner"
+)
+
+// hooksFor returns the hooks that run for an action, by stage, in run
+// order. None without a store.
+func (o *OnRamp) hooksFor(ws *workspace, component, action string) (map[string][]store.ActionHook, error) {
+	st := o.Store()
+	if st.Path() == "" {
+		return nil, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	list, err := st.ListActionHooks(ctx, ws.name, component)
+	if err != nil {
+		return nil, err
+	}
+	hooks := make(map[string][]store.ActionHook)
+	for _, h := range list {
+		if h.Action == "" || h.Action == action {
+			hooks[h.Stage] = append(hooks[h.Stage], h)
+		}
+	}
+	// Component-wide hooks are listed first, so they win position ties.
+	for _, hs := range hooks {
+		slices.SortStableFunc(hs, func(a, b store.ActionHook) int { return a.Position - b.Position })
+	}
+	return hooks, nil
+}
+
+// submitAction submits an action's task together with its hooks. With before
+// hooks the action's task is held in its place in the queue: when it reaches
+// the front they run ahead of it, each as its own task, and it is released
+// once they have all succeeded; one that fails aborts the action. When the
+// action finishes, its after hooks run if it succeeded and its on_failure
+// hooks if it failed or was aborted. Canceling the held action cancels its
+// running before hook and skips the rest.
+//
+// spec.OnComplete records the action's result as soon as it is known. then,
+// if not nil, is called once the action and its hooks are done, with the
+// action's view marked failed when a before or after hook failed.
+func (o *OnRamp) submitAction(ws *workspace, spec taskrunner.TaskSpec, then func(taskrunner.TaskView)) (taskrunner.TaskView, error) {
+	hooks, err := o.hooksFor(ws, spec.Labels["component"], spec.Labels["action"])
+	if err != nil {
+		return taskrunner.TaskView{}, fmt.Errorf("load hooks: %w", err)
+	}
+	if then == nil {
+		then = func(taskrunner.TaskView) {}
+	}
+
+	record := spec.OnComplete
+	if record == nil {
+		record = func(taskrunner.TaskView) {}
+	}
+	finish := func(v taskrunner.TaskView) {
+		switch v.Status {
+		case taskrunner.StatusSucceeded:
+			o.runHooks(ws, spec, hooks[store.HookAfter], true, func(status taskrunner.TaskStatus, errMsg string) {
+				if status != taskrunner.StatusSucceeded {
+					v.Status = taskrunner.StatusFailed
+					v.Error = errMsg
+				}
+				then(v)
+			})
+		case taskrunner.StatusFailed:
+			o.runHooks(ws, spec, hooks[store.HookOnFailure], false, func(taskrunner.TaskStatus, string) { then(v) })
+		default:
+			// An action canceled while held stops its running before hook.
+			for _, h := range ws.runner.List(&taskrunner.ListFilter{Label: map[string]string{
+				"parent_action_id": spec.ID, "hook_stage": store.HookBefore,
+			}}) {
+				_ = ws.runner.Cancel(h.ID)
+			}
+			then(v)
+		}
+	}
+	spec.OnComplete = func(v taskrunner.TaskView) {
+		record(v)
+		finish(v)
+	}
+	spec = o.withSecrets(ws, spec)
+
+	before := hooks[store.HookBefore]
+	if len(before) == 0 {
+		return ws.submit(spec)
+	}
+	parent := spec
+	spec.Hold = true
+	spec.OnReady = func(taskrunner.TaskView) {
+		o.runHooks(ws, parent, before, true, func(status taskrunner.TaskStatus, errMsg string) {
+			var err error
+			if status == taskrunner.StatusSucceeded {
+				err = ws.runner.Release(parent.ID)
+			} else {
+				// The action never started, so recording it leaves the
+				// component state alone.
+				err = ws.runner.Fail(parent.ID, errMsg)
+			}
+			// The action may have been canceled while its hooks ran.
+			if err != nil && !errors.Is(err, taskrunner.ErrNotPending) {
+				o.Log().Error("failed to release action after before hooks", "action_id", parent.ID, "error", err)
+			}
+		})
+	}
+	return ws.submit(spec)
+}
+
+// runHooks runs hooks one after another as tasks linked to the action
+// described by parent. With stopOnFailure the first hook that does not
+// succeed ends the run; otherwise failures are only logged. done receives
+// StatusSucceeded, or the status and error of the hook that stopped the run.
+func (o *OnRamp) runHooks(ws *workspace, parent taskrunner.TaskSpec, hooks []store.ActionHook, stopOnFailure bool, done func(taskrunner.TaskStatus, string)) {
+	if len(hooks) == 0 {
+		done(taskrunner.StatusSucceeded, "")
+		return
+	}
+	h := hooks[0]
+	o.submitHook(ws, parent, h, func(status taskrunner.TaskStatus, errMsg string) {
+		if status != taskrunner.StatusSucceeded {
+			errMsg = fmt.Sprintf("%s hook %s %s: %s", h.Stage, h.Name, status, errMsg)
+			if stopOnFailure {
+				done(status, errMsg)
+				return
+			}
+			o.Log().Warn("hook did not succeed", "action_id", parent.ID, "hook", h.Name, "error", errMsg)
+		}
+		o.runHooks(ws, parent, hooks[1:], stopOnFailure, done)
+	})
+}
+
+// submitHook records a hook run and submits its task. next is called with
+// the task's final status, or with StatusFailed when it cannot be started.
+func (o *OnRamp) submitHook(ws *workspace, parent taskrunner.TaskSpec, h store.ActionHook, next func(taskrunner.TaskStatus, string)) {
+	// Before hooks of an action canceled in the meantime are skipped.
+	if h.Stage == store.HookBefore {
+		if v, err := ws.runner.Get(parent.ID); err != nil || v.Status != taskrunner.StatusPending {
+			next(taskrunner.StatusCanceled, "canceled")
+			return
+		}
+	}
+	st := o.Store()
+	log := o.Log()
+	runID := uuid.NewString()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	run := store.HookRun{
+		ID:        runID,
+		ActionID:  parent.ID,
+		HookID:    h.ID,
+		Stage:     h.Stage,
+		Name:      h.Name,
+		Target:    h.Target,
+		Status:    "pending",
+		ExitCode:  -1,
+		StartedAt: time.Now().UTC(),
+	}
+	if err := st.InsertHookRun(ctx, run); err != nil {
+		log.Error("failed to insert hook run", "hook_run_id", runID, "error", err)
+	}
+	update := func(ctx context.Context, result store.ActionResult) {
+		if err := st.UpdateHookRun(ctx, runID, result); err != nil {
+			log.Error("failed to update hook run", "hook_run_id", runID, "error", err)
+		}
+	}
+
+	cmd, err := o.commandFor(ws, Action{Kind: h.Kind, Target: h.Target, Args: h.Args})
+	if err == nil {
+		labels := maps.Clone(parent.Labels)
+		labels["target"] = h.Target
+		labels["hook"] = h.Name
+		labels["hook_stage"] = h.Stage
+		labels["parent_action_id"] = parent.ID
+		hook := o.withSecrets(ws, taskrunner.TaskSpec{
+			ID:          runID,
+			Command:     cmd.command,
+			Args:        cmd.args,
+			Env:         cmd.env,
+			Dir:         ws.config.OnRampDir,
+			Description: fmt.Sprintf("%s %s hook %s", parent.Description, h.Stage, h.Name),
+			Labels:      labels,
+			OnStart: func(taskrunner.TaskView) {
+				ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+				defer cancel()
+				update(ctx, store.ActionResult{Status: "running", ExitCode: -1})
+			},
+			OnComplete: func(v taskrunner.TaskView) {
+				ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+				update(ctx, store.ActionResult{
+					Status:     string(v.Status),
+					Error:      v.Error,
+					ExitCode:   v.ExitCode,
+					FinishedAt: v.FinishedAt,
+				})
+				cancel()
+				next(v.Status, v.Error)
+			},
+		})
+		// Before hooks run in front of their held action.
+		if h.Stage == store.HookBefore {
+			_, err = ws.submitAhead(hook, parent.ID)
+		} else {
+			_, err = ws.submit(hook)
+		}
+	}
+	if err != nil {
+		update(ctx, store.ActionResult{Status: "failed", Error: err.Error(), ExitCode: -1, FinishedAt: time.Now().UTC()})
+		next(taskrunner.StatusFailed, err.Error())
+	}
+}
+
+// hookRunItems returns the hook runs of an action for API responses.
+func (o *OnRamp) hookRunItems(ctx context.Context, actionID string) []HookRunItem {
+	runs, err := o.Store().ListHookRuns(ctx, actionID)
+	if err != nil {
+		o.Log().Error("failed to list hook runs", "action_id", actionID, "error", err)
+		return nil
+	}
+	var out []HookRunItem
+	for _, r := range runs {
+		item := HookRunItem{
+			ID:        r.ID,
+			HookID:    r.HookID,
+			Stage:     r.Stage,
+			Name:      r.Name,
+			Target:    r.Target,
+			Status:    r.Status,
+			ExitCode:  r.ExitCode,
+			Error:     r.Error,
+			StartedAt: r.StartedAt.Unix(),
+		}
+		if !r.FinishedAt.IsZero() {
+			item.FinishedAt = r.FinishedAt.Unix()
+		}
+		out = append(out, item)
+	}
+	return out
+}
+
+func toActionHook(h store.ActionHook) ActionHook {
+	return ActionHook{
+		ID:        h.ID,
+		Component: h.Component,
+		Action:    h.Action,
+		Stage:     h.Stage,
+		Name:      h.Name,
+		Kind:      h.Kind,
+		Target:    h.Target,
+		Args:      h.Args,
+		Position:  h.Position,
+		CreatedAt: h.CreatedAt.Unix(),
+		UpdatedAt: h.UpdatedAt.Unix(),
+	}
+}
+
+// validateHookSpec checks a hook definition against the workspace's
+// components and converts it for the store.
+func (o *OnRamp) validateHookSpec(ws *workspace, id string, spec HookSpec) (store.ActionHook, error) {
+	h := store.ActionHook{
+		ID:        id,
+		Workspace: ws.name,
+		Component: spec.Component,
+		Action:    spec.Action,
+		Stage:     spec.Stage,
+		Name:      spec.Name,
+		Target:    spec.Target,
+		Args:      spec.Args,
+		Position:  spec.Position,
+	}
+	reg := o.registry(ws)
+	if _, ok := reg.component(spec.Component); !ok {
+		return h, huma.Error422UnprocessableEntity(fmt.Sprintf("unknown component: %s", spec.Component))
+	}
+	if spec.Action != "" {
+		if _, ok := reg.action(spec.Component, spec.Action); !ok {
+			return h, huma.Error422UnprocessableEntity(fmt.Sprintf("component %s has no action %s", spec.Component, spec.Action))
+		}
+	}
+	switch spec.Stage {
+	case store.HookBefore, store.HookAfter, store.HookOnFailure:
+	default:
+		return h, huma.Error422UnprocessableEntity(fmt.Sprintf("invalid stage %q", spec.Stage))
+	}
+	if !customNameRe.MatchString(spec.Name) {
+		return h, huma.Error422UnprocessableEntity(fmt.Sprintf("invalid hook name %q: use lowercase letters, digits, dashes, and underscores", spec.Name))
+	}
+	kind, err := o.checkTarget(spec.Kind, spec.Target, spec.Args)
+	if err != nil {
+		return h, huma.Error422UnprocessableEntity(fmt.Sprintf("hook %s: %v", spec.Name, err))
+	}
+	h.Kind = kind
+	return h, nil
+}
+
+// ---------------------------------------------------------------------------
+// Handlers
+// ---------------------------------------------------------------------------
+
+// HandleListHooks returns the workspace's hooks, optionally only those of a
+// component or that run for an action.
+func (o *OnRamp) HandleListHooks(ctx context.Context, in *HookListInput) (*HookListOutput, error) {
+	st, err := o.requireStore("hooks are")
+	if err != nil {
+		return nil, err
+	}
+	ws, err := o.workspace(ctx, in.Workspace)
+	if err != nil {
+		return nil, err
+	}
+	list, err := st.ListActionHooks(ctx, ws.name, in.Component)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("failed to list hooks", err)
+	}
+	out := make([]ActionHook, 0, len(list))
+	for _, h := range list {
+		if in.Action != "" && h.Action != "" && h.Action != in.Action {
+			continue
+		}
+		out = append(out, toActionHook(h))
+	}
+	return &HookListOutput{Body: out}, nil
+}
+
+// HandleGetHook returns one hook.
+func (o *OnRamp) HandleGetHook(ctx context.Context, in *HookGetInput) (*HookOutput, error) {
+	st, err := o.requireStore("hooks are")
+	if err != nil {
+		return nil, err
+	}
+	ws, err := o.workspace(ctx, in.Workspace)
+	if err != nil {
+		return nil, err
+	}
+	h, err := o.lookupHook(ctx, st, ws, in.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &HookOutput{Body: toActionHook(h)}, nil
+}
+
+// HandleCreateHook adds a hook.
+func (o *OnRamp) HandleCreateHook(ctx context.Context, in *HookCreateInput) (*HookOutput, error) {
+	st, err := o.requireStore("hooks are")
+	if err != nil {
+		return nil, err
+	}
+	ws, err := o.workspace(ctx, in.Workspace)
+	if err != nil {
+		return nil, err
+	}
+	if err := o.CheckChangeLock(ctx, ws.name); err != nil {
+		return nil, err
+	}
+	h, err := o.validateHookSpec(ws, uuid.NewString(), in.Body)
+	if err != nil {
+		return nil, err
+	}
+	return o.saveHook(ctx, st, ws, h)
+}
+
+// HandleUpdateHook replaces a hook's definition.
+func (o *OnRamp) HandleUpdateHook(ctx context.Context, in *HookUpdateInput) (*HookOutput, error) {
+	st, err := o.requireStore("hooks are")
+	if err != nil {
+		return nil, err
+	}
+	ws, err := o.workspace(ctx, in.Workspace)
+	if err != nil {
+		return nil, err
+	}
+	if err := o.CheckChangeLock(ctx, ws.name); err != nil {
+		return nil, err
+	}
+	existing, err := o.lookupHook(ctx, st, ws, in.ID)
+	if err != nil {
+		return nil, err
+	}
+	h, err := o.validateHookSpec(ws, in.ID, in.Body)
+	if err != nil {
+		return nil, err
+	}
+	h.CreatedAt = existing.CreatedAt
+	return o.saveHook(ctx, st, ws, h)
+}
+
+// HandleDeleteHook removes a hook. Its recorded runs are kept.
+func (o *OnRamp) HandleDeleteHook(ctx context.Context, in *HookDeleteInput) (*HookDeleteOutput, error) {
+	st, err := o.requireStore("hooks are")
+	if err != nil {
+		return nil, err
+	}
+	ws, err := o.workspace(ctx, in.Workspace)
+	if err != nil {
+		return nil, err
+	}
+	if err := o.CheckChangeLock(ctx, ws.name); err != nil {
+		return nil, err
+	}
+	if _, err := o.lookupHook(ctx, st, ws, in.ID); err != nil {
+		return nil, err
+	}
+	if err := st.DeleteActionHook(ctx, in.ID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, huma.Error404NotFound(fmt.Sprintf("hook %s not found", in.ID))
+		}
+		return nil, huma.Error500InternalServerError("failed to delete hook", err)
+	}
+	out := &HookDeleteOutput{}
+	out.Body.Message = fmt.Sprintf("hook %s deleted", in.ID)
+	return out, nil
+}
+
+// lookupHook returns a hook of the workspace, or 404.
+func (o *OnRamp) lookupHook(ctx context.Context, st store.Client, ws *workspace, id string) (store.ActionHook, error) {
+	h, ok, err := st.GetActionHook(ctx, id)
+	if err != nil {
+		return h, huma.Error500InternalServerError("failed to get hook", err)
+	}
+	if !ok || h.Workspace != ws.name {
+		return h, huma.Error404NotFound(fmt.Sprintf("hook %s not found", id))
+	}
+	return h, nil
+}
+
+func (o *OnRamp) saveHook(ctx context.Context, st store.Client, ws *workspace, h store.ActionHook) (*HookOutput, error) {
+	saved, err := st.SaveActionHook(ctx, h)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("failed to save hook", err)
+	}
+	o.Log().Info("hook saved", "workspace", ws.name, "hook_id", h.ID, "component", h.Component, "action", h.Action, "stage", h.Stage)
+	return &HookOutput{Body: toActionHook(saved)}, nil
+}
@@ -0,0 +1,377 @@
+package onramp
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bengrewell/aether-webui/internal/store"
+	"github.com/bengrewell/aether-webui/internal/taskrunner"
+)
+
+// writeScript creates an executable script in the provider's script directory.
+func writeScript(t *testing.T, o *OnRamp, name, body string) {
+	t.Helper()
+	path := filepath.Join(o.config.ScriptDir, name)
+	writeTestFile(t, path, "#!/bin/sh\n"+body+"\n")
+	if err := os.Chmod(path, 0o755); err != nil {
+		t.Fatalf("Chmod: %v", err)
+	}
+}
+
+// newHookTestProvider returns a provider with a custom "site" component
+// whose install action appends to order.log in the checkout, as do the
+// pre, post and cleanup scripts; fail.sh exits 1.
+func newHookTestProvider(t *testing.T) *OnRamp {
+	t.Helper()
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available on PATH")
+	}
+	o := newCustomTestProvider(t)
+	for _, name := range []string{"install", "pre", "post", "cleanup"} {
+		writeScript(t, o, name+".sh", `echo `+name+` >> "$ONRAMP_DIR/order.log"`)
+	}
+	writeScript(t, o, "fail.sh", "exit 1")
+	if _, err := createCustom(t, o, "site", CustomComponentSpec{Actions: []Action{
+		{Name: "install", Kind: store.CustomActionScript, Target: "install.sh"},
+	}}); err != nil {
+		t.Fatalf("create site: %v", err)
+	}
+	return o
+}
+
+func createHook(t *testing.T, o *OnRamp, spec HookSpec) ActionHook {
+	t.Helper()
+	if spec.Kind == "" {
+		spec.Kind = store.CustomActionScript
+	}
+	out, err := o.HandleCreateHook(t.Context(), &HookCreateInput{Body: spec})
+	if err != nil {
+		t.Fatalf("create hook %s: %v", spec.Name, err)
+	}
+	return out.Body
+}
+
+// waitForAction polls until the action record is terminal and its hooks
+// have finished.
+func waitForAction(t *testing.T, o *OnRamp, id string) ActionHistoryItem {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		out, err := o.HandleGetAction(t.Context(), &ActionGetInput{ID: id})
+		if err != nil {
+			t.Fatalf("HandleGetAction: %v", err)
+		}
+		done := out.Body.Status != "pending" && out.Body.Status != "running"
+		for _, h := range out.Body.Hooks {
+			if h.Status == "pending" || h.Status == "running" {
+				done = false
+			}
+		}
+		if done {
+			return out.Body
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("action %s did not finish", id)
+	return ActionHistoryItem{}
+}
+
+func readOrder(t *testing.T, o *OnRamp) string {
+	t.Helper()
+	data, _ := os.ReadFile(filepath.Join(o.config.OnRampDir, "order.log"))
+	return strings.Join(strings.Fields(string(data)), " ")
+}
+
+func hookNames(runs []HookRunItem) string {
+	var out []string
+	for _, r := range runs {
+		out = append(out, r.Stage+":"+r.Name+":"+r.Status)
+	}
+	return strings.Join(out, " ")
+}
+
+func TestHooks_CRUD(t *testing.T) {
+	o := newCustomTestProvider(t)
+	h := createHook(t, o, HookSpec{Component: "5gc", Action: "uninstall", Stage: store.HookBefore, Name: "backup", Target: "backup.sh", Position: 2})
+	createHook(t, o, HookSpec{Component: "5gc", Stage: store.HookOnFailure, Name: "page", Target: "page.sh"})
+	createHook(t, o, HookSpec{Component: "k8s", Stage: store.HookAfter, Name: "pingall", Kind: store.CustomActionMake, Target: "aether-pingall"})
+
+	if h.ID == "" || h.Kind != store.CustomActionScript || h.CreatedAt == 0 {
+		t.Errorf("created = %+v", h)
+	}
+
+	list, err := o.HandleListHooks(t.Context(), &HookListInput{Component: "5gc", Action: "install"})
+	if err != nil {
+		t.Fatalf("HandleListHooks: %v", err)
+	}
+	if len(list.Body) != 1 || list.Body[0].Name != "page" {
+		t.Errorf("5gc/install hooks = %+v", list.Body)
+	}
+	all, _ := o.HandleListHooks(t.Context(), &HookListInput{})
+	if len(all.Body) != 3 {
+		t.Errorf("listed %d hooks, want 3", len(all.Body))
+	}
+
+	spec := HookSpec{Component: "5gc", Action: "uninstall", Stage: store.HookBefore, Name: "backup", Kind: store.CustomActionScript, Target: "backup-values.sh"}
+	upd, err := o.HandleUpdateHook(t.Context(), &HookUpdateInput{ID: h.ID, Body: spec})
+	if err != nil {
+		t.Fatalf("HandleUpdateHook: %v", err)
+	}
+	if upd.Body.Target != "backup-values.sh" || upd.Body.Position != 0 || upd.Body.CreatedAt != h.CreatedAt {
+		t.Errorf("updated = %+v", upd.Body)
+	}
+	_, err = o.HandleUpdateHook(t.Context(), &HookUpdateInput{ID: "missing", Body: spec})
+	wantStatus(t, err, 404)
+
+	if _, err := o.HandleDeleteHook(t.Context(), &HookDeleteInput{ID: h.ID}); err != nil {
+		t.Fatalf("HandleDeleteHook: %v", err)
+	}
+	_, err = o.HandleGetHook(t.Context(), &HookGetInput{ID: h.ID})
+	wantStatus(t, err, 404)
+	_, err = o.HandleDeleteHook(t.Context(), &HookDeleteInput{ID: h.ID})
+	wantStatus(t, err, 404)
+}
+
+func TestHooks_Validation(t *testing.T) {
+	o := newCustomTestProvider(t)
+	tests := []struct {
+		name string
+		spec HookSpec
+	}{
+		{"unknown component", HookSpec{Component: "nope", Stage: store.HookBefore, Name: "x", Target: "x"}},
+		{"unknown action", HookSpec{Component: "5gc", Action: "deploy", Stage: store.HookBefore, Name: "x", Target: "x"}},
+		{"bad stage", HookSpec{Component: "5gc", Stage: "during", Name: "x", Target: "x"}},
+		{"bad name", HookSpec{Component: "5gc", Stage: store.HookBefore, Name: "Backup Values", Target: "x"}},
+		{"script escapes dir", HookSpec{Component: "5gc", Stage: store.HookBefore, Name: "x", Kind: store.CustomActionScript, Target: "../x.sh"}},
+		{"option as arg", HookSpec{Component: "5gc", Stage: store.HookBefore, Name: "x", Target: "x", Args: []string{"--eval=x"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := o.HandleCreateHook(t.Context(), &HookCreateInput{Body: tt.spec})
+			wantStatus(t, err, 422)
+		})
+	}
+
+	_, err := newTestProvider(t, "").HandleListHooks(t.Context(), &HookListInput{})
+	wantStatus(t, err, 503)
+}
+
+func TestHooks_BeforeAndAfter(t *testing.T) {
+	o := newHookTestProvider(t)
+	createHook(t, o, HookSpec{Component: "site", Action: "install", Stage: store.HookAfter, Name: "post", Target: "post.sh"})
+	createHook(t, o, HookSpec{Component: "site", Stage: store.HookBefore, Name: "pre", Target: "pre.sh"})
+	createHook(t, o, HookSpec{Component: "site", Stage: store.HookOnFailure, Name: "cleanup", Target: "cleanup.sh"})
+
+	out, err := o.HandleExecuteAction(t.Context(), &ExecuteActionInput{Component: "site", Action: "install"})
+	if err != nil {
+		t.Fatalf("HandleExecuteAction: %v", err)
+	}
+	if out.Body.Component != "site" || out.Body.Action != "install" {
+		t.Errorf("task = %+v", out.Body)
+	}
+
+	rec := waitForAction(t, o, out.Body.ID)
+	if rec.Status != "succeeded" {
+		t.Errorf("action status = %q (%s)", rec.Status, rec.Error)
+	}
+	if got := hookNames(rec.Hooks); got != "before:pre:succeeded after:post:succeeded" {
+		t.Errorf("hooks = %s", got)
+	}
+	if got := readOrder(t, o); got != "pre install post" {
+		t.Errorf("run order = %q", got)
+	}
+
+	// Hook tasks are linked to the action.
+	view, err := o.runner.Get(rec.Hooks[0].ID)
+	if err != nil {
+		t.Fatalf("hook task: %v", err)
+	}
+	if view.Labels["parent_action_id"] != out.Body.ID || view.Labels["hook_stage"] != store.HookBefore {
+		t.Errorf("hook labels = %v", view.Labels)
+	}
+	cs, _, _ := o.Store().GetComponentState(t.Context(), "", "site")
+	if cs.Status != "installed" {
+		t.Errorf("component state = %+v", cs)
+	}
+}
+
+func TestHooks_BeforeFailureAborts(t *testing.T) {
+	o := newHookTestProvider(t)
+	createHook(t, o, HookSpec{Component: "site", Stage: store.HookBefore, Name: "check", Target: "fail.sh"})
+	createHook(t, o, HookSpec{Component: "site", Stage: store.HookBefore, Name: "pre", Target: "pre.sh", Position: 1})
+	createHook(t, o, HookSpec{Component: "site", Stage: store.HookOnFailure, Name: "cleanup", Target: "cleanup.sh"})
+
+	out, err := o.HandleExecuteAction(t.Context(), &ExecuteActionInput{Component: "site", Action: "install"})
+	if err != nil {
+		t.Fatalf("HandleExecuteAction: %v", err)
+	}
+	if out.Body.Status != "pending" {
+		t.Errorf("status = %q, want pending while before hooks run", out.Body.Status)
+	}
+
+	rec := waitForAction(t, o, out.Body.ID)
+	if rec.Status != "failed" || !strings.Contains(rec.Error, "before hook check failed") {
+		t.Errorf("action = %s: %s", rec.Status, rec.Error)
+	}
+	if got := hookNames(rec.Hooks); got != "before:check:failed on_failure:cleanup:succeeded" {
+		t.Errorf("hooks = %s", got)
+	}
+	if got := readOrder(t, o); got != "cleanup" {
+		t.Errorf("run order = %q, want only cleanup", got)
+	}
+	if v, _ := o.runner.Get(out.Body.ID); v.Status != "failed" || !v.StartedAt.IsZero() {
+		t.Errorf("aborted action task = %+v, want failed without starting", v)
+	}
+	if _, ok, _ := o.Store().GetComponentState(t.Context(), "", "site"); ok {
+		t.Error("aborted action changed the component state")
+	}
+}
+
+// waitForHook polls until a hook task of the action is running.
+func waitForHook(t *testing.T, o *OnRamp, actionID string) {
+	t.Helper()
+	running := taskrunner.StatusRunning
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if len(o.runner.List(&taskrunner.ListFilter{Status: &running, Label: map[string]string{"parent_action_id": actionID}})) > 0 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("no hook of action %s started", actionID)
+}
+
+func TestHooks_HeldActionKeepsItsPlace(t *testing.T) {
+	o := newHookTestProvider(t)
+	writeScript(t, o, "slow.sh", `sleep 0.3; echo pre >> "$ONRAMP_DIR/order.log"`)
+	writeScript(t, o, "other.sh", `echo other >> "$ONRAMP_DIR/order.log"`)
+	createHook(t, o, HookSpec{Component: "site", Stage: store.HookBefore, Name: "slow", Target: "slow.sh"})
+
+	out, err := o.HandleExecuteAction(t.Context(), &ExecuteActionInput{Component: "site", Action: "install"})
+	if err != nil {
+		t.Fatalf("HandleExecuteAction: %v", err)
+	}
+	waitForHook(t, o, out.Body.ID)
+
+	// The action is queued while its hook runs, ahead of later tasks.
+	other, err := o.runner.Submit(taskrunner.TaskSpec{Command: filepath.Join(o.config.ScriptDir, "other.sh"), Env: []string{"ONRAMP_DIR=" + o.config.OnRampDir}})
+	if err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	queue, err := o.HandleGetQueue(t.Context(), &WorkspaceInput{})
+	if err != nil {
+		t.Fatalf("HandleGetQueue: %v", err)
+	}
+	if len(queue.Body.Tasks) != 2 || queue.Body.Tasks[0].ID != out.Body.ID || queue.Body.Tasks[0].QueuePosition != 1 ||
+		queue.Body.Tasks[1].ID != other.ID {
+		t.Errorf("queue = %+v", queue.Body.Tasks)
+	}
+
+	if rec := waitForAction(t, o, out.Body.ID); rec.Status != "succeeded" {
+		t.Errorf("action = %s: %s", rec.Status, rec.Error)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for v, _ := o.runner.Get(other.ID); v.FinishedAt.IsZero() && time.Now().Before(deadline); v, _ = o.runner.Get(other.ID) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := readOrder(t, o); got != "pre install other" {
+		t.Errorf("run order = %q, want the action before the later task", got)
+	}
+}
+
+func TestHooks_CancelWhileBeforeHooksRun(t *testing.T) {
+	for _, drain := range []bool{false, true} {
+		o := newHookTestProvider(t)
+		writeScript(t, o, "slow.sh", "exec sleep 5")
+		createHook(t, o, HookSpec{Component: "site", Stage: store.HookBefore, Name: "slow", Target: "slow.sh"})
+		createHook(t, o, HookSpec{Component: "site", Stage: store.HookBefore, Name: "pre", Target: "pre.sh", Position: 1})
+
+		out, err := o.HandleExecuteAction(t.Context(), &ExecuteActionInput{Component: "site", Action: "install"})
+		if err != nil {
+			t.Fatalf("HandleExecuteAction: %v", err)
+		}
+		waitForHook(t, o, out.Body.ID)
+
+		task, err := o.HandleGetTask(t.Context(), &TaskGetInput{ID: out.Body.ID})
+		if err != nil {
+			t.Fatalf("HandleGetTask while hooks run: %v", err)
+		}
+		if task.Body.Status != "pending" || task.Body.QueuePosition != 1 {
+			t.Errorf("task = %+v", task.Body)
+		}
+
+		if drain {
+			drained, err := o.HandleDrainQueue(t.Context(), &WorkspaceInput{})
+			if err != nil {
+				t.Fatalf("HandleDrainQueue: %v", err)
+			}
+			if len(drained.Body.Canceled) != 1 || drained.Body.Canceled[0] != out.Body.ID {
+				t.Errorf("drained = %v", drained.Body.Canceled)
+			}
+		} else if _, err := o.HandleRemoveQueueTask(t.Context(), &QueueTaskInput{ID: out.Body.ID}); err != nil {
+			t.Fatalf("HandleRemoveQueueTask: %v", err)
+		}
+		rec := waitForAction(t, o, out.Body.ID)
+		if rec.Status != "canceled" {
+			t.Errorf("drain=%v: action = %s: %s", drain, rec.Status, rec.Error)
+		}
+		if got := hookNames(rec.Hooks); got != "before:slow:canceled" {
+			t.Errorf("drain=%v: hooks = %s", drain, got)
+		}
+		if got := readOrder(t, o); got != "" {
+			t.Errorf("drain=%v: run order = %q, want nothing run", drain, got)
+		}
+	}
+}
+
+func TestHooks_MissingScriptAborts(t *testing.T) {
+	o := newHookTestProvider(t)
+	createHook(t, o, HookSpec{Component: "site", Stage: store.HookBefore, Name: "gone", Target: "gone.sh"})
+
+	out, err := o.HandleExecuteAction(t.Context(), &ExecuteActionInput{Component: "site", Action: "install"})
+	if err != nil {
+		t.Fatalf("HandleExecuteAction: %v", err)
+	}
+	rec := waitForAction(t, o, out.Body.ID)
+	if rec.Status != "failed" || len(rec.Hooks) != 1 || rec.Hooks[0].Status != "failed" {
+		t.Errorf("action = %+v", rec)
+	}
+}
+
+func TestHooks_DeploymentAfterFailure(t *testing.T) {
+	o := newHookTestProvider(t)
+	createHook(t, o, HookSpec{Component: "site", Action: "install", Stage: store.HookAfter, Name: "smoke", Target: "fail.sh"})
+
+	in := &DeployInput{}
+	in.Body.Actions = []ComponentActionPair{{Component: "site", Action: "install"}}
+	in.Body.SkipValidation = true
+	out, err := o.HandleDeploy(t.Context(), in)
+	if err != nil {
+		t.Fatalf("HandleDeploy: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	var dep DeploymentItem
+	for time.Now().Before(deadline) {
+		got, err := o.HandleGetDeployment(t.Context(), &DeploymentGetInput{ID: out.Body.ID})
+		if err != nil {
+			t.Fatalf("HandleGetDeployment: %v", err)
+		}
+		if dep = got.Body; dep.Status != "running" {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if dep.Status != "failed" || !strings.Contains(dep.Error, "after hook smoke failed") {
+		t.Fatalf("deployment = %s: %s", dep.Status, dep.Error)
+	}
+	// The action itself succeeded; the hook result shows beside it.
+	a := dep.Actions[0]
+	if a.Status != "succeeded" || hookNames(a.Hooks) != "after:smoke:failed" {
+		t.Errorf("deployment action = %+v", a)
+	}
+}
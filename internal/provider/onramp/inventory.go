@@ -4,37 +4,34 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/danielgtaylor/huma/v2"
+	"github.com/google/uuid"
 
 	"github.com/bengrewell/aether-webui/internal/store"
 )
 
-// roleSections defines the fixed ordering of [role_nodes] sections in hosts.ini.
-var roleSections = []struct {
-	role    string
-	section string
-}{
-	{"master", "master_nodes"},
-	{"worker", "worker_nodes"},
-	{"gnbsim", "gnbsim_nodes"},
-	{"oai", "oai_nodes"},
-	{"ueransim", "ueransim_nodes"},
-	{"srsran", "srsran_nodes"},
-	{"oscric", "oscric_nodes"},
-	{"n3iwf", "n3iwf_nodes"},
-}
-
 // ---------------------------------------------------------------------------
 // Handlers
 // ---------------------------------------------------------------------------
 
-func (o *OnRamp) HandleGetInventory(_ context.Context, _ *struct{}) (*InventoryGetOutput, error) {
-	path := filepath.Join(o.config.OnRampDir, "hosts.ini")
+func (o *OnRamp) HandleGetInventory(ctx context.Context, in *WorkspaceInput) (*InventoryGetOutput, error) {
+	ws, err := o.workspace(ctx, in.workspaceName())
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(ws.config.OnRampDir, "hosts.ini")
 	data, err := os.ReadFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -46,126 +43,818 @@ func (o *OnRamp) HandleGetInventory(_ context.Context, _ *struct{}) (*InventoryG
 	return &InventoryGetOutput{Body: inv}, nil
 }
 
-func (o *OnRamp) HandleSyncInventory(ctx context.Context, _ *struct{}) (*InventorySyncOutput, error) {
-	infos, err := o.Store().ListNodes(ctx)
+func (o *OnRamp) HandleSyncInventory(ctx context.Context, in *InventorySyncInput) (*InventorySyncOutput, error) {
+	ws, err := o.workspace(ctx, in.Workspace)
 	if err != nil {
-		return nil, huma.Error500InternalServerError("failed to list nodes", err)
+		return nil, err
+	}
+	if err := o.CheckChangeLock(ctx, ws.name); err != nil {
+		return nil, err
+	}
+
+	path := filepath.Join(ws.config.OnRampDir, "hosts.ini")
+	var warnings []string
+	if old, err := os.ReadFile(path); err == nil {
+		stamped, edited := hostsINIStamp(old)
+		switch {
+		case edited && !in.Force:
+			return nil, huma.Error409Conflict("hosts.ini was edited since the last sync; " +
+				"review GET /api/v1/onramp/inventory/drift, then reconcile or sync with force=true")
+		case edited:
+			warnings = append(warnings, "overwrote manual edits to hosts.ini")
+		case !stamped && len(ReadHostsINI(old)) > 0:
+			warnings = append(warnings, "replaced a hosts.ini not written by inventory sync")
+		}
 	}
 
-	// Fetch full node data (with decrypted secrets) for each node.
-	nodes := make([]store.Node, 0, len(infos))
-	for _, info := range infos {
-		node, ok, err := o.Store().GetNode(ctx, info.ID)
+	nodes, err := o.workspaceNodes(ctx, ws)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("failed to list nodes", err)
+	}
+	// An empty node database leaves nothing to check.
+	if !in.SkipValidation && len(nodes) > 0 {
+		components, err := o.deployedComponents(ctx, ws)
 		if err != nil {
-			return nil, huma.Error500InternalServerError("failed to get node", err)
+			return nil, huma.Error500InternalServerError("failed to list component states", err)
+		}
+		res := checkTopology(o.registry(ws), nodeTopology(nodes), components)
+		if !res.Valid {
+			return nil, TopologyError("node roles break topology rules; fix them or set skip_validation", res.Errors)
 		}
-		if ok {
-			nodes = append(nodes, node)
+		for _, w := range res.Warnings {
+			warnings = append(warnings, w.Message)
 		}
 	}
+	if err := o.syncSecrets(ws, nodes); err != nil {
+		return nil, huma.Error500InternalServerError("failed to write node secrets", err)
+	}
+	if err := o.syncJumpFiles(ctx, ws); err != nil {
+		return nil, huma.Error500InternalServerError("failed to write jump host files", err)
+	}
+	if nodes, err = o.jumpProxies(ctx, ws, nodes); err != nil {
+		return nil, huma.Error422UnprocessableEntity(err.Error())
+	}
 
-	data := generateHostsINI(nodes)
-	path := filepath.Join(o.config.OnRampDir, "hosts.ini")
+	groupVars, err := o.Store().ListGroupVars(ctx, ws.name)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("failed to list group vars", err)
+	}
+	data := stampHostsINI(generateHostsINI(nodes, o.registry(ws).roles, groupVars))
 	if err := os.WriteFile(path, data, 0o644); err != nil {
 		return nil, huma.Error500InternalServerError("failed to write hosts.ini", err)
 	}
+	for _, w := range warnings {
+		o.Log().Warn("inventory sync: "+w, "workspace", ws.name, "path", path)
+	}
 
 	out := &InventorySyncOutput{}
 	out.Body.Message = fmt.Sprintf("hosts.ini written with %d nodes", len(nodes))
 	out.Body.Path = path
+	out.Body.Warnings = warnings
+	return out, nil
+}
+
+func (o *OnRamp) HandleGetInventoryDrift(ctx context.Context, in *WorkspaceInput) (*InventoryDriftOutput, error) {
+	if _, err := o.requireStore("inventory drift is"); err != nil {
+		return nil, err
+	}
+	ws, err := o.workspace(ctx, in.workspaceName())
+	if err != nil {
+		return nil, err
+	}
+	st, err := o.readInventoryState(ctx, ws)
+	if err != nil {
+		return nil, err
+	}
+	return &InventoryDriftOutput{Body: st.drift()}, nil
+}
+
+func (o *OnRamp) HandleReconcileInventory(ctx context.Context, in *InventoryReconcileInput) (*InventoryReconcileOutput, error) {
+	if _, err := o.requireStore("inventory reconcile is"); err != nil {
+		return nil, err
+	}
+	ws, err := o.workspace(ctx, in.Workspace)
+	if err != nil {
+		return nil, err
+	}
+	if err := o.CheckChangeLock(ctx, ws.name); err != nil {
+		return nil, err
+	}
+	st, err := o.readInventoryState(ctx, ws)
+	if err != nil {
+		return nil, err
+	}
+	drift := st.drift()
+
+	out := &InventoryReconcileOutput{}
+	out.Body.Applied = []InventoryDriftItem{}
+	for _, it := range drift.Items {
+		// to_db keeps nodes missing from hosts.ini unless told to delete them.
+		if in.Body.Direction == ReconcileToDB && it.Kind == DriftMissingInFile && !in.Body.DeleteMissing {
+			out.Body.Kept = append(out.Body.Kept, it.Name)
+			continue
+		}
+		out.Body.Applied = append(out.Body.Applied, it)
+	}
+	out.Body.GroupVars = drift.GroupVars
+	switch in.Body.Direction {
+	case ReconcileToFile:
+		sync, err := o.HandleSyncInventory(ctx, &InventorySyncInput{WorkspaceParam: in.WorkspaceParam, Force: true})
+		if err != nil {
+			return nil, err
+		}
+		out.Body.Message = sync.Body.Message
+	case ReconcileToDB:
+		if st.file == nil {
+			return nil, huma.Error404NotFound("hosts.ini not found")
+		}
+		if err := o.applyInventory(ctx, ws, st, drift, in.Body.DeleteMissing, in.Body.SkipValidation); err != nil {
+			return nil, err
+		}
+		// The file's contents are now the reviewed baseline for sync.
+		if drift.Edited || !st.stamped {
+			if err := os.WriteFile(st.path, stampHostsINI(st.body), 0o644); err != nil {
+				return nil, huma.Error500InternalServerError("failed to write hosts.ini", err)
+			}
+		}
+		out.Body.Message = fmt.Sprintf("applied %d hosts.ini changes to the node database", len(out.Body.Applied)+len(drift.GroupVars))
+		if len(out.Body.Kept) > 0 {
+			out.Body.Message += fmt.Sprintf("; kept %d nodes missing from hosts.ini (set delete_missing to delete them)", len(out.Body.Kept))
+		}
+	default:
+		return nil, huma.Error422UnprocessableEntity(fmt.Sprintf("unknown direction %q", in.Body.Direction))
+	}
 	return out, nil
 }
 
+func (o *OnRamp) HandleGetGroupVars(ctx context.Context, in *WorkspaceInput) (*InventoryGroupVarsOutput, error) {
+	db, err := o.requireStore("group vars are")
+	if err != nil {
+		return nil, err
+	}
+	ws, err := o.workspace(ctx, in.workspaceName())
+	if err != nil {
+		return nil, err
+	}
+	vars, err := db.ListGroupVars(ctx, ws.name)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("failed to list group vars", err)
+	}
+	out := &InventoryGroupVarsOutput{}
+	out.Body.GroupVars = vars
+	return out, nil
+}
+
+func (o *OnRamp) HandleSetGroupVars(ctx context.Context, in *InventoryGroupVarsSetInput) (*InventoryGroupVarsSetOutput, error) {
+	db, err := o.requireStore("group vars are")
+	if err != nil {
+		return nil, err
+	}
+	ws, err := o.workspace(ctx, in.Workspace)
+	if err != nil {
+		return nil, err
+	}
+	if err := o.CheckChangeLock(ctx, ws.name); err != nil {
+		return nil, err
+	}
+	if roles := o.registry(ws).roles; !slices.Contains(roles, in.Role) {
+		return nil, huma.Error404NotFound(fmt.Sprintf("unknown role %q; valid roles: %s", in.Role, strings.Join(roles, ", ")))
+	}
+	if err := CheckInventoryVars(in.Body.Vars); err != nil {
+		return nil, huma.Error422UnprocessableEntity(err.Error())
+	}
+	if err := db.SetGroupVars(ctx, ws.name, in.Role, in.Body.Vars); err != nil {
+		return nil, huma.Error500InternalServerError("failed to save group vars", err)
+	}
+	out := &InventoryGroupVarsSetOutput{}
+	out.Body.Role = in.Role
+	out.Body.Vars = in.Body.Vars
+	if out.Body.Vars == nil {
+		out.Body.Vars = map[string]string{}
+	}
+	return out, nil
+}
+
+// ---------------------------------------------------------------------------
+// Drift
+// ---------------------------------------------------------------------------
+
+// inventoryState is the node database and hosts.ini of a workspace, read for
+// comparison.
+type inventoryState struct {
+	path    string
+	body    []byte // hosts.ini without the sync stamp
+	stamped bool
+	edited  bool
+	nodes   []store.NodeInfo
+	file    []InventoryHost // nil when hosts.ini does not exist
+
+	groupVars     map[string]map[string]string // by role, from the database
+	fileGroupVars map[string]map[string]string // by role, from hosts.ini
+}
+
+func (o *OnRamp) readInventoryState(ctx context.Context, ws *workspace) (inventoryState, error) {
+	st := inventoryState{path: filepath.Join(ws.config.OnRampDir, "hosts.ini")}
+	data, err := os.ReadFile(st.path)
+	switch {
+	case err == nil:
+		st.stamped, st.edited = hostsINIStamp(data)
+		st.body = data
+		if st.stamped {
+			_, st.body, _ = bytes.Cut(data, []byte("\n"))
+		}
+		st.file = ReadHostsINI(data)
+		if st.file == nil {
+			st.file = []InventoryHost{}
+		}
+		st.fileGroupVars = roleGroupVars(ReadGroupVars(data))
+	case !os.IsNotExist(err):
+		return st, huma.Error500InternalServerError("failed to read hosts.ini", err)
+	}
+	st.nodes, err = o.Store().ListNodes(ctx, ws.name)
+	if err != nil {
+		return st, huma.Error500InternalServerError("failed to list nodes", err)
+	}
+	st.groupVars, err = o.Store().ListGroupVars(ctx, ws.name)
+	if err != nil {
+		return st, huma.Error500InternalServerError("failed to list group vars", err)
+	}
+	return st, nil
+}
+
+// drift lists the nodes that differ, database nodes first in their order,
+// then those only in hosts.ini in file order, and the roles whose group
+// variables differ.
+func (st inventoryState) drift() InventoryDrift {
+	d := InventoryDrift{Edited: st.edited, Items: []InventoryDriftItem{}}
+	inFile := make(map[string]InventoryNode, len(st.file))
+	for _, h := range st.file {
+		inFile[h.Name] = fileNode(h)
+	}
+	inDB := make(map[string]bool, len(st.nodes))
+	for _, info := range st.nodes {
+		inDB[info.Name] = true
+		db := infoNode(info)
+		file, ok := inFile[info.Name]
+		if !ok {
+			d.Items = append(d.Items, InventoryDriftItem{Name: info.Name, Kind: DriftMissingInFile, DB: &db})
+			continue
+		}
+		var fields []string
+		if db.AnsibleHost != file.AnsibleHost {
+			fields = append(fields, "ansible_host")
+		}
+		if db.AnsibleUser != file.AnsibleUser {
+			fields = append(fields, "ansible_user")
+		}
+		if db.AnsiblePort != file.AnsiblePort {
+			fields = append(fields, "ansible_port")
+		}
+		if db.PythonInterpreter != file.PythonInterpreter {
+			fields = append(fields, "ansible_python_interpreter")
+		}
+		if db.SSHCommonArgs != file.SSHCommonArgs {
+			fields = append(fields, "ansible_ssh_common_args")
+		}
+		if db.BecomeMethod != file.BecomeMethod {
+			fields = append(fields, "ansible_become_method")
+		}
+		if !maps.Equal(db.HostVars, file.HostVars) {
+			fields = append(fields, "host_vars")
+		}
+		if !slices.Equal(slices.Sorted(slices.Values(db.Roles)), slices.Sorted(slices.Values(file.Roles))) {
+			fields = append(fields, "roles")
+		}
+		if len(fields) > 0 {
+			d.Items = append(d.Items, InventoryDriftItem{Name: info.Name, Kind: DriftChanged, Fields: fields, DB: &db, File: &file})
+		}
+	}
+	for _, h := range st.file {
+		if !inDB[h.Name] {
+			file := inFile[h.Name]
+			d.Items = append(d.Items, InventoryDriftItem{Name: h.Name, Kind: DriftMissingInDB, File: &file})
+		}
+	}
+	// Without a hosts.ini only the nodes are reported missing.
+	if st.file != nil {
+		roles := slices.Collect(maps.Keys(st.groupVars))
+		for role := range st.fileGroupVars {
+			if _, ok := st.groupVars[role]; !ok {
+				roles = append(roles, role)
+			}
+		}
+		slices.Sort(roles)
+		for _, role := range roles {
+			if !maps.Equal(st.groupVars[role], st.fileGroupVars[role]) {
+				d.GroupVars = append(d.GroupVars, role)
+			}
+		}
+	}
+	d.InSync = len(d.Items) == 0 && len(d.GroupVars) == 0
+	return d
+}
+
+// fileNode is a hosts.ini host as a node; without ansible_host Ansible
+// connects to the host's name.
+func fileNode(h InventoryHost) InventoryNode {
+	n := inventoryNode(h)
+	if n.AnsibleHost == "" {
+		n.AnsibleHost = h.Name
+	}
+	return n
+}
+
+// infoNode is a database node as it appears in hosts.ini.
+func infoNode(info store.NodeInfo) InventoryNode {
+	return InventoryNode{
+		Name:              info.Name,
+		AnsibleHost:       info.AnsibleHost,
+		AnsibleUser:       info.AnsibleUser,
+		AnsiblePort:       info.AnsiblePort,
+		PythonInterpreter: info.PythonInterpreter,
+		SSHCommonArgs:     info.SSHCommonArgs,
+		BecomeMethod:      info.BecomeMethod,
+		HostVars:          info.HostVars,
+		Roles:             info.Roles,
+	}
+}
+
+// roleGroupVars keeps the group variables of hosts.ini's role groups, keyed
+// by role.
+func roleGroupVars(groups map[string]map[string]string) map[string]map[string]string {
+	vars := make(map[string]map[string]string)
+	for g, v := range groups {
+		if role := sectionToRole(g); role != "" && len(v) > 0 {
+			vars[role] = v
+		}
+	}
+	return vars
+}
+
+// applyInventory makes the node database match hosts.ini for the given drift.
+// Everything is checked up front and written in one transaction, so an
+// invalid file changes nothing. Nodes missing from hosts.ini are deleted only
+// with deleteMissing. Passwords in hosts.ini are imported; otherwise nodes
+// keep theirs.
+func (o *OnRamp) applyInventory(ctx context.Context, ws *workspace, st inventoryState, drift InventoryDrift, deleteMissing, skipValidation bool) error {
+	roles := o.registry(ws).roles
+	for _, role := range drift.GroupVars {
+		vars, ok := st.fileGroupVars[role]
+		if !ok {
+			continue
+		}
+		if !slices.Contains(roles, role) {
+			return huma.Error422UnprocessableEntity(fmt.Sprintf("[%s:vars]: invalid role %q; valid roles: %s",
+				roleSection(role), role, strings.Join(roles, ", ")))
+		}
+		if err := CheckInventoryVars(vars); err != nil {
+			return huma.Error422UnprocessableEntity(fmt.Sprintf("[%s:vars]: %v", roleSection(role), err))
+		}
+	}
+	for _, it := range drift.Items {
+		if it.File == nil {
+			continue
+		}
+		for _, r := range it.File.Roles {
+			if !slices.Contains(roles, r) {
+				return huma.Error422UnprocessableEntity(fmt.Sprintf("node %s: invalid role %q; valid roles: %s",
+					it.Name, r, strings.Join(roles, ", ")))
+			}
+		}
+	}
+	ids := make(map[string]string, len(st.nodes))
+	topology := make(map[string]TopologyNode, len(st.nodes))
+	for _, info := range st.nodes {
+		ids[info.Name] = info.ID
+		topology[info.Name] = TopologyNode{Name: info.Name, Roles: info.Roles}
+	}
+	vars := make(map[string]map[string]string, len(st.file))
+	for _, h := range st.file {
+		vars[h.Name] = h.Vars
+	}
+
+	db := o.Store()
+	var batch store.NodeBatch
+	for _, it := range drift.Items {
+		if it.Kind == DriftMissingInFile {
+			if deleteMissing {
+				batch.Delete = append(batch.Delete, ids[it.Name])
+				delete(topology, it.Name)
+			}
+			continue
+		}
+		node := store.Node{ID: uuid.NewString(), Workspace: ws.name}
+		if id, ok := ids[it.Name]; ok {
+			cur, found, err := db.GetNode(ctx, id)
+			if err != nil || !found {
+				return huma.Error500InternalServerError(fmt.Sprintf("failed to get node %s", it.Name), err)
+			}
+			node = cur
+			node.UpdatedAt = time.Time{}
+		}
+		node.Name = it.File.Name
+		node.AnsibleHost = it.File.AnsibleHost
+		node.AnsibleUser = it.File.AnsibleUser
+		node.Roles = it.File.Roles
+		node.NodeVars = store.NodeVars{
+			AnsiblePort:       it.File.AnsiblePort,
+			PythonInterpreter: it.File.PythonInterpreter,
+			SSHCommonArgs:     it.File.SSHCommonArgs,
+			BecomeMethod:      it.File.BecomeMethod,
+			HostVars:          it.File.HostVars,
+			JumpHost:          node.JumpHost,
+		}
+		if err := CheckNodeVars(node.NodeVars); err != nil {
+			return huma.Error422UnprocessableEntity(fmt.Sprintf("node %s: %v", it.Name, err))
+		}
+		password, sudoPassword := HostCredentials(vars[it.Name])
+		if password != "" {
+			node.Password = []byte(password)
+		}
+		if sudoPassword != "" {
+			node.SudoPassword = []byte(sudoPassword)
+		}
+		batch.Upsert = append(batch.Upsert, node)
+		topology[node.Name] = TopologyNode{Name: node.Name, Roles: node.Roles}
+	}
+	for _, role := range drift.GroupVars {
+		if batch.GroupVars == nil {
+			batch.GroupVars = make(map[string]map[string]string)
+		}
+		batch.GroupVars[role] = st.fileGroupVars[role]
+	}
+
+	// Like sync, an empty node set leaves nothing to check.
+	if !skipValidation && len(topology) > 0 {
+		components, err := o.deployedComponents(ctx, ws)
+		if err != nil {
+			return huma.Error500InternalServerError("failed to list component states", err)
+		}
+		nodes := make([]TopologyNode, 0, len(topology))
+		for _, name := range slices.Sorted(maps.Keys(topology)) {
+			nodes = append(nodes, topology[name])
+		}
+		if res := checkTopology(o.registry(ws), nodes, components); !res.Valid {
+			return TopologyError("node roles break topology rules; fix them or set skip_validation", res.Errors)
+		}
+	}
+	if err := db.ApplyNodes(ctx, ws.name, batch); err != nil {
+		return huma.Error500InternalServerError("failed to apply hosts.ini to the node database", err)
+	}
+	return nil
+}
+
+// hostsINIStampPrefix starts the first line of a hosts.ini written by
+// inventory sync, which records the SHA-256 of the rest of the file.
+const hostsINIStampPrefix = "# Written by aether-webd inventory sync; sha256="
+
+func stampHostsINI(body []byte) []byte {
+	sum := sha256.Sum256(body)
+	return append([]byte(hostsINIStampPrefix+hex.EncodeToString(sum[:])+"\n"), body...)
+}
+
+// hostsINIStamp reports whether data was written by inventory sync, and if
+// so whether it has been edited since.
+func hostsINIStamp(data []byte) (stamped, edited bool) {
+	first, rest, _ := bytes.Cut(data, []byte("\n"))
+	want, ok := bytes.CutPrefix(first, []byte(hostsINIStampPrefix))
+	if !ok {
+		return false, false
+	}
+	sum := sha256.Sum256(rest)
+	return true, hex.EncodeToString(sum[:]) != string(want)
+}
+
 // ---------------------------------------------------------------------------
 // Parser
 // ---------------------------------------------------------------------------
 
-// parseHostsINI parses an Ansible hosts.ini file into structured inventory data.
-func parseHostsINI(data []byte) InventoryData {
-	inv := InventoryData{}
+// InventoryHost is a host read from a hosts.ini file with all of its inline
+// variables, secrets included, and the groups that list it.
+type InventoryHost struct {
+	Name   string
+	Vars   map[string]string
+	Groups []string
+}
+
+// ReadHostsINI reads the hosts of an Ansible hosts.ini file in the order they
+// first appear. A host may be defined in any group; its inline variables are
+// merged across the lines that list it. [group:vars] sections are left to
+// ReadGroupVars and [group:children] sections are skipped.
+func ReadHostsINI(data []byte) []InventoryHost {
+	hosts, _ := readHostsINI(data)
+	return hosts
+}
 
-	// First pass: parse [all] section for node definitions.
-	nodeMap := make(map[string]*InventoryNode)
+// ReadGroupVars reads the [group:vars] sections of an Ansible hosts.ini file,
+// keyed by group.
+func ReadGroupVars(data []byte) map[string]map[string]string {
+	_, groupVars := readHostsINI(data)
+	return groupVars
+}
+
+func readHostsINI(data []byte) ([]InventoryHost, map[string]map[string]string) {
+	var hosts []InventoryHost
+	index := make(map[string]int)
+	groupVars := make(map[string]map[string]string)
 	scanner := bufio.NewScanner(bytes.NewReader(data))
-	currentSection := ""
+	section := ""
 
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
-		if line == "" || strings.HasPrefix(line, "#") {
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
 			continue
 		}
-
 		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
-			currentSection = strings.Trim(line, "[]")
+			section = strings.TrimSpace(strings.Trim(line, "[]"))
+			continue
+		}
+		if group, ok := strings.CutSuffix(section, ":vars"); ok {
+			k, v, ok := strings.Cut(line, "=")
+			if !ok {
+				continue
+			}
+			if groupVars[group] == nil {
+				groupVars[group] = make(map[string]string)
+			}
+			groupVars[group][strings.TrimSpace(k)] = unquoteVar(strings.TrimSpace(v))
+			continue
+		}
+		if strings.Contains(section, ":") {
+			continue
+		}
+
+		fields := splitHostLine(line)
+		if len(fields) == 0 || fields[0] == "" {
 			continue
 		}
+		name := fields[0]
+		i, ok := index[name]
+		if !ok {
+			i = len(hosts)
+			index[name] = i
+			hosts = append(hosts, InventoryHost{Name: name, Vars: make(map[string]string)})
+		}
+		h := &hosts[i]
+		for _, f := range fields[1:] {
+			if k, v, ok := strings.Cut(f, "="); ok {
+				h.Vars[k] = v
+			}
+		}
+		if section != "" && section != "all" && !slices.Contains(h.Groups, section) {
+			h.Groups = append(h.Groups, section)
+		}
+	}
+	return hosts, groupVars
+}
 
+// splitHostLine splits a host line into fields on whitespace the way Ansible
+// does: quoted values are kept together without their quotes, a backslash
+// escapes the next character outside single quotes, and an unquoted # starts
+// a comment.
+func splitHostLine(line string) []string {
+	var fields []string
+	var cur strings.Builder
+	var quote rune
+	inField, escaped := false, false
+	for _, r := range line {
 		switch {
-		case currentSection == "all":
-			node := parseAllLine(line)
-			if node.Name != "" {
-				nodeMap[node.Name] = &node
+		case escaped:
+			cur.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inField = true
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			cur.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote = r
+			inField = true
+		case r == '#' && !inField:
+			return fields
+		case r == ' ' || r == '\t':
+			if inField {
+				fields = append(fields, cur.String())
+				cur.Reset()
+				inField = false
 			}
+		default:
+			cur.WriteRune(r)
+			inField = true
+		}
+	}
+	if inField {
+		fields = append(fields, cur.String())
+	}
+	return fields
+}
 
+// unquoteVar strips the quotes around a [group:vars] value. Backslash
+// escapes are undone inside double quotes, as quoteVar writes them.
+func unquoteVar(v string) string {
+	if len(v) >= 2 && v[0] == '\'' && v[len(v)-1] == '\'' {
+		return v[1 : len(v)-1]
+	}
+	if len(v) >= 2 && v[0] == '"' && v[len(v)-1] == '"' {
+		return strings.NewReplacer(`\\`, `\`, `\"`, `"`).Replace(v[1 : len(v)-1])
+	}
+	return v
+}
+
+// quoteVar quotes a host line or [group:vars] value so that splitHostLine
+// or unquoteVar reads it back unchanged.
+func quoteVar(v string) string {
+	if v != "" && !strings.ContainsAny(v, " \t\"'\\#") {
+		return v
+	}
+	if !strings.Contains(v, "'") {
+		return "'" + v + "'"
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(v) + `"`
+}
+
+// parseHostsINI parses an Ansible hosts.ini file into structured inventory
+// data. Passwords are never returned.
+func parseHostsINI(data []byte) InventoryData {
+	hosts, groupVars := readHostsINI(data)
+	inv := InventoryData{}
+	for _, h := range hosts {
+		inv.Nodes = append(inv.Nodes, inventoryNode(h))
+	}
+	for role, vars := range roleGroupVars(groupVars) {
+		for _, k := range secretVars {
+			delete(vars, k)
+		}
+		if len(vars) > 0 {
+			if inv.GroupVars == nil {
+				inv.GroupVars = make(map[string]map[string]string)
+			}
+			inv.GroupVars[role] = vars
+		}
+	}
+	return inv
+}
+
+// inventoryNode is a hosts.ini host as a node, passwords left out.
+func inventoryNode(h InventoryHost) InventoryNode {
+	v := h.NodeVars()
+	n := InventoryNode{
+		Name:              h.Name,
+		AnsibleHost:       h.Vars["ansible_host"],
+		AnsibleUser:       h.Vars["ansible_user"],
+		AnsiblePort:       v.AnsiblePort,
+		PythonInterpreter: v.PythonInterpreter,
+		SSHCommonArgs:     v.SSHCommonArgs,
+		BecomeMethod:      v.BecomeMethod,
+		HostVars:          v.HostVars,
+	}
+	for _, g := range h.Groups {
+		if role := sectionToRole(g); role != "" {
+			n.Roles = append(n.Roles, role)
+		}
+	}
+	return n
+}
+
+// NodeVars returns the host's variables as a node keeps them. Addresses,
+// users, passwords and the ProxyCommand of its jump hosts are left out; a
+// port that is not a number is kept as a host var.
+func (h InventoryHost) NodeVars() store.NodeVars {
+	var v store.NodeVars
+	for k, val := range h.Vars {
+		switch k {
+		case "ansible_host", "ansible_ssh_host", "ansible_user", "ansible_ssh_user":
+		case "ansible_python_interpreter":
+			v.PythonInterpreter = val
+		case "ansible_ssh_common_args":
+			v.SSHCommonArgs = stripJumpProxy(val)
+		case "ansible_become_method":
+			v.BecomeMethod = val
+		case "ansible_port":
+			if port, err := strconv.Atoi(val); err == nil {
+				v.AnsiblePort = port
+				continue
+			}
+			fallthrough
 		default:
-			// Role sections: bare node names
-			role := sectionToRole(currentSection)
-			if role == "" {
+			if slices.Contains(secretVars, k) {
 				continue
 			}
-			name := strings.Fields(line)[0]
-			if n, ok := nodeMap[name]; ok {
-				n.Roles = append(n.Roles, role)
+			if v.HostVars == nil {
+				v.HostVars = make(map[string]string)
 			}
+			v.HostVars[k] = val
 		}
 	}
+	return v
+}
 
-	for _, n := range nodeMap {
-		inv.Nodes = append(inv.Nodes, *n)
+// secretVars are the inventory variables that hold passwords.
+var secretVars = []string{
+	"ansible_password", "ansible_ssh_pass",
+	"ansible_sudo_pass", "ansible_become_password", "ansible_become_pass",
+}
+
+// HostCredentials returns the SSH and sudo passwords among a host's
+// inventory variables, under any of the names Ansible accepts.
+func HostCredentials(vars map[string]string) (password, sudoPassword string) {
+	password = firstVar(vars, secretVars[:2]...)
+	sudoPassword = firstVar(vars, secretVars[2:]...)
+	return password, sudoPassword
+}
+
+func firstVar(vars map[string]string, keys ...string) string {
+	for _, k := range keys {
+		if v := vars[k]; v != "" {
+			return v
+		}
 	}
-	return inv
+	return ""
 }
 
-// parseAllLine parses a line from the [all] section.
-// Format: name ansible_host=... ansible_user=... ansible_password=... ansible_sudo_pass=...
-func parseAllLine(line string) InventoryNode {
-	fields := strings.Fields(line)
-	if len(fields) == 0 {
-		return InventoryNode{}
+// roleSection is the hosts.ini group holding the nodes with a role.
+func roleSection(role string) string {
+	return role + "_nodes"
+}
+
+// sectionToRole maps an INI section name to a node role.
+func sectionToRole(section string) string {
+	role, ok := strings.CutSuffix(section, "_nodes")
+	if !ok {
+		return ""
 	}
+	return role
+}
 
-	node := InventoryNode{Name: fields[0]}
-	for _, f := range fields[1:] {
-		k, v, ok := strings.Cut(f, "=")
-		if !ok {
-			continue
+// ---------------------------------------------------------------------------
+// Validation
+// ---------------------------------------------------------------------------
+
+var varNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// nodeVarNames are the host variables a node has fields for.
+var nodeVarNames = []string{
+	"ansible_host", "ansible_user", "ansible_port",
+	"ansible_python_interpreter", "ansible_ssh_common_args", "ansible_become_method",
+}
+
+// CheckNodeVars reports why a node's inventory variables cannot be written
+// to hosts.ini, or nil if they can.
+func CheckNodeVars(v store.NodeVars) error {
+	if v.AnsiblePort < 0 || v.AnsiblePort > 65535 {
+		return fmt.Errorf("ansible_port %d is out of range", v.AnsiblePort)
+	}
+	for _, f := range [][2]string{
+		{"python_interpreter", v.PythonInterpreter},
+		{"ssh_common_args", v.SSHCommonArgs},
+		{"become_method", v.BecomeMethod},
+	} {
+		if strings.ContainsAny(f[1], "\r\n") {
+			return fmt.Errorf("%s must be a single line", f[0])
 		}
-		switch k {
-		case "ansible_host":
-			node.AnsibleHost = v
-		case "ansible_user":
-			node.AnsibleUser = v
+	}
+	for _, k := range slices.Sorted(maps.Keys(v.HostVars)) {
+		if slices.Contains(nodeVarNames, k) {
+			return fmt.Errorf("host var %q has its own field", k)
 		}
 	}
-	return node
+	return CheckInventoryVars(v.HostVars)
 }
 
-// sectionToRole maps an INI section name to a node role.
-func sectionToRole(section string) string {
-	for _, rs := range roleSections {
-		if rs.section == section {
-			return rs.role
+// CheckInventoryVars reports why host or group variables cannot be written
+// to hosts.ini, or nil if they can. Passwords are refused: they are kept on
+// the node and out of hosts.ini.
+func CheckInventoryVars(vars map[string]string) error {
+	for _, k := range slices.Sorted(maps.Keys(vars)) {
+		switch {
+		case !varNamePattern.MatchString(k):
+			return fmt.Errorf("invalid variable name %q", k)
+		case slices.Contains(secretVars, k):
+			return fmt.Errorf("variable %q holds a password; set it on the node instead", k)
+		case strings.ContainsAny(vars[k], "\r\n"):
+			return fmt.Errorf("variable %q must be a single line", k)
 		}
 	}
-	return ""
+	return nil
 }
 
 // ---------------------------------------------------------------------------
 // Generator
 // ---------------------------------------------------------------------------
 
-// generateHostsINI produces an Ansible hosts.ini file from the given nodes.
-func generateHostsINI(nodes []store.Node) []byte {
+// HostsINI returns the hosts.ini inventory sync writes for nodes, with a
+// group for each role in roles and the group variables of each role.
+func HostsINI(nodes []store.Node, roles []string, groupVars map[string]map[string]string) []byte {
+	return generateHostsINI(nodes, roles, groupVars)
+}
+
+// generateHostsINI produces an Ansible hosts.ini file from the given nodes,
+// with a group for each role in roles, in that order, followed by its
+// [<role>_nodes:vars] when groupVars has any for the role. Node passwords are
+// left out; see writeSecrets.
+func generateHostsINI(nodes []store.Node, roles []string, groupVars map[string]map[string]string) []byte {
 	var buf bytes.Buffer
 
 	// [all] section
@@ -173,18 +862,12 @@ func generateHostsINI(nodes []store.Node) []byte {
 	for _, n := range nodes {
 		buf.WriteString(n.Name)
 		buf.WriteString(" ansible_host=")
-		buf.WriteString(n.AnsibleHost)
-		if n.AnsibleUser != "" {
-			buf.WriteString(" ansible_user=")
-			buf.WriteString(n.AnsibleUser)
-		}
-		if len(n.Password) > 0 {
-			buf.WriteString(" ansible_password=")
-			buf.WriteString(string(n.Password))
-		}
-		if len(n.SudoPassword) > 0 {
-			buf.WriteString(" ansible_sudo_pass=")
-			buf.WriteString(string(n.SudoPassword))
+		buf.WriteString(quoteVar(n.AnsibleHost))
+		for _, kv := range hostLineVars(n) {
+			buf.WriteString(" ")
+			buf.WriteString(kv[0])
+			buf.WriteString("=")
+			buf.WriteString(quoteVar(kv[1]))
 		}
 		buf.WriteString("\n")
 	}
@@ -197,16 +880,49 @@ func generateHostsINI(nodes []store.Node) []byte {
 		}
 	}
 
-	// Emit role sections in fixed order
-	for _, rs := range roleSections {
+	// Emit role sections in registry order
+	for _, role := range roles {
 		buf.WriteString("\n[")
-		buf.WriteString(rs.section)
+		buf.WriteString(roleSection(role))
 		buf.WriteString("]\n")
-		for _, name := range roleNodes[rs.role] {
+		for _, name := range roleNodes[role] {
 			buf.WriteString(name)
 			buf.WriteString("\n")
 		}
+		if vars := groupVars[role]; len(vars) > 0 {
+			buf.WriteString("\n[")
+			buf.WriteString(roleSection(role))
+			buf.WriteString(":vars]\n")
+			for _, k := range slices.Sorted(maps.Keys(vars)) {
+				buf.WriteString(k)
+				buf.WriteString("=")
+				buf.WriteString(quoteVar(vars[k]))
+				buf.WriteString("\n")
+			}
+		}
 	}
 
 	return buf.Bytes()
 }
+
+// hostLineVars returns the variables after ansible_host on a node's hosts.ini
+// line: the connection settings that are set, then its host vars by name.
+func hostLineVars(n store.Node) [][2]string {
+	var vars [][2]string
+	add := func(k, v string) {
+		if v != "" {
+			vars = append(vars, [2]string{k, v})
+		}
+	}
+	add("ansible_user", n.AnsibleUser)
+	if n.AnsiblePort != 0 {
+		add("ansible_port", strconv.Itoa(n.AnsiblePort))
+	}
+	add("ansible_python_interpreter", n.PythonInterpreter)
+	add("ansible_ssh_common_args", n.SSHCommonArgs)
+	add("ansible_become_method", n.BecomeMethod)
+	for _, k := range slices.Sorted(maps.Keys(n.HostVars)) {
+		vars = append(vars, [2]string{k, n.HostVars[k]})
+	}
+	return vars
+}
@@ -0,0 +1,440 @@
+package onramp
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bengrewell/aether-webui/internal/store"
+)
+
+// newInventoryTestProvider returns a provider with node1 (master) and node2
+// (worker) synced to hosts.ini.
+func newInventoryTestProvider(t *testing.T) *OnRamp {
+	t.Helper()
+	o := newTestProviderWithStore(t, "")
+	addNode(t, o, store.Node{ID: "n1", Name: "node1", AnsibleHost: "10.0.0.1", AnsibleUser: "aether", Roles: []string{"master"}})
+	addNode(t, o, store.Node{ID: "n2", Name: "node2", AnsibleHost: "10.0.0.2", AnsibleUser: "aether", Roles: []string{"worker"}})
+	if _, err := o.HandleSyncInventory(t.Context(), &InventorySyncInput{}); err != nil {
+		t.Fatalf("HandleSyncInventory: %v", err)
+	}
+	return o
+}
+
+// editHostsINI replaces the body of hosts.ini, keeping its sync stamp.
+func editHostsINI(t *testing.T, o *OnRamp, body string) {
+	t.Helper()
+	path := filepath.Join(o.config.OnRampDir, "hosts.ini")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	stamp, _, _ := strings.Cut(string(data), "\n")
+	writeTestFile(t, path, stamp+"\n"+body)
+}
+
+const editedHostsINI = `[all]
+node1 ansible_host=10.0.0.9 ansible_user=aether
+node3 ansible_host=10.0.0.3 ansible_user=ubuntu ansible_password=pw3
+
+[master_nodes]
+node1
+
+[worker_nodes]
+node3
+`
+
+func driftKinds(d InventoryDrift) map[string]string {
+	kinds := make(map[string]string)
+	for _, it := range d.Items {
+		kinds[it.Name] = it.Kind
+	}
+	return kinds
+}
+
+func TestInventoryDrift(t *testing.T) {
+	o := newInventoryTestProvider(t)
+
+	out, err := o.HandleGetInventoryDrift(t.Context(), &WorkspaceInput{})
+	if err != nil {
+		t.Fatalf("drift: %v", err)
+	}
+	if !out.Body.InSync || out.Body.Edited {
+		t.Fatalf("drift after sync = %+v", out.Body)
+	}
+
+	editHostsINI(t, o, editedHostsINI)
+	out, err = o.HandleGetInventoryDrift(t.Context(), &WorkspaceInput{})
+	if err != nil {
+		t.Fatalf("drift: %v", err)
+	}
+	if out.Body.InSync || !out.Body.Edited {
+		t.Errorf("drift = %+v", out.Body)
+	}
+	want := map[string]string{"node1": DriftChanged, "node2": DriftMissingInFile, "node3": DriftMissingInDB}
+	if got := driftKinds(out.Body); !reflect.DeepEqual(got, want) {
+		t.Errorf("kinds = %v, want %v", got, want)
+	}
+	if it := out.Body.Items[0]; !reflect.DeepEqual(it.Fields, []string{"ansible_host"}) || it.File.AnsibleHost != "10.0.0.9" {
+		t.Errorf("node1 = %+v", it)
+	}
+
+	o.config.OnRampDir = t.TempDir()
+	o.defaultWorkspace().config.OnRampDir = o.config.OnRampDir
+	out, err = o.HandleGetInventoryDrift(t.Context(), &WorkspaceInput{})
+	if err != nil {
+		t.Fatalf("drift without hosts.ini: %v", err)
+	}
+	if got := driftKinds(out.Body); got["node1"] != DriftMissingInFile || got["node2"] != DriftMissingInFile {
+		t.Errorf("kinds without hosts.ini = %v", got)
+	}
+}
+
+func TestSyncInventory_ManualEdits(t *testing.T) {
+	o := newInventoryTestProvider(t)
+	editHostsINI(t, o, editedHostsINI)
+
+	_, err := o.HandleSyncInventory(t.Context(), &InventorySyncInput{})
+	wantStatus(t, err, 409)
+
+	out, err := o.HandleSyncInventory(t.Context(), &InventorySyncInput{Force: true})
+	if err != nil {
+		t.Fatalf("forced sync: %v", err)
+	}
+	if len(out.Body.Warnings) != 1 || !strings.Contains(out.Body.Warnings[0], "manual edits") {
+		t.Errorf("warnings = %v", out.Body.Warnings)
+	}
+
+	// A hosts.ini sync did not write is replaced with a warning.
+	writeTestFile(t, filepath.Join(o.config.OnRampDir, "hosts.ini"), editedHostsINI)
+	out, err = o.HandleSyncInventory(t.Context(), &InventorySyncInput{})
+	if err != nil {
+		t.Fatalf("sync over unstamped file: %v", err)
+	}
+	if len(out.Body.Warnings) != 1 || !strings.Contains(out.Body.Warnings[0], "not written by inventory sync") {
+		t.Errorf("warnings = %v", out.Body.Warnings)
+	}
+}
+
+func TestReconcileInventory_ToDB(t *testing.T) {
+	o := newInventoryTestProvider(t)
+	editHostsINI(t, o, editedHostsINI)
+
+	in := &InventoryReconcileInput{}
+	in.Body.Direction = ReconcileToDB
+	in.Body.DeleteMissing = true
+	out, err := o.HandleReconcileInventory(t.Context(), in)
+	if err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if len(out.Body.Applied) != 3 {
+		t.Errorf("applied = %+v", out.Body.Applied)
+	}
+
+	drift, err := o.HandleGetInventoryDrift(t.Context(), &WorkspaceInput{})
+	if err != nil {
+		t.Fatalf("drift: %v", err)
+	}
+	if !drift.Body.InSync || drift.Body.Edited {
+		t.Errorf("drift after reconcile = %+v", drift.Body)
+	}
+	nodes, err := o.workspaceNodes(t.Context(), o.defaultWorkspace())
+	if err != nil {
+		t.Fatalf("workspaceNodes: %v", err)
+	}
+	byName := make(map[string]store.Node)
+	for _, n := range nodes {
+		byName[n.Name] = n
+	}
+	if _, ok := byName["node2"]; ok || len(nodes) != 2 {
+		t.Errorf("nodes = %+v", nodes)
+	}
+	if n := byName["node3"]; string(n.Password) != "pw3" || !reflect.DeepEqual(n.Roles, []string{"worker"}) {
+		t.Errorf("node3 = %+v", n)
+	}
+	if byName["node1"].ID != "n1" || byName["node1"].AnsibleHost != "10.0.0.9" {
+		t.Errorf("node1 = %+v", byName["node1"])
+	}
+	// The reviewed file is kept and accepted by the next sync.
+	data, _ := os.ReadFile(filepath.Join(o.config.OnRampDir, "hosts.ini"))
+	if !strings.Contains(string(data), "ansible_password=pw3") {
+		t.Errorf("hosts.ini was rewritten:\n%s", data)
+	}
+	if _, err := o.HandleSyncInventory(t.Context(), &InventorySyncInput{}); err != nil {
+		t.Errorf("sync after reconcile: %v", err)
+	}
+
+	editHostsINI(t, o, "[all]\nnode1 ansible_host=10.0.0.1\n\n[bogus_nodes]\nnode1\n")
+	_, err = o.HandleReconcileInventory(t.Context(), in)
+	wantStatus(t, err, 422)
+}
+
+func TestReconcileInventory_KeepMissing(t *testing.T) {
+	o := newInventoryTestProvider(t)
+	editHostsINI(t, o, editedHostsINI)
+
+	in := &InventoryReconcileInput{}
+	in.Body.Direction = ReconcileToDB
+	out, err := o.HandleReconcileInventory(t.Context(), in)
+	if err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if len(out.Body.Applied) != 2 || !reflect.DeepEqual(out.Body.Kept, []string{"node2"}) {
+		t.Errorf("applied = %+v, kept = %v", out.Body.Applied, out.Body.Kept)
+	}
+	if _, found, _ := o.Store().GetNode(t.Context(), "n2"); !found {
+		t.Error("node2 was deleted without delete_missing")
+	}
+	if n, found, _ := o.Store().GetNode(t.Context(), "n1"); !found || n.AnsibleHost != "10.0.0.9" {
+		t.Errorf("node1 = %+v", n)
+	}
+}
+
+func TestReconcileInventory_Validation(t *testing.T) {
+	o := newInventoryTestProvider(t)
+	unchanged := func() {
+		t.Helper()
+		nodes, err := o.workspaceNodes(t.Context(), o.defaultWorkspace())
+		if err != nil {
+			t.Fatalf("workspaceNodes: %v", err)
+		}
+		if len(nodes) != 2 || nodes[0].AnsibleHost != "10.0.0.1" || nodes[1].AnsibleHost != "10.0.0.2" {
+			t.Errorf("nodes changed: %+v", nodes)
+		}
+	}
+	in := &InventoryReconcileInput{}
+	in.Body.Direction = ReconcileToDB
+	in.Body.DeleteMissing = true
+
+	// Deleting the master leaves no node for it.
+	editHostsINI(t, o, "[all]\nnode2 ansible_host=10.0.0.5 ansible_user=aether\n\n[worker_nodes]\nnode2\n")
+	_, err := o.HandleReconcileInventory(t.Context(), in)
+	wantStatus(t, err, 422)
+	unchanged()
+
+	// A bad node is caught before the valid ones are written.
+	editHostsINI(t, o, `[all]
+node1 ansible_host=10.0.0.8 ansible_user=aether
+node2 ansible_host=10.0.0.9 ansible_user=aether ansible_port=70000
+
+[master_nodes]
+node1
+
+[worker_nodes]
+node2
+`)
+	_, err = o.HandleReconcileInventory(t.Context(), in)
+	wantStatus(t, err, 422)
+	unchanged()
+
+	editHostsINI(t, o, "[all]\nnode2 ansible_host=10.0.0.5 ansible_user=aether\n\n[worker_nodes]\nnode2\n")
+	in.Body.SkipValidation = true
+	if _, err := o.HandleReconcileInventory(t.Context(), in); err != nil {
+		t.Fatalf("reconcile with skip_validation: %v", err)
+	}
+	nodes, _ := o.workspaceNodes(t.Context(), o.defaultWorkspace())
+	if len(nodes) != 1 || nodes[0].AnsibleHost != "10.0.0.5" {
+		t.Errorf("nodes = %+v", nodes)
+	}
+}
+
+func TestReconcileInventory_ToFile(t *testing.T) {
+	o := newInventoryTestProvider(t)
+	editHostsINI(t, o, editedHostsINI)
+
+	in := &InventoryReconcileInput{}
+	in.Body.Direction = ReconcileToFile
+	if _, err := o.HandleReconcileInventory(t.Context(), in); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	drift, err := o.HandleGetInventoryDrift(t.Context(), &WorkspaceInput{})
+	if err != nil {
+		t.Fatalf("drift: %v", err)
+	}
+	if !drift.Body.InSync || drift.Body.Edited {
+		t.Errorf("drift after reconcile = %+v", drift.Body)
+	}
+}
+
+func TestInventoryDrift_NoStore(t *testing.T) {
+	o := newTestProvider(t, "")
+	_, err := o.HandleGetInventoryDrift(t.Context(), &WorkspaceInput{})
+	wantStatus(t, err, 503)
+}
+
+func TestHostsINI_VarsRoundTrip(t *testing.T) {
+	nodes := []store.Node{{
+		Name: "node1", AnsibleHost: "10.0.0.1", AnsibleUser: "aether", Roles: []string{"master"},
+		Password: []byte("pw1"),
+		NodeVars: store.NodeVars{
+			AnsiblePort:       2222,
+			PythonInterpreter: "/usr/bin/python3",
+			SSHCommonArgs:     `-o ProxyCommand="ssh -W %h:%p bastion"`,
+			BecomeMethod:      "su",
+			HostVars:          map[string]string{"data_iface": "ens18", "motd": "it's #1", "empty": ""},
+		},
+	}}
+	groupVars := map[string]map[string]string{"master": {
+		"ntp_server": "pool.ntp.org",
+		"note":       "two words",
+		"banner":     `"quoted"`,
+		"motd":       `it's "#1" \o/`,
+		"padded":     " x ",
+		"empty":      "",
+	}}
+
+	data := generateHostsINI(nodes, DefaultRoles(), groupVars)
+	if !strings.Contains(string(data), "note='two words'\nntp_server=pool.ntp.org\n") {
+		t.Errorf("missing group vars:\n%s", data)
+	}
+	if strings.Contains(string(data), "[worker_nodes:vars]") {
+		t.Errorf("empty group vars written:\n%s", data)
+	}
+
+	inv := parseHostsINI(data)
+	if len(inv.Nodes) != 1 {
+		t.Fatalf("nodes = %+v", inv.Nodes)
+	}
+	n := inv.Nodes[0]
+	v := nodes[0].NodeVars
+	if n.AnsiblePort != v.AnsiblePort || n.PythonInterpreter != v.PythonInterpreter ||
+		n.SSHCommonArgs != v.SSHCommonArgs || n.BecomeMethod != v.BecomeMethod {
+		t.Errorf("node = %+v\n%s", n, data)
+	}
+	if !reflect.DeepEqual(n.HostVars, v.HostVars) {
+		t.Errorf("host vars = %v, want %v\n%s", n.HostVars, v.HostVars, data)
+	}
+	if !reflect.DeepEqual(inv.GroupVars, groupVars) {
+		t.Errorf("group vars = %v, want %v", inv.GroupVars, groupVars)
+	}
+}
+
+func TestParseHostsINI_SkipsSecretVars(t *testing.T) {
+	inv := parseHostsINI([]byte(`[all]
+node1 ansible_host=10.0.0.1 ansible_ssh_pass=pw ansible_port=ssh # trailing comment
+
+[master_nodes:vars]
+ansible_become_pass=sudo
+ansible_port = "2200"
+`))
+	n := inv.Nodes[0]
+	// A port that is not a number is kept as written.
+	if want := map[string]string{"ansible_port": "ssh"}; !reflect.DeepEqual(n.HostVars, want) || n.AnsiblePort != 0 {
+		t.Errorf("node = %+v", n)
+	}
+	if want := map[string]map[string]string{"master": {"ansible_port": "2200"}}; !reflect.DeepEqual(inv.GroupVars, want) {
+		t.Errorf("group vars = %v, want %v", inv.GroupVars, want)
+	}
+}
+
+func TestCheckNodeVars(t *testing.T) {
+	ok := store.NodeVars{AnsiblePort: 22, HostVars: map[string]string{"data_iface": "ens18"}}
+	if err := CheckNodeVars(ok); err != nil {
+		t.Errorf("CheckNodeVars(%+v) = %v", ok, err)
+	}
+	for _, v := range []store.NodeVars{
+		{AnsiblePort: 70000},
+		{SSHCommonArgs: "-o A\n-o B"},
+		{HostVars: map[string]string{"ansible_port": "22"}},
+		{HostVars: map[string]string{"ansible_password": "pw"}},
+		{HostVars: map[string]string{"bad-name": "x"}},
+		{HostVars: map[string]string{"x": "a\nb"}},
+	} {
+		if err := CheckNodeVars(v); err == nil {
+			t.Errorf("CheckNodeVars(%+v) = nil, want error", v)
+		}
+	}
+}
+
+func TestGroupVars(t *testing.T) {
+	o := newInventoryTestProvider(t)
+
+	in := &InventoryGroupVarsSetInput{Role: "master"}
+	in.Body.Vars = map[string]string{"ntp_server": "pool.ntp.org"}
+	if _, err := o.HandleSetGroupVars(t.Context(), in); err != nil {
+		t.Fatalf("HandleSetGroupVars: %v", err)
+	}
+	out, err := o.HandleGetGroupVars(t.Context(), &WorkspaceInput{})
+	if err != nil {
+		t.Fatalf("HandleGetGroupVars: %v", err)
+	}
+	if want := map[string]map[string]string{"master": in.Body.Vars}; !reflect.DeepEqual(out.Body.GroupVars, want) {
+		t.Errorf("group vars = %v, want %v", out.Body.GroupVars, want)
+	}
+
+	// hosts.ini lags until the next sync.
+	drift, err := o.HandleGetInventoryDrift(t.Context(), &WorkspaceInput{})
+	if err != nil {
+		t.Fatalf("drift: %v", err)
+	}
+	if drift.Body.InSync || !reflect.DeepEqual(drift.Body.GroupVars, []string{"master"}) {
+		t.Errorf("drift = %+v", drift.Body)
+	}
+	if _, err := o.HandleSyncInventory(t.Context(), &InventorySyncInput{}); err != nil {
+		t.Fatalf("HandleSyncInventory: %v", err)
+	}
+	inv, err := o.HandleGetInventory(t.Context(), &WorkspaceInput{})
+	if err != nil {
+		t.Fatalf("HandleGetInventory: %v", err)
+	}
+	if !reflect.DeepEqual(inv.Body.GroupVars["master"], in.Body.Vars) {
+		t.Errorf("inventory group vars = %v", inv.Body.GroupVars)
+	}
+
+	bad := &InventoryGroupVarsSetInput{Role: "bogus"}
+	_, err = o.HandleSetGroupVars(t.Context(), bad)
+	wantStatus(t, err, 404)
+	bad.Role = "master"
+	bad.Body.Vars = map[string]string{"ansible_password": "pw"}
+	_, err = o.HandleSetGroupVars(t.Context(), bad)
+	wantStatus(t, err, 422)
+}
+
+func TestReconcileInventory_ToDBVars(t *testing.T) {
+	o := newInventoryTestProvider(t)
+	editHostsINI(t, o, `[all]
+node1 ansible_host=10.0.0.1 ansible_user=aether ansible_port=2222 ansible_ssh_common_args='-o ProxyJump=bastion'
+node2 ansible_host=10.0.0.2 ansible_user=aether
+
+[master_nodes]
+node1
+
+[worker_nodes]
+node2
+
+[worker_nodes:vars]
+ansible_become_method=su
+`)
+	drift, err := o.HandleGetInventoryDrift(t.Context(), &WorkspaceInput{})
+	if err != nil {
+		t.Fatalf("drift: %v", err)
+	}
+	if len(drift.Body.Items) != 1 || !reflect.DeepEqual(drift.Body.Items[0].Fields, []string{"ansible_port", "ansible_ssh_common_args"}) ||
+		!reflect.DeepEqual(drift.Body.GroupVars, []string{"worker"}) {
+		t.Fatalf("drift = %+v", drift.Body)
+	}
+
+	in := &InventoryReconcileInput{}
+	in.Body.Direction = ReconcileToDB
+	if _, err := o.HandleReconcileInventory(t.Context(), in); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	node, _, _ := o.Store().GetNode(t.Context(), "n1")
+	if node.AnsiblePort != 2222 || node.SSHCommonArgs != "-o ProxyJump=bastion" {
+		t.Errorf("node1 = %+v", node)
+	}
+	gv, _ := o.Store().ListGroupVars(t.Context(), "")
+	if gv["worker"]["ansible_become_method"] != "su" {
+		t.Errorf("group vars = %v", gv)
+	}
+	// Vars survive the next sync.
+	if _, err := o.HandleSyncInventory(t.Context(), &InventorySyncInput{}); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	drift, _ = o.HandleGetInventoryDrift(t.Context(), &WorkspaceInput{})
+	if !drift.Body.InSync {
+		t.Errorf("drift after sync = %+v", drift.Body)
+	}
+}
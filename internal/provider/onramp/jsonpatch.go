@@ -0,0 +1,313 @@
+package onramp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Patch formats accepted by PATCH /api/v1/onramp/config, selected by the
+// request's Content-Type. Anything other than JSON Patch is treated as a
+// merge patch.
+const (
+	mediaMergePatch = "application/merge-patch+json" // RFC 7386
+	mediaJSONPatch  = "application/json-patch+json"  // RFC 6902
+)
+
+var (
+	// errBadPatch marks a patch document that cannot be decoded.
+	errBadPatch = errors.New("malformed patch")
+	// errPatchTest is returned when a JSON Patch test operation fails.
+	errPatchTest = errors.New("test operation failed")
+)
+
+// patchOp is one RFC 6902 operation. Value is kept raw so that an explicit
+// null can be told apart from a missing value.
+type patchOp struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// jsonPatch applies an RFC 6902 patch to the document. Operations apply in
+// order to the YAML nodes, so comments and layout outside the touched paths
+// are kept. On error the document may be partly patched and must be
+// discarded.
+func (d *varsDoc) jsonPatch(patchJSON []byte) error {
+	var ops []patchOp
+	if err := json.Unmarshal(patchJSON, &ops); err != nil {
+		return fmt.Errorf("%w: JSON Patch must be an array of operations: %v", errBadPatch, err)
+	}
+	for i, op := range ops {
+		if err := d.applyOp(op); err != nil {
+			return fmt.Errorf("operation %d (%s %s): %w", i, op.Op, op.Path, err)
+		}
+	}
+	return nil
+}
+
+func (d *varsDoc) applyOp(op patchOp) error {
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return err
+	}
+	switch op.Op {
+	case "add", "replace", "test":
+		if len(op.Value) == 0 {
+			return fmt.Errorf("%w: %s requires a value", errBadPatch, op.Op)
+		}
+	case "move", "copy":
+		if _, err := parsePointer(op.From); err != nil {
+			return err
+		}
+	case "remove":
+	default:
+		return fmt.Errorf("%w: unknown op %q", errBadPatch, op.Op)
+	}
+
+	switch op.Op {
+	case "add", "replace":
+		v, err := rawValueNode(op.Value)
+		if err != nil {
+			return err
+		}
+		return d.set(path, v, op.Op == "replace")
+	case "remove":
+		_, err := d.take(path)
+		return err
+	case "move":
+		if op.From == op.Path {
+			return nil
+		}
+		if strings.HasPrefix(op.Path, op.From+"/") {
+			return fmt.Errorf("cannot move %s into itself", op.From)
+		}
+		from, _ := parsePointer(op.From)
+		v, err := d.take(from)
+		if err != nil {
+			return err
+		}
+		return d.set(path, v, false)
+	case "copy":
+		from, _ := parsePointer(op.From)
+		v, err := d.lookup(from)
+		if err != nil {
+			return err
+		}
+		return d.set(path, copyNode(v), false)
+	default: // test
+		n, err := d.lookup(path)
+		if err != nil {
+			return err
+		}
+		var want any
+		if err := json.Unmarshal(op.Value, &want); err != nil {
+			return fmt.Errorf("%w: %v", errBadPatch, err)
+		}
+		if !reflect.DeepEqual(nodeJSON(n), want) {
+			return errPatchTest
+		}
+		return nil
+	}
+}
+
+// parsePointer splits an RFC 6901 JSON Pointer into unescaped tokens. The
+// empty pointer refers to the whole document.
+func parsePointer(p string) ([]string, error) {
+	if p == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(p, "/") {
+		return nil, fmt.Errorf("%w: pointer %q must start with /", errBadPatch, p)
+	}
+	toks := strings.Split(p[1:], "/")
+	for i, t := range toks {
+		toks[i] = strings.ReplaceAll(strings.ReplaceAll(t, "~1", "/"), "~0", "~")
+	}
+	return toks, nil
+}
+
+// lookup returns the node a pointer refers to.
+func (d *varsDoc) lookup(path []string) (*yaml.Node, error) {
+	n := d.root
+	for i, tok := range path {
+		switch n.Kind {
+		case yaml.MappingNode:
+			k := keyIndex(n, tok)
+			if k < 0 {
+				return nil, fmt.Errorf("/%s does not exist", strings.Join(path[:i+1], "/"))
+			}
+			n = n.Content[k+1]
+		case yaml.SequenceNode:
+			idx, err := seqIndex(n, tok, false)
+			if err != nil {
+				return nil, err
+			}
+			n = n.Content[idx]
+		default:
+			return nil, fmt.Errorf("/%s is not an object or array", strings.Join(path[:i], "/"))
+		}
+	}
+	return n, nil
+}
+
+// parent returns the container holding the last token of path.
+func (d *varsDoc) parent(path []string) (*yaml.Node, string, error) {
+	p, err := d.lookup(path[:len(path)-1])
+	if err != nil {
+		return nil, "", err
+	}
+	if p.Kind != yaml.MappingNode && p.Kind != yaml.SequenceNode {
+		return nil, "", fmt.Errorf("/%s is not an object or array", strings.Join(path[:len(path)-1], "/"))
+	}
+	return p, path[len(path)-1], nil
+}
+
+// set adds v at path, replacing any existing value. With mustExist the
+// target must already exist (replace); otherwise array targets insert
+// before the given index (add).
+func (d *varsDoc) set(path []string, v *yaml.Node, mustExist bool) error {
+	if len(path) == 0 {
+		if v.Kind != yaml.MappingNode {
+			return errors.New("the document must remain an object")
+		}
+		d.root.Content = v.Content
+		return nil
+	}
+	p, tok, err := d.parent(path)
+	if err != nil {
+		return err
+	}
+	if p.Kind == yaml.MappingNode {
+		i := keyIndex(p, tok)
+		if i < 0 && mustExist {
+			return fmt.Errorf("/%s does not exist", strings.Join(path, "/"))
+		}
+		if i >= 0 {
+			keepQuoting(p.Content[i+1], v)
+		}
+		setKey(p, tok, v)
+		return nil
+	}
+	idx, err := seqIndex(p, tok, !mustExist)
+	if err != nil {
+		return err
+	}
+	if mustExist {
+		p.Content[idx] = v
+		return nil
+	}
+	p.Content = append(p.Content[:idx], append([]*yaml.Node{v}, p.Content[idx:]...)...)
+	return nil
+}
+
+// take removes and returns the value at path.
+func (d *varsDoc) take(path []string) (*yaml.Node, error) {
+	if len(path) == 0 {
+		return nil, errors.New("cannot remove the whole document")
+	}
+	p, tok, err := d.parent(path)
+	if err != nil {
+		return nil, err
+	}
+	if p.Kind == yaml.MappingNode {
+		i := keyIndex(p, tok)
+		if i < 0 {
+			return nil, fmt.Errorf("/%s does not exist", strings.Join(path, "/"))
+		}
+		v := p.Content[i+1]
+		removeKey(p, tok)
+		return v, nil
+	}
+	idx, err := seqIndex(p, tok, false)
+	if err != nil {
+		return nil, err
+	}
+	v := p.Content[idx]
+	p.Content = append(p.Content[:idx], p.Content[idx+1:]...)
+	return v, nil
+}
+
+// seqIndex parses an array index token. When inserting, "-" and the array
+// length are allowed and refer to the end of the array.
+func seqIndex(n *yaml.Node, tok string, insert bool) (int, error) {
+	if tok == "-" && insert {
+		return len(n.Content), nil
+	}
+	i, err := strconv.Atoi(tok)
+	if err != nil || i < 0 || strconv.Itoa(i) != tok {
+		return 0, fmt.Errorf("%q is not an array index", tok)
+	}
+	limit := len(n.Content)
+	if !insert {
+		limit--
+	}
+	if i > limit {
+		return 0, fmt.Errorf("array index %d out of range", i)
+	}
+	return i, nil
+}
+
+// rawValueNode converts a JSON value to a YAML node.
+func rawValueNode(raw json.RawMessage) (*yaml.Node, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("%w: %v", errBadPatch, err)
+	}
+	return valueNode(v)
+}
+
+// nodeJSON converts a node to the value encoding/json would decode for the
+// same data, so that test operations compare by JSON semantics.
+func nodeJSON(n *yaml.Node) any {
+	switch n.Kind {
+	case yaml.MappingNode:
+		m := make(map[string]any, len(n.Content)/2)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			m[n.Content[i].Value] = nodeJSON(n.Content[i+1])
+		}
+		return m
+	case yaml.SequenceNode:
+		s := make([]any, 0, len(n.Content))
+		for _, c := range n.Content {
+			s = append(s, nodeJSON(c))
+		}
+		return s
+	case yaml.AliasNode:
+		return nodeJSON(n.Alias)
+	}
+	var v any
+	if err := n.Decode(&v); err != nil {
+		return n.Value
+	}
+	switch x := v.(type) {
+	case int:
+		return float64(x)
+	case int64:
+		return float64(x)
+	case uint64:
+		return float64(x)
+	}
+	return v
+}
+
+// copyNode returns a deep copy of n.
+func copyNode(n *yaml.Node) *yaml.Node {
+	c := *n
+	if n.Content != nil {
+		c.Content = make([]*yaml.Node, len(n.Content))
+		for i, child := range n.Content {
+			c.Content[i] = copyNode(child)
+		}
+	}
+	return &c
+}
@@ -0,0 +1,187 @@
+package onramp
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const patchSrc = `core:
+  data_iface: ens18   # uplink
+  upf:
+    additional_upfs:
+      "1":
+        ip:
+          access: "192.168.252.6/24"
+        ue_ip_pool: "172.248.0.0/16"
+      "2":
+        ue_ip_pool: "172.249.0.0/16"   # lab only
+srsran:
+  servers:
+    0:
+      gnb_ip: "10.76.28.115"
+k8s:
+  rke2:
+    config:
+      node_labels: [a, b]
+`
+
+func TestJSONPatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch string
+		want  string // replacements applied to patchSrc, old then new
+		err   error
+	}{
+		{
+			name:  "remove map entry",
+			patch: `[{"op": "remove", "path": "/core/upf/additional_upfs/2"}]`,
+			want:  "      \"2\":\n        ue_ip_pool: \"172.249.0.0/16\"   # lab only\n|",
+		},
+		{
+			name:  "replace keeps comment",
+			patch: `[{"op": "replace", "path": "/core/data_iface", "value": "ens19"}]`,
+			want:  "ens18   # uplink|ens19 # uplink",
+		},
+		{
+			name:  "add integer-keyed server",
+			patch: `[{"op": "add", "path": "/srsran/servers/1", "value": {"gnb_ip": "10.76.28.116"}}]`,
+			want:  "      gnb_ip: \"10.76.28.115\"\n|      gnb_ip: \"10.76.28.115\"\n    1:\n      gnb_ip: 10.76.28.116\n",
+		},
+		{
+			name:  "array insert and append",
+			patch: `[{"op": "add", "path": "/k8s/rke2/config/node_labels/0", "value": "z"}, {"op": "add", "path": "/k8s/rke2/config/node_labels/-", "value": "c"}]`,
+			want:  "[a, b]|[z, a, b, c]",
+		},
+		{
+			name: "move and test",
+			patch: `[{"op": "test", "path": "/core/upf/additional_upfs/2/ue_ip_pool", "value": "172.249.0.0/16"},
+				{"op": "move", "from": "/core/upf/additional_upfs/1/ip", "path": "/core/upf/additional_upfs/2/ip"},
+				{"op": "test", "path": "/srsran/servers/0", "value": {"gnb_ip": "10.76.28.115"}}]`,
+			want: "        ip:\n          access: \"192.168.252.6/24\"\n        ue_ip_pool: \"172.248.0.0/16\"\n|        ue_ip_pool: \"172.248.0.0/16\"\n" +
+				"|        ue_ip_pool: \"172.249.0.0/16\"   # lab only\n|        ue_ip_pool: \"172.249.0.0/16\"   # lab only\n        ip:\n          access: \"192.168.252.6/24\"\n",
+		},
+		{
+			name:  "pointer escapes",
+			patch: `[{"op": "add", "path": "/a~1b~0c", "value": 1}]`,
+			want:  "[a, b]\n|[a, b]\na/b~c: 1\n",
+		},
+		{name: "failed test", patch: `[{"op": "test", "path": "/core/data_iface", "value": "eth0"}]`, err: errPatchTest},
+		{name: "not an array", patch: `{"op": "remove"}`, err: errBadPatch},
+		{name: "unknown op", patch: `[{"op": "merge", "path": "/core"}]`, err: errBadPatch},
+		{name: "missing value", patch: `[{"op": "add", "path": "/core/x"}]`, err: errBadPatch},
+		{name: "remove missing", patch: `[{"op": "remove", "path": "/core/upf/additional_upfs/3"}]`},
+		{name: "replace missing", patch: `[{"op": "replace", "path": "/core/mode", "value": "x"}]`},
+		{name: "index out of range", patch: `[{"op": "add", "path": "/k8s/rke2/config/node_labels/5", "value": "x"}]`},
+		{name: "move into child", patch: `[{"op": "move", "from": "/core", "path": "/core/upf/core"}]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := parseVarsDoc([]byte(patchSrc))
+			if err != nil {
+				t.Fatalf("parseVarsDoc: %v", err)
+			}
+			err = d.jsonPatch([]byte(tt.patch))
+			if tt.want == "" {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				if tt.err != nil && !errors.Is(err, tt.err) {
+					t.Fatalf("err = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("jsonPatch: %v", err)
+			}
+			out, err := d.bytes()
+			if err != nil {
+				t.Fatalf("bytes: %v", err)
+			}
+			want := strings.NewReplacer(strings.Split(tt.want, "|")...).Replace(patchSrc)
+			if string(out) != want {
+				t.Errorf("patched =\n%s\nwant\n%s", out, want)
+			}
+		})
+	}
+}
+
+func TestHandlePatchConfig_ETag(t *testing.T) {
+	o := newTestProvider(t, testMainYML)
+	got, err := o.HandleGetConfig(t.Context(), nil)
+	if err != nil {
+		t.Fatalf("HandleGetConfig: %v", err)
+	}
+	etag := got.ETag
+	if etag != `"`+contentHash([]byte(testMainYML))+`"` {
+		t.Fatalf("ETag = %s", etag)
+	}
+
+	in := &ConfigPatchInput{
+		IfMatch:     etag,
+		ContentType: "application/json-patch+json; charset=utf-8",
+		RawBody:     []byte(`[{"op": "remove", "path": "/core/data_iface"}]`),
+	}
+	out, err := o.HandlePatchConfig(t.Context(), in)
+	if err != nil {
+		t.Fatalf("HandlePatchConfig: %v", err)
+	}
+	if out.Body.Core.DataIface != "" || out.ETag == etag {
+		t.Fatalf("patch output = %+v, ETag %s", out.Body.Core, out.ETag)
+	}
+
+	// The second tab still holds the old ETag.
+	_, err = o.HandlePatchConfig(t.Context(), &ConfigPatchInput{
+		IfMatch: etag,
+		RawBody: []byte(`{"core": {"data_iface": "ens20"}}`),
+	})
+	wantStatus(t, err, 412)
+
+	data, _ := os.ReadFile(filepath.Join(o.config.OnRampDir, "vars", "main.yml"))
+	if strings.Contains(string(data), "data_iface") || configETag(data) != out.ETag {
+		t.Errorf("main.yml = %q", data)
+	}
+
+	for i, match := range []string{"%s", `"x", %s`, "*"} {
+		cur, _ := o.HandleGetConfig(t.Context(), nil)
+		if strings.Contains(match, "%s") {
+			match = fmt.Sprintf(match, cur.ETag)
+		}
+		if _, err := o.HandlePatchConfig(t.Context(), &ConfigPatchInput{
+			IfMatch: match,
+			RawBody: []byte(fmt.Sprintf(`{"core": {"values_file": "v%d.yaml"}}`, i)),
+		}); err != nil {
+			t.Errorf("If-Match %s: %v", match, err)
+		}
+	}
+	_, err = o.HandlePatchConfig(t.Context(), &ConfigPatchInput{
+		IfMatch: "W/" + configETag(data),
+		RawBody: []byte(`{}`),
+	})
+	wantStatus(t, err, 412)
+}
+
+func TestHandlePatchConfig_Errors(t *testing.T) {
+	o := newTestProvider(t, testMainYML)
+	tests := []struct {
+		contentType, body string
+		status            int
+	}{
+		{mediaMergePatch, `[{"op": "remove", "path": "/core"}]`, 400},
+		{mediaJSONPatch, `{"core": null}`, 400},
+		{mediaJSONPatch, `[{"op": "test", "path": "/core/data_iface", "value": "eth0"}]`, 409},
+		{mediaJSONPatch, `[{"op": "remove", "path": "/gnbsim"}]`, 422},
+		{mediaJSONPatch, `[{"op": "replace", "path": "/k8s/rke2", "value": [1]}]`, 422},
+	}
+	for _, tt := range tests {
+		_, err := o.HandlePatchConfig(t.Context(), &ConfigPatchInput{ContentType: tt.contentType, RawBody: []byte(tt.body)})
+		wantStatus(t, err, tt.status)
+	}
+	data, _ := os.ReadFile(filepath.Join(o.config.OnRampDir, "vars", "main.yml"))
+	if string(data) != testMainYML {
+		t.Errorf("failed patches changed main.yml:\n%s", data)
+	}
+}
@@ -0,0 +1,181 @@
+package onramp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	internalssh "github.com/bengrewell/aether-webui/internal/ssh"
+	"github.com/bengrewell/aether-webui/internal/store"
+)
+
+// Nodes reached through jump hosts get a ProxyCommand appended to their
+// ansible_ssh_common_args in hosts.ini. It runs ssh with an ssh_config that
+// has a Host entry for each of the workspace's jump hosts, chained through
+// their own ProxyCommand, so OnRamp tasks take the same path as the daemon.
+//
+// The ssh_config, jump host keys and passwords are written to jumpDir in the
+// checkout. Keys cannot be vault encrypted, so like unvaulted node passwords
+// the files only exist while tasks run. Jump hosts with a password need
+// sshpass on the daemon host.
+
+// jumpDir is the directory in the checkout holding the jump host files.
+const jumpDir = ".aether-webd-jump"
+
+// jumpAliasPrefix starts the ssh_config Host alias of a jump host.
+const jumpAliasPrefix = "aether-jump-"
+
+// jumpProxyPrefix starts the ProxyCommand option inventory sync appends to
+// ansible_ssh_common_args.
+const jumpProxyPrefix = `-o ProxyCommand="`
+
+// jumpPaths returns the absolute jump host directory of the checkout at dir
+// and the ssh_config in it.
+func jumpPaths(dir string) (string, string) {
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	d := filepath.Join(dir, jumpDir)
+	return d, filepath.Join(d, "ssh_config")
+}
+
+// jumpCommand returns the command that connects to %h:%p through jump host
+// j, whose Host entry is in the ssh_config under dir.
+func jumpCommand(dir string, j store.JumpHost) string {
+	d, config := jumpPaths(dir)
+	cmd := fmt.Sprintf("ssh -F %s -W %%h:%%p %s%s", config, jumpAliasPrefix, j.Name)
+	if len(j.Password) > 0 {
+		cmd = fmt.Sprintf("sshpass -f %s %s", filepath.Join(d, j.Name+".pass"), cmd)
+	}
+	return cmd
+}
+
+// withJumpProxy appends to a node's ansible_ssh_common_args the
+// ProxyCommand that reaches it through the last hop of chain.
+func withJumpProxy(dir, args string, chain []store.JumpHost) string {
+	if len(chain) == 0 {
+		return args
+	}
+	proxy := jumpProxyPrefix + jumpCommand(dir, chain[len(chain)-1]) + `"`
+	if args == "" {
+		return proxy
+	}
+	return args + " " + proxy
+}
+
+// stripJumpProxy removes the ProxyCommand appended by withJumpProxy from
+// ansible_ssh_common_args, leaving the node's own.
+func stripJumpProxy(args string) string {
+	i := strings.LastIndex(args, jumpProxyPrefix)
+	if i < 0 || !strings.Contains(args[i:], string(filepath.Separator)+jumpDir+string(filepath.Separator)) {
+		return args
+	}
+	return strings.TrimSpace(args[:i])
+}
+
+// jumpProxies returns nodes with the ProxyCommand of their jump hosts
+// appended to their ansible_ssh_common_args.
+func (o *OnRamp) jumpProxies(ctx context.Context, ws *workspace, nodes []store.Node) ([]store.Node, error) {
+	out := make([]store.Node, len(nodes))
+	for i, n := range nodes {
+		chain, err := internalssh.JumpChain(ctx, o.Store(), ws.name, n.JumpHost)
+		if err != nil {
+			return nil, fmt.Errorf("node %s: %w", n.Name, err)
+		}
+		n.SSHCommonArgs = withJumpProxy(ws.config.OnRampDir, n.SSHCommonArgs, chain)
+		out[i] = n
+	}
+	return out, nil
+}
+
+// jumpSSHConfig renders the ssh_config with a Host entry for each jump host.
+func jumpSSHConfig(dir string, hosts []store.JumpHost) []byte {
+	d, _ := jumpPaths(dir)
+	byName := make(map[string]store.JumpHost, len(hosts))
+	for _, j := range hosts {
+		byName[j.Name] = j
+	}
+
+	var b strings.Builder
+	b.WriteString("# Jump hosts written by aether-webd; do not edit.\n")
+	for _, j := range hosts {
+		port := j.Port
+		if port == 0 {
+			port = 22
+		}
+		fmt.Fprintf(&b, "\nHost %s%s\n", jumpAliasPrefix, j.Name)
+		fmt.Fprintf(&b, "  HostName %s\n", j.Host)
+		fmt.Fprintf(&b, "  Port %s\n", strconv.Itoa(port))
+		if j.User != "" {
+			fmt.Fprintf(&b, "  User %s\n", j.User)
+		}
+		if len(j.SSHKey) > 0 {
+			fmt.Fprintf(&b, "  IdentityFile %s\n", filepath.Join(d, j.Name+".key"))
+			b.WriteString("  IdentitiesOnly yes\n")
+		}
+		b.WriteString("  StrictHostKeyChecking no\n")
+		b.WriteString("  UserKnownHostsFile /dev/null\n")
+		if via, ok := byName[j.Via]; ok {
+			fmt.Fprintf(&b, "  ProxyCommand %s\n", jumpCommand(dir, via))
+		}
+	}
+	return []byte(b.String())
+}
+
+// writeJumpFiles makes the jump host files in the checkout at dir match
+// hosts, removing them all when there are none.
+func writeJumpFiles(dir string, hosts []store.JumpHost) error {
+	d, config := jumpPaths(dir)
+	if len(hosts) == 0 {
+		if err := os.RemoveAll(d); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	if err := os.MkdirAll(d, 0o700); err != nil {
+		return err
+	}
+	files := map[string][]byte{config: jumpSSHConfig(dir, hosts)}
+	for _, j := range hosts {
+		if len(j.SSHKey) > 0 {
+			key := j.SSHKey
+			// ssh refuses a key file without a final newline.
+			if key[len(key)-1] != '\n' {
+				key = append(append([]byte(nil), key...), '\n')
+			}
+			files[filepath.Join(d, j.Name+".key")] = key
+		}
+		if len(j.Password) > 0 {
+			files[filepath.Join(d, j.Name+".pass")] = j.Password
+		}
+	}
+	for path, data := range files {
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			return err
+		}
+		// WriteFile keeps the mode of a file that already exists.
+		if err := os.Chmod(path, 0o600); err != nil {
+			return err
+		}
+	}
+
+	entries, err := os.ReadDir(d)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, e := range entries {
+		path := filepath.Join(d, e.Name())
+		if _, ok := files[path]; ok {
+			continue
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,106 @@
+package onramp
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bengrewell/aether-webui/internal/store"
+)
+
+func TestSyncInventory_JumpHosts(t *testing.T) {
+	o := newTestProviderWithStore(t, "")
+	ctx := t.Context()
+	for _, j := range []store.JumpHost{
+		{Name: "gw", Host: "203.0.113.1", User: "ops", SSHKey: []byte("KEY"), Default: true},
+		{Name: "lab", Host: "10.10.0.1", Port: 2222, User: "lab", Password: []byte("labpw"), Via: "gw"},
+	} {
+		if err := o.Store().UpsertJumpHost(ctx, j); err != nil {
+			t.Fatalf("UpsertJumpHost: %v", err)
+		}
+	}
+	addNode(t, o, store.Node{ID: "n1", Name: "node1", AnsibleHost: "10.0.0.1",
+		NodeVars: store.NodeVars{SSHCommonArgs: "-o ServerAliveInterval=30"}})
+	addNode(t, o, store.Node{ID: "n2", Name: "node2", AnsibleHost: "10.0.0.2",
+		NodeVars: store.NodeVars{JumpHost: "lab"}})
+	addNode(t, o, store.Node{ID: "n3", Name: "node3", AnsibleHost: "10.0.0.3",
+		NodeVars: store.NodeVars{JumpHost: store.JumpHostNone}})
+
+	if _, err := o.HandleSyncInventory(ctx, &InventorySyncInput{}); err != nil {
+		t.Fatalf("HandleSyncInventory: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(o.config.OnRampDir, "hosts.ini"))
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	d, config := jumpPaths(o.config.OnRampDir)
+	args := make(map[string]string)
+	for _, h := range ReadHostsINI(data) {
+		args[h.Name] = h.Vars["ansible_ssh_common_args"]
+	}
+	if want := `-o ServerAliveInterval=30 -o ProxyCommand="ssh -F ` + config + ` -W %h:%p aether-jump-gw"`; args["node1"] != want {
+		t.Errorf("node1 args = %q, want %q", args["node1"], want)
+	}
+	if want := `-o ProxyCommand="sshpass -f ` + filepath.Join(d, "lab.pass") + ` ssh -F ` + config + ` -W %h:%p aether-jump-lab"`; args["node2"] != want {
+		t.Errorf("node2 args = %q, want %q", args["node2"], want)
+	}
+	if args["node3"] != "" {
+		t.Errorf("node3 args = %q, want none", args["node3"])
+	}
+
+	// The ProxyCommand is not a node setting.
+	drift, err := o.HandleGetInventoryDrift(ctx, &WorkspaceInput{})
+	if err != nil {
+		t.Fatalf("drift: %v", err)
+	}
+	if !drift.Body.InSync {
+		t.Errorf("drift after sync = %+v", drift.Body)
+	}
+
+	// The jump host files only exist while tasks run.
+	if _, err := os.Stat(d); !os.IsNotExist(err) {
+		t.Errorf("jump files written outside a run: %v", err)
+	}
+	ws := o.defaultWorkspace()
+	o.acquireSecrets(ws)
+	cfg, err := os.ReadFile(config)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	for _, want := range []string{
+		"Host aether-jump-gw\n  HostName 203.0.113.1\n  Port 22\n  User ops\n  IdentityFile " + filepath.Join(d, "gw.key"),
+		"Host aether-jump-lab\n  HostName 10.10.0.1\n  Port 2222\n",
+		"  ProxyCommand ssh -F " + config + " -W %h:%p aether-jump-gw\n",
+	} {
+		if !strings.Contains(string(cfg), want) {
+			t.Errorf("ssh_config missing %q:\n%s", want, cfg)
+		}
+	}
+	for name, want := range map[string]string{"gw.key": "KEY\n", "lab.pass": "labpw"} {
+		path := filepath.Join(d, name)
+		got, err := os.ReadFile(path)
+		if err != nil || string(got) != want {
+			t.Errorf("%s = %q, %v; want %q", name, got, err, want)
+		}
+		if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != 0o600 {
+			t.Errorf("%s mode = %v, %v", name, fi.Mode(), err)
+		}
+	}
+	o.releaseSecrets(ws)
+	if _, err := os.Stat(d); !os.IsNotExist(err) {
+		t.Errorf("jump files left after the run: %v", err)
+	}
+}
+
+func TestSyncInventory_JumpHostLoop(t *testing.T) {
+	o := newTestProviderWithStore(t, "")
+	ctx := t.Context()
+	if err := o.Store().UpsertJumpHost(ctx, store.JumpHost{Name: "gw", Host: "203.0.113.1", Via: "gw"}); err != nil {
+		t.Fatalf("UpsertJumpHost: %v", err)
+	}
+	addNode(t, o, store.Node{ID: "n1", Name: "node1", AnsibleHost: "10.0.0.1", NodeVars: store.NodeVars{JumpHost: "gw"}})
+
+	_, err := o.HandleSyncInventory(ctx, &InventorySyncInput{})
+	wantStatus(t, err, 422)
+}
@@ -0,0 +1,185 @@
+package onramp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/google/uuid"
+
+	"github.com/bengrewell/aether-webui/internal/provider"
+	"github.com/bengrewell/aether-webui/internal/store"
+)
+
+// defaultMaintenanceTTL is used when a maintenance lock request omits ttl_seconds.
+const defaultMaintenanceTTL = time.Hour
+
+// acquireDeploymentLock takes a workspace's change lock on behalf of a
+// deployment. Deployment locks do not expire; they are released when the
+// deployment reaches a terminal state or recovered on startup.
+func (o *OnRamp) acquireDeploymentLock(ctx context.Context, workspace, deployID string) error {
+	st := o.Store()
+	if st.Path() == "" {
+		return nil
+	}
+	cur, err := st.AcquireLock(ctx, store.Lock{
+		Name:     store.ChangeLockFor(workspace),
+		HolderID: deployID,
+		Kind:     store.LockKindDeployment,
+		Owner:    "deployment " + deployID,
+	})
+	if errors.Is(err, store.ErrLocked) {
+		return provider.ChangeLockError(cur)
+	}
+	if err != nil {
+		return huma.Error500InternalServerError("failed to acquire change lock", err)
+	}
+	return nil
+}
+
+// releaseDeploymentLock releases the change lock held by a deployment. A lock
+// that was already broken or released is not an error.
+func (o *OnRamp) releaseDeploymentLock(workspace, deployID string) {
+	st := o.Store()
+	if st.Path() == "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err := st.ReleaseLock(ctx, store.ChangeLockFor(workspace), deployID)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		o.Log().Error("failed to release deployment lock", "deployment_id", deployID, "error", err)
+	}
+}
+
+// HandleGetLock reports whether a workspace's change lock is held and by whom.
+func (o *OnRamp) HandleGetLock(ctx context.Context, in *WorkspaceInput) (*LockGetOutput, error) {
+	st, err := o.requireStore("change locks are")
+	if err != nil {
+		return nil, err
+	}
+	ws, err := o.workspace(ctx, in.workspaceName())
+	if err != nil {
+		return nil, err
+	}
+	l, ok, err := st.GetLock(ctx, store.ChangeLockFor(ws.name))
+	if err != nil {
+		return nil, huma.Error500InternalServerError("failed to read change lock", err)
+	}
+	out := &LockGetOutput{}
+	out.Body.Locked = ok
+	if ok {
+		info := toLockInfo(l)
+		out.Body.Lock = &info
+	}
+	return out, nil
+}
+
+// HandleAcquireLock takes a maintenance lock. The returned lock_id must be
+// presented to release it.
+func (o *OnRamp) HandleAcquireLock(ctx context.Context, in *LockAcquireInput) (*LockAcquireOutput, error) {
+	st, err := o.requireStore("change locks are")
+	if err != nil {
+		return nil, err
+	}
+	ws, err := o.workspace(ctx, in.Workspace)
+	if err != nil {
+		return nil, err
+	}
+	ttl := defaultMaintenanceTTL
+	if in.Body.TTLSeconds > 0 {
+		ttl = time.Duration(in.Body.TTLSeconds) * time.Second
+	}
+	now := time.Now().UTC()
+	l, err := st.AcquireLock(ctx, store.Lock{
+		Name:       store.ChangeLockFor(ws.name),
+		HolderID:   uuid.NewString(),
+		Kind:       store.LockKindMaintenance,
+		Owner:      in.Body.Owner,
+		Reason:     in.Body.Reason,
+		AcquiredAt: now,
+		ExpiresAt:  now.Add(ttl),
+	})
+	if errors.Is(err, store.ErrLocked) {
+		return nil, provider.ChangeLockError(l)
+	}
+	if err != nil {
+		return nil, huma.Error500InternalServerError("failed to acquire change lock", err)
+	}
+	o.Log().Info("maintenance lock acquired", "workspace", ws.name, "owner", l.Owner, "reason", l.Reason, "expires_at", l.ExpiresAt)
+
+	out := &LockAcquireOutput{}
+	out.Body = toLockInfo(l)
+	out.Body.LockID = l.HolderID
+	return out, nil
+}
+
+// HandleReleaseLock releases a maintenance lock using the lock_id returned
+// when it was acquired.
+func (o *OnRamp) HandleReleaseLock(ctx context.Context, in *LockReleaseInput) (*LockReleaseOutput, error) {
+	st, err := o.requireStore("change locks are")
+	if err != nil {
+		return nil, err
+	}
+	ws, err := o.workspace(ctx, in.Workspace)
+	if err != nil {
+		return nil, err
+	}
+	if err := st.ReleaseLock(ctx, store.ChangeLockFor(ws.name), in.LockID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, huma.Error404NotFound("lock not held",
+				fmt.Errorf("no change lock held with id %s", in.LockID))
+		}
+		return nil, huma.Error500InternalServerError("failed to release change lock", err)
+	}
+	out := &LockReleaseOutput{}
+	out.Body.Message = "change lock released"
+	return out, nil
+}
+
+// HandleBreakLock removes the change lock regardless of holder. Breaking a
+// deployment's lock does not stop the deployment.
+func (o *OnRamp) HandleBreakLock(ctx context.Context, in *LockBreakInput) (*LockBreakOutput, error) {
+	st, err := o.requireStore("change locks are")
+	if err != nil {
+		return nil, err
+	}
+	ws, err := o.workspace(ctx, in.Workspace)
+	if err != nil {
+		return nil, err
+	}
+	l, ok, err := st.BreakLock(ctx, store.ChangeLockFor(ws.name))
+	if err != nil {
+		return nil, huma.Error500InternalServerError("failed to break change lock", err)
+	}
+	out := &LockBreakOutput{}
+	if !ok {
+		out.Body.Message = "change lock was not held"
+		return out, nil
+	}
+	o.Log().Warn("change lock broken", "workspace", ws.name, "kind", l.Kind, "owner", l.Owner, "reason", in.Body.Reason)
+	info := toLockInfo(l)
+	out.Body.Broken = &info
+	out.Body.Message = fmt.Sprintf("change lock held by %s broken", l.Owner)
+	return out, nil
+}
+
+// toLockInfo converts a store.Lock to its API representation. The holder
+// token is omitted; only the acquire response carries it.
+func toLockInfo(l store.Lock) LockInfo {
+	info := LockInfo{
+		Kind:       l.Kind,
+		Owner:      l.Owner,
+		Reason:     l.Reason,
+		AcquiredAt: l.AcquiredAt.Unix(),
+	}
+	if !l.ExpiresAt.IsZero() {
+		info.ExpiresAt = l.ExpiresAt.Unix()
+	}
+	if l.Kind == store.LockKindDeployment {
+		info.DeploymentID = l.HolderID
+	}
+	return info
+}
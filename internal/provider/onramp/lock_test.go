@@ -0,0 +1,190 @@
+package onramp
+
+import (
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"github.com/bengrewell/aether-webui/internal/store"
+)
+
+// wantStatus fails the test unless err is a huma error with the given status.
+func wantStatus(t *testing.T, err error, status int) {
+	t.Helper()
+	var se huma.StatusError
+	if !errors.As(err, &se) || se.GetStatus() != status {
+		t.Fatalf("err = %v, want status %d", err, status)
+	}
+}
+
+func acquireMaintenance(t *testing.T, o *OnRamp) string {
+	t.Helper()
+	in := &LockAcquireInput{}
+	in.Body.Owner = "alice"
+	in.Body.Reason = "kernel upgrade"
+	out, err := o.HandleAcquireLock(t.Context(), in)
+	if err != nil {
+		t.Fatalf("HandleAcquireLock: %v", err)
+	}
+	if out.Body.LockID == "" {
+		t.Fatal("expected lock_id in acquire response")
+	}
+	return out.Body.LockID
+}
+
+func TestHandleAcquireLock_BlocksMutations(t *testing.T) {
+	o := newTestProviderWithStore(t, testMainYML)
+	lockID := acquireMaintenance(t, o)
+
+	_, err := o.HandlePatchConfig(t.Context(), &ConfigPatchInput{
+		RawBody: []byte(`{"core": {"data_iface": "ens20"}}`),
+	})
+	wantStatus(t, err, 423)
+
+	_, err = o.HandleExecuteAction(t.Context(), &ExecuteActionInput{Component: "k8s", Action: "install"})
+	wantStatus(t, err, 423)
+
+	dep := &DeployInput{}
+	dep.Body.Actions = []ComponentActionPair{{Component: "k8s", Action: "install"}}
+	_, err = o.HandleDeploy(t.Context(), dep)
+	wantStatus(t, err, 423)
+
+	// A second maintenance lock is refused while the first is held.
+	in := &LockAcquireInput{}
+	in.Body.Owner = "bob"
+	_, err = o.HandleAcquireLock(t.Context(), in)
+	wantStatus(t, err, 423)
+
+	// Reads are unaffected.
+	if _, err := o.HandleGetConfig(t.Context(), nil); err != nil {
+		t.Fatalf("HandleGetConfig: %v", err)
+	}
+
+	if _, err := o.HandleReleaseLock(t.Context(), &LockReleaseInput{LockID: lockID}); err != nil {
+		t.Fatalf("HandleReleaseLock: %v", err)
+	}
+	if _, err := o.HandlePatchConfig(t.Context(), &ConfigPatchInput{
+		RawBody: []byte(`{"core": {"data_iface": "ens20"}}`),
+	}); err != nil {
+		t.Fatalf("HandlePatchConfig after release: %v", err)
+	}
+}
+
+func TestHandleGetLock(t *testing.T) {
+	o := newTestProviderWithStore(t, "")
+
+	out, err := o.HandleGetLock(t.Context(), nil)
+	if err != nil {
+		t.Fatalf("HandleGetLock: %v", err)
+	}
+	if out.Body.Locked || out.Body.Lock != nil {
+		t.Fatalf("expected unlocked, got %+v", out.Body)
+	}
+
+	acquireMaintenance(t, o)
+	out, err = o.HandleGetLock(t.Context(), nil)
+	if err != nil {
+		t.Fatalf("HandleGetLock: %v", err)
+	}
+	if !out.Body.Locked || out.Body.Lock == nil {
+		t.Fatal("expected locked")
+	}
+	l := out.Body.Lock
+	if l.Kind != store.LockKindMaintenance || l.Owner != "alice" || l.Reason != "kernel upgrade" {
+		t.Errorf("lock = %+v", l)
+	}
+	if l.LockID != "" {
+		t.Error("lock_id must not be exposed by get")
+	}
+	if l.ExpiresAt <= l.AcquiredAt {
+		t.Errorf("expires_at %d should be after acquired_at %d", l.ExpiresAt, l.AcquiredAt)
+	}
+}
+
+func TestHandleReleaseLock_WrongID(t *testing.T) {
+	o := newTestProviderWithStore(t, "")
+	acquireMaintenance(t, o)
+
+	_, err := o.HandleReleaseLock(t.Context(), &LockReleaseInput{LockID: "nope"})
+	wantStatus(t, err, 404)
+
+	if err := o.CheckChangeLock(t.Context(), ""); err == nil {
+		t.Fatal("lock should still be held")
+	}
+}
+
+func TestHandleBreakLock(t *testing.T) {
+	o := newTestProviderWithStore(t, "")
+
+	out, err := o.HandleBreakLock(t.Context(), &LockBreakInput{})
+	if err != nil {
+		t.Fatalf("HandleBreakLock: %v", err)
+	}
+	if out.Body.Broken != nil {
+		t.Error("expected nothing broken when unlocked")
+	}
+
+	if err := o.acquireDeploymentLock(t.Context(), "", "dep-1"); err != nil {
+		t.Fatalf("acquireDeploymentLock: %v", err)
+	}
+	in := &LockBreakInput{}
+	in.Body.Reason = "stuck"
+	out, err = o.HandleBreakLock(t.Context(), in)
+	if err != nil {
+		t.Fatalf("HandleBreakLock: %v", err)
+	}
+	if out.Body.Broken == nil || out.Body.Broken.DeploymentID != "dep-1" {
+		t.Fatalf("broken = %+v, want deployment dep-1", out.Body.Broken)
+	}
+	if err := o.CheckChangeLock(t.Context(), ""); err != nil {
+		t.Fatalf("CheckChangeLock after break: %v", err)
+	}
+}
+
+func TestDeploymentLock_Exclusive(t *testing.T) {
+	o := newTestProviderWithStore(t, "")
+
+	if err := o.acquireDeploymentLock(t.Context(), "", "dep-1"); err != nil {
+		t.Fatalf("acquireDeploymentLock: %v", err)
+	}
+	wantStatus(t, o.acquireDeploymentLock(t.Context(), "", "dep-2"), 423)
+
+	o.releaseDeploymentLock("", "dep-1")
+	if err := o.acquireDeploymentLock(t.Context(), "", "dep-2"); err != nil {
+		t.Fatalf("acquireDeploymentLock after release: %v", err)
+	}
+	// Releasing a lock that is no longer held is harmless.
+	o.releaseDeploymentLock("", "dep-1")
+	wantStatus(t, o.CheckChangeLock(t.Context(), ""), 423)
+}
+
+func TestLockHandlers_NoStore(t *testing.T) {
+	o := newTestProvider(t, "")
+
+	_, err := o.HandleGetLock(t.Context(), nil)
+	wantStatus(t, err, 503)
+	if err := o.CheckChangeLock(t.Context(), ""); err != nil {
+		t.Errorf("CheckChangeLock without store = %v, want nil", err)
+	}
+}
+
+func TestRecoverStaleTasks_ReleasesDeploymentLock(t *testing.T) {
+	o := newTestProviderWithStore(t, "")
+	st := o.Store()
+
+	if err := o.acquireDeploymentLock(t.Context(), "", "dep-1"); err != nil {
+		t.Fatalf("acquireDeploymentLock: %v", err)
+	}
+	recoverStaleTasks(st, slog.Default())
+	if _, ok, _ := st.GetLock(t.Context(), store.ChangeLockName); ok {
+		t.Error("deployment lock should be released on recovery")
+	}
+
+	acquireMaintenance(t, o)
+	recoverStaleTasks(st, slog.Default())
+	if _, ok, _ := st.GetLock(t.Context(), store.ChangeLockName); !ok {
+		t.Error("maintenance lock should survive recovery")
+	}
+}
@@ -2,9 +2,11 @@ package onramp
 
 import (
 	"fmt"
+	"sync"
 
 	"github.com/bengrewell/aether-webui/internal/endpoint"
 	"github.com/bengrewell/aether-webui/internal/provider"
+	"github.com/bengrewell/aether-webui/internal/store"
 	"github.com/bengrewell/aether-webui/internal/taskrunner"
 )
 
@@ -12,35 +14,66 @@ var _ provider.Provider = (*OnRamp)(nil)
 
 // Config holds the settings for the OnRamp provider.
 type Config struct {
-	OnRampDir string // path to aether-onramp on disk
-	RepoURL   string // git clone URL
-	Version   string // tag, branch, or commit to pin
+	OnRampDir     string // path to aether-onramp on disk
+	RepoURL       string // git clone URL
+	Version       string // tag, branch, or commit to pin
+	WorkspacesDir string // parent of per-workspace checkouts; default: {OnRampDir}/../workspaces
+
+	// Offline import: when BundlePath is set, the archive is verified and
+	// unpacked into the default workspace at start instead of cloning.
+	BundlePath      string // tarball of aether-onramp
+	BundleSHA256    string // expected SHA-256 of BundlePath, hex encoded
+	BundleSignature string // base64 ed25519 signature of the archive's SHA-256 digest
+	TrustedKeysFile string // PEM ed25519 public keys accepted for bundle signatures
+
+	// ComponentsFile is an optional overlay merged over the built-in
+	// components.yaml to describe components the Makefile adds.
+	ComponentsFile string
+
+	// Custom component actions of kind playbook and script run files from
+	// these directories; each kind is unavailable while its directory is
+	// unset.
+	PlaybookDir string
+	ScriptDir   string
+
+	// VaultPasswordFile, when set, makes inventory sync keep node passwords
+	// ansible-vault encrypted in host_vars; otherwise they are written in
+	// the clear for each task run and removed when it ends.
+	VaultPasswordFile string
 }
 
 // OnRamp is a provider that wraps the Aether OnRamp Make/Ansible toolchain.
+// config and runner belong to the default workspace; other workspaces are
+// loaded from the store on first use.
 type OnRamp struct {
 	*provider.Base
 	config    Config
 	endpoints []endpoint.AnyEndpoint
 	runner    *taskrunner.Runner
+
+	mu         sync.Mutex
+	workspaces map[string]*workspace
 }
 
 // NewProvider creates a new OnRamp provider with all endpoints registered.
 func NewProvider(cfg Config, opts ...provider.Option) *OnRamp {
 	base := provider.New("onramp", opts...)
 	o := &OnRamp{
-		Base:      base,
-		config:    cfg,
-		endpoints: make([]endpoint.AnyEndpoint, 0, 23),
-		runner: taskrunner.New(taskrunner.RunnerConfig{
-			MaxConcurrent: 1,
-			Logger:        base.Log(),
-		}),
+		Base:       base,
+		config:     cfg,
+		endpoints:  make([]endpoint.AnyEndpoint, 0, 70),
+		runner:     newRunner(base),
+		workspaces: make(map[string]*workspace),
+	}
+	o.workspaces[store.DefaultWorkspace] = &workspace{
+		name:   store.DefaultWorkspace,
+		config: cfg,
+		runner: o.runner,
 	}
 
 	// --- Repo ---
 
-	provider.Register(o.Base, endpoint.Endpoint[struct{}, RepoStatusOutput]{
+	provider.Register(o.Base, endpoint.Endpoint[WorkspaceInput, RepoStatusOutput]{
 		Desc: endpoint.Descriptor{
 			OperationID: "onramp-get-repo-status",
 			Semantics:   endpoint.Read,
@@ -52,7 +85,7 @@ func NewProvider(cfg Config, opts ...provider.Option) *OnRamp {
 		Handler: o.HandleGetRepoStatus,
 	})
 
-	provider.Register(o.Base, endpoint.Endpoint[struct{}, RepoRefreshOutput]{
+	provider.Register(o.Base, endpoint.Endpoint[WorkspaceInput, RepoRefreshOutput]{
 		Desc: endpoint.Descriptor{
 			OperationID: "onramp-refresh-repo",
 			Semantics:   endpoint.Action,
@@ -64,14 +97,86 @@ func NewProvider(cfg Config, opts ...provider.Option) *OnRamp {
 		Handler: o.HandleRefreshRepo,
 	})
 
+	provider.Register(o.Base, endpoint.Endpoint[WorkspaceInput, RepoVersionsOutput]{
+		Desc: endpoint.Descriptor{
+			OperationID: "onramp-list-repo-versions",
+			Semantics:   endpoint.Read,
+			Summary:     "List OnRamp versions",
+			Description: "Returns the pinned version and the tags and branches available in the local clone. Tags are ordered newest first.",
+			Tags:        []string{"onramp"},
+			HTTP:        endpoint.HTTPHint{Path: "/api/v1/onramp/repo/versions"},
+		},
+		Handler: o.HandleListRepoVersions,
+	})
+
+	provider.Register(o.Base, endpoint.Endpoint[RepoVersionSetInput, RepoVersionSetOutput]{
+		Desc: endpoint.Descriptor{
+			OperationID: "onramp-set-repo-version",
+			Semantics:   endpoint.Update,
+			Summary:     "Switch OnRamp version",
+			Description: "Checks out a tag, branch, or commit and persists it as the pinned version. Refused while components are installed unless force is set, and while the working tree has local changes or tasks are queued.",
+			Tags:        []string{"onramp"},
+			HTTP:        endpoint.HTTPHint{Path: "/api/v1/onramp/repo/version"},
+		},
+		Handler: o.HandleSetRepoVersion,
+	})
+
+	provider.Register(o.Base, endpoint.Endpoint[WorkspaceInput, RepoDiffOutput]{
+		Desc: endpoint.Descriptor{
+			OperationID: "onramp-get-repo-diff",
+			Semantics:   endpoint.Read,
+			Summary:     "Get OnRamp local changes",
+			Description: "Lists modified and untracked files and returns the diff of tracked files against HEAD, capped at 1 MiB.",
+			Tags:        []string{"onramp"},
+			HTTP:        endpoint.HTTPHint{Path: "/api/v1/onramp/repo/diff"},
+		},
+		Handler: o.HandleGetRepoDiff,
+	})
+
+	provider.Register(o.Base, endpoint.Endpoint[RepoStashInput, RepoLocalChangesOutput]{
+		Desc: endpoint.Descriptor{
+			OperationID: "onramp-stash-repo",
+			Semantics:   endpoint.Action,
+			Summary:     "Stash OnRamp local changes",
+			Description: "Saves local modifications, including untracked files, with git stash and restores a clean working tree.",
+			Tags:        []string{"onramp"},
+			HTTP:        endpoint.HTTPHint{Path: "/api/v1/onramp/repo/stash"},
+		},
+		Handler: o.HandleStashRepo,
+	})
+
+	provider.Register(o.Base, endpoint.Endpoint[RepoResetInput, RepoLocalChangesOutput]{
+		Desc: endpoint.Descriptor{
+			OperationID: "onramp-reset-repo",
+			Semantics:   endpoint.Action,
+			Summary:     "Discard OnRamp local changes",
+			Description: "Resets tracked files to HEAD. With clean set, untracked files are deleted as well; ignored files are kept.",
+			Tags:        []string{"onramp"},
+			HTTP:        endpoint.HTTPHint{Path: "/api/v1/onramp/repo/reset"},
+		},
+		Handler: o.HandleResetRepo,
+	})
+
+	provider.Register(o.Base, endpoint.Endpoint[RepoBundleImportInput, RepoBundleImportOutput]{
+		Desc: endpoint.Descriptor{
+			OperationID: "onramp-import-repo-bundle",
+			Semantics:   endpoint.Action,
+			Summary:     "Import offline OnRamp bundle",
+			Description: "Replaces the checkout with a tarball streamed as the application/octet-stream request body, for sites without internet access. The archive must match the sha256 checksum or a signature from a trusted key. The imported commit becomes the pinned version and is never fetched from the remote.",
+			Tags:        []string{"onramp"},
+			HTTP:        endpoint.HTTPHint{Path: "/api/v1/onramp/repo/bundle"},
+		},
+		Handler: o.HandleImportRepoBundle,
+	})
+
 	// --- Components ---
 
-	provider.Register(o.Base, endpoint.Endpoint[struct{}, ComponentListOutput]{
+	provider.Register(o.Base, endpoint.Endpoint[WorkspaceInput, ComponentListOutput]{
 		Desc: endpoint.Descriptor{
 			OperationID: "onramp-list-components",
 			Semantics:   endpoint.Read,
 			Summary:     "List OnRamp components",
-			Description: "Returns all available OnRamp components and their actions.",
+			Description: "Returns the components and actions of the workspace's OnRamp checkout, built from the Makefile's aether-* targets and the component overlay. The list is rebuilt when the repo version or the Makefile changes.",
 			Tags:        []string{"onramp"},
 			HTTP:        endpoint.HTTPHint{Path: "/api/v1/onramp/components"},
 		},
@@ -95,16 +200,140 @@ func NewProvider(cfg Config, opts ...provider.Option) *OnRamp {
 			OperationID: "onramp-execute-action",
 			Semantics:   endpoint.Action,
 			Summary:     "Execute component action",
-			Description: "Runs a make target for the specified component and action.",
+			Description: "Runs the make target, or for custom components the playbook or script, of the specified component and action.",
 			Tags:        []string{"onramp"},
 			HTTP:        endpoint.HTTPHint{Path: "/api/v1/onramp/components/{component}/{action}"},
 		},
 		Handler: o.HandleExecuteAction,
 	})
 
+	// --- Custom components ---
+
+	provider.Register(o.Base, endpoint.Endpoint[WorkspaceInput, ComponentListOutput]{
+		Desc: endpoint.Descriptor{
+			OperationID: "onramp-list-custom-components",
+			Semantics:   endpoint.Read,
+			Summary:     "List custom components",
+			Description: "Returns the user-defined components of a workspace. They are also listed with the OnRamp components and run, deploy and track state the same way.",
+			Tags:        []string{"onramp"},
+			HTTP:        endpoint.HTTPHint{Path: "/api/v1/onramp/custom-components"},
+		},
+		Handler: o.HandleListCustomComponents,
+	})
+
+	provider.Register(o.Base, endpoint.Endpoint[CustomComponentGetInput, CustomComponentOutput]{
+		Desc: endpoint.Descriptor{
+			OperationID: "onramp-get-custom-component",
+			Semantics:   endpoint.Read,
+			Summary:     "Get custom component",
+			Description: "Returns a single user-defined component by name.",
+			Tags:        []string{"onramp"},
+			HTTP:        endpoint.HTTPHint{Path: "/api/v1/onramp/custom-components/{name}"},
+		},
+		Handler: o.HandleGetCustomComponent,
+	})
+
+	provider.Register(o.Base, endpoint.Endpoint[CustomComponentCreateInput, CustomComponentOutput]{
+		Desc: endpoint.Descriptor{
+			OperationID: "onramp-create-custom-component",
+			Semantics:   endpoint.Create,
+			Summary:     "Create custom component",
+			Description: "Registers a component whose actions run a make target, a playbook from the playbook directory, or a script from the script directory. The name must not be taken by an OnRamp component.",
+			Tags:        []string{"onramp"},
+			HTTP:        endpoint.HTTPHint{Path: "/api/v1/onramp/custom-components"},
+		},
+		Handler: o.HandleCreateCustomComponent,
+	})
+
+	provider.Register(o.Base, endpoint.Endpoint[CustomComponentUpdateInput, CustomComponentOutput]{
+		Desc: endpoint.Descriptor{
+			OperationID: "onramp-update-custom-component",
+			Semantics:   endpoint.Update,
+			Summary:     "Update custom component",
+			Description: "Replaces the definition of a user-defined component.",
+			Tags:        []string{"onramp"},
+			HTTP:        endpoint.HTTPHint{Path: "/api/v1/onramp/custom-components/{name}"},
+		},
+		Handler: o.HandleUpdateCustomComponent,
+	})
+
+	provider.Register(o.Base, endpoint.Endpoint[CustomComponentDeleteInput, CustomComponentDeleteOutput]{
+		Desc: endpoint.Descriptor{
+			OperationID: "onramp-delete-custom-component",
+			Semantics:   endpoint.Delete,
+			Summary:     "Delete custom component",
+			Description: "Removes a user-defined component. Its action history and state records are kept.",
+			Tags:        []string{"onramp"},
+			HTTP:        endpoint.HTTPHint{Path: "/api/v1/onramp/custom-components/{name}"},
+		},
+		Handler: o.HandleDeleteCustomComponent,
+	})
+
+	// --- Action hooks ---
+
+	provider.Register(o.Base, endpoint.Endpoint[HookListInput, HookListOutput]{
+		Desc: endpoint.Descriptor{
+			OperationID: "onramp-list-hooks",
+			Semantics:   endpoint.Read,
+			Summary:     "List action hooks",
+			Description: "Returns the workspace's action hooks in run order, optionally only those of a component or that run for an action.",
+			Tags:        []string{"onramp"},
+			HTTP:        endpoint.HTTPHint{Path: "/api/v1/onramp/hooks"},
+		},
+		Handler: o.HandleListHooks,
+	})
+
+	provider.Register(o.Base, endpoint.Endpoint[HookGetInput, HookOutput]{
+		Desc: endpoint.Descriptor{
+			OperationID: "onramp-get-hook",
+			Semantics:   endpoint.Read,
+			Summary:     "Get action hook",
+			Description: "Returns a single action hook by ID.",
+			Tags:        []string{"onramp"},
+			HTTP:        endpoint.HTTPHint{Path: "/api/v1/onramp/hooks/{id}"},
+		},
+		Handler: o.HandleGetHook,
+	})
+
+	provider.Register(o.Base, endpoint.Endpoint[HookCreateInput, HookOutput]{
+		Desc: endpoint.Descriptor{
+			OperationID: "onramp-create-hook",
+			Semantics:   endpoint.Create,
+			Summary:     "Create action hook",
+			Description: "Adds a make target, playbook or script that runs as its own task before, after, or on failure of a component action. A failing before hook aborts the action.",
+			Tags:        []string{"onramp"},
+			HTTP:        endpoint.HTTPHint{Path: "/api/v1/onramp/hooks"},
+		},
+		Handler: o.HandleCreateHook,
+	})
+
+	provider.Register(o.Base, endpoint.Endpoint[HookUpdateInput, HookOutput]{
+		Desc: endpoint.Descriptor{
+			OperationID: "onramp-update-hook",
+			Semantics:   endpoint.Update,
+			Summary:     "Update action hook",
+			Description: "Replaces the definition of an action hook.",
+			Tags:        []string{"onramp"},
+			HTTP:        endpoint.HTTPHint{Path: "/api/v1/onramp/hooks/{id}"},
+		},
+		Handler: o.HandleUpdateHook,
+	})
+
+	provider.Register(o.Base, endpoint.Endpoint[HookDeleteInput, HookDeleteOutput]{
+		Desc: endpoint.Descriptor{
+			OperationID: "onramp-delete-hook",
+			Semantics:   endpoint.Delete,
+			Summary:     "Delete action hook",
+			Description: "Removes an action hook. Its recorded runs are kept.",
+			Tags:        []string{"onramp"},
+			HTTP:        endpoint.HTTPHint{Path: "/api/v1/onramp/hooks/{id}"},
+		},
+		Handler: o.HandleDeleteHook,
+	})
+
 	// --- Tasks ---
 
-	provider.Register(o.Base, endpoint.Endpoint[struct{}, TaskListOutput]{
+	provider.Register(o.Base, endpoint.Endpoint[WorkspaceInput, TaskListOutput]{
 		Desc: endpoint.Descriptor{
 			OperationID: "onramp-list-tasks",
 			Semantics:   endpoint.Read,
@@ -128,6 +357,92 @@ func NewProvider(cfg Config, opts ...provider.Option) *OnRamp {
 		Handler: o.HandleGetTask,
 	})
 
+	// --- Queue ---
+
+	provider.Register(o.Base, endpoint.Endpoint[WorkspaceInput, QueueGetOutput]{
+		Desc: endpoint.Descriptor{
+			OperationID: "onramp-get-queue",
+			Semantics:   endpoint.Read,
+			Summary:     "Get task queue",
+			Description: "Returns pending tasks in start order with queue positions, estimated start times, and whether the queue is paused.",
+			Tags:        []string{"onramp"},
+			HTTP:        endpoint.HTTPHint{Path: "/api/v1/onramp/queue"},
+		},
+		Handler: o.HandleGetQueue,
+	})
+
+	provider.Register(o.Base, endpoint.Endpoint[QueueMoveInput, QueueUpdateOutput]{
+		Desc: endpoint.Descriptor{
+			OperationID: "onramp-move-queue-task",
+			Semantics:   endpoint.Action,
+			Summary:     "Move queued task",
+			Description: "Moves a pending task to a new 1-based position in the queue.",
+			Tags:        []string{"onramp"},
+			HTTP:        endpoint.HTTPHint{Path: "/api/v1/onramp/queue/{id}/move"},
+		},
+		Handler: o.HandleMoveQueueTask,
+	})
+
+	provider.Register(o.Base, endpoint.Endpoint[QueueTaskInput, QueueUpdateOutput]{
+		Desc: endpoint.Descriptor{
+			OperationID: "onramp-prioritize-queue-task",
+			Semantics:   endpoint.Action,
+			Summary:     "Prioritize queued task",
+			Description: "Moves a pending task to the front of the queue so it starts next.",
+			Tags:        []string{"onramp"},
+			HTTP:        endpoint.HTTPHint{Path: "/api/v1/onramp/queue/{id}/prioritize"},
+		},
+		Handler: o.HandlePrioritizeQueueTask,
+	})
+
+	provider.Register(o.Base, endpoint.Endpoint[QueueTaskInput, QueueRemoveOutput]{
+		Desc: endpoint.Descriptor{
+			OperationID: "onramp-remove-queue-task",
+			Semantics:   endpoint.Delete,
+			Summary:     "Remove queued task",
+			Description: "Removes a pending task from the queue and marks its action as canceled. Running tasks cannot be removed.",
+			Tags:        []string{"onramp"},
+			HTTP:        endpoint.HTTPHint{Path: "/api/v1/onramp/queue/{id}"},
+		},
+		Handler: o.HandleRemoveQueueTask,
+	})
+
+	provider.Register(o.Base, endpoint.Endpoint[WorkspaceInput, QueueUpdateOutput]{
+		Desc: endpoint.Descriptor{
+			OperationID: "onramp-pause-queue",
+			Semantics:   endpoint.Action,
+			Summary:     "Pause task queue",
+			Description: "Stops queued tasks from starting. The running task, if any, is not interrupted.",
+			Tags:        []string{"onramp"},
+			HTTP:        endpoint.HTTPHint{Path: "/api/v1/onramp/queue/pause"},
+		},
+		Handler: o.HandlePauseQueue,
+	})
+
+	provider.Register(o.Base, endpoint.Endpoint[WorkspaceInput, QueueUpdateOutput]{
+		Desc: endpoint.Descriptor{
+			OperationID: "onramp-resume-queue",
+			Semantics:   endpoint.Action,
+			Summary:     "Resume task queue",
+			Description: "Resumes a paused queue and starts the next pending task if capacity allows.",
+			Tags:        []string{"onramp"},
+			HTTP:        endpoint.HTTPHint{Path: "/api/v1/onramp/queue/resume"},
+		},
+		Handler: o.HandleResumeQueue,
+	})
+
+	provider.Register(o.Base, endpoint.Endpoint[WorkspaceInput, QueueDrainOutput]{
+		Desc: endpoint.Descriptor{
+			OperationID: "onramp-drain-queue",
+			Semantics:   endpoint.Action,
+			Summary:     "Drain task queue",
+			Description: "Cancels every pending task. The running task, if any, is not interrupted.",
+			Tags:        []string{"onramp"},
+			HTTP:        endpoint.HTTPHint{Path: "/api/v1/onramp/queue/drain"},
+		},
+		Handler: o.HandleDrainQueue,
+	})
+
 	// --- Actions ---
 
 	provider.Register(o.Base, endpoint.Endpoint[ActionListInput, ActionListOutput]{
@@ -147,7 +462,7 @@ func NewProvider(cfg Config, opts ...provider.Option) *OnRamp {
 			OperationID: "onramp-get-action",
 			Semantics:   endpoint.Read,
 			Summary:     "Get action detail",
-			Description: "Returns a single action execution record by ID.",
+			Description: "Returns a single action execution record by ID, with the hooks run for it.",
 			Tags:        []string{"onramp"},
 			HTTP:        endpoint.HTTPHint{Path: "/api/v1/onramp/actions/{id}"},
 		},
@@ -156,7 +471,7 @@ func NewProvider(cfg Config, opts ...provider.Option) *OnRamp {
 
 	// --- State ---
 
-	provider.Register(o.Base, endpoint.Endpoint[struct{}, ComponentStateListOutput]{
+	provider.Register(o.Base, endpoint.Endpoint[WorkspaceInput, ComponentStateListOutput]{
 		Desc: endpoint.Descriptor{
 			OperationID: "onramp-list-state",
 			Semantics:   endpoint.Read,
@@ -182,12 +497,12 @@ func NewProvider(cfg Config, opts ...provider.Option) *OnRamp {
 
 	// --- Config ---
 
-	provider.Register(o.Base, endpoint.Endpoint[struct{}, ConfigGetOutput]{
+	provider.Register(o.Base, endpoint.Endpoint[WorkspaceInput, ConfigGetOutput]{
 		Desc: endpoint.Descriptor{
 			OperationID: "onramp-get-config",
 			Semantics:   endpoint.Read,
 			Summary:     "Get OnRamp configuration",
-			Description: "Reads vars/main.yml and returns the parsed configuration.",
+			Description: "Reads vars/main.yml and returns the parsed configuration. The ETag header is the file's hash.",
 			Tags:        []string{"onramp"},
 			HTTP:        endpoint.HTTPHint{Path: "/api/v1/onramp/config"},
 		},
@@ -199,21 +514,119 @@ func NewProvider(cfg Config, opts ...provider.Option) *OnRamp {
 			OperationID: "onramp-patch-config",
 			Semantics:   endpoint.Update,
 			Summary:     "Patch OnRamp configuration",
-			Description: "Merges the provided fields into vars/main.yml, preserving untouched values.",
+			Description: "Applies a JSON Merge Patch (RFC 7386; null removes a key) or, with Content-Type application/json-patch+json, a JSON Patch (RFC 6902) to vars/main.yml, preserving untouched values. Send the ETag from a read as If-Match to get 412 instead of overwriting a concurrent change. Rejected with 422 when the result has validation errors the current file does not, unless force is set.",
 			Tags:        []string{"onramp"},
 			HTTP:        endpoint.HTTPHint{Method: "PATCH", Path: "/api/v1/onramp/config"},
 		},
 		Handler: o.HandlePatchConfig,
 	})
 
+	provider.Register(o.Base, endpoint.Endpoint[ConfigValidateInput, ConfigValidateOutput]{
+		Desc: endpoint.Descriptor{
+			OperationID: "onramp-validate-config",
+			Semantics:   endpoint.Action,
+			Summary:     "Validate OnRamp configuration",
+			Description: "Checks CIDR and address syntax, addresses outside their subnet, overlapping subnets and UE pools, and interface names against gathered node facts. Validates the request body, or vars/main.yml when the body is omitted.",
+			Tags:        []string{"onramp"},
+			HTTP:        endpoint.HTTPHint{Path: "/api/v1/onramp/config/validate"},
+		},
+		Handler: o.HandleValidateConfig,
+	})
+
+	provider.Register(o.Base, endpoint.Endpoint[TopologyValidateInput, TopologyValidateOutput]{
+		Desc: endpoint.Descriptor{
+			OperationID: "onramp-validate-topology",
+			Semantics:   endpoint.Action,
+			Summary:     "Validate node role topology",
+			Description: "Checks node role assignments against the checkout's topology rules: role counts such as the single master, roles that cannot share a node, roles that need another role, and the roles the deployed components run on. Validates the nodes and components in the request body, or the workspace's nodes and deployed components when they are omitted.",
+			Tags:        []string{"onramp"},
+			HTTP:        endpoint.HTTPHint{Path: "/api/v1/onramp/topology/validate"},
+		},
+		Handler: o.HandleValidateTopology,
+	})
+
+	provider.Register(o.Base, endpoint.Endpoint[struct{}, ConfigSchemaOutput]{
+		Desc: endpoint.Descriptor{
+			OperationID: "onramp-get-config-schema",
+			Semantics:   endpoint.Read,
+			Summary:     "Get OnRamp config JSON Schema",
+			Description: "Returns a JSON Schema (draft 2020-12) for vars/main.yml generated from the config types, with descriptions, enums, address formats and OnRamp's defaults. All fields are optional and unknown keys are allowed.",
+			Tags:        []string{"onramp"},
+			HTTP:        endpoint.HTTPHint{Path: "/api/v1/onramp/config/schema"},
+		},
+		Handler: o.HandleGetConfigSchema,
+	})
+
+	provider.Register(o.Base, endpoint.Endpoint[ConfigSectionSchemaInput, ConfigSchemaOutput]{
+		Desc: endpoint.Descriptor{
+			OperationID: "onramp-get-config-section-schema",
+			Semantics:   endpoint.Read,
+			Summary:     "Get JSON Schema for a config section",
+			Description: "Returns the JSON Schema for one top-level section of vars/main.yml, such as core or srsran.",
+			Tags:        []string{"onramp"},
+			HTTP:        endpoint.HTTPHint{Path: "/api/v1/onramp/config/schema/{section}"},
+		},
+		Handler: o.HandleGetConfigSectionSchema,
+	})
+
+	// --- Config history ---
+
+	provider.Register(o.Base, endpoint.Endpoint[WorkspaceInput, ConfigRevisionListOutput]{
+		Desc: endpoint.Descriptor{
+			OperationID: "onramp-list-config-revisions",
+			Semantics:   endpoint.Read,
+			Summary:     "List config revisions",
+			Description: "Returns the recorded versions of vars/main.yml, newest first, with author, time, source operation, and content hash.",
+			Tags:        []string{"onramp"},
+			HTTP:        endpoint.HTTPHint{Path: "/api/v1/onramp/config/revisions"},
+		},
+		Handler: o.HandleListConfigRevisions,
+	})
+
+	provider.Register(o.Base, endpoint.Endpoint[ConfigRevisionGetInput, ConfigRevisionGetOutput]{
+		Desc: endpoint.Descriptor{
+			OperationID: "onramp-get-config-revision",
+			Semantics:   endpoint.Read,
+			Summary:     "Get config revision",
+			Description: "Returns a recorded version of vars/main.yml including its content.",
+			Tags:        []string{"onramp"},
+			HTTP:        endpoint.HTTPHint{Path: "/api/v1/onramp/config/revisions/{id}"},
+		},
+		Handler: o.HandleGetConfigRevision,
+	})
+
+	provider.Register(o.Base, endpoint.Endpoint[ConfigRevisionDiffInput, ConfigRevisionDiffOutput]{
+		Desc: endpoint.Descriptor{
+			OperationID: "onramp-diff-config-revision",
+			Semantics:   endpoint.Read,
+			Summary:     "Diff config revisions",
+			Description: "Compares a revision with another revision, or with the current vars/main.yml when to is omitted. Returns a unified diff and a list of changed keys.",
+			Tags:        []string{"onramp"},
+			HTTP:        endpoint.HTTPHint{Path: "/api/v1/onramp/config/revisions/{id}/diff"},
+		},
+		Handler: o.HandleDiffConfigRevision,
+	})
+
+	provider.Register(o.Base, endpoint.Endpoint[ConfigRevisionRollbackInput, ConfigRevisionRollbackOutput]{
+		Desc: endpoint.Descriptor{
+			OperationID: "onramp-rollback-config",
+			Semantics:   endpoint.Action,
+			Summary:     "Roll back config",
+			Description: "Restores vars/main.yml to a recorded revision. The rollback is recorded as a new revision.",
+			Tags:        []string{"onramp"},
+			HTTP:        endpoint.HTTPHint{Path: "/api/v1/onramp/config/revisions/{id}/rollback"},
+		},
+		Handler: o.HandleRollbackConfig,
+	})
+
 	// --- Profiles ---
 
-	provider.Register(o.Base, endpoint.Endpoint[struct{}, ProfileListOutput]{
+	provider.Register(o.Base, endpoint.Endpoint[WorkspaceInput, ProfileListOutput]{
 		Desc: endpoint.Descriptor{
 			OperationID: "onramp-list-profiles",
 			Semantics:   endpoint.Read,
 			Summary:     "List OnRamp config profiles",
-			Description: "Lists available vars profiles (main-*.yml files).",
+			Description: "Lists vars profiles: the main-*.yml blueprints shipped with OnRamp, untracked main-*.yml files, and profiles saved in the database.",
 			Tags:        []string{"onramp"},
 			HTTP:        endpoint.HTTPHint{Path: "/api/v1/onramp/config/profiles"},
 		},
@@ -244,6 +657,54 @@ func NewProvider(cfg Config, opts ...provider.Option) *OnRamp {
 		Handler: o.HandleActivateProfile,
 	})
 
+	provider.Register(o.Base, endpoint.Endpoint[ProfileCreateInput, ProfileCreateOutput]{
+		Desc: endpoint.Descriptor{
+			OperationID: "onramp-create-profile",
+			Semantics:   endpoint.Create,
+			Summary:     "Save OnRamp config profile",
+			Description: "Saves the active vars/main.yml, or a copy of another profile, as a profile in the database or as vars/main-<name>.yml. Blueprints cannot be overwritten.",
+			Tags:        []string{"onramp"},
+			HTTP:        endpoint.HTTPHint{Path: "/api/v1/onramp/config/profiles"},
+		},
+		Handler: o.HandleCreateProfile,
+	})
+
+	provider.Register(o.Base, endpoint.Endpoint[ProfileRenameInput, ProfileRenameOutput]{
+		Desc: endpoint.Descriptor{
+			OperationID: "onramp-rename-profile",
+			Semantics:   endpoint.Action,
+			Summary:     "Rename OnRamp config profile",
+			Description: "Renames a user profile where it is stored. Blueprints cannot be renamed.",
+			Tags:        []string{"onramp"},
+			HTTP:        endpoint.HTTPHint{Path: "/api/v1/onramp/config/profiles/{name}/rename"},
+		},
+		Handler: o.HandleRenameProfile,
+	})
+
+	provider.Register(o.Base, endpoint.Endpoint[ProfileDeleteInput, ProfileDeleteOutput]{
+		Desc: endpoint.Descriptor{
+			OperationID: "onramp-delete-profile",
+			Semantics:   endpoint.Delete,
+			Summary:     "Delete OnRamp config profile",
+			Description: "Deletes a user profile. Blueprints cannot be deleted.",
+			Tags:        []string{"onramp"},
+			HTTP:        endpoint.HTTPHint{Path: "/api/v1/onramp/config/profiles/{name}"},
+		},
+		Handler: o.HandleDeleteProfile,
+	})
+
+	provider.Register(o.Base, endpoint.Endpoint[ProfileDiffInput, ProfileDiffOutput]{
+		Desc: endpoint.Descriptor{
+			OperationID: "onramp-diff-profile",
+			Semantics:   endpoint.Read,
+			Summary:     "Diff OnRamp config profile",
+			Description: "Compares a profile with another profile, or with the active vars/main.yml when to is omitted. Returns a unified diff and a list of changed keys.",
+			Tags:        []string{"onramp"},
+			HTTP:        endpoint.HTTPHint{Path: "/api/v1/onramp/config/profiles/{name}/diff"},
+		},
+		Handler: o.HandleDiffProfile,
+	})
+
 	// --- Config Compose ---
 
 	provider.Register(o.Base, endpoint.Endpoint[ConfigComposeInput, ConfigComposeOutput]{
@@ -251,7 +712,7 @@ func NewProvider(cfg Config, opts ...provider.Option) *OnRamp {
 			OperationID: "onramp-compose-config",
 			Semantics:   endpoint.Action,
 			Summary:     "Compose config from blueprints",
-			Description: "Builds vars/main.yml from the base config plus selected component blueprints, pruning sections for unselected components.",
+			Description: "Builds vars/main.yml from the base config plus selected component blueprints, pruning sections for unselected components. With dry_run=true, returns the resulting file and a diff against the current one without writing it.",
 			Tags:        []string{"onramp"},
 			HTTP:        endpoint.HTTPHint{Path: "/api/v1/onramp/config/compose"},
 		},
@@ -260,30 +721,78 @@ func NewProvider(cfg Config, opts ...provider.Option) *OnRamp {
 
 	// --- Inventory ---
 
-	provider.Register(o.Base, endpoint.Endpoint[struct{}, InventoryGetOutput]{
+	provider.Register(o.Base, endpoint.Endpoint[WorkspaceInput, InventoryGetOutput]{
 		Desc: endpoint.Descriptor{
 			OperationID: "onramp-get-inventory",
 			Semantics:   endpoint.Read,
 			Summary:     "Get Ansible inventory",
-			Description: "Parses the current hosts.ini and returns structured inventory data.",
+			Description: "Parses the current hosts.ini and returns structured inventory data. Passwords are never returned.",
 			Tags:        []string{"onramp"},
 			HTTP:        endpoint.HTTPHint{Path: "/api/v1/onramp/inventory"},
 		},
 		Handler: o.HandleGetInventory,
 	})
 
-	provider.Register(o.Base, endpoint.Endpoint[struct{}, InventorySyncOutput]{
+	provider.Register(o.Base, endpoint.Endpoint[InventorySyncInput, InventorySyncOutput]{
 		Desc: endpoint.Descriptor{
 			OperationID: "onramp-sync-inventory",
 			Semantics:   endpoint.Action,
 			Summary:     "Sync inventory to hosts.ini",
-			Description: "Generates hosts.ini from managed nodes in the database and writes it to disk.",
+			Description: "Generates hosts.ini from managed nodes in the database and writes it to disk. Node passwords are kept out of hosts.ini and written to owner-only host_vars files. Returns 409 if hosts.ini was edited since the last sync, unless force=true.",
 			Tags:        []string{"onramp"},
 			HTTP:        endpoint.HTTPHint{Path: "/api/v1/onramp/inventory/sync"},
 		},
 		Handler: o.HandleSyncInventory,
 	})
 
+	provider.Register(o.Base, endpoint.Endpoint[WorkspaceInput, InventoryDriftOutput]{
+		Desc: endpoint.Descriptor{
+			OperationID: "onramp-get-inventory-drift",
+			Semantics:   endpoint.Read,
+			Summary:     "Compare nodes with hosts.ini",
+			Description: "Lists nodes missing from the database or from hosts.ini and nodes whose address, user or roles differ, and reports whether hosts.ini was edited since the last sync.",
+			Tags:        []string{"onramp"},
+			HTTP:        endpoint.HTTPHint{Path: "/api/v1/onramp/inventory/drift"},
+		},
+		Handler: o.HandleGetInventoryDrift,
+	})
+
+	provider.Register(o.Base, endpoint.Endpoint[InventoryReconcileInput, InventoryReconcileOutput]{
+		Desc: endpoint.Descriptor{
+			OperationID: "onramp-reconcile-inventory",
+			Semantics:   endpoint.Action,
+			Summary:     "Reconcile nodes and hosts.ini",
+			Description: "Resolves inventory drift in one direction: to_file rewrites hosts.ini from the database; to_db creates, updates and deletes nodes to match hosts.ini.",
+			Tags:        []string{"onramp"},
+			HTTP:        endpoint.HTTPHint{Path: "/api/v1/onramp/inventory/reconcile"},
+		},
+		Handler: o.HandleReconcileInventory,
+	})
+
+	provider.Register(o.Base, endpoint.Endpoint[WorkspaceInput, InventoryGroupVarsOutput]{
+		Desc: endpoint.Descriptor{
+			OperationID: "onramp-get-group-vars",
+			Semantics:   endpoint.Read,
+			Summary:     "Get inventory group vars",
+			Description: "Returns the variables written to each role's [<role>_nodes:vars] section of hosts.ini, by role.",
+			Tags:        []string{"onramp"},
+			HTTP:        endpoint.HTTPHint{Path: "/api/v1/onramp/inventory/group-vars"},
+		},
+		Handler: o.HandleGetGroupVars,
+	})
+
+	provider.Register(o.Base, endpoint.Endpoint[InventoryGroupVarsSetInput, InventoryGroupVarsSetOutput]{
+		Desc: endpoint.Descriptor{
+			OperationID: "onramp-set-group-vars",
+			Semantics:   endpoint.Update,
+			Summary:     "Set inventory group vars",
+			Description: "Replaces the variables of a role's [<role>_nodes:vars] section of hosts.ini. An empty map removes them. Passwords are refused. Takes effect at the next inventory sync.",
+			Tags:        []string{"onramp"},
+			HTTP:        endpoint.HTTPHint{Path: "/api/v1/onramp/inventory/group-vars/{role}"},
+		},
+		Handler: o.HandleSetGroupVars,
+	})
+
 	// --- Deployments ---
 
 	provider.Register(o.Base, endpoint.Endpoint[DeployInput, DeployOutput]{
@@ -315,7 +824,7 @@ func NewProvider(cfg Config, opts ...provider.Option) *OnRamp {
 			OperationID: "onramp-get-deployment",
 			Semantics:   endpoint.Read,
 			Summary:     "Get deployment",
-			Description: "Returns a single deployment with enriched per-action statuses.",
+			Description: "Returns a single deployment with enriched per-action statuses and hook results.",
 			Tags:        []string{"onramp"},
 			HTTP:        endpoint.HTTPHint{Path: "/api/v1/onramp/deployments/{id}"},
 		},
@@ -334,26 +843,134 @@ func NewProvider(cfg Config, opts ...provider.Option) *OnRamp {
 		Handler: o.HandleCancelDeployment,
 	})
 
+	// --- Change lock ---
+
+	provider.Register(o.Base, endpoint.Endpoint[WorkspaceInput, LockGetOutput]{
+		Desc: endpoint.Descriptor{
+			OperationID: "onramp-get-lock",
+			Semantics:   endpoint.Read,
+			Summary:     "Get change lock",
+			Description: "Reports whether the cluster change lock is held by a deployment or a maintenance window.",
+			Tags:        []string{"onramp"},
+			HTTP:        endpoint.HTTPHint{Path: "/api/v1/onramp/lock"},
+		},
+		Handler: o.HandleGetLock,
+	})
+
+	provider.Register(o.Base, endpoint.Endpoint[LockAcquireInput, LockAcquireOutput]{
+		Desc: endpoint.Descriptor{
+			OperationID: "onramp-acquire-lock",
+			Semantics:   endpoint.Create,
+			Summary:     "Acquire maintenance lock",
+			Description: "Takes the cluster change lock for maintenance. Mutating OnRamp, config defaults, and node endpoints are rejected until it is released or expires.",
+			Tags:        []string{"onramp"},
+			HTTP:        endpoint.HTTPHint{Path: "/api/v1/onramp/lock"},
+		},
+		Handler: o.HandleAcquireLock,
+	})
+
+	provider.Register(o.Base, endpoint.Endpoint[LockReleaseInput, LockReleaseOutput]{
+		Desc: endpoint.Descriptor{
+			OperationID: "onramp-release-lock",
+			Semantics:   endpoint.Delete,
+			Summary:     "Release maintenance lock",
+			Description: "Releases a maintenance lock using the lock_id returned when it was acquired.",
+			Tags:        []string{"onramp"},
+			HTTP:        endpoint.HTTPHint{Path: "/api/v1/onramp/lock"},
+		},
+		Handler: o.HandleReleaseLock,
+	})
+
+	provider.Register(o.Base, endpoint.Endpoint[LockBreakInput, LockBreakOutput]{
+		Desc: endpoint.Descriptor{
+			OperationID: "onramp-break-lock",
+			Semantics:   endpoint.Action,
+			Summary:     "Force-break change lock",
+			Description: "Administrative override that removes the change lock regardless of holder. Breaking a deployment lock does not stop the deployment.",
+			Tags:        []string{"onramp"},
+			HTTP:        endpoint.HTTPHint{Path: "/api/v1/onramp/lock/break"},
+		},
+		Handler: o.HandleBreakLock,
+	})
+
+	// --- Workspaces ---
+
+	provider.Register(o.Base, endpoint.Endpoint[struct{}, WorkspaceListOutput]{
+		Desc: endpoint.Descriptor{
+			OperationID: "onramp-list-workspaces",
+			Semantics:   endpoint.Read,
+			Summary:     "List workspaces",
+			Description: "Returns the default workspace followed by every named workspace. Each workspace has its own OnRamp checkout, inventory, configuration, component state, and task queue.",
+			Tags:        []string{"onramp"},
+			HTTP:        endpoint.HTTPHint{Path: "/api/v1/onramp/workspaces"},
+		},
+		Handler: o.HandleListWorkspaces,
+	})
+
+	provider.Register(o.Base, endpoint.Endpoint[WorkspaceCreateInput, WorkspaceCreateOutput]{
+		Desc: endpoint.Descriptor{
+			OperationID: "onramp-create-workspace",
+			Semantics:   endpoint.Create,
+			Summary:     "Create workspace",
+			Description: "Registers a named workspace with its own OnRamp checkout. Repo URL and version default to the default workspace's. The checkout is cloned by a repo refresh in the new workspace.",
+			Tags:        []string{"onramp"},
+			HTTP:        endpoint.HTTPHint{Path: "/api/v1/onramp/workspaces"},
+		},
+		Handler: o.HandleCreateWorkspace,
+	})
+
+	provider.Register(o.Base, endpoint.Endpoint[WorkspaceGetInput, WorkspaceGetOutput]{
+		Desc: endpoint.Descriptor{
+			OperationID: "onramp-get-workspace",
+			Semantics:   endpoint.Read,
+			Summary:     "Get workspace",
+			Description: "Returns a single workspace by name.",
+			Tags:        []string{"onramp"},
+			HTTP:        endpoint.HTTPHint{Path: "/api/v1/onramp/workspaces/{name}"},
+		},
+		Handler: o.HandleGetWorkspace,
+	})
+
+	provider.Register(o.Base, endpoint.Endpoint[WorkspaceDeleteInput, WorkspaceDeleteOutput]{
+		Desc: endpoint.Descriptor{
+			OperationID: "onramp-delete-workspace",
+			Semantics:   endpoint.Delete,
+			Summary:     "Delete workspace",
+			Description: "Removes a named workspace and its component state. Rejected while the workspace has nodes, queued or running tasks, or a held change lock. Action and deployment history are kept and the checkout is left on disk.",
+			Tags:        []string{"onramp"},
+			HTTP:        endpoint.HTTPHint{Path: "/api/v1/onramp/workspaces/{name}"},
+		},
+		Handler: o.HandleDeleteWorkspace,
+	})
+
 	return o
 }
 
 // Endpoints returns all registered endpoints for the provider.
 func (o *OnRamp) Endpoints() []endpoint.AnyEndpoint { return o.endpoints }
 
-// Runner returns the task runner used by this provider.
+// Runner returns the task runner of the default workspace.
 func (o *OnRamp) Runner() *taskrunner.Runner { return o.runner }
 
-// Start clones/validates the OnRamp repo, recovers any tasks that were
-// interrupted by a previous shutdown, and marks the provider as running.
-// If repo setup fails, the provider logs the error and starts in degraded mode.
+// Start applies any runtime version pin, clones/validates the OnRamp repo,
+// recovers any tasks that were interrupted by a previous shutdown, and marks
+// the provider as running. If repo setup fails, the provider logs the error
+// and starts in degraded mode.
 func (o *OnRamp) Start() error {
 	log := o.Log()
-	if err := ensureRepo(o.config, log); err != nil {
+	o.loadPinnedVersion()
+	if o.config.BundlePath != "" {
+		if err := o.importStartupBundle(); err != nil {
+			log.Error("onramp bundle import failed", "bundle", o.config.BundlePath, "error", err)
+		}
+	}
+	if err := ensureRepo(o.defaultWorkspace().currentConfig(), log); err != nil {
 		log.Error("onramp repo setup failed; provider starting in degraded mode", "error", err)
 		o.SetDegraded(fmt.Sprintf("repo setup: %v", err))
 	} else {
 		o.ClearDegraded()
 	}
+	o.prepareWorkspaces()
 	recoverStaleTasks(o.Store(), log)
 	o.SetRunning(true)
 	return nil
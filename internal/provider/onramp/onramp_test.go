@@ -1,15 +1,19 @@
 package onramp
 
 import (
+	"encoding/json"
 	"log/slog"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 	"testing"
 	"time"
 
+	"gopkg.in/yaml.v3"
+
 	"github.com/bengrewell/aether-webui/internal/provider"
 	"github.com/bengrewell/aether-webui/internal/store"
 	"github.com/bengrewell/aether-webui/internal/taskrunner"
@@ -150,8 +154,8 @@ func TestNewProvider_ImplementsInterface(t *testing.T) {
 func TestNewProvider_EndpointCount(t *testing.T) {
 	p := newTestProvider(t, "")
 	descs := p.Base.Descriptors()
-	if len(descs) != 23 {
-		t.Errorf("registered %d endpoints, want 23", len(descs))
+	if len(descs) != 70 {
+		t.Errorf("registered %d endpoints, want 70", len(descs))
 	}
 }
 
@@ -159,29 +163,76 @@ func TestNewProvider_EndpointPaths(t *testing.T) {
 	p := newTestProvider(t, "")
 
 	wantOps := map[string]string{
-		"onramp-get-repo-status":   "/api/v1/onramp/repo",
-		"onramp-refresh-repo":      "/api/v1/onramp/repo/refresh",
-		"onramp-list-components":   "/api/v1/onramp/components",
-		"onramp-get-component":     "/api/v1/onramp/components/{component}",
-		"onramp-execute-action":    "/api/v1/onramp/components/{component}/{action}",
-		"onramp-list-tasks":        "/api/v1/onramp/tasks",
-		"onramp-get-task":          "/api/v1/onramp/tasks/{id}",
-		"onramp-list-actions":      "/api/v1/onramp/actions",
-		"onramp-get-action":        "/api/v1/onramp/actions/{id}",
-		"onramp-list-state":        "/api/v1/onramp/state",
-		"onramp-get-state":         "/api/v1/onramp/state/{component}",
-		"onramp-get-config":        "/api/v1/onramp/config",
-		"onramp-patch-config":      "/api/v1/onramp/config",
-		"onramp-list-profiles":     "/api/v1/onramp/config/profiles",
-		"onramp-get-profile":       "/api/v1/onramp/config/profiles/{name}",
-		"onramp-activate-profile":  "/api/v1/onramp/config/profiles/{name}/activate",
-		"onramp-get-inventory":     "/api/v1/onramp/inventory",
-		"onramp-sync-inventory":    "/api/v1/onramp/inventory/sync",
-		"onramp-deploy":            "/api/v1/onramp/deploy",
-		"onramp-list-deployments":  "/api/v1/onramp/deployments",
-		"onramp-get-deployment":    "/api/v1/onramp/deployments/{id}",
-		"onramp-cancel-deployment": "/api/v1/onramp/deployments/{id}",
-		"onramp-compose-config":    "/api/v1/onramp/config/compose",
+		"onramp-get-repo-status":           "/api/v1/onramp/repo",
+		"onramp-refresh-repo":              "/api/v1/onramp/repo/refresh",
+		"onramp-list-repo-versions":        "/api/v1/onramp/repo/versions",
+		"onramp-set-repo-version":          "/api/v1/onramp/repo/version",
+		"onramp-get-repo-diff":             "/api/v1/onramp/repo/diff",
+		"onramp-stash-repo":                "/api/v1/onramp/repo/stash",
+		"onramp-reset-repo":                "/api/v1/onramp/repo/reset",
+		"onramp-import-repo-bundle":        "/api/v1/onramp/repo/bundle",
+		"onramp-list-config-revisions":     "/api/v1/onramp/config/revisions",
+		"onramp-get-config-revision":       "/api/v1/onramp/config/revisions/{id}",
+		"onramp-diff-config-revision":      "/api/v1/onramp/config/revisions/{id}/diff",
+		"onramp-rollback-config":           "/api/v1/onramp/config/revisions/{id}/rollback",
+		"onramp-list-components":           "/api/v1/onramp/components",
+		"onramp-get-component":             "/api/v1/onramp/components/{component}",
+		"onramp-execute-action":            "/api/v1/onramp/components/{component}/{action}",
+		"onramp-list-custom-components":    "/api/v1/onramp/custom-components",
+		"onramp-get-custom-component":      "/api/v1/onramp/custom-components/{name}",
+		"onramp-create-custom-component":   "/api/v1/onramp/custom-components",
+		"onramp-update-custom-component":   "/api/v1/onramp/custom-components/{name}",
+		"onramp-delete-custom-component":   "/api/v1/onramp/custom-components/{name}",
+		"onramp-list-hooks":                "/api/v1/onramp/hooks",
+		"onramp-get-hook":                  "/api/v1/onramp/hooks/{id}",
+		"onramp-create-hook":               "/api/v1/onramp/hooks",
+		"onramp-update-hook":               "/api/v1/onramp/hooks/{id}",
+		"onramp-delete-hook":               "/api/v1/onramp/hooks/{id}",
+		"onramp-list-tasks":                "/api/v1/onramp/tasks",
+		"onramp-get-task":                  "/api/v1/onramp/tasks/{id}",
+		"onramp-get-queue":                 "/api/v1/onramp/queue",
+		"onramp-move-queue-task":           "/api/v1/onramp/queue/{id}/move",
+		"onramp-prioritize-queue-task":     "/api/v1/onramp/queue/{id}/prioritize",
+		"onramp-remove-queue-task":         "/api/v1/onramp/queue/{id}",
+		"onramp-pause-queue":               "/api/v1/onramp/queue/pause",
+		"onramp-resume-queue":              "/api/v1/onramp/queue/resume",
+		"onramp-drain-queue":               "/api/v1/onramp/queue/drain",
+		"onramp-list-actions":              "/api/v1/onramp/actions",
+		"onramp-get-action":                "/api/v1/onramp/actions/{id}",
+		"onramp-list-state":                "/api/v1/onramp/state",
+		"onramp-get-state":                 "/api/v1/onramp/state/{component}",
+		"onramp-get-config":                "/api/v1/onramp/config",
+		"onramp-patch-config":              "/api/v1/onramp/config",
+		"onramp-validate-config":           "/api/v1/onramp/config/validate",
+		"onramp-get-config-schema":         "/api/v1/onramp/config/schema",
+		"onramp-get-config-section-schema": "/api/v1/onramp/config/schema/{section}",
+		"onramp-list-profiles":             "/api/v1/onramp/config/profiles",
+		"onramp-get-profile":               "/api/v1/onramp/config/profiles/{name}",
+		"onramp-activate-profile":          "/api/v1/onramp/config/profiles/{name}/activate",
+		"onramp-create-profile":            "/api/v1/onramp/config/profiles",
+		"onramp-rename-profile":            "/api/v1/onramp/config/profiles/{name}/rename",
+		"onramp-delete-profile":            "/api/v1/onramp/config/profiles/{name}",
+		"onramp-diff-profile":              "/api/v1/onramp/config/profiles/{name}/diff",
+		"onramp-get-inventory":             "/api/v1/onramp/inventory",
+		"onramp-sync-inventory":            "/api/v1/onramp/inventory/sync",
+		"onramp-get-inventory-drift":       "/api/v1/onramp/inventory/drift",
+		"onramp-reconcile-inventory":       "/api/v1/onramp/inventory/reconcile",
+		"onramp-get-group-vars":            "/api/v1/onramp/inventory/group-vars",
+		"onramp-validate-topology":         "/api/v1/onramp/topology/validate",
+		"onramp-set-group-vars":            "/api/v1/onramp/inventory/group-vars/{role}",
+		"onramp-deploy":                    "/api/v1/onramp/deploy",
+		"onramp-list-deployments":          "/api/v1/onramp/deployments",
+		"onramp-get-deployment":            "/api/v1/onramp/deployments/{id}",
+		"onramp-cancel-deployment":         "/api/v1/onramp/deployments/{id}",
+		"onramp-compose-config":            "/api/v1/onramp/config/compose",
+		"onramp-get-lock":                  "/api/v1/onramp/lock",
+		"onramp-acquire-lock":              "/api/v1/onramp/lock",
+		"onramp-release-lock":              "/api/v1/onramp/lock",
+		"onramp-break-lock":                "/api/v1/onramp/lock/break",
+		"onramp-list-workspaces":           "/api/v1/onramp/workspaces",
+		"onramp-create-workspace":          "/api/v1/onramp/workspaces",
+		"onramp-get-workspace":             "/api/v1/onramp/workspaces/{name}",
+		"onramp-delete-workspace":          "/api/v1/onramp/workspaces/{name}",
 	}
 
 	descs := p.Base.Descriptors()
@@ -211,8 +262,8 @@ func TestHandleListComponents(t *testing.T) {
 	if err != nil {
 		t.Fatalf("handleListComponents: %v", err)
 	}
-	if len(out.Body) != len(componentRegistry) {
-		t.Fatalf("got %d components, want %d", len(out.Body), len(componentRegistry))
+	if len(out.Body) != len(builtinRegistry().components) {
+		t.Fatalf("got %d components, want %d", len(out.Body), len(builtinRegistry().components))
 	}
 	// Verify a known component is present.
 	found := false
@@ -254,7 +305,7 @@ func TestHandleGetComponent_NotFound(t *testing.T) {
 
 func TestHandleGetComponent_AllRegistered(t *testing.T) {
 	p := newTestProvider(t, "")
-	for _, comp := range componentRegistry {
+	for _, comp := range builtinRegistry().components {
 		out, err := p.HandleGetComponent(t.Context(), &ComponentGetInput{Component: comp.Name})
 		if err != nil {
 			t.Errorf("handleGetComponent(%q): %v", comp.Name, err)
@@ -589,9 +640,16 @@ func TestHandleListProfiles(t *testing.T) {
 	if len(out.Body) != 2 {
 		t.Fatalf("got %d profiles, want 2", len(out.Body))
 	}
-	sort.Strings(out.Body)
-	if out.Body[0] != "production" || out.Body[1] != "staging" {
-		t.Errorf("profiles = %v, want [production staging]", out.Body)
+	var names []string
+	for _, p := range out.Body {
+		names = append(names, p.Name)
+		if p.Source != "file" || p.ReadOnly {
+			t.Errorf("profile %s: source = %s, read_only = %v", p.Name, p.Source, p.ReadOnly)
+		}
+	}
+	sort.Strings(names)
+	if names[0] != "production" || names[1] != "staging" {
+		t.Errorf("profiles = %v, want [production staging]", names)
 	}
 }
 
@@ -872,16 +930,26 @@ func gitRunTest(t *testing.T, dir string, args ...string) {
 }
 
 // ---------------------------------------------------------------------------
-// deepMergeConfig
+// MergeVars
 // ---------------------------------------------------------------------------
 
-func TestDeepMergeConfig_NilFieldsPreserved(t *testing.T) {
+// mergeConfig writes base as a vars file and merges patchJSON into it.
+func mergeConfig(base *OnRampConfig, patchJSON []byte) (OnRampConfig, error) {
+	data, err := yaml.Marshal(base)
+	if err != nil {
+		return OnRampConfig{}, err
+	}
+	_, merged, err := MergeVars(data, patchJSON)
+	return merged, err
+}
+
+func TestMergeVars_NilFieldsPreserved(t *testing.T) {
 	base := OnRampConfig{
 		K8s:  &K8sConfig{RKE2: &RKE2Config{Version: "v1"}},
 		Core: &CoreConfig{DataIface: "ens18"},
 	}
 
-	merged, err := deepMergeConfig(&base, []byte(`{}`))
+	merged, err := mergeConfig(&base, []byte(`{}`))
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -894,13 +962,13 @@ func TestDeepMergeConfig_NilFieldsPreserved(t *testing.T) {
 	}
 }
 
-func TestDeepMergeConfig_NonNilFieldsOverwrite(t *testing.T) {
+func TestMergeVars_NonNilFieldsOverwrite(t *testing.T) {
 	base := OnRampConfig{
 		K8s:  &K8sConfig{RKE2: &RKE2Config{Version: "v1"}},
 		Core: &CoreConfig{DataIface: "ens18"},
 	}
 
-	merged, err := deepMergeConfig(&base, []byte(`{"core": {"data_iface": "ens20"}}`))
+	merged, err := mergeConfig(&base, []byte(`{"core": {"data_iface": "ens20"}}`))
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -913,7 +981,7 @@ func TestDeepMergeConfig_NonNilFieldsOverwrite(t *testing.T) {
 	}
 }
 
-func TestDeepMergeConfig_PreservesSiblingFields(t *testing.T) {
+func TestMergeVars_PreservesSiblingFields(t *testing.T) {
 	standalone := true
 	base := OnRampConfig{
 		Core: &CoreConfig{
@@ -925,7 +993,7 @@ func TestDeepMergeConfig_PreservesSiblingFields(t *testing.T) {
 		},
 	}
 
-	merged, err := deepMergeConfig(&base, []byte(`{"core": {"data_iface": "enp5s0"}}`))
+	merged, err := mergeConfig(&base, []byte(`{"core": {"data_iface": "enp5s0"}}`))
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -947,7 +1015,7 @@ func TestDeepMergeConfig_PreservesSiblingFields(t *testing.T) {
 	}
 }
 
-func TestDeepMergeConfig_ZeroValueOverwrite(t *testing.T) {
+func TestMergeVars_ZeroValueOverwrite(t *testing.T) {
 	base := OnRampConfig{
 		Core: &CoreConfig{
 			DataIface: "ens18",
@@ -956,7 +1024,7 @@ func TestDeepMergeConfig_ZeroValueOverwrite(t *testing.T) {
 	}
 
 	// Explicitly set ran_subnet to empty string.
-	merged, err := deepMergeConfig(&base, []byte(`{"core": {"data_iface": "enp5s0", "ran_subnet": ""}}`))
+	merged, err := mergeConfig(&base, []byte(`{"core": {"data_iface": "enp5s0", "ran_subnet": ""}}`))
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -969,7 +1037,7 @@ func TestDeepMergeConfig_ZeroValueOverwrite(t *testing.T) {
 	}
 }
 
-func TestDeepMergeConfig_NestedOverwrite(t *testing.T) {
+func TestMergeVars_NestedOverwrite(t *testing.T) {
 	base := OnRampConfig{
 		K8s: &K8sConfig{
 			RKE2: &RKE2Config{Version: "v1.24", Config: &RKE2Inner{Token: "secret", Port: 9345}},
@@ -977,7 +1045,7 @@ func TestDeepMergeConfig_NestedOverwrite(t *testing.T) {
 		},
 	}
 
-	merged, err := deepMergeConfig(&base, []byte(`{"k8s": {"rke2": {"version": "v1.25"}}}`))
+	merged, err := mergeConfig(&base, []byte(`{"k8s": {"rke2": {"version": "v1.25"}}}`))
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -1001,7 +1069,7 @@ func TestDeepMergeConfig_NestedOverwrite(t *testing.T) {
 	}
 }
 
-func TestDeepMergeConfig_AllSections(t *testing.T) {
+func TestMergeVars_AllSections(t *testing.T) {
 	base := OnRampConfig{}
 	patch := []byte(`{
 		"k8s": {},
@@ -1015,7 +1083,7 @@ func TestDeepMergeConfig_AllSections(t *testing.T) {
 		"n3iwf": {}
 	}`)
 
-	merged, err := deepMergeConfig(&base, patch)
+	merged, err := mergeConfig(&base, patch)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -1118,12 +1186,13 @@ func TestToOnRampTask_MissingLabels(t *testing.T) {
 // ---------------------------------------------------------------------------
 
 func TestComponentRegistryConsistency(t *testing.T) {
-	if len(componentRegistry) != len(componentIndex) {
+	reg := builtinRegistry()
+	if len(reg.components) != len(reg.index) {
 		t.Fatalf("registry has %d components but index has %d",
-			len(componentRegistry), len(componentIndex))
+			len(reg.components), len(reg.index))
 	}
 
-	for _, comp := range componentRegistry {
+	for _, comp := range reg.components {
 		if comp.Name == "" {
 			t.Error("component with empty name")
 		}
@@ -1139,7 +1208,7 @@ func TestComponentRegistryConsistency(t *testing.T) {
 			}
 		}
 		// Verify index lookup.
-		indexed, ok := componentIndex[comp.Name]
+		indexed, ok := reg.component(comp.Name)
 		if !ok {
 			t.Errorf("component %q missing from index", comp.Name)
 		} else if indexed.Name != comp.Name {
@@ -1219,6 +1288,41 @@ node1
 	}
 }
 
+func TestReadHostsINI(t *testing.T) {
+	data := []byte(`[all]
+node1 ansible_host=10.0.0.1 ansible_password="two words" ansible_sudo_pass='s=1'
+; ini comment
+
+[master_nodes]
+node1
+node3 ansible_host=10.0.0.3
+
+[worker_nodes:vars]
+ansible_port=2222
+
+[worker_nodes]
+node1
+`)
+	hosts := ReadHostsINI(data)
+	if len(hosts) != 2 || hosts[0].Name != "node1" || hosts[1].Name != "node3" {
+		t.Fatalf("hosts = %+v", hosts)
+	}
+	h := hosts[0]
+	if h.Vars["ansible_password"] != "two words" || h.Vars["ansible_sudo_pass"] != "s=1" {
+		t.Errorf("vars = %v", h.Vars)
+	}
+	if _, ok := h.Vars["ansible_port"]; ok {
+		t.Error("group vars applied to the host")
+	}
+	if want := []string{"master_nodes", "worker_nodes"}; !slices.Equal(h.Groups, want) {
+		t.Errorf("groups = %v, want %v", h.Groups, want)
+	}
+	// A host defined only in a group is still read.
+	if hosts[1].Vars["ansible_host"] != "10.0.0.3" {
+		t.Errorf("node3 = %+v", hosts[1])
+	}
+}
+
 // ---------------------------------------------------------------------------
 // Inventory: generateHostsINI
 // ---------------------------------------------------------------------------
@@ -1234,20 +1338,23 @@ func TestGenerateHostsINI(t *testing.T) {
 			Roles:        []string{"master"},
 		},
 		{
-			Name:         "node2",
-			AnsibleHost:  "10.0.0.2",
-			AnsibleUser:  "root",
-			Roles:        []string{"worker"},
+			Name:        "node2",
+			AnsibleHost: "10.0.0.2",
+			AnsibleUser: "root",
+			Roles:       []string{"worker"},
 		},
 	}
 
-	data := generateHostsINI(nodes)
+	data := generateHostsINI(nodes, DefaultRoles(), nil)
 	content := string(data)
 
 	// Verify [all] section entries.
-	if !strings.Contains(content, "node1 ansible_host=10.0.0.1 ansible_user=ubuntu ansible_password=pass1 ansible_sudo_pass=sudo1") {
+	if !strings.Contains(content, "node1 ansible_host=10.0.0.1 ansible_user=ubuntu\n") {
 		t.Errorf("missing node1 in [all] section:\n%s", content)
 	}
+	if strings.Contains(content, "pass1") || strings.Contains(content, "sudo1") {
+		t.Errorf("hosts.ini holds node passwords:\n%s", content)
+	}
 	if !strings.Contains(content, "node2 ansible_host=10.0.0.2 ansible_user=root") {
 		t.Errorf("missing node2 in [all] section:\n%s", content)
 	}
@@ -1263,7 +1370,7 @@ func TestGenerateHostsINI(t *testing.T) {
 
 func TestGenerateHostsINI_EmptySections(t *testing.T) {
 	// No nodes: all role sections should still be emitted.
-	data := generateHostsINI(nil)
+	data := generateHostsINI(nil, DefaultRoles(), nil)
 	content := string(data)
 
 	for _, section := range []string{"[master_nodes]", "[worker_nodes]", "[gnbsim_nodes]"} {
@@ -1312,6 +1419,23 @@ node1
 	}
 }
 
+func TestHandleGetInventory_DoesNotEchoSecrets(t *testing.T) {
+	p := newTestProvider(t, "")
+	hostsINI := "[all]\nnode1 ansible_host=10.0.0.1 ansible_password=secret ansible_sudo_pass=sudo\n"
+	if err := os.WriteFile(filepath.Join(p.config.OnRampDir, "hosts.ini"), []byte(hostsINI), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	out, err := p.HandleGetInventory(t.Context(), nil)
+	if err != nil {
+		t.Fatalf("handleGetInventory: %v", err)
+	}
+	data, _ := json.Marshal(out.Body)
+	if strings.Contains(string(data), "secret") || strings.Contains(string(data), "sudo") {
+		t.Errorf("inventory echoes secrets: %s", data)
+	}
+}
+
 func TestHandleSyncInventory(t *testing.T) {
 	p := newTestProvider(t, "")
 
@@ -0,0 +1,402 @@
+package onramp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/danielgtaylor/huma/v2"
+	"gopkg.in/yaml.v3"
+
+	"github.com/bengrewell/aether-webui/internal/store"
+)
+
+// Profile sources. Blueprints are the vars/main-*.yml files tracked by the
+// OnRamp repo; untracked files in vars/ are user profiles, as are profiles
+// saved in the store.
+const (
+	profileBlueprint = "blueprint"
+	profileFile      = "file"
+	profileStore     = "store"
+)
+
+// profileNameRe is the form of names given to profiles the daemon saves or
+// renames. Blueprints and files put in vars/ by hand, such as main-eNB.yml,
+// only need profileFileRe: a name that cannot leave vars/.
+var (
+	profileNameRe = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+	profileFileRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,126}$`)
+)
+
+// profile is a resolved profile with its content.
+type profile struct {
+	ProfileInfo
+	content []byte
+}
+
+func profilePath(ws *workspace, name string) string {
+	return filepath.Join(ws.config.OnRampDir, "vars", fmt.Sprintf("main-%s.yml", name))
+}
+
+// trackedProfiles returns the names of the vars/main-*.yml files the OnRamp
+// repo tracks. A directory that is not a git checkout tracks nothing.
+func trackedProfiles(dir string) (map[string]bool, error) {
+	tracked := make(map[string]bool)
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err != nil {
+		return tracked, nil
+	}
+	out, err := gitOutput(dir, "ls-files", "--", "vars/main-*.yml")
+	if err != nil {
+		return nil, fmt.Errorf("list tracked profiles: %w", err)
+	}
+	for _, f := range strings.Split(out, "\n") {
+		if name, ok := profileNameFromFile(filepath.Base(f)); ok {
+			tracked[name] = true
+		}
+	}
+	return tracked, nil
+}
+
+func profileNameFromFile(base string) (string, bool) {
+	if !strings.HasPrefix(base, "main-") || !strings.HasSuffix(base, ".yml") {
+		return "", false
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(base, "main-"), ".yml"), true
+}
+
+// fileProfiles lists the profiles in vars/. Files whose names findProfile
+// would refuse are left out.
+func fileProfiles(ws *workspace) ([]ProfileInfo, error) {
+	matches, err := filepath.Glob(filepath.Join(ws.config.OnRampDir, "vars", "main-*.yml"))
+	if err != nil {
+		return nil, err
+	}
+	tracked, err := trackedProfiles(ws.config.OnRampDir)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]ProfileInfo, 0, len(matches))
+	for _, m := range matches {
+		name, _ := profileNameFromFile(filepath.Base(m))
+		if !profileFileRe.MatchString(name) {
+			continue
+		}
+		info := ProfileInfo{Name: name, Source: profileFile}
+		if tracked[name] {
+			info.Source = profileBlueprint
+			info.ReadOnly = true
+		}
+		out = append(out, info)
+	}
+	return out, nil
+}
+
+func storeProfileInfo(p store.ConfigProfile) ProfileInfo {
+	return ProfileInfo{
+		Name:        p.Name,
+		Source:      profileStore,
+		Description: p.Description,
+		Author:      p.Author,
+		UpdatedAt:   p.UpdatedAt.Unix(),
+	}
+}
+
+// findProfile resolves a profile by name. source restricts the lookup to
+// vars files ("file") or the store ("store"); without it files win.
+func (o *OnRamp) findProfile(ctx context.Context, ws *workspace, name, source string) (*profile, error) {
+	notFound := huma.Error404NotFound("profile not found", fmt.Errorf("no profile named %s", name))
+	if !profileFileRe.MatchString(name) {
+		return nil, notFound
+	}
+	if source != profileStore {
+		data, err := os.ReadFile(profilePath(ws, name))
+		switch {
+		case err == nil:
+			tracked, err := trackedProfiles(ws.config.OnRampDir)
+			if err != nil {
+				return nil, huma.Error500InternalServerError("failed to read profile", err)
+			}
+			p := &profile{ProfileInfo: ProfileInfo{Name: name, Source: profileFile}, content: data}
+			if tracked[name] {
+				p.Source = profileBlueprint
+				p.ReadOnly = true
+			}
+			return p, nil
+		case !errors.Is(err, os.ErrNotExist):
+			return nil, huma.Error500InternalServerError("failed to read profile", err)
+		}
+	}
+	if source == profileFile || o.Store().Path() == "" {
+		return nil, notFound
+	}
+	sp, ok, err := o.Store().GetConfigProfile(ctx, ws.name, name)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("failed to read profile", err)
+	}
+	if !ok {
+		return nil, notFound
+	}
+	return &profile{ProfileInfo: storeProfileInfo(sp), content: sp.Content}, nil
+}
+
+// profileExists reports whether name is taken in either location.
+func (o *OnRamp) profileExists(ctx context.Context, ws *workspace, name string) (bool, error) {
+	_, err := o.findProfile(ctx, ws, name, "")
+	var se huma.StatusError
+	if errors.As(err, &se) && se.GetStatus() == 404 {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// ---------------------------------------------------------------------------
+// Handlers
+// ---------------------------------------------------------------------------
+
+// HandleListProfiles returns the profiles in vars/ followed by those in the
+// store, each sorted by name.
+func (o *OnRamp) HandleListProfiles(ctx context.Context, in *WorkspaceInput) (*ProfileListOutput, error) {
+	ws, err := o.workspace(ctx, in.workspaceName())
+	if err != nil {
+		return nil, err
+	}
+	out, err := fileProfiles(ws)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("failed to list profiles", err)
+	}
+	if st := o.Store(); st.Path() != "" {
+		stored, err := st.ListConfigProfiles(ctx, ws.name)
+		if err != nil {
+			return nil, huma.Error500InternalServerError("failed to list profiles", err)
+		}
+		for _, p := range stored {
+			out = append(out, storeProfileInfo(p))
+		}
+	}
+	return &ProfileListOutput{Body: out}, nil
+}
+
+func (o *OnRamp) HandleGetProfile(ctx context.Context, in *ProfileGetInput) (*ProfileGetOutput, error) {
+	ws, err := o.workspace(ctx, in.Workspace)
+	if err != nil {
+		return nil, err
+	}
+	p, err := o.findProfile(ctx, ws, in.Name, in.Source)
+	if err != nil {
+		return nil, err
+	}
+	var cfg OnRampConfig
+	if err := yaml.Unmarshal(p.content, &cfg); err != nil {
+		return nil, huma.Error500InternalServerError("failed to read profile", err)
+	}
+	return &ProfileGetOutput{Body: cfg}, nil
+}
+
+func (o *OnRamp) HandleActivateProfile(ctx context.Context, in *ProfileActivateInput) (*ProfileActivateOutput, error) {
+	ws, err := o.workspace(ctx, in.Workspace)
+	if err != nil {
+		return nil, err
+	}
+	if err := o.CheckChangeLock(ctx, ws.name); err != nil {
+		return nil, err
+	}
+	p, err := o.findProfile(ctx, ws, in.Name, in.Source)
+	if err != nil {
+		return nil, err
+	}
+
+	defer LockVars(ws.config.OnRampDir)()
+	if err := o.saveVars(ctx, ws, p.content, "profile", in.Name, in.Author); err != nil {
+		return nil, huma.Error500InternalServerError("failed to write main.yml", err)
+	}
+
+	out := &ProfileActivateOutput{}
+	out.Body.Message = fmt.Sprintf("profile %q activated", in.Name)
+	return out, nil
+}
+
+// HandleCreateProfile saves the active config, or a copy of another
+// profile, as a new profile in the store or in vars/.
+func (o *OnRamp) HandleCreateProfile(ctx context.Context, in *ProfileCreateInput) (*ProfileCreateOutput, error) {
+	ws, err := o.workspace(ctx, in.Workspace)
+	if err != nil {
+		return nil, err
+	}
+	b := in.Body
+	if !profileNameRe.MatchString(b.Name) {
+		return nil, huma.Error422UnprocessableEntity("invalid profile name")
+	}
+	storage := b.Storage
+	if storage == "" {
+		storage = profileStore
+		if o.Store().Path() == "" {
+			storage = profileFile
+		}
+	}
+	if storage == profileStore {
+		if _, err := o.requireStore("stored profiles are"); err != nil {
+			return nil, err
+		}
+	}
+
+	var content []byte
+	if b.From != "" {
+		src, err := o.findProfile(ctx, ws, b.From, "")
+		if err != nil {
+			return nil, err
+		}
+		content = src.content
+	} else if content, err = os.ReadFile(filepath.Join(ws.config.OnRampDir, "vars", "main.yml")); err != nil {
+		return nil, huma.Error500InternalServerError("failed to read config", err)
+	}
+
+	existing, err := o.findProfile(ctx, ws, b.Name, storage)
+	var se huma.StatusError
+	switch {
+	case err == nil && existing.ReadOnly:
+		return nil, huma.Error403Forbidden(fmt.Sprintf("profile %s is an OnRamp blueprint and cannot be changed", b.Name))
+	case err == nil && !b.Overwrite:
+		return nil, huma.Error409Conflict(fmt.Sprintf("profile %s already exists; set overwrite to replace it", b.Name))
+	case err != nil && (!errors.As(err, &se) || se.GetStatus() != 404):
+		return nil, err
+	case err != nil:
+		// A new name must not shadow, or be shadowed by, a profile in the
+		// other location.
+		if taken, err := o.profileExists(ctx, ws, b.Name); err != nil {
+			return nil, err
+		} else if taken {
+			return nil, huma.Error409Conflict(fmt.Sprintf("a profile named %s already exists", b.Name))
+		}
+	}
+
+	if storage == profileFile {
+		if err := os.WriteFile(profilePath(ws, b.Name), content, 0o644); err != nil {
+			return nil, huma.Error500InternalServerError("failed to write profile", err)
+		}
+		return &ProfileCreateOutput{Body: ProfileInfo{Name: b.Name, Source: profileFile}}, nil
+	}
+	saved, err := o.Store().SaveConfigProfile(ctx, store.ConfigProfile{
+		Workspace:   ws.name,
+		Name:        b.Name,
+		Description: b.Description,
+		Author:      in.Author,
+		Hash:        contentHash(content),
+		Content:     content,
+	})
+	if err != nil {
+		return nil, huma.Error500InternalServerError("failed to save profile", err)
+	}
+	return &ProfileCreateOutput{Body: storeProfileInfo(saved)}, nil
+}
+
+// HandleRenameProfile renames a user profile in place. Blueprints cannot be
+// renamed.
+func (o *OnRamp) HandleRenameProfile(ctx context.Context, in *ProfileRenameInput) (*ProfileRenameOutput, error) {
+	ws, err := o.workspace(ctx, in.Workspace)
+	if err != nil {
+		return nil, err
+	}
+	newName := in.Body.NewName
+	if !profileNameRe.MatchString(newName) {
+		return nil, huma.Error422UnprocessableEntity("invalid profile name")
+	}
+	p, err := o.findProfile(ctx, ws, in.Name, in.Source)
+	if err != nil {
+		return nil, err
+	}
+	if p.ReadOnly {
+		return nil, huma.Error403Forbidden(fmt.Sprintf("profile %s is an OnRamp blueprint and cannot be changed", in.Name))
+	}
+	if newName == in.Name {
+		return &ProfileRenameOutput{Body: p.ProfileInfo}, nil
+	}
+	if taken, err := o.profileExists(ctx, ws, newName); err != nil {
+		return nil, err
+	} else if taken {
+		return nil, huma.Error409Conflict(fmt.Sprintf("a profile named %s already exists", newName))
+	}
+
+	if p.Source == profileFile {
+		if err := os.Rename(profilePath(ws, in.Name), profilePath(ws, newName)); err != nil {
+			return nil, huma.Error500InternalServerError("failed to rename profile", err)
+		}
+		p.Name = newName
+		return &ProfileRenameOutput{Body: p.ProfileInfo}, nil
+	}
+	st := o.Store()
+	if err := st.RenameConfigProfile(ctx, ws.name, in.Name, newName); err != nil {
+		if errors.Is(err, store.ErrConflict) {
+			return nil, huma.Error409Conflict(fmt.Sprintf("a profile named %s already exists", newName))
+		}
+		return nil, huma.Error500InternalServerError("failed to rename profile", err)
+	}
+	renamed, _, err := st.GetConfigProfile(ctx, ws.name, newName)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("failed to read profile", err)
+	}
+	return &ProfileRenameOutput{Body: storeProfileInfo(renamed)}, nil
+}
+
+// HandleDeleteProfile removes a user profile. Blueprints cannot be deleted.
+func (o *OnRamp) HandleDeleteProfile(ctx context.Context, in *ProfileDeleteInput) (*ProfileDeleteOutput, error) {
+	ws, err := o.workspace(ctx, in.Workspace)
+	if err != nil {
+		return nil, err
+	}
+	p, err := o.findProfile(ctx, ws, in.Name, in.Source)
+	if err != nil {
+		return nil, err
+	}
+	switch p.Source {
+	case profileBlueprint:
+		return nil, huma.Error403Forbidden(fmt.Sprintf("profile %s is an OnRamp blueprint and cannot be changed", in.Name))
+	case profileFile:
+		err = os.Remove(profilePath(ws, in.Name))
+	default:
+		err = o.Store().DeleteConfigProfile(ctx, ws.name, in.Name)
+	}
+	if err != nil {
+		return nil, huma.Error500InternalServerError("failed to delete profile", err)
+	}
+	out := &ProfileDeleteOutput{}
+	out.Body.Message = fmt.Sprintf("profile %q deleted", in.Name)
+	return out, nil
+}
+
+// HandleDiffProfile compares a profile with another profile, or with the
+// active vars/main.yml.
+func (o *OnRamp) HandleDiffProfile(ctx context.Context, in *ProfileDiffInput) (*ProfileDiffOutput, error) {
+	ws, err := o.workspace(ctx, in.Workspace)
+	if err != nil {
+		return nil, err
+	}
+	from, err := o.findProfile(ctx, ws, in.Name, in.Source)
+	if err != nil {
+		return nil, err
+	}
+	toName := "vars/main.yml"
+	var toContent []byte
+	if in.To != "" {
+		to, err := o.findProfile(ctx, ws, in.To, in.ToSource)
+		if err != nil {
+			return nil, err
+		}
+		toName = "profile " + in.To
+		toContent = to.content
+	} else {
+		toContent, err = os.ReadFile(filepath.Join(ws.config.OnRampDir, "vars", "main.yml"))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, huma.Error500InternalServerError("failed to read config", err)
+		}
+	}
+	return &ProfileDiffOutput{Body: ProfileDiff{
+		From:    in.Name,
+		To:      in.To,
+		Unified: unifiedDiff("profile "+in.Name, toName, string(from.content), string(toContent)),
+		Changes: structuralDiff(from.content, toContent),
+	}}, nil
+}
@@ -0,0 +1,219 @@
+package onramp
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// commitBlueprint writes vars/main-{name}.yml and commits it, making it an
+// upstream blueprint.
+func commitBlueprint(t *testing.T, o *OnRamp, name, content string) {
+	t.Helper()
+	writeProfile(t, o, name, content)
+	for _, args := range [][]string{
+		{"add", filepath.Join("vars", "main-"+name+".yml")},
+		{"commit", "-m", "add " + name},
+	} {
+		cmd := exec.Command("git", append([]string{"-C", o.config.OnRampDir}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %v\n%s", args[0], err, out)
+		}
+	}
+}
+
+func createProfile(t *testing.T, o *OnRamp, name, storage, from string) (ProfileInfo, error) {
+	t.Helper()
+	in := &ProfileCreateInput{}
+	in.Author = "bob"
+	in.Body.Name = name
+	in.Body.Storage = storage
+	in.Body.From = from
+	out, err := o.HandleCreateProfile(t.Context(), in)
+	if err != nil {
+		return ProfileInfo{}, err
+	}
+	return out.Body, nil
+}
+
+func TestProfiles_CreateAndProtect(t *testing.T) {
+	o := newTestProviderWithStore(t, testMainYML)
+	initGitRepo(t, o.config.OnRampDir)
+	commitBlueprint(t, o, "gnbsim", testProfileYML)
+
+	lab, err := createProfile(t, o, "lab", "", "")
+	if err != nil {
+		t.Fatalf("create lab: %v", err)
+	}
+	if lab.Source != "store" || lab.Author != "bob" || lab.ReadOnly || lab.UpdatedAt == 0 {
+		t.Errorf("lab = %+v", lab)
+	}
+	if edge, err := createProfile(t, o, "edge", "file", "gnbsim"); err != nil || edge.Source != "file" {
+		t.Fatalf("create edge = %+v, %v", edge, err)
+	}
+
+	list, err := o.HandleListProfiles(t.Context(), nil)
+	if err != nil {
+		t.Fatalf("HandleListProfiles: %v", err)
+	}
+	var got []string
+	for _, p := range list.Body {
+		got = append(got, p.Name+"/"+p.Source)
+	}
+	if strings.Join(got, " ") != "edge/file gnbsim/blueprint lab/store" {
+		t.Errorf("profiles = %v", got)
+	}
+
+	_, err = createProfile(t, o, "lab", "", "")
+	wantStatus(t, err, 409)
+	_, err = createProfile(t, o, "gnbsim", "store", "")
+	wantStatus(t, err, 409)
+	in := &ProfileCreateInput{}
+	in.Body.Name, in.Body.Storage, in.Body.Overwrite = "gnbsim", "file", true
+	_, err = o.HandleCreateProfile(t.Context(), in)
+	wantStatus(t, err, 403)
+	in.Body.Name, in.Body.Storage = "lab", ""
+	if _, err := o.HandleCreateProfile(t.Context(), in); err != nil {
+		t.Errorf("overwrite lab: %v", err)
+	}
+
+	_, err = o.HandleDeleteProfile(t.Context(), &ProfileDeleteInput{Name: "gnbsim"})
+	wantStatus(t, err, 403)
+	rename := &ProfileRenameInput{Name: "gnbsim"}
+	rename.Body.NewName = "other"
+	_, err = o.HandleRenameProfile(t.Context(), rename)
+	wantStatus(t, err, 403)
+	if data, _ := os.ReadFile(profilePath(o.workspaces["default"], "gnbsim")); string(data) != testProfileYML {
+		t.Errorf("blueprint changed: %q", data)
+	}
+
+	// Lookups reject names that could escape vars/.
+	_, err = o.HandleGetProfile(t.Context(), &ProfileGetInput{Name: "../main"})
+	wantStatus(t, err, 404)
+}
+
+func TestProfiles_RenameDeleteActivate(t *testing.T) {
+	o := newTestProviderWithStore(t, testMainYML)
+	if _, err := createProfile(t, o, "lab", "store", ""); err != nil {
+		t.Fatalf("create lab: %v", err)
+	}
+	writeProfile(t, o, "edge", testProfileYML)
+
+	rename := &ProfileRenameInput{Name: "lab"}
+	rename.Body.NewName = "edge"
+	_, err := o.HandleRenameProfile(t.Context(), rename)
+	wantStatus(t, err, 409)
+	rename.Body.NewName = "site-a"
+	out, err := o.HandleRenameProfile(t.Context(), rename)
+	if err != nil || out.Body.Name != "site-a" || out.Body.Source != "store" {
+		t.Fatalf("rename lab = %+v, %v", out, err)
+	}
+	rename = &ProfileRenameInput{Name: "edge"}
+	rename.Body.NewName = "edge-2"
+	if _, err := o.HandleRenameProfile(t.Context(), rename); err != nil {
+		t.Fatalf("rename edge: %v", err)
+	}
+	if _, err := os.Stat(profilePath(o.workspaces["default"], "edge-2")); err != nil {
+		t.Errorf("renamed file: %v", err)
+	}
+
+	// A stored profile activates like a file and survives removal of vars/.
+	patchConfig(t, o, `{"core": {"data_iface": "ens99"}}`, "")
+	if _, err := o.HandleActivateProfile(t.Context(), &ProfileActivateInput{Name: "site-a", ProfileSourceParam: ProfileSourceParam{Source: "store"}}); err != nil {
+		t.Fatalf("activate site-a: %v", err)
+	}
+	data, _ := os.ReadFile(filepath.Join(o.config.OnRampDir, "vars", "main.yml"))
+	if string(data) != testMainYML {
+		t.Errorf("main.yml = %q", data)
+	}
+
+	if _, err := o.HandleDeleteProfile(t.Context(), &ProfileDeleteInput{Name: "site-a"}); err != nil {
+		t.Fatalf("delete site-a: %v", err)
+	}
+	if _, err := o.HandleDeleteProfile(t.Context(), &ProfileDeleteInput{Name: "edge-2"}); err != nil {
+		t.Fatalf("delete edge-2: %v", err)
+	}
+	_, err = o.HandleDeleteProfile(t.Context(), &ProfileDeleteInput{Name: "site-a"})
+	wantStatus(t, err, 404)
+	if list, _ := o.HandleListProfiles(t.Context(), nil); len(list.Body) != 0 {
+		t.Errorf("profiles after delete = %+v", list.Body)
+	}
+}
+
+func TestProfiles_BlueprintNames(t *testing.T) {
+	o := newTestProviderWithStore(t, testMainYML)
+	initGitRepo(t, o.config.OnRampDir)
+	commitBlueprint(t, o, "eNB", testProfileYML)
+	writeProfile(t, o, "lab two", testProfileYML)
+
+	list, err := o.HandleListProfiles(t.Context(), nil)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(list.Body) != 1 || list.Body[0].Name != "eNB" || list.Body[0].Source != "blueprint" {
+		t.Fatalf("profiles = %+v", list.Body)
+	}
+	for _, p := range list.Body {
+		if _, err := o.HandleGetProfile(t.Context(), &ProfileGetInput{Name: p.Name}); err != nil {
+			t.Errorf("get listed profile %s: %v", p.Name, err)
+		}
+	}
+	if _, err := o.HandleActivateProfile(t.Context(), &ProfileActivateInput{Name: "eNB"}); err != nil {
+		t.Fatalf("activate eNB: %v", err)
+	}
+
+	// Saved profiles still need the strict form.
+	_, err = createProfile(t, o, "My-eNB", "store", "eNB")
+	wantStatus(t, err, 422)
+	_, err = o.HandleGetProfile(t.Context(), &ProfileGetInput{Name: "../main"})
+	wantStatus(t, err, 404)
+}
+
+func TestProfiles_Diff(t *testing.T) {
+	o := newTestProviderWithStore(t, testMainYML)
+	writeProfile(t, o, "staging", testProfileYML)
+	if _, err := createProfile(t, o, "lab", "store", ""); err != nil {
+		t.Fatalf("create lab: %v", err)
+	}
+
+	out, err := o.HandleDiffProfile(t.Context(), &ProfileDiffInput{Name: "lab"})
+	if err != nil {
+		t.Fatalf("HandleDiffProfile: %v", err)
+	}
+	if out.Body.Unified != "" || len(out.Body.Changes) != 0 {
+		t.Errorf("diff against active = %+v", out.Body)
+	}
+
+	out, err = o.HandleDiffProfile(t.Context(), &ProfileDiffInput{Name: "lab", To: "staging"})
+	if err != nil {
+		t.Fatalf("HandleDiffProfile: %v", err)
+	}
+	if !strings.Contains(out.Body.Unified, "--- profile lab\n+++ profile staging\n") ||
+		!strings.Contains(out.Body.Unified, "+  data_iface: ens19\n") {
+		t.Errorf("unified diff:\n%s", out.Body.Unified)
+	}
+	found := false
+	for _, c := range out.Body.Changes {
+		if c.Path == "core.data_iface" && c.Old == "ens18" && c.New == "ens19" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("changes = %+v", out.Body.Changes)
+	}
+
+	_, err = o.HandleDiffProfile(t.Context(), &ProfileDiffInput{Name: "lab", To: "staging", ToSource: "store"})
+	wantStatus(t, err, 404)
+}
+
+func TestProfiles_NoStore(t *testing.T) {
+	o := newTestProvider(t, testMainYML)
+	p, err := createProfile(t, o, "lab", "", "")
+	if err != nil || p.Source != "file" {
+		t.Fatalf("create without store = %+v, %v", p, err)
+	}
+	_, err = createProfile(t, o, "lab2", "store", "")
+	wantStatus(t, err, 503)
+}
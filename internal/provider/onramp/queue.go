@@ -0,0 +1,132 @@
+package onramp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"github.com/bengrewell/aether-webui/internal/taskrunner"
+)
+
+// HandleGetQueue returns the pending task queue with positions and estimated
+// start times.
+func (o *OnRamp) HandleGetQueue(ctx context.Context, in *WorkspaceInput) (*QueueGetOutput, error) {
+	ws, err := o.workspace(ctx, in.workspaceName())
+	if err != nil {
+		return nil, err
+	}
+	return &QueueGetOutput{Body: toQueueStatus(ws.runner.Queue())}, nil
+}
+
+// HandleMoveQueueTask moves a pending task to a new position in the queue.
+func (o *OnRamp) HandleMoveQueueTask(ctx context.Context, in *QueueMoveInput) (*QueueUpdateOutput, error) {
+	ws, err := o.workspace(ctx, in.Workspace)
+	if err != nil {
+		return nil, err
+	}
+	qv, err := ws.runner.Move(in.ID, in.Body.Position)
+	if err != nil {
+		return nil, queueError(in.ID, err)
+	}
+	return &QueueUpdateOutput{Body: toQueueStatus(qv)}, nil
+}
+
+// HandlePrioritizeQueueTask moves a pending task to the front of the queue.
+func (o *OnRamp) HandlePrioritizeQueueTask(ctx context.Context, in *QueueTaskInput) (*QueueUpdateOutput, error) {
+	ws, err := o.workspace(ctx, in.Workspace)
+	if err != nil {
+		return nil, err
+	}
+	qv, err := ws.runner.Prioritize(in.ID)
+	if err != nil {
+		return nil, queueError(in.ID, err)
+	}
+	return &QueueUpdateOutput{Body: toQueueStatus(qv)}, nil
+}
+
+// HandleRemoveQueueTask removes a pending task from the queue. Its action
+// record is marked canceled by the task's OnComplete callback; deployments
+// that owned the task fail and cancel their remaining actions. An action
+// held for its before hooks is removed and its running hook canceled.
+func (o *OnRamp) HandleRemoveQueueTask(ctx context.Context, in *QueueTaskInput) (*QueueRemoveOutput, error) {
+	ws, err := o.workspace(ctx, in.Workspace)
+	if err != nil {
+		return nil, err
+	}
+	view, err := ws.runner.Get(in.ID)
+	if err != nil {
+		return nil, queueError(in.ID, err)
+	}
+	if view.Status != taskrunner.StatusPending {
+		return nil, queueError(in.ID, taskrunner.ErrNotPending)
+	}
+	if err := ws.runner.Cancel(in.ID); err != nil {
+		return nil, queueError(in.ID, err)
+	}
+	out := &QueueRemoveOutput{}
+	out.Body.Message = fmt.Sprintf("task %s removed from queue", in.ID)
+	return out, nil
+}
+
+// HandlePauseQueue stops queued tasks from starting. The running task, if
+// any, continues.
+func (o *OnRamp) HandlePauseQueue(ctx context.Context, in *WorkspaceInput) (*QueueUpdateOutput, error) {
+	ws, err := o.workspace(ctx, in.workspaceName())
+	if err != nil {
+		return nil, err
+	}
+	return &QueueUpdateOutput{Body: toQueueStatus(ws.runner.Pause())}, nil
+}
+
+// HandleResumeQueue releases a paused queue.
+func (o *OnRamp) HandleResumeQueue(ctx context.Context, in *WorkspaceInput) (*QueueUpdateOutput, error) {
+	ws, err := o.workspace(ctx, in.workspaceName())
+	if err != nil {
+		return nil, err
+	}
+	return &QueueUpdateOutput{Body: toQueueStatus(ws.runner.Resume())}, nil
+}
+
+// HandleDrainQueue cancels every pending task. The running task, if any, is
+// not affected.
+func (o *OnRamp) HandleDrainQueue(ctx context.Context, in *WorkspaceInput) (*QueueDrainOutput, error) {
+	ws, err := o.workspace(ctx, in.workspaceName())
+	if err != nil {
+		return nil, err
+	}
+	drained := ws.runner.Drain()
+	out := &QueueDrainOutput{}
+	out.Body.Canceled = make([]string, len(drained))
+	for i, v := range drained {
+		out.Body.Canceled[i] = v.ID
+	}
+	out.Body.Message = fmt.Sprintf("%d queued task(s) canceled", len(drained))
+	return out, nil
+}
+
+// toQueueStatus converts a runner queue snapshot to the API representation.
+func toQueueStatus(qv taskrunner.QueueView) QueueStatus {
+	qs := QueueStatus{
+		Paused: qv.Paused,
+		Depth:  len(qv.Tasks),
+		Tasks:  make([]OnRampTask, len(qv.Tasks)),
+	}
+	for i, v := range qv.Tasks {
+		qs.Tasks[i] = toOnRampTask(v, "", 0)
+	}
+	return qs
+}
+
+// queueError maps taskrunner errors to HTTP errors for queue operations.
+func queueError(id string, err error) error {
+	switch {
+	case errors.Is(err, taskrunner.ErrNotFound):
+		return huma.Error404NotFound("task not found", fmt.Errorf("no task with id %s", id))
+	case errors.Is(err, taskrunner.ErrNotPending), errors.Is(err, taskrunner.ErrNotRunning):
+		return huma.Error409Conflict(fmt.Sprintf("task %s is not queued", id))
+	default:
+		return huma.Error500InternalServerError("queue operation failed", err)
+	}
+}
@@ -0,0 +1,172 @@
+package onramp
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"github.com/bengrewell/aether-webui/internal/store"
+	"github.com/bengrewell/aether-webui/internal/taskrunner"
+)
+
+// submitBlocker occupies the provider's single runner slot until the test ends.
+func submitBlocker(t *testing.T, o *OnRamp) taskrunner.TaskView {
+	t.Helper()
+	v, err := o.runner.Submit(taskrunner.TaskSpec{Command: "sleep", Args: []string{"10"}})
+	if err != nil {
+		t.Fatalf("Submit blocker: %v", err)
+	}
+	t.Cleanup(func() { o.runner.Cancel(v.ID) })
+	return v
+}
+
+// submitQueuedAction inserts an action record and queues a task wired to the
+// standard action/state callbacks.
+func submitQueuedAction(t *testing.T, o *OnRamp, id, component, action string) {
+	t.Helper()
+	st := o.Store()
+	if err := st.InsertAction(t.Context(), store.ActionRecord{
+		ID:        id,
+		Component: component,
+		Action:    action,
+		Target:    "aether-" + component + "-" + action,
+		Status:    "pending",
+		ExitCode:  -1,
+		StartedAt: time.Now(),
+	}); err != nil {
+		t.Fatalf("InsertAction: %v", err)
+	}
+	v, err := o.runner.Submit(taskrunner.TaskSpec{
+		ID:         id,
+		Command:    "echo",
+		Labels:     map[string]string{"component": component, "action": action},
+		OnStart:    buildOnStart(st, o.Log(), store.DefaultWorkspace, id, component, action),
+		OnComplete: buildOnComplete(st, o.Log(), store.DefaultWorkspace, id, component, action),
+	})
+	if err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	if v.Status != taskrunner.StatusPending {
+		t.Fatalf("status = %q, want pending", v.Status)
+	}
+}
+
+func TestHandleGetQueue(t *testing.T) {
+	o := newTestProviderWithStore(t, "")
+	submitBlocker(t, o)
+	submitQueuedAction(t, o, "a1", "5gc", "install")
+	submitQueuedAction(t, o, "a2", "5gc", "uninstall")
+
+	out, err := o.HandleGetQueue(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("HandleGetQueue: %v", err)
+	}
+	if out.Body.Depth != 2 {
+		t.Fatalf("Depth = %d, want 2", out.Body.Depth)
+	}
+	if out.Body.Tasks[0].ID != "a1" || out.Body.Tasks[0].QueuePosition != 1 {
+		t.Errorf("first = %s@%d, want a1@1", out.Body.Tasks[0].ID, out.Body.Tasks[0].QueuePosition)
+	}
+	if out.Body.Tasks[1].Component != "5gc" || out.Body.Tasks[1].Action != "uninstall" {
+		t.Errorf("second = %s/%s, want 5gc/uninstall", out.Body.Tasks[1].Component, out.Body.Tasks[1].Action)
+	}
+}
+
+func TestHandlePrioritizeQueueTask(t *testing.T) {
+	o := newTestProviderWithStore(t, "")
+	submitBlocker(t, o)
+	submitQueuedAction(t, o, "a1", "5gc", "install")
+	submitQueuedAction(t, o, "a2", "5gc", "uninstall")
+
+	out, err := o.HandlePrioritizeQueueTask(context.Background(), &QueueTaskInput{ID: "a2"})
+	if err != nil {
+		t.Fatalf("HandlePrioritizeQueueTask: %v", err)
+	}
+	if out.Body.Tasks[0].ID != "a2" {
+		t.Errorf("front = %s, want a2", out.Body.Tasks[0].ID)
+	}
+
+	in := &QueueMoveInput{ID: "a2"}
+	in.Body.Position = 2
+	moved, err := o.HandleMoveQueueTask(context.Background(), in)
+	if err != nil {
+		t.Fatalf("HandleMoveQueueTask: %v", err)
+	}
+	if moved.Body.Tasks[1].ID != "a2" {
+		t.Errorf("position 2 = %s, want a2", moved.Body.Tasks[1].ID)
+	}
+}
+
+func TestHandleRemoveQueueTask_CancelsAction(t *testing.T) {
+	o := newTestProviderWithStore(t, "")
+	submitBlocker(t, o)
+	submitQueuedAction(t, o, "a1", "5gc", "uninstall")
+
+	if _, err := o.HandleRemoveQueueTask(context.Background(), &QueueTaskInput{ID: "a1"}); err != nil {
+		t.Fatalf("HandleRemoveQueueTask: %v", err)
+	}
+
+	rec, ok, err := o.Store().GetAction(t.Context(), "a1")
+	if err != nil || !ok {
+		t.Fatalf("GetAction: ok=%v err=%v", ok, err)
+	}
+	if rec.Status != "canceled" {
+		t.Errorf("action status = %q, want canceled", rec.Status)
+	}
+
+	// The task never ran, so the component state must be untouched.
+	if _, found, _ := o.Store().GetComponentState(t.Context(), "", "5gc"); found {
+		t.Error("component state should not be written for a removed queued task")
+	}
+}
+
+func TestHandleRemoveQueueTask_Running(t *testing.T) {
+	o := newTestProviderWithStore(t, "")
+	blocker := submitBlocker(t, o)
+
+	_, err := o.HandleRemoveQueueTask(context.Background(), &QueueTaskInput{ID: blocker.ID})
+	var se huma.StatusError
+	if !errors.As(err, &se) || se.GetStatus() != 409 {
+		t.Fatalf("err = %v, want 409", err)
+	}
+
+	_, err = o.HandleRemoveQueueTask(context.Background(), &QueueTaskInput{ID: "missing"})
+	if !errors.As(err, &se) || se.GetStatus() != 404 {
+		t.Fatalf("err = %v, want 404", err)
+	}
+}
+
+func TestHandleDrainQueue(t *testing.T) {
+	o := newTestProviderWithStore(t, "")
+	submitBlocker(t, o)
+	submitQueuedAction(t, o, "a1", "5gc", "install")
+	submitQueuedAction(t, o, "a2", "gnbsim", "install")
+
+	if _, err := o.HandlePauseQueue(context.Background(), nil); err != nil {
+		t.Fatalf("HandlePauseQueue: %v", err)
+	}
+	out, err := o.HandleDrainQueue(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("HandleDrainQueue: %v", err)
+	}
+	if len(out.Body.Canceled) != 2 {
+		t.Fatalf("canceled = %v, want 2 entries", out.Body.Canceled)
+	}
+	for _, id := range out.Body.Canceled {
+		rec, _, _ := o.Store().GetAction(t.Context(), id)
+		if rec.Status != "canceled" {
+			t.Errorf("action %s status = %q, want canceled", id, rec.Status)
+		}
+	}
+
+	resumed, err := o.HandleResumeQueue(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("HandleResumeQueue: %v", err)
+	}
+	if resumed.Body.Paused || resumed.Body.Depth != 0 {
+		t.Errorf("after resume paused=%v depth=%d, want false/0", resumed.Body.Paused, resumed.Body.Depth)
+	}
+}
@@ -67,4 +67,27 @@ func recoverStaleTasks(st store.Client, log *slog.Logger) {
 			}
 		}
 	}
+
+	// A deployment lock outliving a restart belongs to a deployment that was
+	// just marked failed above. Maintenance locks are left in place.
+	names := []string{store.DefaultWorkspace}
+	if list, err := st.ListWorkspaces(ctx); err != nil {
+		log.Error("failed to list workspaces", "error", err)
+	} else {
+		for _, w := range list {
+			names = append(names, w.Name)
+		}
+	}
+	for _, ws := range names {
+		lockName := store.ChangeLockFor(ws)
+		if l, ok, err := st.GetLock(ctx, lockName); err != nil {
+			log.Error("failed to check change lock", "workspace", ws, "error", err)
+		} else if ok && l.Kind == store.LockKindDeployment {
+			if _, _, err := st.BreakLock(ctx, lockName); err != nil {
+				log.Error("failed to release stale deployment lock", "workspace", ws, "deployment_id", l.HolderID, "error", err)
+			} else {
+				log.Warn("released stale deployment lock", "workspace", ws, "deployment_id", l.HolderID)
+			}
+		}
+	}
 }
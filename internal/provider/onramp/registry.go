@@ -0,0 +1,622 @@
+package onramp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	_ "embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/bengrewell/aether-webui/internal/store"
+)
+
+// Registry sources reported by componentRegistry.source.
+const (
+	registrySourceMakefile = "makefile" // actions discovered from the checkout's Makefile
+	registrySourceBuiltin  = "builtin"  // no Makefile; overlay actions used as they are
+)
+
+// builtinOverlay describes the components of the OnRamp releases this
+// server was written against. See components.yaml for the format.
+//
+//go:embed components.yaml
+var builtinOverlay []byte
+
+// overlayFile is the format of components.yaml and of the operator overlay
+// named by Config.ComponentsFile.
+type overlayFile struct {
+	Roles         []string            `yaml:"roles"`
+	IgnoreTargets []string            `yaml:"ignore_targets"`
+	Topology      map[string]roleRule `yaml:"topology"`
+	Components    []overlayComponent  `yaml:"components"`
+}
+
+// roleRule constrains how a node role may be assigned; see checkTopology.
+type roleRule struct {
+	Max        int      `yaml:"max"`        // most nodes with the role; 0 for no limit
+	Requires   []string `yaml:"requires"`   // roles some node must have when one has this role
+	Conflicts  []string `yaml:"conflicts"`  // roles a node with this role may not also have
+	Components []string `yaml:"components"` // components, one of which should be deployed
+}
+
+// overlayComponent is one component entry of an overlay. Unset fields leave
+// the value from an earlier overlay in place.
+type overlayComponent struct {
+	Name        string   `yaml:"name"`
+	Description string   `yaml:"description"`
+	Tier        *int     `yaml:"tier"`
+	Section     string   `yaml:"section"`
+	Blueprint   string   `yaml:"blueprint"`
+	KeepSection *bool    `yaml:"keep_section"`
+	Requires    []string `yaml:"requires"`
+	Roles       []string `yaml:"roles"`
+	Actions     []Action `yaml:"actions"`
+}
+
+// defaultTier is the install tier of components no overlay describes. It
+// places them after the core and platform services they usually need.
+const defaultTier = 3
+
+// clusterComponent collects aether-* targets that do not name a component,
+// such as aether-pingall.
+const clusterComponent = "cluster"
+
+// componentRegistry is the set of components and node roles a checkout
+// supports. It is built from the Makefile's aether-* targets and the
+// overlays, and is read-only once built.
+type componentRegistry struct {
+	components []Component
+	index      map[string]*Component
+	roles      []string
+	topology   map[string]roleRule
+	source     string
+
+	// stamps records every file the registry was built from, so a change to
+	// the Makefile or an overlay is noticed without a version change.
+	stamps map[string]fileStamp
+}
+
+// fileStamp identifies one version of a file; the zero value means absent.
+type fileStamp struct {
+	mod  time.Time
+	size int64
+}
+
+func statStamp(path string) fileStamp {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}
+	}
+	return fileStamp{mod: fi.ModTime(), size: fi.Size()}
+}
+
+// stale reports whether any file the registry was built from has changed.
+func (r *componentRegistry) stale() bool {
+	for path, s := range r.stamps {
+		if statStamp(path) != s {
+			return true
+		}
+	}
+	return false
+}
+
+// component looks up a component by name.
+func (r *componentRegistry) component(name string) (*Component, bool) {
+	c, ok := r.index[name]
+	return c, ok
+}
+
+// action looks up a component's action by name.
+func (r *componentRegistry) action(component, action string) (Action, bool) {
+	c, ok := r.index[component]
+	if !ok {
+		return Action{}, false
+	}
+	for _, a := range c.Actions {
+		if a.Name == action {
+			return a, true
+		}
+	}
+	return Action{}, false
+}
+
+// target looks up the target for a component/action pair, or "".
+func (r *componentRegistry) target(component, action string) string {
+	a, _ := r.action(component, action)
+	return a.Target
+}
+
+// tier returns a component's install tier.
+func (r *componentRegistry) tier(component string) int {
+	if c, ok := r.index[component]; ok {
+		return c.Tier
+	}
+	return defaultTier
+}
+
+// builtinRegistry is the registry described by the embedded overlay alone.
+// It serves callers that have no checkout, such as role validation before
+// the OnRamp provider is linked.
+var builtinRegistry = sync.OnceValue(func() *componentRegistry {
+	ov, err := parseOverlay(builtinOverlay)
+	if err != nil {
+		panic(fmt.Sprintf("onramp: embedded components.yaml: %v", err))
+	}
+	return buildRegistry(ov, nil, nil)
+})
+
+// DefaultRoles returns the node roles of the built-in component registry in
+// hosts.ini order.
+func DefaultRoles() []string {
+	return slices.Clone(builtinRegistry().roles)
+}
+
+// loadRegistry builds the registry for the checkout in dir. overlayPath, if
+// set, names an operator overlay merged over the embedded one.
+func loadRegistry(dir, overlayPath string) (*componentRegistry, error) {
+	ov, err := parseOverlay(builtinOverlay)
+	if err != nil {
+		return nil, fmt.Errorf("embedded components.yaml: %w", err)
+	}
+	stamps := make(map[string]fileStamp)
+	if overlayPath != "" {
+		data, err := os.ReadFile(overlayPath)
+		if err != nil {
+			return nil, fmt.Errorf("read component overlay: %w", err)
+		}
+		stamps[overlayPath] = statStamp(overlayPath)
+		user, err := parseOverlay(data)
+		if err != nil {
+			return nil, fmt.Errorf("parse %s: %w", overlayPath, err)
+		}
+		ov.merge(user)
+	}
+
+	makefile := filepath.Join(dir, "Makefile")
+	targets, files, err := makeTargets(makefile)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("read Makefile: %w", err)
+	}
+	for _, f := range files {
+		stamps[f] = statStamp(f)
+	}
+	// An absent Makefile is stamped too, so the registry is rebuilt once a
+	// clone or checkout creates it.
+	stamps[makefile] = statStamp(makefile)
+	if err != nil {
+		targets = nil
+	}
+
+	reg := buildRegistry(ov, targets, func(name string) bool {
+		_, err := os.Stat(filepath.Join(dir, "vars", "main-"+name+".yml"))
+		return err == nil
+	})
+	reg.stamps = stamps
+	return reg, nil
+}
+
+func parseOverlay(data []byte) (*overlayFile, error) {
+	var ov overlayFile
+	if err := yaml.Unmarshal(data, &ov); err != nil {
+		return nil, err
+	}
+	for i, c := range ov.Components {
+		if c.Name == "" {
+			return nil, fmt.Errorf("component %d has no name", i+1)
+		}
+		for _, a := range c.Actions {
+			if a.Name == "" {
+				return nil, fmt.Errorf("component %s has an action with no name", c.Name)
+			}
+		}
+	}
+	return &ov, nil
+}
+
+// merge applies next over ov. Components and their actions are matched by
+// name; anything new is appended. A role's topology rule replaces the one
+// before it.
+func (ov *overlayFile) merge(next *overlayFile) {
+	ov.Roles = appendUnique(ov.Roles, next.Roles...)
+	ov.IgnoreTargets = appendUnique(ov.IgnoreTargets, next.IgnoreTargets...)
+	for role, rule := range next.Topology {
+		if ov.Topology == nil {
+			ov.Topology = make(map[string]roleRule)
+		}
+		ov.Topology[role] = rule
+	}
+	for _, nc := range next.Components {
+		i := slices.IndexFunc(ov.Components, func(c overlayComponent) bool { return c.Name == nc.Name })
+		if i < 0 {
+			ov.Components = append(ov.Components, nc)
+			continue
+		}
+		c := &ov.Components[i]
+		if nc.Description != "" {
+			c.Description = nc.Description
+		}
+		if nc.Tier != nil {
+			c.Tier = nc.Tier
+		}
+		if nc.Section != "" {
+			c.Section = nc.Section
+		}
+		if nc.Blueprint != "" {
+			c.Blueprint = nc.Blueprint
+		}
+		if nc.KeepSection != nil {
+			c.KeepSection = nc.KeepSection
+		}
+		if nc.Requires != nil {
+			c.Requires = nc.Requires
+		}
+		if nc.Roles != nil {
+			c.Roles = nc.Roles
+		}
+		for _, na := range nc.Actions {
+			j := slices.IndexFunc(c.Actions, func(a Action) bool { return a.Name == na.Name })
+			if j < 0 {
+				c.Actions = append(c.Actions, na)
+				continue
+			}
+			a := &c.Actions[j]
+			if na.Description != "" {
+				a.Description = na.Description
+			}
+			if na.Target != "" {
+				a.Target = na.Target
+			}
+		}
+	}
+}
+
+// buildRegistry combines an overlay with the Makefile targets. With targets
+// nil the overlay's actions are used as they are. hasBlueprint reports
+// whether vars/main-<name>.yml exists, for components the overlay does not
+// describe; it may be nil.
+func buildRegistry(ov *overlayFile, targets []string, hasBlueprint func(string) bool) *componentRegistry {
+	reg := &componentRegistry{source: registrySourceBuiltin, topology: ov.Topology}
+
+	for _, oc := range ov.Components {
+		c := Component{
+			Name:        oc.Name,
+			Description: oc.Description,
+			Tier:        defaultTier,
+			Section:     oc.Section,
+			Blueprint:   oc.Blueprint,
+			Requires:    slices.Clone(oc.Requires),
+			Roles:       slices.Clone(oc.Roles),
+			Actions:     slices.Clone(oc.Actions),
+		}
+		if oc.Tier != nil {
+			c.Tier = *oc.Tier
+		}
+		if oc.KeepSection != nil {
+			c.KeepSection = *oc.KeepSection
+		}
+		reg.components = append(reg.components, c)
+	}
+
+	if targets != nil {
+		reg.source = registrySourceMakefile
+		reg.applyTargets(ov, targets, hasBlueprint)
+	}
+
+	// Overlay roles come first so hosts.ini keeps a stable section order;
+	// component roles not listed there follow in component order.
+	reg.roles = appendUnique(nil, ov.Roles...)
+	reg.reindex()
+	return reg
+}
+
+// reindex rebuilds the name index and appends component roles missing from
+// the role list.
+func (r *componentRegistry) reindex() {
+	r.index = make(map[string]*Component, len(r.components))
+	for i := range r.components {
+		r.index[r.components[i].Name] = &r.components[i]
+		r.roles = appendUnique(r.roles, r.components[i].Roles...)
+	}
+}
+
+// clone returns a copy that can be extended without affecting r.
+func (r *componentRegistry) clone() *componentRegistry {
+	c := *r
+	c.components = slices.Clone(r.components)
+	c.roles = slices.Clone(r.roles)
+	c.reindex()
+	return &c
+}
+
+// addCustom appends user-defined components. One whose name is taken by a
+// checkout component is skipped and reported through skip.
+func (r *componentRegistry) addCustom(custom []store.CustomComponent, skip func(name string)) {
+	for _, cc := range custom {
+		if _, ok := r.index[cc.Name]; ok {
+			skip(cc.Name)
+			continue
+		}
+		r.components = append(r.components, customComponent(cc))
+		r.reindex()
+	}
+}
+
+// applyTargets replaces the overlay's actions with the Makefile's targets.
+// A target keeps the overlay action that names it. Any other aether-<x>-<y>
+// target becomes action <y> of component <x> when <x> is a known component
+// or prefixes more than one target; the rest are cluster actions named
+// after the target.
+func (r *componentRegistry) applyTargets(ov *overlayFile, targets []string, hasBlueprint func(string) bool) {
+	available := make(map[string]bool, len(targets))
+	for _, t := range targets {
+		available[t] = true
+	}
+	for _, t := range ov.IgnoreTargets {
+		delete(available, t)
+	}
+
+	known := make(map[string]bool)
+	for i := range r.components {
+		c := &r.components[i]
+		known[c.Name] = true
+		kept := c.Actions[:0]
+		for _, a := range c.Actions {
+			if !isMakeAction(a) || available[a.Target] {
+				kept = append(kept, a)
+				delete(available, a.Target)
+			}
+		}
+		c.Actions = kept
+	}
+
+	prefixCount := make(map[string]int)
+	for _, t := range targets {
+		if prefix, _, ok := strings.Cut(strings.TrimPrefix(t, "aether-"), "-"); ok {
+			prefixCount[prefix]++
+		}
+	}
+
+	var discovered []string
+	for _, t := range targets {
+		if !available[t] {
+			continue
+		}
+		delete(available, t)
+		name := strings.TrimPrefix(t, "aether-")
+		comp, action := clusterComponent, name
+		if prefix, rest, ok := strings.Cut(name, "-"); ok && (known[prefix] || prefixCount[prefix] > 1) {
+			comp, action = prefix, rest
+		}
+		c, ok := r.find(comp)
+		if !ok {
+			r.components = append(r.components, r.discoveredComponent(comp, hasBlueprint))
+			c = &r.components[len(r.components)-1]
+			known[comp] = true
+			discovered = append(discovered, comp)
+		}
+		c.Actions = append(c.Actions, Action{
+			Name:        action,
+			Description: "Run make " + t,
+			Target:      t,
+		})
+	}
+
+	// Components the Makefile no longer provides are dropped.
+	r.components = slices.DeleteFunc(r.components, func(c Component) bool { return len(c.Actions) == 0 })
+
+	// Discovered components follow the described ones in name order.
+	sort.SliceStable(r.components, func(i, j int) bool {
+		di := slices.Contains(discovered, r.components[i].Name)
+		dj := slices.Contains(discovered, r.components[j].Name)
+		if di != dj {
+			return !di
+		}
+		return di && r.components[i].Name < r.components[j].Name
+	})
+}
+
+func (r *componentRegistry) find(name string) (*Component, bool) {
+	for i := range r.components {
+		if r.components[i].Name == name {
+			return &r.components[i], true
+		}
+	}
+	return nil, false
+}
+
+// discoveredComponent describes a component found only in the Makefile. A
+// vars/main-<name>.yml blueprint marks it as a compose component with its
+// own section and node role.
+func (r *componentRegistry) discoveredComponent(name string, hasBlueprint func(string) bool) Component {
+	c := Component{
+		Name:        name,
+		Description: name + " (from Makefile)",
+		Tier:        defaultTier,
+		Discovered:  true,
+	}
+	if name == clusterComponent {
+		c.Description = "Cluster-level operations"
+	}
+	if name != clusterComponent && hasBlueprint != nil && hasBlueprint(name) {
+		c.Section = name
+		c.Blueprint = "main-" + name + ".yml"
+		c.Roles = []string{name}
+	}
+	return c
+}
+
+// makeTargets returns the aether-* targets defined by a Makefile and the
+// files it read, following include directives with literal paths or globs.
+// Includes that use make variables cannot be resolved and are skipped.
+func makeTargets(path string) ([]string, []string, error) {
+	var targets, files []string
+	seen := make(map[string]bool)
+	visited := make(map[string]bool)
+
+	var read func(path string, top bool) error
+	read = func(path string, top bool) error {
+		if visited[path] {
+			return nil
+		}
+		visited[path] = true
+		data, err := os.ReadFile(path)
+		if err != nil {
+			if top {
+				return err
+			}
+			return nil // make itself tolerates missing -include files
+		}
+		files = append(files, path)
+		for _, line := range makeLines(data) {
+			if inc, ok := makeInclude(line); ok {
+				for _, pattern := range inc {
+					if strings.Contains(pattern, "$") {
+						continue
+					}
+					if !filepath.IsAbs(pattern) {
+						pattern = filepath.Join(filepath.Dir(path), pattern)
+					}
+					matches, _ := filepath.Glob(pattern)
+					for _, m := range matches {
+						if err := read(m, false); err != nil {
+							return err
+						}
+					}
+				}
+				continue
+			}
+			for _, t := range ruleTargets(line) {
+				if !seen[t] {
+					seen[t] = true
+					targets = append(targets, t)
+				}
+			}
+		}
+		return nil
+	}
+	if err := read(path, true); err != nil {
+		return nil, nil, err
+	}
+	return targets, files, nil
+}
+
+// makeLines splits a Makefile into logical lines, joining backslash
+// continuations and dropping comments and recipe lines.
+func makeLines(data []byte) []string {
+	var out []string
+	var cur strings.Builder
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		line := sc.Text()
+		if cur.Len() == 0 && strings.HasPrefix(line, "\t") {
+			continue
+		}
+		if before, ok := strings.CutSuffix(line, "\\"); ok {
+			cur.WriteString(before)
+			cur.WriteByte(' ')
+			continue
+		}
+		cur.WriteString(line)
+		logical := cur.String()
+		cur.Reset()
+		if i := strings.IndexByte(logical, '#'); i >= 0 {
+			logical = logical[:i]
+		}
+		if strings.TrimSpace(logical) != "" {
+			out = append(out, logical)
+		}
+	}
+	return out
+}
+
+// makeInclude reports the operands of an include, -include or sinclude line.
+func makeInclude(line string) ([]string, bool) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return nil, false
+	}
+	switch fields[0] {
+	case "include", "-include", "sinclude":
+		return fields[1:], true
+	}
+	return nil, false
+}
+
+// ruleTargets returns the aether-* targets a rule line defines. Variable
+// assignments, pattern rules and special targets such as .PHONY yield none.
+func ruleTargets(line string) []string {
+	i := strings.IndexByte(line, ':')
+	if i < 0 || strings.ContainsAny(line[:i], "=$") {
+		return nil
+	}
+	if rest := line[i+1:]; strings.HasPrefix(rest, "=") || strings.HasPrefix(rest, ":=") {
+		return nil
+	}
+	var out []string
+	for _, f := range strings.Fields(line[:i]) {
+		if strings.HasPrefix(f, "aether-") && len(f) > len("aether-") && !strings.Contains(f, "%") {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+func appendUnique(list []string, items ...string) []string {
+	for _, s := range items {
+		if !slices.Contains(list, s) {
+			list = append(list, s)
+		}
+	}
+	return list
+}
+
+// registry returns the component registry of a workspace: the checkout's
+// components followed by the workspace's custom components. It is rebuilt
+// after a version change or a custom component edit, and whenever the
+// Makefile or an overlay changes on disk. If part of it cannot be loaded the
+// rest is used and the build is retried on the next call.
+func (o *OnRamp) registry(ws *workspace) *componentRegistry {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if ws.reg != nil && !ws.reg.stale() {
+		return ws.reg
+	}
+	log := o.Log().With("workspace", ws.name)
+	cache := true
+	reg, err := loadRegistry(ws.config.OnRampDir, o.config.ComponentsFile)
+	if err != nil {
+		log.Warn("failed to build component registry; using built-in components", "error", err)
+		reg, cache = builtinRegistry().clone(), false
+	}
+	custom, err := o.listCustomComponents(ws.name)
+	if err != nil {
+		log.Warn("failed to load custom components", "error", err)
+		cache = false
+	}
+	reg.addCustom(custom, func(name string) {
+		log.Warn("custom component shadowed by an OnRamp component; ignoring it", "component", name)
+	})
+	if cache {
+		ws.reg = reg
+	}
+	return reg
+}
+
+// Roles returns the node roles the workspace's checkout supports, in
+// hosts.ini order. The nodes provider validates role assignments with it.
+func (o *OnRamp) Roles(ctx context.Context, workspace string) ([]string, error) {
+	ws, err := o.workspace(ctx, workspace)
+	if err != nil {
+		return nil, err
+	}
+	return slices.Clone(o.registry(ws).roles), nil
+}
@@ -0,0 +1,245 @@
+package onramp
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// testMakefile defines part of the OnRamp targets plus a component the
+// built-in overlay does not know, the latter from an included file.
+const testMakefile = `.PHONY: aether-k8s-install aether-k8s-uninstall
+ONRAMP_ROOT ?= $(shell pwd)
+include mk/*.mk
+-include $(ONRAMP_ROOT)/local.mk
+
+aether-pingall:
+	ansible-playbook pingall.yml
+
+aether-k8s-install: \
+		aether-pingall
+	ansible-playbook k8s-install.yml
+aether-k8s-uninstall:
+	ansible-playbook k8s-uninstall.yml  # aether-bogus: in a recipe
+aether-5gc-install aether-5gc-uninstall:
+	ansible-playbook 5gc.yml
+aether-%-debug:
+	echo $*
+`
+
+const testFooMakefile = `aether-foo-install:
+	ansible-playbook foo-install.yml
+aether-foo-uninstall:
+	ansible-playbook foo-uninstall.yml
+aether-backup:
+	ansible-playbook backup.yml
+`
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+}
+
+func actionNames(c *Component) []string {
+	var out []string
+	for _, a := range c.Actions {
+		out = append(out, a.Name)
+	}
+	return out
+}
+
+func TestLoadRegistry_Makefile(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "Makefile"), testMakefile)
+	writeTestFile(t, filepath.Join(dir, "mk", "foo.mk"), testFooMakefile)
+	writeTestFile(t, filepath.Join(dir, "vars", "main-foo.yml"), "foo:\n  enabled: true\n")
+
+	reg, err := loadRegistry(dir, "")
+	if err != nil {
+		t.Fatalf("loadRegistry: %v", err)
+	}
+	if reg.source != registrySourceMakefile {
+		t.Errorf("source = %q, want %q", reg.source, registrySourceMakefile)
+	}
+
+	var names []string
+	for _, c := range reg.components {
+		names = append(names, c.Name)
+	}
+	// Described components keep overlay order; those missing from the
+	// Makefile are dropped and discovered ones follow.
+	if want := []string{"k8s", "5gc", "cluster", "foo"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("components = %v, want %v", names, want)
+	}
+
+	k8s, _ := reg.component("k8s")
+	if got := actionNames(k8s); !reflect.DeepEqual(got, []string{"install", "uninstall"}) {
+		t.Errorf("k8s actions = %v", got)
+	}
+	if k8s.Description != "Kubernetes (RKE2) cluster lifecycle" || k8s.Tier != 0 || k8s.Discovered {
+		t.Errorf("k8s = %+v, want overlay description and tier", k8s)
+	}
+	fivegc, _ := reg.component("5gc")
+	if got := actionNames(fivegc); !reflect.DeepEqual(got, []string{"install", "uninstall"}) {
+		t.Errorf("5gc actions = %v, want reset dropped", got)
+	}
+
+	cluster, _ := reg.component("cluster")
+	if got := actionNames(cluster); !reflect.DeepEqual(got, []string{"pingall", "backup"}) {
+		t.Errorf("cluster actions = %v", got)
+	}
+	if got := reg.target("cluster", "backup"); got != "aether-backup" {
+		t.Errorf("cluster/backup target = %q", got)
+	}
+
+	foo, ok := reg.component("foo")
+	if !ok {
+		t.Fatal("foo not discovered")
+	}
+	if !foo.Discovered || foo.Tier != defaultTier || foo.Section != "foo" || foo.Blueprint != "main-foo.yml" {
+		t.Errorf("foo = %+v", foo)
+	}
+	if got := reg.target("foo", "install"); got != "aether-foo-install" {
+		t.Errorf("foo/install target = %q", got)
+	}
+	if want := []string{"master", "worker", "gnbsim", "oai", "ueransim", "srsran", "oscric", "n3iwf", "foo"}; !reflect.DeepEqual(reg.roles, want) {
+		t.Errorf("roles = %v, want %v", reg.roles, want)
+	}
+	if got := rolesToComponents(reg, []string{"foo", "worker"}); !reflect.DeepEqual(got, []string{"foo"}) {
+		t.Errorf("rolesToComponents(foo, worker) = %v", got)
+	}
+}
+
+func TestLoadRegistry_NoMakefile(t *testing.T) {
+	reg, err := loadRegistry(t.TempDir(), "")
+	if err != nil {
+		t.Fatalf("loadRegistry: %v", err)
+	}
+	if reg.source != registrySourceBuiltin {
+		t.Errorf("source = %q, want %q", reg.source, registrySourceBuiltin)
+	}
+	if !reflect.DeepEqual(reg.components, builtinRegistry().components) {
+		t.Error("registry without a Makefile differs from the built-in one")
+	}
+}
+
+func TestLoadRegistry_Overlay(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "Makefile"), testMakefile)
+	writeTestFile(t, filepath.Join(dir, "mk", "foo.mk"), testFooMakefile)
+	overlay := filepath.Join(t.TempDir(), "components.yaml")
+	writeTestFile(t, overlay, `roles: [edge]
+ignore_targets: [aether-backup]
+components:
+  - name: foo
+    description: Foo service
+    tier: 1
+    requires: [k8s]
+    roles: [edge]
+    actions:
+      - {name: deploy, description: Deploy Foo, target: aether-foo-install}
+  - name: k8s
+    tier: 5
+    actions:
+      - {name: install, description: Install RKE2}
+`)
+
+	reg, err := loadRegistry(dir, overlay)
+	if err != nil {
+		t.Fatalf("loadRegistry: %v", err)
+	}
+	foo, ok := reg.component("foo")
+	if !ok {
+		t.Fatal("foo missing")
+	}
+	if foo.Discovered || foo.Description != "Foo service" || foo.Tier != 1 {
+		t.Errorf("foo = %+v", foo)
+	}
+	if got := actionNames(foo); !reflect.DeepEqual(got, []string{"deploy", "uninstall"}) {
+		t.Errorf("foo actions = %v", got)
+	}
+	k8s, _ := reg.component("k8s")
+	if k8s.Tier != 5 || k8s.Actions[0].Description != "Install RKE2" || k8s.Actions[0].Target != "aether-k8s-install" {
+		t.Errorf("k8s = %+v", k8s)
+	}
+	if got := reg.target("cluster", "backup"); got != "" {
+		t.Errorf("ignored target exposed as cluster/backup")
+	}
+	if reg.roles[len(reg.roles)-1] != "edge" {
+		t.Errorf("roles = %v, want edge appended", reg.roles)
+	}
+
+	writeTestFile(t, overlay, "components: [{description: nameless}]\n")
+	if _, err := loadRegistry(dir, overlay); err == nil {
+		t.Error("expected error for component without a name")
+	}
+}
+
+func TestRegistryReload(t *testing.T) {
+	o := newTestProvider(t, "")
+	ws := o.defaultWorkspace()
+	makefile := filepath.Join(o.config.OnRampDir, "Makefile")
+
+	if reg := o.registry(ws); reg.source != registrySourceBuiltin {
+		t.Fatalf("source = %q before clone", reg.source)
+	}
+
+	writeTestFile(t, makefile, testMakefile)
+	reg := o.registry(ws)
+	if reg.source != registrySourceMakefile {
+		t.Fatalf("source = %q after the Makefile appeared", reg.source)
+	}
+	if o.registry(ws) != reg {
+		t.Error("unchanged checkout rebuilt the registry")
+	}
+
+	writeTestFile(t, makefile, testMakefile+"aether-5gc-reset:\n\tansible-playbook reset.yml\n")
+	reg = o.registry(ws)
+	if got := reg.target("5gc", "reset"); got != "aether-5gc-reset" {
+		t.Errorf("5gc/reset target = %q after Makefile change", got)
+	}
+
+	ws.setVersion("v2.1.0")
+	if o.registry(ws) == reg {
+		t.Error("version change kept the old registry")
+	}
+
+	// Handlers see the checkout's registry.
+	out, err := o.HandleListComponents(t.Context(), nil)
+	if err != nil {
+		t.Fatalf("HandleListComponents: %v", err)
+	}
+	if len(out.Body) != 3 {
+		t.Errorf("listed %d components, want 3", len(out.Body))
+	}
+	_, err = o.HandleGetComponent(t.Context(), &ComponentGetInput{Component: "gnbsim"})
+	wantStatus(t, err, 404)
+}
+
+func TestRuleTargets(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{"aether-k8s-install: aether-pingall", []string{"aether-k8s-install"}},
+		{"aether-a aether-b:", []string{"aether-a", "aether-b"}},
+		{"aether-x:: y", []string{"aether-x"}},
+		{".PHONY: aether-k8s-install", nil},
+		{"aether-%-install:", nil},
+		{"TARGET := aether-k8s-install", nil},
+		{"aether-var ?= x:y", nil},
+		{"aether-simple:= value", nil},
+		{"aether-:", nil},
+	}
+	for _, tt := range tests {
+		if got := ruleTargets(tt.line); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ruleTargets(%q) = %v, want %v", tt.line, got, tt.want)
+		}
+	}
+}
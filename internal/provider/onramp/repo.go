@@ -12,7 +12,8 @@ import (
 
 // ensureRepo clones the OnRamp repo if it does not exist, checks out the
 // pinned version, ensures submodules are initialized, and validates that
-// expected files are present.
+// expected files are present. A checkout imported from an offline bundle is
+// already present, so no step needs the remote.
 func ensureRepo(cfg Config, log *slog.Logger) error {
 	if err := cloneIfMissing(cfg, log); err != nil {
 		return err
@@ -82,6 +83,9 @@ func checkoutVersion(cfg Config, log *slog.Logger) error {
 //
 // After checkout, validateSubmodules confirms every submodule directory has
 // content. If any are empty the provider enters degraded mode.
+//
+// Checkouts imported from an offline bundle never fetch: the submodule
+// objects must already be in the bundle.
 func ensureSubmodules(cfg Config, log *slog.Logger) error {
 	log.Info("ensuring submodules are initialized", "dir", cfg.OnRampDir)
 
@@ -89,7 +93,11 @@ func ensureSubmodules(cfg Config, log *slog.Logger) error {
 		return fmt.Errorf("submodule sync: %w", err)
 	}
 
-	if err := gitRun(cfg.OnRampDir, "submodule", "update", "--init", "--recursive"); err != nil {
+	update := []string{"submodule", "update", "--init", "--recursive"}
+	if readProvenance(cfg.OnRampDir) != nil {
+		update = append(update, "--no-fetch")
+	}
+	if err := gitRun(cfg.OnRampDir, update...); err != nil {
 		return fmt.Errorf("submodule update: %w", err)
 	}
 
@@ -103,50 +111,70 @@ func ensureSubmodules(cfg Config, log *slog.Logger) error {
 // validateSubmodules confirms each registered submodule directory contains
 // checked-out content (at least one non-.git entry).
 func validateSubmodules(dir string, log *slog.Logger) error {
+	subs, err := listSubmodules(dir)
+	if err != nil {
+		return err
+	}
+	for _, sub := range subs {
+		absPath := filepath.Join(dir, sub.path)
+
+		entries, err := os.ReadDir(absPath)
+		if err != nil {
+			return fmt.Errorf("submodule %s: directory missing: %w", sub.path, err)
+		}
+
+		contentCount := 0
+		for _, e := range entries {
+			if e.Name() != ".git" {
+				contentCount++
+			}
+		}
+		if contentCount == 0 {
+			return fmt.Errorf("submodule %s: no checked-out content (clone may have failed)", sub.path)
+		}
+		log.Debug("submodule validated", "path", sub.path, "files", contentCount)
+	}
+	return nil
+}
+
+// submodule is one entry of a .gitmodules file.
+type submodule struct {
+	name string
+	path string
+}
+
+// listSubmodules returns the submodules registered in dir's .gitmodules, in
+// file order. None without the file.
+func listSubmodules(dir string) ([]submodule, error) {
 	gitmodules := filepath.Join(dir, ".gitmodules")
 	if _, err := os.Stat(gitmodules); errors.Is(err, os.ErrNotExist) {
-		return nil
+		return nil, nil
 	} else if err != nil {
-		return fmt.Errorf("stat .gitmodules: %w", err)
+		return nil, fmt.Errorf("stat .gitmodules: %w", err)
 	}
 
 	out, err := gitOutput(dir, "config", "--file", ".gitmodules", "--get-regexp", `submodule\..*\.path`)
 	if err != nil {
 		var exitErr *exec.ExitError
 		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
-			return nil
+			return nil, nil
 		}
-		return fmt.Errorf("parse .gitmodules: %w", err)
+		return nil, fmt.Errorf("parse .gitmodules: %w", err)
 	}
 	if out == "" {
-		return nil
+		return nil, nil
 	}
 
+	var subs []submodule
 	for _, line := range strings.Split(out, "\n") {
 		parts := strings.SplitN(strings.TrimSpace(line), " ", 2)
 		if len(parts) != 2 {
 			continue
 		}
-		subPath := parts[1]
-		absPath := filepath.Join(dir, subPath)
-
-		entries, err := os.ReadDir(absPath)
-		if err != nil {
-			return fmt.Errorf("submodule %s: directory missing: %w", subPath, err)
-		}
-
-		contentCount := 0
-		for _, e := range entries {
-			if e.Name() != ".git" {
-				contentCount++
-			}
-		}
-		if contentCount == 0 {
-			return fmt.Errorf("submodule %s: no checked-out content (clone may have failed)", subPath)
-		}
-		log.Debug("submodule validated", "path", subPath, "files", contentCount)
+		name := strings.TrimSuffix(strings.TrimPrefix(parts[0], "submodule."), ".path")
+		subs = append(subs, submodule{name: name, path: parts[1]})
 	}
-	return nil
+	return subs, nil
 }
 
 // validateRepo confirms the OnRamp directory contains the expected Makefile
@@ -171,10 +199,17 @@ func gitRun(dir string, args ...string) error {
 
 // gitOutput executes a git command inside dir and returns trimmed stdout.
 func gitOutput(dir string, args ...string) (string, error) {
+	out, err := gitRaw(dir, args...)
+	return strings.TrimSpace(out), err
+}
+
+// gitRaw executes a git command inside dir and returns stdout unmodified,
+// for output where leading whitespace is significant.
+func gitRaw(dir string, args ...string) (string, error) {
 	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
 	out, err := cmd.Output()
 	if err != nil {
 		return "", err
 	}
-	return strings.TrimSpace(string(out)), nil
+	return string(out), nil
 }
@@ -0,0 +1,470 @@
+package onramp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/danielgtaylor/huma/v2"
+	"gopkg.in/yaml.v3"
+
+	"github.com/bengrewell/aether-webui/internal/store"
+)
+
+// diffContext is the number of unchanged lines shown around each change in
+// a unified diff.
+const diffContext = 3
+
+// VarsChange describes a write to a workspace's vars/main.yml for the
+// config history.
+type VarsChange struct {
+	Workspace string // empty means the default workspace
+	Dir       string // OnRamp checkout of the workspace
+	Source    string // operation making the change: patch, profile, compose, ...
+	Note      string
+	Author    string
+}
+
+// varsLocks holds a mutex for each OnRamp checkout, keyed by its cleaned
+// path. See LockVars.
+var varsLocks sync.Map
+
+// LockVars locks vars/main.yml of the checkout at dir and returns the unlock
+// function. Every writer holds it across its read, precondition checks and
+// SaveVars, so a concurrent change cannot be lost or slip past an ETag the
+// client already checked.
+func LockVars(dir string) func() {
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	mu, _ := varsLocks.LoadOrStore(filepath.Clean(dir), &sync.Mutex{})
+	mu.(*sync.Mutex).Lock()
+	return mu.(*sync.Mutex).Unlock
+}
+
+// SaveVars writes data to vars/main.yml and records it as a config
+// revision. If the file on disk does not match the latest revision, it was
+// edited outside the API and is recorded first so a rollback can return to
+// it. Writes that do not change the file add no revision and return the
+// latest one. Without a store the file is written unrecorded.
+func SaveVars(ctx context.Context, st store.Client, ch VarsChange, data []byte) (store.ConfigRevision, error) {
+	path := filepath.Join(ch.Dir, "vars", "main.yml")
+	if st.Path() == "" {
+		return store.ConfigRevision{}, os.WriteFile(path, data, 0o644)
+	}
+
+	prev, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return store.ConfigRevision{}, err
+	}
+	latest, ok, err := st.LatestConfigRevision(ctx, ch.Workspace)
+	if err != nil {
+		return store.ConfigRevision{}, fmt.Errorf("load latest config revision: %w", err)
+	}
+	if prev != nil && (!ok || latest.Hash != contentHash(prev)) {
+		note := "edited outside the API"
+		if !ok {
+			note = "content before the first recorded change"
+		}
+		if latest, err = st.InsertConfigRevision(ctx, store.ConfigRevision{
+			Workspace: ch.Workspace,
+			Source:    "external",
+			Note:      note,
+			Hash:      contentHash(prev),
+			Content:   prev,
+		}); err != nil {
+			return store.ConfigRevision{}, fmt.Errorf("record config revision: %w", err)
+		}
+		ok = true
+	}
+
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return store.ConfigRevision{}, err
+	}
+	if ok && latest.Hash == contentHash(data) {
+		return latest, nil
+	}
+	rev, err := st.InsertConfigRevision(ctx, store.ConfigRevision{
+		Workspace: ch.Workspace,
+		Source:    ch.Source,
+		Note:      ch.Note,
+		Author:    ch.Author,
+		Hash:      contentHash(data),
+		Content:   data,
+	})
+	if err != nil {
+		return store.ConfigRevision{}, fmt.Errorf("record config revision: %w", err)
+	}
+	return rev, nil
+}
+
+// saveVars writes a workspace's vars/main.yml through SaveVars.
+func (o *OnRamp) saveVars(ctx context.Context, ws *workspace, data []byte, source, note, author string) error {
+	_, err := SaveVars(ctx, o.Store(), VarsChange{
+		Workspace: ws.name,
+		Dir:       ws.config.OnRampDir,
+		Source:    source,
+		Note:      note,
+		Author:    author,
+	}, data)
+	return err
+}
+
+func contentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// ---------------------------------------------------------------------------
+// Handlers
+// ---------------------------------------------------------------------------
+
+// HandleListConfigRevisions returns a workspace's config history, newest
+// first, without file contents.
+func (o *OnRamp) HandleListConfigRevisions(ctx context.Context, in *WorkspaceInput) (*ConfigRevisionListOutput, error) {
+	ws, err := o.workspace(ctx, in.workspaceName())
+	if err != nil {
+		return nil, err
+	}
+	st, err := o.requireStore("config history is")
+	if err != nil {
+		return nil, err
+	}
+	revs, err := st.ListConfigRevisions(ctx, ws.name)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("failed to list config revisions", err)
+	}
+	items := make([]ConfigRevision, 0, len(revs))
+	for _, r := range revs {
+		items = append(items, toConfigRevision(r))
+	}
+	return &ConfigRevisionListOutput{Body: items}, nil
+}
+
+// HandleGetConfigRevision returns a single revision including its content.
+func (o *OnRamp) HandleGetConfigRevision(ctx context.Context, in *ConfigRevisionGetInput) (*ConfigRevisionGetOutput, error) {
+	ws, err := o.workspace(ctx, in.Workspace)
+	if err != nil {
+		return nil, err
+	}
+	rev, err := o.loadConfigRevision(ctx, ws, in.ID)
+	if err != nil {
+		return nil, err
+	}
+	out := toConfigRevision(rev)
+	out.Content = string(rev.Content)
+	return &ConfigRevisionGetOutput{Body: out}, nil
+}
+
+// HandleDiffConfigRevision compares a revision with another revision or, when
+// no target is given, with the current vars/main.yml.
+func (o *OnRamp) HandleDiffConfigRevision(ctx context.Context, in *ConfigRevisionDiffInput) (*ConfigRevisionDiffOutput, error) {
+	ws, err := o.workspace(ctx, in.Workspace)
+	if err != nil {
+		return nil, err
+	}
+	from, err := o.loadConfigRevision(ctx, ws, in.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	toName := "vars/main.yml"
+	var toContent []byte
+	if in.To != 0 {
+		to, err := o.loadConfigRevision(ctx, ws, in.To)
+		if err != nil {
+			return nil, err
+		}
+		toName = fmt.Sprintf("revision %d", to.ID)
+		toContent = to.Content
+	} else {
+		toContent, err = os.ReadFile(filepath.Join(ws.config.OnRampDir, "vars", "main.yml"))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, huma.Error500InternalServerError("failed to read config", err)
+		}
+	}
+
+	return &ConfigRevisionDiffOutput{Body: ConfigRevisionDiff{
+		From:    from.ID,
+		To:      in.To,
+		Unified: unifiedDiff(fmt.Sprintf("revision %d", from.ID), toName, string(from.Content), string(toContent)),
+		Changes: structuralDiff(from.Content, toContent),
+	}}, nil
+}
+
+// HandleRollbackConfig restores vars/main.yml to a previous revision. The
+// rollback is itself recorded, so it can be undone the same way.
+func (o *OnRamp) HandleRollbackConfig(ctx context.Context, in *ConfigRevisionRollbackInput) (*ConfigRevisionRollbackOutput, error) {
+	ws, err := o.workspace(ctx, in.Workspace)
+	if err != nil {
+		return nil, err
+	}
+	if err := o.CheckChangeLock(ctx, ws.name); err != nil {
+		return nil, err
+	}
+	rev, err := o.loadConfigRevision(ctx, ws, in.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer LockVars(ws.config.OnRampDir)()
+	saved, err := SaveVars(ctx, o.Store(), VarsChange{
+		Workspace: ws.name,
+		Dir:       ws.config.OnRampDir,
+		Source:    "rollback",
+		Note:      fmt.Sprintf("restored revision %d", rev.ID),
+		Author:    in.Author,
+	}, rev.Content)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("failed to write config", err)
+	}
+	o.Log().Info("config rolled back", "workspace", ws.name, "revision", rev.ID, "author", in.Author)
+	return &ConfigRevisionRollbackOutput{Body: toConfigRevision(saved)}, nil
+}
+
+// loadConfigRevision fetches a revision of the workspace, returning a 404 if
+// it does not exist there.
+func (o *OnRamp) loadConfigRevision(ctx context.Context, ws *workspace, id int64) (store.ConfigRevision, error) {
+	st, err := o.requireStore("config history is")
+	if err != nil {
+		return store.ConfigRevision{}, err
+	}
+	rev, ok, err := st.GetConfigRevision(ctx, ws.name, id)
+	if err != nil {
+		return store.ConfigRevision{}, huma.Error500InternalServerError("failed to load config revision", err)
+	}
+	if !ok {
+		return store.ConfigRevision{}, huma.Error404NotFound("config revision not found",
+			fmt.Errorf("no revision %d in workspace %s", id, ws.name))
+	}
+	return rev, nil
+}
+
+func toConfigRevision(r store.ConfigRevision) ConfigRevision {
+	return ConfigRevision{
+		ID:        r.ID,
+		Workspace: r.Workspace,
+		Source:    r.Source,
+		Note:      r.Note,
+		Author:    r.Author,
+		Hash:      r.Hash,
+		CreatedAt: r.CreatedAt.Unix(),
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Diffs
+// ---------------------------------------------------------------------------
+
+// PreviewVars describes replacing the vars file content current with next,
+// for endpoints that report a change instead of writing it.
+func PreviewVars(current, next []byte) ConfigPreview {
+	return ConfigPreview{
+		Content: string(next),
+		Unified: unifiedDiff("vars/main.yml", "vars/main.yml (preview)", string(current), string(next)),
+		Changes: structuralDiff(current, next),
+	}
+}
+
+// structuralDiff compares two YAML documents key by key. Content that does
+// not parse as a mapping is treated as empty.
+func structuralDiff(a, b []byte) []ConfigChange {
+	changes := []ConfigChange{}
+	diffValues("", parseYAMLMap(a), parseYAMLMap(b), &changes)
+	return changes
+}
+
+func parseYAMLMap(data []byte) map[string]any {
+	var v any
+	if err := yaml.Unmarshal(data, &v); err != nil {
+		return map[string]any{}
+	}
+	m, ok := normalizeYAML(v).(map[string]any)
+	if !ok {
+		return map[string]any{}
+	}
+	return m
+}
+
+// normalizeYAML converts maps with non-string keys, such as the numbered
+// srsran servers, to string-keyed maps so they can be compared and encoded
+// as JSON.
+func normalizeYAML(v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		for k, e := range t {
+			t[k] = normalizeYAML(e)
+		}
+		return t
+	case map[any]any:
+		m := make(map[string]any, len(t))
+		for k, e := range t {
+			m[fmt.Sprint(k)] = normalizeYAML(e)
+		}
+		return m
+	case []any:
+		for i, e := range t {
+			t[i] = normalizeYAML(e)
+		}
+		return t
+	default:
+		return v
+	}
+}
+
+func diffValues(path string, a, b map[string]any, out *[]ConfigChange) {
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		p := k
+		if path != "" {
+			p = path + "." + k
+		}
+		av, inA := a[k]
+		bv, inB := b[k]
+		switch {
+		case !inA:
+			*out = append(*out, ConfigChange{Path: p, Kind: "added", New: bv})
+		case !inB:
+			*out = append(*out, ConfigChange{Path: p, Kind: "removed", Old: av})
+		case reflect.DeepEqual(av, bv):
+		default:
+			am, aok := av.(map[string]any)
+			bm, bok := bv.(map[string]any)
+			if aok && bok {
+				diffValues(p, am, bm, out)
+			} else {
+				*out = append(*out, ConfigChange{Path: p, Kind: "changed", Old: av, New: bv})
+			}
+		}
+	}
+}
+
+// unifiedDiff returns a unified diff of two texts, or "" if they are equal.
+// vars files are small, so a quadratic LCS is fine.
+func unifiedDiff(aName, bName, a, b string) string {
+	al, bl := splitLines(a), splitLines(b)
+	lcs := lcsTable(al, bl)
+
+	type edit struct {
+		op   byte // ' ', '-', or '+'
+		text string
+		a, b int // 1-based line numbers before this edit is applied
+	}
+	var edits []edit
+	changed := false
+	i, j := 0, 0
+	for i < len(al) || j < len(bl) {
+		switch {
+		case i < len(al) && j < len(bl) && al[i] == bl[j]:
+			edits = append(edits, edit{' ', al[i], i + 1, j + 1})
+			i++
+			j++
+		case j < len(bl) && (i == len(al) || lcs[i][j+1] > lcs[i+1][j]):
+			edits = append(edits, edit{'+', bl[j], i + 1, j + 1})
+			changed = true
+			j++
+		default:
+			edits = append(edits, edit{'-', al[i], i + 1, j + 1})
+			changed = true
+			i++
+		}
+	}
+	if !changed {
+		return ""
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", aName, bName)
+	for k := 0; k < len(edits); {
+		if edits[k].op == ' ' {
+			k++
+			continue
+		}
+		start := max(0, k-diffContext)
+		end := k
+		for end < len(edits) {
+			if edits[end].op != ' ' {
+				end++
+				continue
+			}
+			run := end
+			for run < len(edits) && edits[run].op == ' ' {
+				run++
+			}
+			if run == len(edits) || run-end > 2*diffContext {
+				end = min(len(edits), end+diffContext)
+				break
+			}
+			end = run
+		}
+
+		hunk := edits[start:end]
+		aCount, bCount := 0, 0
+		for _, e := range hunk {
+			if e.op != '+' {
+				aCount++
+			}
+			if e.op != '-' {
+				bCount++
+			}
+		}
+		aStart, bStart := hunk[0].a, hunk[0].b
+		if aCount == 0 {
+			aStart--
+		}
+		if bCount == 0 {
+			bStart--
+		}
+		fmt.Fprintf(&sb, "@@ -%d,%d +%d,%d @@\n", aStart, aCount, bStart, bCount)
+		for _, e := range hunk {
+			sb.WriteByte(e.op)
+			sb.WriteString(e.text)
+			sb.WriteByte('\n')
+		}
+		k = end
+	}
+	return sb.String()
+}
+
+// lcsTable returns a table whose [i][j] entry is the length of the longest
+// common subsequence of a[i:] and b[j:].
+func lcsTable(a, b []string) [][]int {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	return lcs
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
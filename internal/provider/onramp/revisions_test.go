@@ -0,0 +1,167 @@
+package onramp
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func patchConfig(t *testing.T, o *OnRamp, body, author string) {
+	t.Helper()
+	in := &ConfigPatchInput{RawBody: []byte(body)}
+	in.Author = author
+	if _, err := o.HandlePatchConfig(t.Context(), in); err != nil {
+		t.Fatalf("HandlePatchConfig: %v", err)
+	}
+}
+
+func listRevisions(t *testing.T, o *OnRamp) []ConfigRevision {
+	t.Helper()
+	out, err := o.HandleListConfigRevisions(t.Context(), nil)
+	if err != nil {
+		t.Fatalf("HandleListConfigRevisions: %v", err)
+	}
+	return out.Body
+}
+
+func TestConfigRevisions_RecordWrites(t *testing.T) {
+	o := newTestProviderWithStore(t, testMainYML)
+	mainYML := filepath.Join(o.config.OnRampDir, "vars", "main.yml")
+
+	patchConfig(t, o, `{"core": {"data_iface": "ens99"}}`, "alice")
+	revs := listRevisions(t, o)
+	if len(revs) != 2 {
+		t.Fatalf("revisions = %+v, want original and patch", revs)
+	}
+	if revs[0].Source != "patch" || revs[0].Author != "alice" || revs[0].Hash == "" || revs[0].CreatedAt == 0 {
+		t.Errorf("patch revision = %+v", revs[0])
+	}
+	if revs[1].Source != "external" || revs[1].Hash != contentHash([]byte(testMainYML)) {
+		t.Errorf("original revision = %+v", revs[1])
+	}
+
+	// A write that changes nothing adds no revision.
+	patchConfig(t, o, `{"core": {"data_iface": "ens99"}}`, "alice")
+	if n := len(listRevisions(t, o)); n != 2 {
+		t.Errorf("revisions after no-op patch = %d, want 2", n)
+	}
+
+	// Edits made outside the API are captured before the next write.
+	if err := os.WriteFile(mainYML, []byte(testMainYML+"# hand edit\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(o.config.OnRampDir, "vars", "main-lab.yml"), []byte(testMainYML), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	in := &ProfileActivateInput{Name: "lab"}
+	in.Author = "bob"
+	if _, err := o.HandleActivateProfile(t.Context(), in); err != nil {
+		t.Fatalf("HandleActivateProfile: %v", err)
+	}
+	revs = listRevisions(t, o)
+	if len(revs) != 4 || revs[1].Source != "external" || revs[1].Note != "edited outside the API" {
+		t.Fatalf("revisions = %+v", revs)
+	}
+	if revs[0].Source != "profile" || revs[0].Note != "lab" || revs[0].Author != "bob" {
+		t.Errorf("profile revision = %+v", revs[0])
+	}
+
+	got, err := o.HandleGetConfigRevision(t.Context(), &ConfigRevisionGetInput{ID: revs[1].ID})
+	if err != nil {
+		t.Fatalf("HandleGetConfigRevision: %v", err)
+	}
+	if !strings.HasSuffix(got.Body.Content, "# hand edit\n") {
+		t.Errorf("content = %q", got.Body.Content)
+	}
+}
+
+func TestConfigRevisions_DiffAndRollback(t *testing.T) {
+	o := newTestProviderWithStore(t, testMainYML)
+	patchConfig(t, o, `{"core": {"data_iface": "ens99"}}`, "")
+	revs := listRevisions(t, o)
+	original, patched := revs[1].ID, revs[0].ID
+
+	diff, err := o.HandleDiffConfigRevision(t.Context(), &ConfigRevisionDiffInput{ID: original, To: patched})
+	if err != nil {
+		t.Fatalf("HandleDiffConfigRevision: %v", err)
+	}
+	if !strings.Contains(diff.Body.Unified, "-  data_iface: ens18\n") || !strings.Contains(diff.Body.Unified, "+  data_iface: ens99\n") {
+		t.Errorf("unified diff:\n%s", diff.Body.Unified)
+	}
+	found := false
+	for _, c := range diff.Body.Changes {
+		if c.Path == "core.data_iface" && c.Kind == "changed" && c.Old == "ens18" && c.New == "ens99" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("changes = %+v", diff.Body.Changes)
+	}
+
+	// Without a target the revision is compared with the current file.
+	cur, err := o.HandleDiffConfigRevision(t.Context(), &ConfigRevisionDiffInput{ID: patched})
+	if err != nil {
+		t.Fatalf("HandleDiffConfigRevision: %v", err)
+	}
+	if cur.Body.Unified != "" || len(cur.Body.Changes) != 0 {
+		t.Errorf("diff against current = %+v", cur.Body)
+	}
+
+	in := &ConfigRevisionRollbackInput{ID: original}
+	in.Author = "carol"
+	rb, err := o.HandleRollbackConfig(t.Context(), in)
+	if err != nil {
+		t.Fatalf("HandleRollbackConfig: %v", err)
+	}
+	if rb.Body.Source != "rollback" || rb.Body.Author != "carol" || rb.Body.Hash != revs[1].Hash {
+		t.Errorf("rollback revision = %+v", rb.Body)
+	}
+	data, _ := os.ReadFile(filepath.Join(o.config.OnRampDir, "vars", "main.yml"))
+	if string(data) != testMainYML {
+		t.Errorf("main.yml after rollback = %q", data)
+	}
+
+	_, err = o.HandleGetConfigRevision(t.Context(), &ConfigRevisionGetInput{ID: 999})
+	wantStatus(t, err, 404)
+	acquireMaintenance(t, o)
+	_, err = o.HandleRollbackConfig(t.Context(), in)
+	wantStatus(t, err, 423)
+}
+
+func TestConfigRevisions_NoStore(t *testing.T) {
+	o := newTestProvider(t, testMainYML)
+	patchConfig(t, o, `{"core": {"data_iface": "ens99"}}`, "")
+
+	_, err := o.HandleListConfigRevisions(t.Context(), nil)
+	wantStatus(t, err, 503)
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	a := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n"
+	b := "a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\n"
+	want := `--- old
++++ new
+@@ -1,5 +1,5 @@
+ a
+-b
++B
+ c
+ d
+ e
+@@ -8,3 +8,4 @@
+ h
+ i
+ j
++k
+`
+	if got := unifiedDiff("old", "new", a, b); got != want {
+		t.Errorf("unifiedDiff =\n%s\nwant\n%s", got, want)
+	}
+	if got := unifiedDiff("old", "new", a, a); got != "" {
+		t.Errorf("unifiedDiff of equal input = %q", got)
+	}
+	if got := unifiedDiff("old", "new", "", "x\n"); got != "--- old\n+++ new\n@@ -0,0 +1,1 @@\n+x\n" {
+		t.Errorf("unifiedDiff from empty = %q", got)
+	}
+}
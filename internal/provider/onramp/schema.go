@@ -0,0 +1,214 @@
+package onramp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// jsonSchemaDialect is the JSON Schema version the config schema is written
+// against.
+const jsonSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// Custom formats used by the config schema beside the standard ipv4.
+const (
+	formatCIDR     = "cidr"       // subnet such as 192.168.252.1/24
+	formatIPOrCIDR = "ip-or-cidr" // address with an optional prefix length
+)
+
+// schemaHint enriches the schema generated from a config field's Go type.
+// These are kept out of the struct tags on purpose: huma enforces enum,
+// format and default on request bodies, while OnRamp accepts an empty value
+// for most fields and validateConfig reports bad ones without rejecting the
+// request.
+type schemaHint struct {
+	Description string
+	Format      string
+	Enum        []any
+	Default     any
+}
+
+// configSchemaHints is keyed by dotted JSON path; "*" stands for any key of
+// a map. Defaults are the values OnRamp's own vars/main.yml ships with.
+var configSchemaHints = map[string]schemaHint{
+	"k8s":                                   {Description: "Kubernetes (RKE2) cluster settings"},
+	"k8s.rke2.version":                      {Description: "RKE2 release to install", Default: "v1.33.1+rke2r1"},
+	"k8s.rke2.config.token":                 {Description: "Shared secret nodes use to join the cluster", Default: "myrke2token"},
+	"k8s.rke2.config.port":                  {Description: "Supervisor port for node registration", Default: 9345},
+	"k8s.rke2.config.params_file":           {Description: "RKE2 config templates for server and agent nodes"},
+	"k8s.helm.version":                      {Description: "Helm release to install", Default: "v3.10.3"},
+	"core":                                  {Description: "5G or 4G core (SD-Core) settings"},
+	"core.standalone":                       {Description: "Run the core standalone; false places it under control of the ROC", Default: true},
+	"core.data_iface":                       {Description: "Interface on master nodes that carries user-plane traffic"},
+	"core.values_file":                      {Description: "Helm values file for SD-Core"},
+	"core.ran_subnet":                       {Description: "Subnet gNBs reach the core from; empty derives it from data_iface", Format: formatCIDR, Default: "172.20.0.0/16"},
+	"core.helm.local_charts":                {Description: "Install from a local chart path given in chart_ref", Default: false},
+	"core.helm.chart_ref":                   {Description: "Helm chart reference", Default: "aether/sd-core"},
+	"core.upf":                              {Description: "User plane function settings"},
+	"core.upf.access_subnet":                {Description: "Access (N3) subnet and gateway", Format: formatCIDR, Default: "192.168.252.1/24"},
+	"core.upf.core_subnet":                  {Description: "Core (N6) subnet and gateway", Format: formatCIDR, Default: "192.168.250.1/24"},
+	"core.upf.mode":                         {Description: "UPF data plane; dpdk needs VF devices bound to vfio-pci", Enum: []any{"af_packet", "dpdk"}, Default: "af_packet"},
+	"core.upf.multihop_gnb":                 {Description: "Route to gNBs that are more than one hop away", Default: false},
+	"core.upf.default_upf":                  {Description: "The UPF every deployment has"},
+	"core.upf.additional_upfs":              {Description: "Further UPFs keyed by instance number"},
+	"core.upf.default_upf.ip.access":        {Description: "UPF address on the access subnet", Format: formatIPOrCIDR, Default: "192.168.252.3"},
+	"core.upf.default_upf.ip.core":          {Description: "UPF address on the core subnet", Format: formatIPOrCIDR, Default: "192.168.250.3"},
+	"core.upf.default_upf.ue_ip_pool":       {Description: "Addresses assigned to UEs served by this UPF", Format: formatCIDR, Default: "172.250.0.0/16"},
+	"core.upf.additional_upfs.*.ip.access":  {Description: "UPF address on the access subnet", Format: formatIPOrCIDR},
+	"core.upf.additional_upfs.*.ip.core":    {Description: "UPF address on the core subnet", Format: formatIPOrCIDR},
+	"core.upf.additional_upfs.*.ue_ip_pool": {Description: "Addresses assigned to UEs served by this UPF; must not overlap other pools", Format: formatCIDR},
+	"core.amf.ip":                           {Description: "AMF address gNBs connect to; usually the master node's address", Format: "ipv4"},
+	"core.mme.ip":                           {Description: "MME address eNBs connect to (4G core)", Format: "ipv4"},
+	"gnbsim":                                {Description: "gNBSim simulated RAN settings"},
+	"gnbsim.docker.container.image":         {Description: "gNBSim container image", Default: "omecproject/5gc-gnbsim:rel-1.6.3"},
+	"gnbsim.docker.container.prefix":        {Description: "Container name prefix", Default: "gnbsim"},
+	"gnbsim.docker.container.count":         {Description: "Containers to run on each gnbsim node", Default: 2},
+	"gnbsim.router.data_iface":              {Description: "Interface on gnbsim nodes the macvlan network attaches to"},
+	"gnbsim.router.macvlan.subnet_prefix":   {Description: "First two octets of the gNBSim macvlan subnet", Default: "172.20"},
+	"gnbsim.servers":                        {Description: "gNBSim config files per server index"},
+	"amp":                                   {Description: "Aether Management Platform (ROC and monitoring) settings"},
+	"amp.roc_models":                        {Description: "ROC model file loaded at install"},
+	"amp.monitor_dashboard":                 {Description: "Directory of Grafana dashboards"},
+	"sdran":                                 {Description: "SD-RAN controller settings"},
+	"sdran.sdran.import":                    {Description: "SD-RAN services to install"},
+	"sdran.sdran.ransim":                    {Description: "RAN simulator model and metrics files"},
+	"ueransim":                              {Description: "UERANSIM simulator settings"},
+	"ueransim.gnb.ip":                       {Description: "Address of the UERANSIM gNB", Format: "ipv4"},
+	"ueransim.servers":                      {Description: "gNB and UE config files per server index"},
+	"oai":                                   {Description: "OpenAirInterface RAN settings"},
+	"oai.simulation":                        {Description: "Run the RF simulator instead of a radio", Default: true},
+	"oai.docker.network.data_iface":         {Description: "Interface on oai nodes the gNB network attaches to"},
+	"oai.docker.network.subnet":             {Description: "Subnet of the gNB container network", Format: formatCIDR},
+	"oai.servers":                           {Description: "gNB and UE settings per server index"},
+	"oai.servers.*.gnb_ip":                  {Description: "gNB address", Format: "ipv4"},
+	"srsran":                                {Description: "srsRAN Project RAN settings"},
+	"srsran.simulation":                     {Description: "Use ZMQ in place of a radio", Default: true},
+	"srsran.servers":                        {Description: "gNB and UE settings per server index"},
+	"srsran.servers.*.gnb_ip":               {Description: "gNB address", Format: "ipv4"},
+	"n3iwf":                                 {Description: "Non-3GPP Interworking Function settings"},
+	"n3iwf.servers":                         {Description: "N3IWF settings per server index"},
+	"n3iwf.servers.*.n3iwf_ip":              {Description: "N3IWF address on its container network", Format: "ipv4"},
+	"n3iwf.servers.*.n2_ip":                 {Description: "N2 (AMF-facing) address", Format: "ipv4"},
+	"n3iwf.servers.*.n3_ip":                 {Description: "N3 (UPF-facing) address", Format: "ipv4"},
+	"n3iwf.servers.*.nwu_ip":                {Description: "NWu (UE-facing) address", Format: "ipv4"},
+}
+
+// configSchema is the JSON Schema for vars/main.yml. It depends only on the
+// Go types, so it is built once.
+var configSchema = sync.OnceValues(buildConfigSchema)
+
+// buildConfigSchema reflects OnRampConfig into a self-contained JSON Schema
+// and applies configSchemaHints. Every field is optional and unknown keys
+// are allowed, as OnRamp releases add keys OnRampConfig does not model.
+func buildConfigSchema() (map[string]any, error) {
+	reg := huma.NewMapRegistry("#/$defs/", huma.DefaultSchemaNamer)
+	root := inlineSchema(reg, reg.Schema(reflect.TypeFor[OnRampConfig](), true, ""))
+	root.Title = "OnRamp vars/main.yml"
+	root.Description = "Deployment settings read by the OnRamp playbooks"
+
+	paths := make([]string, 0, len(configSchemaHints))
+	for p := range configSchemaHints {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		s := schemaAt(root, p)
+		if s == nil {
+			return nil, fmt.Errorf("schema hint %s does not match a config field", p)
+		}
+		h := configSchemaHints[p]
+		s.Description = h.Description
+		s.Format = h.Format
+		s.Enum = h.Enum
+		s.Default = h.Default
+	}
+
+	data, err := json.Marshal(root)
+	if err != nil {
+		return nil, err
+	}
+	var out map[string]any
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, err
+	}
+	out["$schema"] = jsonSchemaDialect
+	return out, nil
+}
+
+// inlineSchema returns a copy of s with every $ref replaced by the schema it
+// points to, so shared types such as HelmRef get their own copy to annotate.
+func inlineSchema(reg huma.Registry, s *huma.Schema) *huma.Schema {
+	if s == nil {
+		return nil
+	}
+	if s.Ref != "" {
+		s = reg.SchemaFromRef(s.Ref)
+	}
+	c := *s
+	c.Required = nil
+	c.Items = inlineSchema(reg, s.Items)
+	switch ap := s.AdditionalProperties.(type) {
+	case *huma.Schema:
+		c.AdditionalProperties = inlineSchema(reg, ap)
+	case bool:
+		c.AdditionalProperties = nil
+	}
+	if s.Properties != nil {
+		c.Properties = make(map[string]*huma.Schema, len(s.Properties))
+		for name, p := range s.Properties {
+			c.Properties[name] = inlineSchema(reg, p)
+		}
+	}
+	return &c
+}
+
+// schemaAt returns the schema at a dotted path, or nil.
+func schemaAt(s *huma.Schema, path string) *huma.Schema {
+	for _, tok := range strings.Split(path, ".") {
+		if s == nil {
+			return nil
+		}
+		if tok == "*" {
+			s, _ = s.AdditionalProperties.(*huma.Schema)
+			continue
+		}
+		s = s.Properties[tok]
+	}
+	return s
+}
+
+// HandleGetConfigSchema returns the JSON Schema for vars/main.yml.
+func (o *OnRamp) HandleGetConfigSchema(_ context.Context, _ *struct{}) (*ConfigSchemaOutput, error) {
+	schema, err := configSchema()
+	if err != nil {
+		return nil, huma.Error500InternalServerError("failed to build config schema", err)
+	}
+	return &ConfigSchemaOutput{Body: schema}, nil
+}
+
+// HandleGetConfigSectionSchema returns the JSON Schema for one top-level
+// section of vars/main.yml.
+func (o *OnRamp) HandleGetConfigSectionSchema(_ context.Context, in *ConfigSectionSchemaInput) (*ConfigSchemaOutput, error) {
+	schema, err := configSchema()
+	if err != nil {
+		return nil, huma.Error500InternalServerError("failed to build config schema", err)
+	}
+	props, _ := schema["properties"].(map[string]any)
+	section, ok := props[in.Section].(map[string]any)
+	if !ok {
+		return nil, huma.Error404NotFound(fmt.Sprintf("unknown config section %q", in.Section))
+	}
+	out := make(map[string]any, len(section)+2)
+	for k, v := range section {
+		out[k] = v
+	}
+	out["$schema"] = jsonSchemaDialect
+	out["title"] = "OnRamp " + in.Section + " section"
+	return &ConfigSchemaOutput{Body: out}, nil
+}
@@ -0,0 +1,103 @@
+package onramp
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// schemaProp follows a dotted path of property names ("*" for map values)
+// through a schema decoded from JSON.
+func schemaProp(t *testing.T, s map[string]any, path string) map[string]any {
+	t.Helper()
+	for _, tok := range strings.Split(path, ".") {
+		var next any
+		if tok == "*" {
+			next = s["additionalProperties"]
+		} else {
+			props, _ := s["properties"].(map[string]any)
+			next = props[tok]
+		}
+		m, ok := next.(map[string]any)
+		if !ok {
+			t.Fatalf("schema has no %s (at %s)", path, tok)
+		}
+		s = m
+	}
+	return s
+}
+
+func TestConfigSchema(t *testing.T) {
+	o := newTestProvider(t, "")
+	out, err := o.HandleGetConfigSchema(t.Context(), &struct{}{})
+	if err != nil {
+		t.Fatalf("HandleGetConfigSchema: %v", err)
+	}
+	s := out.Body
+	if s["$schema"] != jsonSchemaDialect || s["type"] != "object" {
+		t.Fatalf("root = %v, %v", s["$schema"], s["type"])
+	}
+
+	// Every section of OnRampConfig is present.
+	typ := reflect.TypeFor[OnRampConfig]()
+	for i := range typ.NumField() {
+		name := strings.Split(typ.Field(i).Tag.Get("json"), ",")[0]
+		if schemaProp(t, s, name)["description"] == "" {
+			t.Errorf("section %s has no description", name)
+		}
+	}
+
+	mode := schemaProp(t, s, "core.upf.mode")
+	if !reflect.DeepEqual(mode["enum"], []any{"af_packet", "dpdk"}) || mode["default"] != "af_packet" {
+		t.Errorf("core.upf.mode = %v", mode)
+	}
+	if f := schemaProp(t, s, "core.ran_subnet")["format"]; f != "cidr" {
+		t.Errorf("core.ran_subnet format = %v", f)
+	}
+	if f := schemaProp(t, s, "core.amf.ip")["format"]; f != "ipv4" {
+		t.Errorf("core.amf.ip format = %v", f)
+	}
+	if f := schemaProp(t, s, "srsran.servers.*.gnb_ip")["format"]; f != "ipv4" {
+		t.Errorf("srsran.servers.*.gnb_ip format = %v", f)
+	}
+	if d := schemaProp(t, s, "k8s.rke2.config.port")["default"]; d != float64(9345) {
+		t.Errorf("k8s.rke2.config.port default = %v", d)
+	}
+
+	// Shared types are copied, so hints on one use do not leak to another.
+	if d := schemaProp(t, s, "k8s.helm.chart_ref")["default"]; d != nil {
+		t.Errorf("k8s.helm.chart_ref default = %v, want none", d)
+	}
+
+	// Fields are optional and keys OnRampConfig does not model are allowed.
+	core := schemaProp(t, s, "core")
+	if _, ok := core["required"]; ok {
+		t.Errorf("core.required = %v", core["required"])
+	}
+	if ap, ok := core["additionalProperties"]; ok {
+		t.Errorf("core.additionalProperties = %v", ap)
+	}
+}
+
+func TestConfigSchemaHintsMatchFields(t *testing.T) {
+	if _, err := buildConfigSchema(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConfigSectionSchema(t *testing.T) {
+	o := newTestProvider(t, "")
+	out, err := o.HandleGetConfigSectionSchema(t.Context(), &ConfigSectionSchemaInput{Section: "core"})
+	if err != nil {
+		t.Fatalf("HandleGetConfigSectionSchema: %v", err)
+	}
+	if out.Body["$schema"] != jsonSchemaDialect || out.Body["title"] != "OnRamp core section" {
+		t.Errorf("header = %v, %v", out.Body["$schema"], out.Body["title"])
+	}
+	if schemaProp(t, out.Body, "upf.access_subnet")["format"] != "cidr" {
+		t.Error("section schema lacks core.upf.access_subnet format")
+	}
+
+	_, err = o.HandleGetConfigSectionSchema(t.Context(), &ConfigSectionSchemaInput{Section: "nope"})
+	wantStatus(t, err, 404)
+}
@@ -0,0 +1,324 @@
+package onramp
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/bengrewell/aether-webui/internal/store"
+	"github.com/bengrewell/aether-webui/internal/taskrunner"
+)
+
+// Node passwords never go into hosts.ini, which is world-readable in the
+// checkout. Ansible also loads variables from host_vars/<host>/ next to the
+// inventory, so each node's passwords are written there to a file of its
+// own that only the owner can read:
+//
+//   - with a vault password file configured, inventory sync writes the file
+//     with every value ansible-vault encrypted, and tasks get
+//     ANSIBLE_VAULT_PASSWORD_FILE to decrypt them;
+//   - otherwise the file holds the passwords in the clear and only exists
+//     while tasks run: it is written when the first task of a workspace
+//     starts and removed when the last one ends.
+//
+// Nodes without passwords get no file and authenticate with SSH keys.
+
+// secretsFile is the name of the managed file in host_vars/<host>/.
+const secretsFile = "aether-webd-secrets.yml"
+
+const secretsHeader = "Node passwords written by aether-webd; do not edit."
+
+// nodeSecretVars returns the inventory variables holding a node's passwords,
+// in the order they are written.
+func nodeSecretVars(n store.Node) [][2]string {
+	var vars [][2]string
+	if len(n.Password) > 0 {
+		vars = append(vars, [2]string{"ansible_password", string(n.Password)})
+	}
+	if len(n.SudoPassword) > 0 {
+		vars = append(vars, [2]string{"ansible_sudo_pass", string(n.SudoPassword)})
+	}
+	return vars
+}
+
+// secretsPath returns the managed secrets file of a node, or false when the
+// node name cannot be used as a directory name.
+func secretsPath(dir, name string) (string, bool) {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return "", false
+	}
+	return filepath.Join(dir, "host_vars", name, secretsFile), true
+}
+
+// writeSecrets makes the managed secrets files in the checkout at dir match
+// nodes: a file for each node with passwords, encrypted with vaultPass when
+// it is not nil, and none for any other host.
+func writeSecrets(dir string, nodes []store.Node, vaultPass []byte) error {
+	keep := make(map[string]bool)
+	for _, n := range nodes {
+		vars := nodeSecretVars(n)
+		if len(vars) == 0 {
+			continue
+		}
+		path, ok := secretsPath(dir, n.Name)
+		if !ok {
+			return fmt.Errorf("node name %q cannot be used in host_vars", n.Name)
+		}
+		data, err := secretsYAML(vars, vaultPass)
+		if err != nil {
+			return fmt.Errorf("node %s: %w", n.Name, err)
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			return err
+		}
+		// WriteFile keeps the mode of a file that already exists.
+		if err := os.Chmod(path, 0o600); err != nil {
+			return err
+		}
+		keep[path] = true
+	}
+
+	existing, err := filepath.Glob(filepath.Join(dir, "host_vars", "*", secretsFile))
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, path := range existing {
+		if keep[path] {
+			continue
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			errs = append(errs, err)
+			continue
+		}
+		// Leave the host's directory if it holds other variables.
+		_ = os.Remove(filepath.Dir(path))
+	}
+	return errors.Join(errs...)
+}
+
+// secretsYAML renders a host_vars file holding vars, with each value
+// encrypted as an inline !vault scalar when vaultPass is not nil.
+func secretsYAML(vars [][2]string, vaultPass []byte) ([]byte, error) {
+	m := &yaml.Node{Kind: yaml.MappingNode, HeadComment: secretsHeader}
+	for _, kv := range vars {
+		// Quoted, so YAML 1.1 readers such as Ansible keep "yes" a string.
+		value := &yaml.Node{Kind: yaml.ScalarNode, Style: yaml.DoubleQuotedStyle, Value: kv[1]}
+		if vaultPass != nil {
+			enc, err := vaultEncrypt([]byte(kv[1]), vaultPass)
+			if err != nil {
+				return nil, err
+			}
+			value = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!vault", Style: yaml.LiteralStyle, Value: enc + "\n"}
+		}
+		m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: kv[0]}, value)
+	}
+	return yaml.Marshal(&yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{m}})
+}
+
+// vaultEncrypt encrypts plaintext in ansible-vault's 1.1 AES256 format.
+func vaultEncrypt(plaintext, password []byte) (string, error) {
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key, err := pbkdf2.Key(sha256.New, string(password), salt, 10000, 80)
+	if err != nil {
+		return "", err
+	}
+	block, err := aes.NewCipher(key[:32])
+	if err != nil {
+		return "", err
+	}
+	pad := aes.BlockSize - len(plaintext)%aes.BlockSize
+	padded := append(bytes.Clone(plaintext), bytes.Repeat([]byte{byte(pad)}, pad)...)
+	ciphertext := make([]byte, len(padded))
+	cipher.NewCTR(block, key[64:80]).XORKeyStream(ciphertext, padded)
+
+	mac := hmac.New(sha256.New, key[32:64])
+	mac.Write(ciphertext)
+	body := hex.EncodeToString([]byte(hex.EncodeToString(salt) + "\n" +
+		hex.EncodeToString(mac.Sum(nil)) + "\n" + hex.EncodeToString(ciphertext)))
+
+	var b strings.Builder
+	b.WriteString("$ANSIBLE_VAULT;1.1;AES256")
+	for len(body) > 0 {
+		n := min(80, len(body))
+		b.WriteString("\n")
+		b.WriteString(body[:n])
+		body = body[n:]
+	}
+	return b.String(), nil
+}
+
+// vaultPassword reads the configured vault password file the way
+// ansible-vault does, ignoring surrounding whitespace.
+func (o *OnRamp) vaultPassword() ([]byte, error) {
+	data, err := os.ReadFile(o.config.VaultPasswordFile)
+	if err != nil {
+		return nil, fmt.Errorf("read vault password file: %w", err)
+	}
+	pass := bytes.TrimSpace(data)
+	if len(pass) == 0 {
+		return nil, errors.New("vault password file is empty")
+	}
+	return pass, nil
+}
+
+// workspaceNodes returns the workspace's nodes with their secrets decrypted.
+func (o *OnRamp) workspaceNodes(ctx context.Context, ws *workspace) ([]store.Node, error) {
+	st := o.Store()
+	infos, err := st.ListNodes(ctx, ws.name)
+	if err != nil {
+		return nil, err
+	}
+	nodes := make([]store.Node, 0, len(infos))
+	for _, info := range infos {
+		node, ok, err := st.GetNode(ctx, info.ID)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes, nil
+}
+
+// syncSecrets brings the managed secrets files in line with nodes during
+// inventory sync. Without a vault, they are refreshed while tasks run and
+// any left behind are removed otherwise.
+func (o *OnRamp) syncSecrets(ws *workspace, nodes []store.Node) error {
+	dir := ws.config.OnRampDir
+	if o.config.VaultPasswordFile != "" {
+		pass, err := o.vaultPassword()
+		if err != nil {
+			return err
+		}
+		return writeSecrets(dir, nodes, pass)
+	}
+	ws.secretsMu.Lock()
+	defer ws.secretsMu.Unlock()
+	if ws.secretsUsers == 0 {
+		nodes = nil
+	}
+	return writeSecrets(dir, nodes, nil)
+}
+
+// syncJumpFiles brings the jump host files in line with the workspace's jump
+// hosts during inventory sync, if tasks are running; see jump.go.
+func (o *OnRamp) syncJumpFiles(ctx context.Context, ws *workspace) error {
+	ws.secretsMu.Lock()
+	defer ws.secretsMu.Unlock()
+	var hosts []store.JumpHost
+	if ws.secretsUsers > 0 {
+		var err error
+		if hosts, err = o.Store().ListJumpHosts(ctx, ws.name); err != nil {
+			return err
+		}
+	}
+	return writeJumpFiles(ws.config.OnRampDir, hosts)
+}
+
+// withSecrets arranges for a task to see the workspace's node passwords and
+// jump hosts. With a vault the passwords come from the password file in its
+// environment; otherwise the secrets files, like the jump host files, exist
+// from when it starts until it ends.
+func (o *OnRamp) withSecrets(ws *workspace, spec taskrunner.TaskSpec) taskrunner.TaskSpec {
+	if path := o.config.VaultPasswordFile; path != "" {
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+		spec.Env = append(spec.Env, "ANSIBLE_VAULT_PASSWORD_FILE="+path)
+	}
+	if o.Store().Path() == "" {
+		return spec
+	}
+
+	// A task canceled while pending completes without starting.
+	var started atomic.Bool
+	onStart, onComplete := spec.OnStart, spec.OnComplete
+	spec.OnStart = func(v taskrunner.TaskView) {
+		started.Store(true)
+		o.acquireSecrets(ws)
+		if onStart != nil {
+			onStart(v)
+		}
+	}
+	spec.OnComplete = func(v taskrunner.TaskView) {
+		if started.Load() {
+			o.releaseSecrets(ws)
+		}
+		if onComplete != nil {
+			onComplete(v)
+		}
+	}
+	return spec
+}
+
+// acquireSecrets writes the secrets files, unless vaulted, and the jump host
+// files for the first running task of a workspace. A failure is logged; the
+// task then runs with key-only auth or without its jump hosts.
+func (o *OnRamp) acquireSecrets(ws *workspace) {
+	ws.secretsMu.Lock()
+	defer ws.secretsMu.Unlock()
+	ws.secretsUsers++
+	if ws.secretsUsers > 1 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if o.config.VaultPasswordFile == "" {
+		nodes, err := o.workspaceNodes(ctx, ws)
+		if err == nil {
+			err = writeSecrets(ws.config.OnRampDir, nodes, nil)
+		}
+		if err != nil {
+			o.Log().Error("failed to write node secrets", "workspace", ws.name, "error", err)
+		}
+	}
+	hosts, err := o.Store().ListJumpHosts(ctx, ws.name)
+	if err == nil {
+		err = writeJumpFiles(ws.config.OnRampDir, hosts)
+	}
+	if err != nil {
+		o.Log().Error("failed to write jump host files", "workspace", ws.name, "error", err)
+	}
+}
+
+// releaseSecrets removes the unvaulted secrets files and the jump host files
+// once the last running task of a workspace has ended.
+func (o *OnRamp) releaseSecrets(ws *workspace) {
+	ws.secretsMu.Lock()
+	defer ws.secretsMu.Unlock()
+	ws.secretsUsers--
+	if ws.secretsUsers > 0 {
+		return
+	}
+	if o.config.VaultPasswordFile == "" {
+		if err := writeSecrets(ws.config.OnRampDir, nil, nil); err != nil {
+			o.Log().Error("failed to remove node secrets", "workspace", ws.name, "error", err)
+		}
+	}
+	if err := writeJumpFiles(ws.config.OnRampDir, nil); err != nil {
+		o.Log().Error("failed to remove jump host files", "workspace", ws.name, "error", err)
+	}
+}
@@ -0,0 +1,230 @@
+package onramp
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/bengrewell/aether-webui/internal/store"
+	"github.com/bengrewell/aether-webui/internal/taskrunner"
+)
+
+// vaultDecrypt reverses vaultEncrypt following ansible-vault's 1.1 format.
+func vaultDecrypt(t *testing.T, envelope string, password []byte) string {
+	t.Helper()
+	header, body, ok := strings.Cut(strings.TrimSpace(envelope), "\n")
+	if !ok || header != "$ANSIBLE_VAULT;1.1;AES256" {
+		t.Fatalf("bad vault header in %q", envelope)
+	}
+	inner, err := hex.DecodeString(strings.ReplaceAll(body, "\n", ""))
+	if err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+	parts := strings.Split(string(inner), "\n")
+	if len(parts) != 3 {
+		t.Fatalf("vault body has %d parts", len(parts))
+	}
+	var raw [3][]byte
+	for i, p := range parts {
+		if raw[i], err = hex.DecodeString(p); err != nil {
+			t.Fatalf("decode part %d: %v", i, err)
+		}
+	}
+	salt, sum, ciphertext := raw[0], raw[1], raw[2]
+	key, err := pbkdf2.Key(sha256.New, string(password), salt, 10000, 80)
+	if err != nil {
+		t.Fatalf("pbkdf2: %v", err)
+	}
+	mac := hmac.New(sha256.New, key[32:64])
+	mac.Write(ciphertext)
+	if !hmac.Equal(mac.Sum(nil), sum) {
+		t.Fatal("vault HMAC mismatch")
+	}
+	block, err := aes.NewCipher(key[:32])
+	if err != nil {
+		t.Fatalf("aes: %v", err)
+	}
+	plain := make([]byte, len(ciphertext))
+	cipher.NewCTR(block, key[64:80]).XORKeyStream(plain, ciphertext)
+	pad := int(plain[len(plain)-1])
+	return string(plain[:len(plain)-pad])
+}
+
+func addNode(t *testing.T, o *OnRamp, node store.Node) {
+	t.Helper()
+	if err := o.Store().UpsertNode(t.Context(), node); err != nil {
+		t.Fatalf("UpsertNode: %v", err)
+	}
+}
+
+func TestVaultEncrypt(t *testing.T) {
+	for _, plain := range []string{"", "secret", "exactly16bytes!!", strings.Repeat("x", 100)} {
+		enc, err := vaultEncrypt([]byte(plain), []byte("vault-pass"))
+		if err != nil {
+			t.Fatalf("vaultEncrypt: %v", err)
+		}
+		for _, line := range strings.Split(enc, "\n")[1:] {
+			if len(line) > 80 {
+				t.Errorf("line of %d chars, want at most 80", len(line))
+			}
+		}
+		if got := vaultDecrypt(t, enc, []byte("vault-pass")); got != plain {
+			t.Errorf("round trip = %q, want %q", got, plain)
+		}
+	}
+}
+
+func TestWriteSecrets(t *testing.T) {
+	dir := t.TempDir()
+	nodes := []store.Node{
+		{Name: "node1", Password: []byte("pa: ss"), SudoPassword: []byte("yes")},
+		{Name: "node2"},
+	}
+	// Another tool's host variables are left alone.
+	writeTestFile(t, filepath.Join(dir, "host_vars", "node1", "site.yml"), "ntp: pool\n")
+
+	if err := writeSecrets(dir, nodes, nil); err != nil {
+		t.Fatalf("writeSecrets: %v", err)
+	}
+	path := filepath.Join(dir, "host_vars", "node1", secretsFile)
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if fi.Mode().Perm() != 0o600 {
+		t.Errorf("mode = %v, want 0600", fi.Mode().Perm())
+	}
+	data, _ := os.ReadFile(path)
+	var vars map[string]string
+	if err := yaml.Unmarshal(data, &vars); err != nil {
+		t.Fatalf("Unmarshal: %v\n%s", err, data)
+	}
+	if vars["ansible_password"] != "pa: ss" || vars["ansible_sudo_pass"] != "yes" {
+		t.Errorf("vars = %v", vars)
+	}
+	if !bytes.Contains(data, []byte(`ansible_sudo_pass: "yes"`)) {
+		t.Errorf("password not quoted:\n%s", data)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "host_vars", "node2")); !os.IsNotExist(err) {
+		t.Errorf("node without passwords got host_vars: %v", err)
+	}
+
+	if err := writeSecrets(dir, nil, nil); err != nil {
+		t.Fatalf("writeSecrets(nil): %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("secrets file not removed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "host_vars", "node1", "site.yml")); err != nil {
+		t.Errorf("unmanaged host_vars removed: %v", err)
+	}
+
+	if err := writeSecrets(dir, []store.Node{{Name: "../x", Password: []byte("p")}}, nil); err == nil {
+		t.Error("expected error for a node name that leaves host_vars")
+	}
+}
+
+func TestSyncInventory_NoSecretsInHostsINI(t *testing.T) {
+	o := newTestProviderWithStore(t, "")
+	addNode(t, o, store.Node{
+		ID: "n1", Name: "node1", AnsibleHost: "10.0.0.1", AnsibleUser: "ubuntu",
+		Password: []byte("pass1"), SudoPassword: []byte("sudo1"), Roles: []string{"master"},
+	})
+	dir := o.config.OnRampDir
+
+	if _, err := o.HandleSyncInventory(t.Context(), &InventorySyncInput{}); err != nil {
+		t.Fatalf("HandleSyncInventory: %v", err)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "hosts.ini"))
+	if bytes.Contains(data, []byte("pass1")) || bytes.Contains(data, []byte("sudo1")) {
+		t.Errorf("hosts.ini holds secrets:\n%s", data)
+	}
+	// Without a vault, secrets only exist while tasks run.
+	if _, err := os.Stat(filepath.Join(dir, "host_vars", "node1", secretsFile)); !os.IsNotExist(err) {
+		t.Errorf("secrets written outside a run: %v", err)
+	}
+}
+
+func TestSyncInventory_Vault(t *testing.T) {
+	o := newTestProviderWithStore(t, "")
+	o.config.VaultPasswordFile = filepath.Join(t.TempDir(), "vault-pass")
+	addNode(t, o, store.Node{ID: "n1", Name: "node1", AnsibleHost: "10.0.0.1", Password: []byte("pass1")})
+	dir := o.config.OnRampDir
+
+	_, err := o.HandleSyncInventory(t.Context(), &InventorySyncInput{})
+	wantStatus(t, err, 500)
+
+	writeTestFile(t, o.config.VaultPasswordFile, "vault-pass\n")
+	if _, err := o.HandleSyncInventory(t.Context(), &InventorySyncInput{}); err != nil {
+		t.Fatalf("HandleSyncInventory: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "host_vars", "node1", secretsFile))
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if bytes.Contains(data, []byte("pass1")) {
+		t.Fatalf("vault file holds the password in the clear:\n%s", data)
+	}
+	var doc struct {
+		Password yaml.Node `yaml:"ansible_password"`
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if doc.Password.Tag != "!vault" {
+		t.Errorf("tag = %q, want !vault", doc.Password.Tag)
+	}
+	if got := vaultDecrypt(t, doc.Password.Value, []byte("vault-pass")); got != "pass1" {
+		t.Errorf("decrypted = %q", got)
+	}
+
+	env := o.withSecrets(o.defaultWorkspace(), taskrunner.TaskSpec{}).Env
+	if len(env) != 1 || env[0] != "ANSIBLE_VAULT_PASSWORD_FILE="+o.config.VaultPasswordFile {
+		t.Errorf("env = %v", env)
+	}
+
+	// Clearing the password drops the file.
+	addNode(t, o, store.Node{ID: "n1", Name: "node1", AnsibleHost: "10.0.0.1"})
+	if _, err := o.HandleSyncInventory(t.Context(), &InventorySyncInput{}); err != nil {
+		t.Fatalf("HandleSyncInventory: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "host_vars", "node1")); !os.IsNotExist(err) {
+		t.Errorf("host_vars not removed: %v", err)
+	}
+}
+
+func TestWithSecrets_PerRun(t *testing.T) {
+	o := newHookTestProvider(t)
+	addNode(t, o, store.Node{ID: "n1", Name: "node1", AnsibleHost: "10.0.0.1", Password: []byte("pass1")})
+	dir := o.config.OnRampDir
+	path := filepath.Join(dir, "host_vars", "node1", secretsFile)
+	writeScript(t, o, "install.sh", `cat "$ONRAMP_DIR/host_vars/node1/`+secretsFile+`"`)
+	// Hooks see the secrets too.
+	writeScript(t, o, "pre.sh", `test -f "$ONRAMP_DIR/host_vars/node1/`+secretsFile+`"`)
+	createHook(t, o, HookSpec{Component: "site", Stage: store.HookBefore, Name: "pre", Target: "pre.sh"})
+
+	out, err := o.HandleExecuteAction(t.Context(), &ExecuteActionInput{Component: "site", Action: "install"})
+	if err != nil {
+		t.Fatalf("HandleExecuteAction: %v", err)
+	}
+	if item := waitForAction(t, o, out.Body.ID); item.Status != "succeeded" {
+		t.Fatalf("action = %+v", item)
+	}
+	chunk, err := o.runner.Output(out.Body.ID, 0)
+	if err != nil || !strings.Contains(chunk.Data, `ansible_password: "pass1"`) {
+		t.Errorf("task did not see the secrets: %q, %v", chunk.Data, err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("secrets file left after the run: %v", err)
+	}
+}
@@ -25,7 +25,7 @@ func actionCategory(action string) string {
 
 // buildOnStart returns a callback that updates the action record and component
 // state when a queued task transitions to running.
-func buildOnStart(st store.Client, log *slog.Logger, actionID, component, action string) func(taskrunner.TaskView) {
+func buildOnStart(st store.Client, log *slog.Logger, workspace, actionID, component, action string) func(taskrunner.TaskView) {
 	return func(v taskrunner.TaskView) {
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
@@ -37,8 +37,10 @@ func buildOnStart(st store.Client, log *slog.Logger, actionID, component, action
 			log.Error("failed to update action to running", "action_id", actionID, "error", err)
 		}
 
+		// Tasks removed from the queue before starting leave the component
+		// untouched.
 		cat := actionCategory(action)
-		if cat == "" {
+		if cat == "" || v.StartedAt.IsZero() {
 			return
 		}
 		status := "installing"
@@ -46,6 +48,7 @@ func buildOnStart(st store.Client, log *slog.Logger, actionID, component, action
 			status = "uninstalling"
 		}
 		cs := store.ComponentState{
+			Workspace:  workspace,
 			Component:  component,
 			Status:     status,
 			LastAction: action,
@@ -61,7 +64,7 @@ func buildOnStart(st store.Client, log *slog.Logger, actionID, component, action
 // buildOnComplete returns a TaskView callback that persists the action result
 // and updates component state when appropriate. The callback is safe to call
 // from the task goroutine (no mutex held).
-func buildOnComplete(st store.Client, log *slog.Logger, actionID, component, action string) func(taskrunner.TaskView) {
+func buildOnComplete(st store.Client, log *slog.Logger, workspace, actionID, component, action string) func(taskrunner.TaskView) {
 	return func(v taskrunner.TaskView) {
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
@@ -77,8 +80,10 @@ func buildOnComplete(st store.Client, log *slog.Logger, actionID, component, act
 			log.Error("failed to update action result", "action_id", actionID, "error", err)
 		}
 
+		// Tasks removed from the queue before starting leave the component
+		// untouched.
 		cat := actionCategory(action)
-		if cat == "" {
+		if cat == "" || v.StartedAt.IsZero() {
 			return
 		}
 
@@ -95,6 +100,7 @@ func buildOnComplete(st store.Client, log *slog.Logger, actionID, component, act
 		}
 
 		cs := store.ComponentState{
+			Workspace:  workspace,
 			Component:  component,
 			Status:     compStatus,
 			LastAction: action,
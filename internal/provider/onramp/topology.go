@@ -0,0 +1,253 @@
+package onramp
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"github.com/bengrewell/aether-webui/internal/store"
+)
+
+// Topology rules. Role and component requirements are checked against the
+// whole workspace; the others hold for any subset of its nodes.
+const (
+	TopologyRoleUnknown    = "role_unknown"    // a node has a role the checkout does not define
+	TopologyRoleMax        = "role_max"        // more nodes have a role than its rule allows
+	TopologyRoleConflict   = "role_conflict"   // a node has two roles that conflict
+	TopologyRoleRequires   = "role_requires"   // a role's required role has no node
+	TopologyComponentRoles = "component_roles" // a deployed component has no node to run on
+	TopologyRoleComponents = "role_components" // a role's components are not deployed (warning)
+)
+
+// checkTopology checks node role assignments against the registry's
+// topology rules and the roles of the deployed components.
+func checkTopology(reg *componentRegistry, nodes []TopologyNode, components []string) TopologyResult {
+	res := TopologyResult{
+		Components: slices.Sorted(slices.Values(components)),
+		Errors:     []TopologyIssue{},
+		Warnings:   []TopologyIssue{},
+	}
+	add := func(severity string, issue TopologyIssue, format string, args ...any) {
+		issue.Severity = severity
+		issue.Message = fmt.Sprintf(format, args...)
+		if severity == severityError {
+			res.Errors = append(res.Errors, issue)
+		} else {
+			res.Warnings = append(res.Warnings, issue)
+		}
+	}
+
+	byRole := make(map[string][]string)
+	for _, n := range nodes {
+		for i, role := range n.Roles {
+			if !slices.Contains(reg.roles, role) {
+				add(severityError, TopologyIssue{Rule: TopologyRoleUnknown, Role: role, Nodes: []string{n.Name}},
+					"node %s has unknown role %q; valid roles: %s", n.Name, role, strings.Join(reg.roles, ", "))
+				continue
+			}
+			byRole[role] = append(byRole[role], n.Name)
+			for _, other := range n.Roles[i+1:] {
+				if slices.Contains(reg.topology[role].Conflicts, other) || slices.Contains(reg.topology[other].Conflicts, role) {
+					add(severityError, TopologyIssue{Rule: TopologyRoleConflict, Role: role, Nodes: []string{n.Name}},
+						"node %s cannot have both the %s and %s roles", n.Name, role, other)
+				}
+			}
+		}
+	}
+
+	for _, role := range reg.roles {
+		names := byRole[role]
+		if len(names) == 0 {
+			continue
+		}
+		rule := reg.topology[role]
+		if rule.Max > 0 && len(names) > rule.Max {
+			add(severityError, TopologyIssue{Rule: TopologyRoleMax, Role: role, Nodes: names},
+				"%d nodes have the %s role (%s); at most %d may", len(names), role, strings.Join(names, ", "), rule.Max)
+		}
+		for _, req := range rule.Requires {
+			if len(byRole[req]) == 0 {
+				add(severityError, TopologyIssue{Rule: TopologyRoleRequires, Role: role, Nodes: names},
+					"%s nodes need a %s node, and no node has that role", role, req)
+			}
+		}
+		if len(rule.Components) > 0 && !slices.ContainsFunc(rule.Components, func(c string) bool {
+			return slices.Contains(components, c)
+		}) {
+			add(severityWarning, TopologyIssue{Rule: TopologyRoleComponents, Role: role, Nodes: names},
+				"%s nodes need %s deployed", role, strings.Join(rule.Components, " or "))
+		}
+	}
+
+	for _, name := range res.Components {
+		c, ok := reg.component(name)
+		if !ok || len(c.Roles) == 0 {
+			continue
+		}
+		if !slices.ContainsFunc(c.Roles, func(r string) bool { return len(byRole[r]) > 0 }) {
+			add(severityError, TopologyIssue{Rule: TopologyComponentRoles, Component: name},
+				"component %s runs on %s nodes, and no node has that role", name, strings.Join(c.Roles, " or "))
+		}
+	}
+
+	res.Valid = len(res.Errors) == 0
+	return res
+}
+
+// CheckBuiltinTopology checks node role assignments against the built-in
+// component registry with nothing deployed. It serves callers that have no
+// checkout, such as the nodes provider before the OnRamp provider is linked.
+func CheckBuiltinTopology(nodes []TopologyNode) TopologyResult {
+	return checkTopology(builtinRegistry(), nodes, nil)
+}
+
+// CheckTopology checks node role assignments against the rules of a
+// workspace's checkout and its deployed components. The nodes provider
+// validates role changes with it.
+func (o *OnRamp) CheckTopology(ctx context.Context, workspace string, nodes []TopologyNode) (TopologyResult, error) {
+	ws, err := o.workspace(ctx, workspace)
+	if err != nil {
+		return TopologyResult{}, err
+	}
+	components, err := o.deployedComponents(ctx, ws)
+	if err != nil {
+		return TopologyResult{}, huma.Error500InternalServerError("failed to list component states", err)
+	}
+	return checkTopology(o.registry(ws), nodes, components), nil
+}
+
+// HandleValidateTopology checks the assignment in the request body, or the
+// workspace's nodes and deployed components when it is omitted.
+func (o *OnRamp) HandleValidateTopology(ctx context.Context, in *TopologyValidateInput) (*TopologyValidateOutput, error) {
+	ws, err := o.workspace(ctx, in.Workspace)
+	if err != nil {
+		return nil, err
+	}
+	body := in.Body
+	if body == nil {
+		body = &TopologyValidateBody{}
+	}
+	reg := o.registry(ws)
+
+	nodes := body.Nodes
+	if nodes == nil {
+		if nodes, err = o.topologyNodes(ctx, ws); err != nil {
+			return nil, huma.Error500InternalServerError("failed to list nodes", err)
+		}
+	}
+	components := body.Components
+	if components == nil {
+		if components, err = o.deployedComponents(ctx, ws); err != nil {
+			return nil, huma.Error500InternalServerError("failed to list component states", err)
+		}
+	}
+	for _, c := range components {
+		if _, ok := reg.component(c); !ok {
+			return nil, huma.Error422UnprocessableEntity(fmt.Sprintf("unknown component: %s", c))
+		}
+	}
+	return &TopologyValidateOutput{Body: checkTopology(reg, nodes, components)}, nil
+}
+
+// deployedComponents returns the components of a workspace that are
+// installed or being installed. Without a store nothing is deployed.
+func (o *OnRamp) deployedComponents(ctx context.Context, ws *workspace) ([]string, error) {
+	st := o.Store()
+	if st.Path() == "" {
+		return []string{}, nil
+	}
+	states, err := st.ListComponentStates(ctx, ws.name)
+	if err != nil {
+		return nil, err
+	}
+	out := []string{}
+	for _, s := range states {
+		if s.Status == "installed" || s.Status == "installing" {
+			out = append(out, s.Component)
+		}
+	}
+	return out, nil
+}
+
+// topologyNodes returns the role assignments of a workspace's nodes.
+// Without a store there are none.
+func (o *OnRamp) topologyNodes(ctx context.Context, ws *workspace) ([]TopologyNode, error) {
+	st := o.Store()
+	if st.Path() == "" {
+		return []TopologyNode{}, nil
+	}
+	infos, err := st.ListNodes(ctx, ws.name)
+	if err != nil {
+		return nil, err
+	}
+	nodes := make([]TopologyNode, len(infos))
+	for i, info := range infos {
+		nodes[i] = TopologyNode{Name: info.Name, Roles: info.Roles}
+	}
+	return nodes, nil
+}
+
+func nodeTopology(nodes []store.Node) []TopologyNode {
+	out := make([]TopologyNode, len(nodes))
+	for i, n := range nodes {
+		out[i] = TopologyNode{Name: n.Name, Roles: n.Roles}
+	}
+	return out
+}
+
+// checkTopologyForDeploy rejects a deployment with install actions when the
+// workspace's nodes break a topology rule once its components are in place.
+// A workspace without managed nodes keeps its inventory by hand and is not
+// checked.
+func (o *OnRamp) checkTopologyForDeploy(ctx context.Context, ws *workspace, actions []ComponentActionPair) error {
+	deployed, err := o.deployedComponents(ctx, ws)
+	if err != nil {
+		return huma.Error500InternalServerError("failed to list component states", err)
+	}
+	installs := false
+	for _, a := range actions {
+		switch actionCategory(a.Action) {
+		case "install":
+			installs = true
+			if !slices.Contains(deployed, a.Component) {
+				deployed = append(deployed, a.Component)
+			}
+		case "uninstall":
+			deployed = slices.DeleteFunc(deployed, func(c string) bool { return c == a.Component })
+		}
+	}
+	if !installs {
+		return nil
+	}
+	nodes, err := o.topologyNodes(ctx, ws)
+	if err != nil {
+		return huma.Error500InternalServerError("failed to list nodes", err)
+	}
+	if len(nodes) == 0 {
+		return nil
+	}
+	if res := checkTopology(o.registry(ws), nodes, deployed); !res.Valid {
+		return TopologyError("node roles break topology rules; fix them or set skip_validation", res.Errors)
+	}
+	return nil
+}
+
+// TopologyError builds a 422 response listing issues as error details.
+func TopologyError(msg string, issues []TopologyIssue) error {
+	details := make([]error, 0, len(issues))
+	for _, i := range issues {
+		location := i.Role
+		if location == "" {
+			location = i.Component
+		}
+		details = append(details, &huma.ErrorDetail{
+			Message:  i.Message,
+			Location: location,
+			Value:    i.Rule,
+		})
+	}
+	return huma.Error422UnprocessableEntity(msg, details...)
+}
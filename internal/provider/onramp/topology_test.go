@@ -0,0 +1,156 @@
+package onramp
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/bengrewell/aether-webui/internal/store"
+)
+
+// topologyKeys returns "rule" or "rule/role" for each issue, for compact
+// comparisons.
+func topologyKeys(issues []TopologyIssue) []string {
+	out := make([]string, 0, len(issues))
+	for _, i := range issues {
+		key := i.Rule
+		if i.Role != "" {
+			key += "/" + i.Role
+		} else if i.Component != "" {
+			key += "/" + i.Component
+		}
+		out = append(out, key)
+	}
+	return out
+}
+
+func TestCheckTopology_Rules(t *testing.T) {
+	tests := []struct {
+		name       string
+		nodes      []TopologyNode
+		components []string
+		errors     []string
+		warnings   []string
+	}{
+		{
+			name:  "single master and worker",
+			nodes: []TopologyNode{{Name: "n1", Roles: []string{"master"}}, {Name: "n2", Roles: []string{"worker"}}},
+		},
+		{
+			name:   "two masters",
+			nodes:  []TopologyNode{{Name: "n1", Roles: []string{"master"}}, {Name: "n2", Roles: []string{"master"}}},
+			errors: []string{"role_max/master"},
+		},
+		{
+			name:   "worker without master",
+			nodes:  []TopologyNode{{Name: "n1", Roles: []string{"worker"}}},
+			errors: []string{"role_requires/worker"},
+		},
+		{
+			name:   "unknown role",
+			nodes:  []TopologyNode{{Name: "n1", Roles: []string{"master", "foo"}}},
+			errors: []string{"role_unknown/foo"},
+		},
+		{
+			name:     "conflicting ran roles",
+			nodes:    []TopologyNode{{Name: "n1", Roles: []string{"master", "gnbsim", "srsran"}}},
+			errors:   []string{"role_conflict/gnbsim"},
+			warnings: []string{"role_components/gnbsim", "role_components/srsran"},
+		},
+		{
+			name:     "gnbsim without core",
+			nodes:    []TopologyNode{{Name: "n1", Roles: []string{"master", "gnbsim"}}},
+			warnings: []string{"role_components/gnbsim"},
+		},
+		{
+			name:       "gnbsim with core",
+			nodes:      []TopologyNode{{Name: "n1", Roles: []string{"master", "gnbsim"}}},
+			components: []string{"k8s", "5gc", "gnbsim"},
+		},
+		{
+			name:       "component without its role",
+			nodes:      []TopologyNode{{Name: "n1", Roles: []string{"master"}}},
+			components: []string{"5gc", "gnbsim"},
+			errors:     []string{"component_roles/gnbsim"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := checkTopology(builtinRegistry(), tt.nodes, tt.components)
+			if got := topologyKeys(res.Errors); !slices.Equal(got, tt.errors) {
+				t.Errorf("errors = %v, want %v", got, tt.errors)
+			}
+			if got := topologyKeys(res.Warnings); !slices.Equal(got, tt.warnings) {
+				t.Errorf("warnings = %v, want %v", got, tt.warnings)
+			}
+			if res.Valid != (len(tt.errors) == 0) {
+				t.Errorf("valid = %v", res.Valid)
+			}
+		})
+	}
+}
+
+func TestCheckTopology_OverlayRule(t *testing.T) {
+	ov, err := parseOverlay(builtinOverlay)
+	if err != nil {
+		t.Fatalf("parseOverlay: %v", err)
+	}
+	next, err := parseOverlay([]byte("topology:\n  master: {max: 3}\n"))
+	if err != nil {
+		t.Fatalf("parseOverlay: %v", err)
+	}
+	ov.merge(next)
+	reg := buildRegistry(ov, nil, nil)
+
+	nodes := []TopologyNode{{Name: "n1", Roles: []string{"master"}}, {Name: "n2", Roles: []string{"master"}}}
+	if res := checkTopology(reg, nodes, nil); !res.Valid {
+		t.Errorf("errors = %v", topologyKeys(res.Errors))
+	}
+}
+
+func TestHandleValidateTopology_Workspace(t *testing.T) {
+	o := newTestProviderWithStore(t, "")
+	addNode(t, o, store.Node{ID: "n1", Name: "node1", AnsibleHost: "10.0.0.1", Roles: []string{"master"}})
+	addNode(t, o, store.Node{ID: "n2", Name: "node2", AnsibleHost: "10.0.0.2", Roles: []string{"master"}})
+	if err := o.Store().UpsertComponentState(t.Context(), store.ComponentState{Component: "5gc", Status: "installed"}); err != nil {
+		t.Fatalf("UpsertComponentState: %v", err)
+	}
+
+	out, err := o.HandleValidateTopology(t.Context(), &TopologyValidateInput{})
+	if err != nil {
+		t.Fatalf("HandleValidateTopology: %v", err)
+	}
+	if out.Body.Valid || !slices.Equal(topologyKeys(out.Body.Errors), []string{"role_max/master"}) {
+		t.Errorf("result = %+v", out.Body)
+	}
+	if !slices.Equal(out.Body.Components, []string{"5gc"}) {
+		t.Errorf("components = %v, want [5gc]", out.Body.Components)
+	}
+
+	// A body overrides the workspace's nodes and components.
+	in := &TopologyValidateInput{Body: &TopologyValidateBody{
+		Nodes:      []TopologyNode{{Name: "a", Roles: []string{"master", "gnbsim"}}},
+		Components: []string{"5gc", "gnbsim"},
+	}}
+	if out, err = o.HandleValidateTopology(t.Context(), in); err != nil {
+		t.Fatalf("HandleValidateTopology: %v", err)
+	}
+	if !out.Body.Valid || len(out.Body.Warnings) != 0 {
+		t.Errorf("result = %+v", out.Body)
+	}
+
+	in.Body.Components = []string{"nope"}
+	_, err = o.HandleValidateTopology(t.Context(), in)
+	wantStatus(t, err, 422)
+}
+
+func TestSyncInventory_Topology(t *testing.T) {
+	o := newTestProviderWithStore(t, "")
+	addNode(t, o, store.Node{ID: "n1", Name: "node1", AnsibleHost: "10.0.0.1", AnsibleUser: "aether", Roles: []string{"worker"}})
+
+	_, err := o.HandleSyncInventory(t.Context(), &InventorySyncInput{})
+	wantStatus(t, err, 422)
+
+	if _, err := o.HandleSyncInventory(t.Context(), &InventorySyncInput{SkipValidation: true}); err != nil {
+		t.Fatalf("sync with skip_validation: %v", err)
+	}
+}
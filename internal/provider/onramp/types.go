@@ -1,6 +1,7 @@
 package onramp
 
 import (
+	"io"
 	"time"
 
 	"github.com/bengrewell/aether-webui/internal/taskrunner"
@@ -11,139 +12,30 @@ import (
 // ---------------------------------------------------------------------------
 
 // Component describes a deployable OnRamp component and its available actions.
+// The set of components comes from the checked-out Makefile's aether-*
+// targets; see components.yaml for the overlay that describes them.
 type Component struct {
 	Name        string   `json:"name"`
 	Description string   `json:"description"`
+	Tier        int      `json:"tier" doc:"Install order; lower tiers install first and uninstall last"`
+	Section     string   `json:"section,omitempty" doc:"Top-level vars/main.yml key the component configures"`
+	Blueprint   string   `json:"blueprint,omitempty" doc:"vars/ file merged by config compose when the component is selected"`
+	KeepSection bool     `json:"keep_section,omitempty" doc:"Config compose keeps the section even when the component is not selected"`
+	Requires    []string `json:"requires,omitempty" doc:"Components config compose adds when this one is selected"`
+	Roles       []string `json:"roles,omitempty" doc:"Node roles the component runs on"`
+	Discovered  bool     `json:"discovered,omitempty" doc:"Found in the Makefile but not described by an overlay"`
+	Custom      bool     `json:"custom,omitempty" doc:"User-defined through the custom components API"`
 	Actions     []Action `json:"actions"`
 }
 
-// Action maps a human-readable operation to a Makefile target.
+// Action maps a human-readable operation to a Makefile target, or for custom
+// components to a playbook or script.
 type Action struct {
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	Target      string `json:"target"`
-}
-
-// componentRegistry is the static set of components derived from the OnRamp Makefile.
-var componentRegistry = []Component{
-	{
-		Name:        "k8s",
-		Description: "Kubernetes (RKE2) cluster lifecycle",
-		Actions: []Action{
-			{Name: "install", Description: "Deploy Kubernetes (RKE2)", Target: "aether-k8s-install"},
-			{Name: "uninstall", Description: "Remove Kubernetes (RKE2)", Target: "aether-k8s-uninstall"},
-		},
-	},
-	{
-		Name:        "5gc",
-		Description: "5G core network (SD-Core)",
-		Actions: []Action{
-			{Name: "install", Description: "Deploy 5G core", Target: "aether-5gc-install"},
-			{Name: "uninstall", Description: "Remove 5G core", Target: "aether-5gc-uninstall"},
-			{Name: "reset", Description: "Reset 5G core state", Target: "aether-5gc-reset"},
-		},
-	},
-	{
-		Name:        "4gc",
-		Description: "4G core network",
-		Actions: []Action{
-			{Name: "install", Description: "Deploy 4G core", Target: "aether-4gc-install"},
-			{Name: "uninstall", Description: "Remove 4G core", Target: "aether-4gc-uninstall"},
-			{Name: "reset", Description: "Reset 4G core state", Target: "aether-4gc-reset"},
-		},
-	},
-	{
-		Name:        "gnbsim",
-		Description: "gNBSim simulated RAN",
-		Actions: []Action{
-			{Name: "install", Description: "Deploy gNBSim", Target: "aether-gnbsim-install"},
-			{Name: "uninstall", Description: "Remove gNBSim", Target: "aether-gnbsim-uninstall"},
-			{Name: "run", Description: "Run gNBSim simulation", Target: "aether-gnbsim-run"},
-		},
-	},
-	{
-		Name:        "amp",
-		Description: "Aether Management Platform",
-		Actions: []Action{
-			{Name: "install", Description: "Deploy AMP", Target: "aether-amp-install"},
-			{Name: "uninstall", Description: "Remove AMP", Target: "aether-amp-uninstall"},
-		},
-	},
-	{
-		Name:        "sdran",
-		Description: "SD-RAN intelligent RAN controller",
-		Actions: []Action{
-			{Name: "install", Description: "Deploy SD-RAN", Target: "aether-sdran-install"},
-			{Name: "uninstall", Description: "Remove SD-RAN", Target: "aether-sdran-uninstall"},
-		},
-	},
-	{
-		Name:        "ueransim",
-		Description: "UERANSIM UE and gNB simulator",
-		Actions: []Action{
-			{Name: "install", Description: "Deploy UERANSIM", Target: "aether-ueransim-install"},
-			{Name: "uninstall", Description: "Remove UERANSIM", Target: "aether-ueransim-uninstall"},
-			{Name: "run", Description: "Start UERANSIM simulation", Target: "aether-ueransim-run"},
-			{Name: "stop", Description: "Stop UERANSIM simulation", Target: "aether-ueransim-stop"},
-		},
-	},
-	{
-		Name:        "oai",
-		Description: "OpenAirInterface RAN",
-		Actions: []Action{
-			{Name: "gnb-install", Description: "Deploy OAI gNB", Target: "aether-oai-gnb-install"},
-			{Name: "gnb-uninstall", Description: "Remove OAI gNB", Target: "aether-oai-gnb-uninstall"},
-			{Name: "uesim-start", Description: "Start OAI UE simulator", Target: "aether-oai-uesim-start"},
-			{Name: "uesim-stop", Description: "Stop OAI UE simulator", Target: "aether-oai-uesim-stop"},
-		},
-	},
-	{
-		Name:        "srsran",
-		Description: "srsRAN Project RAN",
-		Actions: []Action{
-			{Name: "gnb-install", Description: "Deploy srsRAN gNB", Target: "aether-srsran-gnb-install"},
-			{Name: "gnb-uninstall", Description: "Remove srsRAN gNB", Target: "aether-srsran-gnb-uninstall"},
-			{Name: "uesim-start", Description: "Start srsRAN UE simulator", Target: "aether-srsran-uesim-start"},
-			{Name: "uesim-stop", Description: "Stop srsRAN UE simulator", Target: "aether-srsran-uesim-stop"},
-		},
-	},
-	{
-		Name:        "oscric",
-		Description: "O-RAN SC near-RT RIC",
-		Actions: []Action{
-			{Name: "ric-install", Description: "Deploy OSC near-RT RIC", Target: "aether-oscric-ric-install"},
-			{Name: "ric-uninstall", Description: "Remove OSC near-RT RIC", Target: "aether-oscric-ric-uninstall"},
-		},
-	},
-	{
-		Name:        "n3iwf",
-		Description: "Non-3GPP Interworking Function",
-		Actions: []Action{
-			{Name: "install", Description: "Deploy N3IWF", Target: "aether-n3iwf-install"},
-			{Name: "uninstall", Description: "Remove N3IWF", Target: "aether-n3iwf-uninstall"},
-		},
-	},
-	{
-		Name:        "cluster",
-		Description: "Cluster-level operations",
-		Actions: []Action{
-			{Name: "pingall", Description: "Ping all cluster nodes", Target: "aether-pingall"},
-			{Name: "install", Description: "Deploy full Aether stack", Target: "aether-install"},
-			{Name: "uninstall", Description: "Remove full Aether stack", Target: "aether-uninstall"},
-			{Name: "add-upfs", Description: "Add additional UPFs", Target: "aether-add-upfs"},
-			{Name: "remove-upfs", Description: "Remove additional UPFs", Target: "aether-remove-upfs"},
-		},
-	},
-}
-
-// componentIndex provides O(1) lookup by component name.
-var componentIndex map[string]*Component
-
-func init() {
-	componentIndex = make(map[string]*Component, len(componentRegistry))
-	for i := range componentRegistry {
-		componentIndex[componentRegistry[i].Name] = &componentRegistry[i]
-	}
+	Name        string   `json:"name"`
+	Description string   `json:"description" required:"false"`
+	Kind        string   `json:"kind,omitempty" enum:"make,playbook,script" doc:"How the action runs; default make"`
+	Target      string   `json:"target" doc:"Make target, or a path relative to the playbook or script directory"`
+	Args        []string `json:"args,omitempty" doc:"KEY=value variables for make or ansible-playbook (passed as --extra-vars), or arguments for the script"`
 }
 
 // ---------------------------------------------------------------------------
@@ -164,6 +56,9 @@ type OnRampTask struct {
 	ExitCode     int       `json:"exit_code"`
 	Output       string    `json:"output"`
 	OutputOffset int       `json:"output_offset"`
+
+	QueuePosition    int       `json:"queue_position,omitempty"`
+	EstimatedStartAt time.Time `json:"estimated_start_at,omitzero"`
 }
 
 // toOnRampTask converts a TaskView and output chunk into the OnRamp-specific
@@ -181,6 +76,9 @@ func toOnRampTask(view taskrunner.TaskView, output string, outputOffset int) OnR
 		ExitCode:     view.ExitCode,
 		Output:       output,
 		OutputOffset: outputOffset,
+
+		QueuePosition:    view.QueuePosition,
+		EstimatedStartAt: view.EstimatedStartAt,
 	}
 }
 
@@ -481,6 +379,7 @@ type N3IWFServer struct {
 
 // RepoStatus describes the current state of the cloned OnRamp repository.
 type RepoStatus struct {
+	Workspace string `json:"workspace"`
 	Cloned    bool   `json:"cloned"`
 	Dir       string `json:"dir"`
 	RepoURL   string `json:"repo_url"`
@@ -490,12 +389,94 @@ type RepoStatus struct {
 	Tag       string `json:"tag,omitempty"`
 	Dirty     bool   `json:"dirty"`
 	Error     string `json:"error,omitempty"`
+
+	Bundle *RepoProvenance `json:"bundle,omitempty"`
+}
+
+// RepoProvenance records where an offline bundle came from and how it was
+// verified. It is set when the checkout was imported rather than cloned.
+type RepoProvenance struct {
+	Source     string `json:"source" doc:"upload or file"`
+	Filename   string `json:"filename,omitempty"`
+	Format     string `json:"format" doc:"tar or git-bundle"`
+	SHA256     string `json:"sha256"`
+	VerifiedBy string `json:"verified_by" doc:"sha256 or signature"`
+	KeyID      string `json:"key_id,omitempty" doc:"Fingerprint of the key that signed the bundle"`
+	Commit     string `json:"commit"`
+	Version    string `json:"version"`
+	ImportedAt int64  `json:"imported_at"`
+}
+
+// RepoRef is a tag or branch available in the local clone.
+type RepoRef struct {
+	Name   string `json:"name"`
+	Commit string `json:"commit"`
+	Date   int64  `json:"date,omitempty"`
+	Remote bool   `json:"remote,omitempty"`
+}
+
+// RepoVersions lists the refs a workspace can be pinned to.
+type RepoVersions struct {
+	Version  string    `json:"version"`
+	Tags     []RepoRef `json:"tags"`
+	Branches []RepoRef `json:"branches"`
+}
+
+// RepoChange is one modified, added, deleted, or untracked path reported by
+// git status.
+type RepoChange struct {
+	Path   string `json:"path"`
+	Status string `json:"status"`
+}
+
+// RepoDiff describes local modifications to a workspace's checkout.
+type RepoDiff struct {
+	Dirty     bool         `json:"dirty"`
+	Changes   []RepoChange `json:"changes"`
+	Diff      string       `json:"diff"`
+	Truncated bool         `json:"truncated,omitempty"`
 }
 
 // ---------------------------------------------------------------------------
 // Huma I/O types
 // ---------------------------------------------------------------------------
 
+// --- Workspace scoping ---
+
+// WorkspaceParam selects the workspace a request applies to. It is embedded
+// in every workspace-scoped input; an empty value means the default
+// workspace.
+type WorkspaceParam struct {
+	Workspace string `query:"workspace" doc:"Workspace name; omit for the default workspace" example:"lab2"`
+}
+
+// WorkspaceInput is the input for workspace-scoped endpoints that take no
+// other parameters.
+type WorkspaceInput struct {
+	WorkspaceParam
+}
+
+// workspaceName returns the requested workspace, tolerating a nil input
+// from internal callers.
+func (in *WorkspaceInput) workspaceName() string {
+	if in == nil {
+		return ""
+	}
+	return in.Workspace
+}
+
+// AuthorParam identifies who made a config change. It is embedded in inputs
+// that write vars/main.yml and recorded in the config history.
+type AuthorParam struct {
+	Author string `header:"X-Aether-Author" doc:"Who is making the change; recorded in the config history" example:"alice"`
+}
+
+// DryRunParam asks an endpoint that writes vars/main.yml to report what it
+// would write instead.
+type DryRunParam struct {
+	DryRun bool `query:"dry_run" default:"false" doc:"Return the resulting config and a diff against vars/main.yml without writing anything"`
+}
+
 // --- Repo ---
 
 type RepoStatusOutput struct {
@@ -506,6 +487,66 @@ type RepoRefreshOutput struct {
 	Body RepoStatus
 }
 
+type RepoVersionsOutput struct {
+	Body RepoVersions
+}
+
+type RepoVersionSetInput struct {
+	WorkspaceParam
+	Body struct {
+		Version string `json:"version" minLength:"1" doc:"Tag, branch, or commit to pin" example:"v2.1.0"`
+		Force   bool   `json:"force,omitempty" doc:"Switch even when components are installed"`
+	}
+}
+
+type RepoVersionSetOutput struct {
+	Body RepoStatus
+}
+
+type RepoDiffOutput struct {
+	Body RepoDiff
+}
+
+type RepoBundleImportInput struct {
+	WorkspaceParam
+	Filename  string `query:"filename" doc:"Original archive name, recorded in the provenance" example:"aether-onramp-v2.1.0.tar.gz"`
+	SHA256    string `query:"sha256" doc:"Expected SHA-256 of the archive, hex encoded"`
+	Signature string `query:"signature" doc:"Base64 ed25519 signature of the archive's SHA-256 digest"`
+	Force     bool   `query:"force" doc:"Replace the checkout even when components are installed or it has local changes"`
+
+	// Bundle is the unread request body, set by Resolve.
+	Bundle io.Reader
+}
+
+type RepoBundleImportOutput struct {
+	Body RepoStatus
+}
+
+type RepoStashInput struct {
+	WorkspaceParam
+	Body *RepoStashBody `json:",omitempty"`
+}
+
+type RepoStashBody struct {
+	Message string `json:"message,omitempty" doc:"Stash message"`
+}
+
+type RepoResetInput struct {
+	WorkspaceParam
+	Body *RepoResetBody `json:",omitempty"`
+}
+
+type RepoResetBody struct {
+	Clean bool `json:"clean,omitempty" doc:"Also delete untracked files (ignored files are kept)"`
+}
+
+type RepoLocalChangesOutput struct {
+	Body struct {
+		Message string     `json:"message"`
+		Status  RepoStatus `json:"status"`
+	}
+}
+
 // --- Components ---
 
 type ComponentListOutput struct {
@@ -513,6 +554,7 @@ type ComponentListOutput struct {
 }
 
 type ComponentGetInput struct {
+	WorkspaceParam
 	Component string `path:"component" doc:"Component name"`
 }
 
@@ -521,6 +563,7 @@ type ComponentGetOutput struct {
 }
 
 type ExecuteActionInput struct {
+	WorkspaceParam
 	Component string            `path:"component" doc:"Component name"`
 	Action    string            `path:"action" doc:"Action name"`
 	Body      *ExecuteActionBody `json:",omitempty"`
@@ -542,6 +585,7 @@ type TaskListOutput struct {
 }
 
 type TaskGetInput struct {
+	WorkspaceParam
 	ID     string `path:"id" doc:"Task ID"`
 	Offset int    `query:"offset" default:"0" doc:"Byte offset for incremental output reads"`
 }
@@ -550,28 +594,179 @@ type TaskGetOutput struct {
 	Body OnRampTask
 }
 
+// --- Queue ---
+
+// QueueStatus describes the pending task queue in start order.
+type QueueStatus struct {
+	Paused bool         `json:"paused"`
+	Depth  int          `json:"depth"`
+	Tasks  []OnRampTask `json:"tasks"`
+}
+
+type QueueGetOutput struct {
+	Body QueueStatus
+}
+
+type QueueMoveInput struct {
+	WorkspaceParam
+	ID   string `path:"id" doc:"Task ID"`
+	Body struct {
+		Position int `json:"position" minimum:"1" doc:"New 1-based queue position (clamped to the queue length)"`
+	}
+}
+
+type QueueTaskInput struct {
+	WorkspaceParam
+	ID string `path:"id" doc:"Task ID"`
+}
+
+type QueueUpdateOutput struct {
+	Body QueueStatus
+}
+
+type QueueRemoveOutput struct {
+	Body struct {
+		Message string `json:"message"`
+	}
+}
+
+type QueueDrainOutput struct {
+	Body struct {
+		Message  string   `json:"message"`
+		Canceled []string `json:"canceled"`
+	}
+}
+
 // --- Config ---
 
 type ConfigGetOutput struct {
+	ETag string `header:"ETag" doc:"Hash of vars/main.yml; send it as If-Match when patching"`
 	Body OnRampConfig
 }
 
+// ConfigPatchInput carries a merge patch (RFC 7386) or, when Content-Type
+// is application/json-patch+json, a JSON Patch (RFC 6902). The body is kept
+// raw because the two formats have different shapes.
 type ConfigPatchInput struct {
-	RawBody []byte
-	Body    OnRampConfig
+	WorkspaceParam
+	AuthorParam
+	Force       bool   `query:"force" doc:"Write the config even if the patch introduces validation errors"`
+	IfMatch     string `header:"If-Match" doc:"ETag from a previous read; the patch is refused with 412 if the file has changed since"`
+	ContentType string `header:"Content-Type" doc:"application/merge-patch+json (default) or application/json-patch+json"`
+	RawBody     []byte `contentType:"application/merge-patch+json"`
 }
 
 type ConfigPatchOutput struct {
+	ETag string `header:"ETag" doc:"Hash of the patched vars/main.yml"`
 	Body OnRampConfig
 }
 
+type ConfigValidateInput struct {
+	WorkspaceParam
+	Body *OnRampConfig `doc:"Config to validate; omit to validate the workspace's vars/main.yml"`
+}
+
+type ConfigValidateOutput struct {
+	Body ValidationResult
+}
+
+// ValidationResult is the outcome of checking a config. Valid is false when
+// there is at least one error; warnings never make a config invalid.
+type ValidationResult struct {
+	Valid    bool              `json:"valid"`
+	Errors   []ValidationIssue `json:"errors"`
+	Warnings []ValidationIssue `json:"warnings"`
+}
+
+// ValidationIssue is one problem found in a config.
+type ValidationIssue struct {
+	Field    string `json:"field" doc:"Dotted config path" example:"core.upf.access_subnet"`
+	Rule     string `json:"rule" doc:"Rule that found the problem" enum:"cidr,ip,ip_in_subnet,overlap,interface,facts"`
+	Severity string `json:"severity" enum:"error,warning"`
+	Message  string `json:"message"`
+	Node     string `json:"node,omitempty" doc:"Node the check ran against, for rules that use node facts"`
+}
+
+// --- Topology ---
+
+// TopologyNode is a node's role assignment as topology validation sees it.
+type TopologyNode struct {
+	Name  string   `json:"name"`
+	Roles []string `json:"roles"`
+}
+
+type TopologyValidateInput struct {
+	WorkspaceParam
+	Body *TopologyValidateBody `doc:"Assignment to validate; omit to validate the workspace's nodes against its deployed components"`
+}
+
+type TopologyValidateBody struct {
+	Nodes      []TopologyNode `json:"nodes,omitempty" doc:"Nodes and their roles; omit for the workspace's nodes"`
+	Components []string       `json:"components,omitempty" doc:"Components that will be deployed; omit for the components installed or installing"`
+}
+
+type TopologyValidateOutput struct {
+	Body TopologyResult
+}
+
+// TopologyResult is the outcome of checking node role assignments. Valid is
+// false when there is at least one error.
+type TopologyResult struct {
+	Valid      bool            `json:"valid"`
+	Components []string        `json:"components" doc:"Components the check took as deployed"`
+	Errors     []TopologyIssue `json:"errors"`
+	Warnings   []TopologyIssue `json:"warnings"`
+}
+
+// TopologyIssue is one broken topology rule.
+type TopologyIssue struct {
+	Rule      string   `json:"rule" doc:"Rule that found the problem" enum:"role_unknown,role_max,role_conflict,role_requires,component_roles,role_components"`
+	Severity  string   `json:"severity" enum:"error,warning"`
+	Message   string   `json:"message"`
+	Role      string   `json:"role,omitempty"`
+	Component string   `json:"component,omitempty"`
+	Nodes     []string `json:"nodes,omitempty" doc:"Nodes the problem is about"`
+}
+
+// --- Config schema ---
+
+type ConfigSectionSchemaInput struct {
+	Section string `path:"section" doc:"Top-level section of vars/main.yml" example:"core"`
+}
+
+// ConfigSchemaOutput carries a JSON Schema document. It is built from
+// OnRampConfig at runtime, so it is returned as a plain object.
+type ConfigSchemaOutput struct {
+	Body map[string]any
+}
+
 // --- Profiles ---
 
+// ProfileInfo describes a config profile. Blueprints are the
+// vars/main-*.yml files that ship with OnRamp and cannot be changed through
+// the API; other files in vars/ and profiles saved in the database can.
+type ProfileInfo struct {
+	Name        string `json:"name"`
+	Source      string `json:"source" enum:"blueprint,file,store" doc:"blueprint: vars/main-<name>.yml tracked by the OnRamp repo; file: an untracked vars/main-<name>.yml; store: saved in the database"`
+	ReadOnly    bool   `json:"read_only"`
+	Description string `json:"description,omitempty"`
+	Author      string `json:"author,omitempty"`
+	UpdatedAt   int64  `json:"updated_at,omitempty" doc:"Last change to a stored profile (Unix seconds)"`
+}
+
+// ProfileSourceParam picks between a vars file and a stored profile of the
+// same name. Without it, files are preferred.
+type ProfileSourceParam struct {
+	Source string `query:"source" enum:"file,store" doc:"Where to look up the profile; omit to try vars/ first, then the database"`
+}
+
 type ProfileListOutput struct {
-	Body []string
+	Body []ProfileInfo
 }
 
 type ProfileGetInput struct {
+	WorkspaceParam
+	ProfileSourceParam
 	Name string `path:"name" doc:"Profile name"`
 }
 
@@ -580,7 +775,141 @@ type ProfileGetOutput struct {
 }
 
 type ProfileActivateInput struct {
+	WorkspaceParam
+	ProfileSourceParam
+	AuthorParam
+	Name string `path:"name" doc:"Profile name"`
+}
+
+type ProfileCreateInput struct {
+	WorkspaceParam
+	AuthorParam
+	Body struct {
+		Name        string `json:"name" pattern:"^[a-z0-9][a-z0-9_-]{0,62}$" doc:"Profile name (lowercase letters, digits, dashes, and underscores)"`
+		Description string `json:"description,omitempty"`
+		Storage     string `json:"storage,omitempty" enum:"store,file" doc:"Save in the database (default when one is configured) or as vars/main-<name>.yml"`
+		From        string `json:"from,omitempty" doc:"Profile to copy; omit to save the active vars/main.yml"`
+		Overwrite   bool   `json:"overwrite,omitempty" doc:"Replace an existing profile of the same name and storage"`
+	}
+}
+
+type ProfileCreateOutput struct {
+	Body ProfileInfo
+}
+
+type ProfileRenameInput struct {
+	WorkspaceParam
+	ProfileSourceParam
 	Name string `path:"name" doc:"Profile name"`
+	Body struct {
+		NewName string `json:"new_name" pattern:"^[a-z0-9][a-z0-9_-]{0,62}$" doc:"New profile name"`
+	}
+}
+
+type ProfileRenameOutput struct {
+	Body ProfileInfo
+}
+
+type ProfileDeleteInput struct {
+	WorkspaceParam
+	ProfileSourceParam
+	Name string `path:"name" doc:"Profile name"`
+}
+
+type ProfileDeleteOutput struct {
+	Body struct {
+		Message string `json:"message"`
+	}
+}
+
+type ProfileDiffInput struct {
+	WorkspaceParam
+	ProfileSourceParam
+	Name     string `path:"name" doc:"Profile to diff from"`
+	To       string `query:"to" doc:"Profile to diff to; omit to compare with the active vars/main.yml"`
+	ToSource string `query:"to_source" enum:"file,store" doc:"Where to look up the to profile"`
+}
+
+// ProfileDiff compares a profile with another profile or the active config.
+type ProfileDiff struct {
+	From    string         `json:"from"`
+	To      string         `json:"to" doc:"Empty means the active vars/main.yml"`
+	Unified string         `json:"unified"`
+	Changes []ConfigChange `json:"changes"`
+}
+
+type ProfileDiffOutput struct {
+	Body ProfileDiff
+}
+
+// ConfigRevision is a recorded version of vars/main.yml.
+type ConfigRevision struct {
+	ID        int64  `json:"id"`
+	Workspace string `json:"workspace"`
+	Source    string `json:"source" doc:"Operation that wrote the file: patch, profile, compose, configdefaults, rollback, or external for edits made outside the API"`
+	Note      string `json:"note,omitempty"`
+	Author    string `json:"author,omitempty"`
+	Hash      string `json:"hash" doc:"SHA-256 of the file content"`
+	CreatedAt int64  `json:"created_at"`
+	Content   string `json:"content,omitempty" doc:"File content; only returned for a single revision"`
+}
+
+// ConfigChange is one difference between two parsed configs. Lists are
+// compared as a whole.
+type ConfigChange struct {
+	Path string `json:"path" doc:"Dotted key path" example:"core.data_iface"`
+	Kind string `json:"kind" enum:"added,removed,changed"`
+	Old  any    `json:"old,omitempty"`
+	New  any    `json:"new,omitempty"`
+}
+
+// ConfigPreview is what a dry run would write to vars/main.yml.
+type ConfigPreview struct {
+	Content string         `json:"content" doc:"The resulting vars/main.yml"`
+	Unified string         `json:"unified" doc:"Unified diff from the current file"`
+	Changes []ConfigChange `json:"changes"`
+}
+
+// ConfigRevisionDiff compares two revisions, or a revision with the current
+// file when To is 0.
+type ConfigRevisionDiff struct {
+	From    int64          `json:"from"`
+	To      int64          `json:"to" doc:"0 means the current vars/main.yml"`
+	Unified string         `json:"unified"`
+	Changes []ConfigChange `json:"changes"`
+}
+
+type ConfigRevisionListOutput struct {
+	Body []ConfigRevision
+}
+
+type ConfigRevisionGetInput struct {
+	WorkspaceParam
+	ID int64 `path:"id" doc:"Revision ID"`
+}
+
+type ConfigRevisionGetOutput struct {
+	Body ConfigRevision
+}
+
+type ConfigRevisionDiffInput struct {
+	WorkspaceParam
+	ID int64 `path:"id" doc:"Revision to diff from"`
+	To int64 `query:"to" doc:"Revision to diff to; omit to compare with the current vars/main.yml"`
+}
+
+type ConfigRevisionDiffOutput struct {
+	Body ConfigRevisionDiff
+}
+
+type ConfigRevisionRollbackInput struct {
+	WorkspaceParam
+	AuthorParam
+	ID int64 `path:"id" doc:"Revision to restore"`
+}
+
+type ConfigRevisionRollbackOutput struct {
+	Body ConfigRevision
 }
 
 type ProfileActivateOutput struct {
@@ -592,6 +921,7 @@ type ProfileActivateOutput struct {
 // --- Actions ---
 
 type ActionListInput struct {
+	WorkspaceParam
 	Component string `query:"component" doc:"Filter by component name"`
 	Action    string `query:"action" doc:"Filter by action name"`
 	Status    string `query:"status" doc:"Filter by status"`
@@ -604,6 +934,7 @@ type ActionListOutput struct {
 }
 
 type ActionGetInput struct {
+	WorkspaceParam
 	ID string `path:"id" doc:"Action ID"`
 }
 
@@ -614,6 +945,7 @@ type ActionGetOutput struct {
 // ActionHistoryItem is the API-facing representation of an action execution.
 type ActionHistoryItem struct {
 	ID         string            `json:"id"`
+	Workspace  string            `json:"workspace"`
 	Component  string            `json:"component"`
 	Action     string            `json:"action"`
 	Target     string            `json:"target"`
@@ -624,6 +956,7 @@ type ActionHistoryItem struct {
 	Tags       []string          `json:"tags,omitempty"`
 	StartedAt  int64             `json:"started_at"`
 	FinishedAt int64             `json:"finished_at,omitempty"`
+	Hooks      []HookRunItem     `json:"hooks,omitempty" doc:"Hooks run for the action (single action only)"`
 }
 
 // --- Component State ---
@@ -633,6 +966,7 @@ type ComponentStateListOutput struct {
 }
 
 type ComponentStateGetInput struct {
+	WorkspaceParam
 	Component string `path:"component" doc:"Component name"`
 }
 
@@ -654,11 +988,13 @@ type ComponentStateItem struct {
 // ---------------------------------------------------------------------------
 
 type DeployInput struct {
+	WorkspaceParam
 	Body DeployBody
 }
 
 type DeployBody struct {
-	Actions []ComponentActionPair `json:"actions"`
+	Actions        []ComponentActionPair `json:"actions"`
+	SkipValidation bool                  `json:"skip_validation,omitempty" doc:"Start install actions even if vars/main.yml has validation errors or node roles break topology rules"`
 }
 
 type ComponentActionPair struct {
@@ -671,6 +1007,7 @@ type DeployOutput struct {
 }
 
 type DeploymentListInput struct {
+	WorkspaceParam
 	Status string `query:"status" default:"" doc:"Filter by status"`
 	Limit  int    `query:"limit" default:"20" doc:"Max results"`
 	Offset int    `query:"offset" default:"0" doc:"Pagination offset"`
@@ -681,6 +1018,7 @@ type DeploymentListOutput struct {
 }
 
 type DeploymentGetInput struct {
+	WorkspaceParam
 	ID string `path:"id" doc:"Deployment ID"`
 }
 
@@ -689,6 +1027,7 @@ type DeploymentGetOutput struct {
 }
 
 type DeploymentCancelInput struct {
+	WorkspaceParam
 	ID string `path:"id" doc:"Deployment ID"`
 }
 
@@ -700,6 +1039,7 @@ type DeploymentCancelOutput struct {
 
 type DeploymentItem struct {
 	ID         string                 `json:"id"`
+	Workspace  string                 `json:"workspace"`
 	Status     string                 `json:"status"`
 	Actions    []DeploymentActionItem `json:"actions"`
 	CreatedAt  int64                  `json:"created_at"`
@@ -709,11 +1049,72 @@ type DeploymentItem struct {
 }
 
 type DeploymentActionItem struct {
-	Seq       int    `json:"seq"`
-	ActionID  string `json:"action_id"`
-	Component string `json:"component"`
-	Action    string `json:"action"`
-	Status    string `json:"status"`
+	Seq       int           `json:"seq"`
+	ActionID  string        `json:"action_id"`
+	Component string        `json:"component"`
+	Action    string        `json:"action"`
+	Status    string        `json:"status"`
+	Hooks     []HookRunItem `json:"hooks,omitempty"`
+}
+
+// ---------------------------------------------------------------------------
+// Change lock types
+// ---------------------------------------------------------------------------
+
+// LockInfo describes the holder of the cluster change lock.
+type LockInfo struct {
+	LockID       string `json:"lock_id,omitempty"`
+	Kind         string `json:"kind"`
+	Owner        string `json:"owner"`
+	Reason       string `json:"reason,omitempty"`
+	DeploymentID string `json:"deployment_id,omitempty"`
+	AcquiredAt   int64  `json:"acquired_at"`
+	ExpiresAt    int64  `json:"expires_at,omitempty"`
+}
+
+type LockGetOutput struct {
+	Body struct {
+		Locked bool      `json:"locked"`
+		Lock   *LockInfo `json:"lock,omitempty"`
+	}
+}
+
+type LockAcquireInput struct {
+	WorkspaceParam
+	Body struct {
+		Owner      string `json:"owner" minLength:"1" doc:"Who is taking the lock"`
+		Reason     string `json:"reason,omitempty" doc:"Why the cluster is locked"`
+		TTLSeconds int    `json:"ttl_seconds,omitempty" minimum:"0" doc:"Lock lifetime in seconds (default 3600)"`
+	}
+}
+
+type LockAcquireOutput struct {
+	Body LockInfo
+}
+
+type LockReleaseInput struct {
+	WorkspaceParam
+	LockID string `query:"lock_id" required:"true" doc:"Lock ID returned when the lock was acquired"`
+}
+
+type LockReleaseOutput struct {
+	Body struct {
+		Message string `json:"message"`
+	}
+}
+
+type LockBreakInput struct {
+	WorkspaceParam
+	Body struct {
+		Reason string `json:"reason,omitempty" doc:"Why the lock is being broken (logged)"`
+	}
+}
+
+type LockBreakOutput struct {
+	Body struct {
+		Message string    `json:"message"`
+		Broken  *LockInfo `json:"broken,omitempty"`
+	}
 }
 
 // ---------------------------------------------------------------------------
@@ -721,19 +1122,28 @@ type DeploymentActionItem struct {
 // ---------------------------------------------------------------------------
 
 type InventoryData struct {
-	Nodes []InventoryNode `json:"nodes"`
+	Nodes     []InventoryNode              `json:"nodes"`
+	GroupVars map[string]map[string]string `json:"group_vars,omitempty" doc:"Variables of each role's [<role>_nodes:vars] section, by role"`
 }
 
 type InventoryNode struct {
-	Name        string   `json:"name"`
-	AnsibleHost string   `json:"ansible_host"`
-	AnsibleUser string   `json:"ansible_user"`
-	Roles       []string `json:"roles"`
+	Name              string            `json:"name"`
+	AnsibleHost       string            `json:"ansible_host"`
+	AnsibleUser       string            `json:"ansible_user"`
+	AnsiblePort       int               `json:"ansible_port,omitempty"`
+	PythonInterpreter string            `json:"python_interpreter,omitempty"`
+	SSHCommonArgs     string            `json:"ssh_common_args,omitempty"`
+	BecomeMethod      string            `json:"become_method,omitempty"`
+	HostVars          map[string]string `json:"host_vars,omitempty" doc:"Other host variables, passwords excluded"`
+	Roles             []string          `json:"roles"`
 }
 
 // --- Config Compose ---
 
 type ConfigComposeInput struct {
+	WorkspaceParam
+	AuthorParam
+	DryRunParam
 	Body ConfigComposeBody
 }
 
@@ -748,16 +1158,284 @@ type ConfigComposeOutput struct {
 type ConfigComposeResult struct {
 	ActiveBlueprints []string     `json:"active_blueprints,omitempty"`
 	Components       []string     `json:"components"`
+	Pruned           []string     `json:"pruned" doc:"Top-level sections removed because no selected component uses them"`
 	Config           OnRampConfig `json:"config"`
+	DryRun           bool         `json:"dry_run,omitempty"`
+	Preview          *ConfigPreview `json:"preview,omitempty" doc:"Only set for a dry run"`
 }
 
 type InventoryGetOutput struct {
 	Body InventoryData
 }
 
+type InventorySyncInput struct {
+	WorkspaceParam
+	Force          bool `query:"force" default:"false" doc:"Overwrite hosts.ini even if it was edited since the last sync"`
+	SkipValidation bool `query:"skip_validation" default:"false" doc:"Write hosts.ini even if node roles break topology rules"`
+}
+
 type InventorySyncOutput struct {
+	Body struct {
+		Message  string   `json:"message"`
+		Path     string   `json:"path"`
+		Warnings []string `json:"warnings,omitempty"`
+	}
+}
+
+// Drift kinds.
+const (
+	DriftMissingInFile = "missing_in_file"
+	DriftMissingInDB   = "missing_in_db"
+	DriftChanged       = "changed"
+)
+
+// InventoryDrift compares the node database with hosts.ini.
+type InventoryDrift struct {
+	InSync    bool                 `json:"in_sync" doc:"The database and hosts.ini list the same nodes with the same variables and roles, and the same group variables"`
+	Edited    bool                 `json:"edited" doc:"hosts.ini was edited since inventory sync last wrote it"`
+	Items     []InventoryDriftItem `json:"items"`
+	GroupVars []string             `json:"group_vars,omitempty" doc:"Roles whose group variables differ"`
+}
+
+// InventoryDriftItem is a node that differs between the database and
+// hosts.ini.
+type InventoryDriftItem struct {
+	Name   string         `json:"name"`
+	Kind   string         `json:"kind" enum:"missing_in_file,missing_in_db,changed"`
+	Fields []string       `json:"fields,omitempty" doc:"Fields that differ: ansible_host, ansible_user, ansible_port, ansible_python_interpreter, ansible_ssh_common_args, ansible_become_method, host_vars, roles"`
+	DB     *InventoryNode `json:"db,omitempty" doc:"The node in the database"`
+	File   *InventoryNode `json:"file,omitempty" doc:"The node in hosts.ini"`
+}
+
+type InventoryDriftOutput struct {
+	Body InventoryDrift
+}
+
+// Reconcile directions.
+const (
+	ReconcileToFile = "to_file"
+	ReconcileToDB   = "to_db"
+)
+
+type InventoryReconcileInput struct {
+	WorkspaceParam
+	Body struct {
+		Direction      string `json:"direction" enum:"to_file,to_db" doc:"to_file rewrites hosts.ini from the database; to_db creates and updates nodes to match hosts.ini in one transaction"`
+		DeleteMissing  bool   `json:"delete_missing,omitempty" doc:"With to_db, also delete nodes that are not in hosts.ini; without it they are kept"`
+		SkipValidation bool   `json:"skip_validation,omitempty" doc:"With to_db, apply hosts.ini even if its node roles break topology rules"`
+	}
+}
+
+type InventoryReconcileOutput struct {
+	Body struct {
+		Message   string               `json:"message"`
+		Applied   []InventoryDriftItem `json:"applied" doc:"The differences that were resolved"`
+		GroupVars []string             `json:"group_vars,omitempty" doc:"Roles whose group variables were reconciled"`
+		Kept      []string             `json:"kept,omitempty" doc:"Nodes missing from hosts.ini that to_db kept because delete_missing was not set"`
+	}
+}
+
+type InventoryGroupVarsOutput struct {
+	Body struct {
+		GroupVars map[string]map[string]string `json:"group_vars" doc:"Variables of each role's [<role>_nodes:vars] section, by role"`
+	}
+}
+
+type InventoryGroupVarsSetInput struct {
+	WorkspaceParam
+	Role string `path:"role" doc:"Node role whose hosts.ini group the variables apply to"`
+	Body struct {
+		Vars map[string]string `json:"vars" doc:"Replaces the group's variables; empty removes them"`
+	}
+}
+
+type InventoryGroupVarsSetOutput struct {
+	Body struct {
+		Role string            `json:"role"`
+		Vars map[string]string `json:"vars"`
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Workspace types
+// ---------------------------------------------------------------------------
+
+// WorkspaceInfo describes a named OnRamp workspace.
+type WorkspaceInfo struct {
+	Name      string `json:"name"`
+	Default   bool   `json:"default"`
+	Dir       string `json:"dir"`
+	RepoURL   string `json:"repo_url"`
+	Version   string `json:"version"`
+	CreatedAt int64  `json:"created_at,omitempty"`
+}
+
+type WorkspaceListOutput struct {
+	Body []WorkspaceInfo
+}
+
+type WorkspaceGetInput struct {
+	Name string `path:"name" doc:"Workspace name"`
+}
+
+type WorkspaceGetOutput struct {
+	Body WorkspaceInfo
+}
+
+type WorkspaceCreateInput struct {
+	Body struct {
+		Name    string `json:"name" pattern:"^[a-z0-9][a-z0-9-]{0,62}$" doc:"Workspace name (lowercase letters, digits, and dashes)"`
+		RepoURL string `json:"repo_url,omitempty" doc:"Git clone URL (default: same as the default workspace)"`
+		Version string `json:"version,omitempty" doc:"Tag, branch, or commit to pin (default: same as the default workspace)"`
+	}
+}
+
+type WorkspaceCreateOutput struct {
+	Body WorkspaceInfo
+}
+
+type WorkspaceDeleteInput struct {
+	Name string `path:"name" doc:"Workspace name"`
+}
+
+type WorkspaceDeleteOutput struct {
+	Body struct {
+		Message string `json:"message"`
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Custom components
+// ---------------------------------------------------------------------------
+
+// CustomComponentSpec defines a user component. Its actions run site make
+// targets, playbooks from the playbook directory, or scripts from the script
+// directory.
+type CustomComponentSpec struct {
+	Description string   `json:"description,omitempty"`
+	Tier        *int     `json:"tier,omitempty" doc:"Install order among all components; default 3"`
+	Roles       []string `json:"roles,omitempty" doc:"Node roles (hosts.ini groups) the component adds"`
+	Actions     []Action `json:"actions" minItems:"1"`
+}
+
+type CustomComponentGetInput struct {
+	WorkspaceParam
+	Name string `path:"name" doc:"Custom component name"`
+}
+
+type CustomComponentCreateInput struct {
+	WorkspaceParam
+	Body struct {
+		Name string `json:"name" pattern:"^[a-z0-9][a-z0-9_-]{0,62}$" doc:"Component name (lowercase letters, digits, dashes, and underscores)"`
+		CustomComponentSpec
+	}
+}
+
+type CustomComponentUpdateInput struct {
+	WorkspaceParam
+	Name string `path:"name" doc:"Custom component name"`
+	Body CustomComponentSpec
+}
+
+type CustomComponentDeleteInput struct {
+	WorkspaceParam
+	Name string `path:"name" doc:"Custom component name"`
+}
+
+type CustomComponentOutput struct {
+	Body Component
+}
+
+type CustomComponentDeleteOutput struct {
+	Body struct {
+		Message string `json:"message"`
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Action hooks
+// ---------------------------------------------------------------------------
+
+// ActionHook is a make target, playbook or script run before, after, or on
+// failure of a component action, as its own task.
+type ActionHook struct {
+	ID        string   `json:"id"`
+	Component string   `json:"component"`
+	Action    string   `json:"action,omitempty" doc:"Action the hook runs for; omitted when it runs for every action of the component"`
+	Stage     string   `json:"stage" enum:"before,after,on_failure"`
+	Name      string   `json:"name"`
+	Kind      string   `json:"kind" enum:"make,playbook,script"`
+	Target    string   `json:"target"`
+	Args      []string `json:"args,omitempty"`
+	Position  int      `json:"position" doc:"Run order within the stage"`
+	CreatedAt int64    `json:"created_at"`
+	UpdatedAt int64    `json:"updated_at"`
+}
+
+// HookSpec defines an action hook.
+type HookSpec struct {
+	Component string   `json:"component" doc:"Component the hook belongs to"`
+	Action    string   `json:"action,omitempty" doc:"Action the hook runs for; empty for every action of the component"`
+	Stage     string   `json:"stage" enum:"before,after,on_failure" doc:"before hooks abort the action when they fail; after hooks run when it succeeds, on_failure hooks when it fails"`
+	Name      string   `json:"name" pattern:"^[a-z0-9][a-z0-9_-]{0,62}$"`
+	Kind      string   `json:"kind,omitempty" enum:"make,playbook,script" doc:"How the hook runs; default make"`
+	Target    string   `json:"target" doc:"Make target, or a path relative to the playbook or script directory"`
+	Args      []string `json:"args,omitempty" doc:"KEY=value variables for make or ansible-playbook (passed as --extra-vars), or arguments for the script"`
+	Position  int      `json:"position,omitempty" doc:"Run order within the stage; ties run in creation order"`
+}
+
+// HookRunItem is one execution of a hook for an action. ID is the hook's
+// task ID.
+type HookRunItem struct {
+	ID         string `json:"id"`
+	HookID     string `json:"hook_id"`
+	Stage      string `json:"stage"`
+	Name       string `json:"name"`
+	Target     string `json:"target"`
+	Status     string `json:"status"`
+	ExitCode   int    `json:"exit_code"`
+	Error      string `json:"error,omitempty"`
+	StartedAt  int64  `json:"started_at"`
+	FinishedAt int64  `json:"finished_at,omitempty"`
+}
+
+type HookListInput struct {
+	WorkspaceParam
+	Component string `query:"component" doc:"Only hooks of this component"`
+	Action    string `query:"action" doc:"Only hooks that run for this action"`
+}
+
+type HookListOutput struct {
+	Body []ActionHook
+}
+
+type HookGetInput struct {
+	WorkspaceParam
+	ID string `path:"id" doc:"Hook ID"`
+}
+
+type HookCreateInput struct {
+	WorkspaceParam
+	Body HookSpec
+}
+
+type HookUpdateInput struct {
+	WorkspaceParam
+	ID   string `path:"id" doc:"Hook ID"`
+	Body HookSpec
+}
+
+type HookDeleteInput struct {
+	WorkspaceParam
+	ID string `path:"id" doc:"Hook ID"`
+}
+
+type HookOutput struct {
+	Body ActionHook
+}
+
+type HookDeleteOutput struct {
 	Body struct {
 		Message string `json:"message"`
-		Path    string `json:"path"`
 	}
 }
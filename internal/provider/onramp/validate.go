@@ -0,0 +1,464 @@
+package onramp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"github.com/bengrewell/aether-webui/internal/nodefacts"
+	"github.com/bengrewell/aether-webui/internal/store"
+)
+
+// Issue severities. Errors make a config invalid; warnings flag settings
+// that are legal but often wrong.
+const (
+	severityError   = "error"
+	severityWarning = "warning"
+)
+
+// ifaceFields maps a node role to the config field naming the interface the
+// role's playbooks bind to on that node.
+var ifaceFields = []struct {
+	Role    string
+	Section string
+	Field   string
+	Iface   func(cfg *OnRampConfig) string
+}{
+	{"master", "core", "core.data_iface", func(c *OnRampConfig) string {
+		return c.Core.DataIface
+	}},
+	{"gnbsim", "gnbsim", "gnbsim.router.data_iface", func(c *OnRampConfig) string {
+		if c.GNBSim.Router == nil {
+			return ""
+		}
+		return c.GNBSim.Router.DataIface
+	}},
+	{"oai", "oai", "oai.docker.network.data_iface", func(c *OnRampConfig) string {
+		if c.OAI.Docker == nil || c.OAI.Docker.Network == nil {
+			return ""
+		}
+		return c.OAI.Docker.Network.DataIface
+	}},
+}
+
+// validationNode is a workspace node with its last gathered facts, if any.
+type validationNode struct {
+	store.NodeInfo
+	Facts *nodefacts.NodeFacts
+}
+
+// validator accumulates the issues found in one config.
+type validator struct {
+	cfg    *OnRampConfig
+	nodes  []validationNode
+	result ValidationResult
+}
+
+// validateConfig checks cfg for malformed addresses, addresses outside the
+// subnet they belong to, overlapping subnets and interfaces that do not
+// exist on the nodes that use them. nodes may be empty, in which case the
+// checks that need node facts are skipped.
+func validateConfig(cfg *OnRampConfig, nodes []validationNode) ValidationResult {
+	v := &validator{
+		cfg:    cfg,
+		nodes:  nodes,
+		result: ValidationResult{Errors: []ValidationIssue{}, Warnings: []ValidationIssue{}},
+	}
+	v.checkCore()
+	v.checkRAN()
+	v.checkInterfaces()
+	v.result.Valid = len(v.result.Errors) == 0
+	return v.result
+}
+
+func (v *validator) add(severity, field, rule, node, format string, args ...any) {
+	issue := ValidationIssue{
+		Field:    field,
+		Rule:     rule,
+		Severity: severity,
+		Message:  fmt.Sprintf(format, args...),
+		Node:     node,
+	}
+	if severity == severityError {
+		v.result.Errors = append(v.result.Errors, issue)
+	} else {
+		v.result.Warnings = append(v.result.Warnings, issue)
+	}
+}
+
+// prefix parses a CIDR field. Empty values are allowed and return false
+// without an issue.
+func (v *validator) prefix(field, value string) (netip.Prefix, bool) {
+	if value == "" {
+		return netip.Prefix{}, false
+	}
+	p, err := netip.ParsePrefix(value)
+	if err != nil {
+		v.add(severityError, field, "cidr", "", "%q is not a CIDR prefix such as 192.168.252.1/24", value)
+		return netip.Prefix{}, false
+	}
+	return p, true
+}
+
+// addr parses an address field. OnRamp writes some addresses with a prefix
+// length (192.168.252.6/24), so both forms are accepted. Empty values are
+// allowed and return false without an issue.
+func (v *validator) addr(field, value string) (netip.Addr, bool) {
+	if value == "" {
+		return netip.Addr{}, false
+	}
+	if a, err := netip.ParseAddr(value); err == nil {
+		return a, true
+	}
+	if p, err := netip.ParsePrefix(value); err == nil {
+		return p.Addr(), true
+	}
+	v.add(severityError, field, "ip", "", "%q is not an IP address", value)
+	return netip.Addr{}, false
+}
+
+// namedPrefix is a parsed subnet with the field it came from.
+type namedPrefix struct {
+	field  string
+	prefix netip.Prefix
+}
+
+// overlaps reports an error for every pair of subnets in prefixes that
+// overlap, against the later field.
+func (v *validator) overlaps(prefixes []namedPrefix) {
+	for i := range prefixes {
+		for j := i + 1; j < len(prefixes); j++ {
+			a, b := prefixes[i], prefixes[j]
+			if a.prefix.Masked().Overlaps(b.prefix.Masked()) {
+				v.add(severityError, b.field, "overlap", "", "%s overlaps %s (%s)", b.prefix, a.field, a.prefix)
+			}
+		}
+	}
+}
+
+func (v *validator) checkCore() {
+	core := v.cfg.Core
+	if core == nil {
+		return
+	}
+	var subnets []namedPrefix
+	ran, hasRAN := v.prefix("core.ran_subnet", core.RANSubnet)
+	if hasRAN {
+		subnets = append(subnets, namedPrefix{"core.ran_subnet", ran})
+	}
+
+	if core.AMF != nil {
+		if ip, ok := v.addr("core.amf.ip", core.AMF.IP); ok && hasRAN && !ran.Contains(ip) {
+			v.add(severityWarning, "core.amf.ip", "ip_in_subnet", "",
+				"%s is outside core.ran_subnet %s; gNBs need a route to the AMF", ip, ran)
+		}
+	}
+	if core.MME != nil {
+		v.addr("core.mme.ip", core.MME.IP)
+	}
+
+	upf := core.UPF
+	if upf == nil {
+		return
+	}
+	access, hasAccess := v.prefix("core.upf.access_subnet", upf.AccessSubnet)
+	coreNet, hasCore := v.prefix("core.upf.core_subnet", upf.CoreSubnet)
+	if hasAccess {
+		subnets = append(subnets, namedPrefix{"core.upf.access_subnet", access})
+	}
+	if hasCore {
+		subnets = append(subnets, namedPrefix{"core.upf.core_subnet", coreNet})
+	}
+
+	type namedUPF struct {
+		path string
+		upf  *UPFInstance
+	}
+	upfs := []namedUPF{{"core.upf.default_upf", upf.DefaultUPF}}
+	names := make([]string, 0, len(upf.AdditionalUPFs))
+	for name := range upf.AdditionalUPFs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		upfs = append(upfs, namedUPF{"core.upf.additional_upfs." + name, upf.AdditionalUPFs[name]})
+	}
+
+	for _, u := range upfs {
+		if u.upf == nil {
+			continue
+		}
+		if u.upf.IP != nil {
+			if ip, ok := v.addr(u.path+".ip.access", u.upf.IP.Access); ok && hasAccess && !access.Contains(ip) {
+				v.add(severityError, u.path+".ip.access", "ip_in_subnet", "",
+					"%s is outside core.upf.access_subnet %s", ip, access)
+			}
+			if ip, ok := v.addr(u.path+".ip.core", u.upf.IP.Core); ok && hasCore && !coreNet.Contains(ip) {
+				v.add(severityError, u.path+".ip.core", "ip_in_subnet", "",
+					"%s is outside core.upf.core_subnet %s", ip, coreNet)
+			}
+		}
+		if pool, ok := v.prefix(u.path+".ue_ip_pool", u.upf.UEIPPool); ok {
+			subnets = append(subnets, namedPrefix{u.path + ".ue_ip_pool", pool})
+		}
+	}
+	v.overlaps(subnets)
+}
+
+// checkRAN validates the addresses and subnets of the RAN sections.
+func (v *validator) checkRAN() {
+	cfg := v.cfg
+	if cfg.UERANSIM != nil && cfg.UERANSIM.GNB != nil {
+		v.addr("ueransim.gnb.ip", cfg.UERANSIM.GNB.IP)
+	}
+	if cfg.OAI != nil {
+		if cfg.OAI.Docker != nil && cfg.OAI.Docker.Network != nil {
+			v.prefix("oai.docker.network.subnet", cfg.OAI.Docker.Network.Subnet)
+		}
+		for _, i := range sortedKeys(cfg.OAI.Servers) {
+			if s := cfg.OAI.Servers[i]; s != nil {
+				v.addr(fmt.Sprintf("oai.servers.%d.gnb_ip", i), s.GNBIP)
+			}
+		}
+	}
+	if cfg.SRSRan != nil {
+		for _, i := range sortedKeys(cfg.SRSRan.Servers) {
+			if s := cfg.SRSRan.Servers[i]; s != nil {
+				v.addr(fmt.Sprintf("srsran.servers.%d.gnb_ip", i), s.GNBIP)
+			}
+		}
+	}
+	if cfg.N3IWF != nil {
+		for _, i := range sortedKeys(cfg.N3IWF.Servers) {
+			s := cfg.N3IWF.Servers[i]
+			if s == nil {
+				continue
+			}
+			path := fmt.Sprintf("n3iwf.servers.%d", i)
+			v.addr(path+".n3iwf_ip", s.N3IWFIP)
+			v.addr(path+".n2_ip", s.N2IP)
+			v.addr(path+".n3_ip", s.N3IP)
+		}
+	}
+}
+
+// checkInterfaces cross-checks interface names against the facts gathered
+// from the nodes that will use them. A node without facts only produces a
+// warning, since the interface may well exist.
+func (v *validator) checkInterfaces() {
+	for _, f := range ifaceFields {
+		if !v.hasSection(f.Section) {
+			continue
+		}
+		iface := f.Iface(v.cfg)
+		if iface == "" {
+			continue
+		}
+		for _, n := range v.nodes {
+			if !hasRole(n.Roles, f.Role) {
+				continue
+			}
+			if n.Facts == nil || n.Facts.Error != "" {
+				v.add(severityWarning, f.Field, "facts", n.Name,
+					"no facts gathered for %s; cannot check that %s exists", n.Name, iface)
+				continue
+			}
+			info, ok := findInterface(n.Facts, iface)
+			switch {
+			case !ok:
+				v.add(severityError, f.Field, "interface", n.Name,
+					"interface %s does not exist on %s (has %s)", iface, n.Name, strings.Join(interfaceNames(n.Facts), ", "))
+			case !info.IsUp:
+				v.add(severityWarning, f.Field, "interface", n.Name, "interface %s is down on %s", iface, n.Name)
+			}
+		}
+	}
+
+	// The AMF normally listens on the master's data interface.
+	if v.cfg.Core == nil || v.cfg.Core.AMF == nil {
+		return
+	}
+	amf, err := netip.ParseAddr(v.cfg.Core.AMF.IP)
+	if err != nil {
+		return
+	}
+	for _, n := range v.nodes {
+		if !hasRole(n.Roles, "master") || n.Facts == nil || n.Facts.Error != "" {
+			continue
+		}
+		if !nodeHasAddr(n.Facts, amf) {
+			v.add(severityWarning, "core.amf.ip", "interface", n.Name, "%s is not assigned to any interface on %s", amf, n.Name)
+		}
+	}
+}
+
+func (v *validator) hasSection(name string) bool {
+	switch name {
+	case "core":
+		return v.cfg.Core != nil
+	case "gnbsim":
+		return v.cfg.GNBSim != nil
+	case "oai":
+		return v.cfg.OAI != nil
+	}
+	return false
+}
+
+func hasRole(roles []string, role string) bool {
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+func findInterface(f *nodefacts.NodeFacts, name string) (nodefacts.InterfaceInfo, bool) {
+	for _, i := range f.Interfaces {
+		if i.Name == name {
+			return i, true
+		}
+	}
+	return nodefacts.InterfaceInfo{}, false
+}
+
+func interfaceNames(f *nodefacts.NodeFacts) []string {
+	names := make([]string, 0, len(f.Interfaces))
+	for _, i := range f.Interfaces {
+		names = append(names, i.Name)
+	}
+	return names
+}
+
+func nodeHasAddr(f *nodefacts.NodeFacts, ip netip.Addr) bool {
+	for _, i := range f.Interfaces {
+		for _, a := range i.Addresses {
+			if p, err := netip.ParsePrefix(a); err == nil && p.Addr() == ip {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func sortedKeys[V any](m map[int]V) []int {
+	keys := make([]int, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	return keys
+}
+
+// ---------------------------------------------------------------------------
+// Handler and callers
+// ---------------------------------------------------------------------------
+
+// HandleValidateConfig checks the config in the request body, or the
+// workspace's vars/main.yml when the body is omitted.
+func (o *OnRamp) HandleValidateConfig(ctx context.Context, in *ConfigValidateInput) (*ConfigValidateOutput, error) {
+	ws, err := o.workspace(ctx, in.Workspace)
+	if err != nil {
+		return nil, err
+	}
+	cfg := in.Body
+	if cfg == nil {
+		cur, err := o.readVarsFile(filepath.Join(ws.config.OnRampDir, "vars", "main.yml"))
+		if err != nil {
+			return nil, huma.Error500InternalServerError("failed to read config", err)
+		}
+		cfg = &cur
+	}
+	return &ConfigValidateOutput{Body: o.validate(ctx, ws, cfg)}, nil
+}
+
+// validate runs validateConfig against the workspace's nodes and their
+// cached facts. Without a store the node checks are skipped.
+func (o *OnRamp) validate(ctx context.Context, ws *workspace, cfg *OnRampConfig) ValidationResult {
+	return validateConfig(cfg, o.validationNodes(ctx, ws))
+}
+
+func (o *OnRamp) validationNodes(ctx context.Context, ws *workspace) []validationNode {
+	st := o.Store()
+	if st.Path() == "" {
+		return nil
+	}
+	infos, err := st.ListNodes(ctx, ws.name)
+	if err != nil {
+		o.Log().Warn("failed to list nodes for config validation", "workspace", ws.name, "error", err)
+		return nil
+	}
+	nodes := make([]validationNode, 0, len(infos))
+	for _, ni := range infos {
+		n := validationNode{NodeInfo: ni}
+		// Stale facts are still the best evidence of what interfaces exist.
+		v, ok, err := nodefacts.Load(ctx, st, ni.ID, 0)
+		if err == nil && ok {
+			n.Facts = &v.Facts
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes
+}
+
+// newIssues returns the errors in after that are not in before, so a patch
+// is only blamed for the problems it introduces.
+func newIssues(before, after ValidationResult) []ValidationIssue {
+	seen := make(map[ValidationIssue]bool, len(before.Errors))
+	for _, e := range before.Errors {
+		seen[e] = true
+	}
+	var out []ValidationIssue
+	for _, e := range after.Errors {
+		if !seen[e] {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// validationError builds a 422 response listing issues as error details.
+func validationError(msg string, issues []ValidationIssue) error {
+	details := make([]error, 0, len(issues))
+	for _, i := range issues {
+		details = append(details, &huma.ErrorDetail{
+			Message:  i.Message,
+			Location: i.Field,
+			Value:    i.Rule,
+		})
+	}
+	return huma.Error422UnprocessableEntity(msg, details...)
+}
+
+// validateForDeploy rejects a deployment with install actions when the
+// workspace's vars/main.yml has validation errors.
+func (o *OnRamp) validateForDeploy(ctx context.Context, ws *workspace, actions []ComponentActionPair) error {
+	installs := false
+	for _, a := range actions {
+		if actionCategory(a.Action) == "install" {
+			installs = true
+			break
+		}
+	}
+	if !installs {
+		return nil
+	}
+	cfg, err := o.readVarsFile(filepath.Join(ws.config.OnRampDir, "vars", "main.yml"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return huma.Error422UnprocessableEntity("vars/main.yml cannot be parsed", err)
+	}
+	if res := o.validate(ctx, ws, &cfg); !res.Valid {
+		return validationError("config has validation errors; fix them or set skip_validation", res.Errors)
+	}
+	return nil
+}
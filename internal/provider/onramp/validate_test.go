@@ -0,0 +1,229 @@
+package onramp
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/danielgtaylor/huma/v2"
+	"gopkg.in/yaml.v3"
+
+	"github.com/bengrewell/aether-webui/internal/nodefacts"
+	"github.com/bengrewell/aether-webui/internal/store"
+)
+
+// issueKeys returns "field/rule" for each issue, for compact comparisons.
+func issueKeys(issues []ValidationIssue) []string {
+	out := make([]string, 0, len(issues))
+	for _, i := range issues {
+		out = append(out, i.Field+"/"+i.Rule)
+	}
+	return out
+}
+
+func mustConfig(t *testing.T, src string) *OnRampConfig {
+	t.Helper()
+	var cfg OnRampConfig
+	if err := yaml.Unmarshal([]byte(src), &cfg); err != nil {
+		t.Fatalf("yaml.Unmarshal: %v", err)
+	}
+	return &cfg
+}
+
+func TestValidateConfig_Blueprints(t *testing.T) {
+	for name, src := range blueprintFiles(t) {
+		res := validateConfig(mustConfig(t, string(src)), nil)
+		if !res.Valid || len(res.Errors) != 0 {
+			t.Errorf("%s: errors = %+v", name, res.Errors)
+		}
+	}
+}
+
+func TestValidateConfig_Rules(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   string
+		errors   []string
+		warnings []string
+	}{
+		{
+			name:   "bad cidr and ip",
+			config: "core:\n  ran_subnet: 10.0.0.0/33\n  amf:\n    ip: 10.0.0.x\n",
+			errors: []string{"core.ran_subnet/cidr", "core.amf.ip/ip"},
+		},
+		{
+			name:     "amf outside ran subnet",
+			config:   "core:\n  ran_subnet: 172.20.0.0/16\n  amf:\n    ip: 10.76.28.113\n",
+			warnings: []string{"core.amf.ip/ip_in_subnet"},
+		},
+		{
+			name: "upf address outside its subnet",
+			config: `core:
+  upf:
+    access_subnet: 192.168.252.1/24
+    core_subnet: 192.168.250.1/24
+    default_upf:
+      ip:
+        access: 192.168.252.3/24
+        core: 192.168.251.3/24
+      ue_ip_pool: 172.250.0.0/16
+`,
+			errors: []string{"core.upf.default_upf.ip.core/ip_in_subnet"},
+		},
+		{
+			name: "overlapping ue pools",
+			config: `core:
+  ran_subnet: 172.20.0.0/16
+  upf:
+    default_upf:
+      ue_ip_pool: 172.250.0.0/16
+    additional_upfs:
+      "1":
+        ue_ip_pool: 172.250.128.0/17
+      "2":
+        ue_ip_pool: 172.20.5.0/24
+`,
+			errors: []string{"core.upf.additional_upfs.2.ue_ip_pool/overlap", "core.upf.additional_upfs.1.ue_ip_pool/overlap"},
+		},
+		{
+			name:   "ran addresses",
+			config: "srsran:\n  servers:\n    0:\n      gnb_ip: not-an-ip\noai:\n  docker:\n    network:\n      subnet: 172.20.0.0\n",
+			errors: []string{"oai.docker.network.subnet/cidr", "srsran.servers.0.gnb_ip/ip"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := validateConfig(mustConfig(t, tt.config), nil)
+			if got := issueKeys(res.Errors); !slices.Equal(got, tt.errors) {
+				t.Errorf("errors = %v, want %v", got, tt.errors)
+			}
+			if got := issueKeys(res.Warnings); !slices.Equal(got, tt.warnings) {
+				t.Errorf("warnings = %v, want %v", got, tt.warnings)
+			}
+			if res.Valid != (len(tt.errors) == 0) {
+				t.Errorf("valid = %v", res.Valid)
+			}
+		})
+	}
+}
+
+func TestValidateConfig_Interfaces(t *testing.T) {
+	cfg := mustConfig(t, "core:\n  data_iface: ens18\n  amf:\n    ip: 10.0.0.10\n")
+	facts := &nodefacts.NodeFacts{Interfaces: []nodefacts.InterfaceInfo{
+		{Name: "lo", Addresses: []string{"127.0.0.1/8"}, IsUp: true},
+		{Name: "eno1", Addresses: []string{"10.0.0.10/24"}, IsUp: true},
+		{Name: "ens18", IsUp: false},
+	}}
+	nodes := []validationNode{
+		{NodeInfo: store.NodeInfo{Name: "node1", Roles: []string{"master"}}, Facts: facts},
+		{NodeInfo: store.NodeInfo{Name: "node2", Roles: []string{"master"}}},
+		{NodeInfo: store.NodeInfo{Name: "node3", Roles: []string{"worker"}}},
+	}
+
+	res := validateConfig(cfg, nodes)
+	if !res.Valid {
+		t.Fatalf("errors = %+v", res.Errors)
+	}
+	want := []string{"core.data_iface/interface", "core.data_iface/facts"}
+	if got := issueKeys(res.Warnings); !slices.Equal(got, want) {
+		t.Errorf("warnings = %v, want %v", got, want)
+	}
+
+	cfg.Core.DataIface = "ens99"
+	cfg.Core.AMF.IP = "10.0.0.11"
+	res = validateConfig(cfg, nodes)
+	if len(res.Errors) != 1 || res.Errors[0].Rule != "interface" || res.Errors[0].Node != "node1" {
+		t.Fatalf("errors = %+v", res.Errors)
+	}
+	if want := "interface ens99 does not exist on node1 (has lo, eno1, ens18)"; res.Errors[0].Message != want {
+		t.Errorf("message = %q, want %q", res.Errors[0].Message, want)
+	}
+	want = []string{"core.data_iface/facts", "core.amf.ip/interface"}
+	if got := issueKeys(res.Warnings); !slices.Equal(got, want) {
+		t.Errorf("warnings = %v, want %v", got, want)
+	}
+}
+
+func TestHandleValidateConfig_UsesNodeFacts(t *testing.T) {
+	o := newTestProviderWithStore(t, testMainYML)
+	st := o.Store()
+	if err := st.UpsertNode(t.Context(), store.Node{
+		ID: "n1", Name: "node1", AnsibleHost: "10.0.0.1", Roles: []string{"master"},
+	}); err != nil {
+		t.Fatalf("UpsertNode: %v", err)
+	}
+	facts := nodefacts.NodeFacts{NodeID: "n1", Interfaces: []nodefacts.InterfaceInfo{{Name: "eno1", IsUp: true}}}
+	if _, _, err := nodefacts.Record(t.Context(), st, facts); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+
+	out, err := o.HandleValidateConfig(t.Context(), &ConfigValidateInput{})
+	if err != nil {
+		t.Fatalf("HandleValidateConfig: %v", err)
+	}
+	if out.Body.Valid || issueKeys(out.Body.Errors)[0] != "core.data_iface/interface" {
+		t.Fatalf("result = %+v", out.Body)
+	}
+
+	// A body is validated instead of the file.
+	out, err = o.HandleValidateConfig(t.Context(), &ConfigValidateInput{
+		Body: mustConfig(t, "core:\n  data_iface: eno1\n"),
+	})
+	if err != nil {
+		t.Fatalf("HandleValidateConfig: %v", err)
+	}
+	if !out.Body.Valid {
+		t.Errorf("result = %+v", out.Body)
+	}
+}
+
+func TestHandlePatchConfig_RejectsValidationErrors(t *testing.T) {
+	o := newTestProvider(t, testMainYML)
+
+	_, err := o.HandlePatchConfig(t.Context(), &ConfigPatchInput{
+		RawBody: []byte(`{"core": {"ran_subnet": "192.168.251.0/40"}}`),
+	})
+	wantStatus(t, err, 422)
+	var se *huma.ErrorModel
+	if !errors.As(err, &se) || len(se.Errors) != 1 || se.Errors[0].Location != "core.ran_subnet" {
+		t.Fatalf("error = %+v", err)
+	}
+
+	// force writes the config anyway.
+	out, err := o.HandlePatchConfig(t.Context(), &ConfigPatchInput{
+		RawBody: []byte(`{"core": {"ran_subnet": "192.168.251.0/40"}}`),
+		Force:   true,
+	})
+	if err != nil {
+		t.Fatalf("HandlePatchConfig with force: %v", err)
+	}
+	if out.Body.Core.RANSubnet != "192.168.251.0/40" {
+		t.Fatalf("ran_subnet = %q", out.Body.Core.RANSubnet)
+	}
+
+	// Patches that leave an existing error alone are not blamed for it.
+	patchConfig(t, o, `{"core": {"data_iface": "ens19"}}`, "")
+}
+
+func TestHandleDeploy_RejectsInvalidConfig(t *testing.T) {
+	invalid := testMainYML + "  amf:\n    ip: 10.0.0.300\n"
+	o := newTestProviderWithStore(t, invalid)
+
+	dep := &DeployInput{}
+	dep.Body.Actions = []ComponentActionPair{{Component: "k8s", Action: "install"}}
+	_, err := o.HandleDeploy(t.Context(), dep)
+	wantStatus(t, err, 422)
+
+	dep.Body.SkipValidation = true
+	if _, err := o.HandleDeploy(t.Context(), dep); err != nil {
+		t.Fatalf("HandleDeploy with skip_validation: %v", err)
+	}
+
+	// Uninstalls do not depend on the config being valid.
+	o = newTestProviderWithStore(t, invalid)
+	dep = &DeployInput{}
+	dep.Body.Actions = []ComponentActionPair{{Component: "k8s", Action: "uninstall"}}
+	if _, err := o.HandleDeploy(t.Context(), dep); err != nil {
+		t.Fatalf("HandleDeploy uninstall: %v", err)
+	}
+}
@@ -0,0 +1,513 @@
+package onramp
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"github.com/bengrewell/aether-webui/internal/store"
+	"github.com/bengrewell/aether-webui/internal/taskrunner"
+)
+
+// maxDiffBytes caps the diff returned by the repo diff endpoint.
+const maxDiffBytes = 1 << 20
+
+// versionKey is where a runtime version pin for the default workspace is
+// persisted. It overrides --onramp-version across restarts. Named workspaces
+// keep their pin in the workspaces table.
+var versionKey = store.Key{Namespace: "_onramp", ID: "default_version"}
+
+// blockingStates are component states that make a version switch unsafe:
+// the new checkout may not know how to uninstall what the old one installed.
+var blockingStates = map[string]bool{
+	"installed":    true,
+	"installing":   true,
+	"uninstalling": true,
+}
+
+// HandleListRepoVersions returns the tags and branches available in the
+// workspace's local clone. Tags are ordered newest first.
+func (o *OnRamp) HandleListRepoVersions(ctx context.Context, in *WorkspaceInput) (*RepoVersionsOutput, error) {
+	ws, err := o.workspace(ctx, in.workspaceName())
+	if err != nil {
+		return nil, err
+	}
+	cfg := ws.currentConfig()
+	if err := requireClone(cfg.OnRampDir); err != nil {
+		return nil, err
+	}
+	refs, err := gitOutput(cfg.OnRampDir, "for-each-ref", "--sort=-creatordate",
+		"--format=%(refname)\t%(objectname)\t%(*objectname)\t%(creatordate:unix)",
+		"refs/tags", "refs/heads", "refs/remotes")
+	if err != nil {
+		return nil, huma.Error500InternalServerError("failed to list refs", err)
+	}
+
+	out := &RepoVersionsOutput{}
+	out.Body.Version = cfg.Version
+	out.Body.Tags = []RepoRef{}
+	out.Body.Branches = []RepoRef{}
+	for _, line := range strings.Split(refs, "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 4 {
+			continue
+		}
+		ref := RepoRef{Commit: fields[1]}
+		// Annotated tags point at a tag object; report the commit instead.
+		if fields[2] != "" {
+			ref.Commit = fields[2]
+		}
+		if ts, err := strconv.ParseInt(fields[3], 10, 64); err == nil {
+			ref.Date = ts
+		}
+		switch name := fields[0]; {
+		case strings.HasPrefix(name, "refs/tags/"):
+			ref.Name = strings.TrimPrefix(name, "refs/tags/")
+			out.Body.Tags = append(out.Body.Tags, ref)
+		case strings.HasPrefix(name, "refs/heads/"):
+			ref.Name = strings.TrimPrefix(name, "refs/heads/")
+			out.Body.Branches = append(out.Body.Branches, ref)
+		case strings.HasPrefix(name, "refs/remotes/") && !strings.HasSuffix(name, "/HEAD"):
+			ref.Name = strings.TrimPrefix(name, "refs/remotes/")
+			ref.Remote = true
+			out.Body.Branches = append(out.Body.Branches, ref)
+		}
+	}
+	sort.SliceStable(out.Body.Branches, func(i, j int) bool {
+		bi, bj := out.Body.Branches[i], out.Body.Branches[j]
+		if bi.Remote != bj.Remote {
+			return !bi.Remote
+		}
+		return bi.Name < bj.Name
+	})
+	return out, nil
+}
+
+// HandleSetRepoVersion checks out a different tag, branch, or commit and
+// persists it as the workspace's pinned version. It refuses while components
+// are installed unless forced, and always refuses with active tasks or local
+// modifications other than to the files the daemon writes, whose changes
+// are kept across the switch.
+func (o *OnRamp) HandleSetRepoVersion(ctx context.Context, in *RepoVersionSetInput) (*RepoVersionSetOutput, error) {
+	ws, err := o.workspace(ctx, in.Workspace)
+	if err != nil {
+		return nil, err
+	}
+	if err := o.CheckChangeLock(ctx, ws.name); err != nil {
+		return nil, err
+	}
+	cfg := ws.currentConfig()
+	if err := requireClone(cfg.OnRampDir); err != nil {
+		return nil, err
+	}
+	if err := ws.requireIdle(); err != nil {
+		return nil, err
+	}
+
+	version := in.Body.Version
+	if strings.HasPrefix(version, "-") {
+		return nil, huma.Error422UnprocessableEntity(fmt.Sprintf("invalid version %q", version))
+	}
+	if _, err := gitOutput(cfg.OnRampDir, "rev-parse", "--verify", "--quiet", version+"^{commit}"); err != nil {
+		return nil, huma.Error422UnprocessableEntity(
+			fmt.Sprintf("version %s not found in the local clone", version))
+	}
+
+	if !in.Body.Force {
+		installed, err := o.installedComponents(ctx, ws.name)
+		if err != nil {
+			return nil, huma.Error500InternalServerError("failed to check component state", err)
+		}
+		if len(installed) > 0 {
+			return nil, huma.Error409Conflict(fmt.Sprintf(
+				"components installed: %s; uninstall them or set force to switch anyway",
+				strings.Join(installed, ", ")))
+		}
+	}
+	managed, err := requireUnmodified(cfg.OnRampDir)
+	if err != nil {
+		return nil, err
+	}
+
+	// The workspace's config is carried over to the new version.
+	defer LockVars(cfg.OnRampDir)()
+	saved, err := saveManagedFiles(cfg.OnRampDir, managed)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("failed to save config files", err)
+	}
+	log := o.Log().With("workspace", ws.name)
+	log.Info("switching onramp version", "from", cfg.Version, "to", version, "force", in.Body.Force)
+	if err := discardManagedFiles(cfg.OnRampDir, managed); err != nil {
+		return nil, huma.Error500InternalServerError("failed to set config files aside", err)
+	}
+	checkoutErr := gitRun(cfg.OnRampDir, "checkout", version)
+	if err := restoreManagedFiles(cfg.OnRampDir, saved); err != nil {
+		return nil, huma.Error500InternalServerError("failed to restore config files", err)
+	}
+	if checkoutErr != nil {
+		return nil, huma.Error500InternalServerError(fmt.Sprintf("git checkout %s failed", version), checkoutErr)
+	}
+	if err := o.persistVersion(ctx, ws.name, version); err != nil {
+		return nil, huma.Error500InternalServerError("failed to save pinned version", err)
+	}
+	ws.setVersion(version)
+
+	cfg.Version = version
+	setupErr := ensureSubmodules(cfg, log)
+	if setupErr == nil {
+		setupErr = validateRepo(cfg.OnRampDir)
+	}
+	if ws.name == store.DefaultWorkspace {
+		if setupErr != nil {
+			o.SetDegraded(fmt.Sprintf("repo setup: %v", setupErr))
+		} else {
+			o.ClearDegraded()
+		}
+	}
+	status := gatherRepoStatus(ws)
+	if setupErr != nil {
+		status.Error = setupErr.Error()
+	}
+	return &RepoVersionSetOutput{Body: status}, nil
+}
+
+// HandleGetRepoDiff lists local modifications and returns the diff of tracked
+// files against HEAD. Untracked files are listed but not diffed.
+func (o *OnRamp) HandleGetRepoDiff(ctx context.Context, in *WorkspaceInput) (*RepoDiffOutput, error) {
+	ws, err := o.workspace(ctx, in.workspaceName())
+	if err != nil {
+		return nil, err
+	}
+	dir := ws.currentConfig().OnRampDir
+	if err := requireClone(dir); err != nil {
+		return nil, err
+	}
+
+	porcelain, err := gitRaw(dir, "status", "--porcelain", "--untracked-files=all")
+	if err != nil {
+		return nil, huma.Error500InternalServerError("git status failed", err)
+	}
+	out := &RepoDiffOutput{}
+	out.Body.Changes = parsePorcelain(porcelain)
+	out.Body.Dirty = len(out.Body.Changes) > 0
+	if !out.Body.Dirty {
+		return out, nil
+	}
+
+	diff, err := gitRaw(dir, "diff", "HEAD")
+	if err != nil {
+		return nil, huma.Error500InternalServerError("git diff failed", err)
+	}
+	if len(diff) > maxDiffBytes {
+		diff = diff[:maxDiffBytes]
+		out.Body.Truncated = true
+	}
+	out.Body.Diff = diff
+	return out, nil
+}
+
+// HandleStashRepo stashes local modifications, including untracked files,
+// so they can be recovered with git stash pop. The files the daemon writes
+// are left in place; see isManagedPath.
+func (o *OnRamp) HandleStashRepo(ctx context.Context, in *RepoStashInput) (*RepoLocalChangesOutput, error) {
+	ws, dir, err := o.prepareLocalChange(ctx, in.Workspace)
+	if err != nil {
+		return nil, err
+	}
+	msg := fmt.Sprintf("aether-webui stash %s", time.Now().UTC().Format(time.RFC3339))
+	if in.Body != nil && in.Body.Message != "" {
+		msg = in.Body.Message
+	}
+
+	// Config writes wait until the checkout is settled.
+	defer LockVars(dir)()
+	other, _, err := localChanges(dir)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("git status failed", err)
+	}
+	out := &RepoLocalChangesOutput{}
+	if len(other) == 0 {
+		out.Body.Message = "no local changes to stash"
+	} else {
+		args := append([]string{"stash", "push", "--include-untracked", "-m", msg}, unmanagedPathspec()...)
+		if err := gitRun(dir, args...); err != nil {
+			return nil, huma.Error500InternalServerError("git stash failed", err)
+		}
+		o.Log().Info("onramp local changes stashed", "workspace", ws.name, "message", msg)
+		out.Body.Message = fmt.Sprintf("local changes stashed as %q", msg)
+	}
+	out.Body.Status = gatherRepoStatus(ws)
+	return out, nil
+}
+
+// HandleResetRepo discards local modifications to tracked files and,
+// optionally, deletes untracked files. The files the daemon writes are left
+// in place, so the workspace's config, and its revision history, is kept.
+func (o *OnRamp) HandleResetRepo(ctx context.Context, in *RepoResetInput) (*RepoLocalChangesOutput, error) {
+	ws, dir, err := o.prepareLocalChange(ctx, in.Workspace)
+	if err != nil {
+		return nil, err
+	}
+	clean := in.Body != nil && in.Body.Clean
+
+	defer LockVars(dir)()
+	args := append([]string{"restore", "--source=HEAD", "--staged", "--worktree"}, unmanagedPathspec()...)
+	if err := gitRun(dir, args...); err != nil {
+		return nil, huma.Error500InternalServerError("git restore failed", err)
+	}
+	if clean {
+		// Exclude patterns, unlike pathspecs, keep clean from removing a
+		// whole untracked directory such as host_vars/<node>.
+		args := []string{"clean", "-fd"}
+		for _, p := range managedPatterns() {
+			args = append(args, "-e", "/"+p)
+		}
+		if err := gitRun(dir, args...); err != nil {
+			return nil, huma.Error500InternalServerError("git clean failed", err)
+		}
+	}
+	o.Log().Warn("onramp local changes discarded", "workspace", ws.name, "clean", clean)
+
+	out := &RepoLocalChangesOutput{}
+	out.Body.Message = "local changes discarded"
+	if clean {
+		out.Body.Message = "local changes and untracked files discarded"
+	}
+	out.Body.Status = gatherRepoStatus(ws)
+	return out, nil
+}
+
+// prepareLocalChange runs the checks shared by stash and reset.
+func (o *OnRamp) prepareLocalChange(ctx context.Context, name string) (*workspace, string, error) {
+	ws, err := o.workspace(ctx, name)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := o.CheckChangeLock(ctx, ws.name); err != nil {
+		return nil, "", err
+	}
+	dir := ws.currentConfig().OnRampDir
+	if err := requireClone(dir); err != nil {
+		return nil, "", err
+	}
+	if err := ws.requireIdle(); err != nil {
+		return nil, "", err
+	}
+	return ws, dir, nil
+}
+
+// requireIdle returns a 409 while the workspace has queued or running tasks,
+// which read the checkout.
+func (w *workspace) requireIdle() error {
+	for _, v := range w.runner.List(nil) {
+		if v.Status == taskrunner.StatusPending || v.Status == taskrunner.StatusRunning {
+			return huma.Error409Conflict(fmt.Sprintf("workspace %s has queued or running tasks", w.name))
+		}
+	}
+	return nil
+}
+
+// installedComponents returns the components in a workspace whose state
+// blocks a version switch. Without a store nothing is tracked.
+func (o *OnRamp) installedComponents(ctx context.Context, workspace string) ([]string, error) {
+	st := o.Store()
+	if st.Path() == "" {
+		return nil, nil
+	}
+	states, err := st.ListComponentStates(ctx, workspace)
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for _, s := range states {
+		if blockingStates[s.Status] {
+			out = append(out, s.Component)
+		}
+	}
+	return out, nil
+}
+
+// persistVersion records a workspace's new pin so it survives restarts.
+func (o *OnRamp) persistVersion(ctx context.Context, workspace, version string) error {
+	st := o.Store()
+	if st.Path() == "" {
+		return nil
+	}
+	if workspace == store.DefaultWorkspace {
+		_, err := store.Save(st, ctx, versionKey, version)
+		return err
+	}
+	return st.UpdateWorkspaceVersion(ctx, workspace, version)
+}
+
+// loadPinnedVersion applies a runtime version pin for the default workspace
+// saved by a previous switch.
+func (o *OnRamp) loadPinnedVersion() {
+	st := o.Store()
+	if st.Path() == "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	item, ok, err := store.Load[string](st, ctx, versionKey)
+	if err != nil {
+		o.Log().Error("failed to load pinned onramp version", "error", err)
+		return
+	}
+	if ok && item.Data != "" {
+		o.Log().Info("using pinned onramp version", "version", item.Data, "flag", o.config.Version)
+		o.defaultWorkspace().setVersion(item.Data)
+	}
+}
+
+// requireClone returns a 409 if dir is not a git checkout.
+func requireClone(dir string) error {
+	if info, err := os.Stat(filepath.Join(dir, ".git")); err != nil || !info.IsDir() {
+		return huma.Error409Conflict("onramp repo is not cloned; refresh the repo first")
+	}
+	return nil
+}
+
+// parsePorcelain converts `git status --porcelain` output to changes. Each
+// line is a two-letter status code, a space, and the path.
+func parsePorcelain(out string) []RepoChange {
+	changes := []RepoChange{}
+	for _, line := range strings.Split(out, "\n") {
+		if len(line) < 4 {
+			continue
+		}
+		changes = append(changes, RepoChange{
+			Path:   line[3:],
+			Status: strings.TrimSpace(line[:2]),
+		})
+	}
+	return changes
+}
+
+// managedFiles are the tracked files of the checkout the daemon rewrites
+// itself. Their changes are the workspace's config, not local modifications,
+// and are carried over when the checkout changes version or is replaced.
+var managedFiles = []string{"vars/main.yml", "hosts.ini"}
+
+// isManagedPath reports whether a path in the checkout is written by the
+// daemon: a managed file, a node secrets file or the jump host files.
+func isManagedPath(p string) bool {
+	p = strings.Trim(p, `"`)
+	if slices.Contains(managedFiles, p) || p == jumpDir+"/" || strings.HasPrefix(p, jumpDir+"/") {
+		return true
+	}
+	ok, _ := path.Match("host_vars/*/"+secretsFile, p)
+	return ok
+}
+
+// managedPatterns are glob patterns for the files the daemon writes,
+// matching isManagedPath.
+func managedPatterns() []string {
+	return append(slices.Clone(managedFiles), jumpDir+"/", "host_vars/*/"+secretsFile)
+}
+
+// unmanagedPathspec limits a git command to the checkout's files other than
+// those the daemon writes.
+func unmanagedPathspec() []string {
+	spec := []string{"--", "."}
+	for _, p := range managedPatterns() {
+		spec = append(spec, ":(exclude,glob)"+strings.TrimSuffix(p, "/"))
+	}
+	return spec
+}
+
+// localChanges returns the changes in the checkout at dir other than to
+// the files the daemon writes, and the managed files that were changed.
+func localChanges(dir string) (other, managed []string, err error) {
+	porcelain, err := gitRaw(dir, "status", "--porcelain", "--untracked-files=all")
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, c := range parsePorcelain(porcelain) {
+		switch {
+		case slices.Contains(managedFiles, c.Path):
+			managed = append(managed, c.Path)
+		case !isManagedPath(c.Path):
+			other = append(other, c.Path)
+		}
+	}
+	return other, managed, nil
+}
+
+// requireUnmodified returns a 409 when the checkout at dir has local changes
+// beyond the files the daemon writes, and otherwise the changed managed
+// files, to keep with saveManagedFiles.
+func requireUnmodified(dir string) ([]string, error) {
+	other, managed, err := localChanges(dir)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("git status failed", err)
+	}
+	if len(other) > 0 {
+		return nil, huma.Error409Conflict(fmt.Sprintf(
+			"working tree has local changes (%s); stash or reset them first", strings.Join(other, ", ")))
+	}
+	return managed, nil
+}
+
+// saveManagedFiles reads the named managed files of the checkout at dir, so
+// restoreManagedFiles can put them back after the checkout changes.
+func saveManagedFiles(dir string, names []string) (map[string][]byte, error) {
+	saved := make(map[string][]byte, len(names))
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		saved[name] = data
+	}
+	return saved, nil
+}
+
+// discardManagedFiles returns the named managed files of the checkout at
+// dir to HEAD, removing those git does not track, so a checkout of another
+// version does not trip over them.
+func discardManagedFiles(dir string, names []string) error {
+	for _, name := range names {
+		if _, err := gitOutput(dir, "ls-files", "--error-unmatch", "--", name); err != nil {
+			if err := os.Remove(filepath.Join(dir, filepath.FromSlash(name))); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			continue
+		}
+		if err := gitRun(dir, "checkout", "HEAD", "--", name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restoreManagedFiles writes back the files saveManagedFiles read.
+func restoreManagedFiles(dir string, saved map[string][]byte) error {
+	for name, data := range saved {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			return err
+		}
+		// Node secrets stay readable by the owner only.
+		mode := os.FileMode(0o644)
+		if !slices.Contains(managedFiles, name) {
+			mode = 0o600
+		}
+		if err := os.WriteFile(p, data, mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
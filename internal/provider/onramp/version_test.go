@@ -0,0 +1,301 @@
+package onramp
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bengrewell/aether-webui/internal/provider"
+	"github.com/bengrewell/aether-webui/internal/store"
+)
+
+// initVersionedRepo creates a valid OnRamp checkout with tags v1.0.0 and
+// v2.0.0, leaving HEAD at v2.0.0.
+func initVersionedRepo(t *testing.T, dir string) {
+	t.Helper()
+	initGitRepo(t, dir)
+	if err := os.MkdirAll(filepath.Join(dir, "vars"), 0o755); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+	for i, tag := range []string{"v1.0.0", "v2.0.0"} {
+		if err := os.WriteFile(filepath.Join(dir, "Makefile"), []byte("all:\n"), 0o644); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+		yml := testMainYML + strings.Repeat("#\n", i)
+		if err := os.WriteFile(filepath.Join(dir, "vars", "main.yml"), []byte(yml), 0o644); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+		gitRunTest(t, dir, "add", "-A")
+		gitRunTest(t, dir, "commit", "-m", tag)
+		gitRunTest(t, dir, "tag", "-a", tag, "-m", tag)
+	}
+}
+
+func setVersion(t *testing.T, o *OnRamp, version string, force bool) (*RepoVersionSetOutput, error) {
+	t.Helper()
+	in := &RepoVersionSetInput{}
+	in.Body.Version = version
+	in.Body.Force = force
+	return o.HandleSetRepoVersion(t.Context(), in)
+}
+
+func TestHandleListRepoVersions(t *testing.T) {
+	o := newTestProvider(t, "")
+	initVersionedRepo(t, o.config.OnRampDir)
+
+	out, err := o.HandleListRepoVersions(t.Context(), nil)
+	if err != nil {
+		t.Fatalf("HandleListRepoVersions: %v", err)
+	}
+	if out.Body.Version != "main" {
+		t.Errorf("Version = %q, want main", out.Body.Version)
+	}
+	if len(out.Body.Tags) != 2 {
+		t.Fatalf("tags = %+v, want 2", out.Body.Tags)
+	}
+	head, _ := gitOutput(o.config.OnRampDir, "rev-parse", "HEAD")
+	for _, tag := range out.Body.Tags {
+		if tag.Name == "v2.0.0" && tag.Commit != head {
+			t.Errorf("annotated tag commit = %s, want HEAD %s", tag.Commit, head)
+		}
+	}
+	if len(out.Body.Branches) != 1 || out.Body.Branches[0].Remote {
+		t.Errorf("branches = %+v", out.Body.Branches)
+	}
+}
+
+func TestHandleSetRepoVersion(t *testing.T) {
+	o := newTestProviderWithStore(t, "")
+	initVersionedRepo(t, o.config.OnRampDir)
+
+	out, err := setVersion(t, o, "v1.0.0", false)
+	if err != nil {
+		t.Fatalf("HandleSetRepoVersion: %v", err)
+	}
+	if out.Body.Version != "v1.0.0" || out.Body.Tag != "v1.0.0" || out.Body.Error != "" {
+		t.Errorf("status = %+v", out.Body)
+	}
+
+	// The pin survives a restart and overrides the configured version.
+	o2 := NewProvider(o.config, provider.WithStore(o.Store()))
+	o2.loadPinnedVersion()
+	if got := o2.defaultWorkspace().currentConfig().Version; got != "v1.0.0" {
+		t.Errorf("pinned version after restart = %q, want v1.0.0", got)
+	}
+
+	_, err = setVersion(t, o, "v9.9.9", false)
+	wantStatus(t, err, 422)
+	_, err = setVersion(t, o, "--orphan", false)
+	wantStatus(t, err, 422)
+}
+
+func TestHandleSetRepoVersion_InstalledComponents(t *testing.T) {
+	o := newTestProviderWithStore(t, "")
+	initVersionedRepo(t, o.config.OnRampDir)
+	if err := o.Store().UpsertComponentState(t.Context(), store.ComponentState{
+		Component: "k8s", Status: "installed",
+	}); err != nil {
+		t.Fatalf("UpsertComponentState: %v", err)
+	}
+
+	_, err := setVersion(t, o, "v1.0.0", false)
+	wantStatus(t, err, 409)
+
+	if _, err := setVersion(t, o, "v1.0.0", true); err != nil {
+		t.Fatalf("forced HandleSetRepoVersion: %v", err)
+	}
+}
+
+func TestHandleSetRepoVersion_NamedWorkspace(t *testing.T) {
+	o := newTestProviderWithStore(t, "")
+	o.config.WorkspacesDir = t.TempDir()
+	ws := createWorkspace(t, o, "lab2", testMainYML)
+	if err := os.RemoveAll(ws.config.OnRampDir); err != nil {
+		t.Fatalf("RemoveAll: %v", err)
+	}
+	if err := os.MkdirAll(ws.config.OnRampDir, 0o755); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+	initVersionedRepo(t, ws.config.OnRampDir)
+
+	in := &RepoVersionSetInput{}
+	in.Workspace = "lab2"
+	in.Body.Version = "v1.0.0"
+	if _, err := o.HandleSetRepoVersion(t.Context(), in); err != nil {
+		t.Fatalf("HandleSetRepoVersion: %v", err)
+	}
+	w, _, _ := o.Store().GetWorkspace(t.Context(), "lab2")
+	if w.Version != "v1.0.0" {
+		t.Errorf("stored version = %q, want v1.0.0", w.Version)
+	}
+	if got := o.defaultWorkspace().currentConfig().Version; got != "main" {
+		t.Errorf("default workspace version = %q, want main", got)
+	}
+}
+
+func TestRepoLocalChanges(t *testing.T) {
+	o := newTestProvider(t, "")
+	dir := o.config.OnRampDir
+	initVersionedRepo(t, dir)
+
+	if err := os.WriteFile(filepath.Join(dir, "Makefile"), []byte("all:\n\techo hi\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "scratch.txt"), []byte("x"), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	diff, err := o.HandleGetRepoDiff(t.Context(), nil)
+	if err != nil {
+		t.Fatalf("HandleGetRepoDiff: %v", err)
+	}
+	if !diff.Body.Dirty || len(diff.Body.Changes) != 2 {
+		t.Fatalf("diff = %+v", diff.Body)
+	}
+	want := map[string]string{"Makefile": "M", "scratch.txt": "??"}
+	for _, c := range diff.Body.Changes {
+		if want[c.Path] != c.Status {
+			t.Errorf("change %s = %q, want %q", c.Path, c.Status, want[c.Path])
+		}
+	}
+	if !strings.Contains(diff.Body.Diff, "+\techo hi") {
+		t.Errorf("diff missing change:\n%s", diff.Body.Diff)
+	}
+
+	// Switching versions is refused with local changes.
+	_, err = setVersion(t, o, "v1.0.0", true)
+	wantStatus(t, err, 409)
+
+	stash, err := o.HandleStashRepo(t.Context(), &RepoStashInput{})
+	if err != nil {
+		t.Fatalf("HandleStashRepo: %v", err)
+	}
+	if stash.Body.Status.Dirty {
+		t.Error("expected clean tree after stash")
+	}
+	if list, _ := gitOutput(dir, "stash", "list"); list == "" {
+		t.Error("expected a stash entry")
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "Makefile"), []byte("broken"), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "scratch.txt"), []byte("x"), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	out, err := o.HandleResetRepo(t.Context(), &RepoResetInput{Body: &RepoResetBody{Clean: true}})
+	if err != nil {
+		t.Fatalf("HandleResetRepo: %v", err)
+	}
+	if out.Body.Status.Dirty {
+		t.Error("expected clean tree after reset")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "scratch.txt")); !os.IsNotExist(err) {
+		t.Error("untracked file should be removed by clean reset")
+	}
+}
+
+func TestRepoLocalChanges_KeepsManagedFiles(t *testing.T) {
+	o := newTestProvider(t, "")
+	dir := o.config.OnRampDir
+	initVersionedRepo(t, dir)
+
+	managed := map[string]string{
+		"vars/main.yml":                  testMainYML + "# configured through the API\n",
+		"hosts.ini":                      "[all]\nnode1 ansible_host=10.0.0.1\n",
+		"host_vars/node1/" + secretsFile: "ansible_password: x\n",
+		jumpDir + "/ssh_config":          "Host x\n",
+	}
+	writeManaged := func() {
+		for name, data := range managed {
+			writeTestFile(t, filepath.Join(dir, filepath.FromSlash(name)), data)
+		}
+	}
+	checkManaged := func(op string) {
+		t.Helper()
+		for name, want := range managed {
+			if data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name))); string(data) != want {
+				t.Errorf("after %s %s = %q (%v), want %q", op, name, data, err, want)
+			}
+		}
+	}
+
+	// Only managed files changed: there is nothing to stash.
+	writeManaged()
+	out, err := o.HandleStashRepo(t.Context(), &RepoStashInput{})
+	if err != nil {
+		t.Fatalf("HandleStashRepo: %v", err)
+	}
+	if out.Body.Message != "no local changes to stash" {
+		t.Errorf("message = %q", out.Body.Message)
+	}
+
+	writeTestFile(t, filepath.Join(dir, "Makefile"), "broken")
+	writeTestFile(t, filepath.Join(dir, "scratch.txt"), "x")
+	if _, err := o.HandleStashRepo(t.Context(), &RepoStashInput{}); err != nil {
+		t.Fatalf("HandleStashRepo: %v", err)
+	}
+	checkManaged("stash")
+	if _, err := os.Stat(filepath.Join(dir, "scratch.txt")); !os.IsNotExist(err) {
+		t.Error("untracked file should be stashed")
+	}
+
+	writeTestFile(t, filepath.Join(dir, "Makefile"), "broken")
+	writeTestFile(t, filepath.Join(dir, "scratch.txt"), "x")
+	if _, err := o.HandleResetRepo(t.Context(), &RepoResetInput{Body: &RepoResetBody{Clean: true}}); err != nil {
+		t.Fatalf("HandleResetRepo: %v", err)
+	}
+	checkManaged("reset")
+	if data, _ := os.ReadFile(filepath.Join(dir, "Makefile")); string(data) == "broken" {
+		t.Error("Makefile was not reset")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "scratch.txt")); !os.IsNotExist(err) {
+		t.Error("untracked file should be removed by clean reset")
+	}
+}
+
+func TestRepoVersionHandlers_NotCloned(t *testing.T) {
+	o := newTestProvider(t, "")
+
+	_, err := o.HandleListRepoVersions(t.Context(), nil)
+	wantStatus(t, err, 409)
+	_, err = o.HandleGetRepoDiff(t.Context(), nil)
+	wantStatus(t, err, 409)
+	_, err = setVersion(t, o, "v1.0.0", false)
+	wantStatus(t, err, 409)
+}
+
+func TestSetRepoVersion_KeepsManagedFiles(t *testing.T) {
+	o := newTestProvider(t, "")
+	dir := o.config.OnRampDir
+	initVersionedRepo(t, dir)
+
+	// The daemon's own writes are not local changes.
+	config := testMainYML + "# configured through the API\n"
+	writeTestFile(t, filepath.Join(dir, "vars", "main.yml"), config)
+	writeTestFile(t, filepath.Join(dir, "hosts.ini"), "[all]\nnode1 ansible_host=10.0.0.1\n")
+	writeTestFile(t, filepath.Join(dir, "host_vars", "node1", secretsFile), "ansible_password: x\n")
+	writeTestFile(t, filepath.Join(dir, jumpDir, "ssh_config"), "Host x\n")
+
+	if _, err := setVersion(t, o, "v1.0.0", false); err != nil {
+		t.Fatalf("HandleSetRepoVersion: %v", err)
+	}
+	if head, _ := gitOutput(dir, "describe", "--tags", "--exact-match", "HEAD"); head != "v1.0.0" {
+		t.Errorf("HEAD = %q, want v1.0.0", head)
+	}
+	for name, want := range map[string]string{
+		"vars/main.yml":                  config,
+		"hosts.ini":                      "[all]\nnode1 ansible_host=10.0.0.1\n",
+		"host_vars/node1/" + secretsFile: "ansible_password: x\n",
+	} {
+		if got, err := os.ReadFile(filepath.Join(dir, name)); err != nil || string(got) != want {
+			t.Errorf("%s = %q, %v; want %q", name, got, err, want)
+		}
+	}
+
+	// Other changes still block the switch.
+	writeTestFile(t, filepath.Join(dir, "Makefile"), "edited")
+	_, err := setVersion(t, o, "v2.0.0", false)
+	wantStatus(t, err, 409)
+}
@@ -0,0 +1,334 @@
+package onramp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"github.com/bengrewell/aether-webui/internal/provider"
+	"github.com/bengrewell/aether-webui/internal/store"
+	"github.com/bengrewell/aether-webui/internal/taskrunner"
+)
+
+// workspace is one OnRamp checkout together with the task runner that
+// serialises its make targets. Each workspace manages a separate cluster, so
+// workspaces queue and run independently of each other.
+type workspace struct {
+	name   string
+	config Config // Version may change at runtime; read it via currentConfig
+	runner *taskrunner.Runner
+
+	mu sync.Mutex
+
+	// reg caches the component registry; guarded by mu. See OnRamp.registry.
+	reg *componentRegistry
+
+	// secretsUsers counts the running tasks that need the node secrets
+	// files; guarded by secretsMu. See OnRamp.withSecrets.
+	secretsMu    sync.Mutex
+	secretsUsers int
+
+	// deleted is set when the workspace is removed; guarded by submitMu,
+	// which is held across every task submission. See workspace.submit.
+	submitMu sync.Mutex
+	deleted  bool
+}
+
+// submit queues a task on the workspace's runner unless the workspace has
+// been deleted.
+func (w *workspace) submit(spec taskrunner.TaskSpec) (taskrunner.TaskView, error) {
+	w.submitMu.Lock()
+	defer w.submitMu.Unlock()
+	if w.deleted {
+		return taskrunner.TaskView{}, huma.Error404NotFound("workspace not found", fmt.Errorf("workspace %s was deleted", w.name))
+	}
+	return w.runner.Submit(spec)
+}
+
+// submitAhead is submit for a task that runs ahead of the held task id.
+func (w *workspace) submitAhead(spec taskrunner.TaskSpec, id string) (taskrunner.TaskView, error) {
+	w.submitMu.Lock()
+	defer w.submitMu.Unlock()
+	if w.deleted {
+		return taskrunner.TaskView{}, huma.Error404NotFound("workspace not found", fmt.Errorf("workspace %s was deleted", w.name))
+	}
+	return w.runner.SubmitAhead(spec, id)
+}
+
+// currentConfig returns a snapshot of the workspace configuration including
+// the currently pinned version.
+func (w *workspace) currentConfig() Config {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.config
+}
+
+// setVersion changes the pinned version and drops the component registry
+// built from the previous one.
+func (w *workspace) setVersion(version string) {
+	w.mu.Lock()
+	w.config.Version = version
+	w.reg = nil
+	w.mu.Unlock()
+}
+
+// dropRegistry discards the cached component registry so the next use
+// rebuilds it.
+func (w *workspace) dropRegistry() {
+	w.mu.Lock()
+	w.reg = nil
+	w.mu.Unlock()
+}
+
+// newRunner creates the single-slot task runner used by each workspace.
+func newRunner(base *provider.Base) *taskrunner.Runner {
+	return taskrunner.New(taskrunner.RunnerConfig{
+		MaxConcurrent: 1,
+		Logger:        base.Log(),
+	})
+}
+
+// workspace resolves a workspace by name, loading it from the store on first
+// use. An empty name selects the default workspace. Unknown workspaces
+// return a 404.
+func (o *OnRamp) workspace(ctx context.Context, name string) (*workspace, error) {
+	if name == "" {
+		name = store.DefaultWorkspace
+	}
+	o.mu.Lock()
+	ws, ok := o.workspaces[name]
+	o.mu.Unlock()
+	if ok {
+		return ws, nil
+	}
+
+	w, err := o.ResolveWorkspace(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	// Another request may have loaded it while the store was queried.
+	if ws, ok := o.workspaces[name]; ok {
+		return ws, nil
+	}
+	ws = &workspace{
+		name: w.Name,
+		config: Config{
+			OnRampDir: w.OnRampDir,
+			RepoURL:   w.RepoURL,
+			Version:   w.Version,
+		},
+		runner: newRunner(o.Base),
+	}
+	o.workspaces[name] = ws
+	return ws, nil
+}
+
+// CancelTask cancels a task of the named workspace; an empty name selects
+// the default one. It returns taskrunner.ErrNotFound or
+// taskrunner.ErrNotRunning like taskrunner.Runner.Cancel.
+func (o *OnRamp) CancelTask(ctx context.Context, workspace, id string) error {
+	ws, err := o.workspace(ctx, workspace)
+	if err != nil {
+		return err
+	}
+	return ws.runner.Cancel(id)
+}
+
+// workspacesDir returns the directory under which new workspace checkouts
+// are created.
+func (o *OnRamp) workspacesDir() string {
+	if o.config.WorkspacesDir != "" {
+		return o.config.WorkspacesDir
+	}
+	return filepath.Join(filepath.Dir(o.config.OnRampDir), "workspaces")
+}
+
+// prepareWorkspaces clones and validates the checkout of every stored
+// workspace. Failures are logged; the affected workspace can be repaired with
+// a repo refresh.
+func (o *OnRamp) prepareWorkspaces() {
+	st := o.Store()
+	if st.Path() == "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	list, err := st.ListWorkspaces(ctx)
+	if err != nil {
+		o.Log().Error("failed to list workspaces", "error", err)
+		return
+	}
+	for _, w := range list {
+		ws, err := o.workspace(ctx, w.Name)
+		if err != nil {
+			o.Log().Error("failed to load workspace", "workspace", w.Name, "error", err)
+			continue
+		}
+		if err := ensureRepo(ws.currentConfig(), o.Log().With("workspace", ws.name)); err != nil {
+			o.Log().Warn("workspace repo setup failed", "workspace", ws.name, "error", err)
+		}
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Handlers
+// ---------------------------------------------------------------------------
+
+// HandleListWorkspaces returns the default workspace followed by every stored
+// workspace.
+func (o *OnRamp) HandleListWorkspaces(ctx context.Context, _ *struct{}) (*WorkspaceListOutput, error) {
+	items := []WorkspaceInfo{o.defaultWorkspaceInfo()}
+	st := o.Store()
+	if st.Path() != "" {
+		list, err := st.ListWorkspaces(ctx)
+		if err != nil {
+			return nil, huma.Error500InternalServerError("failed to list workspaces", err)
+		}
+		for _, w := range list {
+			items = append(items, toWorkspaceInfo(w))
+		}
+	}
+	return &WorkspaceListOutput{Body: items}, nil
+}
+
+// HandleGetWorkspace returns a single workspace.
+func (o *OnRamp) HandleGetWorkspace(ctx context.Context, in *WorkspaceGetInput) (*WorkspaceGetOutput, error) {
+	if in.Name == store.DefaultWorkspace {
+		return &WorkspaceGetOutput{Body: o.defaultWorkspaceInfo()}, nil
+	}
+	w, err := o.ResolveWorkspace(ctx, in.Name)
+	if err != nil {
+		return nil, err
+	}
+	return &WorkspaceGetOutput{Body: toWorkspaceInfo(w)}, nil
+}
+
+// HandleCreateWorkspace registers a new workspace. The checkout is cloned by
+// the first repo refresh in that workspace.
+func (o *OnRamp) HandleCreateWorkspace(ctx context.Context, in *WorkspaceCreateInput) (*WorkspaceCreateOutput, error) {
+	st, err := o.requireStore("workspaces are")
+	if err != nil {
+		return nil, err
+	}
+	if in.Body.Name == store.DefaultWorkspace {
+		return nil, huma.Error409Conflict("workspace default already exists")
+	}
+
+	w := store.Workspace{
+		Name:      in.Body.Name,
+		OnRampDir: filepath.Join(o.workspacesDir(), in.Body.Name),
+		RepoURL:   in.Body.RepoURL,
+		Version:   in.Body.Version,
+		CreatedAt: time.Now().UTC(),
+	}
+	if w.RepoURL == "" {
+		w.RepoURL = o.config.RepoURL
+	}
+	if w.Version == "" {
+		w.Version = o.defaultWorkspace().currentConfig().Version
+	}
+	if err := st.CreateWorkspace(ctx, w); err != nil {
+		if errors.Is(err, store.ErrConflict) {
+			return nil, huma.Error409Conflict(fmt.Sprintf("workspace %s already exists", w.Name))
+		}
+		return nil, huma.Error500InternalServerError("failed to create workspace", err)
+	}
+	o.Log().Info("workspace created", "workspace", w.Name, "dir", w.OnRampDir, "version", w.Version)
+	return &WorkspaceCreateOutput{Body: toWorkspaceInfo(w)}, nil
+}
+
+// HandleDeleteWorkspace removes a workspace that has no nodes and no queued
+// or running tasks. The checkout on disk is left in place.
+func (o *OnRamp) HandleDeleteWorkspace(ctx context.Context, in *WorkspaceDeleteInput) (*WorkspaceDeleteOutput, error) {
+	st, err := o.requireStore("workspaces are")
+	if err != nil {
+		return nil, err
+	}
+	if in.Name == store.DefaultWorkspace {
+		return nil, huma.Error409Conflict("the default workspace cannot be deleted")
+	}
+	ws, err := o.workspace(ctx, in.Name)
+	if err != nil {
+		return nil, err
+	}
+	if err := o.CheckChangeLock(ctx, ws.name); err != nil {
+		return nil, err
+	}
+	// Hold off submissions so no task is queued between the idle check and
+	// the delete.
+	ws.submitMu.Lock()
+	defer ws.submitMu.Unlock()
+	if err := ws.requireIdle(); err != nil {
+		return nil, err
+	}
+	nodes, err := st.ListNodes(ctx, ws.name)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("failed to list nodes", err)
+	}
+	if len(nodes) > 0 {
+		return nil, huma.Error409Conflict(fmt.Sprintf("workspace %s still has %d node(s)", ws.name, len(nodes)))
+	}
+
+	if err := st.DeleteWorkspace(ctx, ws.name); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, huma.Error404NotFound("workspace not found", fmt.Errorf("no workspace named %s", ws.name))
+		}
+		return nil, huma.Error500InternalServerError("failed to delete workspace", err)
+	}
+	ws.deleted = true
+	o.mu.Lock()
+	delete(o.workspaces, ws.name)
+	o.mu.Unlock()
+
+	out := &WorkspaceDeleteOutput{}
+	out.Body.Message = fmt.Sprintf("workspace %s deleted; checkout left at %s", ws.name, ws.config.OnRampDir)
+	return out, nil
+}
+
+// requireStore returns the provider's store or a 503 if none is configured.
+func (o *OnRamp) requireStore(feature string) (store.Client, error) {
+	st := o.Store()
+	if st.Path() == "" {
+		return st, huma.Error503ServiceUnavailable(fmt.Sprintf("store not configured; %s unavailable", feature))
+	}
+	return st, nil
+}
+
+// defaultWorkspace returns the always-present default workspace.
+func (o *OnRamp) defaultWorkspace() *workspace {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.workspaces[store.DefaultWorkspace]
+}
+
+func (o *OnRamp) defaultWorkspaceInfo() WorkspaceInfo {
+	cfg := o.defaultWorkspace().currentConfig()
+	return WorkspaceInfo{
+		Name:    store.DefaultWorkspace,
+		Default: true,
+		Dir:     cfg.OnRampDir,
+		RepoURL: cfg.RepoURL,
+		Version: cfg.Version,
+	}
+}
+
+func toWorkspaceInfo(w store.Workspace) WorkspaceInfo {
+	info := WorkspaceInfo{
+		Name:    w.Name,
+		Dir:     w.OnRampDir,
+		RepoURL: w.RepoURL,
+		Version: w.Version,
+	}
+	if !w.CreatedAt.IsZero() {
+		info.CreatedAt = w.CreatedAt.Unix()
+	}
+	return info
+}
@@ -0,0 +1,233 @@
+package onramp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bengrewell/aether-webui/internal/store"
+	"github.com/bengrewell/aether-webui/internal/taskrunner"
+)
+
+// createWorkspace registers a workspace and seeds its checkout with
+// vars/main.yml so handlers can operate without cloning.
+func createWorkspace(t *testing.T, o *OnRamp, name, mainYML string) *workspace {
+	t.Helper()
+	in := &WorkspaceCreateInput{}
+	in.Body.Name = name
+	out, err := o.HandleCreateWorkspace(t.Context(), in)
+	if err != nil {
+		t.Fatalf("HandleCreateWorkspace: %v", err)
+	}
+	varsDir := filepath.Join(out.Body.Dir, "vars")
+	if err := os.MkdirAll(varsDir, 0o755); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(varsDir, "main.yml"), []byte(mainYML), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	ws, err := o.workspace(t.Context(), name)
+	if err != nil {
+		t.Fatalf("workspace(%s): %v", name, err)
+	}
+	return ws
+}
+
+func TestHandleCreateWorkspace(t *testing.T) {
+	o := newTestProviderWithStore(t, "")
+	o.config.WorkspacesDir = t.TempDir()
+
+	in := &WorkspaceCreateInput{}
+	in.Body.Name = "lab2"
+	in.Body.Version = "v2.1.0"
+	out, err := o.HandleCreateWorkspace(t.Context(), in)
+	if err != nil {
+		t.Fatalf("HandleCreateWorkspace: %v", err)
+	}
+	if out.Body.Dir != filepath.Join(o.config.WorkspacesDir, "lab2") {
+		t.Errorf("dir = %q", out.Body.Dir)
+	}
+	if out.Body.RepoURL != o.config.RepoURL || out.Body.Version != "v2.1.0" {
+		t.Errorf("workspace = %+v", out.Body)
+	}
+
+	_, err = o.HandleCreateWorkspace(t.Context(), in)
+	wantStatus(t, err, 409)
+
+	in.Body.Name = store.DefaultWorkspace
+	_, err = o.HandleCreateWorkspace(t.Context(), in)
+	wantStatus(t, err, 409)
+
+	list, err := o.HandleListWorkspaces(t.Context(), nil)
+	if err != nil {
+		t.Fatalf("HandleListWorkspaces: %v", err)
+	}
+	if len(list.Body) != 2 || !list.Body[0].Default || list.Body[1].Name != "lab2" {
+		t.Errorf("workspaces = %+v", list.Body)
+	}
+
+	got, err := o.HandleGetWorkspace(t.Context(), &WorkspaceGetInput{Name: "lab2"})
+	if err != nil {
+		t.Fatalf("HandleGetWorkspace: %v", err)
+	}
+	if got.Body.Version != "v2.1.0" || got.Body.CreatedAt == 0 {
+		t.Errorf("workspace = %+v", got.Body)
+	}
+	_, err = o.HandleGetWorkspace(t.Context(), &WorkspaceGetInput{Name: "nope"})
+	wantStatus(t, err, 404)
+}
+
+func TestWorkspace_Unknown(t *testing.T) {
+	o := newTestProviderWithStore(t, testMainYML)
+
+	in := &ConfigPatchInput{RawBody: []byte(`{}`)}
+	in.Workspace = "nope"
+	_, err := o.HandlePatchConfig(t.Context(), in)
+	wantStatus(t, err, 404)
+
+	_, err = o.HandleGetConfig(t.Context(), &WorkspaceInput{WorkspaceParam{Workspace: "nope"}})
+	wantStatus(t, err, 404)
+}
+
+func TestWorkspace_ScopesConfigAndState(t *testing.T) {
+	o := newTestProviderWithStore(t, testMainYML)
+	o.config.WorkspacesDir = t.TempDir()
+	createWorkspace(t, o, "lab2", testMainYML)
+	lab2 := &WorkspaceInput{WorkspaceParam{Workspace: "lab2"}}
+
+	patch := &ConfigPatchInput{RawBody: []byte(`{"core": {"data_iface": "ens99"}}`)}
+	patch.Workspace = "lab2"
+	if _, err := o.HandlePatchConfig(t.Context(), patch); err != nil {
+		t.Fatalf("HandlePatchConfig: %v", err)
+	}
+
+	def, err := o.HandleGetConfig(t.Context(), nil)
+	if err != nil {
+		t.Fatalf("HandleGetConfig default: %v", err)
+	}
+	other, err := o.HandleGetConfig(t.Context(), lab2)
+	if err != nil {
+		t.Fatalf("HandleGetConfig lab2: %v", err)
+	}
+	if other.Body.Core == nil || other.Body.Core.DataIface != "ens99" {
+		t.Errorf("lab2 core = %+v", other.Body.Core)
+	}
+	if def.Body.Core != nil && def.Body.Core.DataIface == "ens99" {
+		t.Error("patching lab2 changed the default workspace config")
+	}
+
+	st := o.Store()
+	if err := st.UpsertComponentState(t.Context(), store.ComponentState{
+		Workspace: "lab2", Component: "k8s", Status: "installed",
+	}); err != nil {
+		t.Fatalf("UpsertComponentState: %v", err)
+	}
+	states, err := o.HandleListComponentStates(t.Context(), lab2)
+	if err != nil {
+		t.Fatalf("HandleListComponentStates: %v", err)
+	}
+	for _, s := range states.Body {
+		if s.Component == "k8s" && s.Status != "installed" {
+			t.Errorf("lab2 k8s = %q, want installed", s.Status)
+		}
+	}
+	got, err := o.HandleGetComponentState(t.Context(), &ComponentStateGetInput{Component: "k8s"})
+	if err != nil {
+		t.Fatalf("HandleGetComponentState: %v", err)
+	}
+	if got.Body.Status != "not_installed" {
+		t.Errorf("default k8s = %q, want not_installed", got.Body.Status)
+	}
+
+	repo, err := o.HandleGetRepoStatus(t.Context(), lab2)
+	if err != nil {
+		t.Fatalf("HandleGetRepoStatus: %v", err)
+	}
+	if repo.Body.Workspace != "lab2" || repo.Body.Dir != filepath.Join(o.config.WorkspacesDir, "lab2") {
+		t.Errorf("repo status = %+v", repo.Body)
+	}
+}
+
+func TestWorkspace_IndependentLocksAndQueues(t *testing.T) {
+	o := newTestProviderWithStore(t, testMainYML)
+	o.config.WorkspacesDir = t.TempDir()
+	ws := createWorkspace(t, o, "lab2", testMainYML)
+
+	acquireMaintenance(t, o)
+	if err := o.CheckChangeLock(t.Context(), "lab2"); err != nil {
+		t.Fatalf("lab2 should not be locked by the default workspace lock: %v", err)
+	}
+	wantStatus(t, o.CheckChangeLock(t.Context(), ""), 423)
+
+	if ws.runner == o.runner {
+		t.Fatal("workspaces must not share a task runner")
+	}
+	q, err := o.HandlePauseQueue(t.Context(), &WorkspaceInput{WorkspaceParam{Workspace: "lab2"}})
+	if err != nil {
+		t.Fatalf("HandlePauseQueue: %v", err)
+	}
+	if !q.Body.Paused {
+		t.Error("lab2 queue should be paused")
+	}
+	def, _ := o.HandleGetQueue(t.Context(), nil)
+	if def.Body.Paused {
+		t.Error("default queue should not be paused")
+	}
+}
+
+func TestHandleDeleteWorkspace(t *testing.T) {
+	o := newTestProviderWithStore(t, "")
+	o.config.WorkspacesDir = t.TempDir()
+	ws := createWorkspace(t, o, "lab2", testMainYML)
+	st := o.Store()
+
+	_, err := o.HandleDeleteWorkspace(t.Context(), &WorkspaceDeleteInput{Name: store.DefaultWorkspace})
+	wantStatus(t, err, 409)
+
+	if err := st.UpsertNode(t.Context(), store.Node{
+		ID: "n1", Workspace: "lab2", Name: "node1", AnsibleHost: "10.1.0.1",
+	}); err != nil {
+		t.Fatalf("UpsertNode: %v", err)
+	}
+	_, err = o.HandleDeleteWorkspace(t.Context(), &WorkspaceDeleteInput{Name: "lab2"})
+	wantStatus(t, err, 409)
+	if err := st.DeleteNode(t.Context(), "n1"); err != nil {
+		t.Fatalf("DeleteNode: %v", err)
+	}
+
+	if _, err := o.HandleDeleteWorkspace(t.Context(), &WorkspaceDeleteInput{Name: "lab2"}); err != nil {
+		t.Fatalf("HandleDeleteWorkspace: %v", err)
+	}
+	if _, err := os.Stat(ws.config.OnRampDir); err != nil {
+		t.Errorf("checkout should be left on disk: %v", err)
+	}
+	_, err = o.HandleGetConfig(t.Context(), &WorkspaceInput{WorkspaceParam{Workspace: "lab2"}})
+	wantStatus(t, err, 404)
+	_, err = o.HandleDeleteWorkspace(t.Context(), &WorkspaceDeleteInput{Name: "lab2"})
+	wantStatus(t, err, 404)
+
+	// A handler that looked the workspace up before the delete cannot
+	// queue a task on it afterwards.
+	_, err = ws.submit(taskrunner.TaskSpec{ID: "late", Command: "true"})
+	wantStatus(t, err, 404)
+	if len(ws.runner.List(nil)) != 0 {
+		t.Error("task submitted to a deleted workspace")
+	}
+}
+
+func TestWorkspaceHandlers_NoStore(t *testing.T) {
+	o := newTestProvider(t, "")
+
+	in := &WorkspaceCreateInput{}
+	in.Body.Name = "lab2"
+	_, err := o.HandleCreateWorkspace(t.Context(), in)
+	wantStatus(t, err, 503)
+
+	list, err := o.HandleListWorkspaces(t.Context(), nil)
+	if err != nil {
+		t.Fatalf("HandleListWorkspaces: %v", err)
+	}
+	if len(list.Body) != 1 || list.Body[0].Name != store.DefaultWorkspace {
+		t.Errorf("workspaces = %+v", list.Body)
+	}
+}
@@ -0,0 +1,426 @@
+package onramp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// varsDoc is a vars file held as a YAML node tree rather than as an
+// OnRampConfig. Edits made through it touch only the keys they change, so
+// comments, key order and sections the typed config does not model survive
+// a write. Upstream OnRamp adds keys regularly; round-tripping through the
+// struct would silently drop them.
+type varsDoc struct {
+	src  []byte
+	doc  *yaml.Node // document node
+	root *yaml.Node // top-level mapping
+}
+
+// parseVarsDoc parses vars file content. An empty file yields an empty
+// mapping.
+func parseVarsDoc(data []byte) (*varsDoc, error) {
+	d := &varsDoc{src: data, doc: &yaml.Node{}}
+	if err := yaml.Unmarshal(data, d.doc); err != nil {
+		return nil, err
+	}
+	if d.doc.Kind == 0 {
+		d.doc.Kind = yaml.DocumentNode
+	}
+	switch {
+	case len(d.doc.Content) == 0:
+		d.root = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		d.doc.Content = []*yaml.Node{d.root}
+	case d.doc.Content[0].Kind == yaml.MappingNode:
+		d.root = d.doc.Content[0]
+	default:
+		return nil, errors.New("top level of a vars file must be a mapping")
+	}
+	return d, nil
+}
+
+// loadVarsDoc reads and parses the vars file at path.
+func loadVarsDoc(path string) (*varsDoc, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	d, err := parseVarsDoc(data)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return d, nil
+}
+
+// section returns the value under a top-level key, or nil.
+func (d *varsDoc) section(key string) *yaml.Node {
+	if i := keyIndex(d.root, key); i >= 0 {
+		return d.root.Content[i+1]
+	}
+	return nil
+}
+
+// mergeSection merges src into the top-level key. Mappings are merged key by
+// key; anything else replaces the current value.
+func (d *varsDoc) mergeSection(key string, src *yaml.Node) {
+	if cur := d.section(key); cur != nil && cur.Kind == yaml.MappingNode && src.Kind == yaml.MappingNode {
+		mergeNodes(cur, src)
+		return
+	}
+	setKey(d.root, key, src)
+}
+
+// remove deletes a top-level key and reports whether it was present.
+func (d *varsDoc) remove(key string) bool {
+	return removeKey(d.root, key)
+}
+
+// patch deep-merges a JSON object into the document. Nested objects are
+// merged key by key, other values replace what they overwrite, and null
+// removes a key.
+func (d *varsDoc) patch(patchJSON []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(patchJSON))
+	dec.UseNumber()
+	var patch map[string]any
+	if err := dec.Decode(&patch); err != nil {
+		return fmt.Errorf("%w: merge patch must be a JSON object: %v", errBadPatch, err)
+	}
+	return mergePatch(d.root, patch)
+}
+
+// config decodes the document into the typed config.
+func (d *varsDoc) config() (OnRampConfig, error) {
+	var cfg OnRampConfig
+	if err := d.root.Decode(&cfg); err != nil {
+		return OnRampConfig{}, err
+	}
+	return cfg, nil
+}
+
+// bytes encodes the document. The encoder normalises spacing, so lines that
+// are unchanged from the source are copied back verbatim and its blank lines
+// are restored.
+func (d *varsDoc) bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(detectIndent(d.src))
+	if err := enc.Encode(d.doc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return restoreLayout(d.src, buf.Bytes()), nil
+}
+
+// MergeVars deep-merges the JSON object patch into the vars file content
+// data and returns the new content with its typed form. Nested objects are
+// merged key by key, other values replace what they overwrite, and null
+// removes a key. Everything the patch does not name, including comments and
+// keys OnRampConfig does not model, is kept as it was.
+func MergeVars(data, patch []byte) ([]byte, OnRampConfig, error) {
+	d, err := parseVarsDoc(data)
+	if err != nil {
+		return nil, OnRampConfig{}, fmt.Errorf("parse vars: %w", err)
+	}
+	if err := d.patch(patch); err != nil {
+		return nil, OnRampConfig{}, err
+	}
+	cfg, err := d.config()
+	if err != nil {
+		return nil, OnRampConfig{}, fmt.Errorf("decode merged config: %w", err)
+	}
+	out, err := d.bytes()
+	if err != nil {
+		return nil, OnRampConfig{}, fmt.Errorf("encode merged config: %w", err)
+	}
+	return out, cfg, nil
+}
+
+// ---------------------------------------------------------------------------
+// Node helpers
+// ---------------------------------------------------------------------------
+
+// keyIndex returns the index of key's key node in mapping m, or -1.
+func keyIndex(m *yaml.Node, key string) int {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// setKey replaces the value under key in mapping m, or appends the pair.
+// Comments on the replaced value carry over when v has none of its own.
+func setKey(m *yaml.Node, key string, v *yaml.Node) {
+	i := keyIndex(m, key)
+	if i < 0 {
+		appendPair(m, keyNode(key), v)
+		return
+	}
+	old := m.Content[i+1]
+	if v.HeadComment == "" {
+		v.HeadComment = old.HeadComment
+	}
+	if v.LineComment == "" {
+		v.LineComment = old.LineComment
+	}
+	if v.FootComment == "" {
+		v.FootComment = old.FootComment
+	}
+	m.Content[i+1] = v
+}
+
+// appendPair adds a key/value pair to mapping m. An empty flow mapping ({})
+// that gains keys is switched to block style to match the rest of the file.
+func appendPair(m, k, v *yaml.Node) {
+	if len(m.Content) == 0 {
+		m.Style &^= yaml.FlowStyle
+	}
+	m.Content = append(m.Content, k, v)
+}
+
+func removeKey(m *yaml.Node, key string) bool {
+	i := keyIndex(m, key)
+	if i < 0 {
+		return false
+	}
+	m.Content = append(m.Content[:i], m.Content[i+2:]...)
+	return true
+}
+
+// mergeNodes merges mapping src into mapping dst, recursing where both sides
+// hold a mapping.
+func mergeNodes(dst, src *yaml.Node) {
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		k, sv := src.Content[i], src.Content[i+1]
+		j := keyIndex(dst, k.Value)
+		if j < 0 {
+			appendPair(dst, k, sv)
+			continue
+		}
+		if dv := dst.Content[j+1]; dv.Kind == yaml.MappingNode && sv.Kind == yaml.MappingNode {
+			mergeNodes(dv, sv)
+			continue
+		}
+		setKey(dst, k.Value, sv)
+	}
+}
+
+// mergePatch merges a decoded JSON object into mapping m.
+func mergePatch(m *yaml.Node, patch map[string]any) error {
+	keys := make([]string, 0, len(patch))
+	for k := range patch {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		pv := patch[k]
+		if pv == nil {
+			removeKey(m, k)
+			continue
+		}
+		if pm, ok := pv.(map[string]any); ok {
+			if i := keyIndex(m, k); i >= 0 && m.Content[i+1].Kind == yaml.MappingNode {
+				if err := mergePatch(m.Content[i+1], pm); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		v, err := valueNode(pv)
+		if err != nil {
+			return fmt.Errorf("%s: %w", k, err)
+		}
+		if i := keyIndex(m, k); i >= 0 {
+			keepQuoting(m.Content[i+1], v)
+		}
+		setKey(m, k, v)
+	}
+	return nil
+}
+
+// keepQuoting gives a replacement string the quoting style of the value it
+// replaces, so "10.0.0.1" stays quoted the way upstream writes it.
+func keepQuoting(old, v *yaml.Node) {
+	if old.Kind != yaml.ScalarNode || v.Kind != yaml.ScalarNode || v.Tag != "!!str" {
+		return
+	}
+	if old.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle) != 0 {
+		v.Style = old.Style
+	}
+}
+
+// valueNode builds a node from a value decoded from JSON. Object keys are
+// sorted so new sections are written deterministically.
+func valueNode(v any) (*yaml.Node, error) {
+	switch val := v.(type) {
+	case map[string]any:
+		n := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if val[k] == nil {
+				continue
+			}
+			c, err := valueNode(val[k])
+			if err != nil {
+				return nil, err
+			}
+			n.Content = append(n.Content, keyNode(k), c)
+		}
+		return n, nil
+	case []any:
+		n := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for _, e := range val {
+			c, err := valueNode(e)
+			if err != nil {
+				return nil, err
+			}
+			n.Content = append(n.Content, c)
+		}
+		return n, nil
+	case json.Number:
+		if i, err := val.Int64(); err == nil {
+			v = i
+		} else if f, err := val.Float64(); err == nil {
+			v = f
+		} else {
+			return nil, err
+		}
+	}
+	n := &yaml.Node{}
+	if err := n.Encode(v); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// keyNode builds a mapping key. JSON object keys are always strings, but
+// OnRamp indexes servers by bare integers (0:), so numeric keys are written
+// unquoted to keep decoding into map[int] fields.
+func keyNode(k string) *yaml.Node {
+	if _, err := strconv.Atoi(k); err == nil {
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: k}
+	}
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: k}
+}
+
+// ---------------------------------------------------------------------------
+// Layout
+// ---------------------------------------------------------------------------
+
+// detectIndent returns the indentation step of src: the smallest indent of a
+// content line. It defaults to 2.
+func detectIndent(src []byte) int {
+	indent := 0
+	for _, line := range splitLines(string(src)) {
+		trimmed := strings.TrimLeft(line, " ")
+		n := len(line) - len(trimmed)
+		if n == 0 || trimmed == "" || trimmed[0] == '#' {
+			continue
+		}
+		if indent == 0 || n < indent {
+			indent = n
+		}
+	}
+	if indent < 2 {
+		return 2
+	}
+	return indent
+}
+
+// layoutKey normalises a line for matching against the source: runs of
+// spaces, such as aligned values and trailing comments, collapse to one, and
+// comment-only lines ignore indentation.
+func layoutKey(line string) string {
+	rest := strings.TrimLeft(line, " ")
+	indent := line[:len(line)-len(rest)]
+	if strings.HasPrefix(rest, "#") {
+		indent = ""
+	}
+	return indent + strings.Join(strings.Fields(rest), " ")
+}
+
+// restoreLayout takes encoder output and puts back the source formatting the
+// encoder drops. Output lines that match a source line are replaced by the
+// source line, which restores alignment and the indentation of comments, and
+// blank lines that preceded a matched source line are reinserted. New or
+// changed lines are left as the encoder wrote them. Matching ignores spacing
+// inside a line, so the result is kept only if it still parses to the same
+// document as the encoder output.
+func restoreLayout(src, out []byte) []byte {
+	restored := alignLines(src, out)
+	var want, got any
+	if yaml.Unmarshal(out, &want) != nil || yaml.Unmarshal(restored, &got) != nil || !reflect.DeepEqual(want, got) {
+		return out
+	}
+	return restored
+}
+
+func alignLines(src, out []byte) []byte {
+	sl, ol := splitLines(string(src)), splitLines(string(out))
+	if len(sl) == 0 {
+		return out
+	}
+	sk, ok := make([]string, len(sl)), make([]string, len(ol))
+	for i, l := range sl {
+		sk[i] = layoutKey(l)
+	}
+	for j, l := range ol {
+		ok[j] = layoutKey(l)
+	}
+	lcs := lcsTable(sk, ok)
+
+	var b strings.Builder
+	if strings.TrimSpace(sl[0]) == "---" && (len(ol) == 0 || strings.TrimSpace(ol[0]) != "---") {
+		b.WriteString(sl[0] + "\n")
+	}
+	lastSrc, lastBlank := -1, false
+	emit := func(line string) {
+		b.WriteString(line)
+		b.WriteByte('\n')
+		lastBlank = strings.TrimSpace(line) == ""
+	}
+	i, j := 0, 0
+	for j < len(ol) {
+		switch {
+		case i < len(sl) && sk[i] == ok[j]:
+			if sk[i] != "" {
+				start := i
+				for start > lastSrc+1 && strings.TrimSpace(sl[start-1]) == "" {
+					start--
+				}
+				if !lastBlank {
+					for _, blank := range sl[start:i] {
+						emit(blank)
+					}
+				}
+				emit(sl[i])
+			} else if !lastBlank {
+				emit(sl[i])
+			}
+			lastSrc = i
+			i++
+			j++
+		case i < len(sl) && lcs[i+1][j] >= lcs[i][j+1]:
+			i++
+		default:
+			emit(ol[j])
+			j++
+		}
+	}
+	return []byte(b.String())
+}
@@ -0,0 +1,202 @@
+package onramp
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// blueprintFiles returns the vars files in testdata/blueprints, which are
+// modelled on the upstream aether-onramp vars/main-*.yml blueprints:
+// aligned trailing comments, commented-out blocks, blank lines between
+// sections, quoted strings and integer-keyed servers.
+func blueprintFiles(t *testing.T) map[string][]byte {
+	t.Helper()
+	paths, err := filepath.Glob(filepath.Join("testdata", "blueprints", "main*.yml"))
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("no blueprints in testdata: %v", err)
+	}
+	files := make(map[string][]byte, len(paths))
+	for _, p := range paths {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			t.Fatalf("ReadFile: %v", err)
+		}
+		files[filepath.Base(p)] = data
+	}
+	return files
+}
+
+// changedLines returns the lines of a and b that differ, as a unified diff
+// body without headers.
+func changedLines(a, b []byte) []string {
+	var out []string
+	for _, l := range splitLines(unifiedDiff("a", "b", string(a), string(b))) {
+		if (strings.HasPrefix(l, "-") || strings.HasPrefix(l, "+")) &&
+			!strings.HasPrefix(l, "---") && !strings.HasPrefix(l, "+++") {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+func TestVarsDoc_BlueprintsRoundTrip(t *testing.T) {
+	for name, src := range blueprintFiles(t) {
+		t.Run(name, func(t *testing.T) {
+			d, err := parseVarsDoc(src)
+			if err != nil {
+				t.Fatalf("parseVarsDoc: %v", err)
+			}
+			out, err := d.bytes()
+			if err != nil {
+				t.Fatalf("bytes: %v", err)
+			}
+			if string(out) != string(src) {
+				t.Errorf("unedited round trip changed the file:\n%s", strings.Join(changedLines(src, out), "\n"))
+			}
+
+			// The document decodes to the same config as the typed reader.
+			var want OnRampConfig
+			if err := yaml.Unmarshal(src, &want); err != nil {
+				t.Fatalf("yaml.Unmarshal: %v", err)
+			}
+			got, err := d.config()
+			if err != nil {
+				t.Fatalf("config: %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("config = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestMergeVars_BlueprintsChangeOnlyPatchedLines(t *testing.T) {
+	patch := []byte(`{"core": {"data_iface": "enp5s0", "amf": {"ip": "10.0.0.10"}}}`)
+	want := []string{
+		`+    ip: "10.0.0.10"`,
+		"+  data_iface: enp5s0",
+		`-    ip: "10.76.28.113"`,
+		"-  data_iface: ens18",
+	}
+	for name, src := range blueprintFiles(t) {
+		t.Run(name, func(t *testing.T) {
+			out, cfg, err := MergeVars(src, patch)
+			if err != nil {
+				t.Fatalf("MergeVars: %v", err)
+			}
+			if cfg.Core.DataIface != "enp5s0" || cfg.Core.AMF.IP != "10.0.0.10" {
+				t.Errorf("core = %+v", cfg.Core)
+			}
+			got := changedLines(src, out)
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("changed lines = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestMergeVars_UnknownKeysAndComments(t *testing.T) {
+	src := []byte(`# site overrides
+core:
+  data_iface: ens18   # uplink
+  ran_subnet: "172.20.0.0/16"
+  upf:
+    mode: af_packet
+    dpdk_vfs: 2          # not modelled by OnRampConfig
+
+# a section added upstream after this release
+ric:
+  enabled: true
+  xapps: [kpimon, mho]
+`)
+	out, cfg, err := MergeVars(src, []byte(`{"core": {"ran_subnet": "10.10.0.0/16", "upf": {"mode": null}}, "ric": {"replicas": 2}}`))
+	if err != nil {
+		t.Fatalf("MergeVars: %v", err)
+	}
+	want := `# site overrides
+core:
+  data_iface: ens18   # uplink
+  ran_subnet: "10.10.0.0/16"
+  upf:
+    dpdk_vfs: 2          # not modelled by OnRampConfig
+
+# a section added upstream after this release
+ric:
+  enabled: true
+  xapps: [kpimon, mho]
+  replicas: 2
+`
+	if string(out) != want {
+		t.Errorf("merged =\n%s\nwant\n%s", out, want)
+	}
+	if cfg.Core.RANSubnet != "10.10.0.0/16" || cfg.Core.UPF == nil || cfg.Core.UPF.Mode != "" {
+		t.Errorf("core = %+v", cfg.Core)
+	}
+
+	if _, _, err := MergeVars(src, []byte(`{"k8s": {"rke2": {"config": {"port": "x"}}}}`)); err == nil {
+		t.Error("MergeVars accepted a value the typed config cannot hold")
+	}
+}
+
+func TestHandlePatchConfig_KeepsUnknownKeys(t *testing.T) {
+	src := testMainYML + "future:\n  key: value # kept\n"
+	o := newTestProvider(t, src)
+	patchConfig(t, o, `{"core": {"data_iface": "ens99"}}`, "")
+
+	data, err := os.ReadFile(filepath.Join(o.config.OnRampDir, "vars", "main.yml"))
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if want := strings.Replace(src, "data_iface: ens18", "data_iface: ens99", 1); string(data) != want {
+		t.Errorf("main.yml =\n%s\nwant\n%s", data, want)
+	}
+}
+
+func TestComposeConfig_KeepsCommentsAndUnknownKeys(t *testing.T) {
+	files := blueprintFiles(t)
+	base := string(files["main.yml"]) + "\n# local additions\nsite:\n  name: lab-1\n"
+	p := newTestProvider(t, base)
+	writeRawBlueprint(t, p, "main-srsran.yml", string(files["main-srsran.yml"]))
+
+	out, err := p.HandleComposeConfig(t.Context(), &ConfigComposeInput{
+		Body: ConfigComposeBody{Components: []string{"5gc", "srsran"}},
+	})
+	if err != nil {
+		t.Fatalf("HandleComposeConfig: %v", err)
+	}
+	if cfg := out.Body.Config; cfg.SRSRan == nil || cfg.SRSRan.Servers[0] == nil || cfg.GNBSim != nil || cfg.AMP != nil {
+		t.Fatalf("config = %+v", cfg)
+	}
+
+	data, err := os.ReadFile(filepath.Join(p.config.OnRampDir, "vars", "main.yml"))
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	got := string(data)
+	for _, want := range []string{
+		"# Variables for a single-server deployment of 5G core and gNBsim.\n",
+		"  standalone: true                        # set to false to place under control of ROC\n",
+		"    # If mode set to 'dpdk':\n",
+		"      #   ue_ip_pool: \"172.247.0.0/16\"\n",
+		"  values_file: \"deps/srsran/roles/gNB/templates/srsran-5g-values.yaml\"\n",
+		"\nsrsran:\n",
+		"    0:\n      gnb_ip: \"10.76.28.115\"\n",
+		"# local additions\nsite:\n  name: lab-1\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("composed main.yml lacks %q:\n%s", want, got)
+		}
+	}
+	for _, gone := range []string{"gnbsim:", "amp:"} {
+		if strings.Contains(got, gone) {
+			t.Errorf("composed main.yml still has %s", gone)
+		}
+	}
+}
@@ -5,9 +5,13 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"net"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
+
+	internalssh "github.com/bengrewell/aether-webui/internal/ssh"
 )
 
 // registry is the ordered list of all preflight checks.
@@ -486,12 +490,12 @@ func checkNodeSSHReachable() Check {
 	return Check{
 		ID:          "node-ssh-reachable",
 		Name:        "Node SSH Reachability",
-		Description: "Checks that all managed nodes are reachable via SSH (TCP port 22).",
+		Description: "Checks that all managed nodes are reachable via SSH, through their jump hosts if any.",
 		Severity:    SeverityWarning,
 		Category:    CategoryNetwork,
 		RunCheck: func(ctx context.Context, deps CheckDeps) CheckResult {
 			r := newResult("node-ssh-reachable", "Node SSH Reachability",
-				"Checks that all managed nodes are reachable via SSH (TCP port 22).",
+				"Checks that all managed nodes are reachable via SSH, through their jump hosts if any.",
 				SeverityWarning, CategoryNetwork, false)
 
 			// Empty path means no store was configured.
@@ -501,7 +505,8 @@ func checkNodeSSHReachable() Check {
 				return r
 			}
 
-			nodes, err := deps.Store.ListNodes(ctx)
+			// Only the default workspace's nodes are checked.
+			nodes, err := deps.Store.ListNodes(ctx, "")
 			if err != nil {
 				r.Error = fmt.Sprintf("failed to list nodes: %v", err)
 				r.Message = "unable to query node list"
@@ -526,14 +531,36 @@ func checkNodeSSHReachable() Check {
 					continue
 				}
 
-				addr := host + ":22"
-				conn, err := deps.DialTimeout("tcp", addr, dialTimeout)
+				port := node.AnsiblePort
+				if port == 0 {
+					port = 22
+				}
+				addr := net.JoinHostPort(host, strconv.Itoa(port))
+				label := addr
+				chain, err := internalssh.JumpChain(ctx, deps.Store, node.Workspace, node.JumpHost)
+				if err != nil {
+					details = append(details, fmt.Sprintf("  %s (%s): UNREACHABLE — %v", node.Name, label, err))
+					unreachable++
+					continue
+				}
+
+				var conn net.Conn
+				if len(chain) == 0 {
+					conn, err = deps.DialTimeout("tcp", addr, dialTimeout)
+				} else {
+					jumps := make([]internalssh.Config, 0, len(chain))
+					for _, j := range chain {
+						jumps = append(jumps, internalssh.JumpConfig(j, dialTimeout))
+					}
+					label = fmt.Sprintf("%s via %s", addr, chain[len(chain)-1].Name)
+					conn, err = deps.DialJump(ctx, jumps, addr, dialTimeout)
+				}
 				if err != nil {
-					details = append(details, fmt.Sprintf("  %s (%s): UNREACHABLE — %v", node.Name, addr, err))
+					details = append(details, fmt.Sprintf("  %s (%s): UNREACHABLE — %v", node.Name, label, err))
 					unreachable++
 				} else {
 					conn.Close()
-					details = append(details, fmt.Sprintf("  %s (%s): OK", node.Name, addr))
+					details = append(details, fmt.Sprintf("  %s (%s): OK", node.Name, label))
 					reachable++
 				}
 			}
@@ -543,15 +570,15 @@ func checkNodeSSHReachable() Check {
 			if unreachable == 0 && checked > 0 {
 				r.Passed = true
 				if skipped > 0 {
-					r.Message = fmt.Sprintf("%d node(s) reachable on port 22 (%d skipped, no ansible_host)", reachable, skipped)
+					r.Message = fmt.Sprintf("%d node(s) reachable over SSH (%d skipped, no ansible_host)", reachable, skipped)
 				} else {
-					r.Message = fmt.Sprintf("all %d node(s) reachable on port 22", reachable)
+					r.Message = fmt.Sprintf("all %d node(s) reachable over SSH", reachable)
 				}
 			} else if checked == 0 {
 				r.Passed = true
 				r.Message = fmt.Sprintf("all %d node(s) skipped (no ansible_host configured)", skipped)
 			} else {
-				r.Message = fmt.Sprintf("%d of %d node(s) unreachable on port 22", unreachable, checked)
+				r.Message = fmt.Sprintf("%d of %d node(s) unreachable over SSH", unreachable, checked)
 			}
 			return r
 		},
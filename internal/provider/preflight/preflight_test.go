@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/bengrewell/aether-webui/internal/provider"
+	internalssh "github.com/bengrewell/aether-webui/internal/ssh"
 	"github.com/bengrewell/aether-webui/internal/store"
 )
 
@@ -775,6 +776,53 @@ func TestCheckNodeSSHReachable_SomeUnreachable(t *testing.T) {
 	}
 }
 
+func TestCheckNodeSSHReachable_ThroughJumpHost(t *testing.T) {
+	p := newTestProviderWithStore(t)
+	ctx := t.Context()
+
+	if err := p.Store().UpsertJumpHost(ctx, store.JumpHost{
+		Name: "bastion", Host: "203.0.113.1", User: "ops", Default: true,
+	}); err != nil {
+		t.Fatalf("UpsertJumpHost: %v", err)
+	}
+	for _, n := range []store.Node{
+		{ID: "n1", Name: "node1", AnsibleHost: "10.0.0.1", NodeVars: store.NodeVars{AnsiblePort: 2222}},
+		{ID: "n2", Name: "node2", AnsibleHost: "10.0.0.2", NodeVars: store.NodeVars{JumpHost: store.JumpHostNone}},
+	} {
+		if err := p.Store().UpsertNode(ctx, n); err != nil {
+			t.Fatalf("UpsertNode: %v", err)
+		}
+	}
+
+	var direct, jumped []string
+	deps := DefaultDeps(p.Store(), p.Log())
+	deps.DialTimeout = func(network, addr string, timeout time.Duration) (net.Conn, error) {
+		direct = append(direct, addr)
+		return &fakeConn{}, nil
+	}
+	deps.DialJump = func(ctx context.Context, jumps []internalssh.Config, addr string, timeout time.Duration) (net.Conn, error) {
+		if len(jumps) != 1 || jumps[0].Host != "203.0.113.1:22" || jumps[0].User != "ops" {
+			t.Errorf("jumps = %+v", jumps)
+		}
+		jumped = append(jumped, addr)
+		return &fakeConn{}, nil
+	}
+
+	r := checkNodeSSHReachable().RunCheck(ctx, deps)
+	if !r.Passed {
+		t.Errorf("expected Passed=true, message=%q", r.Message)
+	}
+	if len(jumped) != 1 || jumped[0] != "10.0.0.1:2222" {
+		t.Errorf("jumped = %v, want [10.0.0.1:2222]", jumped)
+	}
+	if len(direct) != 1 || direct[0] != "10.0.0.2:22" {
+		t.Errorf("direct = %v, want [10.0.0.2:22]", direct)
+	}
+	if !strings.Contains(r.Details, "via bastion") {
+		t.Errorf("details = %q, expected 'via bastion'", r.Details)
+	}
+}
+
 // ---------------------------------------------------------------------------
 // Fix function tests
 // ---------------------------------------------------------------------------
@@ -10,6 +10,7 @@ import (
 	"os/user"
 	"time"
 
+	internalssh "github.com/bengrewell/aether-webui/internal/ssh"
 	"github.com/bengrewell/aether-webui/internal/store"
 )
 
@@ -41,6 +42,7 @@ type CheckDeps struct {
 	LookupUser  func(string) (*user.User, error)
 	RunCommand  func(ctx context.Context, name string, args ...string) ([]byte, error)
 	DialTimeout func(network, addr string, timeout time.Duration) (net.Conn, error)
+	DialJump    func(ctx context.Context, jumps []internalssh.Config, addr string, timeout time.Duration) (net.Conn, error)
 	Stat        func(string) (os.FileInfo, error)
 }
 
@@ -57,6 +59,7 @@ func DefaultDeps(st store.Client, log *slog.Logger) CheckDeps {
 			return exec.CommandContext(ctx, name, args...).CombinedOutput()
 		},
 		DialTimeout: net.DialTimeout,
+		DialJump:    internalssh.DialTCP,
 		Stat:        os.Stat,
 	}
 }
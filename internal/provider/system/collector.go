@@ -13,13 +13,19 @@ import (
 	"github.com/bengrewell/aether-webui/internal/store"
 )
 
-// Start overrides Base.Start to spawn the background metrics collector.
+// Start overrides Base.Start to spawn the background metrics collector and,
+// when enabled, the node collector. Nodes are sampled on their own goroutine
+// so slow or unreachable nodes cannot delay the local metrics.
 func (s *System) Start() error {
 	ctx, cancel := context.WithCancel(context.Background())
 	s.cancel = cancel
 	s.done = make(chan struct{})
 
 	go s.run(ctx)
+	if s.config.CollectNodes {
+		s.nodesDone = make(chan struct{})
+		go s.runNodes(ctx)
+	}
 	s.Base.SetRunning(true)
 	return nil
 }
@@ -31,7 +37,11 @@ func (s *System) Stop() error {
 		if s.cancel != nil {
 			s.cancel()
 			<-s.done
+			if s.nodesDone != nil {
+				<-s.nodesDone
+			}
 		}
+		s.closeRemote()
 		s.Base.SetRunning(false)
 	})
 	return nil
@@ -58,6 +68,40 @@ func (s *System) run(ctx context.Context) {
 	}
 }
 
+// runNodes samples the managed nodes every CollectInterval. A round that
+// takes longer than the interval delays the next one rather than overlapping
+// it.
+func (s *System) runNodes(ctx context.Context) {
+	defer close(s.nodesDone)
+
+	ticker := time.NewTicker(s.config.CollectInterval)
+	defer ticker.Stop()
+
+	s.collectNodeMetrics(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.collectNodeMetrics(ctx)
+		}
+	}
+}
+
+// collectNodeMetrics samples every managed node and stores the samples.
+func (s *System) collectNodeMetrics(ctx context.Context) {
+	st := s.Store()
+	if st == (store.Client{}) {
+		return
+	}
+	samples := s.collectNodes(ctx, st, time.Now())
+	if len(samples) > 0 {
+		if err := st.AppendSamples(ctx, samples); err != nil {
+			s.Base.Log().Error("failed to store node metrics samples", "error", err, "count", len(samples))
+		}
+	}
+}
+
 func (s *System) collect(ctx context.Context) {
 	st := s.Store()
 	if st == (store.Client{}) {
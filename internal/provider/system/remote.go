@@ -0,0 +1,309 @@
+package system
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	internalssh "github.com/bengrewell/aether-webui/internal/ssh"
+	"github.com/bengrewell/aether-webui/internal/store"
+)
+
+const (
+	// remoteConcurrency is how many nodes are sampled at once.
+	remoteConcurrency = 8
+	// remoteTimeout bounds dialing a node and reading its counters.
+	remoteTimeout = 10 * time.Second
+)
+
+// remoteCommand prints the /proc files the remote collector parses, and df
+// for filesystem usage, each after a "==> name" marker line.
+const remoteCommand = `for f in stat meminfo loadavg net/dev diskstats; do echo "==> $f"; cat /proc/$f; done; ` +
+	`echo "==> df"; df -P -B1 -x tmpfs -x devtmpfs -x overlay -x squashfs 2>/dev/null; true`
+
+// Runner abstracts command execution on a managed node, enabling mock
+// testing of the remote collector.
+type Runner interface {
+	Run(ctx context.Context, cmd string) (stdout, stderr []byte, exitCode int, err error)
+	Close() error
+}
+
+// remoteNode is the collector's state for one managed node.
+type remoteNode struct {
+	runner    Runner
+	updatedAt time.Time           // node.UpdatedAt when runner was dialed
+	cpu       map[string]cpuTimes // last /proc/stat counters by cpu label
+}
+
+// cpuTimes are the busy and total jiffies of one /proc/stat cpu line.
+type cpuTimes struct {
+	busy, total uint64
+}
+
+// dialNode opens an SSH connection to a managed node, through the jump
+// hosts it is reached by.
+func (s *System) dialNode(ctx context.Context, node store.Node) (Runner, error) {
+	cfg, err := internalssh.NodeConfig(ctx, s.Store(), node, remoteTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return internalssh.Dial(ctx, cfg)
+}
+
+// collectNodes samples every managed node of every workspace and returns
+// the samples labeled with the node. Failures are logged and skip the node.
+func (s *System) collectNodes(ctx context.Context, st store.Client, now time.Time) []store.Sample {
+	var (
+		mu      sync.Mutex
+		samples []store.Sample
+		live    = make(map[string]bool)
+	)
+	err := s.ForEachNode(ctx, remoteConcurrency, func(ctx context.Context, ws string, info store.NodeInfo) {
+		label := nodeLabel(ws, info.Name)
+		mu.Lock()
+		live[info.ID] = true
+		mu.Unlock()
+		out, err := s.sampleNode(ctx, st, info.ID, label, now)
+		if err != nil && ctx.Err() == nil {
+			s.Base.Log().Warn("failed to collect node metrics", "node", label, "error", err)
+			return
+		}
+		mu.Lock()
+		samples = append(samples, out...)
+		mu.Unlock()
+	})
+	if err != nil {
+		s.Base.Log().Error("failed to list nodes for node metrics", "error", err)
+	} else if ctx.Err() == nil {
+		// Only a complete listing tells which nodes are gone.
+		s.pruneRemote(live)
+	}
+	return samples
+}
+
+// nodeLabel is the node label value of a node: its name, prefixed with its
+// workspace outside the default one.
+func nodeLabel(workspace, name string) string {
+	if workspace == store.DefaultWorkspace {
+		return name
+	}
+	return workspace + "/" + name
+}
+
+// sampleNode reads /proc on one node over its cached connection, dialing a
+// new one when there is none or the node was edited since.
+func (s *System) sampleNode(ctx context.Context, st store.Client, id, label string, now time.Time) ([]store.Sample, error) {
+	node, ok, err := st.GetNode(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("no node with id %s", id)
+	}
+
+	s.remoteMu.Lock()
+	rn := s.remote[id]
+	if rn == nil {
+		rn = &remoteNode{cpu: map[string]cpuTimes{}}
+		s.remote[id] = rn
+	}
+	s.remoteMu.Unlock()
+
+	// Each node is sampled by one goroutine at a time, so rn needs no lock.
+	if rn.runner != nil && !rn.updatedAt.Equal(node.UpdatedAt) {
+		rn.runner.Close()
+		rn.runner = nil
+	}
+	if rn.runner == nil {
+		dialCtx, cancel := context.WithTimeout(ctx, remoteTimeout)
+		r, err := s.dial(dialCtx, node)
+		cancel()
+		if err != nil {
+			return nil, err
+		}
+		rn.runner, rn.updatedAt = r, node.UpdatedAt
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, remoteTimeout)
+	defer cancel()
+	stdout, _, _, err := rn.runner.Run(runCtx, remoteCommand)
+	if err != nil {
+		// The connection may have dropped; redial on the next tick.
+		rn.runner.Close()
+		rn.runner = nil
+		return nil, err
+	}
+	return parseRemote(stdout, label, now, rn.cpu), nil
+}
+
+// pruneRemote closes the connections of nodes no longer managed.
+func (s *System) pruneRemote(live map[string]bool) {
+	s.remoteMu.Lock()
+	defer s.remoteMu.Unlock()
+	for id, rn := range s.remote {
+		if live[id] {
+			continue
+		}
+		if rn.runner != nil {
+			rn.runner.Close()
+		}
+		delete(s.remote, id)
+	}
+}
+
+// closeRemote closes every cached node connection.
+func (s *System) closeRemote() {
+	s.remoteMu.Lock()
+	defer s.remoteMu.Unlock()
+	for id, rn := range s.remote {
+		if rn.runner != nil {
+			rn.runner.Close()
+		}
+		delete(s.remote, id)
+	}
+}
+
+// parseRemote turns the output of remoteCommand into samples. prev holds the
+// node's last CPU counters and is updated; CPU usage needs two readings, so
+// the first call reports none.
+func parseRemote(out []byte, node string, now time.Time, prev map[string]cpuTimes) []store.Sample {
+	var samples []store.Sample
+	add := func(metric string, value float64, unit string, labels map[string]string) {
+		l := map[string]string{"node": node}
+		for k, v := range labels {
+			l[k] = v
+		}
+		samples = append(samples, store.Sample{Metric: metric, TS: now, Value: value, Labels: l, Unit: unit})
+	}
+
+	for name, lines := range remoteSections(out) {
+		switch name {
+		case "stat":
+			for _, line := range lines {
+				fields := strings.Fields(line)
+				if len(fields) < 5 || !strings.HasPrefix(fields[0], "cpu") {
+					continue
+				}
+				cpu := strings.TrimPrefix(fields[0], "cpu")
+				if cpu == "" {
+					cpu = "total"
+				}
+				var cur cpuTimes
+				for i, f := range fields[1:min(len(fields), 9)] {
+					v, _ := strconv.ParseUint(f, 10, 64)
+					cur.total += v
+					if i != 3 && i != 4 { // idle, iowait
+						cur.busy += v
+					}
+				}
+				if p, ok := prev[cpu]; ok && cur.total > p.total && cur.busy >= p.busy {
+					add("system.cpu.usage_percent", 100*float64(cur.busy-p.busy)/float64(cur.total-p.total), "percent",
+						map[string]string{"cpu": cpu})
+				}
+				prev[cpu] = cur
+			}
+		case "meminfo":
+			mem := map[string]float64{}
+			for _, line := range lines {
+				key, rest, ok := strings.Cut(line, ":")
+				if !ok {
+					continue
+				}
+				fields := strings.Fields(rest)
+				if len(fields) == 0 {
+					continue
+				}
+				v, _ := strconv.ParseFloat(fields[0], 64)
+				mem[key] = v * 1024 // kB
+			}
+			if total := mem["MemTotal"]; total > 0 {
+				used := total - mem["MemAvailable"]
+				add("system.memory.used_bytes", used, "bytes", nil)
+				add("system.memory.available_bytes", mem["MemAvailable"], "bytes", nil)
+				add("system.memory.usage_percent", 100*used/total, "percent", nil)
+			}
+			if _, ok := mem["SwapTotal"]; ok {
+				add("system.swap.used_bytes", mem["SwapTotal"]-mem["SwapFree"], "bytes", nil)
+			}
+		case "loadavg":
+			if len(lines) == 0 {
+				continue
+			}
+			fields := strings.Fields(lines[0])
+			for i, metric := range []string{"system.load.1m", "system.load.5m", "system.load.15m"} {
+				if i < len(fields) {
+					v, _ := strconv.ParseFloat(fields[i], 64)
+					add(metric, v, "", nil)
+				}
+			}
+		case "net/dev":
+			for _, line := range lines {
+				iface, rest, ok := strings.Cut(line, ":")
+				if !ok {
+					continue
+				}
+				fields := strings.Fields(rest)
+				if len(fields) < 9 {
+					continue
+				}
+				recv, _ := strconv.ParseFloat(fields[0], 64)
+				sent, _ := strconv.ParseFloat(fields[8], 64)
+				labels := map[string]string{"interface": strings.TrimSpace(iface)}
+				add("system.net.bytes_sent", sent, "bytes", labels)
+				add("system.net.bytes_recv", recv, "bytes", labels)
+			}
+		case "diskstats":
+			for _, line := range lines {
+				fields := strings.Fields(line)
+				if len(fields) < 10 {
+					continue
+				}
+				read, _ := strconv.ParseFloat(fields[5], 64)
+				written, _ := strconv.ParseFloat(fields[9], 64)
+				labels := map[string]string{"device": fields[2]}
+				add("system.disk.read_bytes", read*512, "bytes", labels)
+				add("system.disk.write_bytes", written*512, "bytes", labels)
+			}
+		case "df":
+			for _, line := range lines {
+				fields := strings.Fields(line)
+				if len(fields) < 6 || fields[0] == "Filesystem" {
+					continue
+				}
+				used, err1 := strconv.ParseFloat(fields[2], 64)
+				avail, err2 := strconv.ParseFloat(fields[3], 64)
+				if err1 != nil || err2 != nil {
+					continue
+				}
+				labels := map[string]string{"device": fields[0], "mount": strings.Join(fields[5:], " ")}
+				add("system.disk.used_bytes", used, "bytes", labels)
+				if used+avail > 0 {
+					add("system.disk.usage_percent", 100*used/(used+avail), "percent", labels)
+				}
+			}
+		}
+	}
+	return samples
+}
+
+// remoteSections splits remoteCommand output into lines by section name.
+func remoteSections(out []byte) map[string][]string {
+	sections := map[string][]string{}
+	var cur string
+	sc := bufio.NewScanner(strings.NewReader(string(out)))
+	for sc.Scan() {
+		line := sc.Text()
+		if name, ok := strings.CutPrefix(line, "==> "); ok {
+			cur = name
+			continue
+		}
+		if cur != "" {
+			sections[cur] = append(sections[cur], line)
+		}
+	}
+	return sections
+}
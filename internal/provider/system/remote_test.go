@@ -0,0 +1,243 @@
+package system
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bengrewell/aether-webui/internal/provider"
+	"github.com/bengrewell/aether-webui/internal/store"
+)
+
+const remoteTemplate = `==> stat
+cpu  BUSY 0 BUSY 800 0 0 0 0 0 0
+cpu0 100 0 100 400 0 0 0 0 0 0
+intr 12345
+==> meminfo
+MemTotal:        4000 kB
+MemFree:         1000 kB
+MemAvailable:    3000 kB
+SwapTotal:       2000 kB
+SwapFree:        1500 kB
+==> loadavg
+0.50 0.25 0.10 1/200 4242
+==> net/dev
+Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+  eth0: 1000      10    0    0    0     0          0         0     2000      20    0    0    0     0       0          0
+==> diskstats
+   8       0 sda 100 0 10 0 50 0 20 0 0 0 0
+==> df
+Filesystem     1-blocks      Used Available Capacity Mounted on
+/dev/sda1          1000       250       750      25% /
+`
+
+// remoteOutput returns remoteCommand output whose total cpu line has the
+// given user and system jiffies.
+func remoteOutput(busy int) []byte {
+	return []byte(strings.ReplaceAll(remoteTemplate, "BUSY", strconv.Itoa(busy)))
+}
+
+// sampleValue returns the value of the sample with the given metric and
+// labels, failing the test if there is none.
+func sampleValue(t *testing.T, samples []store.Sample, metric string, labels map[string]string) float64 {
+	t.Helper()
+	for _, s := range samples {
+		if s.Metric != metric || len(s.Labels) != len(labels) {
+			continue
+		}
+		match := true
+		for k, v := range labels {
+			if s.Labels[k] != v {
+				match = false
+			}
+		}
+		if match {
+			return s.Value
+		}
+	}
+	t.Fatalf("no sample %s %v", metric, labels)
+	return 0
+}
+
+func TestParseRemote(t *testing.T) {
+	prev := map[string]cpuTimes{}
+	now := time.Now()
+
+	first := parseRemote(remoteOutput(100), "n1", now, prev)
+	for _, s := range first {
+		if s.Metric == "system.cpu.usage_percent" {
+			t.Fatalf("first reading reported cpu usage: %+v", s)
+		}
+	}
+
+	// 200 more busy jiffies and no more idle ones: 100% busy.
+	samples := parseRemote(remoteOutput(200), "n1", now, prev)
+
+	tests := []struct {
+		metric string
+		labels map[string]string
+		want   float64
+	}{
+		{"system.cpu.usage_percent", map[string]string{"node": "n1", "cpu": "total"}, 100},
+		{"system.memory.used_bytes", map[string]string{"node": "n1"}, 1000 * 1024},
+		{"system.memory.available_bytes", map[string]string{"node": "n1"}, 3000 * 1024},
+		{"system.memory.usage_percent", map[string]string{"node": "n1"}, 25},
+		{"system.swap.used_bytes", map[string]string{"node": "n1"}, 500 * 1024},
+		{"system.load.1m", map[string]string{"node": "n1"}, 0.5},
+		{"system.load.15m", map[string]string{"node": "n1"}, 0.1},
+		{"system.net.bytes_recv", map[string]string{"node": "n1", "interface": "eth0"}, 1000},
+		{"system.net.bytes_sent", map[string]string{"node": "n1", "interface": "eth0"}, 2000},
+		{"system.disk.read_bytes", map[string]string{"node": "n1", "device": "sda"}, 10 * 512},
+		{"system.disk.write_bytes", map[string]string{"node": "n1", "device": "sda"}, 20 * 512},
+		{"system.disk.used_bytes", map[string]string{"node": "n1", "device": "/dev/sda1", "mount": "/"}, 250},
+		{"system.disk.usage_percent", map[string]string{"node": "n1", "device": "/dev/sda1", "mount": "/"}, 25},
+	}
+	for _, tt := range tests {
+		if got := sampleValue(t, samples, tt.metric, tt.labels); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s %v = %v, want %v", tt.metric, tt.labels, got, tt.want)
+		}
+	}
+}
+
+func TestNodeLabel(t *testing.T) {
+	if got := nodeLabel(store.DefaultWorkspace, "n1"); got != "n1" {
+		t.Errorf("default workspace label = %q", got)
+	}
+	if got := nodeLabel("lab", "n1"); got != "lab/n1" {
+		t.Errorf("named workspace label = %q", got)
+	}
+}
+
+type fakeRunner struct {
+	busy   int
+	fail   bool
+	closed bool
+}
+
+func (f *fakeRunner) Run(context.Context, string) ([]byte, []byte, int, error) {
+	if f.fail {
+		return nil, nil, 0, errors.New("connection lost")
+	}
+	f.busy += 100
+	return remoteOutput(f.busy), nil, 0, nil
+}
+
+func (f *fakeRunner) Close() error {
+	f.closed = true
+	return nil
+}
+
+func TestCollectNodes(t *testing.T) {
+	ctx := t.Context()
+	st, err := store.New(ctx, t.TempDir()+"/test.db")
+	if err != nil {
+		t.Fatalf("store.New: %v", err)
+	}
+	defer st.Close()
+	if err := st.UpsertNode(ctx, store.Node{ID: "n1", Name: "node1", AnsibleHost: "10.0.0.1"}); err != nil {
+		t.Fatalf("UpsertNode: %v", err)
+	}
+
+	p := NewProvider(Config{CollectInterval: 10 * time.Second, CollectNodes: true}, provider.WithStore(st))
+	var dials int
+	runner := &fakeRunner{}
+	p.dial = func(context.Context, store.Node) (Runner, error) {
+		dials++
+		return runner, nil
+	}
+
+	// Local collection leaves the nodes to their own collector.
+	p.collect(ctx)
+	if dials != 0 {
+		t.Errorf("dials = %d after local collection, want none", dials)
+	}
+
+	// The first tick primes the CPU counters; the second reports usage.
+	p.collectNodeMetrics(ctx)
+	p.collectNodeMetrics(ctx)
+	if dials != 1 {
+		t.Errorf("dials = %d, want the connection reused", dials)
+	}
+
+	series, err := st.QueryRange(ctx, store.RangeQuery{
+		Metric:      "system.cpu.usage_percent",
+		Range:       store.TimeRange{From: time.Now().Add(-time.Minute), To: time.Now().Add(time.Minute)},
+		LabelsExact: map[string]string{"node": "node1", "cpu": "total"},
+	})
+	if err != nil {
+		t.Fatalf("QueryRange: %v", err)
+	}
+	if len(series) == 0 || len(series[0].Points) == 0 {
+		t.Fatal("expected node cpu metrics, got none")
+	}
+
+	// A failed read drops the connection so the next tick redials.
+	runner.fail = true
+	p.collectNodeMetrics(ctx)
+	if !runner.closed {
+		t.Error("failed runner was not closed")
+	}
+	runner.fail, runner.closed = false, false
+	p.collectNodeMetrics(ctx)
+	if dials != 2 {
+		t.Errorf("dials = %d, want a redial after failure", dials)
+	}
+
+	// Removed nodes have their connection closed.
+	if err := st.DeleteNode(ctx, "n1"); err != nil {
+		t.Fatalf("DeleteNode: %v", err)
+	}
+	p.collectNodeMetrics(ctx)
+	if !runner.closed || len(p.remote) != 0 {
+		t.Errorf("closed = %v, remote = %v", runner.closed, p.remote)
+	}
+}
+
+func TestCollectNodes_UnfilteredQueryIsLocal(t *testing.T) {
+	ctx := t.Context()
+	st, err := store.New(ctx, t.TempDir()+"/test.db")
+	if err != nil {
+		t.Fatalf("store.New: %v", err)
+	}
+	defer st.Close()
+	if err := st.UpsertNode(ctx, store.Node{ID: "n1", Name: "node1", AnsibleHost: "10.0.0.1"}); err != nil {
+		t.Fatalf("UpsertNode: %v", err)
+	}
+
+	now := time.Now()
+	if err := st.AppendSamples(ctx, []store.Sample{
+		{Metric: "system.memory.used_bytes", TS: now, Value: 42, Unit: "bytes"},
+	}); err != nil {
+		t.Fatalf("AppendSamples: %v", err)
+	}
+	p := NewProvider(Config{CollectInterval: 10 * time.Second, CollectNodes: true}, provider.WithStore(st))
+	p.dial = func(context.Context, store.Node) (Runner, error) { return &fakeRunner{}, nil }
+	p.collectNodeMetrics(ctx)
+
+	in := &MetricsQueryInput{
+		Metric: "system.memory.used_bytes",
+		From:   now.Add(-time.Minute).Format(time.RFC3339),
+		To:     now.Add(time.Minute).Format(time.RFC3339),
+	}
+	out, err := p.HandleMetricsQuery(ctx, in)
+	if err != nil {
+		t.Fatalf("HandleMetricsQuery: %v", err)
+	}
+	if pts := out.Body.Series[0].Points; len(pts) != 1 || pts[0].Value != 42 {
+		t.Errorf("unfiltered points = %+v, want the local host's 42 only", pts)
+	}
+
+	in.Labels = "node=node1"
+	out, err = p.HandleMetricsQuery(ctx, in)
+	if err != nil {
+		t.Fatalf("HandleMetricsQuery: %v", err)
+	}
+	if pts := out.Body.Series[0].Points; len(pts) != 1 || pts[0].Value == 42 {
+		t.Errorf("node1 points = %+v", pts)
+	}
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/bengrewell/aether-webui/internal/endpoint"
 	"github.com/bengrewell/aether-webui/internal/provider"
+	"github.com/bengrewell/aether-webui/internal/store"
 )
 
 // Config holds collection parameters for the system provider.
@@ -14,6 +15,10 @@ import (
 // not here; only the collection interval is provider-scoped.
 type Config struct {
 	CollectInterval time.Duration
+	// CollectNodes also samples every managed node over SSH every
+	// CollectInterval, on a goroutine of its own, labeling its samples with
+	// the node.
+	CollectNodes bool
 }
 
 // System is the provider that exposes host system info and metrics endpoints.
@@ -23,7 +28,12 @@ type System struct {
 	endpoints []endpoint.AnyEndpoint
 	cancel    context.CancelFunc
 	done      chan struct{} // closed when collector goroutine exits
+	nodesDone chan struct{} // closed when the node collector exits; nil when disabled
 	stopOnce  sync.Once
+
+	dial     func(context.Context, store.Node) (Runner, error)
+	remoteMu sync.Mutex
+	remote   map[string]*remoteNode // by node ID
 }
 
 var _ provider.Provider = (*System)(nil)
@@ -34,7 +44,9 @@ func NewProvider(cfg Config, opts ...provider.Option) *System {
 		Base:      provider.New("system", opts...),
 		config:    cfg,
 		endpoints: make([]endpoint.AnyEndpoint, 0, 8),
+		remote:    make(map[string]*remoteNode),
 	}
+	s.dial = s.dialNode
 
 	provider.Register(s.Base, endpoint.Endpoint[struct{}, CPUInfoOutput]{
 		Desc: endpoint.Descriptor{
@@ -0,0 +1,81 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"github.com/bengrewell/aether-webui/internal/store"
+)
+
+// ResolveWorkspace normalizes the workspace named in a request and confirms
+// it exists. An empty name selects store.DefaultWorkspace, which always
+// exists and is returned with only its Name set; its directory comes from
+// the provider's own configuration. Unknown workspaces return a 404.
+func (b *Base) ResolveWorkspace(ctx context.Context, name string) (store.Workspace, error) {
+	if name == "" || name == store.DefaultWorkspace {
+		return store.Workspace{Name: store.DefaultWorkspace}, nil
+	}
+	st := b.Store()
+	if st.Path() == "" {
+		return store.Workspace{}, huma.Error404NotFound("workspace not found",
+			fmt.Errorf("no workspace named %s", name))
+	}
+	w, ok, err := st.GetWorkspace(ctx, name)
+	if err != nil {
+		return store.Workspace{}, huma.Error500InternalServerError("failed to load workspace", err)
+	}
+	if !ok {
+		return store.Workspace{}, huma.Error404NotFound("workspace not found",
+			fmt.Errorf("no workspace named %s", name))
+	}
+	return w, nil
+}
+
+// ForEachNode calls fn for every node of every workspace, the default one
+// first, with at most limit calls running at once, and returns when they all
+// have. It stops starting calls once ctx is done. Workspaces whose nodes
+// cannot be listed are skipped and their errors returned; without a store
+// there is nothing to visit.
+func (b *Base) ForEachNode(ctx context.Context, limit int, fn func(ctx context.Context, workspace string, node store.NodeInfo)) error {
+	st := b.Store()
+	if st.Path() == "" {
+		return nil
+	}
+	var errs []error
+	workspaces := []string{store.DefaultWorkspace}
+	named, err := st.ListWorkspaces(ctx)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("list workspaces: %w", err))
+	}
+	for _, w := range named {
+		workspaces = append(workspaces, w.Name)
+	}
+
+	sem := make(chan struct{}, max(limit, 1))
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for _, ws := range workspaces {
+		infos, err := st.ListNodes(ctx, ws)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("list nodes of workspace %s: %w", ws, err))
+			continue
+		}
+		for _, info := range infos {
+			select {
+			case <-ctx.Done():
+				return errors.Join(errs...)
+			case sem <- struct{}{}:
+			}
+			wg.Add(1)
+			go func() {
+				defer func() { <-sem; wg.Done() }()
+				fn(ctx, ws, info)
+			}()
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,75 @@
+package provider
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bengrewell/aether-webui/internal/store"
+)
+
+func TestForEachNode(t *testing.T) {
+	ctx := t.Context()
+	st, err := store.New(ctx, t.TempDir()+"/test.db")
+	if err != nil {
+		t.Fatalf("store.New: %v", err)
+	}
+	defer st.Close()
+	if err := st.CreateWorkspace(ctx, store.Workspace{Name: "lab", OnRampDir: t.TempDir()}); err != nil {
+		t.Fatalf("CreateWorkspace: %v", err)
+	}
+	for _, n := range []store.Node{
+		{ID: "n1", Name: "node1", AnsibleHost: "10.0.0.1"},
+		{ID: "n2", Name: "node2", AnsibleHost: "10.0.0.2"},
+		{ID: "n3", Name: "node3", AnsibleHost: "10.0.0.3", Workspace: "lab"},
+	} {
+		if err := st.UpsertNode(ctx, n); err != nil {
+			t.Fatalf("UpsertNode: %v", err)
+		}
+	}
+
+	b := New("test", WithStore(st))
+	var (
+		mu            sync.Mutex
+		seen          = map[string]string{}
+		running, peak atomic.Int32
+	)
+	err = b.ForEachNode(ctx, 2, func(_ context.Context, ws string, info store.NodeInfo) {
+		cur := running.Add(1)
+		defer running.Add(-1)
+		for {
+			p := peak.Load()
+			if cur <= p || peak.CompareAndSwap(p, cur) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		seen[info.Name] = ws
+		mu.Unlock()
+	})
+	if err != nil {
+		t.Fatalf("ForEachNode: %v", err)
+	}
+	want := map[string]string{"node1": store.DefaultWorkspace, "node2": store.DefaultWorkspace, "node3": "lab"}
+	if len(seen) != len(want) {
+		t.Fatalf("visited %v, want %v", seen, want)
+	}
+	for name, ws := range want {
+		if seen[name] != ws {
+			t.Errorf("%s visited in %q, want %q", name, seen[name], ws)
+		}
+	}
+	if p := peak.Load(); p > 2 {
+		t.Errorf("%d calls ran at once, want at most 2", p)
+	}
+
+	// Without a store there is nothing to visit.
+	if err := New("test").ForEachNode(ctx, 2, func(context.Context, string, store.NodeInfo) {
+		t.Error("called without a store")
+	}); err != nil {
+		t.Errorf("ForEachNode without store: %v", err)
+	}
+}
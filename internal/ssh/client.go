@@ -17,16 +17,70 @@ type Config struct {
 	Password string        // optional
 	Key      []byte        // optional PEM-encoded private key
 	Timeout  time.Duration // dial timeout; defaults to 10s
+
+	// Jumps are the jump hosts the connection goes through, in order: the
+	// first is dialed directly and each later one through the one before.
+	// Their own Jumps are ignored.
+	Jumps []Config
 }
 
 // Client wraps an SSH connection and provides a simple Run interface.
 type Client struct {
 	conn *ssh.Client
+	hops []*ssh.Client // jump host connections, closed with conn
 }
 
-// Dial establishes an SSH connection using the provided config.
-// Authentication methods are tried in order: key, then password.
+// Dial establishes an SSH connection using the provided config, through its
+// jump hosts if it has any. Authentication methods are tried in order: key,
+// then password.
 func Dial(ctx context.Context, cfg Config) (*Client, error) {
+	hops, err := dialHops(ctx, cfg.Jumps)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := connect(ctx, lastHop(hops), cfg)
+	if err != nil {
+		closeHops(hops)
+		return nil, err
+	}
+	return &Client{conn: conn, hops: hops}, nil
+}
+
+// DialTCP opens a TCP connection to addr through the given jump hosts, or
+// directly without any. Closing the connection also closes the jump hosts'.
+func DialTCP(ctx context.Context, jumps []Config, addr string, timeout time.Duration) (net.Conn, error) {
+	hops, err := dialHops(ctx, jumps)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := dialVia(ctx, lastHop(hops), addr, timeout)
+	if err != nil {
+		closeHops(hops)
+		return nil, err
+	}
+	if len(hops) == 0 {
+		return conn, nil
+	}
+	return &hopConn{Conn: conn, hops: hops}, nil
+}
+
+// dialHops connects to each jump host through the ones before it.
+func dialHops(ctx context.Context, jumps []Config) ([]*ssh.Client, error) {
+	var hops []*ssh.Client
+	for _, j := range jumps {
+		c, err := connect(ctx, lastHop(hops), j)
+		if err != nil {
+			closeHops(hops)
+			return nil, fmt.Errorf("ssh: jump host %s: %w", j.Host, err)
+		}
+		hops = append(hops, c)
+	}
+	return hops, nil
+}
+
+// connect dials cfg.Host directly, or through via when it is not nil, and
+// performs the SSH handshake.
+func connect(ctx context.Context, via *ssh.Client, cfg Config) (*ssh.Client, error) {
 	timeout := cfg.Timeout
 	if timeout == 0 {
 		timeout = 10 * time.Second
@@ -54,16 +108,10 @@ func Dial(ctx context.Context, cfg Config) (*Client, error) {
 		Timeout:         timeout,
 	}
 
-	host := cfg.Host
-	if _, _, err := net.SplitHostPort(host); err != nil {
-		host = net.JoinHostPort(host, "22")
-	}
-
-	// Use a dialer that respects context cancellation.
-	var d net.Dialer
-	conn, err := d.DialContext(ctx, "tcp", host)
+	host := hostPort(cfg.Host)
+	conn, err := dialVia(ctx, via, host, timeout)
 	if err != nil {
-		return nil, fmt.Errorf("ssh: dial %s: %w", host, err)
+		return nil, err
 	}
 
 	sshConn, chans, reqs, err := ssh.NewClientConn(conn, host, sshCfg)
@@ -71,13 +119,76 @@ func Dial(ctx context.Context, cfg Config) (*Client, error) {
 		conn.Close()
 		return nil, fmt.Errorf("ssh: handshake %s: %w", host, err)
 	}
+	return ssh.NewClient(sshConn, chans, reqs), nil
+}
+
+// dialVia opens a TCP connection to addr, through via when it is not nil.
+// Both respect context cancellation.
+func dialVia(ctx context.Context, via *ssh.Client, addr string, timeout time.Duration) (net.Conn, error) {
+	if via == nil {
+		d := net.Dialer{Timeout: timeout}
+		conn, err := d.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return nil, fmt.Errorf("ssh: dial %s: %w", addr, err)
+		}
+		return conn, nil
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	conn, err := via.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("ssh: dial %s via %s: %w", addr, via.RemoteAddr(), err)
+	}
+	return conn, nil
+}
 
-	return &Client{conn: ssh.NewClient(sshConn, chans, reqs)}, nil
+// hostPort appends the default SSH port to a bare host.
+func hostPort(host string) string {
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		return net.JoinHostPort(host, "22")
+	}
+	return host
+}
+
+func lastHop(hops []*ssh.Client) *ssh.Client {
+	if len(hops) == 0 {
+		return nil
+	}
+	return hops[len(hops)-1]
+}
+
+// closeHops closes jump host connections, the furthest first.
+func closeHops(hops []*ssh.Client) {
+	for i := len(hops) - 1; i >= 0; i-- {
+		hops[i].Close()
+	}
+}
+
+// hopConn is a connection made through jump hosts.
+type hopConn struct {
+	net.Conn
+	hops []*ssh.Client
+}
+
+func (c *hopConn) Close() error {
+	err := c.Conn.Close()
+	closeHops(c.hops)
+	return err
 }
 
 // Run executes a command on the remote host and returns its stdout, stderr,
 // exit code, and any error. The context controls the session lifetime.
 func (c *Client) Run(ctx context.Context, cmd string) (stdout, stderr []byte, exitCode int, err error) {
+	return c.RunInput(ctx, cmd, nil)
+}
+
+// RunInput is Run with stdin fed to the command. Secrets such as a sudo
+// password belong here rather than in cmd, which the remote shell's process
+// listing exposes.
+func (c *Client) RunInput(ctx context.Context, cmd string, stdin []byte) (stdout, stderr []byte, exitCode int, err error) {
 	sess, err := c.conn.NewSession()
 	if err != nil {
 		return nil, nil, -1, fmt.Errorf("ssh: new session: %w", err)
@@ -87,6 +198,9 @@ func (c *Client) Run(ctx context.Context, cmd string) (stdout, stderr []byte, ex
 	var outBuf, errBuf bytes.Buffer
 	sess.Stdout = &outBuf
 	sess.Stderr = &errBuf
+	if stdin != nil {
+		sess.Stdin = bytes.NewReader(stdin)
+	}
 
 	// Cancel the session when the context is done.
 	done := make(chan error, 1)
@@ -109,7 +223,9 @@ func (c *Client) Run(ctx context.Context, cmd string) (stdout, stderr []byte, ex
 	}
 }
 
-// Close terminates the SSH connection.
+// Close terminates the SSH connection and those to its jump hosts.
 func (c *Client) Close() error {
-	return c.conn.Close()
+	err := c.conn.Close()
+	closeHops(c.hops)
+	return err
 }
@@ -0,0 +1,214 @@
+package ssh
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"io"
+	"net"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/bengrewell/aether-webui/internal/store"
+)
+
+// startJumpServer runs an SSH server that accepts the password "secret" and
+// forwards direct-tcpip channels, as a jump host does.
+func startJumpServer(t *testing.T) string {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &ssh.ServerConfig{
+		PasswordCallback: func(_ ssh.ConnMetadata, pass []byte) (*ssh.Permissions, error) {
+			if string(pass) != "secret" {
+				return nil, io.EOF
+			}
+			return nil, nil
+		},
+	}
+	cfg.AddHostKey(signer)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serveJump(conn, cfg)
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func serveJump(conn net.Conn, cfg *ssh.ServerConfig) {
+	_, chans, reqs, err := ssh.NewServerConn(conn, cfg)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	for nc := range chans {
+		if nc.ChannelType() != "direct-tcpip" {
+			nc.Reject(ssh.UnknownChannelType, "only direct-tcpip")
+			continue
+		}
+		var target struct {
+			Host       string
+			Port       uint32
+			OriginHost string
+			OriginPort uint32
+		}
+		if err := ssh.Unmarshal(nc.ExtraData(), &target); err != nil {
+			nc.Reject(ssh.ConnectionFailed, err.Error())
+			continue
+		}
+		out, err := net.Dial("tcp", net.JoinHostPort(target.Host, strconv.FormatUint(uint64(target.Port), 10)))
+		if err != nil {
+			nc.Reject(ssh.ConnectionFailed, err.Error())
+			continue
+		}
+		ch, chReqs, err := nc.Accept()
+		if err != nil {
+			out.Close()
+			continue
+		}
+		go ssh.DiscardRequests(chReqs)
+		go func() {
+			defer ch.Close()
+			defer out.Close()
+			go io.Copy(out, ch)
+			io.Copy(ch, out)
+		}()
+	}
+}
+
+func TestDialThroughJumpHosts(t *testing.T) {
+	ctx := t.Context()
+	jump := Config{Host: startJumpServer(t), User: "jump", Password: "secret", Timeout: 5 * time.Second}
+
+	// A plain TCP echo service behind the jump hosts.
+	echo, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer echo.Close()
+	go func() {
+		for {
+			c, err := echo.Accept()
+			if err != nil {
+				return
+			}
+			go func() { defer c.Close(); io.Copy(c, c) }()
+		}
+	}()
+
+	// Two hops: the second jump host is reached through the first.
+	conn, err := DialTCP(ctx, []Config{jump, jump}, echo.Addr().String(), 5*time.Second)
+	if err != nil {
+		t.Fatalf("DialTCP: %v", err)
+	}
+	if _, err := conn.Write([]byte("ping")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(conn, buf); err != nil || string(buf) != "ping" {
+		t.Fatalf("read %q, %v", buf, err)
+	}
+	if err := conn.Close(); err != nil {
+		t.Errorf("Close: %v", err)
+	}
+
+	// An SSH login through a jump host; the target is the jump server itself.
+	client, err := Dial(ctx, Config{Host: jump.Host, User: "node", Password: "secret", Jumps: []Config{jump}})
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	if len(client.hops) != 1 {
+		t.Errorf("hops = %d, want 1", len(client.hops))
+	}
+	client.Close()
+
+	// A failing jump host is named in the error.
+	bad := jump
+	bad.Password = "wrong"
+	if _, err := Dial(ctx, Config{Host: jump.Host, User: "node", Password: "secret", Jumps: []Config{bad}}); err == nil {
+		t.Error("expected an error for a jump host that rejects the login")
+	}
+}
+
+func TestJumpChain(t *testing.T) {
+	ctx := t.Context()
+	st, err := store.New(ctx, filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("store.New: %v", err)
+	}
+	defer st.Close()
+
+	for _, j := range []store.JumpHost{
+		{Name: "gw", Host: "203.0.113.10", User: "jump", Password: []byte("p")},
+		{Name: "lab", Host: "10.1.0.1", Port: 2222, User: "lab", Password: []byte("p"), Via: "gw", Default: true},
+		{Name: "loop-a", Host: "10.9.0.1", Via: "loop-b"},
+		{Name: "loop-b", Host: "10.9.0.2", Via: "loop-a"},
+		{Name: "orphan", Host: "10.9.0.3", Via: "missing"},
+	} {
+		if err := st.UpsertJumpHost(ctx, j); err != nil {
+			t.Fatalf("UpsertJumpHost(%s): %v", j.Name, err)
+		}
+	}
+
+	names := func(chain []store.JumpHost) []string {
+		var out []string
+		for _, j := range chain {
+			out = append(out, j.Name)
+		}
+		return out
+	}
+	tests := []struct {
+		jumpHost string
+		want     []string
+		wantErr  bool
+	}{
+		{"", []string{"gw", "lab"}, false}, // the workspace default
+		{"gw", []string{"gw"}, false},
+		{store.JumpHostNone, nil, false},
+		{"missing", nil, true},
+		{"loop-a", nil, true},
+		{"orphan", nil, true},
+	}
+	for _, tt := range tests {
+		chain, err := JumpChain(ctx, st, "", tt.jumpHost)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("JumpChain(%q) error = %v", tt.jumpHost, err)
+			continue
+		}
+		if got := names(chain); len(got) != len(tt.want) || (len(got) > 0 && got[0] != tt.want[0]) {
+			t.Errorf("JumpChain(%q) = %v, want %v", tt.jumpHost, got, tt.want)
+		}
+	}
+
+	// Another workspace without jump hosts connects directly.
+	if chain, err := JumpChain(ctx, st, "lab2", ""); err != nil || chain != nil {
+		t.Errorf("lab2 chain = %v, %v", chain, err)
+	}
+
+	cfg, err := NodeConfig(ctx, st, store.Node{AnsibleHost: "10.1.0.5", AnsibleUser: "ubuntu", Password: []byte("pw")}, time.Second)
+	if err != nil {
+		t.Fatalf("NodeConfig: %v", err)
+	}
+	if cfg.Host != "10.1.0.5:22" || len(cfg.Jumps) != 2 || cfg.Jumps[0].Host != "203.0.113.10:22" || cfg.Jumps[1].Host != "10.1.0.1:2222" {
+		t.Errorf("config = %+v", cfg)
+	}
+}
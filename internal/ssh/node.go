@@ -0,0 +1,91 @@
+package ssh
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/bengrewell/aether-webui/internal/store"
+)
+
+// NodeConfig returns the connection settings of a managed node, through the
+// jump hosts it is reached by.
+func NodeConfig(ctx context.Context, st store.Client, node store.Node, timeout time.Duration) (Config, error) {
+	chain, err := JumpChain(ctx, st, node.Workspace, node.JumpHost)
+	if err != nil {
+		return Config{}, err
+	}
+	cfg := Config{
+		Host:     endpoint(node.AnsibleHost, node.AnsiblePort),
+		User:     node.AnsibleUser,
+		Password: string(node.Password),
+		Key:      node.SSHKey,
+		Timeout:  timeout,
+	}
+	for _, j := range chain {
+		cfg.Jumps = append(cfg.Jumps, JumpConfig(j, timeout))
+	}
+	return cfg, nil
+}
+
+// JumpConfig returns the connection settings of a jump host.
+func JumpConfig(j store.JumpHost, timeout time.Duration) Config {
+	return Config{
+		Host:     endpoint(j.Host, j.Port),
+		User:     j.User,
+		Password: string(j.Password),
+		Key:      j.SSHKey,
+		Timeout:  timeout,
+	}
+}
+
+// JumpChain returns the jump hosts a node of workspace that names jumpHost
+// is reached through, the one dialed first first. An empty name selects the
+// workspace's default jump host, if any, and store.JumpHostNone none.
+func JumpChain(ctx context.Context, st store.Client, workspace, jumpHost string) ([]store.JumpHost, error) {
+	if jumpHost == store.JumpHostNone {
+		return nil, nil
+	}
+	all, err := st.ListJumpHosts(ctx, workspace)
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]store.JumpHost, len(all))
+	for _, j := range all {
+		byName[j.Name] = j
+		if jumpHost == "" && j.Default {
+			jumpHost = j.Name
+		}
+	}
+	if jumpHost == "" {
+		return nil, nil
+	}
+
+	var chain []store.JumpHost
+	seen := make(map[string]bool)
+	for name := jumpHost; name != ""; {
+		if seen[name] {
+			return nil, fmt.Errorf("jump host %q is reached through itself", name)
+		}
+		seen[name] = true
+		j, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("jump host %q not found", name)
+		}
+		chain = append(chain, j)
+		name = j.Via
+	}
+	slices.Reverse(chain)
+	return chain, nil
+}
+
+// endpoint joins a host and an SSH port, 0 meaning 22.
+func endpoint(host string, port int) string {
+	if port == 0 {
+		port = 22
+	}
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
@@ -15,6 +15,7 @@ func (d *db) InsertAction(ctx context.Context, rec ActionRecord) error {
 	if rec.ID == "" || rec.Component == "" || rec.Action == "" || rec.Target == "" {
 		return ErrInvalidArgument
 	}
+	rec.Workspace = workspaceOrDefault(rec.Workspace)
 	if rec.Status == "" {
 		rec.Status = "running"
 	}
@@ -42,9 +43,9 @@ func (d *db) InsertAction(ctx context.Context, rec ActionRecord) error {
 	}
 
 	_, err = d.conn.ExecContext(ctx, `
-		INSERT INTO action_history(id, component, action, target, status, exit_code, error, labels_json, tags_json, started_at, finished_at)
-		VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`, rec.ID, rec.Component, rec.Action, rec.Target, rec.Status, rec.ExitCode,
+		INSERT INTO action_history(id, workspace, component, action, target, status, exit_code, error, labels_json, tags_json, started_at, finished_at)
+		VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, rec.ID, rec.Workspace, rec.Component, rec.Action, rec.Target, rec.Status, rec.ExitCode,
 		nullString(rec.Error), labelsJSON, tagsJSON, startedAt, finishedAt)
 	return err
 }
@@ -84,9 +85,9 @@ func (d *db) GetAction(ctx context.Context, id string) (ActionRecord, bool, erro
 	var finishedAt sql.NullInt64
 
 	err := d.conn.QueryRowContext(ctx, `
-		SELECT id, component, action, target, status, exit_code, error, labels_json, tags_json, started_at, finished_at
+		SELECT id, workspace, component, action, target, status, exit_code, error, labels_json, tags_json, started_at, finished_at
 		FROM action_history WHERE id = ?
-	`, id).Scan(&rec.ID, &rec.Component, &rec.Action, &rec.Target, &rec.Status,
+	`, id).Scan(&rec.ID, &rec.Workspace, &rec.Component, &rec.Action, &rec.Target, &rec.Status,
 		&rec.ExitCode, &errStr, &labelsJSON, &tagsJSON, &startedAt, &finishedAt)
 
 	if err == sql.ErrNoRows {
@@ -115,10 +116,14 @@ func (d *db) GetAction(ctx context.Context, id string) (ActionRecord, bool, erro
 }
 
 func (d *db) ListActions(ctx context.Context, filter ActionFilter) ([]ActionRecord, error) {
-	query := `SELECT id, component, action, target, status, exit_code, error, labels_json, tags_json, started_at, finished_at FROM action_history`
+	query := `SELECT id, workspace, component, action, target, status, exit_code, error, labels_json, tags_json, started_at, finished_at FROM action_history`
 	var conditions []string
 	var args []any
 
+	if filter.Workspace != "" {
+		conditions = append(conditions, "workspace = ?")
+		args = append(args, filter.Workspace)
+	}
 	if filter.Component != "" {
 		conditions = append(conditions, "component = ?")
 		args = append(args, filter.Component)
@@ -167,7 +172,7 @@ func (d *db) ListActions(ctx context.Context, filter ActionFilter) ([]ActionReco
 		var startedAt int64
 		var finishedAt sql.NullInt64
 
-		if err := rows.Scan(&rec.ID, &rec.Component, &rec.Action, &rec.Target, &rec.Status,
+		if err := rows.Scan(&rec.ID, &rec.Workspace, &rec.Component, &rec.Action, &rec.Target, &rec.Status,
 			&rec.ExitCode, &errStr, &labelsJSON, &tagsJSON, &startedAt, &finishedAt); err != nil {
 			return nil, err
 		}
@@ -200,34 +205,36 @@ func (d *db) UpsertComponentState(ctx context.Context, cs ComponentState) error
 	if cs.Component == "" || cs.Status == "" {
 		return ErrInvalidArgument
 	}
+	cs.Workspace = workspaceOrDefault(cs.Workspace)
 	updatedAt := cs.UpdatedAt.Unix()
 	if cs.UpdatedAt.IsZero() {
 		updatedAt = d.now().Unix()
 	}
 	_, err := d.conn.ExecContext(ctx, `
-		INSERT INTO component_state(component, status, last_action, action_id, updated_at)
-		VALUES(?, ?, ?, ?, ?)
-		ON CONFLICT(component) DO UPDATE SET
+		INSERT INTO component_state(workspace, component, status, last_action, action_id, updated_at)
+		VALUES(?, ?, ?, ?, ?, ?)
+		ON CONFLICT(workspace, component) DO UPDATE SET
 			status = excluded.status,
 			last_action = excluded.last_action,
 			action_id = excluded.action_id,
 			updated_at = excluded.updated_at
-	`, cs.Component, cs.Status, nullString(cs.LastAction), nullString(cs.ActionID), updatedAt)
+	`, cs.Workspace, cs.Component, cs.Status, nullString(cs.LastAction), nullString(cs.ActionID), updatedAt)
 	return err
 }
 
-func (d *db) GetComponentState(ctx context.Context, component string) (ComponentState, bool, error) {
+func (d *db) GetComponentState(ctx context.Context, workspace, component string) (ComponentState, bool, error) {
 	if component == "" {
 		return ComponentState{}, false, ErrInvalidArgument
 	}
+	workspace = workspaceOrDefault(workspace)
 	var cs ComponentState
 	var lastAction, actionID sql.NullString
 	var updatedAt int64
 
 	err := d.conn.QueryRowContext(ctx, `
-		SELECT component, status, last_action, action_id, updated_at
-		FROM component_state WHERE component = ?
-	`, component).Scan(&cs.Component, &cs.Status, &lastAction, &actionID, &updatedAt)
+		SELECT workspace, component, status, last_action, action_id, updated_at
+		FROM component_state WHERE workspace = ? AND component = ?
+	`, workspace, component).Scan(&cs.Workspace, &cs.Component, &cs.Status, &lastAction, &actionID, &updatedAt)
 
 	if err == sql.ErrNoRows {
 		return ComponentState{}, false, nil
@@ -242,11 +249,12 @@ func (d *db) GetComponentState(ctx context.Context, component string) (Component
 	return cs, true, nil
 }
 
-func (d *db) ListComponentStates(ctx context.Context) ([]ComponentState, error) {
+func (d *db) ListComponentStates(ctx context.Context, workspace string) ([]ComponentState, error) {
+	workspace = workspaceOrDefault(workspace)
 	rows, err := d.conn.QueryContext(ctx, `
-		SELECT component, status, last_action, action_id, updated_at
-		FROM component_state ORDER BY component
-	`)
+		SELECT workspace, component, status, last_action, action_id, updated_at
+		FROM component_state WHERE workspace = ? ORDER BY component
+	`, workspace)
 	if err != nil {
 		return nil, err
 	}
@@ -258,7 +266,7 @@ func (d *db) ListComponentStates(ctx context.Context) ([]ComponentState, error)
 		var lastAction, actionID sql.NullString
 		var updatedAt int64
 
-		if err := rows.Scan(&cs.Component, &cs.Status, &lastAction, &actionID, &updatedAt); err != nil {
+		if err := rows.Scan(&cs.Workspace, &cs.Component, &cs.Status, &lastAction, &actionID, &updatedAt); err != nil {
 			return nil, err
 		}
 		cs.LastAction = lastAction.String
@@ -385,7 +385,7 @@ func TestUpsertComponentState_RoundTrip(t *testing.T) {
 		t.Fatalf("UpsertComponentState: %v", err)
 	}
 
-	got, ok, err := st.GetComponentState(ctx, "k8s")
+	got, ok, err := st.GetComponentState(ctx, "", "k8s")
 	if err != nil {
 		t.Fatalf("GetComponentState: %v", err)
 	}
@@ -429,7 +429,7 @@ func TestUpsertComponentState_Update(t *testing.T) {
 		t.Fatalf("UpsertComponentState (update): %v", err)
 	}
 
-	got, _, _ := st.GetComponentState(ctx, "k8s")
+	got, _, _ := st.GetComponentState(ctx, "", "k8s")
 	if got.Status != "installed" {
 		t.Errorf("Status = %q, want %q", got.Status, "installed")
 	}
@@ -462,7 +462,7 @@ func TestGetComponentState_NotFound(t *testing.T) {
 	st := newTestStore(t)
 	ctx := t.Context()
 
-	_, ok, err := st.GetComponentState(ctx, "nonexistent")
+	_, ok, err := st.GetComponentState(ctx, "", "nonexistent")
 	if err != nil {
 		t.Fatalf("GetComponentState: %v", err)
 	}
@@ -475,7 +475,7 @@ func TestGetComponentState_EmptyName(t *testing.T) {
 	st := newTestStore(t)
 	ctx := t.Context()
 
-	_, _, err := st.GetComponentState(ctx, "")
+	_, _, err := st.GetComponentState(ctx, "", "")
 	if err != ErrInvalidArgument {
 		t.Errorf("expected ErrInvalidArgument, got %v", err)
 	}
@@ -486,7 +486,7 @@ func TestListComponentStates(t *testing.T) {
 	ctx := t.Context()
 
 	// Start empty.
-	list, err := st.ListComponentStates(ctx)
+	list, err := st.ListComponentStates(ctx, "")
 	if err != nil {
 		t.Fatalf("ListComponentStates: %v", err)
 	}
@@ -505,7 +505,7 @@ func TestListComponentStates(t *testing.T) {
 		}
 	}
 
-	list, err = st.ListComponentStates(ctx)
+	list, err = st.ListComponentStates(ctx, "")
 	if err != nil {
 		t.Fatalf("ListComponentStates: %v", err)
 	}
@@ -105,9 +105,108 @@ func (c Client) DeleteNode(ctx context.Context, id string) error {
 	return c.s.DeleteNode(ctx, id)
 }
 
-// ListNodes returns all nodes without secrets.
-func (c Client) ListNodes(ctx context.Context) ([]NodeInfo, error) {
-	return c.s.ListNodes(ctx)
+// ListNodes returns the nodes in a workspace without secrets. An empty
+// workspace selects DefaultWorkspace.
+func (c Client) ListNodes(ctx context.Context, workspace string) ([]NodeInfo, error) {
+	return c.s.ListNodes(ctx, workspace)
+}
+
+// SetGroupVars replaces the variables of a role's hosts.ini group in a
+// workspace. Empty vars removes them.
+func (c Client) SetGroupVars(ctx context.Context, workspace, role string, vars map[string]string) error {
+	return c.s.SetGroupVars(ctx, workspace, role, vars)
+}
+
+// ListGroupVars returns the group variables of a workspace by role.
+func (c Client) ListGroupVars(ctx context.Context, workspace string) (map[string]map[string]string, error) {
+	return c.s.ListGroupVars(ctx, workspace)
+}
+
+// ApplyNodes deletes, creates and updates nodes of a workspace and sets its
+// group vars in one transaction, so a failure leaves the workspace as it
+// was. An empty workspace selects DefaultWorkspace.
+func (c Client) ApplyNodes(ctx context.Context, workspace string, b NodeBatch) error {
+	return c.s.ApplyNodes(ctx, workspace, b)
+}
+
+// UpsertJumpHost creates or replaces a jump host. Making it the workspace
+// default clears the flag on the workspace's other jump hosts.
+func (c Client) UpsertJumpHost(ctx context.Context, j JumpHost) error {
+	return c.s.UpsertJumpHost(ctx, j)
+}
+
+// GetJumpHost returns a jump host of a workspace with its secrets decrypted.
+func (c Client) GetJumpHost(ctx context.Context, workspace, name string) (JumpHost, bool, error) {
+	return c.s.GetJumpHost(ctx, workspace, name)
+}
+
+// ListJumpHosts returns a workspace's jump hosts ordered by name, with their
+// secrets decrypted.
+func (c Client) ListJumpHosts(ctx context.Context, workspace string) ([]JumpHost, error) {
+	return c.s.ListJumpHosts(ctx, workspace)
+}
+
+// DeleteJumpHost removes a jump host. Nodes and jump hosts that name it are
+// left unchanged.
+func (c Client) DeleteJumpHost(ctx context.Context, workspace, name string) error {
+	return c.s.DeleteJumpHost(ctx, workspace, name)
+}
+
+// RecordNodeHealth saves the result of a node health probe. When the node's
+// status changes, the change is recorded and returned with true.
+func (c Client) RecordNodeHealth(ctx context.Context, h NodeHealth) (NodeHealthEvent, bool, error) {
+	return c.s.RecordNodeHealth(ctx, h)
+}
+
+// GetNodeHealth returns the latest health probe of a node.
+func (c Client) GetNodeHealth(ctx context.Context, nodeID string) (NodeHealth, bool, error) {
+	return c.s.GetNodeHealth(ctx, nodeID)
+}
+
+// ListNodeHealth returns the latest health probes of a workspace's nodes.
+// Nodes never probed are omitted.
+func (c Client) ListNodeHealth(ctx context.Context, workspace string) ([]NodeHealth, error) {
+	return c.s.ListNodeHealth(ctx, workspace)
+}
+
+// ListNodeHealthEvents returns health status changes newest first.
+func (c Client) ListNodeHealthEvents(ctx context.Context, filter NodeHealthEventFilter) ([]NodeHealthEvent, error) {
+	return c.s.ListNodeHealthEvents(ctx, filter)
+}
+
+// AddNodeFacts stores a new version of a node's facts and returns its ID.
+func (c Client) AddNodeFacts(ctx context.Context, v NodeFactsVersion) (int64, error) {
+	return c.s.AddNodeFacts(ctx, v)
+}
+
+// TouchNodeFacts records that a gather at the given time found the facts of
+// version id unchanged.
+func (c Client) TouchNodeFacts(ctx context.Context, id int64, at time.Time) error {
+	return c.s.TouchNodeFacts(ctx, id, at)
+}
+
+// GetNodeFacts returns version id of a node's facts, or the latest version
+// if id is 0.
+func (c Client) GetNodeFacts(ctx context.Context, nodeID string, id int64) (NodeFactsVersion, bool, error) {
+	return c.s.GetNodeFacts(ctx, nodeID, id)
+}
+
+// ListNodeFacts returns a node's facts versions newest first. limit <= 0
+// returns all of them.
+func (c Client) ListNodeFacts(ctx context.Context, nodeID string, limit int) ([]NodeFactsVersion, error) {
+	return c.s.ListNodeFacts(ctx, nodeID, limit)
+}
+
+// UpsertConfigDefault records a value set by the config defaults engine,
+// replacing the previous record for the same workspace and field.
+func (c Client) UpsertConfigDefault(ctx context.Context, d ConfigDefault) error {
+	return c.s.UpsertConfigDefault(ctx, d)
+}
+
+// ListConfigDefaults returns a workspace's recorded config defaults ordered
+// by field.
+func (c Client) ListConfigDefaults(ctx context.Context, workspace string) ([]ConfigDefault, error) {
+	return c.s.ListConfigDefaults(ctx, workspace)
 }
 
 // InsertAction records a new action execution in the action history.
@@ -135,14 +234,14 @@ func (c Client) UpsertComponentState(ctx context.Context, cs ComponentState) err
 	return c.s.UpsertComponentState(ctx, cs)
 }
 
-// GetComponentState retrieves the state of a single component.
-func (c Client) GetComponentState(ctx context.Context, component string) (ComponentState, bool, error) {
-	return c.s.GetComponentState(ctx, component)
+// GetComponentState retrieves the state of a single component in a workspace.
+func (c Client) GetComponentState(ctx context.Context, workspace, component string) (ComponentState, bool, error) {
+	return c.s.GetComponentState(ctx, workspace, component)
 }
 
-// ListComponentStates returns the state of all tracked components.
-func (c Client) ListComponentStates(ctx context.Context) ([]ComponentState, error) {
-	return c.s.ListComponentStates(ctx)
+// ListComponentStates returns the state of all tracked components in a workspace.
+func (c Client) ListComponentStates(ctx context.Context, workspace string) ([]ComponentState, error) {
+	return c.s.ListComponentStates(ctx, workspace)
 }
 
 // InsertDeployment creates a new deployment with its actions in a single transaction.
@@ -165,6 +264,167 @@ func (c Client) ListDeployments(ctx context.Context, filter DeploymentFilter) ([
 	return c.s.ListDeployments(ctx, filter)
 }
 
+// CreateWorkspace registers a named workspace. Returns ErrConflict if the
+// name is taken.
+func (c Client) CreateWorkspace(ctx context.Context, w Workspace) error {
+	return c.s.CreateWorkspace(ctx, w)
+}
+
+// GetWorkspace retrieves a workspace by name.
+func (c Client) GetWorkspace(ctx context.Context, name string) (Workspace, bool, error) {
+	return c.s.GetWorkspace(ctx, name)
+}
+
+// ListWorkspaces returns all stored workspaces. DefaultWorkspace is not included.
+func (c Client) ListWorkspaces(ctx context.Context) ([]Workspace, error) {
+	return c.s.ListWorkspaces(ctx)
+}
+
+// UpdateWorkspaceVersion changes the OnRamp version pinned by a workspace.
+// Returns ErrNotFound if the workspace does not exist.
+func (c Client) UpdateWorkspaceVersion(ctx context.Context, name, version string) error {
+	return c.s.UpdateWorkspaceVersion(ctx, name, version)
+}
+
+// DeleteWorkspace removes a workspace and its component state.
+func (c Client) DeleteWorkspace(ctx context.Context, name string) error {
+	return c.s.DeleteWorkspace(ctx, name)
+}
+
+// InsertConfigRevision records a version of a workspace's vars/main.yml and
+// returns it with its assigned ID and timestamp.
+func (c Client) InsertConfigRevision(ctx context.Context, r ConfigRevision) (ConfigRevision, error) {
+	return c.s.InsertConfigRevision(ctx, r)
+}
+
+// GetConfigRevision retrieves a revision including its content. Revisions of
+// other workspaces are not found.
+func (c Client) GetConfigRevision(ctx context.Context, workspace string, id int64) (ConfigRevision, bool, error) {
+	return c.s.GetConfigRevision(ctx, workspace, id)
+}
+
+// LatestConfigRevision returns the most recent revision of a workspace,
+// including its content.
+func (c Client) LatestConfigRevision(ctx context.Context, workspace string) (ConfigRevision, bool, error) {
+	return c.s.LatestConfigRevision(ctx, workspace)
+}
+
+// ListConfigRevisions returns a workspace's revisions newest first, without
+// content.
+func (c Client) ListConfigRevisions(ctx context.Context, workspace string) ([]ConfigRevision, error) {
+	return c.s.ListConfigRevisions(ctx, workspace)
+}
+
+// SaveConfigProfile creates or replaces a stored config profile and returns
+// it with its timestamps. CreatedAt is kept when a profile is replaced.
+func (c Client) SaveConfigProfile(ctx context.Context, p ConfigProfile) (ConfigProfile, error) {
+	return c.s.SaveConfigProfile(ctx, p)
+}
+
+// GetConfigProfile retrieves a stored profile including its content.
+func (c Client) GetConfigProfile(ctx context.Context, workspace, name string) (ConfigProfile, bool, error) {
+	return c.s.GetConfigProfile(ctx, workspace, name)
+}
+
+// ListConfigProfiles returns a workspace's stored profiles by name, without
+// content.
+func (c Client) ListConfigProfiles(ctx context.Context, workspace string) ([]ConfigProfile, error) {
+	return c.s.ListConfigProfiles(ctx, workspace)
+}
+
+// RenameConfigProfile renames a stored profile. Returns ErrNotFound if it
+// does not exist and ErrConflict if newName is taken.
+func (c Client) RenameConfigProfile(ctx context.Context, workspace, name, newName string) error {
+	return c.s.RenameConfigProfile(ctx, workspace, name, newName)
+}
+
+// DeleteConfigProfile removes a stored profile. Returns ErrNotFound if it
+// does not exist.
+func (c Client) DeleteConfigProfile(ctx context.Context, workspace, name string) error {
+	return c.s.DeleteConfigProfile(ctx, workspace, name)
+}
+
+// SaveCustomComponent creates or replaces a custom component definition and
+// returns it with its timestamps. CreatedAt is kept when one is replaced.
+func (c Client) SaveCustomComponent(ctx context.Context, cc CustomComponent) (CustomComponent, error) {
+	return c.s.SaveCustomComponent(ctx, cc)
+}
+
+// GetCustomComponent retrieves a custom component definition.
+func (c Client) GetCustomComponent(ctx context.Context, workspace, name string) (CustomComponent, bool, error) {
+	return c.s.GetCustomComponent(ctx, workspace, name)
+}
+
+// ListCustomComponents returns a workspace's custom components by name.
+func (c Client) ListCustomComponents(ctx context.Context, workspace string) ([]CustomComponent, error) {
+	return c.s.ListCustomComponents(ctx, workspace)
+}
+
+// DeleteCustomComponent removes a custom component definition. Returns
+// ErrNotFound if it does not exist.
+func (c Client) DeleteCustomComponent(ctx context.Context, workspace, name string) error {
+	return c.s.DeleteCustomComponent(ctx, workspace, name)
+}
+
+// SaveActionHook creates or replaces an action hook and returns it with its
+// timestamps. CreatedAt is kept when one is replaced.
+func (c Client) SaveActionHook(ctx context.Context, h ActionHook) (ActionHook, error) {
+	return c.s.SaveActionHook(ctx, h)
+}
+
+// GetActionHook retrieves an action hook by ID.
+func (c Client) GetActionHook(ctx context.Context, id string) (ActionHook, bool, error) {
+	return c.s.GetActionHook(ctx, id)
+}
+
+// ListActionHooks returns a workspace's hooks in run order, grouped by
+// component and action. An empty component returns them all.
+func (c Client) ListActionHooks(ctx context.Context, workspace, component string) ([]ActionHook, error) {
+	return c.s.ListActionHooks(ctx, workspace, component)
+}
+
+// DeleteActionHook removes an action hook. Returns ErrNotFound if it does
+// not exist. Its recorded runs are kept.
+func (c Client) DeleteActionHook(ctx context.Context, id string) error {
+	return c.s.DeleteActionHook(ctx, id)
+}
+
+// InsertHookRun records a hook execution.
+func (c Client) InsertHookRun(ctx context.Context, r HookRun) error {
+	return c.s.InsertHookRun(ctx, r)
+}
+
+// UpdateHookRun sets the status and result of a hook execution.
+func (c Client) UpdateHookRun(ctx context.Context, id string, result ActionResult) error {
+	return c.s.UpdateHookRun(ctx, id, result)
+}
+
+// ListHookRuns returns the hook executions of an action in start order.
+func (c Client) ListHookRuns(ctx context.Context, actionID string) ([]HookRun, error) {
+	return c.s.ListHookRuns(ctx, actionID)
+}
+
+// AcquireLock takes or renews a named lock. If another holder has an
+// unexpired lock, the current lock is returned together with ErrLocked.
+func (c Client) AcquireLock(ctx context.Context, l Lock) (Lock, error) {
+	return c.s.AcquireLock(ctx, l)
+}
+
+// ReleaseLock releases a named lock held by holderID.
+func (c Client) ReleaseLock(ctx context.Context, name, holderID string) error {
+	return c.s.ReleaseLock(ctx, name, holderID)
+}
+
+// GetLock returns the current unexpired holder of a named lock.
+func (c Client) GetLock(ctx context.Context, name string) (Lock, bool, error) {
+	return c.s.GetLock(ctx, name)
+}
+
+// BreakLock removes a named lock regardless of holder, returning what was removed.
+func (c Client) BreakLock(ctx context.Context, name string) (Lock, bool, error) {
+	return c.s.BreakLock(ctx, name)
+}
+
 func (c Client) GetSchemaVersion() (int, error) {
 	return c.s.GetSchemaVersion()
 }
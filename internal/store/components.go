@@ -0,0 +1,122 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// ---------------------------------------------------------------------------
+// Custom components
+// ---------------------------------------------------------------------------
+
+func (d *db) SaveCustomComponent(ctx context.Context, c CustomComponent) (CustomComponent, error) {
+	if c.Name == "" {
+		return CustomComponent{}, ErrInvalidArgument
+	}
+	c.Workspace = workspaceOrDefault(c.Workspace)
+	rolesJSON, err := json.Marshal(nonNil(c.Roles))
+	if err != nil {
+		return CustomComponent{}, err
+	}
+	actionsJSON, err := json.Marshal(nonNil(c.Actions))
+	if err != nil {
+		return CustomComponent{}, err
+	}
+	now := d.now()
+	if c.CreatedAt.IsZero() {
+		c.CreatedAt = now
+	}
+	_, err = d.conn.ExecContext(ctx, `
+		INSERT INTO custom_components(workspace, name, description, tier, roles, actions, created_at, updated_at)
+		VALUES(?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(workspace, name) DO UPDATE SET
+			description = excluded.description,
+			tier        = excluded.tier,
+			roles       = excluded.roles,
+			actions     = excluded.actions,
+			updated_at  = excluded.updated_at
+	`, c.Workspace, c.Name, c.Description, c.Tier, string(rolesJSON), string(actionsJSON), c.CreatedAt.Unix(), now.Unix())
+	if err != nil {
+		return CustomComponent{}, err
+	}
+	got, _, err := d.GetCustomComponent(ctx, c.Workspace, c.Name)
+	return got, err
+}
+
+func (d *db) GetCustomComponent(ctx context.Context, workspace, name string) (CustomComponent, bool, error) {
+	row := d.conn.QueryRowContext(ctx, `
+		SELECT workspace, name, description, tier, roles, actions, created_at, updated_at
+		FROM custom_components WHERE workspace = ? AND name = ?
+	`, workspaceOrDefault(workspace), name)
+	c, err := scanCustomComponent(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return CustomComponent{}, false, nil
+	}
+	if err != nil {
+		return CustomComponent{}, false, err
+	}
+	return c, true, nil
+}
+
+func (d *db) ListCustomComponents(ctx context.Context, workspace string) ([]CustomComponent, error) {
+	rows, err := d.conn.QueryContext(ctx, `
+		SELECT workspace, name, description, tier, roles, actions, created_at, updated_at
+		FROM custom_components WHERE workspace = ? ORDER BY name
+	`, workspaceOrDefault(workspace))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []CustomComponent
+	for rows.Next() {
+		c, err := scanCustomComponent(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+func (d *db) DeleteCustomComponent(ctx context.Context, workspace, name string) error {
+	res, err := d.conn.ExecContext(ctx, `
+		DELETE FROM custom_components WHERE workspace = ? AND name = ?
+	`, workspaceOrDefault(workspace), name)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func scanCustomComponent(sc interface{ Scan(...any) error }) (CustomComponent, error) {
+	var c CustomComponent
+	var rolesJSON, actionsJSON string
+	var createdAt, updatedAt int64
+	if err := sc.Scan(&c.Workspace, &c.Name, &c.Description, &c.Tier, &rolesJSON, &actionsJSON, &createdAt, &updatedAt); err != nil {
+		return CustomComponent{}, err
+	}
+	if err := json.Unmarshal([]byte(rolesJSON), &c.Roles); err != nil {
+		return CustomComponent{}, err
+	}
+	if err := json.Unmarshal([]byte(actionsJSON), &c.Actions); err != nil {
+		return CustomComponent{}, err
+	}
+	c.CreatedAt = time.Unix(createdAt, 0)
+	c.UpdatedAt = time.Unix(updatedAt, 0)
+	return c, nil
+}
+
+// nonNil returns s, or an empty slice so it encodes as [] rather than null.
+func nonNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
@@ -0,0 +1,67 @@
+package store
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCustomComponents(t *testing.T) {
+	st := newTestStore(t)
+	ctx := t.Context()
+
+	if _, ok, err := st.GetCustomComponent(ctx, "", "monitoring"); err != nil || ok {
+		t.Fatalf("GetCustomComponent on empty store: ok=%v err=%v", ok, err)
+	}
+
+	actions := []CustomAction{
+		{Name: "install", Kind: CustomActionPlaybook, Target: "monitoring/install.yml", Args: []string{"--tags", "grafana"}},
+		{Name: "uninstall", Kind: CustomActionMake, Target: "site-monitoring-uninstall"},
+	}
+	saved, err := st.SaveCustomComponent(ctx, CustomComponent{Name: "monitoring", Tier: 2, Roles: []string{"monitor"}, Actions: actions})
+	if err != nil {
+		t.Fatalf("SaveCustomComponent: %v", err)
+	}
+	if saved.Workspace != DefaultWorkspace || saved.Tier != 2 || saved.CreatedAt.IsZero() {
+		t.Errorf("saved = %+v", saved)
+	}
+	if !reflect.DeepEqual(saved.Actions, actions) || !reflect.DeepEqual(saved.Roles, []string{"monitor"}) {
+		t.Errorf("round trip = %+v", saved)
+	}
+
+	if _, err := st.SaveCustomComponent(ctx, CustomComponent{Name: "switches", Actions: []CustomAction{{Name: "configure", Kind: CustomActionScript, Target: "switches.sh"}}}); err != nil {
+		t.Fatalf("SaveCustomComponent: %v", err)
+	}
+	if _, err := st.SaveCustomComponent(ctx, CustomComponent{Workspace: "lab2", Name: "monitoring"}); err != nil {
+		t.Fatalf("SaveCustomComponent lab2: %v", err)
+	}
+
+	// Saving again replaces the definition and keeps the creation time.
+	updated, err := st.SaveCustomComponent(ctx, CustomComponent{Name: "monitoring", Description: "Prometheus", CreatedAt: saved.CreatedAt})
+	if err != nil {
+		t.Fatalf("SaveCustomComponent update: %v", err)
+	}
+	if updated.Description != "Prometheus" || len(updated.Actions) != 0 || updated.Roles == nil || !updated.CreatedAt.Equal(saved.CreatedAt) {
+		t.Errorf("updated = %+v", updated)
+	}
+
+	list, err := st.ListCustomComponents(ctx, "")
+	if err != nil {
+		t.Fatalf("ListCustomComponents: %v", err)
+	}
+	if len(list) != 2 || list[0].Name != "monitoring" || list[1].Name != "switches" {
+		t.Errorf("ListCustomComponents = %+v", list)
+	}
+
+	if err := st.DeleteCustomComponent(ctx, "", "switches"); err != nil {
+		t.Fatalf("DeleteCustomComponent: %v", err)
+	}
+	if err := st.DeleteCustomComponent(ctx, "", "switches"); err != ErrNotFound {
+		t.Errorf("DeleteCustomComponent twice = %v, want ErrNotFound", err)
+	}
+	if list, _ := st.ListCustomComponents(ctx, "lab2"); len(list) != 1 {
+		t.Errorf("lab2 components = %+v", list)
+	}
+	if _, err := st.SaveCustomComponent(ctx, CustomComponent{}); err != ErrInvalidArgument {
+		t.Errorf("SaveCustomComponent without name = %v, want ErrInvalidArgument", err)
+	}
+}
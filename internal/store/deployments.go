@@ -3,6 +3,7 @@ package store
 import (
 	"context"
 	"database/sql"
+	"strings"
 	"time"
 )
 
@@ -10,6 +11,7 @@ func (d *db) InsertDeployment(ctx context.Context, dep Deployment) error {
 	if dep.ID == "" {
 		return ErrInvalidArgument
 	}
+	dep.Workspace = workspaceOrDefault(dep.Workspace)
 	if dep.Status == "" {
 		dep.Status = "pending"
 	}
@@ -35,9 +37,9 @@ func (d *db) InsertDeployment(ctx context.Context, dep Deployment) error {
 	}
 
 	if _, err := tx.ExecContext(ctx, `
-		INSERT INTO deployments(id, status, created_at, started_at, finished_at, error)
-		VALUES(?, ?, ?, ?, ?, ?)
-	`, dep.ID, dep.Status, createdAt, startedAt, finishedAt, nullString(dep.Error)); err != nil {
+		INSERT INTO deployments(id, workspace, status, created_at, started_at, finished_at, error)
+		VALUES(?, ?, ?, ?, ?, ?, ?)
+	`, dep.ID, dep.Workspace, dep.Status, createdAt, startedAt, finishedAt, nullString(dep.Error)); err != nil {
 		return err
 	}
 
@@ -92,9 +94,9 @@ func (d *db) GetDeployment(ctx context.Context, id string) (Deployment, bool, er
 	var startedAt, finishedAt sql.NullInt64
 
 	err := d.conn.QueryRowContext(ctx, `
-		SELECT id, status, created_at, started_at, finished_at, error
+		SELECT id, workspace, status, created_at, started_at, finished_at, error
 		FROM deployments WHERE id = ?
-	`, id).Scan(&dep.ID, &dep.Status, &createdAt, &startedAt, &finishedAt, &errStr)
+	`, id).Scan(&dep.ID, &dep.Workspace, &dep.Status, &createdAt, &startedAt, &finishedAt, &errStr)
 	if err == sql.ErrNoRows {
 		return Deployment{}, false, nil
 	}
@@ -121,13 +123,21 @@ func (d *db) GetDeployment(ctx context.Context, id string) (Deployment, bool, er
 }
 
 func (d *db) ListDeployments(ctx context.Context, filter DeploymentFilter) ([]Deployment, error) {
-	query := `SELECT id, status, created_at, started_at, finished_at, error FROM deployments`
+	query := `SELECT id, workspace, status, created_at, started_at, finished_at, error FROM deployments`
+	var conditions []string
 	var args []any
 
+	if filter.Workspace != "" {
+		conditions = append(conditions, "workspace = ?")
+		args = append(args, filter.Workspace)
+	}
 	if filter.Status != "" {
-		query += " WHERE status = ?"
+		conditions = append(conditions, "status = ?")
 		args = append(args, filter.Status)
 	}
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
 
 	query += " ORDER BY created_at DESC"
 
@@ -156,7 +166,7 @@ func (d *db) ListDeployments(ctx context.Context, filter DeploymentFilter) ([]De
 		var createdAt int64
 		var startedAt, finishedAt sql.NullInt64
 
-		if err := rows.Scan(&dep.ID, &dep.Status, &createdAt, &startedAt, &finishedAt, &errStr); err != nil {
+		if err := rows.Scan(&dep.ID, &dep.Workspace, &dep.Status, &createdAt, &startedAt, &finishedAt, &errStr); err != nil {
 			return nil, err
 		}
 
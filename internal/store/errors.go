@@ -7,4 +7,5 @@ var (
 	ErrNotFound        = errors.New("store: not found")
 	ErrExpired         = errors.New("store: expired")
 	ErrInvalidArgument = errors.New("store: invalid argument")
+	ErrLocked          = errors.New("store: locked")
 )
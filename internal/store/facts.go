@@ -0,0 +1,178 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ---------------------------------------------------------------------------
+// Node facts
+// ---------------------------------------------------------------------------
+
+const nodeFactsColumns = `id, node_id, facts, changes, gathered_at, last_gathered_at`
+
+func (d *db) AddNodeFacts(ctx context.Context, v NodeFactsVersion) (int64, error) {
+	if v.NodeID == "" || len(v.Facts) == 0 {
+		return 0, ErrInvalidArgument
+	}
+	if v.GatheredAt.IsZero() {
+		v.GatheredAt = d.now()
+	}
+	if v.LastGatheredAt.IsZero() {
+		v.LastGatheredAt = v.GatheredAt
+	}
+	var changes *string
+	if v.Changes != nil {
+		s := string(v.Changes)
+		changes = &s
+	}
+	res, err := d.conn.ExecContext(ctx, `
+		INSERT INTO node_facts(node_id, facts, changes, gathered_at, last_gathered_at)
+		VALUES(?, ?, ?, ?, ?)
+	`, v.NodeID, string(v.Facts), changes, v.GatheredAt.Unix(), v.LastGatheredAt.Unix())
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+func (d *db) TouchNodeFacts(ctx context.Context, id int64, at time.Time) error {
+	if id == 0 {
+		return ErrInvalidArgument
+	}
+	if at.IsZero() {
+		at = d.now()
+	}
+	res, err := d.conn.ExecContext(ctx, `
+		UPDATE node_facts SET last_gathered_at = MAX(last_gathered_at, ?) WHERE id = ?
+	`, at.Unix(), id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (d *db) GetNodeFacts(ctx context.Context, nodeID string, id int64) (NodeFactsVersion, bool, error) {
+	if nodeID == "" {
+		return NodeFactsVersion{}, false, ErrInvalidArgument
+	}
+	var row *sql.Row
+	if id == 0 {
+		row = d.conn.QueryRowContext(ctx, `
+			SELECT `+nodeFactsColumns+` FROM node_facts WHERE node_id = ? ORDER BY id DESC LIMIT 1
+		`, nodeID)
+	} else {
+		row = d.conn.QueryRowContext(ctx, `
+			SELECT `+nodeFactsColumns+` FROM node_facts WHERE node_id = ? AND id = ?
+		`, nodeID, id)
+	}
+	v, err := scanNodeFacts(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return NodeFactsVersion{}, false, nil
+	}
+	if err != nil {
+		return NodeFactsVersion{}, false, err
+	}
+	return v, true, nil
+}
+
+func (d *db) ListNodeFacts(ctx context.Context, nodeID string, limit int) ([]NodeFactsVersion, error) {
+	if nodeID == "" {
+		return nil, ErrInvalidArgument
+	}
+	query := `SELECT ` + nodeFactsColumns + ` FROM node_facts WHERE node_id = ? ORDER BY id DESC`
+	args := []any{nodeID}
+	if limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, limit)
+	}
+	rows, err := d.conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []NodeFactsVersion
+	for rows.Next() {
+		v, err := scanNodeFacts(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, rows.Err()
+}
+
+func scanNodeFacts(sc interface{ Scan(...any) error }) (NodeFactsVersion, error) {
+	var v NodeFactsVersion
+	var facts string
+	var changes sql.NullString
+	var gatheredAt, lastGatheredAt int64
+	if err := sc.Scan(&v.ID, &v.NodeID, &facts, &changes, &gatheredAt, &lastGatheredAt); err != nil {
+		return NodeFactsVersion{}, err
+	}
+	v.Facts = []byte(facts)
+	if changes.Valid {
+		v.Changes = []byte(changes.String)
+	}
+	v.GatheredAt = time.Unix(gatheredAt, 0)
+	v.LastGatheredAt = time.Unix(lastGatheredAt, 0)
+	return v, nil
+}
+
+// ---------------------------------------------------------------------------
+// Config defaults
+// ---------------------------------------------------------------------------
+
+func (d *db) UpsertConfigDefault(ctx context.Context, c ConfigDefault) error {
+	if c.Field == "" || c.NodeID == "" || len(c.Value) == 0 {
+		return ErrInvalidArgument
+	}
+	if c.AppliedAt.IsZero() {
+		c.AppliedAt = d.now()
+	}
+	_, err := d.conn.ExecContext(ctx, `
+		INSERT INTO config_defaults(workspace, field, value, node_id, facts_id, applied_at)
+		VALUES(?, ?, ?, ?, ?, ?)
+		ON CONFLICT(workspace, field) DO UPDATE SET
+			value      = excluded.value,
+			node_id    = excluded.node_id,
+			facts_id   = excluded.facts_id,
+			applied_at = excluded.applied_at
+	`, workspaceOrDefault(c.Workspace), c.Field, string(c.Value), c.NodeID, c.FactsID, c.AppliedAt.Unix())
+	return err
+}
+
+func (d *db) ListConfigDefaults(ctx context.Context, workspace string) ([]ConfigDefault, error) {
+	rows, err := d.conn.QueryContext(ctx, `
+		SELECT workspace, field, value, node_id, facts_id, applied_at
+		FROM config_defaults WHERE workspace = ? ORDER BY field
+	`, workspaceOrDefault(workspace))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []ConfigDefault
+	for rows.Next() {
+		var c ConfigDefault
+		var value string
+		var appliedAt int64
+		if err := rows.Scan(&c.Workspace, &c.Field, &value, &c.NodeID, &c.FactsID, &appliedAt); err != nil {
+			return nil, err
+		}
+		c.Value = []byte(value)
+		c.AppliedAt = time.Unix(appliedAt, 0)
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,95 @@
+package store
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestNodeFacts(t *testing.T) {
+	st := newTestStore(t)
+	ctx := t.Context()
+
+	if err := st.UpsertNode(ctx, Node{ID: "n1", Name: "node1", AnsibleHost: "10.0.0.1"}); err != nil {
+		t.Fatalf("UpsertNode: %v", err)
+	}
+	if _, ok, err := st.GetNodeFacts(ctx, "n1", 0); err != nil || ok {
+		t.Fatalf("GetNodeFacts before add: ok=%v err=%v", ok, err)
+	}
+
+	t0 := time.Unix(1_700_000_000, 0)
+	id1, err := st.AddNodeFacts(ctx, NodeFactsVersion{NodeID: "n1", Facts: []byte(`{"a":1}`), GatheredAt: t0})
+	if err != nil {
+		t.Fatalf("AddNodeFacts: %v", err)
+	}
+	id2, err := st.AddNodeFacts(ctx, NodeFactsVersion{
+		NodeID: "n1", Facts: []byte(`{"a":2}`), Changes: []byte(`[]`), GatheredAt: t0.Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("AddNodeFacts: %v", err)
+	}
+
+	if err := st.TouchNodeFacts(ctx, id2, t0.Add(2*time.Hour)); err != nil {
+		t.Fatalf("TouchNodeFacts: %v", err)
+	}
+	// An older gather does not move last_gathered_at back.
+	if err := st.TouchNodeFacts(ctx, id2, t0); err != nil {
+		t.Fatalf("TouchNodeFacts: %v", err)
+	}
+	if err := st.TouchNodeFacts(ctx, 999, t0); !errors.Is(err, ErrNotFound) {
+		t.Errorf("TouchNodeFacts(missing) = %v, want ErrNotFound", err)
+	}
+
+	latest, ok, err := st.GetNodeFacts(ctx, "n1", 0)
+	if err != nil || !ok {
+		t.Fatalf("GetNodeFacts: ok=%v err=%v", ok, err)
+	}
+	if latest.ID != id2 || string(latest.Facts) != `{"a":2}` || string(latest.Changes) != `[]` ||
+		!latest.GatheredAt.Equal(t0.Add(time.Hour)) || !latest.LastGatheredAt.Equal(t0.Add(2*time.Hour)) {
+		t.Errorf("latest = %+v", latest)
+	}
+	first, ok, err := st.GetNodeFacts(ctx, "n1", id1)
+	if err != nil || !ok || first.Changes != nil || !first.LastGatheredAt.Equal(t0) {
+		t.Errorf("first = %+v, ok=%v err=%v", first, ok, err)
+	}
+
+	list, err := st.ListNodeFacts(ctx, "n1", 1)
+	if err != nil || len(list) != 1 || list[0].ID != id2 {
+		t.Errorf("ListNodeFacts(limit 1) = %+v, %v", list, err)
+	}
+	if list, _ := st.ListNodeFacts(ctx, "n1", 0); len(list) != 2 {
+		t.Errorf("ListNodeFacts = %d versions, want 2", len(list))
+	}
+
+	if err := st.UpsertConfigDefault(ctx, ConfigDefault{
+		Field: "core.data_iface", Value: []byte(`"ens18"`), NodeID: "n1", FactsID: id1, AppliedAt: t0,
+	}); err != nil {
+		t.Fatalf("UpsertConfigDefault: %v", err)
+	}
+	if err := st.UpsertConfigDefault(ctx, ConfigDefault{
+		Field: "core.data_iface", Value: []byte(`"ens19"`), NodeID: "n1", FactsID: id2, AppliedAt: t0.Add(time.Hour),
+	}); err != nil {
+		t.Fatalf("UpsertConfigDefault: %v", err)
+	}
+	defaults, err := st.ListConfigDefaults(ctx, "")
+	if err != nil || len(defaults) != 1 {
+		t.Fatalf("ListConfigDefaults = %+v, %v", defaults, err)
+	}
+	if d := defaults[0]; d.Workspace != DefaultWorkspace || string(d.Value) != `"ens19"` || d.FactsID != id2 {
+		t.Errorf("default = %+v", d)
+	}
+	if other, _ := st.ListConfigDefaults(ctx, "lab2"); len(other) != 0 {
+		t.Errorf("lab2 defaults = %+v, want none", other)
+	}
+
+	// Deleting the node drops its facts and the defaults taken from them.
+	if err := st.DeleteNode(ctx, "n1"); err != nil {
+		t.Fatalf("DeleteNode: %v", err)
+	}
+	if list, _ := st.ListNodeFacts(ctx, "n1", 0); len(list) != 0 {
+		t.Errorf("facts left after delete: %+v", list)
+	}
+	if defaults, _ := st.ListConfigDefaults(ctx, ""); len(defaults) != 0 {
+		t.Errorf("defaults left after delete: %+v", defaults)
+	}
+}
@@ -0,0 +1,177 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ---------------------------------------------------------------------------
+// Node health
+// ---------------------------------------------------------------------------
+
+const nodeHealthColumns = `h.node_id, h.status, h.tcp_ok, h.ssh_ok, h.sudo_ok, h.latency_ms, h.error, h.checked_at, h.since, h.last_seen`
+
+func (d *db) RecordNodeHealth(ctx context.Context, h NodeHealth) (NodeHealthEvent, bool, error) {
+	if h.NodeID == "" || h.Status == "" {
+		return NodeHealthEvent{}, false, ErrInvalidArgument
+	}
+	if h.CheckedAt.IsZero() {
+		h.CheckedAt = d.now()
+	}
+
+	tx, err := d.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return NodeHealthEvent{}, false, err
+	}
+	defer tx.Rollback()
+
+	prev := NodeHealth{Status: NodeHealthUnknown}
+	row := tx.QueryRowContext(ctx, `SELECT `+nodeHealthColumns+` FROM node_health h WHERE h.node_id = ?`, h.NodeID)
+	if p, err := scanNodeHealth(row); err == nil {
+		prev = p
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return NodeHealthEvent{}, false, err
+	}
+
+	h.Since = prev.Since
+	if h.Status != prev.Status {
+		h.Since = h.CheckedAt
+	}
+	h.LastSeen = prev.LastSeen
+	if h.SSH {
+		h.LastSeen = h.CheckedAt
+	}
+	var lastSeen *int64
+	if !h.LastSeen.IsZero() {
+		v := h.LastSeen.Unix()
+		lastSeen = &v
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO node_health(node_id, status, tcp_ok, ssh_ok, sudo_ok, latency_ms, error, checked_at, since, last_seen)
+		VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(node_id) DO UPDATE SET
+			status     = excluded.status,
+			tcp_ok     = excluded.tcp_ok,
+			ssh_ok     = excluded.ssh_ok,
+			sudo_ok    = excluded.sudo_ok,
+			latency_ms = excluded.latency_ms,
+			error      = excluded.error,
+			checked_at = excluded.checked_at,
+			since      = excluded.since,
+			last_seen  = excluded.last_seen
+	`, h.NodeID, h.Status, h.TCP, h.SSH, h.Sudo, h.Latency.Milliseconds(), h.Error,
+		h.CheckedAt.Unix(), h.Since.Unix(), lastSeen); err != nil {
+		return NodeHealthEvent{}, false, err
+	}
+
+	if h.Status == prev.Status {
+		return NodeHealthEvent{}, false, tx.Commit()
+	}
+	ev := NodeHealthEvent{NodeID: h.NodeID, From: prev.Status, To: h.Status, Error: h.Error, At: h.CheckedAt}
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO node_health_events(node_id, from_status, to_status, error, at) VALUES(?, ?, ?, ?, ?)
+	`, ev.NodeID, ev.From, ev.To, ev.Error, ev.At.Unix())
+	if err != nil {
+		return NodeHealthEvent{}, false, err
+	}
+	if ev.ID, err = res.LastInsertId(); err != nil {
+		return NodeHealthEvent{}, false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return NodeHealthEvent{}, false, err
+	}
+	ev.At = time.Unix(ev.At.Unix(), 0)
+	return ev, true, nil
+}
+
+func (d *db) GetNodeHealth(ctx context.Context, nodeID string) (NodeHealth, bool, error) {
+	if nodeID == "" {
+		return NodeHealth{}, false, ErrInvalidArgument
+	}
+	row := d.conn.QueryRowContext(ctx, `SELECT `+nodeHealthColumns+` FROM node_health h WHERE h.node_id = ?`, nodeID)
+	h, err := scanNodeHealth(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return NodeHealth{}, false, nil
+	}
+	if err != nil {
+		return NodeHealth{}, false, err
+	}
+	return h, true, nil
+}
+
+func (d *db) ListNodeHealth(ctx context.Context, workspace string) ([]NodeHealth, error) {
+	rows, err := d.conn.QueryContext(ctx, `
+		SELECT `+nodeHealthColumns+`
+		FROM node_health h JOIN nodes n ON n.id = h.node_id
+		WHERE n.workspace = ? ORDER BY n.name
+	`, workspaceOrDefault(workspace))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []NodeHealth
+	for rows.Next() {
+		h, err := scanNodeHealth(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, h)
+	}
+	return out, rows.Err()
+}
+
+func (d *db) ListNodeHealthEvents(ctx context.Context, filter NodeHealthEventFilter) ([]NodeHealthEvent, error) {
+	query := `
+		SELECT e.id, e.node_id, e.from_status, e.to_status, e.error, e.at
+		FROM node_health_events e JOIN nodes n ON n.id = e.node_id
+		WHERE n.workspace = ?`
+	args := []any{workspaceOrDefault(filter.Workspace)}
+	if filter.NodeID != "" {
+		query += ` AND e.node_id = ?`
+		args = append(args, filter.NodeID)
+	}
+	query += ` ORDER BY e.at DESC, e.id DESC`
+	if filter.Limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := d.conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []NodeHealthEvent
+	for rows.Next() {
+		var ev NodeHealthEvent
+		var at int64
+		if err := rows.Scan(&ev.ID, &ev.NodeID, &ev.From, &ev.To, &ev.Error, &at); err != nil {
+			return nil, err
+		}
+		ev.At = time.Unix(at, 0)
+		out = append(out, ev)
+	}
+	return out, rows.Err()
+}
+
+func scanNodeHealth(sc interface{ Scan(...any) error }) (NodeHealth, error) {
+	var h NodeHealth
+	var latencyMS, checkedAt, since int64
+	var lastSeen sql.NullInt64
+	if err := sc.Scan(&h.NodeID, &h.Status, &h.TCP, &h.SSH, &h.Sudo, &latencyMS, &h.Error,
+		&checkedAt, &since, &lastSeen); err != nil {
+		return NodeHealth{}, err
+	}
+	h.Latency = time.Duration(latencyMS) * time.Millisecond
+	h.CheckedAt = time.Unix(checkedAt, 0)
+	h.Since = time.Unix(since, 0)
+	if lastSeen.Valid {
+		h.LastSeen = time.Unix(lastSeen.Int64, 0)
+	}
+	return h, nil
+}
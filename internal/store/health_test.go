@@ -0,0 +1,92 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNodeHealth(t *testing.T) {
+	st := newTestStore(t)
+	ctx := t.Context()
+
+	for _, n := range []Node{
+		{ID: "n1", Name: "node1", AnsibleHost: "10.0.0.1"},
+		{ID: "n2", Workspace: "lab2", Name: "node2", AnsibleHost: "10.0.0.2"},
+	} {
+		if err := st.UpsertNode(ctx, n); err != nil {
+			t.Fatalf("UpsertNode(%s): %v", n.ID, err)
+		}
+	}
+
+	t0 := time.Unix(1_700_000_000, 0)
+	ev, changed, err := st.RecordNodeHealth(ctx, NodeHealth{
+		NodeID: "n1", Status: NodeHealthHealthy, TCP: true, SSH: true, Sudo: true,
+		Latency: 12 * time.Millisecond, CheckedAt: t0,
+	})
+	if err != nil || !changed {
+		t.Fatalf("RecordNodeHealth: changed=%v err=%v", changed, err)
+	}
+	if ev.From != NodeHealthUnknown || ev.To != NodeHealthHealthy || ev.ID == 0 {
+		t.Errorf("first event = %+v", ev)
+	}
+
+	// The same status again is not a change and keeps Since.
+	if _, changed, err := st.RecordNodeHealth(ctx, NodeHealth{
+		NodeID: "n1", Status: NodeHealthHealthy, TCP: true, SSH: true, Sudo: true, CheckedAt: t0.Add(time.Minute),
+	}); err != nil || changed {
+		t.Fatalf("repeat: changed=%v err=%v", changed, err)
+	}
+	h, ok, err := st.GetNodeHealth(ctx, "n1")
+	if err != nil || !ok {
+		t.Fatalf("GetNodeHealth: ok=%v err=%v", ok, err)
+	}
+	if !h.Since.Equal(t0) || !h.LastSeen.Equal(t0.Add(time.Minute)) || h.Latency != 0 {
+		t.Errorf("health = %+v", h)
+	}
+
+	// Losing the node keeps the last time it was seen.
+	ev, changed, err = st.RecordNodeHealth(ctx, NodeHealth{
+		NodeID: "n1", Status: NodeHealthUnreachable, Error: "connection refused", CheckedAt: t0.Add(2 * time.Minute),
+	})
+	if err != nil || !changed || ev.From != NodeHealthHealthy || ev.Error != "connection refused" {
+		t.Fatalf("down: ev=%+v changed=%v err=%v", ev, changed, err)
+	}
+	h, _, _ = st.GetNodeHealth(ctx, "n1")
+	if h.Status != NodeHealthUnreachable || h.TCP || !h.Since.Equal(t0.Add(2*time.Minute)) || !h.LastSeen.Equal(t0.Add(time.Minute)) {
+		t.Errorf("health after down = %+v", h)
+	}
+
+	if _, _, err := st.RecordNodeHealth(ctx, NodeHealth{NodeID: "n2", Status: NodeHealthDegraded, TCP: true}); err != nil {
+		t.Fatalf("RecordNodeHealth(n2): %v", err)
+	}
+	list, err := st.ListNodeHealth(ctx, "")
+	if err != nil || len(list) != 1 || list[0].NodeID != "n1" {
+		t.Errorf("ListNodeHealth(default) = %+v, %v", list, err)
+	}
+	if h, _, _ := st.GetNodeHealth(ctx, "n2"); !h.LastSeen.IsZero() {
+		t.Errorf("n2 last seen = %v, want zero", h.LastSeen)
+	}
+
+	events, err := st.ListNodeHealthEvents(ctx, NodeHealthEventFilter{NodeID: "n1"})
+	if err != nil || len(events) != 2 || events[0].To != NodeHealthUnreachable {
+		t.Errorf("events = %+v, %v", events, err)
+	}
+	if events, _ := st.ListNodeHealthEvents(ctx, NodeHealthEventFilter{Workspace: "lab2", Limit: 5}); len(events) != 1 {
+		t.Errorf("lab2 events = %+v", events)
+	}
+
+	// Deleting a node removes its health.
+	if err := st.DeleteNode(ctx, "n1"); err != nil {
+		t.Fatalf("DeleteNode: %v", err)
+	}
+	if _, ok, _ := st.GetNodeHealth(ctx, "n1"); ok {
+		t.Error("health kept after node delete")
+	}
+	if events, _ := st.ListNodeHealthEvents(ctx, NodeHealthEventFilter{}); len(events) != 0 {
+		t.Errorf("events after delete = %+v", events)
+	}
+
+	if _, _, err := st.RecordNodeHealth(ctx, NodeHealth{NodeID: "n2"}); err != ErrInvalidArgument {
+		t.Errorf("missing status err = %v", err)
+	}
+}
@@ -0,0 +1,196 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// ---------------------------------------------------------------------------
+// Action hooks
+// ---------------------------------------------------------------------------
+
+const actionHookColumns = `id, workspace, component, action, stage, name, kind, target, args, position, created_at, updated_at`
+
+func (d *db) SaveActionHook(ctx context.Context, h ActionHook) (ActionHook, error) {
+	if h.ID == "" || h.Component == "" || h.Stage == "" || h.Name == "" || h.Target == "" {
+		return ActionHook{}, ErrInvalidArgument
+	}
+	h.Workspace = workspaceOrDefault(h.Workspace)
+	argsJSON, err := json.Marshal(nonNil(h.Args))
+	if err != nil {
+		return ActionHook{}, err
+	}
+	now := d.now()
+	if h.CreatedAt.IsZero() {
+		h.CreatedAt = now
+	}
+	_, err = d.conn.ExecContext(ctx, `
+		INSERT INTO action_hooks(`+actionHookColumns+`)
+		VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			component  = excluded.component,
+			action     = excluded.action,
+			stage      = excluded.stage,
+			name       = excluded.name,
+			kind       = excluded.kind,
+			target     = excluded.target,
+			args       = excluded.args,
+			position   = excluded.position,
+			updated_at = excluded.updated_at
+	`, h.ID, h.Workspace, h.Component, h.Action, h.Stage, h.Name, h.Kind, h.Target, string(argsJSON), h.Position,
+		h.CreatedAt.Unix(), now.Unix())
+	if err != nil {
+		return ActionHook{}, err
+	}
+	got, _, err := d.GetActionHook(ctx, h.ID)
+	return got, err
+}
+
+func (d *db) GetActionHook(ctx context.Context, id string) (ActionHook, bool, error) {
+	row := d.conn.QueryRowContext(ctx, `SELECT `+actionHookColumns+` FROM action_hooks WHERE id = ?`, id)
+	h, err := scanActionHook(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ActionHook{}, false, nil
+	}
+	if err != nil {
+		return ActionHook{}, false, err
+	}
+	return h, true, nil
+}
+
+func (d *db) ListActionHooks(ctx context.Context, workspace, component string) ([]ActionHook, error) {
+	query := `SELECT ` + actionHookColumns + ` FROM action_hooks WHERE workspace = ?`
+	args := []any{workspaceOrDefault(workspace)}
+	if component != "" {
+		query += ` AND component = ?`
+		args = append(args, component)
+	}
+	query += ` ORDER BY component, action, stage, position, created_at, rowid`
+
+	rows, err := d.conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []ActionHook
+	for rows.Next() {
+		h, err := scanActionHook(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, h)
+	}
+	return out, rows.Err()
+}
+
+func (d *db) DeleteActionHook(ctx context.Context, id string) error {
+	res, err := d.conn.ExecContext(ctx, `DELETE FROM action_hooks WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func scanActionHook(sc interface{ Scan(...any) error }) (ActionHook, error) {
+	var h ActionHook
+	var argsJSON string
+	var createdAt, updatedAt int64
+	if err := sc.Scan(&h.ID, &h.Workspace, &h.Component, &h.Action, &h.Stage, &h.Name, &h.Kind, &h.Target,
+		&argsJSON, &h.Position, &createdAt, &updatedAt); err != nil {
+		return ActionHook{}, err
+	}
+	if err := json.Unmarshal([]byte(argsJSON), &h.Args); err != nil {
+		return ActionHook{}, err
+	}
+	if len(h.Args) == 0 {
+		h.Args = nil
+	}
+	h.CreatedAt = time.Unix(createdAt, 0)
+	h.UpdatedAt = time.Unix(updatedAt, 0)
+	return h, nil
+}
+
+// ---------------------------------------------------------------------------
+// Hook runs
+// ---------------------------------------------------------------------------
+
+func (d *db) InsertHookRun(ctx context.Context, r HookRun) error {
+	if r.ID == "" || r.ActionID == "" || r.HookID == "" {
+		return ErrInvalidArgument
+	}
+	if r.Status == "" {
+		r.Status = "pending"
+	}
+	if r.StartedAt.IsZero() {
+		r.StartedAt = d.now()
+	}
+	var finishedAt *int64
+	if !r.FinishedAt.IsZero() {
+		v := r.FinishedAt.Unix()
+		finishedAt = &v
+	}
+	_, err := d.conn.ExecContext(ctx, `
+		INSERT INTO hook_runs(id, action_id, hook_id, stage, name, target, status, exit_code, error, started_at, finished_at)
+		VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, r.ID, r.ActionID, r.HookID, r.Stage, r.Name, r.Target, r.Status, r.ExitCode, nullString(r.Error),
+		r.StartedAt.Unix(), finishedAt)
+	return err
+}
+
+func (d *db) UpdateHookRun(ctx context.Context, id string, result ActionResult) error {
+	if id == "" {
+		return ErrInvalidArgument
+	}
+	var finishedAt *int64
+	if !result.FinishedAt.IsZero() {
+		v := result.FinishedAt.Unix()
+		finishedAt = &v
+	}
+	res, err := d.conn.ExecContext(ctx, `
+		UPDATE hook_runs SET status = ?, exit_code = ?, error = ?, finished_at = ? WHERE id = ?
+	`, result.Status, result.ExitCode, nullString(result.Error), finishedAt, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (d *db) ListHookRuns(ctx context.Context, actionID string) ([]HookRun, error) {
+	rows, err := d.conn.QueryContext(ctx, `
+		SELECT id, action_id, hook_id, stage, name, target, status, exit_code, error, started_at, finished_at
+		FROM hook_runs WHERE action_id = ? ORDER BY started_at, rowid
+	`, actionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []HookRun
+	for rows.Next() {
+		var r HookRun
+		var errStr sql.NullString
+		var startedAt int64
+		var finishedAt sql.NullInt64
+		if err := rows.Scan(&r.ID, &r.ActionID, &r.HookID, &r.Stage, &r.Name, &r.Target, &r.Status,
+			&r.ExitCode, &errStr, &startedAt, &finishedAt); err != nil {
+			return nil, err
+		}
+		r.Error = errStr.String
+		r.StartedAt = time.Unix(startedAt, 0)
+		if finishedAt.Valid {
+			r.FinishedAt = time.Unix(finishedAt.Int64, 0)
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
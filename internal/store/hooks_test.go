@@ -0,0 +1,111 @@
+package store
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestActionHooks(t *testing.T) {
+	st := newTestStore(t)
+	ctx := t.Context()
+
+	hooks := []ActionHook{
+		{ID: "h1", Component: "5gc", Action: "uninstall", Stage: HookBefore, Name: "backup", Kind: CustomActionScript, Target: "backup.sh", Args: []string{"--values"}, Position: 2},
+		{ID: "h2", Component: "5gc", Action: "uninstall", Stage: HookBefore, Name: "notify", Kind: CustomActionScript, Target: "notify.sh", Position: 1},
+		{ID: "h3", Component: "5gc", Stage: HookOnFailure, Name: "page", Kind: CustomActionScript, Target: "page.sh"},
+		{ID: "h4", Component: "srsran", Action: "gnb-install", Stage: HookAfter, Name: "smoke", Kind: CustomActionPlaybook, Target: "smoke.yml"},
+		{ID: "h5", Workspace: "lab2", Component: "5gc", Stage: HookAfter, Name: "other", Kind: CustomActionMake, Target: "x"},
+	}
+	for _, h := range hooks {
+		if _, err := st.SaveActionHook(ctx, h); err != nil {
+			t.Fatalf("SaveActionHook(%s): %v", h.ID, err)
+		}
+	}
+
+	got, ok, err := st.GetActionHook(ctx, "h1")
+	if err != nil || !ok {
+		t.Fatalf("GetActionHook: ok=%v err=%v", ok, err)
+	}
+	if got.Workspace != DefaultWorkspace || !reflect.DeepEqual(got.Args, []string{"--values"}) || got.CreatedAt.IsZero() {
+		t.Errorf("h1 = %+v", got)
+	}
+
+	list, err := st.ListActionHooks(ctx, "", "5gc")
+	if err != nil {
+		t.Fatalf("ListActionHooks: %v", err)
+	}
+	var ids []string
+	for _, h := range list {
+		ids = append(ids, h.ID)
+	}
+	// Grouped by action (component-wide hooks first), then by position.
+	if want := []string{"h3", "h2", "h1"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("5gc hooks = %v, want %v", ids, want)
+	}
+	if all, _ := st.ListActionHooks(ctx, "", ""); len(all) != 4 {
+		t.Errorf("default workspace hooks = %d, want 4", len(all))
+	}
+
+	got.Target = "backup-v2.sh"
+	got.Args = nil
+	updated, err := st.SaveActionHook(ctx, got)
+	if err != nil {
+		t.Fatalf("SaveActionHook update: %v", err)
+	}
+	if updated.Target != "backup-v2.sh" || updated.Args != nil || !updated.CreatedAt.Equal(got.CreatedAt) {
+		t.Errorf("updated = %+v", updated)
+	}
+
+	if err := st.DeleteActionHook(ctx, "h4"); err != nil {
+		t.Fatalf("DeleteActionHook: %v", err)
+	}
+	if err := st.DeleteActionHook(ctx, "h4"); err != ErrNotFound {
+		t.Errorf("DeleteActionHook twice = %v, want ErrNotFound", err)
+	}
+	if _, err := st.SaveActionHook(ctx, ActionHook{ID: "h6", Component: "k8s"}); err != ErrInvalidArgument {
+		t.Errorf("SaveActionHook without stage = %v, want ErrInvalidArgument", err)
+	}
+}
+
+func TestHookRuns(t *testing.T) {
+	st := newTestStore(t)
+	ctx := t.Context()
+
+	start := time.Unix(1700000000, 0)
+	for i, r := range []HookRun{
+		{ID: "r1", ActionID: "a1", HookID: "h1", Stage: HookBefore, Name: "backup", Target: "backup.sh", StartedAt: start},
+		{ID: "r2", ActionID: "a1", HookID: "h2", Stage: HookAfter, Name: "smoke", Target: "smoke.yml", StartedAt: start.Add(time.Minute)},
+		{ID: "r3", ActionID: "a2", HookID: "h1", Stage: HookBefore, Name: "backup", Target: "backup.sh"},
+	} {
+		if err := st.InsertHookRun(ctx, r); err != nil {
+			t.Fatalf("InsertHookRun(%d): %v", i, err)
+		}
+	}
+
+	finished := start.Add(30 * time.Second)
+	if err := st.UpdateHookRun(ctx, "r1", ActionResult{Status: "failed", Error: "exit status 2", ExitCode: 2, FinishedAt: finished}); err != nil {
+		t.Fatalf("UpdateHookRun: %v", err)
+	}
+	if err := st.UpdateHookRun(ctx, "missing", ActionResult{Status: "failed"}); err != ErrNotFound {
+		t.Errorf("UpdateHookRun missing = %v, want ErrNotFound", err)
+	}
+
+	runs, err := st.ListHookRuns(ctx, "a1")
+	if err != nil {
+		t.Fatalf("ListHookRuns: %v", err)
+	}
+	if len(runs) != 2 || runs[0].ID != "r1" || runs[1].ID != "r2" {
+		t.Fatalf("runs = %+v", runs)
+	}
+	r1 := runs[0]
+	if r1.Status != "failed" || r1.ExitCode != 2 || r1.Error != "exit status 2" || !r1.FinishedAt.Equal(finished) {
+		t.Errorf("r1 = %+v", r1)
+	}
+	if runs[1].Status != "pending" || !runs[1].FinishedAt.IsZero() {
+		t.Errorf("r2 = %+v", runs[1])
+	}
+	if err := st.InsertHookRun(ctx, HookRun{ID: "r4"}); err != ErrInvalidArgument {
+		t.Errorf("InsertHookRun without action = %v, want ErrInvalidArgument", err)
+	}
+}
@@ -0,0 +1,130 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+const jumpHostColumns = `workspace, name, host, port, user, password_ct, ssh_key_ct, via, is_default, created_at, updated_at`
+
+func (d *db) UpsertJumpHost(ctx context.Context, j JumpHost) error {
+	if j.Name == "" || j.Host == "" {
+		return ErrInvalidArgument
+	}
+	j.Workspace = workspaceOrDefault(j.Workspace)
+	now := d.now()
+	if j.CreatedAt.IsZero() {
+		j.CreatedAt = now
+	}
+	if j.UpdatedAt.IsZero() {
+		j.UpdatedAt = now
+	}
+	passwordCT, err := d.encryptOptional(j.Password)
+	if err != nil {
+		return err
+	}
+	sshKeyCT, err := d.encryptOptional(j.SSHKey)
+	if err != nil {
+		return err
+	}
+
+	tx, err := d.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if j.Default {
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE jump_hosts SET is_default = 0 WHERE workspace = ? AND name <> ?
+		`, j.Workspace, j.Name); err != nil {
+			return err
+		}
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO jump_hosts(`+jumpHostColumns+`)
+		VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(workspace, name) DO UPDATE SET
+			host        = excluded.host,
+			port        = excluded.port,
+			user        = excluded.user,
+			password_ct = excluded.password_ct,
+			ssh_key_ct  = excluded.ssh_key_ct,
+			via         = excluded.via,
+			is_default  = excluded.is_default,
+			updated_at  = excluded.updated_at
+	`, j.Workspace, j.Name, j.Host, j.Port, j.User, passwordCT, sshKeyCT, j.Via, j.Default,
+		j.CreatedAt.Unix(), j.UpdatedAt.Unix())
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (d *db) GetJumpHost(ctx context.Context, workspace, name string) (JumpHost, bool, error) {
+	if name == "" {
+		return JumpHost{}, false, ErrInvalidArgument
+	}
+	row := d.conn.QueryRowContext(ctx, `
+		SELECT `+jumpHostColumns+` FROM jump_hosts WHERE workspace = ? AND name = ?
+	`, workspaceOrDefault(workspace), name)
+	j, err := d.scanJumpHost(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return JumpHost{}, false, nil
+	}
+	if err != nil {
+		return JumpHost{}, false, err
+	}
+	return j, true, nil
+}
+
+func (d *db) ListJumpHosts(ctx context.Context, workspace string) ([]JumpHost, error) {
+	rows, err := d.conn.QueryContext(ctx, `
+		SELECT `+jumpHostColumns+` FROM jump_hosts WHERE workspace = ? ORDER BY name
+	`, workspaceOrDefault(workspace))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []JumpHost
+	for rows.Next() {
+		j, err := d.scanJumpHost(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, j)
+	}
+	return out, rows.Err()
+}
+
+func (d *db) DeleteJumpHost(ctx context.Context, workspace, name string) error {
+	if name == "" {
+		return ErrInvalidArgument
+	}
+	_, err := d.conn.ExecContext(ctx, `DELETE FROM jump_hosts WHERE workspace = ? AND name = ?`,
+		workspaceOrDefault(workspace), name)
+	return err
+}
+
+func (d *db) scanJumpHost(sc interface{ Scan(...any) error }) (JumpHost, error) {
+	var j JumpHost
+	var passwordCT, sshKeyCT []byte
+	var createdAt, updatedAt int64
+	if err := sc.Scan(&j.Workspace, &j.Name, &j.Host, &j.Port, &j.User, &passwordCT, &sshKeyCT,
+		&j.Via, &j.Default, &createdAt, &updatedAt); err != nil {
+		return JumpHost{}, err
+	}
+	var err error
+	if j.Password, err = d.decryptOptional(passwordCT); err != nil {
+		return JumpHost{}, err
+	}
+	if j.SSHKey, err = d.decryptOptional(sshKeyCT); err != nil {
+		return JumpHost{}, err
+	}
+	j.CreatedAt = time.Unix(createdAt, 0)
+	j.UpdatedAt = time.Unix(updatedAt, 0)
+	return j, nil
+}
@@ -0,0 +1,67 @@
+package store
+
+import "testing"
+
+func TestJumpHosts(t *testing.T) {
+	st := newTestStore(t)
+	ctx := t.Context()
+
+	if err := st.UpsertJumpHost(ctx, JumpHost{Name: "gw"}); err == nil {
+		t.Error("UpsertJumpHost without host: expected error")
+	}
+
+	for _, j := range []JumpHost{
+		{Name: "gw", Host: "203.0.113.10", User: "jump", SSHKey: []byte("KEY"), Default: true},
+		{Name: "lab", Host: "10.1.0.1", Port: 2222, User: "lab", Password: []byte("secret"), Via: "gw"},
+		{Workspace: "lab2", Name: "gw", Host: "198.51.100.1", User: "other", Default: true},
+	} {
+		if err := st.UpsertJumpHost(ctx, j); err != nil {
+			t.Fatalf("UpsertJumpHost(%s): %v", j.Name, err)
+		}
+	}
+
+	lab, ok, err := st.GetJumpHost(ctx, "", "lab")
+	if err != nil || !ok {
+		t.Fatalf("GetJumpHost: ok=%v err=%v", ok, err)
+	}
+	if lab.Workspace != DefaultWorkspace || lab.Port != 2222 || lab.Via != "gw" || string(lab.Password) != "secret" || lab.Default {
+		t.Errorf("lab = %+v", lab)
+	}
+
+	// Making lab the default clears the flag on gw, but not in lab2.
+	lab.Default = true
+	if err := st.UpsertJumpHost(ctx, lab); err != nil {
+		t.Fatalf("UpsertJumpHost: %v", err)
+	}
+	list, err := st.ListJumpHosts(ctx, "")
+	if err != nil || len(list) != 2 {
+		t.Fatalf("ListJumpHosts = %+v, %v", list, err)
+	}
+	if list[0].Name != "gw" || list[0].Default || string(list[0].SSHKey) != "KEY" || !list[1].Default {
+		t.Errorf("jump hosts = %+v", list)
+	}
+	if other, _, _ := st.GetJumpHost(ctx, "lab2", "gw"); !other.Default || other.Host != "198.51.100.1" {
+		t.Errorf("lab2 gw = %+v", other)
+	}
+
+	if err := st.DeleteJumpHost(ctx, "", "gw"); err != nil {
+		t.Fatalf("DeleteJumpHost: %v", err)
+	}
+	if _, ok, _ := st.GetJumpHost(ctx, "", "gw"); ok {
+		t.Error("gw still exists after delete")
+	}
+	if _, ok, _ := st.GetJumpHost(ctx, "lab2", "gw"); !ok {
+		t.Error("delete removed lab2's gw")
+	}
+
+	// Nodes keep their jump host name.
+	if err := st.UpsertNode(ctx, Node{ID: "n1", Name: "node1", AnsibleHost: "10.1.0.5", NodeVars: NodeVars{JumpHost: "lab"}}); err != nil {
+		t.Fatalf("UpsertNode: %v", err)
+	}
+	if n, _, _ := st.GetNode(ctx, "n1"); n.JumpHost != "lab" {
+		t.Errorf("node JumpHost = %q", n.JumpHost)
+	}
+	if infos, _ := st.ListNodes(ctx, ""); len(infos) != 1 || infos[0].JumpHost != "lab" {
+		t.Errorf("ListNodes = %+v", infos)
+	}
+}
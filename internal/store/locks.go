@@ -0,0 +1,146 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ---------------------------------------------------------------------------
+// Locks
+// ---------------------------------------------------------------------------
+
+func (d *db) AcquireLock(ctx context.Context, l Lock) (Lock, error) {
+	if l.Name == "" || l.HolderID == "" || l.Kind == "" {
+		return Lock{}, ErrInvalidArgument
+	}
+	now := d.now()
+	if l.AcquiredAt.IsZero() {
+		l.AcquiredAt = now
+	}
+
+	tx, err := d.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return Lock{}, err
+	}
+	defer tx.Rollback()
+
+	cur, ok, err := getLockTx(ctx, tx, l.Name, now)
+	if err != nil {
+		return Lock{}, err
+	}
+	if ok && cur.HolderID != l.HolderID {
+		return cur, ErrLocked
+	}
+	if ok {
+		// Renewal keeps the original acquisition time.
+		l.AcquiredAt = cur.AcquiredAt
+	}
+
+	var expiresAt *int64
+	if !l.ExpiresAt.IsZero() {
+		v := l.ExpiresAt.Unix()
+		expiresAt = &v
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO locks(name, holder_id, kind, owner, reason, acquired_at, expires_at)
+		VALUES(?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(name) DO UPDATE SET
+			holder_id = excluded.holder_id,
+			kind = excluded.kind,
+			owner = excluded.owner,
+			reason = excluded.reason,
+			acquired_at = excluded.acquired_at,
+			expires_at = excluded.expires_at
+	`, l.Name, l.HolderID, l.Kind, l.Owner, l.Reason, l.AcquiredAt.Unix(), expiresAt); err != nil {
+		return Lock{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return Lock{}, err
+	}
+
+	l.AcquiredAt = time.Unix(l.AcquiredAt.Unix(), 0).UTC()
+	if !l.ExpiresAt.IsZero() {
+		l.ExpiresAt = time.Unix(l.ExpiresAt.Unix(), 0).UTC()
+	}
+	return l, nil
+}
+
+func (d *db) ReleaseLock(ctx context.Context, name, holderID string) error {
+	if name == "" || holderID == "" {
+		return ErrInvalidArgument
+	}
+	res, err := d.conn.ExecContext(ctx, `DELETE FROM locks WHERE name = ? AND holder_id = ?`, name, holderID)
+	if err != nil {
+		return err
+	}
+	n, _ := res.RowsAffected()
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (d *db) GetLock(ctx context.Context, name string) (Lock, bool, error) {
+	if name == "" {
+		return Lock{}, false, ErrInvalidArgument
+	}
+	tx, err := d.conn.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return Lock{}, false, err
+	}
+	defer tx.Rollback()
+	return getLockTx(ctx, tx, name, d.now())
+}
+
+func (d *db) BreakLock(ctx context.Context, name string) (Lock, bool, error) {
+	if name == "" {
+		return Lock{}, false, ErrInvalidArgument
+	}
+	tx, err := d.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return Lock{}, false, err
+	}
+	defer tx.Rollback()
+
+	cur, ok, err := getLockTx(ctx, tx, name, d.now())
+	if err != nil {
+		return Lock{}, false, err
+	}
+	// Expired rows are removed too so the table does not accumulate them.
+	if _, err := tx.ExecContext(ctx, `DELETE FROM locks WHERE name = ?`, name); err != nil {
+		return Lock{}, false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return Lock{}, false, err
+	}
+	return cur, ok, nil
+}
+
+// getLockTx loads a lock row, treating expired locks as absent.
+func getLockTx(ctx context.Context, tx *sql.Tx, name string, now time.Time) (Lock, bool, error) {
+	var (
+		l          Lock
+		acquiredAt int64
+		expiresAt  sql.NullInt64
+	)
+	err := tx.QueryRowContext(ctx, `
+		SELECT name, holder_id, kind, owner, reason, acquired_at, expires_at
+		FROM locks WHERE name = ?
+	`, name).Scan(&l.Name, &l.HolderID, &l.Kind, &l.Owner, &l.Reason, &acquiredAt, &expiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Lock{}, false, nil
+	}
+	if err != nil {
+		return Lock{}, false, err
+	}
+	l.AcquiredAt = time.Unix(acquiredAt, 0).UTC()
+	if expiresAt.Valid {
+		l.ExpiresAt = time.Unix(expiresAt.Int64, 0).UTC()
+		if !l.ExpiresAt.After(now) {
+			return Lock{}, false, nil
+		}
+	}
+	return l, true, nil
+}
@@ -0,0 +1,139 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAcquireLock_RoundTrip(t *testing.T) {
+	st := newTestStore(t)
+	ctx := t.Context()
+
+	exp := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	got, err := st.AcquireLock(ctx, Lock{
+		Name:      ChangeLockName,
+		HolderID:  "h1",
+		Kind:      LockKindMaintenance,
+		Owner:     "alice",
+		Reason:    "kernel upgrade",
+		ExpiresAt: exp,
+	})
+	if err != nil {
+		t.Fatalf("AcquireLock: %v", err)
+	}
+	if got.AcquiredAt.IsZero() {
+		t.Error("expected non-zero AcquiredAt")
+	}
+
+	l, ok, err := st.GetLock(ctx, ChangeLockName)
+	if err != nil || !ok {
+		t.Fatalf("GetLock: ok=%v err=%v", ok, err)
+	}
+	if l.HolderID != "h1" || l.Owner != "alice" || l.Reason != "kernel upgrade" || l.Kind != LockKindMaintenance {
+		t.Errorf("GetLock = %+v", l)
+	}
+	if !l.ExpiresAt.Equal(exp) {
+		t.Errorf("ExpiresAt = %v, want %v", l.ExpiresAt, exp)
+	}
+}
+
+func TestAcquireLock_Conflict(t *testing.T) {
+	st := newTestStore(t)
+	ctx := t.Context()
+
+	if _, err := st.AcquireLock(ctx, Lock{Name: "x", HolderID: "h1", Kind: LockKindDeployment}); err != nil {
+		t.Fatalf("AcquireLock h1: %v", err)
+	}
+	cur, err := st.AcquireLock(ctx, Lock{Name: "x", HolderID: "h2", Kind: LockKindMaintenance})
+	if err != ErrLocked {
+		t.Fatalf("AcquireLock h2 err = %v, want ErrLocked", err)
+	}
+	if cur.HolderID != "h1" {
+		t.Errorf("conflicting holder = %q, want h1", cur.HolderID)
+	}
+
+	// The same holder can renew.
+	if _, err := st.AcquireLock(ctx, Lock{Name: "x", HolderID: "h1", Kind: LockKindDeployment, Reason: "renewed"}); err != nil {
+		t.Fatalf("renew: %v", err)
+	}
+	l, _, _ := st.GetLock(ctx, "x")
+	if l.Reason != "renewed" {
+		t.Errorf("Reason after renew = %q, want renewed", l.Reason)
+	}
+}
+
+func TestAcquireLock_Expired(t *testing.T) {
+	st := newTestStore(t)
+	ctx := t.Context()
+
+	if _, err := st.AcquireLock(ctx, Lock{
+		Name:      "x",
+		HolderID:  "h1",
+		Kind:      LockKindMaintenance,
+		ExpiresAt: time.Now().Add(-time.Minute),
+	}); err != nil {
+		t.Fatalf("AcquireLock: %v", err)
+	}
+
+	if _, ok, _ := st.GetLock(ctx, "x"); ok {
+		t.Error("expired lock should not be returned")
+	}
+	if _, err := st.AcquireLock(ctx, Lock{Name: "x", HolderID: "h2", Kind: LockKindMaintenance}); err != nil {
+		t.Fatalf("AcquireLock over expired lock: %v", err)
+	}
+}
+
+func TestReleaseLock(t *testing.T) {
+	st := newTestStore(t)
+	ctx := t.Context()
+
+	if _, err := st.AcquireLock(ctx, Lock{Name: "x", HolderID: "h1", Kind: LockKindMaintenance}); err != nil {
+		t.Fatalf("AcquireLock: %v", err)
+	}
+	if err := st.ReleaseLock(ctx, "x", "wrong"); err != ErrNotFound {
+		t.Errorf("ReleaseLock wrong holder = %v, want ErrNotFound", err)
+	}
+	if err := st.ReleaseLock(ctx, "x", "h1"); err != nil {
+		t.Fatalf("ReleaseLock: %v", err)
+	}
+	if _, ok, _ := st.GetLock(ctx, "x"); ok {
+		t.Error("lock still held after release")
+	}
+}
+
+func TestBreakLock(t *testing.T) {
+	st := newTestStore(t)
+	ctx := t.Context()
+
+	if _, ok, err := st.BreakLock(ctx, "x"); err != nil || ok {
+		t.Fatalf("BreakLock on free lock: ok=%v err=%v", ok, err)
+	}
+	if _, err := st.AcquireLock(ctx, Lock{Name: "x", HolderID: "h1", Kind: LockKindDeployment}); err != nil {
+		t.Fatalf("AcquireLock: %v", err)
+	}
+	broken, ok, err := st.BreakLock(ctx, "x")
+	if err != nil || !ok {
+		t.Fatalf("BreakLock: ok=%v err=%v", ok, err)
+	}
+	if broken.HolderID != "h1" {
+		t.Errorf("broken holder = %q, want h1", broken.HolderID)
+	}
+	if _, ok, _ := st.GetLock(ctx, "x"); ok {
+		t.Error("lock still held after break")
+	}
+}
+
+func TestAcquireLock_Validation(t *testing.T) {
+	st := newTestStore(t)
+	ctx := t.Context()
+
+	for _, l := range []Lock{
+		{HolderID: "h", Kind: LockKindMaintenance},
+		{Name: "x", Kind: LockKindMaintenance},
+		{Name: "x", HolderID: "h"},
+	} {
+		if _, err := st.AcquireLock(ctx, l); err != ErrInvalidArgument {
+			t.Errorf("AcquireLock(%+v) = %v, want ErrInvalidArgument", l, err)
+		}
+	}
+}
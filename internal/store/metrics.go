@@ -83,7 +83,9 @@ func (d *db) QueryRange(ctx context.Context, q RangeQuery) ([]Series, error) {
 	labelsNorm, labelsHash := NormalizeLabels(q.LabelsExact)
 
 	// Current minimal behavior:
-	// - If LabelsExact is provided, match exact labels_hash+labels_norm.
+	// - Match exact labels_hash+labels_norm. Without LabelsExact only
+	//   unlabeled series match, so series that differ by a label such as
+	//   node are never averaged together.
 	// - GroupBy ignored.
 	// - One series returned.
 	sec, err := bucketSeconds(q.Agg)
@@ -102,24 +104,14 @@ func (d *db) QueryRange(ctx context.Context, q RangeQuery) ([]Series, error) {
 	var rows *sql.Rows
 
 	if q.Agg == AggRaw {
-		if len(q.LabelsExact) > 0 {
-			rows, err = d.conn.QueryContext(ctx, `
-				SELECT ts, value
-				FROM metrics_samples
-				WHERE metric = ? AND ts >= ? AND ts <= ?
-				  AND labels_hash = ? AND labels_norm = ?
-				ORDER BY ts
-				LIMIT ?
-			`, q.Metric, from, to, labelsHash, labelsNorm, limit)
-		} else {
-			rows, err = d.conn.QueryContext(ctx, `
-				SELECT ts, value
-				FROM metrics_samples
-				WHERE metric = ? AND ts >= ? AND ts <= ?
-				ORDER BY ts
-				LIMIT ?
-			`, q.Metric, from, to, limit)
-		}
+		rows, err = d.conn.QueryContext(ctx, `
+			SELECT ts, value
+			FROM metrics_samples
+			WHERE metric = ? AND ts >= ? AND ts <= ?
+			  AND labels_hash = ? AND labels_norm = ?
+			ORDER BY ts
+			LIMIT ?
+		`, q.Metric, from, to, labelsHash, labelsNorm, limit)
 		if err != nil {
 			return nil, err
 		}
@@ -145,26 +137,15 @@ func (d *db) QueryRange(ctx context.Context, q RangeQuery) ([]Series, error) {
 
 	// Aggregated by time bucket: avg(value) per bucket
 	// bucket_ts = (ts / sec) * sec
-	if len(q.LabelsExact) > 0 {
-		rows, err = d.conn.QueryContext(ctx, `
-			SELECT (ts / ?) * ? AS bucket_ts, AVG(value) AS avg_value
-			FROM metrics_samples
-			WHERE metric = ? AND ts >= ? AND ts <= ?
-			  AND labels_hash = ? AND labels_norm = ?
-			GROUP BY bucket_ts
-			ORDER BY bucket_ts
-			LIMIT ?
-		`, sec, sec, q.Metric, from, to, labelsHash, labelsNorm, limit)
-	} else {
-		rows, err = d.conn.QueryContext(ctx, `
-			SELECT (ts / ?) * ? AS bucket_ts, AVG(value) AS avg_value
-			FROM metrics_samples
-			WHERE metric = ? AND ts >= ? AND ts <= ?
-			GROUP BY bucket_ts
-			ORDER BY bucket_ts
-			LIMIT ?
-		`, sec, sec, q.Metric, from, to, limit)
-	}
+	rows, err = d.conn.QueryContext(ctx, `
+		SELECT (ts / ?) * ? AS bucket_ts, AVG(value) AS avg_value
+		FROM metrics_samples
+		WHERE metric = ? AND ts >= ? AND ts <= ?
+		  AND labels_hash = ? AND labels_norm = ?
+		GROUP BY bucket_ts
+		ORDER BY bucket_ts
+		LIMIT ?
+	`, sec, sec, q.Metric, from, to, labelsHash, labelsNorm, limit)
 	if err != nil {
 		return nil, err
 	}
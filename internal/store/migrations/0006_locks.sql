@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS locks (
+    name        TEXT PRIMARY KEY,
+    holder_id   TEXT NOT NULL,
+    kind        TEXT NOT NULL,
+    owner       TEXT NOT NULL DEFAULT '',
+    reason      TEXT NOT NULL DEFAULT '',
+    acquired_at INTEGER NOT NULL,
+    expires_at  INTEGER
+);
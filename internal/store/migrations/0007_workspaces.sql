@@ -0,0 +1,67 @@
+-- workspaces holds named OnRamp checkouts beyond the built-in "default"
+-- workspace, which is configured by command-line flags.
+CREATE TABLE IF NOT EXISTS workspaces (
+    name       TEXT PRIMARY KEY,
+    onramp_dir TEXT NOT NULL,
+    repo_url   TEXT NOT NULL,
+    version    TEXT NOT NULL,
+    created_at INTEGER NOT NULL
+);
+
+-- Node names are unique per workspace rather than globally. SQLite cannot
+-- alter a UNIQUE constraint, so nodes and node_roles are rebuilt. node_roles
+-- is recreated against the new table before the old one is dropped so the
+-- ON DELETE CASCADE does not fire.
+CREATE TABLE nodes_new (
+    id           TEXT PRIMARY KEY,
+    workspace    TEXT NOT NULL DEFAULT 'default',
+    name         TEXT NOT NULL,
+    ansible_host TEXT NOT NULL,
+    ansible_user TEXT NOT NULL DEFAULT '',
+    password_ct  BLOB,
+    sudo_pass_ct BLOB,
+    ssh_key_ct   BLOB,
+    created_at   INTEGER NOT NULL,
+    updated_at   INTEGER NOT NULL,
+    UNIQUE(workspace, name)
+);
+INSERT INTO nodes_new(id, name, ansible_host, ansible_user, password_ct, sudo_pass_ct, ssh_key_ct, created_at, updated_at)
+    SELECT id, name, ansible_host, ansible_user, password_ct, sudo_pass_ct, ssh_key_ct, created_at, updated_at FROM nodes;
+
+CREATE TABLE node_roles_new (
+    node_id TEXT NOT NULL REFERENCES nodes_new(id) ON DELETE CASCADE,
+    role    TEXT NOT NULL,
+    UNIQUE(node_id, role)
+);
+INSERT INTO node_roles_new(node_id, role) SELECT node_id, role FROM node_roles;
+
+DROP TABLE node_roles;
+DROP TABLE nodes;
+ALTER TABLE nodes_new RENAME TO nodes;
+ALTER TABLE node_roles_new RENAME TO node_roles;
+
+CREATE INDEX IF NOT EXISTS idx_nodes_workspace ON nodes(workspace);
+CREATE INDEX IF NOT EXISTS idx_node_roles_node_id ON node_roles(node_id);
+CREATE INDEX IF NOT EXISTS idx_node_roles_role ON node_roles(role);
+
+-- Existing history belongs to the default workspace.
+ALTER TABLE action_history ADD COLUMN workspace TEXT NOT NULL DEFAULT 'default';
+CREATE INDEX IF NOT EXISTS idx_ah_workspace ON action_history(workspace);
+
+ALTER TABLE deployments ADD COLUMN workspace TEXT NOT NULL DEFAULT 'default';
+CREATE INDEX IF NOT EXISTS idx_deployments_workspace ON deployments(workspace);
+
+-- component_state is keyed by (workspace, component).
+CREATE TABLE component_state_new (
+    workspace   TEXT NOT NULL DEFAULT 'default',
+    component   TEXT NOT NULL,
+    status      TEXT NOT NULL,
+    last_action TEXT,
+    action_id   TEXT,
+    updated_at  INTEGER NOT NULL,
+    PRIMARY KEY (workspace, component)
+);
+INSERT INTO component_state_new(component, status, last_action, action_id, updated_at)
+    SELECT component, status, last_action, action_id, updated_at FROM component_state;
+DROP TABLE component_state;
+ALTER TABLE component_state_new RENAME TO component_state;
@@ -0,0 +1,14 @@
+-- config_revisions records every write to a workspace's vars/main.yml so
+-- changes can be audited, compared, and rolled back.
+CREATE TABLE IF NOT EXISTS config_revisions (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    workspace  TEXT NOT NULL DEFAULT 'default',
+    source     TEXT NOT NULL,
+    note       TEXT NOT NULL DEFAULT '',
+    author     TEXT NOT NULL DEFAULT '',
+    hash       TEXT NOT NULL,
+    content    BLOB NOT NULL,
+    created_at INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_config_revisions_workspace ON config_revisions(workspace, id);
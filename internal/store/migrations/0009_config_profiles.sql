@@ -0,0 +1,13 @@
+-- config_profiles holds OnRamp config profiles saved through the API, so
+-- they survive a reset or replacement of the OnRamp checkout.
+CREATE TABLE IF NOT EXISTS config_profiles (
+    workspace   TEXT NOT NULL DEFAULT 'default',
+    name        TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    author      TEXT NOT NULL DEFAULT '',
+    hash        TEXT NOT NULL,
+    content     BLOB NOT NULL,
+    created_at  INTEGER NOT NULL,
+    updated_at  INTEGER NOT NULL,
+    PRIMARY KEY (workspace, name)
+);
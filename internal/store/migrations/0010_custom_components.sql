@@ -0,0 +1,14 @@
+-- custom_components holds user-defined components whose actions run site
+-- make targets, playbooks or scripts beside the OnRamp components. roles and
+-- actions are JSON arrays.
+CREATE TABLE IF NOT EXISTS custom_components (
+    workspace   TEXT NOT NULL DEFAULT 'default',
+    name        TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    tier        INTEGER NOT NULL DEFAULT 0,
+    roles       TEXT NOT NULL DEFAULT '[]',
+    actions     TEXT NOT NULL DEFAULT '[]',
+    created_at  INTEGER NOT NULL,
+    updated_at  INTEGER NOT NULL,
+    PRIMARY KEY (workspace, name)
+);
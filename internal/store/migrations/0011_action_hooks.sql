@@ -0,0 +1,37 @@
+-- action_hooks holds commands run before, after, or on failure of a
+-- component action. An empty action matches every action of the component.
+-- args is a JSON array.
+CREATE TABLE IF NOT EXISTS action_hooks (
+    id          TEXT PRIMARY KEY,
+    workspace   TEXT NOT NULL DEFAULT 'default',
+    component   TEXT NOT NULL,
+    action      TEXT NOT NULL DEFAULT '',
+    stage       TEXT NOT NULL,
+    name        TEXT NOT NULL,
+    kind        TEXT NOT NULL,
+    target      TEXT NOT NULL,
+    args        TEXT NOT NULL DEFAULT '[]',
+    position    INTEGER NOT NULL DEFAULT 0,
+    created_at  INTEGER NOT NULL,
+    updated_at  INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_action_hooks_component ON action_hooks(workspace, component);
+
+-- hook_runs records each hook execution. id is the hook's task ID and
+-- action_id the action_history row of the action it ran for.
+CREATE TABLE IF NOT EXISTS hook_runs (
+    id          TEXT PRIMARY KEY,
+    action_id   TEXT NOT NULL,
+    hook_id     TEXT NOT NULL,
+    stage       TEXT NOT NULL,
+    name        TEXT NOT NULL,
+    target      TEXT NOT NULL,
+    status      TEXT NOT NULL,
+    exit_code   INTEGER NOT NULL DEFAULT -1,
+    error       TEXT,
+    started_at  INTEGER NOT NULL,
+    finished_at INTEGER
+);
+
+CREATE INDEX IF NOT EXISTS idx_hook_runs_action ON hook_runs(action_id);
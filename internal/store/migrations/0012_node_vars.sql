@@ -0,0 +1,17 @@
+-- Per-node Ansible connection settings and inventory variables. host_vars is
+-- a JSON object of string values; ansible_port 0 leaves Ansible's default.
+ALTER TABLE nodes ADD COLUMN ansible_port INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE nodes ADD COLUMN python_interpreter TEXT NOT NULL DEFAULT '';
+ALTER TABLE nodes ADD COLUMN ssh_common_args TEXT NOT NULL DEFAULT '';
+ALTER TABLE nodes ADD COLUMN become_method TEXT NOT NULL DEFAULT '';
+ALTER TABLE nodes ADD COLUMN host_vars TEXT NOT NULL DEFAULT '{}';
+
+-- group_vars holds the [<role>_nodes:vars] section of a workspace's
+-- hosts.ini as a JSON object of string values.
+CREATE TABLE IF NOT EXISTS group_vars (
+    workspace  TEXT NOT NULL DEFAULT 'default',
+    role       TEXT NOT NULL,
+    vars       TEXT NOT NULL DEFAULT '{}',
+    updated_at INTEGER NOT NULL,
+    PRIMARY KEY (workspace, role)
+);
@@ -0,0 +1,26 @@
+-- node_health holds the latest health probe of each node. since is when the
+-- node entered status and last_seen when an SSH login last succeeded.
+CREATE TABLE IF NOT EXISTS node_health (
+    node_id    TEXT PRIMARY KEY REFERENCES nodes(id) ON DELETE CASCADE,
+    status     TEXT NOT NULL,
+    tcp_ok     INTEGER NOT NULL DEFAULT 0,
+    ssh_ok     INTEGER NOT NULL DEFAULT 0,
+    sudo_ok    INTEGER NOT NULL DEFAULT 0,
+    latency_ms INTEGER NOT NULL DEFAULT 0,
+    error      TEXT NOT NULL DEFAULT '',
+    checked_at INTEGER NOT NULL,
+    since      INTEGER NOT NULL,
+    last_seen  INTEGER
+);
+
+-- node_health_events records each change of a node's health status.
+CREATE TABLE IF NOT EXISTS node_health_events (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    node_id     TEXT NOT NULL REFERENCES nodes(id) ON DELETE CASCADE,
+    from_status TEXT NOT NULL,
+    to_status   TEXT NOT NULL,
+    error       TEXT NOT NULL DEFAULT '',
+    at          INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_node_health_events_node ON node_health_events(node_id, at);
@@ -0,0 +1,30 @@
+-- node_facts keeps each distinct version of a node's gathered facts. A new
+-- version is added only when a gather finds different facts; otherwise the
+-- latest version's last_gathered_at is advanced. changes holds the JSON diff
+-- from the previous version.
+CREATE TABLE IF NOT EXISTS node_facts (
+    id               INTEGER PRIMARY KEY AUTOINCREMENT,
+    node_id          TEXT NOT NULL REFERENCES nodes(id) ON DELETE CASCADE,
+    facts            TEXT NOT NULL,
+    changes          TEXT,
+    gathered_at      INTEGER NOT NULL,
+    last_gathered_at INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_node_facts_node ON node_facts(node_id, id);
+
+-- config_defaults records the config values last set by the config defaults
+-- engine, with the node and facts version each was computed from, so values
+-- whose facts have since changed can be flagged.
+CREATE TABLE IF NOT EXISTS config_defaults (
+    workspace  TEXT NOT NULL,
+    field      TEXT NOT NULL,
+    value      TEXT NOT NULL,
+    node_id    TEXT NOT NULL REFERENCES nodes(id) ON DELETE CASCADE,
+    facts_id   INTEGER NOT NULL,
+    applied_at INTEGER NOT NULL,
+    PRIMARY KEY (workspace, field)
+);
+
+-- Facts were cached as objects before they had their own table.
+DELETE FROM objects WHERE namespace = '_nodefacts';
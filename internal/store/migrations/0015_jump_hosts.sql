@@ -0,0 +1,22 @@
+-- jump_hosts holds the SSH bastions a workspace's nodes are reached through,
+-- with their own credentials. via names the jump host this one is reached
+-- through, so hops can be chained; is_default marks the one used by nodes
+-- that name none.
+CREATE TABLE IF NOT EXISTS jump_hosts (
+    workspace   TEXT NOT NULL DEFAULT 'default',
+    name        TEXT NOT NULL,
+    host        TEXT NOT NULL,
+    port        INTEGER NOT NULL DEFAULT 0,
+    user        TEXT NOT NULL DEFAULT '',
+    password_ct BLOB,
+    ssh_key_ct  BLOB,
+    via         TEXT NOT NULL DEFAULT '',
+    is_default  INTEGER NOT NULL DEFAULT 0,
+    created_at  INTEGER NOT NULL,
+    updated_at  INTEGER NOT NULL,
+    PRIMARY KEY (workspace, name)
+);
+
+-- A node's jump host by name; empty uses the workspace default and "none"
+-- connects directly.
+ALTER TABLE nodes ADD COLUMN jump_host TEXT NOT NULL DEFAULT '';
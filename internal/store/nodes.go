@@ -3,14 +3,30 @@ package store
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"time"
 )
 
-func (d *db) UpsertNode(ctx context.Context, node Node) error {
+// execer is a database connection or transaction.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// nodeRow is a node ready to be written, with its secrets encrypted.
+type nodeRow struct {
+	Node
+	passwordCT, sudoPassCT, sshKeyCT []byte
+	hostVarsJSON                     string
+}
+
+// prepareNode validates a node, fills in its defaults and encrypts its
+// secrets.
+func (d *db) prepareNode(node Node) (nodeRow, error) {
 	if node.ID == "" || node.Name == "" || node.AnsibleHost == "" {
-		return ErrInvalidArgument
+		return nodeRow{}, ErrInvalidArgument
 	}
 
+	node.Workspace = workspaceOrDefault(node.Workspace)
 	now := d.now()
 	if node.CreatedAt.IsZero() {
 		node.CreatedAt = now
@@ -19,15 +35,27 @@ func (d *db) UpsertNode(ctx context.Context, node Node) error {
 		node.UpdatedAt = now
 	}
 
-	passwordCT, err := d.encryptOptional(node.Password)
-	if err != nil {
-		return err
+	r := nodeRow{Node: node}
+	var err error
+	if r.passwordCT, err = d.encryptOptional(node.Password); err != nil {
+		return nodeRow{}, err
+	}
+	if r.sudoPassCT, err = d.encryptOptional(node.SudoPassword); err != nil {
+		return nodeRow{}, err
 	}
-	sudoPassCT, err := d.encryptOptional(node.SudoPassword)
+	if r.sshKeyCT, err = d.encryptOptional(node.SSHKey); err != nil {
+		return nodeRow{}, err
+	}
+	hostVarsJSON, err := json.Marshal(nonNilMap(node.HostVars))
 	if err != nil {
-		return err
+		return nodeRow{}, err
 	}
-	sshKeyCT, err := d.encryptOptional(node.SSHKey)
+	r.hostVarsJSON = string(hostVarsJSON)
+	return r, nil
+}
+
+func (d *db) UpsertNode(ctx context.Context, node Node) error {
+	r, err := d.prepareNode(node)
 	if err != nil {
 		return err
 	}
@@ -38,33 +66,100 @@ func (d *db) UpsertNode(ctx context.Context, node Node) error {
 	}
 	defer tx.Rollback()
 
-	_, err = tx.ExecContext(ctx, `
-		INSERT INTO nodes(id, name, ansible_host, ansible_user, password_ct, sudo_pass_ct, ssh_key_ct, created_at, updated_at)
-		VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?)
+	if err := upsertNodeTx(ctx, tx, r); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// upsertNodeTx writes a node and replaces its roles.
+func upsertNodeTx(ctx context.Context, tx *sql.Tx, r nodeRow) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO nodes(id, workspace, name, ansible_host, ansible_user, password_ct, sudo_pass_ct, ssh_key_ct,
+			ansible_port, python_interpreter, ssh_common_args, become_method, host_vars, jump_host, created_at, updated_at)
+		VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		ON CONFLICT(id) DO UPDATE SET
+			workspace = excluded.workspace,
 			name = excluded.name,
 			ansible_host = excluded.ansible_host,
 			ansible_user = excluded.ansible_user,
 			password_ct = excluded.password_ct,
 			sudo_pass_ct = excluded.sudo_pass_ct,
 			ssh_key_ct = excluded.ssh_key_ct,
+			ansible_port = excluded.ansible_port,
+			python_interpreter = excluded.python_interpreter,
+			ssh_common_args = excluded.ssh_common_args,
+			become_method = excluded.become_method,
+			host_vars = excluded.host_vars,
+			jump_host = excluded.jump_host,
 			updated_at = excluded.updated_at
-	`, node.ID, node.Name, node.AnsibleHost, node.AnsibleUser,
-		passwordCT, sudoPassCT, sshKeyCT,
-		node.CreatedAt.Unix(), node.UpdatedAt.Unix())
+	`, r.ID, r.Workspace, r.Name, r.AnsibleHost, r.AnsibleUser,
+		r.passwordCT, r.sudoPassCT, r.sshKeyCT,
+		r.AnsiblePort, r.PythonInterpreter, r.SSHCommonArgs, r.BecomeMethod, r.hostVarsJSON, r.JumpHost,
+		r.CreatedAt.Unix(), r.UpdatedAt.Unix())
 	if err != nil {
 		return err
 	}
 
-	if _, err := tx.ExecContext(ctx, `DELETE FROM node_roles WHERE node_id = ?`, node.ID); err != nil {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM node_roles WHERE node_id = ?`, r.ID); err != nil {
 		return err
 	}
-	for _, role := range node.Roles {
-		if _, err := tx.ExecContext(ctx, `INSERT INTO node_roles(node_id, role) VALUES(?, ?)`, node.ID, role); err != nil {
+	for _, role := range r.Roles {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO node_roles(node_id, role) VALUES(?, ?)`, r.ID, role); err != nil {
 			return err
 		}
 	}
+	return nil
+}
 
+// ApplyNodes makes the changes of b to a workspace in one transaction:
+// deletes first, so a batch can reuse a removed node's name, then upserts,
+// then group vars. Nodes are moved into the workspace; deletes only remove
+// nodes that are in it.
+func (d *db) ApplyNodes(ctx context.Context, workspace string, b NodeBatch) error {
+	workspace = workspaceOrDefault(workspace)
+	rows := make([]nodeRow, len(b.Upsert))
+	for i, node := range b.Upsert {
+		node.Workspace = workspace
+		r, err := d.prepareNode(node)
+		if err != nil {
+			return err
+		}
+		rows[i] = r
+	}
+	for _, id := range b.Delete {
+		if id == "" {
+			return ErrInvalidArgument
+		}
+	}
+	for role := range b.GroupVars {
+		if role == "" {
+			return ErrInvalidArgument
+		}
+	}
+
+	tx, err := d.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, id := range b.Delete {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM nodes WHERE id = ? AND workspace = ?`, id, workspace); err != nil {
+			return err
+		}
+	}
+	for _, r := range rows {
+		if err := upsertNodeTx(ctx, tx, r); err != nil {
+			return err
+		}
+	}
+	now := d.now()
+	for role, vars := range b.GroupVars {
+		if err := setGroupVars(ctx, tx, workspace, role, vars, now); err != nil {
+			return err
+		}
+	}
 	return tx.Commit()
 }
 
@@ -73,14 +168,19 @@ func (d *db) GetNode(ctx context.Context, id string) (Node, bool, error) {
 		return Node{}, false, ErrInvalidArgument
 	}
 
-	var name, ansibleHost, ansibleUser string
+	var workspace, name, ansibleHost, ansibleUser string
 	var passwordCT, sudoPassCT, sshKeyCT []byte
+	var vars NodeVars
+	var hostVarsJSON string
 	var createdAtUnix, updatedAtUnix int64
 
 	err := d.conn.QueryRowContext(ctx, `
-		SELECT name, ansible_host, ansible_user, password_ct, sudo_pass_ct, ssh_key_ct, created_at, updated_at
+		SELECT workspace, name, ansible_host, ansible_user, password_ct, sudo_pass_ct, ssh_key_ct,
+			ansible_port, python_interpreter, ssh_common_args, become_method, host_vars, jump_host, created_at, updated_at
 		FROM nodes WHERE id = ?
-	`, id).Scan(&name, &ansibleHost, &ansibleUser, &passwordCT, &sudoPassCT, &sshKeyCT, &createdAtUnix, &updatedAtUnix)
+	`, id).Scan(&workspace, &name, &ansibleHost, &ansibleUser, &passwordCT, &sudoPassCT, &sshKeyCT,
+		&vars.AnsiblePort, &vars.PythonInterpreter, &vars.SSHCommonArgs, &vars.BecomeMethod, &hostVarsJSON, &vars.JumpHost,
+		&createdAtUnix, &updatedAtUnix)
 
 	if err == sql.ErrNoRows {
 		return Node{}, false, nil
@@ -106,9 +206,13 @@ func (d *db) GetNode(ctx context.Context, id string) (Node, bool, error) {
 	if err != nil {
 		return Node{}, false, err
 	}
+	if vars.HostVars, err = decodeHostVars(hostVarsJSON); err != nil {
+		return Node{}, false, err
+	}
 
 	return Node{
 		ID:           id,
+		Workspace:    workspace,
 		Name:         name,
 		AnsibleHost:  ansibleHost,
 		AnsibleUser:  ansibleUser,
@@ -116,6 +220,7 @@ func (d *db) GetNode(ctx context.Context, id string) (Node, bool, error) {
 		SudoPassword: sudoPass,
 		SSHKey:       sshKey,
 		Roles:        roles,
+		NodeVars:     vars,
 		CreatedAt:    time.Unix(createdAtUnix, 0),
 		UpdatedAt:    time.Unix(updatedAtUnix, 0),
 	}, true, nil
@@ -129,11 +234,13 @@ func (d *db) DeleteNode(ctx context.Context, id string) error {
 	return err
 }
 
-func (d *db) ListNodes(ctx context.Context) ([]NodeInfo, error) {
+func (d *db) ListNodes(ctx context.Context, workspace string) ([]NodeInfo, error) {
+	workspace = workspaceOrDefault(workspace)
 	rows, err := d.conn.QueryContext(ctx, `
-		SELECT id, name, ansible_host, ansible_user, created_at, updated_at
-		FROM nodes ORDER BY name
-	`)
+		SELECT id, name, ansible_host, ansible_user,
+			ansible_port, python_interpreter, ssh_common_args, become_method, host_vars, jump_host, created_at, updated_at
+		FROM nodes WHERE workspace = ? ORDER BY name
+	`, workspace)
 	if err != nil {
 		return nil, err
 	}
@@ -141,31 +248,51 @@ func (d *db) ListNodes(ctx context.Context) ([]NodeInfo, error) {
 
 	out := make([]NodeInfo, 0, 32)
 	for rows.Next() {
-		var id, name, ansibleHost, ansibleUser string
+		var info NodeInfo
+		var hostVarsJSON string
 		var createdAtUnix, updatedAtUnix int64
 
-		if err := rows.Scan(&id, &name, &ansibleHost, &ansibleUser, &createdAtUnix, &updatedAtUnix); err != nil {
+		if err := rows.Scan(&info.ID, &info.Name, &info.AnsibleHost, &info.AnsibleUser,
+			&info.AnsiblePort, &info.PythonInterpreter, &info.SSHCommonArgs, &info.BecomeMethod, &hostVarsJSON, &info.JumpHost,
+			&createdAtUnix, &updatedAtUnix); err != nil {
 			return nil, err
 		}
 
-		roles, err := d.nodeRoles(ctx, id)
-		if err != nil {
+		if info.Roles, err = d.nodeRoles(ctx, info.ID); err != nil {
 			return nil, err
 		}
-
-		out = append(out, NodeInfo{
-			ID:          id,
-			Name:        name,
-			AnsibleHost: ansibleHost,
-			AnsibleUser: ansibleUser,
-			Roles:       roles,
-			CreatedAt:   time.Unix(createdAtUnix, 0),
-			UpdatedAt:   time.Unix(updatedAtUnix, 0),
-		})
+		if info.HostVars, err = decodeHostVars(hostVarsJSON); err != nil {
+			return nil, err
+		}
+		info.Workspace = workspace
+		info.CreatedAt = time.Unix(createdAtUnix, 0)
+		info.UpdatedAt = time.Unix(updatedAtUnix, 0)
+		out = append(out, info)
 	}
 	return out, rows.Err()
 }
 
+// nonNilMap returns m, or an empty map so it encodes as {} rather than null.
+func nonNilMap(m map[string]string) map[string]string {
+	if m == nil {
+		return map[string]string{}
+	}
+	return m
+}
+
+// decodeHostVars decodes a host_vars or group_vars column; an empty object
+// is returned as nil.
+func decodeHostVars(s string) (map[string]string, error) {
+	var vars map[string]string
+	if err := json.Unmarshal([]byte(s), &vars); err != nil {
+		return nil, err
+	}
+	if len(vars) == 0 {
+		return nil, nil
+	}
+	return vars, nil
+}
+
 // nodeRoles queries the node_roles table for the given node ID.
 func (d *db) nodeRoles(ctx context.Context, nodeID string) ([]string, error) {
 	rows, err := d.conn.QueryContext(ctx, `SELECT role FROM node_roles WHERE node_id = ? ORDER BY role`, nodeID)
@@ -200,3 +327,56 @@ func (d *db) decryptOptional(ciphertext []byte) ([]byte, error) {
 	}
 	return d.crypter.Decrypt(ciphertext)
 }
+
+// ---------------------------------------------------------------------------
+// Group vars
+// ---------------------------------------------------------------------------
+
+func (d *db) SetGroupVars(ctx context.Context, workspace, role string, vars map[string]string) error {
+	if role == "" {
+		return ErrInvalidArgument
+	}
+	return setGroupVars(ctx, d.conn, workspaceOrDefault(workspace), role, vars, d.now())
+}
+
+// setGroupVars replaces or, for empty vars, removes a role's group vars.
+func setGroupVars(ctx context.Context, ex execer, workspace, role string, vars map[string]string, now time.Time) error {
+	if len(vars) == 0 {
+		_, err := ex.ExecContext(ctx, `DELETE FROM group_vars WHERE workspace = ? AND role = ?`, workspace, role)
+		return err
+	}
+	varsJSON, err := json.Marshal(vars)
+	if err != nil {
+		return err
+	}
+	_, err = ex.ExecContext(ctx, `
+		INSERT INTO group_vars(workspace, role, vars, updated_at) VALUES(?, ?, ?, ?)
+		ON CONFLICT(workspace, role) DO UPDATE SET vars = excluded.vars, updated_at = excluded.updated_at
+	`, workspace, role, string(varsJSON), now.Unix())
+	return err
+}
+
+func (d *db) ListGroupVars(ctx context.Context, workspace string) (map[string]map[string]string, error) {
+	rows, err := d.conn.QueryContext(ctx, `SELECT role, vars FROM group_vars WHERE workspace = ? ORDER BY role`,
+		workspaceOrDefault(workspace))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make(map[string]map[string]string)
+	for rows.Next() {
+		var role, varsJSON string
+		if err := rows.Scan(&role, &varsJSON); err != nil {
+			return nil, err
+		}
+		vars, err := decodeHostVars(varsJSON)
+		if err != nil {
+			return nil, err
+		}
+		if len(vars) > 0 {
+			out[role] = vars
+		}
+	}
+	return out, rows.Err()
+}
@@ -1,6 +1,7 @@
 package store
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -238,7 +239,7 @@ func TestListNodes(t *testing.T) {
 	ctx := t.Context()
 
 	// Start empty.
-	list, err := st.ListNodes(ctx)
+	list, err := st.ListNodes(ctx, "")
 	if err != nil {
 		t.Fatalf("ListNodes: %v", err)
 	}
@@ -256,7 +257,7 @@ func TestListNodes(t *testing.T) {
 		}
 	}
 
-	list, err = st.ListNodes(ctx)
+	list, err = st.ListNodes(ctx, "")
 	if err != nil {
 		t.Fatalf("ListNodes: %v", err)
 	}
@@ -306,6 +307,74 @@ func TestUpsertNode_NilSecrets(t *testing.T) {
 	}
 }
 
+func TestUpsertNode_Vars(t *testing.T) {
+	st := newTestStore(t)
+	ctx := t.Context()
+
+	vars := NodeVars{
+		AnsiblePort:       2222,
+		PythonInterpreter: "/usr/bin/python3",
+		SSHCommonArgs:     "-o ProxyJump=bastion",
+		BecomeMethod:      "su",
+		HostVars:          map[string]string{"data_iface": "ens18"},
+	}
+	node := Node{ID: "n1", Name: "node1", AnsibleHost: "10.0.0.1", NodeVars: vars}
+	if err := st.UpsertNode(ctx, node); err != nil {
+		t.Fatalf("UpsertNode: %v", err)
+	}
+	got, _, err := st.GetNode(ctx, "n1")
+	if err != nil {
+		t.Fatalf("GetNode: %v", err)
+	}
+	if !reflect.DeepEqual(got.NodeVars, vars) {
+		t.Errorf("GetNode vars = %+v, want %+v", got.NodeVars, vars)
+	}
+	list, err := st.ListNodes(ctx, "")
+	if err != nil || len(list) != 1 {
+		t.Fatalf("ListNodes = %v, %v", list, err)
+	}
+	if !reflect.DeepEqual(list[0].NodeVars, vars) {
+		t.Errorf("ListNodes vars = %+v, want %+v", list[0].NodeVars, vars)
+	}
+
+	node.NodeVars = NodeVars{}
+	if err := st.UpsertNode(ctx, node); err != nil {
+		t.Fatalf("UpsertNode: %v", err)
+	}
+	if got, _, _ := st.GetNode(ctx, "n1"); !reflect.DeepEqual(got.NodeVars, NodeVars{}) {
+		t.Errorf("cleared vars = %+v", got.NodeVars)
+	}
+}
+
+func TestGroupVars(t *testing.T) {
+	st := newTestStore(t)
+	ctx := t.Context()
+
+	if err := st.SetGroupVars(ctx, "", "master", map[string]string{"ansible_port": "2222"}); err != nil {
+		t.Fatalf("SetGroupVars: %v", err)
+	}
+	if err := st.SetGroupVars(ctx, "lab2", "master", map[string]string{"ntp": "pool"}); err != nil {
+		t.Fatalf("SetGroupVars: %v", err)
+	}
+	got, err := st.ListGroupVars(ctx, "")
+	if err != nil {
+		t.Fatalf("ListGroupVars: %v", err)
+	}
+	if want := map[string]map[string]string{"master": {"ansible_port": "2222"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("group vars = %v, want %v", got, want)
+	}
+
+	if err := st.SetGroupVars(ctx, "", "master", nil); err != nil {
+		t.Fatalf("SetGroupVars(nil): %v", err)
+	}
+	if got, _ := st.ListGroupVars(ctx, ""); len(got) != 0 {
+		t.Errorf("group vars after clear = %v", got)
+	}
+	if err := st.SetGroupVars(ctx, "", "", map[string]string{"a": "b"}); err != ErrInvalidArgument {
+		t.Errorf("empty role err = %v", err)
+	}
+}
+
 func TestMigrationCount(t *testing.T) {
 	st := newTestStore(t)
 	ctx := t.Context()
@@ -316,7 +385,70 @@ func TestMigrationCount(t *testing.T) {
 	if err != nil {
 		t.Fatalf("count migrations: %v", err)
 	}
-	if count != 5 {
-		t.Errorf("migration count = %d, want 5", count)
+	if count != 15 {
+		t.Errorf("migration count = %d, want 15", count)
+	}
+}
+
+func TestApplyNodes(t *testing.T) {
+	st := newTestStore(t)
+	ctx := t.Context()
+	for _, n := range []Node{
+		{ID: "n1", Name: "node1", AnsibleHost: "10.0.0.1", Roles: []string{"master"}},
+		{ID: "n2", Name: "node2", AnsibleHost: "10.0.0.2"},
+	} {
+		if err := st.UpsertNode(ctx, n); err != nil {
+			t.Fatalf("UpsertNode: %v", err)
+		}
+	}
+
+	// A batch that fails part way changes nothing.
+	err := st.ApplyNodes(ctx, "", NodeBatch{
+		Delete: []string{"n1"},
+		Upsert: []Node{
+			{ID: "n3", Name: "node3", AnsibleHost: "10.0.0.3"},
+			{ID: "n4", Name: "node2", AnsibleHost: "10.0.0.4"},
+		},
+		GroupVars: map[string]map[string]string{"master": {"k": "v"}},
+	})
+	if err == nil {
+		t.Fatal("expected the duplicate name to fail the batch")
+	}
+	names := func() []string {
+		t.Helper()
+		infos, err := st.ListNodes(ctx, "")
+		if err != nil {
+			t.Fatalf("ListNodes: %v", err)
+		}
+		var out []string
+		for _, info := range infos {
+			out = append(out, info.Name)
+		}
+		return out
+	}
+	if got := names(); len(got) != 2 || got[0] != "node1" || got[1] != "node2" {
+		t.Errorf("nodes after failed batch = %v", got)
+	}
+	if gv, _ := st.ListGroupVars(ctx, ""); len(gv) != 0 {
+		t.Errorf("group vars after failed batch = %v", gv)
+	}
+
+	// A deleted node's name can be reused in the same batch.
+	err = st.ApplyNodes(ctx, "", NodeBatch{
+		Delete:    []string{"n1", "n2"},
+		Upsert:    []Node{{ID: "n5", Name: "node1", AnsibleHost: "10.0.0.5", Roles: []string{"worker"}}},
+		GroupVars: map[string]map[string]string{"worker": {"k": "v"}},
+	})
+	if err != nil {
+		t.Fatalf("ApplyNodes: %v", err)
+	}
+	if got := names(); len(got) != 1 || got[0] != "node1" {
+		t.Errorf("nodes = %v", got)
+	}
+	if n, ok, _ := st.GetNode(ctx, "n5"); !ok || len(n.Roles) != 1 || n.Roles[0] != "worker" {
+		t.Errorf("n5 = %+v, %v", n, ok)
+	}
+	if gv, _ := st.ListGroupVars(ctx, ""); gv["worker"]["k"] != "v" {
+		t.Errorf("group vars = %v", gv)
 	}
 }
@@ -0,0 +1,120 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ---------------------------------------------------------------------------
+// Config profiles
+// ---------------------------------------------------------------------------
+
+func (d *db) SaveConfigProfile(ctx context.Context, p ConfigProfile) (ConfigProfile, error) {
+	if p.Name == "" || p.Hash == "" {
+		return ConfigProfile{}, ErrInvalidArgument
+	}
+	p.Workspace = workspaceOrDefault(p.Workspace)
+	now := d.now()
+	if p.CreatedAt.IsZero() {
+		p.CreatedAt = now
+	}
+	p.UpdatedAt = now
+	if p.Content == nil {
+		p.Content = []byte{}
+	}
+	_, err := d.conn.ExecContext(ctx, `
+		INSERT INTO config_profiles(workspace, name, description, author, hash, content, created_at, updated_at)
+		VALUES(?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(workspace, name) DO UPDATE SET
+			description = excluded.description,
+			author      = excluded.author,
+			hash        = excluded.hash,
+			content     = excluded.content,
+			updated_at  = excluded.updated_at
+	`, p.Workspace, p.Name, p.Description, p.Author, p.Hash, p.Content, p.CreatedAt.Unix(), p.UpdatedAt.Unix())
+	if err != nil {
+		return ConfigProfile{}, err
+	}
+	got, _, err := d.GetConfigProfile(ctx, p.Workspace, p.Name)
+	return got, err
+}
+
+func (d *db) GetConfigProfile(ctx context.Context, workspace, name string) (ConfigProfile, bool, error) {
+	var p ConfigProfile
+	var createdAt, updatedAt int64
+	err := d.conn.QueryRowContext(ctx, `
+		SELECT workspace, name, description, author, hash, content, created_at, updated_at
+		FROM config_profiles WHERE workspace = ? AND name = ?
+	`, workspaceOrDefault(workspace), name).Scan(
+		&p.Workspace, &p.Name, &p.Description, &p.Author, &p.Hash, &p.Content, &createdAt, &updatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ConfigProfile{}, false, nil
+	}
+	if err != nil {
+		return ConfigProfile{}, false, err
+	}
+	p.CreatedAt = time.Unix(createdAt, 0)
+	p.UpdatedAt = time.Unix(updatedAt, 0)
+	return p, true, nil
+}
+
+func (d *db) ListConfigProfiles(ctx context.Context, workspace string) ([]ConfigProfile, error) {
+	rows, err := d.conn.QueryContext(ctx, `
+		SELECT workspace, name, description, author, hash, created_at, updated_at
+		FROM config_profiles WHERE workspace = ? ORDER BY name
+	`, workspaceOrDefault(workspace))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []ConfigProfile
+	for rows.Next() {
+		var p ConfigProfile
+		var createdAt, updatedAt int64
+		if err := rows.Scan(&p.Workspace, &p.Name, &p.Description, &p.Author, &p.Hash, &createdAt, &updatedAt); err != nil {
+			return nil, err
+		}
+		p.CreatedAt = time.Unix(createdAt, 0)
+		p.UpdatedAt = time.Unix(updatedAt, 0)
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+func (d *db) RenameConfigProfile(ctx context.Context, workspace, name, newName string) error {
+	if newName == "" {
+		return ErrInvalidArgument
+	}
+	workspace = workspaceOrDefault(workspace)
+	if _, ok, err := d.GetConfigProfile(ctx, workspace, newName); err != nil {
+		return err
+	} else if ok {
+		return ErrConflict
+	}
+	res, err := d.conn.ExecContext(ctx, `
+		UPDATE config_profiles SET name = ?, updated_at = ? WHERE workspace = ? AND name = ?
+	`, newName, d.now().Unix(), workspace, name)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (d *db) DeleteConfigProfile(ctx context.Context, workspace, name string) error {
+	res, err := d.conn.ExecContext(ctx, `
+		DELETE FROM config_profiles WHERE workspace = ? AND name = ?
+	`, workspaceOrDefault(workspace), name)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
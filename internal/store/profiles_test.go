@@ -0,0 +1,75 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConfigProfiles(t *testing.T) {
+	st := newTestStore(t)
+	ctx := t.Context()
+
+	if _, ok, err := st.GetConfigProfile(ctx, "", "lab"); err != nil || ok {
+		t.Fatalf("GetConfigProfile on empty store: ok=%v err=%v", ok, err)
+	}
+
+	lab, err := st.SaveConfigProfile(ctx, ConfigProfile{Name: "lab", Author: "alice", Hash: "h1", Content: []byte("a: 1\n")})
+	if err != nil {
+		t.Fatalf("SaveConfigProfile: %v", err)
+	}
+	if lab.Workspace != DefaultWorkspace || lab.CreatedAt.IsZero() || lab.UpdatedAt.IsZero() {
+		t.Errorf("saved = %+v", lab)
+	}
+	if _, err := st.SaveConfigProfile(ctx, ConfigProfile{Name: "edge", Hash: "h2", Content: []byte("b: 1\n")}); err != nil {
+		t.Fatalf("SaveConfigProfile: %v", err)
+	}
+	if _, err := st.SaveConfigProfile(ctx, ConfigProfile{Workspace: "lab2", Name: "lab", Hash: "h3"}); err != nil {
+		t.Fatalf("SaveConfigProfile lab2: %v", err)
+	}
+
+	// Saving again replaces the content and keeps the creation time.
+	updated, err := st.SaveConfigProfile(ctx, ConfigProfile{Name: "lab", Description: "tuned", Hash: "h4", Content: []byte("a: 2\n")})
+	if err != nil {
+		t.Fatalf("SaveConfigProfile update: %v", err)
+	}
+	if string(updated.Content) != "a: 2\n" || updated.Description != "tuned" || !updated.CreatedAt.Equal(lab.CreatedAt.Truncate(time.Second)) {
+		t.Errorf("updated = %+v", updated)
+	}
+
+	list, err := st.ListConfigProfiles(ctx, "")
+	if err != nil {
+		t.Fatalf("ListConfigProfiles: %v", err)
+	}
+	if len(list) != 2 || list[0].Name != "edge" || list[1].Name != "lab" || list[1].Content != nil {
+		t.Errorf("ListConfigProfiles = %+v", list)
+	}
+
+	if err := st.RenameConfigProfile(ctx, "", "lab", "edge"); err != ErrConflict {
+		t.Errorf("RenameConfigProfile onto existing = %v, want ErrConflict", err)
+	}
+	if err := st.RenameConfigProfile(ctx, "", "missing", "x"); err != ErrNotFound {
+		t.Errorf("RenameConfigProfile missing = %v, want ErrNotFound", err)
+	}
+	if err := st.RenameConfigProfile(ctx, "", "lab", "site-a"); err != nil {
+		t.Fatalf("RenameConfigProfile: %v", err)
+	}
+	got, ok, err := st.GetConfigProfile(ctx, "", "site-a")
+	if err != nil || !ok || string(got.Content) != "a: 2\n" {
+		t.Errorf("GetConfigProfile after rename = %+v, %v, %v", got, ok, err)
+	}
+
+	if err := st.DeleteConfigProfile(ctx, "", "site-a"); err != nil {
+		t.Fatalf("DeleteConfigProfile: %v", err)
+	}
+	if err := st.DeleteConfigProfile(ctx, "", "site-a"); err != ErrNotFound {
+		t.Errorf("DeleteConfigProfile twice = %v, want ErrNotFound", err)
+	}
+
+	// Profiles are scoped to their workspace.
+	if _, ok, _ := st.GetConfigProfile(ctx, "lab2", "edge"); ok {
+		t.Error("default workspace profile visible from lab2")
+	}
+	if _, err := st.SaveConfigProfile(ctx, ConfigProfile{Hash: "h"}); err != ErrInvalidArgument {
+		t.Errorf("SaveConfigProfile without name = %v, want ErrInvalidArgument", err)
+	}
+}
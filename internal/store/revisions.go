@@ -0,0 +1,87 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ---------------------------------------------------------------------------
+// Config revisions
+// ---------------------------------------------------------------------------
+
+func (d *db) InsertConfigRevision(ctx context.Context, r ConfigRevision) (ConfigRevision, error) {
+	if r.Source == "" || r.Hash == "" {
+		return ConfigRevision{}, ErrInvalidArgument
+	}
+	r.Workspace = workspaceOrDefault(r.Workspace)
+	if r.CreatedAt.IsZero() {
+		r.CreatedAt = d.now()
+	}
+	if r.Content == nil {
+		r.Content = []byte{}
+	}
+	res, err := d.conn.ExecContext(ctx, `
+		INSERT INTO config_revisions(workspace, source, note, author, hash, content, created_at)
+		VALUES(?, ?, ?, ?, ?, ?, ?)
+	`, r.Workspace, r.Source, r.Note, r.Author, r.Hash, r.Content, r.CreatedAt.Unix())
+	if err != nil {
+		return ConfigRevision{}, err
+	}
+	if r.ID, err = res.LastInsertId(); err != nil {
+		return ConfigRevision{}, err
+	}
+	return r, nil
+}
+
+func (d *db) GetConfigRevision(ctx context.Context, workspace string, id int64) (ConfigRevision, bool, error) {
+	return d.scanConfigRevision(d.conn.QueryRowContext(ctx, `
+		SELECT id, workspace, source, note, author, hash, content, created_at
+		FROM config_revisions WHERE workspace = ? AND id = ?
+	`, workspaceOrDefault(workspace), id))
+}
+
+func (d *db) LatestConfigRevision(ctx context.Context, workspace string) (ConfigRevision, bool, error) {
+	return d.scanConfigRevision(d.conn.QueryRowContext(ctx, `
+		SELECT id, workspace, source, note, author, hash, content, created_at
+		FROM config_revisions WHERE workspace = ? ORDER BY id DESC LIMIT 1
+	`, workspaceOrDefault(workspace)))
+}
+
+func (d *db) scanConfigRevision(row *sql.Row) (ConfigRevision, bool, error) {
+	var r ConfigRevision
+	var createdAt int64
+	err := row.Scan(&r.ID, &r.Workspace, &r.Source, &r.Note, &r.Author, &r.Hash, &r.Content, &createdAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ConfigRevision{}, false, nil
+	}
+	if err != nil {
+		return ConfigRevision{}, false, err
+	}
+	r.CreatedAt = time.Unix(createdAt, 0)
+	return r, true, nil
+}
+
+func (d *db) ListConfigRevisions(ctx context.Context, workspace string) ([]ConfigRevision, error) {
+	rows, err := d.conn.QueryContext(ctx, `
+		SELECT id, workspace, source, note, author, hash, created_at
+		FROM config_revisions WHERE workspace = ? ORDER BY id DESC
+	`, workspaceOrDefault(workspace))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []ConfigRevision
+	for rows.Next() {
+		var r ConfigRevision
+		var createdAt int64
+		if err := rows.Scan(&r.ID, &r.Workspace, &r.Source, &r.Note, &r.Author, &r.Hash, &createdAt); err != nil {
+			return nil, err
+		}
+		r.CreatedAt = time.Unix(createdAt, 0)
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
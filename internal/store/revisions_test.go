@@ -0,0 +1,55 @@
+package store
+
+import (
+	"testing"
+)
+
+func TestConfigRevisions(t *testing.T) {
+	st := newTestStore(t)
+	ctx := t.Context()
+
+	if _, ok, err := st.LatestConfigRevision(ctx, ""); err != nil || ok {
+		t.Fatalf("LatestConfigRevision on empty store: ok=%v err=%v", ok, err)
+	}
+
+	first, err := st.InsertConfigRevision(ctx, ConfigRevision{Source: "patch", Author: "alice", Hash: "h1", Content: []byte("a: 1\n")})
+	if err != nil {
+		t.Fatalf("InsertConfigRevision: %v", err)
+	}
+	if first.ID == 0 || first.Workspace != DefaultWorkspace || first.CreatedAt.IsZero() {
+		t.Errorf("inserted = %+v", first)
+	}
+	second, err := st.InsertConfigRevision(ctx, ConfigRevision{Source: "compose", Note: "components: k8s", Hash: "h2", Content: []byte("a: 2\n")})
+	if err != nil {
+		t.Fatalf("InsertConfigRevision: %v", err)
+	}
+	if _, err := st.InsertConfigRevision(ctx, ConfigRevision{Workspace: "lab2", Source: "patch", Hash: "h3", Content: []byte("b: 1\n")}); err != nil {
+		t.Fatalf("InsertConfigRevision lab2: %v", err)
+	}
+
+	list, err := st.ListConfigRevisions(ctx, DefaultWorkspace)
+	if err != nil {
+		t.Fatalf("ListConfigRevisions: %v", err)
+	}
+	if len(list) != 2 || list[0].ID != second.ID || list[1].Author != "alice" || list[0].Content != nil {
+		t.Errorf("ListConfigRevisions = %+v", list)
+	}
+
+	got, ok, err := st.GetConfigRevision(ctx, "", first.ID)
+	if err != nil || !ok || string(got.Content) != "a: 1\n" || got.Source != "patch" {
+		t.Errorf("GetConfigRevision = %+v, %v, %v", got, ok, err)
+	}
+	latest, ok, err := st.LatestConfigRevision(ctx, "")
+	if err != nil || !ok || latest.ID != second.ID || latest.Note != "components: k8s" {
+		t.Errorf("LatestConfigRevision = %+v, %v, %v", latest, ok, err)
+	}
+
+	// Revisions are scoped to their workspace.
+	if _, ok, _ := st.GetConfigRevision(ctx, "lab2", first.ID); ok {
+		t.Error("default workspace revision visible from lab2")
+	}
+
+	if _, err := st.InsertConfigRevision(ctx, ConfigRevision{Hash: "h"}); err != ErrInvalidArgument {
+		t.Errorf("InsertConfigRevision without source = %v, want ErrInvalidArgument", err)
+	}
+}
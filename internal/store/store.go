@@ -26,7 +26,30 @@ type Store interface {
 	UpsertNode(ctx context.Context, node Node) error
 	GetNode(ctx context.Context, id string) (Node, bool, error)
 	DeleteNode(ctx context.Context, id string) error
-	ListNodes(ctx context.Context) ([]NodeInfo, error)
+	ListNodes(ctx context.Context, workspace string) ([]NodeInfo, error)
+	SetGroupVars(ctx context.Context, workspace, role string, vars map[string]string) error
+	ListGroupVars(ctx context.Context, workspace string) (map[string]map[string]string, error)
+	ApplyNodes(ctx context.Context, workspace string, b NodeBatch) error
+
+	// Jump hosts
+	UpsertJumpHost(ctx context.Context, j JumpHost) error
+	GetJumpHost(ctx context.Context, workspace, name string) (JumpHost, bool, error)
+	ListJumpHosts(ctx context.Context, workspace string) ([]JumpHost, error)
+	DeleteJumpHost(ctx context.Context, workspace, name string) error
+
+	// Node health
+	RecordNodeHealth(ctx context.Context, h NodeHealth) (NodeHealthEvent, bool, error)
+	GetNodeHealth(ctx context.Context, nodeID string) (NodeHealth, bool, error)
+	ListNodeHealth(ctx context.Context, workspace string) ([]NodeHealth, error)
+	ListNodeHealthEvents(ctx context.Context, filter NodeHealthEventFilter) ([]NodeHealthEvent, error)
+
+	// Node facts
+	AddNodeFacts(ctx context.Context, v NodeFactsVersion) (int64, error)
+	TouchNodeFacts(ctx context.Context, id int64, at time.Time) error
+	GetNodeFacts(ctx context.Context, nodeID string, id int64) (NodeFactsVersion, bool, error)
+	ListNodeFacts(ctx context.Context, nodeID string, limit int) ([]NodeFactsVersion, error)
+	UpsertConfigDefault(ctx context.Context, d ConfigDefault) error
+	ListConfigDefaults(ctx context.Context, workspace string) ([]ConfigDefault, error)
 
 	// Actions
 	InsertAction(ctx context.Context, rec ActionRecord) error
@@ -36,8 +59,8 @@ type Store interface {
 
 	// Component state
 	UpsertComponentState(ctx context.Context, cs ComponentState) error
-	GetComponentState(ctx context.Context, component string) (ComponentState, bool, error)
-	ListComponentStates(ctx context.Context) ([]ComponentState, error)
+	GetComponentState(ctx context.Context, workspace, component string) (ComponentState, bool, error)
+	ListComponentStates(ctx context.Context, workspace string) ([]ComponentState, error)
 
 	// Deployments
 	InsertDeployment(ctx context.Context, d Deployment) error
@@ -45,6 +68,47 @@ type Store interface {
 	GetDeployment(ctx context.Context, id string) (Deployment, bool, error)
 	ListDeployments(ctx context.Context, filter DeploymentFilter) ([]Deployment, error)
 
+	// Workspaces
+	CreateWorkspace(ctx context.Context, w Workspace) error
+	GetWorkspace(ctx context.Context, name string) (Workspace, bool, error)
+	ListWorkspaces(ctx context.Context) ([]Workspace, error)
+	UpdateWorkspaceVersion(ctx context.Context, name, version string) error
+	DeleteWorkspace(ctx context.Context, name string) error
+
+	// Config revisions
+	InsertConfigRevision(ctx context.Context, r ConfigRevision) (ConfigRevision, error)
+	GetConfigRevision(ctx context.Context, workspace string, id int64) (ConfigRevision, bool, error)
+	LatestConfigRevision(ctx context.Context, workspace string) (ConfigRevision, bool, error)
+	ListConfigRevisions(ctx context.Context, workspace string) ([]ConfigRevision, error)
+
+	// Config profiles
+	SaveConfigProfile(ctx context.Context, p ConfigProfile) (ConfigProfile, error)
+	GetConfigProfile(ctx context.Context, workspace, name string) (ConfigProfile, bool, error)
+	ListConfigProfiles(ctx context.Context, workspace string) ([]ConfigProfile, error)
+	RenameConfigProfile(ctx context.Context, workspace, name, newName string) error
+	DeleteConfigProfile(ctx context.Context, workspace, name string) error
+
+	// Custom components
+	SaveCustomComponent(ctx context.Context, c CustomComponent) (CustomComponent, error)
+	GetCustomComponent(ctx context.Context, workspace, name string) (CustomComponent, bool, error)
+	ListCustomComponents(ctx context.Context, workspace string) ([]CustomComponent, error)
+	DeleteCustomComponent(ctx context.Context, workspace, name string) error
+
+	// Action hooks
+	SaveActionHook(ctx context.Context, h ActionHook) (ActionHook, error)
+	GetActionHook(ctx context.Context, id string) (ActionHook, bool, error)
+	ListActionHooks(ctx context.Context, workspace, component string) ([]ActionHook, error)
+	DeleteActionHook(ctx context.Context, id string) error
+	InsertHookRun(ctx context.Context, r HookRun) error
+	UpdateHookRun(ctx context.Context, id string, result ActionResult) error
+	ListHookRuns(ctx context.Context, actionID string) ([]HookRun, error)
+
+	// Locks
+	AcquireLock(ctx context.Context, l Lock) (Lock, error)
+	ReleaseLock(ctx context.Context, name, holderID string) error
+	GetLock(ctx context.Context, name string) (Lock, bool, error)
+	BreakLock(ctx context.Context, name string) (Lock, bool, error)
+
 	// Metrics (typed)
 	AppendSample(ctx context.Context, s Sample) error
 	AppendSamples(ctx context.Context, samples []Sample) error
@@ -41,6 +41,7 @@ type CredentialInfo struct {
 
 type Node struct {
 	ID           string
+	Workspace    string   // owning workspace; empty means DefaultWorkspace
 	Name         string   // Ansible inventory hostname (e.g. "node1")
 	AnsibleHost  string   // IP or hostname for SSH
 	AnsibleUser  string   // SSH username
@@ -48,32 +49,74 @@ type Node struct {
 	SudoPassword []byte   // plaintext at API boundary; encrypted at rest
 	SSHKey       []byte   // plaintext at API boundary; encrypted at rest
 	Roles        []string // role assignments (master, worker, gnbsim, etc.)
-	CreatedAt    time.Time
-	UpdatedAt    time.Time
+	NodeVars
+	CreatedAt time.Time
+	UpdatedAt time.Time
 }
 
 type NodeInfo struct {
 	ID          string
+	Workspace   string
 	Name        string
 	AnsibleHost string
 	AnsibleUser string
 	Roles       []string
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
+	NodeVars
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// NodeBatch is a set of node changes to one workspace that ApplyNodes makes
+// together or not at all.
+type NodeBatch struct {
+	Delete    []string                     // IDs of nodes to remove
+	Upsert    []Node                       // nodes to create or update
+	GroupVars map[string]map[string]string // group variables to set, by role; empty vars remove them
+}
+
+// NodeVars are a node's Ansible connection settings and other inventory
+// variables, written to its hosts.ini line. None of them are secret.
+type NodeVars struct {
+	AnsiblePort       int               // SSH port; 0 means Ansible's default
+	PythonInterpreter string            // ansible_python_interpreter
+	SSHCommonArgs     string            // ansible_ssh_common_args, e.g. "-o ProxyJump=bastion"
+	BecomeMethod      string            // ansible_become_method, e.g. "sudo"
+	HostVars          map[string]string // any other host variables
+	JumpHost          string            // jump host name; empty uses the workspace default, JumpHostNone none
+}
+
+// JumpHostNone as a node's JumpHost connects to the node directly, even when
+// its workspace has a default jump host.
+const JumpHostNone = "none"
+
+// JumpHost is an SSH bastion that a workspace's nodes are reached through.
+type JumpHost struct {
+	Workspace string // owning workspace; empty means DefaultWorkspace
+	Name      string // unique within the workspace
+	Host      string // IP or hostname
+	Port      int    // SSH port; 0 means 22
+	User      string // SSH username
+	Password  []byte // plaintext at API boundary; encrypted at rest
+	SSHKey    []byte // plaintext at API boundary; encrypted at rest
+	Via       string // jump host this one is reached through; empty connects directly
+	Default   bool   // used by the workspace's nodes that name no jump host
+	CreatedAt time.Time
+	UpdatedAt time.Time
 }
 
 // Actions
 
 type ActionRecord struct {
-	ID        string
-	Component string
-	Action    string
-	Target    string
-	Status    string
-	Error     string
-	ExitCode  int
-	Labels    map[string]string
-	Tags      []string
+	ID         string
+	Workspace  string // empty means DefaultWorkspace
+	Component  string
+	Action     string
+	Target     string
+	Status     string
+	Error      string
+	ExitCode   int
+	Labels     map[string]string
+	Tags       []string
 	StartedAt  time.Time
 	FinishedAt time.Time
 }
@@ -86,6 +129,7 @@ type ActionResult struct {
 }
 
 type ActionFilter struct {
+	Workspace string // empty matches every workspace
 	Component string
 	Action    string
 	Status    string
@@ -94,6 +138,7 @@ type ActionFilter struct {
 }
 
 type ComponentState struct {
+	Workspace  string // empty means DefaultWorkspace
 	Component  string
 	Status     string
 	LastAction string
@@ -105,6 +150,7 @@ type ComponentState struct {
 
 type Deployment struct {
 	ID         string
+	Workspace  string // empty means DefaultWorkspace
 	Status     string
 	CreatedAt  time.Time
 	StartedAt  time.Time
@@ -122,9 +168,225 @@ type DeploymentAction struct {
 }
 
 type DeploymentFilter struct {
-	Status string
-	Limit  int
-	Offset int
+	Workspace string // empty matches every workspace
+	Status    string
+	Limit     int
+	Offset    int
+}
+
+// Workspaces
+
+// DefaultWorkspace is the workspace configured by command-line flags. It
+// always exists and is never stored in the workspaces table.
+const DefaultWorkspace = "default"
+
+type Workspace struct {
+	Name      string
+	OnRampDir string
+	RepoURL   string
+	Version   string
+	CreatedAt time.Time
+}
+
+// Config revisions
+
+// ConfigRevision is one recorded version of a workspace's vars/main.yml.
+type ConfigRevision struct {
+	ID        int64
+	Workspace string // empty means DefaultWorkspace
+	Source    string // operation that wrote the file (patch, compose, rollback, ...)
+	Note      string
+	Author    string
+	Hash      string // hex SHA-256 of Content
+	Content   []byte // omitted by ListConfigRevisions
+	CreatedAt time.Time
+}
+
+// Config profiles
+
+// ConfigProfile is an OnRamp config profile saved in the store rather than
+// as a vars/main-*.yml file in the checkout.
+type ConfigProfile struct {
+	Workspace   string // empty means DefaultWorkspace
+	Name        string
+	Description string
+	Author      string
+	Hash        string // hex SHA-256 of Content
+	Content     []byte // omitted by ListConfigProfiles
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// Custom components
+
+// Kinds of CustomAction.
+const (
+	CustomActionMake     = "make"     // make target in the OnRamp checkout
+	CustomActionPlaybook = "playbook" // ansible-playbook from the playbook directory
+	CustomActionScript   = "script"   // executable from the script directory
+)
+
+// CustomComponent is a user-defined component whose actions run site make
+// targets, playbooks or scripts alongside the OnRamp components.
+type CustomComponent struct {
+	Workspace   string // empty means DefaultWorkspace
+	Name        string
+	Description string
+	Tier        int
+	Roles       []string // extra node roles (hosts.ini groups)
+	Actions     []CustomAction
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// CustomAction is one action of a CustomComponent.
+type CustomAction struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Kind        string   `json:"kind"`   // CustomActionMake, CustomActionPlaybook or CustomActionScript
+	Target      string   `json:"target"` // make target, or path relative to the playbook or script directory
+	Args        []string `json:"args,omitempty"`
+}
+
+// Action hooks
+
+// Stages of an ActionHook.
+const (
+	HookBefore    = "before"     // runs before the action; a failure aborts it
+	HookAfter     = "after"      // runs after the action succeeds
+	HookOnFailure = "on_failure" // runs after the action fails
+)
+
+// ActionHook is a command run around a component action. Kind, Target and
+// Args are as for CustomAction.
+type ActionHook struct {
+	ID        string
+	Workspace string // empty means DefaultWorkspace
+	Component string
+	Action    string // empty matches every action of the component
+	Stage     string // HookBefore, HookAfter or HookOnFailure
+	Name      string
+	Kind      string
+	Target    string
+	Args      []string
+	Position  int // run order within the stage; ties run in creation order
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// HookRun is one execution of an ActionHook for an action. ID is the hook's
+// task ID and ActionID the action_history ID of the action.
+type HookRun struct {
+	ID         string
+	ActionID   string
+	HookID     string
+	Stage      string
+	Name       string
+	Target     string
+	Status     string
+	Error      string
+	ExitCode   int
+	StartedAt  time.Time
+	FinishedAt time.Time
+}
+
+// Node health
+
+// Statuses of a NodeHealth.
+const (
+	NodeHealthUnknown     = "unknown"     // not probed yet
+	NodeHealthHealthy     = "healthy"     // SSH login and sudo work
+	NodeHealthDegraded    = "degraded"    // the SSH port answers but login or sudo fails
+	NodeHealthUnreachable = "unreachable" // the SSH port does not answer
+)
+
+// NodeHealth is the latest health probe of a node.
+type NodeHealth struct {
+	NodeID    string
+	Status    string        // NodeHealthHealthy, NodeHealthDegraded or NodeHealthUnreachable
+	TCP       bool          // the SSH port accepted a connection
+	SSH       bool          // login with the stored credentials succeeded
+	Sudo      bool          // sudo with the stored sudo password succeeded
+	Latency   time.Duration // time to connect to the SSH port
+	Error     string        // why the first failing step failed
+	CheckedAt time.Time
+	Since     time.Time // set by the store: when the node entered Status
+	LastSeen  time.Time // set by the store: last successful login; zero if never
+}
+
+// NodeHealthEvent records a change of a node's health status.
+type NodeHealthEvent struct {
+	ID     int64
+	NodeID string
+	From   string
+	To     string
+	Error  string
+	At     time.Time
+}
+
+type NodeHealthEventFilter struct {
+	Workspace string // empty means DefaultWorkspace
+	NodeID    string // empty matches every node of the workspace
+	Limit     int
+}
+
+// NodeFactsVersion is one distinct version of a node's gathered facts. It
+// covers every consecutive gather that found the same facts.
+type NodeFactsVersion struct {
+	ID             int64
+	NodeID         string
+	Facts          []byte    // JSON-encoded facts
+	Changes        []byte    // JSON-encoded changes from the previous version; nil for the first
+	GatheredAt     time.Time // first gather that found these facts
+	LastGatheredAt time.Time // latest gather that found them; defaults to GatheredAt
+}
+
+// ConfigDefault records a config value set by the config defaults engine
+// from a node's facts.
+type ConfigDefault struct {
+	Workspace string // empty means DefaultWorkspace
+	Field     string // dotted config path
+	Value     []byte // JSON-encoded value
+	NodeID    string // node whose facts the value was computed from
+	FactsID   int64  // NodeFactsVersion the value was computed from
+	AppliedAt time.Time
+}
+
+// workspaceOrDefault maps an empty workspace name to DefaultWorkspace.
+func workspaceOrDefault(name string) string {
+	if name == "" {
+		return DefaultWorkspace
+	}
+	return name
+}
+
+// Locks
+
+// ChangeLockName is the lock that serialises changes to the default
+// workspace's cluster. Use ChangeLockFor for other workspaces.
+const ChangeLockName = "cluster"
+
+// ChangeLockFor returns the change lock name for a workspace.
+func ChangeLockFor(workspace string) string {
+	if workspace == "" || workspace == DefaultWorkspace {
+		return ChangeLockName
+	}
+	return ChangeLockName + ":" + workspace
+}
+
+const (
+	LockKindDeployment  = "deployment"
+	LockKindMaintenance = "maintenance"
+)
+
+type Lock struct {
+	Name       string
+	HolderID   string // opaque token; must match to renew or release
+	Kind       string // LockKindDeployment or LockKindMaintenance
+	Owner      string
+	Reason     string
+	AcquiredAt time.Time
+	ExpiresAt  time.Time // zero means the lock never expires
 }
 
 // Metrics
@@ -0,0 +1,134 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ---------------------------------------------------------------------------
+// Workspaces
+// ---------------------------------------------------------------------------
+
+func (d *db) CreateWorkspace(ctx context.Context, w Workspace) error {
+	if w.Name == "" || w.Name == DefaultWorkspace || w.OnRampDir == "" {
+		return ErrInvalidArgument
+	}
+	if w.CreatedAt.IsZero() {
+		w.CreatedAt = d.now()
+	}
+
+	tx, err := d.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists int
+	err = tx.QueryRowContext(ctx, `SELECT 1 FROM workspaces WHERE name = ?`, w.Name).Scan(&exists)
+	if err == nil {
+		return ErrConflict
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO workspaces(name, onramp_dir, repo_url, version, created_at)
+		VALUES(?, ?, ?, ?, ?)
+	`, w.Name, w.OnRampDir, w.RepoURL, w.Version, w.CreatedAt.Unix()); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (d *db) GetWorkspace(ctx context.Context, name string) (Workspace, bool, error) {
+	if name == "" {
+		return Workspace{}, false, ErrInvalidArgument
+	}
+	var w Workspace
+	var createdAt int64
+	err := d.conn.QueryRowContext(ctx, `
+		SELECT name, onramp_dir, repo_url, version, created_at
+		FROM workspaces WHERE name = ?
+	`, name).Scan(&w.Name, &w.OnRampDir, &w.RepoURL, &w.Version, &createdAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Workspace{}, false, nil
+	}
+	if err != nil {
+		return Workspace{}, false, err
+	}
+	w.CreatedAt = time.Unix(createdAt, 0)
+	return w, true, nil
+}
+
+func (d *db) ListWorkspaces(ctx context.Context) ([]Workspace, error) {
+	rows, err := d.conn.QueryContext(ctx, `
+		SELECT name, onramp_dir, repo_url, version, created_at
+		FROM workspaces ORDER BY name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Workspace
+	for rows.Next() {
+		var w Workspace
+		var createdAt int64
+		if err := rows.Scan(&w.Name, &w.OnRampDir, &w.RepoURL, &w.Version, &createdAt); err != nil {
+			return nil, err
+		}
+		w.CreatedAt = time.Unix(createdAt, 0)
+		out = append(out, w)
+	}
+	return out, rows.Err()
+}
+
+func (d *db) UpdateWorkspaceVersion(ctx context.Context, name, version string) error {
+	if name == "" || name == DefaultWorkspace {
+		return ErrInvalidArgument
+	}
+	res, err := d.conn.ExecContext(ctx, `UPDATE workspaces SET version = ? WHERE name = ?`, version, name)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteWorkspace removes a workspace together with its component state,
+// config profiles, custom components, hooks, group vars, config defaults and
+// jump hosts. Action and deployment history are kept for auditing.
+func (d *db) DeleteWorkspace(ctx context.Context, name string) error {
+	if name == "" || name == DefaultWorkspace {
+		return ErrInvalidArgument
+	}
+	tx, err := d.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM workspaces WHERE name = ?`, name)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	for _, table := range []string{
+		"component_state", "config_profiles", "custom_components", "action_hooks",
+		"group_vars", "config_defaults", "jump_hosts",
+	} {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM `+table+` WHERE workspace = ?`, name); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM locks WHERE name = ?`, ChangeLockFor(name)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,282 @@
+package store
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestCreateWorkspace_RoundTrip(t *testing.T) {
+	st := newTestStore(t)
+	ctx := t.Context()
+
+	w := Workspace{Name: "lab2", OnRampDir: "/data/workspaces/lab2", RepoURL: "https://example.com/onramp.git", Version: "v2.1.0"}
+	if err := st.CreateWorkspace(ctx, w); err != nil {
+		t.Fatalf("CreateWorkspace: %v", err)
+	}
+
+	got, ok, err := st.GetWorkspace(ctx, "lab2")
+	if err != nil || !ok {
+		t.Fatalf("GetWorkspace: ok=%v err=%v", ok, err)
+	}
+	if got.OnRampDir != w.OnRampDir || got.RepoURL != w.RepoURL || got.Version != w.Version {
+		t.Errorf("GetWorkspace = %+v", got)
+	}
+	if got.CreatedAt.IsZero() {
+		t.Error("expected non-zero CreatedAt")
+	}
+
+	if err := st.CreateWorkspace(ctx, w); err != ErrConflict {
+		t.Errorf("duplicate CreateWorkspace = %v, want ErrConflict", err)
+	}
+
+	list, err := st.ListWorkspaces(ctx)
+	if err != nil {
+		t.Fatalf("ListWorkspaces: %v", err)
+	}
+	if len(list) != 1 || list[0].Name != "lab2" {
+		t.Errorf("ListWorkspaces = %+v", list)
+	}
+}
+
+func TestCreateWorkspace_Validation(t *testing.T) {
+	st := newTestStore(t)
+	ctx := t.Context()
+
+	for _, w := range []Workspace{
+		{OnRampDir: "/x"},
+		{Name: DefaultWorkspace, OnRampDir: "/x"},
+		{Name: "lab2"},
+	} {
+		if err := st.CreateWorkspace(ctx, w); err != ErrInvalidArgument {
+			t.Errorf("CreateWorkspace(%+v) = %v, want ErrInvalidArgument", w, err)
+		}
+	}
+}
+
+func TestDeleteWorkspace(t *testing.T) {
+	st := newTestStore(t)
+	ctx := t.Context()
+
+	if err := st.DeleteWorkspace(ctx, "lab2"); err != ErrNotFound {
+		t.Errorf("DeleteWorkspace missing = %v, want ErrNotFound", err)
+	}
+	if err := st.DeleteWorkspace(ctx, DefaultWorkspace); err != ErrInvalidArgument {
+		t.Errorf("DeleteWorkspace default = %v, want ErrInvalidArgument", err)
+	}
+
+	if err := st.CreateWorkspace(ctx, Workspace{Name: "lab2", OnRampDir: "/x"}); err != nil {
+		t.Fatalf("CreateWorkspace: %v", err)
+	}
+	for _, ws := range []string{"lab2", ""} {
+		if err := st.UpsertComponentState(ctx, ComponentState{Workspace: ws, Component: "k8s", Status: "installed"}); err != nil {
+			t.Fatalf("UpsertComponentState(%q): %v", ws, err)
+		}
+	}
+	if _, err := st.AcquireLock(ctx, Lock{Name: ChangeLockFor("lab2"), HolderID: "h", Kind: LockKindMaintenance}); err != nil {
+		t.Fatalf("AcquireLock: %v", err)
+	}
+	if err := st.UpsertNode(ctx, Node{ID: "n2", Workspace: "lab2", Name: "node1", AnsibleHost: "10.1.0.1"}); err != nil {
+		t.Fatalf("UpsertNode: %v", err)
+	}
+	factsID, err := st.AddNodeFacts(ctx, NodeFactsVersion{NodeID: "n2", Facts: []byte(`{}`), GatheredAt: time.Now()})
+	if err != nil {
+		t.Fatalf("AddNodeFacts: %v", err)
+	}
+	for i, ws := range []string{"lab2", ""} {
+		if _, err := st.SaveConfigProfile(ctx, ConfigProfile{Workspace: ws, Name: "p", Hash: "h"}); err != nil {
+			t.Fatalf("SaveConfigProfile(%q): %v", ws, err)
+		}
+		if _, err := st.SaveCustomComponent(ctx, CustomComponent{Workspace: ws, Name: "c"}); err != nil {
+			t.Fatalf("SaveCustomComponent(%q): %v", ws, err)
+		}
+		hook := ActionHook{ID: fmt.Sprintf("h%d", i), Workspace: ws, Component: "k8s", Stage: HookAfter, Name: "n", Kind: CustomActionScript, Target: "x.sh"}
+		if _, err := st.SaveActionHook(ctx, hook); err != nil {
+			t.Fatalf("SaveActionHook(%q): %v", ws, err)
+		}
+		if err := st.SetGroupVars(ctx, ws, "master", map[string]string{"a": "b"}); err != nil {
+			t.Fatalf("SetGroupVars(%q): %v", ws, err)
+		}
+		if err := st.UpsertJumpHost(ctx, JumpHost{Workspace: ws, Name: "gw", Host: "10.0.0.254", Password: []byte("secret")}); err != nil {
+			t.Fatalf("UpsertJumpHost(%q): %v", ws, err)
+		}
+	}
+	if err := st.UpsertConfigDefault(ctx, ConfigDefault{Workspace: "lab2", Field: "f", Value: []byte(`"v"`), NodeID: "n2", FactsID: factsID, AppliedAt: time.Now()}); err != nil {
+		t.Fatalf("UpsertConfigDefault: %v", err)
+	}
+
+	if err := st.DeleteWorkspace(ctx, "lab2"); err != nil {
+		t.Fatalf("DeleteWorkspace: %v", err)
+	}
+	if _, ok, _ := st.GetWorkspace(ctx, "lab2"); ok {
+		t.Error("workspace still present after delete")
+	}
+	if _, ok, _ := st.GetComponentState(ctx, "lab2", "k8s"); ok {
+		t.Error("component state for deleted workspace should be removed")
+	}
+	if _, ok, _ := st.GetComponentState(ctx, DefaultWorkspace, "k8s"); !ok {
+		t.Error("default workspace component state should be untouched")
+	}
+	if _, ok, _ := st.GetLock(ctx, ChangeLockFor("lab2")); ok {
+		t.Error("change lock for deleted workspace should be removed")
+	}
+
+	for _, ws := range []string{"lab2", DefaultWorkspace} {
+		want := 0
+		if ws == DefaultWorkspace {
+			want = 1
+		}
+		profiles, _ := st.ListConfigProfiles(ctx, ws)
+		components, _ := st.ListCustomComponents(ctx, ws)
+		hooks, _ := st.ListActionHooks(ctx, ws, "")
+		groupVars, _ := st.ListGroupVars(ctx, ws)
+		jumpHosts, _ := st.ListJumpHosts(ctx, ws)
+		for name, n := range map[string]int{
+			"config profiles":   len(profiles),
+			"custom components": len(components),
+			"action hooks":      len(hooks),
+			"group vars":        len(groupVars),
+			"jump hosts":        len(jumpHosts),
+		} {
+			if n != want {
+				t.Errorf("%s %s after delete = %d, want %d", ws, name, n, want)
+			}
+		}
+	}
+	if defaults, _ := st.ListConfigDefaults(ctx, "lab2"); len(defaults) != 0 {
+		t.Errorf("config defaults for deleted workspace = %+v, want none", defaults)
+	}
+}
+
+func TestNodes_ScopedByWorkspace(t *testing.T) {
+	st := newTestStore(t)
+	ctx := t.Context()
+
+	// The same inventory name may be used in different workspaces.
+	for _, n := range []Node{
+		{ID: "n1", Name: "node1", AnsibleHost: "10.0.0.1", Roles: []string{"master"}},
+		{ID: "n2", Workspace: "lab2", Name: "node1", AnsibleHost: "10.1.0.1", Roles: []string{"master"}},
+	} {
+		if err := st.UpsertNode(ctx, n); err != nil {
+			t.Fatalf("UpsertNode(%s): %v", n.ID, err)
+		}
+	}
+	if err := st.UpsertNode(ctx, Node{ID: "n3", Workspace: "lab2", Name: "node1", AnsibleHost: "10.1.0.2"}); err == nil {
+		t.Error("expected duplicate name within a workspace to fail")
+	}
+
+	def, err := st.ListNodes(ctx, "")
+	if err != nil {
+		t.Fatalf("ListNodes default: %v", err)
+	}
+	if len(def) != 1 || def[0].ID != "n1" || def[0].Workspace != DefaultWorkspace {
+		t.Errorf("default nodes = %+v", def)
+	}
+	lab, err := st.ListNodes(ctx, "lab2")
+	if err != nil {
+		t.Fatalf("ListNodes lab2: %v", err)
+	}
+	if len(lab) != 1 || lab[0].ID != "n2" {
+		t.Errorf("lab2 nodes = %+v", lab)
+	}
+
+	got, _, _ := st.GetNode(ctx, "n2")
+	if got.Workspace != "lab2" {
+		t.Errorf("GetNode workspace = %q, want lab2", got.Workspace)
+	}
+
+	// Deleting a node still cascades to its roles after the table rebuild.
+	if err := st.DeleteNode(ctx, "n1"); err != nil {
+		t.Fatalf("DeleteNode: %v", err)
+	}
+	var roles int
+	if err := st.s.(*db).conn.QueryRowContext(ctx, `SELECT COUNT(*) FROM node_roles WHERE node_id = 'n1'`).Scan(&roles); err != nil {
+		t.Fatalf("count roles: %v", err)
+	}
+	if roles != 0 {
+		t.Errorf("node_roles rows after delete = %d, want 0", roles)
+	}
+}
+
+func TestActionsAndDeployments_ScopedByWorkspace(t *testing.T) {
+	st := newTestStore(t)
+	ctx := t.Context()
+
+	for _, rec := range []ActionRecord{
+		{ID: "a1", Component: "k8s", Action: "install", Target: "aether-k8s-install"},
+		{ID: "a2", Workspace: "lab2", Component: "k8s", Action: "install", Target: "aether-k8s-install"},
+	} {
+		if err := st.InsertAction(ctx, rec); err != nil {
+			t.Fatalf("InsertAction(%s): %v", rec.ID, err)
+		}
+	}
+	recs, err := st.ListActions(ctx, ActionFilter{Workspace: "lab2"})
+	if err != nil {
+		t.Fatalf("ListActions: %v", err)
+	}
+	if len(recs) != 1 || recs[0].ID != "a2" || recs[0].Workspace != "lab2" {
+		t.Errorf("lab2 actions = %+v", recs)
+	}
+	all, _ := st.ListActions(ctx, ActionFilter{})
+	if len(all) != 2 {
+		t.Errorf("unfiltered actions = %d, want 2", len(all))
+	}
+	a1, _, _ := st.GetAction(ctx, "a1")
+	if a1.Workspace != DefaultWorkspace {
+		t.Errorf("a1 workspace = %q, want %q", a1.Workspace, DefaultWorkspace)
+	}
+
+	for _, d := range []Deployment{
+		{ID: "d1"},
+		{ID: "d2", Workspace: "lab2"},
+	} {
+		if err := st.InsertDeployment(ctx, d); err != nil {
+			t.Fatalf("InsertDeployment(%s): %v", d.ID, err)
+		}
+	}
+	deps, err := st.ListDeployments(ctx, DeploymentFilter{Workspace: DefaultWorkspace, Status: "pending"})
+	if err != nil {
+		t.Fatalf("ListDeployments: %v", err)
+	}
+	if len(deps) != 1 || deps[0].ID != "d1" {
+		t.Errorf("default deployments = %+v", deps)
+	}
+	d2, _, _ := st.GetDeployment(ctx, "d2")
+	if d2.Workspace != "lab2" {
+		t.Errorf("d2 workspace = %q, want lab2", d2.Workspace)
+	}
+}
+
+func TestChangeLockFor(t *testing.T) {
+	if got := ChangeLockFor(""); got != ChangeLockName {
+		t.Errorf("ChangeLockFor(\"\") = %q", got)
+	}
+	if got := ChangeLockFor(DefaultWorkspace); got != ChangeLockName {
+		t.Errorf("ChangeLockFor(default) = %q", got)
+	}
+	if got := ChangeLockFor("lab2"); got != "cluster:lab2" {
+		t.Errorf("ChangeLockFor(lab2) = %q", got)
+	}
+}
+
+func TestUpdateWorkspaceVersion(t *testing.T) {
+	st := newTestStore(t)
+	ctx := t.Context()
+
+	if err := st.UpdateWorkspaceVersion(ctx, "lab2", "v2.2.0"); err != ErrNotFound {
+		t.Errorf("UpdateWorkspaceVersion missing = %v, want ErrNotFound", err)
+	}
+	if err := st.UpdateWorkspaceVersion(ctx, DefaultWorkspace, "v2.2.0"); err != ErrInvalidArgument {
+		t.Errorf("UpdateWorkspaceVersion default = %v, want ErrInvalidArgument", err)
+	}
+	if err := st.CreateWorkspace(ctx, Workspace{Name: "lab2", OnRampDir: "/x", Version: "v2.1.0"}); err != nil {
+		t.Fatalf("CreateWorkspace: %v", err)
+	}
+	if err := st.UpdateWorkspaceVersion(ctx, "lab2", "v2.2.0"); err != nil {
+		t.Fatalf("UpdateWorkspaceVersion: %v", err)
+	}
+	got, _, _ := st.GetWorkspace(ctx, "lab2")
+	if got.Version != "v2.2.0" {
+		t.Errorf("Version = %q, want v2.2.0", got.Version)
+	}
+}
@@ -0,0 +1,313 @@
+package taskrunner
+
+import (
+	"fmt"
+	"os/exec"
+	"slices"
+	"sort"
+	"time"
+)
+
+// QueueView is a snapshot of the pending queue in start order.
+type QueueView struct {
+	Paused bool       `json:"paused"`
+	Tasks  []TaskView `json:"tasks"`
+}
+
+// Queue returns the pending tasks in the order they will be started, each
+// annotated with its queue position and estimated start time.
+func (r *Runner) Queue() QueueView {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.queueViewLocked()
+}
+
+// Move places a pending task at the given 1-based queue position. Positions
+// beyond either end of the queue are clamped. Returns ErrNotFound if the task
+// ID is unknown, or ErrNotPending if the task is no longer queued.
+func (r *Runner) Move(id string, position int) (QueueView, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t, ok := r.tasks[id]
+	if !ok {
+		return QueueView{}, ErrNotFound
+	}
+	if t.status != StatusPending {
+		return QueueView{}, ErrNotPending
+	}
+
+	r.removeFromQueueLocked(id)
+	idx := min(max(position-1, 0), len(r.queue))
+	r.queue = append(r.queue, nil)
+	copy(r.queue[idx+1:], r.queue[idx:])
+	r.queue[idx] = t
+	r.log.Info("task moved in queue", "id", id, "position", idx+1)
+
+	return r.queueViewLocked(), nil
+}
+
+// Prioritize moves a pending task to the front of the queue so it is the next
+// task started.
+func (r *Runner) Prioritize(id string) (QueueView, error) {
+	return r.Move(id, 1)
+}
+
+// Pause stops the runner from starting queued tasks. Running tasks are not
+// affected and new submissions are queued until Resume is called.
+func (r *Runner) Pause() QueueView {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.paused {
+		r.paused = true
+		r.log.Info("task queue paused", "queue_depth", len(r.queue))
+	}
+	return r.queueViewLocked()
+}
+
+// Resume releases a paused queue and immediately starts as many queued tasks
+// as capacity allows.
+func (r *Runner) Resume() QueueView {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.paused {
+		r.paused = false
+		r.log.Info("task queue resumed", "queue_depth", len(r.queue))
+		r.drainQueue()
+	}
+	return r.queueViewLocked()
+}
+
+// Paused reports whether the queue is currently paused.
+func (r *Runner) Paused() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.paused
+}
+
+// Drain cancels every pending task and empties the queue. Running tasks are
+// left alone. OnComplete callbacks for the canceled tasks are invoked in
+// queue order before Drain returns. The returned views describe the canceled
+// tasks.
+func (r *Runner) Drain() []TaskView {
+	r.mu.Lock()
+	drained := r.queue
+	r.queue = nil
+
+	views := make([]TaskView, 0, len(drained))
+	callbacks := make([]func(TaskView), 0, len(drained))
+	for _, t := range drained {
+		if t.status != StatusPending {
+			continue
+		}
+		views = append(views, r.cancelPendingLocked(t))
+		callbacks = append(callbacks, t.spec.OnComplete)
+	}
+	r.mu.Unlock()
+
+	r.log.Info("task queue drained", "canceled", len(views))
+	for i, cb := range callbacks {
+		if cb != nil {
+			r.safeCallback(views[i], cb)
+		}
+	}
+	return views
+}
+
+// SubmitAhead submits spec like Submit, but queues it directly in front of
+// the pending task with the given ID, typically a held task it prepares.
+// Returns ErrNotFound if the task ID is unknown, or ErrNotPending if the task
+// is no longer queued.
+func (r *Runner) SubmitAhead(spec TaskSpec, id string) (TaskView, error) {
+	if _, err := exec.LookPath(spec.Command); err != nil {
+		return TaskView{}, fmt.Errorf("command %q not found: %w", spec.Command, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	behind, ok := r.tasks[id]
+	if !ok {
+		return TaskView{}, ErrNotFound
+	}
+	idx := slices.Index(r.queue, behind)
+	if behind.status != StatusPending || idx < 0 {
+		return TaskView{}, ErrNotPending
+	}
+	spec.Hold = false
+	t := r.newTaskLocked(spec)
+	r.queue = slices.Insert(r.queue, idx, t)
+	r.log.Info("task queued", "id", t.id, "ahead_of", id, "queue_depth", len(r.queue))
+	r.drainQueue()
+	return r.viewLocked(t), nil
+}
+
+// Release lets a held task start in its place in the queue. Returns
+// ErrNotFound if the task ID is unknown, or ErrNotPending if the task is no
+// longer queued.
+func (r *Runner) Release(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t, ok := r.tasks[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if t.status != StatusPending {
+		return ErrNotPending
+	}
+	t.held = false
+	r.drainQueue()
+	return nil
+}
+
+// Fail removes a pending task from the queue without running it and marks it
+// failed with msg; its OnComplete callback is invoked. Returns ErrNotFound if
+// the task ID is unknown, or ErrNotPending if the task is no longer queued.
+func (r *Runner) Fail(id, msg string) error {
+	r.mu.Lock()
+	t, ok := r.tasks[id]
+	if !ok {
+		r.mu.Unlock()
+		return ErrNotFound
+	}
+	if t.status != StatusPending {
+		r.mu.Unlock()
+		return ErrNotPending
+	}
+	r.removeFromQueueLocked(id)
+	v := r.endPendingLocked(t, StatusFailed, msg)
+	r.drainQueue()
+	r.mu.Unlock()
+
+	if cb := t.spec.OnComplete; cb != nil {
+		r.safeCallback(v, cb)
+	}
+	return nil
+}
+
+// removeFromQueueLocked deletes the task with the given ID from the queue if
+// present. Caller must hold r.mu.
+func (r *Runner) removeFromQueueLocked(id string) {
+	for i, qt := range r.queue {
+		if qt.id == id {
+			r.queue = append(r.queue[:i], r.queue[i+1:]...)
+			return
+		}
+	}
+}
+
+// queueViewLocked builds a QueueView from the current queue. Caller must hold
+// r.mu.
+func (r *Runner) queueViewLocked() QueueView {
+	est := r.estimateStartsLocked(time.Now().UTC())
+	qv := QueueView{
+		Paused: r.paused,
+		Tasks:  make([]TaskView, 0, len(r.queue)),
+	}
+	for _, t := range r.queue {
+		if t.status != StatusPending {
+			continue
+		}
+		qv.Tasks = append(qv.Tasks, r.annotateLocked(t.view(), est))
+	}
+	return qv
+}
+
+// viewLocked returns the task's view annotated with queue information.
+// Caller must hold r.mu.
+func (r *Runner) viewLocked(t *task) TaskView {
+	v := t.view()
+	if t.status != StatusPending {
+		return v
+	}
+	return r.annotateLocked(v, r.estimateStartsLocked(time.Now().UTC()))
+}
+
+// annotateLocked fills in QueuePosition and EstimatedStartAt for a pending
+// task view. Caller must hold r.mu.
+func (r *Runner) annotateLocked(v TaskView, est map[string]time.Time) TaskView {
+	if v.Status != StatusPending {
+		return v
+	}
+	for i, qt := range r.queue {
+		if qt.id == v.ID {
+			v.QueuePosition = i + 1
+			break
+		}
+	}
+	v.EstimatedStartAt = est[v.ID]
+	return v
+}
+
+// estimateStartsLocked predicts when each queued task will start by replaying
+// the queue against the available slots, using the mean duration of earlier
+// completed runs of the same command. Estimation stops at the first task
+// whose duration (or whose predecessor's duration) is unknown, and no
+// estimates are produced while the queue is paused. Caller must hold r.mu.
+func (r *Runner) estimateStartsLocked(now time.Time) map[string]time.Time {
+	if r.paused || len(r.queue) == 0 || r.cfg.MaxConcurrent <= 0 {
+		return nil
+	}
+
+	type stat struct {
+		total time.Duration
+		n     int
+	}
+	stats := make(map[string]stat)
+	for _, t := range r.tasks {
+		if t.status != StatusSucceeded && t.status != StatusFailed {
+			continue
+		}
+		if t.startedAt.IsZero() {
+			continue
+		}
+		k := t.durationKey()
+		s := stats[k]
+		s.total += t.finishedAt.Sub(t.startedAt)
+		s.n++
+		stats[k] = s
+	}
+	expected := func(t *task) (time.Duration, bool) {
+		s, ok := stats[t.durationKey()]
+		if !ok || s.n == 0 {
+			return 0, false
+		}
+		return s.total / time.Duration(s.n), true
+	}
+
+	// Each slot holds the time at which it becomes free.
+	slots := make([]time.Time, 0, r.cfg.MaxConcurrent)
+	for _, t := range r.tasks {
+		if t.status != StatusRunning {
+			continue
+		}
+		d, ok := expected(t)
+		if !ok {
+			return nil
+		}
+		free := t.startedAt.Add(d)
+		if free.Before(now) {
+			free = now
+		}
+		slots = append(slots, free)
+	}
+	for len(slots) < r.cfg.MaxConcurrent {
+		slots = append(slots, now)
+	}
+
+	est := make(map[string]time.Time, len(r.queue))
+	for _, t := range r.queue {
+		if t.status != StatusPending {
+			continue
+		}
+		sort.Slice(slots, func(i, j int) bool { return slots[i].Before(slots[j]) })
+		est[t.id] = slots[0]
+		d, ok := expected(t)
+		if !ok {
+			break
+		}
+		slots[0] = slots[0].Add(d)
+	}
+	return est
+}
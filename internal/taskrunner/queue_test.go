@@ -0,0 +1,328 @@
+package taskrunner
+
+import (
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// queueN fills the single slot with a long-running blocker and queues n echo
+// tasks behind it, returning the blocker and queued task IDs.
+func queueN(t *testing.T, r *Runner, n int) (string, []string) {
+	t.Helper()
+	blocker, err := r.Submit(TaskSpec{Command: "sleep", Args: []string{"10"}})
+	if err != nil {
+		t.Fatalf("Submit blocker: %v", err)
+	}
+	ids := make([]string, n)
+	for i := range n {
+		v, err := r.Submit(TaskSpec{Command: "echo", Args: []string{"queued"}})
+		if err != nil {
+			t.Fatalf("Submit queued: %v", err)
+		}
+		ids[i] = v.ID
+	}
+	t.Cleanup(func() { r.Cancel(blocker.ID) })
+	return blocker.ID, ids
+}
+
+func queueIDs(qv QueueView) []string {
+	ids := make([]string, len(qv.Tasks))
+	for i, v := range qv.Tasks {
+		ids[i] = v.ID
+	}
+	return ids
+}
+
+func TestQueue_Positions(t *testing.T) {
+	r := New(RunnerConfig{MaxConcurrent: 1})
+	blockerID, ids := queueN(t, r, 3)
+
+	qv := r.Queue()
+	if len(qv.Tasks) != 3 {
+		t.Fatalf("queue length = %d, want 3", len(qv.Tasks))
+	}
+	for i, v := range qv.Tasks {
+		if v.ID != ids[i] {
+			t.Errorf("queue[%d] = %s, want %s", i, v.ID, ids[i])
+		}
+		if v.QueuePosition != i+1 {
+			t.Errorf("queue[%d].QueuePosition = %d, want %d", i, v.QueuePosition, i+1)
+		}
+	}
+
+	got, _ := r.Get(ids[1])
+	if got.QueuePosition != 2 {
+		t.Errorf("Get QueuePosition = %d, want 2", got.QueuePosition)
+	}
+	running, _ := r.Get(blockerID)
+	if running.QueuePosition != 0 {
+		t.Errorf("running task QueuePosition = %d, want 0", running.QueuePosition)
+	}
+}
+
+func TestQueue_Move(t *testing.T) {
+	r := New(RunnerConfig{MaxConcurrent: 1})
+	_, ids := queueN(t, r, 3)
+
+	qv, err := r.Move(ids[0], 3)
+	if err != nil {
+		t.Fatalf("Move: %v", err)
+	}
+	want := []string{ids[1], ids[2], ids[0]}
+	if got := queueIDs(qv); !slices.Equal(got, want) {
+		t.Errorf("after Move order = %v, want %v", got, want)
+	}
+
+	// Out-of-range positions are clamped.
+	qv, err = r.Move(ids[0], -5)
+	if err != nil {
+		t.Fatalf("Move clamp: %v", err)
+	}
+	want = []string{ids[0], ids[1], ids[2]}
+	if got := queueIDs(qv); !slices.Equal(got, want) {
+		t.Errorf("after clamped Move order = %v, want %v", got, want)
+	}
+}
+
+func TestQueue_Prioritize(t *testing.T) {
+	r := New(RunnerConfig{MaxConcurrent: 1})
+	_, ids := queueN(t, r, 3)
+
+	qv, err := r.Prioritize(ids[2])
+	if err != nil {
+		t.Fatalf("Prioritize: %v", err)
+	}
+	if qv.Tasks[0].ID != ids[2] {
+		t.Errorf("front of queue = %s, want %s", qv.Tasks[0].ID, ids[2])
+	}
+}
+
+func TestQueue_MoveErrors(t *testing.T) {
+	r := New(RunnerConfig{MaxConcurrent: 1})
+	blockerID, _ := queueN(t, r, 1)
+
+	if _, err := r.Move("nope", 1); err != ErrNotFound {
+		t.Errorf("Move unknown = %v, want ErrNotFound", err)
+	}
+	if _, err := r.Move(blockerID, 1); err != ErrNotPending {
+		t.Errorf("Move running = %v, want ErrNotPending", err)
+	}
+}
+
+func TestQueue_PauseResume(t *testing.T) {
+	r := New(RunnerConfig{MaxConcurrent: 1})
+	r.Pause()
+	if !r.Paused() {
+		t.Fatal("Paused() = false after Pause")
+	}
+
+	v, err := r.Submit(TaskSpec{Command: "echo", Args: []string{"held"}})
+	if err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	if v.Status != StatusPending {
+		t.Fatalf("status while paused = %q, want %q", v.Status, StatusPending)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if got, _ := r.Get(v.ID); got.Status != StatusPending {
+		t.Fatalf("status after wait = %q, want %q", got.Status, StatusPending)
+	}
+
+	qv := r.Resume()
+	if qv.Paused {
+		t.Error("QueueView.Paused = true after Resume")
+	}
+	waitForTask(t, r, v.ID, 5*time.Second)
+	if got, _ := r.Get(v.ID); got.Status != StatusSucceeded {
+		t.Fatalf("status after resume = %q, want %q", got.Status, StatusSucceeded)
+	}
+}
+
+func TestQueue_Drain(t *testing.T) {
+	r := New(RunnerConfig{MaxConcurrent: 1})
+	blocker, _ := r.Submit(TaskSpec{Command: "sleep", Args: []string{"10"}})
+	defer r.Cancel(blocker.ID)
+
+	var mu sync.Mutex
+	var completed []TaskView
+	for range 2 {
+		r.Submit(TaskSpec{
+			Command: "echo",
+			OnComplete: func(v TaskView) {
+				mu.Lock()
+				completed = append(completed, v)
+				mu.Unlock()
+			},
+		})
+	}
+
+	drained := r.Drain()
+	if len(drained) != 2 {
+		t.Fatalf("Drain returned %d tasks, want 2", len(drained))
+	}
+	if len(r.Queue().Tasks) != 0 {
+		t.Error("queue not empty after Drain")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(completed) != 2 {
+		t.Fatalf("OnComplete called %d times, want 2", len(completed))
+	}
+	for _, v := range completed {
+		if v.Status != StatusCanceled {
+			t.Errorf("drained status = %q, want %q", v.Status, StatusCanceled)
+		}
+	}
+
+	// The running task is untouched.
+	if got, _ := r.Get(blocker.ID); got.Status != StatusRunning {
+		t.Errorf("blocker status = %q, want %q", got.Status, StatusRunning)
+	}
+}
+
+func TestQueue_EstimatedStart(t *testing.T) {
+	r := New(RunnerConfig{MaxConcurrent: 1})
+
+	// Seed duration history for the queued command.
+	prior, _ := r.Submit(TaskSpec{Command: "echo", Args: []string{"queued"}})
+	waitForTask(t, r, prior.ID, 5*time.Second)
+
+	_, ids := queueN(t, r, 2)
+
+	// The blocker has no history, so nothing can be estimated yet.
+	if got, _ := r.Get(ids[0]); !got.EstimatedStartAt.IsZero() {
+		t.Errorf("EstimatedStartAt = %v, want zero without blocker history", got.EstimatedStartAt)
+	}
+
+	// Pretend the blocker has run before so the queue can be replayed.
+	r.mu.Lock()
+	now := time.Now().UTC()
+	r.tasks["hist"] = &task{
+		id:         "hist",
+		spec:       TaskSpec{Command: "sleep", Args: []string{"10"}},
+		status:     StatusSucceeded,
+		startedAt:  now.Add(-time.Minute),
+		finishedAt: now,
+		output:     &OutputBuffer{},
+	}
+	r.mu.Unlock()
+
+	first, _ := r.Get(ids[0])
+	second, _ := r.Get(ids[1])
+	if first.EstimatedStartAt.IsZero() || second.EstimatedStartAt.IsZero() {
+		t.Fatalf("expected estimates, got %v and %v", first.EstimatedStartAt, second.EstimatedStartAt)
+	}
+	if second.EstimatedStartAt.Before(first.EstimatedStartAt) {
+		t.Errorf("second estimate %v is before first %v", second.EstimatedStartAt, first.EstimatedStartAt)
+	}
+
+	r.Pause()
+	if got, _ := r.Get(ids[0]); !got.EstimatedStartAt.IsZero() {
+		t.Errorf("EstimatedStartAt = %v, want zero while paused", got.EstimatedStartAt)
+	}
+}
+
+func TestCancelPendingTask_CallsOnComplete(t *testing.T) {
+	r := New(RunnerConfig{MaxConcurrent: 1})
+	blocker, _ := r.Submit(TaskSpec{Command: "sleep", Args: []string{"10"}})
+	defer r.Cancel(blocker.ID)
+
+	done := make(chan TaskView, 1)
+	queued, _ := r.Submit(TaskSpec{
+		Command:    "echo",
+		OnComplete: func(v TaskView) { done <- v },
+	})
+	if err := r.Cancel(queued.ID); err != nil {
+		t.Fatalf("Cancel: %v", err)
+	}
+
+	select {
+	case v := <-done:
+		if v.Status != StatusCanceled {
+			t.Errorf("status = %q, want %q", v.Status, StatusCanceled)
+		}
+		if !v.StartedAt.IsZero() {
+			t.Error("canceled pending task should have zero StartedAt")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("OnComplete not called for canceled pending task")
+	}
+}
+
+func TestQueue_Hold(t *testing.T) {
+	r := New(RunnerConfig{MaxConcurrent: 1})
+	blocker, _ := r.Submit(TaskSpec{Command: "sleep", Args: []string{"10"}})
+
+	ready := make(chan TaskView, 1)
+	held, err := r.Submit(TaskSpec{Command: "echo", Args: []string{"held"}, Hold: true, OnReady: func(v TaskView) { ready <- v }})
+	if err != nil {
+		t.Fatalf("Submit held: %v", err)
+	}
+	later, _ := r.Submit(TaskSpec{Command: "echo", Args: []string{"later"}})
+	if held.QueuePosition != 1 {
+		t.Errorf("held QueuePosition = %d, want 1", held.QueuePosition)
+	}
+
+	// Nothing is ready while another task holds the slot.
+	select {
+	case <-ready:
+		t.Fatal("OnReady called while the slot was busy")
+	case <-time.After(50 * time.Millisecond):
+	}
+	r.Cancel(blocker.ID)
+	select {
+	case <-ready:
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnReady not called")
+	}
+
+	// A task submitted ahead runs; the one behind keeps waiting.
+	ahead, err := r.SubmitAhead(TaskSpec{Command: "echo", Args: []string{"ahead"}}, held.ID)
+	if err != nil {
+		t.Fatalf("SubmitAhead: %v", err)
+	}
+	waitForTask(t, r, ahead.ID, 5*time.Second)
+	time.Sleep(50 * time.Millisecond)
+	if got := queueIDs(r.Queue()); !slices.Equal(got, []string{held.ID, later.ID}) {
+		t.Fatalf("queue after ahead task = %v", got)
+	}
+
+	if err := r.Release(held.ID); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	waitForTask(t, r, held.ID, 5*time.Second)
+	if v, _ := r.Get(held.ID); v.Status != StatusSucceeded {
+		t.Errorf("held status = %q", v.Status)
+	}
+	waitForTask(t, r, later.ID, 5*time.Second)
+	if err := r.Release(held.ID); err != ErrNotPending {
+		t.Errorf("Release finished task: err = %v, want ErrNotPending", err)
+	}
+}
+
+func TestQueue_FailHeld(t *testing.T) {
+	r := New(RunnerConfig{MaxConcurrent: 1})
+	done := make(chan TaskView, 1)
+	held, _ := r.Submit(TaskSpec{Command: "echo", Hold: true, OnComplete: func(v TaskView) { done <- v }})
+	later, _ := r.Submit(TaskSpec{Command: "echo"})
+
+	if err := r.Fail(held.ID, "hook failed"); err != nil {
+		t.Fatalf("Fail: %v", err)
+	}
+	v := <-done
+	if v.Status != StatusFailed || v.Error != "hook failed" || v.ExitCode != -1 {
+		t.Errorf("failed view = %+v", v)
+	}
+	// The task behind the failed one is no longer held up.
+	waitForTask(t, r, later.ID, 5*time.Second)
+	if got, _ := r.Get(later.ID); got.Status != StatusSucceeded {
+		t.Errorf("later status = %q", got.Status)
+	}
+	if _, err := r.SubmitAhead(TaskSpec{Command: "echo"}, held.ID); err != ErrNotPending {
+		t.Errorf("SubmitAhead finished task: err = %v, want ErrNotPending", err)
+	}
+}
@@ -2,6 +2,7 @@ package taskrunner
 
 import (
 	"errors"
+	"strings"
 	"time"
 )
 
@@ -20,6 +21,7 @@ const (
 var (
 	ErrNotFound   = errors.New("task not found")
 	ErrNotRunning = errors.New("task is not running")
+	ErrNotPending = errors.New("task is not pending")
 )
 
 // TaskSpec describes a command to execute. Callers provide this to Runner.Submit.
@@ -32,21 +34,30 @@ type TaskSpec struct {
 	Labels      map[string]string // arbitrary provider-specific metadata
 	Description string            // human-readable summary
 	OnStart     func(TaskView)    // called when task transitions from pending to running; nil = no callback
-	OnComplete  func(TaskView)    // called after task finishes; nil = no callback
+	OnComplete  func(TaskView)    // called after task finishes or is removed from the queue; nil = no callback
+
+	// Hold queues the task without starting it until Release is called. A
+	// held task at the front of the queue keeps later tasks waiting, while
+	// tasks submitted with SubmitAhead run in front of it. OnReady is called
+	// once, when the held task first reaches the front with a free slot.
+	Hold    bool
+	OnReady func(TaskView)
 }
 
 // task is the internal mutable state for a running or completed command.
 type task struct {
-	id          string
-	spec        TaskSpec
-	status      TaskStatus
-	createdAt   time.Time
-	startedAt   time.Time
-	finishedAt  time.Time
-	exitCode    int
-	errMsg      string
-	output      *OutputBuffer
-	cancelFunc  func()
+	id         string
+	spec       TaskSpec
+	status     TaskStatus
+	createdAt  time.Time
+	startedAt  time.Time
+	finishedAt time.Time
+	exitCode   int
+	errMsg     string
+	output     *OutputBuffer
+	cancelFunc func()
+	held       bool // waiting for Release; see TaskSpec.Hold
+	ready      bool // OnReady has been called
 }
 
 // view returns an immutable snapshot of the task's current state.
@@ -69,6 +80,10 @@ func (t *task) view() TaskView {
 // TaskView is an immutable snapshot of a task's state, safe for returning to
 // callers. Output is intentionally excluded — fetch it separately via
 // Runner.Output to avoid pulling large blobs on list/get.
+//
+// QueuePosition is the 1-based position of a pending task in the queue and
+// zero otherwise. EstimatedStartAt is derived from the durations of earlier
+// runs of the same command and is left zero when no estimate is possible.
 type TaskView struct {
 	ID          string            `json:"id"`
 	Status      TaskStatus        `json:"status"`
@@ -81,6 +96,9 @@ type TaskView struct {
 	FinishedAt  time.Time         `json:"finished_at,omitzero"`
 	ExitCode    int               `json:"exit_code"`
 	Error       string            `json:"error,omitempty"`
+
+	QueuePosition    int       `json:"queue_position,omitempty"`
+	EstimatedStartAt time.Time `json:"estimated_start_at,omitzero"`
 }
 
 // ListFilter controls which tasks Runner.List returns.
@@ -88,3 +106,9 @@ type ListFilter struct {
 	Status *TaskStatus
 	Label  map[string]string
 }
+
+// durationKey groups tasks that run the same command so their past durations
+// can be used to estimate queue start times.
+func (t *task) durationKey() string {
+	return t.spec.Command + "\x00" + strings.Join(t.spec.Args, "\x00")
+}
@@ -23,13 +23,15 @@ type RunnerConfig struct {
 // Runner manages the lifecycle of asynchronous command executions.
 // When MaxConcurrent is set, tasks beyond the limit are queued in
 // submission order and started automatically as running tasks complete.
+// The queue can be paused, reordered, and drained; see queue.go.
 type Runner struct {
 	cfg RunnerConfig
 	log *slog.Logger
 
-	mu    sync.RWMutex
-	tasks map[string]*task
-	queue []*task // pending tasks in FIFO order
+	mu     sync.RWMutex
+	tasks  map[string]*task
+	queue  []*task // pending tasks in start order
+	paused bool    // when true, queued tasks are held until Resume
 }
 
 // New creates a Runner with the given configuration.
@@ -46,7 +48,8 @@ func New(cfg RunnerConfig) *Runner {
 }
 
 // Submit validates the command, creates a new task, and either starts it
-// immediately or queues it if the concurrency limit is reached. The returned
+// immediately or queues it if the concurrency limit is reached or other tasks
+// are waiting. A held task is always queued; see TaskSpec.Hold. The returned
 // TaskView reflects the initial state (StatusRunning or StatusPending).
 func (r *Runner) Submit(spec TaskSpec) (TaskView, error) {
 	// Validate the command binary exists on PATH.
@@ -57,29 +60,34 @@ func (r *Runner) Submit(spec TaskSpec) (TaskView, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
+	t := r.newTaskLocked(spec)
+	if !spec.Hold && len(r.queue) == 0 && r.canStartLocked() {
+		r.startLocked(t)
+	} else {
+		r.queue = append(r.queue, t)
+		r.log.Info("task queued", "id", t.id, "queue_depth", len(r.queue))
+		r.drainQueue()
+	}
+
+	return r.viewLocked(t), nil
+}
+
+// newTaskLocked registers a pending task for spec. Caller must hold r.mu.
+func (r *Runner) newTaskLocked(spec TaskSpec) *task {
 	id := spec.ID
 	if id == "" {
 		id = uuid.NewString()
 	}
-
-	now := time.Now().UTC()
 	t := &task{
 		id:        id,
 		spec:      spec,
 		status:    StatusPending,
-		createdAt: now,
+		createdAt: time.Now().UTC(),
 		output:    &OutputBuffer{},
+		held:      spec.Hold,
 	}
 	r.tasks[t.id] = t
-
-	if r.canStartLocked() {
-		r.startLocked(t)
-	} else {
-		r.queue = append(r.queue, t)
-		r.log.Info("task queued", "id", t.id, "queue_depth", len(r.queue))
-	}
-
-	return t.view(), nil
+	return t
 }
 
 // Get returns an immutable snapshot of the task with the given ID.
@@ -90,7 +98,7 @@ func (r *Runner) Get(id string) (TaskView, error) {
 		r.mu.RUnlock()
 		return TaskView{}, ErrNotFound
 	}
-	v := t.view()
+	v := r.viewLocked(t)
 	r.mu.RUnlock()
 	return v, nil
 }
@@ -99,12 +107,13 @@ func (r *Runner) Get(id string) (TaskView, error) {
 // can narrow results by status or labels.
 func (r *Runner) List(filter *ListFilter) []TaskView {
 	r.mu.RLock()
+	est := r.estimateStartsLocked(time.Now().UTC())
 	views := make([]TaskView, 0, len(r.tasks))
 	for _, t := range r.tasks {
 		if filter != nil && !matchFilter(t, filter) {
 			continue
 		}
-		views = append(views, t.view())
+		views = append(views, r.annotateLocked(t.view(), est))
 	}
 	r.mu.RUnlock()
 
@@ -132,43 +141,63 @@ func (r *Runner) Output(id string, offset int) (OutputChunk, error) {
 }
 
 // Cancel sends a cancellation signal to a running task. Pending tasks are
-// removed from the queue and marked as canceled immediately. Returns
-// ErrNotFound if the task ID is unknown, or ErrNotRunning if the task has
-// already finished.
+// removed from the queue and marked as canceled immediately, and their
+// OnComplete callback is invoked. Returns ErrNotFound if the task ID is
+// unknown, or ErrNotRunning if the task has already finished.
 func (r *Runner) Cancel(id string) error {
 	r.mu.Lock()
-	defer r.mu.Unlock()
 
 	t, ok := r.tasks[id]
 	if !ok {
+		r.mu.Unlock()
 		return ErrNotFound
 	}
 
 	switch t.status {
 	case StatusPending:
-		// Remove from queue.
-		for i, qt := range r.queue {
-			if qt.id == id {
-				r.queue = append(r.queue[:i], r.queue[i+1:]...)
-				break
-			}
+		r.removeFromQueueLocked(id)
+		v := r.cancelPendingLocked(t)
+		// A held task may have kept the tasks behind it waiting.
+		r.drainQueue()
+		r.mu.Unlock()
+		if cb := t.spec.OnComplete; cb != nil {
+			r.safeCallback(v, cb)
 		}
-		t.status = StatusCanceled
-		t.finishedAt = time.Now().UTC()
-		t.errMsg = "canceled"
-		t.exitCode = -1
 		return nil
 	case StatusRunning:
 		t.cancelFunc()
+		r.mu.Unlock()
 		return nil
 	default:
+		r.mu.Unlock()
 		return ErrNotRunning
 	}
 }
 
-// canStartLocked returns true if there is capacity to start another task.
-// Caller must hold r.mu.
+// cancelPendingLocked marks a task that never started as canceled and returns
+// its final view. The caller is responsible for removing it from the queue
+// and for invoking OnComplete once r.mu has been released.
+func (r *Runner) cancelPendingLocked(t *task) TaskView {
+	return r.endPendingLocked(t, StatusCanceled, "canceled")
+}
+
+// endPendingLocked ends a task that never started with status and msg and
+// returns its final view, like cancelPendingLocked.
+func (r *Runner) endPendingLocked(t *task, status TaskStatus, msg string) TaskView {
+	t.status = status
+	t.held = false
+	t.finishedAt = time.Now().UTC()
+	t.errMsg = msg
+	t.exitCode = -1
+	return t.view()
+}
+
+// canStartLocked returns true if the queue is not paused and there is
+// capacity to start another task. Caller must hold r.mu.
 func (r *Runner) canStartLocked() bool {
+	if r.paused {
+		return false
+	}
 	if r.cfg.MaxConcurrent <= 0 {
 		return true
 	}
@@ -201,15 +230,27 @@ func (r *Runner) startLocked(t *task) {
 }
 
 // drainQueue starts the next pending task from the queue if capacity allows.
-// Caller must hold r.mu.
+// A held task at the front stops it; the first time, its OnReady callback is
+// called. Caller must hold r.mu.
 func (r *Runner) drainQueue() {
 	for len(r.queue) > 0 && r.canStartLocked() {
 		next := r.queue[0]
-		r.queue = r.queue[1:]
 		// Skip tasks that were canceled while pending.
 		if next.status != StatusPending {
+			r.queue = r.queue[1:]
 			continue
 		}
+		if next.held {
+			if !next.ready {
+				next.ready = true
+				if cb := next.spec.OnReady; cb != nil {
+					v := next.view()
+					go r.safeCallback(v, cb)
+				}
+			}
+			return
+		}
+		r.queue = r.queue[1:]
 		r.startLocked(next)
 		r.log.Info("dequeued task", "id", next.id, "remaining", len(r.queue))
 	}